@@ -1,6 +1,8 @@
 package alert
 
 import (
+	"context"
+	"fmt"
 	"watchAlert/alert/consumer"
 	"watchAlert/alert/eval"
 	"watchAlert/alert/probing"
@@ -15,6 +17,17 @@ var (
 	ConsumeProbing probing.ConsumeProbing
 )
 
+// Shutdown 先停止规则评估, 再停止消费进程并发送剩余的告警, 均在 ctx 超时前完成
+func Shutdown(ctx context.Context) error {
+	if err := AlertRule.Shutdown(ctx); err != nil {
+		return fmt.Errorf("等待规则评估完成超时: %w", err)
+	}
+	if err := ConsumerWork.Shutdown(ctx); err != nil {
+		return fmt.Errorf("等待告警通知完成失败: %w", err)
+	}
+	return nil
+}
+
 func Initialize(ctx *ctx.Context) {
 	// 初始化告警规则评估任务
 	AlertRule = eval.NewAlertRuleEval(ctx)
@@ -7,12 +7,16 @@ import (
 	"golang.org/x/sync/errgroup"
 	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 	"watchAlert/alert/mute"
 	"watchAlert/alert/process"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/metrics"
+	"watchAlert/pkg/sender"
+	"watchAlert/pkg/tools"
 )
 
 type (
@@ -21,11 +25,27 @@ type (
 		Stop(faultCenterId string)
 		Watch(ctx context.Context, faultCenter models.FaultCenter)
 		RestartAllConsumers()
+		Shutdown(ctx context.Context) error
 	}
 
 	Consume struct {
 		ctx *ctx.Context
 		sync.RWMutex
+		// 按标签分组聚合时各分组的发送状态
+		groupStates map[string]*groupState
+		groupMux    sync.Mutex
+		// 服务退出时发送所有分组等待中的告警, 不再等待 GroupWait
+		flushing bool
+
+		// 进行中的消费任务, 服务退出时等待其完成
+		inflight    sync.WaitGroup
+		stopping    bool
+		shutdownMux sync.Mutex
+	}
+
+	groupState struct {
+		createdAt  int64 // 分组首次出现时间
+		lastSentAt int64 // 上一次发送时间
 	}
 
 	EventsGroup struct {
@@ -72,7 +92,7 @@ func (ag *AlertGroups) AddAlert(stateId string, alert *models.AlertCurEvent, fau
 					Events: []*models.AlertCurEvent{alert},
 				})
 			}
-			return
+			continue
 		}
 
 		// 插入新Rule
@@ -88,8 +108,12 @@ func (ag *AlertGroups) AddAlert(stateId string, alert *models.AlertCurEvent, fau
 	}
 }
 
-// getNoticeId 从告警路由中获取该事件匹配的通知对象
+// getNoticeId 从告警路由中获取该事件匹配的通知对象, 优先使用告警路由树
 func (ag *AlertGroups) getNoticeId(alert *models.AlertCurEvent, faultCenter models.FaultCenter) []string {
+	if faultCenter.IsRouteTreeEnabled() {
+		return matchRouteTree(alert.Metric, faultCenter)
+	}
+
 	if len(faultCenter.NoticeRoutes) > 0 {
 		metrics := alert.Metric
 
@@ -130,11 +154,19 @@ func (ag *AlertGroups) getGroupNodePos(rule *RulesGroup, groupId string) int {
 
 func NewConsumerWork(ctx *ctx.Context) ConsumeInterface {
 	return &Consume{
-		ctx: ctx,
+		ctx:         ctx,
+		groupStates: make(map[string]*groupState),
 	}
 }
 
 func (c *Consume) Submit(faultCenter models.FaultCenter) {
+	c.shutdownMux.Lock()
+	stopping := c.stopping
+	c.shutdownMux.Unlock()
+	if stopping {
+		return
+	}
+
 	c.ctx.Mux.Lock()
 	defer c.ctx.Mux.Unlock()
 
@@ -169,15 +201,72 @@ func (c *Consume) Watch(ctx context.Context, faultCenter models.FaultCenter) {
 	for {
 		select {
 		case <-timer.C:
-			// 处理任务信号量
-			taskChan <- struct{}{}
-			c.executeTask(faultCenter, taskChan)
+			if !c.beginTask() {
+				return
+			}
+			func() {
+				defer c.inflight.Done()
+				// 处理任务信号量
+				taskChan <- struct{}{}
+				c.executeTask(faultCenter, taskChan)
+			}()
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// beginTask 登记进行中的消费任务, 服务退出中时返回 false
+func (c *Consume) beginTask() bool {
+	c.shutdownMux.Lock()
+	defer c.shutdownMux.Unlock()
+
+	if c.stopping {
+		return false
+	}
+	c.inflight.Add(1)
+	return true
+}
+
+// Shutdown 停止所有消费进程并等待进行中的通知发送完成, 之后对各故障中心再执行一次消费,
+// 发送分组等待中的告警及评估退出前产生的事件, 超时返回 ctx 的错误
+func (c *Consume) Shutdown(ctx context.Context) error {
+	c.shutdownMux.Lock()
+	c.stopping = true
+	c.shutdownMux.Unlock()
+
+	c.ctx.Mux.Lock()
+	for id, cancel := range c.ctx.ConsumerContextMap {
+		cancel()
+		delete(c.ctx.ConsumerContextMap, id)
+	}
+	c.ctx.Mux.Unlock()
+
+	if err := tools.WaitWithContext(ctx, &c.inflight); err != nil {
+		return err
+	}
+
+	list, err := c.ctx.DB.FaultCenter().List(models.FaultCenterQuery{})
+	if err != nil {
+		return fmt.Errorf("获取故障中心列表错误, err: %s", err.Error())
+	}
+
+	c.groupMux.Lock()
+	c.flushing = true
+	c.groupMux.Unlock()
+
+	for _, fc := range list {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		taskChan := make(chan struct{}, 1)
+		taskChan <- struct{}{}
+		c.executeTask(fc, taskChan)
+	}
+
+	return nil
+}
+
 // executeTask 执行具体的任务逻辑
 func (c *Consume) executeTask(faultCenter models.FaultCenter, taskChan chan struct{}) {
 	defer func() {
@@ -193,6 +282,10 @@ func (c *Consume) executeTask(faultCenter models.FaultCenter, taskChan chan stru
 		return
 	}
 
+	// 记录当前告警中的事件数
+	c.recordActiveAlerts(faultCenter, data)
+	// 清理已无告警的分组状态
+	c.pruneGroupStates(faultCenter, data)
 	// 事件过滤
 	filterEvents := c.filterAlertEvents(faultCenter, data)
 	// 事件分组
@@ -205,6 +298,8 @@ func (c *Consume) executeTask(faultCenter models.FaultCenter, taskChan chan stru
 	if err != nil {
 		logc.Error(c.ctx.Ctx, fmt.Sprintf("process alarm upgeade fail, err: %s", err.Error()))
 	}
+	// 处理规则升级策略
+	alarmEscalate(c.ctx, faultCenter, data)
 }
 
 // filterAlertEvents 过滤告警事件
@@ -217,10 +312,21 @@ func (c *Consume) filterAlertEvents(faultCenter models.FaultCenter, alerts map[s
 			continue
 		}
 
+		// 关闭恢复通知时, 已恢复的事件直接归档, 避免一直残留在缓存中
+		if event.IsRecovered && !event.IsRecoverNotify(faultCenter) {
+			c.archiveRecoveredEvent(event)
+			continue
+		}
+
 		if c.isMutedEvent(event, faultCenter) {
 			continue
 		}
 
+		// 源告警触发期间, 被抑制的告警不发送通知
+		if len(mute.InhibitedBy(faultCenter.InhibitRules, event, alerts)) > 0 {
+			continue
+		}
+
 		if valid := c.validateEvent(event, faultCenter); valid {
 			newEvents = append(newEvents, event)
 		}
@@ -237,14 +343,22 @@ func (c *Consume) isMutedEvent(event *models.AlertCurEvent, faultCenter models.F
 		TenantId:      event.TenantId,
 		Metrics:       event.Metric,
 		FaultCenterId: event.FaultCenterId,
-		RecoverNotify: faultCenter.RecoverNotify,
+		RecoverNotify: event.IsRecoverNotify(faultCenter),
 	})
 }
 
 // validateEvent 事件验证
 func (c *Consume) validateEvent(event *models.AlertCurEvent, faultCenter models.FaultCenter) bool {
-	return event.IsRecovered || event.LastSendTime == 0 ||
-		event.LastEvalTime >= event.LastSendTime+faultCenter.RepeatNoticeInterval*60
+	if event.IsRecovered {
+		return true
+	}
+
+	// 按指纹去重, 持续告警时每隔重复通知间隔发送一次
+	lastSendTime := c.ctx.Redis.Alert().GetLastSendTime(event.TenantId, event.FaultCenterId, event.Fingerprint)
+	if lastSendTime == 0 {
+		return true
+	}
+	return time.Now().Unix() >= lastSendTime+event.GetRepeatNoticeInterval(faultCenter)*60
 }
 
 // alarmGrouping 告警分组
@@ -257,33 +371,47 @@ func (c *Consume) alarmGrouping(faultCenter models.FaultCenter, alertGroups *Ale
 	}
 
 	for _, alert := range alerts {
+		// 按标签分组聚合时, 标签值相同的告警 (可跨规则) 分到同一组
+		groupId := alert.RuleId
+		if faultCenter.IsLabelsAggregation() {
+			groupId = faultCenter.GetGroupKey(alert.Metric)
+		}
+
 		// 状态+规则 = 状态 ID
 		var stateId string
 		switch alert.IsRecovered {
 		case true:
-			stateId = "Recover_" + alert.RuleId
+			stateId = "Recover_" + groupId
 		case false:
-			stateId = "Firing_" + alert.RuleId
+			stateId = "Firing_" + groupId
 		default:
-			stateId = "Unknown_" + alert.RuleId
+			stateId = "Unknown_" + groupId
 		}
 
 		alertGroups.AddAlert(stateId, alert, faultCenter)
 		if alert.IsRecovered {
-			c.removeAlertFromCache(alert)
-			if err := process.RecordAlertHisEvent(c.ctx, *alert); err != nil {
-				logc.Error(c.ctx.Ctx, fmt.Sprintf("Failed to record alert history: %v", err))
-			}
+			c.archiveRecoveredEvent(alert)
 		}
 	}
 }
 
+// archiveRecoveredEvent 从缓存中删除已恢复的告警并记录历史事件
+func (c *Consume) archiveRecoveredEvent(alert *models.AlertCurEvent) {
+	c.removeAlertFromCache(alert)
+	if err := process.RecordAlertHisEvent(c.ctx, *alert); err != nil {
+		logc.Error(c.ctx.Ctx, fmt.Sprintf("Failed to record alert history: %v", err))
+	}
+}
+
 // sendAlerts 发送告警
 func (c *Consume) sendAlerts(faultCenter models.FaultCenter, aggEvents *AlertGroups) {
 	c.RLock()
 	defer c.RUnlock()
 
 	for _, rule := range aggEvents.Rules {
+		if !c.allowGroupSend(faultCenter, rule.RuleID) {
+			continue
+		}
 		for _, groups := range rule.Groups {
 			c.processAlertGroup(faultCenter, groups.ID, groups.Events)
 		}
@@ -301,8 +429,80 @@ func (c *Consume) processAlertGroup(faultCenter models.FaultCenter, noticeId str
 	}
 }
 
+// recordActiveAlerts 统计故障中心当前告警中的事件数
+func (c *Consume) recordActiveAlerts(faultCenter models.FaultCenter, events map[string]*models.AlertCurEvent) {
+	var active int
+	for _, event := range events {
+		if event.Status == models.StateAlerting {
+			active++
+		}
+	}
+	metrics.ActiveAlerts.WithLabelValues(faultCenter.TenantId, faultCenter.ID).Set(float64(active))
+}
+
+// allowGroupSend 按标签分组聚合时, 新分组等待 GroupWait 收集告警后再发送, 同一分组的发送间隔不小于 GroupInterval.
+// 恢复事件在分组时已从缓存中移除, 需立即发送
+func (c *Consume) allowGroupSend(faultCenter models.FaultCenter, stateId string) bool {
+	if !faultCenter.IsLabelsAggregation() || !strings.HasPrefix(stateId, "Firing_") {
+		return true
+	}
+
+	c.groupMux.Lock()
+	defer c.groupMux.Unlock()
+
+	now := time.Now().Unix()
+	key := faultCenter.ID + ":" + stateId
+	state, ok := c.groupStates[key]
+	if !ok {
+		state = &groupState{createdAt: now}
+		c.groupStates[key] = state
+	}
+
+	if !c.flushing && now < state.createdAt+faultCenter.GroupWait {
+		return false
+	}
+	if state.lastSentAt > 0 && now < state.lastSentAt+faultCenter.GroupInterval {
+		return false
+	}
+
+	state.lastSentAt = now
+	return true
+}
+
+// pruneGroupStates 清理已无告警事件的分组状态, 分组再次出现时重新等待 GroupWait
+func (c *Consume) pruneGroupStates(faultCenter models.FaultCenter, events map[string]*models.AlertCurEvent) {
+	if !faultCenter.IsLabelsAggregation() {
+		return
+	}
+
+	active := make(map[string]struct{})
+	for _, event := range events {
+		if !event.IsRecovered {
+			active[faultCenter.ID+":Firing_"+faultCenter.GetGroupKey(event.Metric)] = struct{}{}
+		}
+	}
+
+	c.groupMux.Lock()
+	defer c.groupMux.Unlock()
+
+	prefix := faultCenter.ID + ":"
+	for key := range c.groupStates {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if _, ok := active[key]; !ok {
+			delete(c.groupStates, key)
+		}
+	}
+}
+
 // handleSubscribe 处理订阅逻辑
 func (c *Consume) handleSubscribe(alerts []*models.AlertCurEvent) error {
+	// 维护模式下不发送个人订阅通知, 维护结束后仅向通知对象发送汇总
+	if sender.InMaintenance() {
+		return nil
+	}
+
 	g := new(errgroup.Group)
 	for _, event := range alerts {
 		event := event
@@ -354,8 +554,8 @@ func (c *Consume) processSilenceRule(faultCenter models.FaultCenter) {
 		}
 
 		// 如果当前状态为「未生效」，并且生效时间大于等于当前时间，则标记为「生效中」状态
-		if muteRule.Status == 0 && currentTime >= muteRule.StartsAt {
-			muteRule.Status = 1
+		if muteRule.Status == models.SilenceStatusPending && currentTime >= muteRule.StartsAt {
+			muteRule.Status = models.SilenceStatusActive
 			err := c.ctx.DB.Silence().Update(*muteRule)
 			if err != nil {
 				logc.Error(c.ctx.Ctx, fmt.Sprintf("Update silence rule failed, err: %s", err.Error()))
@@ -363,14 +563,17 @@ func (c *Consume) processSilenceRule(faultCenter models.FaultCenter) {
 			}
 		}
 
-		// 如果到达失效日期，则标记「已失效」状态
-		if muteRule.EndsAt <= currentTime {
-			muteRule.Status = 2
+		// 如果到达失效日期，则标记「已失效」状态, 并从缓存中清理; 未设置失效日期的周期性静默长期有效
+		if muteRule.EndsAt > 0 && muteRule.EndsAt <= currentTime {
+			muteRule.Status = models.SilenceStatusExpired
 			err := c.ctx.DB.Silence().Update(*muteRule)
 			if err != nil {
 				logc.Error(c.ctx.Ctx, fmt.Sprintf("Update silence rule failed, err: %s", err.Error()))
 				return
 			}
+			silenceCtx.RemoveAlertMute(muteRule.TenantId, muteRule.FaultCenterId, muteRule.Id)
+			mute.ForgetSilence(*muteRule)
+			continue
 		}
 
 		silenceCtx.PushAlertMute(*muteRule)
@@ -0,0 +1,71 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+	"watchAlert/internal/models"
+)
+
+func TestAllowGroupSend(t *testing.T) {
+	labels := models.FaultCenter{ID: "fc", AggregationType: models.AggregationTypeLabels, GroupBy: []string{"service"}, GroupWait: 30, GroupInterval: 300}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name        string
+		faultCenter models.FaultCenter
+		stateId     string
+		state       *groupState
+		flushing    bool
+		want        bool
+	}{
+		{name: "rule aggregation always sends", faultCenter: models.FaultCenter{ID: "fc", AggregationType: models.AggregationTypeRule}, stateId: "Firing_service=api", want: true},
+		{name: "recovered events send immediately", faultCenter: labels, stateId: "Recover_service=api", want: true},
+		{name: "new group waits group wait", faultCenter: labels, stateId: "Firing_service=api", want: false},
+		{name: "new group without group wait", faultCenter: models.FaultCenter{ID: "fc", AggregationType: models.AggregationTypeLabels, GroupBy: []string{"service"}}, stateId: "Firing_service=api", want: true},
+		{name: "group wait elapsed", faultCenter: labels, stateId: "Firing_service=api", state: &groupState{createdAt: now - 31}, want: true},
+		{name: "within group interval", faultCenter: labels, stateId: "Firing_service=api", state: &groupState{createdAt: now - 600, lastSentAt: now - 60}, want: false},
+		{name: "group interval elapsed", faultCenter: labels, stateId: "Firing_service=api", state: &groupState{createdAt: now - 600, lastSentAt: now - 301}, want: true},
+		{name: "flushing skips group wait", faultCenter: labels, stateId: "Firing_service=api", flushing: true, want: true},
+		{name: "flushing keeps group interval", faultCenter: labels, stateId: "Firing_service=api", state: &groupState{createdAt: now - 600, lastSentAt: now - 60}, flushing: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Consume{groupStates: make(map[string]*groupState), flushing: tt.flushing}
+			key := tt.faultCenter.ID + ":" + tt.stateId
+			if tt.state != nil {
+				c.groupStates[key] = tt.state
+			}
+
+			if got := c.allowGroupSend(tt.faultCenter, tt.stateId); got != tt.want {
+				t.Fatalf("allowGroupSend() = %v, want %v", got, tt.want)
+			}
+			if state := c.groupStates[key]; tt.want && state != nil && state.lastSentAt < now {
+				t.Errorf("lastSentAt = %d, want updated after sending", state.lastSentAt)
+			}
+		})
+	}
+}
+
+func TestPruneGroupStates(t *testing.T) {
+	faultCenter := models.FaultCenter{ID: "fc", AggregationType: models.AggregationTypeLabels, GroupBy: []string{"service"}}
+	c := &Consume{groupStates: map[string]*groupState{
+		"fc:Firing_service=api":    {},
+		"fc:Firing_service=web":    {},
+		"other:Firing_service=web": {},
+	}}
+
+	c.pruneGroupStates(faultCenter, map[string]*models.AlertCurEvent{
+		"1": {Metric: map[string]interface{}{"service": "api"}},
+		"2": {Metric: map[string]interface{}{"service": "web"}, IsRecovered: true},
+	})
+
+	if _, ok := c.groupStates["fc:Firing_service=api"]; !ok {
+		t.Error("active group state should be kept")
+	}
+	if _, ok := c.groupStates["fc:Firing_service=web"]; ok {
+		t.Error("group state without firing events should be pruned")
+	}
+	if _, ok := c.groupStates["other:Firing_service=web"]; !ok {
+		t.Error("group states of other fault centers should be kept")
+	}
+}
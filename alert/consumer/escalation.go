@@ -0,0 +1,73 @@
+package consumer
+
+import (
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"time"
+	"watchAlert/alert/process"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/ctx"
+)
+
+// alarmEscalate 处理规则升级策略, 告警未认领时按超时时间逐级通知, 认领或恢复后停止
+func alarmEscalate(ctx *ctx.Context, faultCenter models.FaultCenter, alerts map[string]*models.AlertCurEvent) {
+	currentTime := time.Now().Unix()
+
+	for _, event := range alerts {
+		if len(event.EscalationPolicy) == 0 || event.UpgradeState.IsConfirm {
+			continue
+		}
+
+		// 过滤掉 预告警, 待恢复 状态及已恢复、已静默的事件
+		if event.Status == models.StatePreAlert || event.Status == models.StatePendingRecovery || event.IsRecovered {
+			continue
+		}
+		if isMutedEvent(event, faultCenter) {
+			continue
+		}
+
+		level := getEscalationLevel(event, currentTime)
+		if level <= event.UpgradeState.EscalationLevel {
+			continue
+		}
+
+		// 同一周期内超过多个级别的超时时间时, 只通知最高级别
+		escalation := event.EscalationPolicy[level-1]
+		notifyEvent := *event
+		notifyEvent.Annotations += fmt.Sprintf("\n告警已 %d 分钟未认领, 升级至第 %d 级通知", escalation.Timeout, level)
+		if err := process.HandleAlert(ctx, faultCenter, escalation.NoticeId, []*models.AlertCurEvent{&notifyEvent}); err != nil {
+			logc.Error(ctx.Ctx, fmt.Sprintf("send escalation notice fail, fingerprint: %s, level: %d, err: %s", event.Fingerprint, level, err.Error()))
+			continue
+		}
+
+		setEscalationLevel(ctx, *event, level, currentTime)
+	}
+}
+
+// getEscalationLevel 获取当前应升级到的级别
+func getEscalationLevel(event *models.AlertCurEvent, currentTime int64) int {
+	var level int
+	for i, escalation := range event.EscalationPolicy {
+		if currentTime < event.FirstTriggerTime+escalation.Timeout*60 {
+			break
+		}
+		level = i + 1
+	}
+	return level
+}
+
+// setEscalationLevel 更新缓存中事件的升级级别
+func setEscalationLevel(ctx *ctx.Context, alertEvent models.AlertCurEvent, level int, curT int64) {
+	cache := ctx.Redis.Alert()
+	event, err := cache.GetEventFromCache(alertEvent.TenantId, alertEvent.FaultCenterId, alertEvent.Fingerprint)
+	if err != nil {
+		logc.Error(ctx.Ctx, fmt.Sprintf("get event info fail, err: %s", err.Error()))
+		return
+	}
+
+	// 发送时缓存中的事件已被写入升级说明, 还原为原始内容
+	event.Annotations = alertEvent.Annotations
+	event.UpgradeState.EscalationLevel = level
+	event.UpgradeState.EscalationSendTime = curT
+	cache.PushAlertEvent(&event)
+}
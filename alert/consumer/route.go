@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"fmt"
+	"watchAlert/alert/mute"
+	"watchAlert/internal/models"
+)
+
+// matchRouteTree 按告警路由树获取告警的通知对象, 根节点未配置通知对象时使用故障中心的通知对象
+func matchRouteTree(metric map[string]interface{}, faultCenter models.FaultCenter) []string {
+	root := faultCenter.RouteTree
+	if len(root.NoticeIds) == 0 {
+		root.NoticeIds = faultCenter.NoticeIds
+	}
+
+	var noticeIds []string
+	seen := make(map[string]struct{})
+	for _, id := range matchRouteNode(metric, root) {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		noticeIds = append(noticeIds, id)
+	}
+	return noticeIds
+}
+
+// matchRouteNode 在已命中的路由下匹配子路由, 无子路由命中时使用当前路由的通知对象
+func matchRouteNode(metric map[string]interface{}, node models.NoticeRouteNode) []string {
+	var noticeIds []string
+	var matched bool
+	for _, child := range node.Routes {
+		if !mute.MatchLabels(metric, child.Matchers) {
+			continue
+		}
+		if len(child.NoticeIds) == 0 {
+			child.NoticeIds = node.NoticeIds
+		}
+
+		matched = true
+		noticeIds = append(noticeIds, matchRouteNode(metric, child)...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if !matched {
+		return node.NoticeIds
+	}
+	return noticeIds
+}
+
+// ValidateRouteTree 校验告警路由树的匹配条件
+func ValidateRouteTree(node models.NoticeRouteNode) error {
+	for _, child := range node.Routes {
+		if len(child.Matchers) > 0 {
+			if err := mute.ValidateSilenceLabels(child.Matchers); err != nil {
+				return fmt.Errorf("告警路由 %s 的匹配条件错误: %s", child.Name, err.Error())
+			}
+		}
+		if err := ValidateRouteTree(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestMatchRouteTree(t *testing.T) {
+	faultCenter := models.FaultCenter{
+		NoticeIds: []string{"default"},
+		RouteTree: models.NoticeRouteNode{
+			Routes: []models.NoticeRouteNode{
+				{
+					Name:      "db",
+					Matchers:  []models.SilenceLabel{{Key: "team", Operator: "==", Value: "db"}},
+					NoticeIds: []string{"db"},
+					Routes: []models.NoticeRouteNode{
+						{Name: "db-p0", Matchers: []models.SilenceLabel{{Key: "severity", Operator: "==", Value: "P0"}}, NoticeIds: []string{"db-oncall"}},
+						// 未配置通知对象时继承上级路由
+						{Name: "db-mysql", Matchers: []models.SilenceLabel{{Key: "service", Operator: "=~", Value: "mysql.*"}}},
+					},
+				},
+				{
+					Name:      "web",
+					Matchers:  []models.SilenceLabel{{Key: "team", Operator: "==", Value: "web"}},
+					NoticeIds: []string{"web", "default"},
+					Continue:  true,
+				},
+				{Name: "audit", Matchers: []models.SilenceLabel{{Key: "env", Operator: "==", Value: "prod"}}, NoticeIds: []string{"audit"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		metric map[string]interface{}
+		want   []string
+	}{
+		{name: "no route matched uses fault center notices", metric: map[string]interface{}{"team": "infra"}, want: []string{"default"}},
+		{name: "deepest matched route", metric: map[string]interface{}{"team": "db", "severity": "P0"}, want: []string{"db-oncall"}},
+		{name: "child inherits parent notices", metric: map[string]interface{}{"team": "db", "service": "mysql-primary"}, want: []string{"db"}},
+		{name: "no child matched uses parent notices", metric: map[string]interface{}{"team": "db", "severity": "P2"}, want: []string{"db"}},
+		{name: "first match stops without continue", metric: map[string]interface{}{"team": "db", "env": "prod"}, want: []string{"db"}},
+		{name: "continue matches following siblings and dedups", metric: map[string]interface{}{"team": "web", "env": "prod"}, want: []string{"web", "default", "audit"}},
+		{name: "continue without following match", metric: map[string]interface{}{"team": "web"}, want: []string{"web", "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRouteTree(tt.metric, faultCenter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchRouteTree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 根节点配置的通知对象优先于故障中心的通知对象
+	withRoot := faultCenter
+	withRoot.RouteTree.NoticeIds = []string{"root"}
+	if got := matchRouteTree(map[string]interface{}{"team": "infra"}, withRoot); !reflect.DeepEqual(got, []string{"root"}) {
+		t.Errorf("matchRouteTree() with root notices = %v", got)
+	}
+}
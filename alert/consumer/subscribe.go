@@ -3,7 +3,11 @@ package consumer
 import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
+	"slices"
 	"strings"
+	"sync"
+	"watchAlert/alert/mute"
+	"watchAlert/alert/process"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/sender"
@@ -15,6 +19,8 @@ type toUser struct {
 	Email            string
 	NoticeSubject    string
 	NoticeTemplateId string
+	// 个人通知对象, 为空时发送邮件
+	NoticeId string
 }
 
 // 向已订阅的用户中发送告警消息
@@ -28,12 +34,13 @@ func processSubscribe(ctx *ctx.Context, alert *models.AlertCurEvent) error {
 	}
 
 	for _, subscribe := range subscribes {
-		// Severity检查
-		severitySet := make(map[string]struct{})
-		for _, sev := range subscribe.SRuleSeverity {
-			severitySet[sev] = struct{}{}
+		// Severity检查, 未指定等级时订阅所有等级
+		if len(subscribe.SRuleSeverity) > 0 && !slices.Contains(subscribe.SRuleSeverity, alert.Severity) {
+			continue
 		}
-		if _, exists := severitySet[alert.Severity]; !exists {
+
+		// 标签匹配检查
+		if len(subscribe.SLabelMatchers) > 0 && !mute.MatchLabels(alert.Metric, subscribe.SLabelMatchers) {
 			continue
 		}
 
@@ -55,6 +62,7 @@ func processSubscribe(ctx *ctx.Context, alert *models.AlertCurEvent) error {
 			Email:            subscribe.SUserEmail,
 			NoticeSubject:    subscribe.SNoticeSubject,
 			NoticeTemplateId: subscribe.SNoticeTemplateId,
+			NoticeId:         subscribe.SNoticeId,
 		})
 	}
 
@@ -62,10 +70,7 @@ func processSubscribe(ctx *ctx.Context, alert *models.AlertCurEvent) error {
 }
 
 func getSubscribes(alert *models.AlertCurEvent) ([]models.AlertSubscribe, error) {
-	list, err := ctx.DB.Subscribe().List(models.AlertSubscribeQuery{
-		STenantId: alert.TenantId,
-		SRuleId:   alert.RuleId,
-	})
+	list, err := ctx.DB.Subscribe().ListMatched(alert.TenantId, alert.RuleId)
 	if err != nil {
 		return nil, fmt.Errorf("获取订阅用户失败, err: %s", err.Error())
 	}
@@ -78,16 +83,28 @@ func sendToSubscribeUser(ctx *ctx.Context, alert models.AlertCurEvent, toUsers [
 		return nil
 	}
 
-	var sem = make(chan struct{}, 10)
+	var (
+		sem = make(chan struct{}, 10)
+		// 等待所有邮件发送完成, 服务退出时不丢失订阅通知
+		wg sync.WaitGroup
+	)
 	for _, user := range toUsers {
 		u := user
 		// 插入信号量，超过 10 则阻塞协程启动
 		sem <- struct{}{}
+		wg.Add(1)
 		go func(u toUser, sem chan struct{}) {
 			defer func() {
 				// 释放信号量
 				<-sem
+				wg.Done()
 			}()
+			if u.NoticeId != "" {
+				if err := process.SendToNotice(ctx, u.NoticeId, alert); err != nil {
+					logc.Errorf(ctx.Ctx, fmt.Sprintf("Email: %s, 订阅通知发送失败, err: %s", u.Email, err.Error()))
+				}
+				return
+			}
 			emailTemp := templates.NewTemplate(ctx, alert, models.AlertNotice{NoticeType: "Email", NoticeTmplId: u.NoticeTemplateId})
 			err := sender.NewEmailSender().Send(sender.SendParams{
 				IsRecovered: alert.IsRecovered,
@@ -103,6 +120,7 @@ func sendToSubscribeUser(ctx *ctx.Context, alert models.AlertCurEvent, toUsers [
 			}
 		}(u, sem)
 	}
+	wg.Wait()
 
 	return nil
 }
@@ -155,6 +155,10 @@ func filterAlertEvents(ctx context.Context, faultCenter models.FaultCenter, aler
 			continue
 		}
 
+		if len(mute.InhibitedBy(faultCenter.InhibitRules, event, alerts)) > 0 {
+			continue
+		}
+
 		newEvents = append(newEvents, event)
 	}
 
@@ -168,7 +172,7 @@ func isMutedEvent(event *models.AlertCurEvent, faultCenter models.FaultCenter) b
 		TenantId:      event.TenantId,
 		Metrics:       event.Metric,
 		FaultCenterId: event.FaultCenterId,
-		RecoverNotify: faultCenter.RecoverNotify,
+		RecoverNotify: event.IsRecoverNotify(faultCenter),
 	})
 }
 
@@ -0,0 +1,165 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/logc"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// enrichCacheLimit 查询结果缓存的最大条目数
+const enrichCacheLimit = 10000
+
+var (
+	enrichCache    *collection.Cache
+	enrichCacheTTL time.Duration
+	enrichCacheMux sync.Mutex
+)
+
+// getCache 获取查询结果缓存, 缓存时长变更后重建
+func getCache(ttl time.Duration) *collection.Cache {
+	enrichCacheMux.Lock()
+	defer enrichCacheMux.Unlock()
+
+	if enrichCache != nil && enrichCacheTTL == ttl {
+		return enrichCache
+	}
+
+	c, err := collection.NewCache(ttl, collection.WithLimit(enrichCacheLimit), collection.WithName("label-enrich"))
+	if err != nil {
+		return nil
+	}
+	enrichCache, enrichCacheTTL = c, ttl
+	return c
+}
+
+// Labels 根据配置查询 CMDB 并返回合并后的标签, 未开启、未命中或查询失败时返回原标签, 不影响告警通知
+func Labels(ctx context.Context, cfg models.EnrichConfig, metric map[string]interface{}) map[string]interface{} {
+	if !cfg.GetEnable() {
+		return metric
+	}
+
+	key, value := lookupLabel(cfg.LabelKeys, metric)
+	if value == "" {
+		return metric
+	}
+
+	attrs, err := lookup(cfg, value)
+	if err != nil {
+		logc.Error(ctx, fmt.Sprintf("告警标签丰富失败, %s: %s, err: %s", key, value, err.Error()))
+		return metric
+	}
+	if len(attrs) == 0 {
+		return metric
+	}
+
+	merged := make(map[string]interface{}, len(metric)+len(attrs))
+	for k, v := range metric {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		if _, exists := merged[k]; exists && !cfg.Override {
+			continue
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// lookupLabel 按顺序返回第一个存在且不为空的标签
+func lookupLabel(keys []string, metric map[string]interface{}) (string, string) {
+	for _, key := range keys {
+		if v, ok := metric[key]; ok {
+			if value := fmt.Sprintf("%v", v); value != "" {
+				return key, value
+			}
+		}
+	}
+	return "", ""
+}
+
+// lookup 查询标签值对应的属性, HTTP 查询结果按缓存时长缓存, 查询失败时不缓存
+func lookup(cfg models.EnrichConfig, value string) (map[string]string, error) {
+	switch cfg.Type {
+	case models.EnrichTypeMapping:
+		return filterFields(cfg.Fields, cfg.Mapping[value]), nil
+	case models.EnrichTypeHTTP:
+		cache := getCache(time.Duration(cfg.GetCacheTTL()) * time.Second)
+		if cache == nil {
+			return lookupHTTP(cfg, value)
+		}
+
+		// 查询地址及请求头变更后不使用旧的缓存
+		key := tools.Md5Hash([]byte(cfg.Url + tools.JsonMarshal(cfg.Headers) + tools.JsonMarshal(cfg.Fields) + value))
+		res, err := cache.Take(key, func() (any, error) {
+			return lookupHTTP(cfg, value)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.(map[string]string), nil
+	default:
+		return nil, fmt.Errorf("不支持的查询方式: %s", cfg.Type)
+	}
+}
+
+// lookupHTTP 请求 CMDB 接口, 返回 JSON 对象中字符串 / 数值 / 布尔类型的属性, 404 视为未命中
+func lookupHTTP(cfg models.EnrichConfig, value string) (map[string]string, error) {
+	if cfg.Url == "" {
+		return nil, fmt.Errorf("查询地址为空")
+	}
+
+	res, err := tools.Get(cfg.Headers, strings.ReplaceAll(cfg.Url, "${value}", url.QueryEscape(value)), cfg.GetTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("status code: %d, body: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析查询结果失败: %s", err.Error())
+	}
+
+	attrs := make(map[string]string, len(data))
+	for k, v := range data {
+		switch v.(type) {
+		case string, float64, bool:
+			attrs[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	return filterFields(cfg.Fields, attrs), nil
+}
+
+// filterFields 仅保留需要合并的属性, fields 为空时全部保留
+func filterFields(fields []string, attrs map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return attrs
+	}
+
+	filtered := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if v, ok := attrs[field]; ok {
+			filtered[field] = v
+		}
+	}
+	return filtered
+}
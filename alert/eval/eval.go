@@ -5,14 +5,21 @@ import (
 	"fmt"
 	"github.com/go-redis/redis"
 	"github.com/zeromicro/go-zero/core/logc"
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel/trace"
+	"math/rand"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 	"watchAlert/alert/process"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/logger"
+	selfMetrics "watchAlert/pkg/metrics"
 	"watchAlert/pkg/provider"
 	"watchAlert/pkg/tools"
+	"watchAlert/pkg/tracing"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -25,15 +32,24 @@ type (
 		Eval(ctx context.Context, rule models.AlertRule)
 		Recover(tenantId, ruleId string, eventCacheKey models.AlertEventCacheKey, faultCenterInfoKey models.FaultCenterInfoCacheKey, curFingerprints []string)
 		RestartAllEvals()
+		Shutdown(ctx context.Context) error
 	}
 
 	// AlertRule 告警规则
 	AlertRule struct {
 		ctx         *ctx.Context
 		watchCtxMap map[string]context.CancelFunc
+
+		// 进行中的评估, 服务退出时等待其完成
+		inflight    sync.WaitGroup
+		stopping    bool
+		shutdownMux sync.Mutex
 	}
 )
 
+// minEvalInterval 最小评估间隔, 避免评估间隔配置过小时持续查询数据源
+const minEvalInterval = 100 * time.Millisecond
+
 func NewAlertRuleEval(ctx *ctx.Context) AlertRuleEval {
 	return &AlertRule{
 		ctx:         ctx,
@@ -42,9 +58,21 @@ func NewAlertRuleEval(ctx *ctx.Context) AlertRuleEval {
 }
 
 func (t *AlertRule) Submit(rule models.AlertRule) {
+	t.shutdownMux.Lock()
+	stopping := t.stopping
+	t.shutdownMux.Unlock()
+	if stopping {
+		return
+	}
+
 	t.ctx.Mux.Lock()
 	defer t.ctx.Mux.Unlock()
 
+	// 同一规则仅保留一个评估协程
+	if cancel, exists := t.watchCtxMap[rule.RuleId]; exists {
+		cancel()
+	}
+
 	c, cancel := context.WithCancel(context.Background())
 	t.watchCtxMap[rule.RuleId] = cancel
 	go t.Eval(c, rule)
@@ -61,7 +89,9 @@ func (t *AlertRule) Stop(ruleId string) {
 }
 
 func (t *AlertRule) Eval(ctx context.Context, rule models.AlertRule) {
-	timer := time.NewTicker(t.getEvalTimeDuration(rule.EvalTimeType, rule.EvalInterval))
+	interval := t.getEvalTimeDuration(rule.EvalTimeType, rule.EvalInterval)
+	// 首次评估前随机延迟, 避免规则同时启动时集中查询数据源
+	timer := time.NewTimer(interval + t.getEvalJitter(rule, interval))
 	defer func() {
 		timer.Stop()
 		if r := recover(); r != nil {
@@ -71,6 +101,9 @@ func (t *AlertRule) Eval(ctx context.Context, rule models.AlertRule) {
 		}
 	}()
 
+	// 已切换至备用数据源的主数据源, 仅由当前协程读写
+	failedOver := make(map[string]bool)
+
 	for {
 		select {
 		case <-timer.C:
@@ -79,58 +112,148 @@ func (t *AlertRule) Eval(ctx context.Context, rule models.AlertRule) {
 				return
 			}
 
-			var curFingerprints []string
-			for _, dsId := range rule.DatasourceIdList {
-				instance, err := t.ctx.DB.Datasource().GetInstance(dsId)
-				if err != nil {
-					logc.Error(t.ctx.Ctx, err.Error())
-					continue
-				}
-
-				ok, _ := provider.CheckDatasourceHealth(instance)
-				if !ok {
-					continue
-				}
-
-				var fingerprints []string
-
-				switch rule.DatasourceType {
-				case "Prometheus", "VictoriaMetrics":
-					fingerprints = metrics(t.ctx, dsId, instance.Type, rule)
-				case "AliCloudSLS", "Loki", "ElasticSearch", "VictoriaLogs":
-					fingerprints = logs(t.ctx, dsId, instance.Type, rule)
-				case "Jaeger":
-					fingerprints = traces(t.ctx, dsId, instance.Type, rule)
-				case "CloudWatch":
-					fingerprints = cloudWatch(t.ctx, dsId, rule)
-				case "KubernetesEvent":
-					fingerprints = kubernetesEvent(t.ctx, dsId, rule)
-				default:
-					continue
-				}
-				// 追加当前数据源的指纹到总列表
-				curFingerprints = append(curFingerprints, fingerprints...)
+			// 服务退出中不再开始新的评估
+			if !t.beginEval() {
+				return
 			}
-			logc.Infof(t.ctx.Ctx, fmt.Sprintf("规则评估 -> %v", tools.JsonMarshal(rule)))
-			t.Recover(rule.TenantId, rule.RuleId, models.BuildAlertEventCacheKey(rule.TenantId, rule.FaultCenterId), models.BuildFaultCenterInfoCacheKey(rule.TenantId, rule.FaultCenterId), curFingerprints)
-			t.GC(t.ctx, rule, curFingerprints)
+
+			evalStartAt := time.Now()
+			t.evaluate(rule, evalStartAt, failedOver)
+			timer.Reset(t.nextEvalDelay(rule, evalStartAt, interval))
 
 		case <-ctx.Done():
 			logc.Infof(t.ctx.Ctx, fmt.Sprintf("停止 RuleId: %v, RuleName: %s 的 Watch 协程", rule.RuleId, rule.RuleName))
 			return
 		}
-		timer.Reset(t.getEvalTimeDuration(rule.EvalTimeType, rule.EvalInterval))
 	}
 }
 
-// getEvalTimeDuration 获取评估时间
+// evaluate 执行一次规则评估, 需先通过 beginEval 登记
+func (t *AlertRule) evaluate(rule models.AlertRule, evalStartAt time.Time, failedOver map[string]bool) {
+	defer t.inflight.Done()
+
+	// 每次评估生成独立的 traceId, 贯穿数据源查询与告警通知
+	evalCtx, span := newEvalContext(t.ctx, rule)
+	evalCtx = evalCtx.WithContext(provider.WithQueryStatsRecorder(evalCtx.Ctx))
+	var (
+		curFingerprints []string
+		// 查询失败 (含并发额度不足 ErrBusy、熔断 ErrCircuitOpen) 的数据源, 未返回数据不代表告警已恢复
+		failedQueries []string
+	)
+	for _, dsId := range rule.DatasourceIdList {
+		// 配置备用数据源时, 主数据源不可用则切换至备用数据源
+		fingerprints, err := t.evalWithFailover(evalCtx, rule, dsId, failedOver)
+		if err != nil {
+			failedQueries = append(failedQueries, fmt.Sprintf("datasourceId: %s, err: %s", dsId, err.Error()))
+		}
+		// 追加当前数据源的指纹到总列表
+		curFingerprints = append(curFingerprints, fingerprints...)
+	}
+	tracing.EndWithCount(span, len(curFingerprints), nil)
+	selfMetrics.RuleEvalDuration.WithLabelValues(rule.DatasourceType).Observe(time.Since(evalStartAt).Seconds())
+	selfMetrics.RuleEvalTotal.WithLabelValues(rule.DatasourceType).Inc()
+	logc.Infof(evalCtx.Ctx, fmt.Sprintf("规则评估 -> %v", tools.JsonMarshal(rule)))
+	// 存在查询失败的数据源时本轮评估视为无数据, 不处理告警恢复及待恢复缓存的清理, 避免发送错误的恢复通知
+	if len(failedQueries) > 0 {
+		logc.Errorf(evalCtx.Ctx, fmt.Sprintf("规则 %s 存在查询失败的数据源, 本轮评估不处理告警恢复, %s", rule.RuleName, strings.Join(failedQueries, "; ")))
+		return
+	}
+	// 查询结果不完整时未命中的告警不能视为已恢复
+	if incomplete := provider.IncompleteQueries(evalCtx.Ctx); len(incomplete) > 0 {
+		logc.Errorf(evalCtx.Ctx, fmt.Sprintf("规则 %s 的查询结果不完整, 本轮评估不处理告警恢复, %s", rule.RuleName, strings.Join(incomplete, "; ")))
+	} else {
+		t.Recover(rule.TenantId, rule.RuleId, models.BuildAlertEventCacheKey(rule.TenantId, rule.FaultCenterId), models.BuildFaultCenterInfoCacheKey(rule.TenantId, rule.FaultCenterId), curFingerprints)
+	}
+	t.GC(t.ctx, rule, curFingerprints)
+}
+
+// beginEval 登记进行中的评估, 服务退出中时返回 false
+func (t *AlertRule) beginEval() bool {
+	t.shutdownMux.Lock()
+	defer t.shutdownMux.Unlock()
+
+	if t.stopping {
+		return false
+	}
+	t.inflight.Add(1)
+	return true
+}
+
+// Shutdown 停止接收新的评估并停止所有评估协程, 等待进行中的评估完成, 超时返回 ctx 的错误
+func (t *AlertRule) Shutdown(ctx context.Context) error {
+	t.shutdownMux.Lock()
+	t.stopping = true
+	t.shutdownMux.Unlock()
+
+	t.ctx.Mux.Lock()
+	for ruleId, cancel := range t.watchCtxMap {
+		cancel()
+		delete(t.watchCtxMap, ruleId)
+	}
+	t.ctx.Mux.Unlock()
+
+	return tools.WaitWithContext(ctx, &t.inflight)
+}
+
+// nextEvalDelay 按评估开始时间计算下次评估的等待时间, 评估耗时超过评估间隔时跳过错过的评估, 不连续补评估
+func (t *AlertRule) nextEvalDelay(rule models.AlertRule, evalStartAt time.Time, interval time.Duration) time.Duration {
+	elapsed := time.Since(evalStartAt)
+	if elapsed < interval {
+		return interval - elapsed
+	}
+
+	skipped := int64(elapsed / interval)
+	selfMetrics.RuleEvalSkippedTotal.WithLabelValues(rule.DatasourceType).Add(float64(skipped))
+	logc.Errorf(t.ctx.Ctx, fmt.Sprintf("规则评估耗时 %s 超过评估间隔 %s, 跳过 %d 次评估, RuleName: %s, RuleId: %s", elapsed, interval, skipped, rule.RuleName, rule.RuleId))
+	return interval - elapsed%interval
+}
+
+// getEvalJitter 获取首次评估的随机延迟, 不超过评估间隔
+func (t *AlertRule) getEvalJitter(rule models.AlertRule, interval time.Duration) time.Duration {
+	if rule.EvalJitter <= 0 {
+		return 0
+	}
+
+	jitter := t.getEvalTimeDuration(rule.EvalTimeType, rule.EvalJitter)
+	if jitter > interval {
+		jitter = interval
+	}
+
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// newEvalContext 派生本次评估的上下文并创建评估 Span, 日志携带 traceId / ruleId / ruleName,
+// 启用链路追踪时 traceId 与 Span 的 TraceId 保持一致
+func newEvalContext(c *ctx.Context, rule models.AlertRule) (*ctx.Context, trace.Span) {
+	evalCtx, span := tracing.Start(c.Ctx, "rule.eval",
+		tracing.AttrRuleId.String(rule.RuleId),
+		tracing.AttrRuleName.String(rule.RuleName),
+		tracing.AttrDatasourceType.String(rule.DatasourceType),
+	)
+
+	traceId := tracing.TraceId(evalCtx)
+	if traceId == "" {
+		traceId = logger.NewTraceId()
+	}
+	evalCtx = logger.WithTraceId(evalCtx, traceId)
+	evalCtx = logx.ContextWithFields(evalCtx, logx.Field("ruleId", rule.RuleId), logx.Field("ruleName", rule.RuleName))
+	return c.WithContext(evalCtx), span
+}
+
+// getEvalTimeDuration 获取评估时间, 不低于最小评估间隔
 func (t *AlertRule) getEvalTimeDuration(evalTimeType string, evalInterval int64) time.Duration {
+	var d time.Duration
 	switch evalTimeType {
 	case "millisecond":
-		return time.Millisecond * time.Duration(evalInterval)
+		d = time.Millisecond * time.Duration(evalInterval)
 	default:
-		return time.Second * time.Duration(evalInterval)
+		d = time.Second * time.Duration(evalInterval)
 	}
+
+	if d < minEvalInterval {
+		return minEvalInterval
+	}
+	return d
 }
 
 func (t *AlertRule) Recover(tenantId, ruleId string, eventCacheKey models.AlertEventCacheKey, faultCenterInfoKey models.FaultCenterInfoCacheKey, curFingerprints []string) {
@@ -162,7 +285,9 @@ func (t *AlertRule) Recover(tenantId, ruleId string, eventCacheKey models.AlertE
 			if !ok {
 				continue
 			}
+			from := event.Status
 			event.TransitionStatus(models.StateAlerting)
+			process.RecordTransition(t.ctx, *event, string(from), string(event.Status), models.TransitionActorSystem)
 			t.ctx.Redis.Alert().PushAlertEvent(event)
 			t.ctx.Redis.PendingRecover().Delete(tenantId, ruleId, fingerprint)
 		}
@@ -179,6 +304,13 @@ func (t *AlertRule) Recover(tenantId, ruleId string, eventCacheKey models.AlertE
 			continue
 		}
 
+		// 预告警期间条件不再满足, 重置持续时间, 无需进入恢复流程
+		if event.Status == models.StatePreAlert {
+			t.ctx.Redis.Alert().RemoveAlertEvent(tenantId, event.FaultCenterId, fingerprint)
+			t.ctx.Redis.PendingRecover().Delete(tenantId, ruleId, fingerprint)
+			continue
+		}
+
 		// 判断是否在等待时间范围内
 		wTime, err := t.ctx.Redis.PendingRecover().Get(tenantId, ruleId, fingerprint)
 		if err != nil && err == redis.Nil {
@@ -187,6 +319,7 @@ func (t *AlertRule) Recover(tenantId, ruleId string, eventCacheKey models.AlertE
 			continue
 		}
 
+		from := event.Status
 		rt := time.Unix(wTime, 0).Add(time.Minute * time.Duration(t.getRecoverWaitTime(faultCenterInfoKey))).Unix()
 		if rt > curTime {
 			// 调整为待恢复状态
@@ -197,6 +330,7 @@ func (t *AlertRule) Recover(tenantId, ruleId string, eventCacheKey models.AlertE
 			t.ctx.Redis.PendingRecover().Delete(tenantId, ruleId, fingerprint)
 		}
 
+		process.RecordTransition(t.ctx, *event, string(from), string(event.Status), models.TransitionActorSystem)
 		t.ctx.Redis.Alert().PushAlertEvent(event)
 	}
 }
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/provider"
+)
+
+// evalWithFailover 评估主数据源, 主数据源健康检查或查询失败时使用备用数据源评估, 主数据源恢复后切回;
+// failedOver 记录已切换至备用数据源的主数据源, 仅在切换时记录日志; 返回的错误为最终评估的数据源的错误
+func (t *AlertRule) evalWithFailover(evalCtx *ctx.Context, rule models.AlertRule, dsId string, failedOver map[string]bool) ([]string, error) {
+	fingerprints, err := t.evalDatasource(evalCtx, rule, dsId)
+
+	secondaryId := rule.FailoverDatasources[dsId]
+	if secondaryId == "" {
+		return fingerprints, err
+	}
+
+	if err == nil {
+		if failedOver[dsId] {
+			delete(failedOver, dsId)
+			logc.Infof(evalCtx.Ctx, fmt.Sprintf("主数据源已恢复, 切回主数据源, primary: %s, secondary: %s", dsId, secondaryId))
+		}
+		return fingerprints, nil
+	}
+
+	// 查询语句错误在备用数据源同样失败, 无需切换
+	if errors.Is(err, provider.ErrBadQuery) {
+		return fingerprints, err
+	}
+
+	if !failedOver[dsId] {
+		failedOver[dsId] = true
+		logc.Errorf(evalCtx.Ctx, fmt.Sprintf("主数据源不可用, 切换至备用数据源, primary: %s, secondary: %s, err: %s", dsId, secondaryId, err.Error()))
+	}
+
+	fingerprints, err = t.evalDatasource(evalCtx, rule, secondaryId)
+	if err != nil {
+		logc.Errorf(evalCtx.Ctx, fmt.Sprintf("备用数据源同样不可用, primary: %s, secondary: %s, err: %s", dsId, secondaryId, err.Error()))
+	}
+
+	return fingerprints, err
+}
+
+// evalDatasource 评估单个数据源, 返回告警指纹; 数据源不存在、健康检查失败或查询失败时返回错误
+func (t *AlertRule) evalDatasource(evalCtx *ctx.Context, rule models.AlertRule, dsId string) ([]string, error) {
+	instance, err := t.ctx.DB.Datasource().GetInstance(dsId)
+	if err != nil {
+		logc.Error(evalCtx.Ctx, err.Error())
+		return nil, err
+	}
+
+	ok, err := provider.CheckDatasourceHealth(evalCtx.Ctx, instance)
+	if !ok {
+		return nil, fmt.Errorf("数据源健康检查失败, datasourceId: %s, err: %v", dsId, err)
+	}
+
+	// 查询函数不返回错误, 通过上下文记录查询是否失败
+	dsCtx := evalCtx.WithContext(provider.WithQueryErrorRecorder(evalCtx.Ctx))
+
+	cli, err := t.ctx.Redis.ProviderPools().GetClient(dsId)
+	if err != nil {
+		logc.Error(evalCtx.Ctx, err.Error())
+		return nil, err
+	}
+
+	// 按客户端实现的接口选择评估方式, 新注册的数据源类型无需修改此处
+	var fingerprints []string
+	switch cli.(type) {
+	case provider.MetricsFactoryProvider:
+		fingerprints = metrics(dsCtx, dsId, instance.Type, rule)
+	case provider.LogsFactoryProvider:
+		fingerprints = logs(dsCtx, dsId, instance.Type, rule)
+	case provider.TracesFactoryProvider:
+		fingerprints = traces(dsCtx, dsId, instance.Type, rule)
+	case provider.AwsConfig:
+		fingerprints = cloudWatch(dsCtx, dsId, rule)
+	case provider.KubernetesClient:
+		fingerprints = kubernetesEvent(dsCtx, dsId, rule)
+	default:
+		return nil, fmt.Errorf("数据源类型 %s 的客户端不支持规则评估, datasourceId: %s", instance.Type, dsId)
+	}
+
+	return fingerprints, provider.QueryError(dsCtx.Ctx)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/zeromicro/go-zero/core/logc"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 	"watchAlert/alert/process"
 	"watchAlert/internal/models"
@@ -13,6 +14,7 @@ import (
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/provider"
 	"watchAlert/pkg/tools"
+	"watchAlert/pkg/tracing"
 )
 
 // Metrics 包含 Prometheus、VictoriaMetrics 数据源
@@ -26,40 +28,31 @@ func metrics(ctx *ctx.Context, datasourceId, datasourceType string, rule models.
 		// 按指纹分组存储事件，每个指纹只保留最高优先级的事件
 		highestPriorityEvents = make(map[string]models.AlertCurEvent)
 	)
-	switch datasourceType {
-	case provider.PrometheusDsProvider:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return nil
-		}
-
-		resQuery, err = cli.(provider.PrometheusProvider).Query(rule.PrometheusConfig.PromQL)
-		if err != nil {
-			logc.Error(ctx.Ctx, err.Error())
-			return nil
-		}
-
-		externalLabels = cli.(provider.PrometheusProvider).GetExternalLabels()
-	case provider.VictoriaMetricsDsProvider:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return nil
-		}
-
-		resQuery, err = cli.(provider.VictoriaMetricsProvider).Query(rule.PrometheusConfig.PromQL)
-		if err != nil {
-			logc.Error(ctx.Ctx, err.Error())
-			return nil
-		}
-
-		externalLabels = cli.(provider.VictoriaMetricsProvider).GetExternalLabels()
-	default:
+	cli, err := pools.GetClient(datasourceId)
+	if err != nil {
+		logc.Errorf(ctx.Ctx, err.Error())
+		return nil
+	}
+	// Prometheus、VictoriaMetrics 等指标数据源均实现 MetricsFactoryProvider
+	metricsCli, ok := cli.(provider.MetricsFactoryProvider)
+	if !ok {
 		logc.Errorf(ctx.Ctx, fmt.Sprintf("Unsupported metrics type, type: %s", datasourceType))
 		return nil
 	}
 
+	spanCtx, span := tracing.StartQuery(ctx.Ctx, datasourceId, datasourceType, rule.PrometheusConfig.PromQL, "")
+	err = provider.RetryQuery(spanCtx, datasourceId, func() error {
+		var err error
+		resQuery, err = metricsCli.Query(rule.PrometheusConfig.PromQL)
+		return err
+	})
+	tracing.EndWithCount(span, len(resQuery), err)
+	if err != nil {
+		logc.Error(ctx.Ctx, err.Error())
+		return nil
+	}
+	externalLabels = metricsCli.GetExternalLabels()
+
 	if resQuery == nil {
 		return nil
 	}
@@ -69,28 +62,48 @@ func metrics(ctx *ctx.Context, datasourceId, datasourceType string, rule models.
 
 	// 按优先级排序规则（P0 > P1 > P2）
 	rules := sortRulesByPriority(rule.PrometheusConfig.Rules)
+	isAnomaly := rule.PrometheusConfig.IsAnomalyMode()
+	if isAnomaly {
+		// 异常检测模式不使用静态阈值, 仅按配置的告警等级评估一次
+		rules = []models.Rules{{Severity: getAnomalySeverity(rule)}}
+	}
 
 	for _, v := range resQuery {
 		fingerprint := v.GetFingerprint()
 
+		var anomaly process.AnomalyResult
+		if isAnomaly {
+			anomaly = evalAnomaly(ctx, rule, fingerprint, v.Value)
+		}
+
 		// 遍历按优先级排序后的规则
 		for _, ruleExpr := range rules {
-			operator, value, err := tools.ProcessRuleExpr(ruleExpr.Expr)
-			if err != nil {
-				logc.Errorf(ctx.Ctx, err.Error())
-				continue
-			}
+			var matched bool
+			if isAnomaly {
+				matched = anomaly.Deviated
+			} else {
+				operator, value, err := tools.ProcessRuleExpr(ruleExpr.Expr)
+				if err != nil {
+					logc.Errorf(ctx.Ctx, err.Error())
+					continue
+				}
 
-			option := models.EvalCondition{
-				Operator:      operator,
-				QueryValue:    v.Value,
-				ExpectedValue: value,
+				matched = process.EvalCondition(models.EvalCondition{
+					Operator:      operator,
+					QueryValue:    v.Value,
+					ExpectedValue: value,
+				})
 			}
 
 			event := process.BuildEvent(rule, func() map[string]interface{} {
 				metric := *v.GetMetric()
 				metric["severity"] = ruleExpr.Severity
 				metric["fingerprint"] = fingerprint
+				if isAnomaly && !anomaly.WarmingUp {
+					metric["baseline_mean"] = anomaly.Mean
+					metric["baseline_upper"] = anomaly.Upper
+					metric["baseline_lower"] = anomaly.Lower
+				}
 				for ek, ev := range externalLabels {
 					metric[ek] = ev
 				}
@@ -106,7 +119,7 @@ func metrics(ctx *ctx.Context, datasourceId, datasourceType string, rule models.
 			event.Annotations = tools.ParserVariables(rule.PrometheusConfig.Annotations, event.Metric)
 			event.SearchQL = rule.PrometheusConfig.PromQL
 
-			if process.EvalCondition(option) {
+			if matched {
 				// 如果条件满足，检查是否已经有更高优先级的事件
 				if _, exists := highestPriorityEvents[fingerprint]; !exists {
 					// 如果该指纹还没有事件，添加当前事件
@@ -147,6 +160,24 @@ func metrics(ctx *ctx.Context, datasourceId, datasourceType string, rule models.
 }
 
 // sortRulesByPriority 按优先级排序规则
+// evalAnomaly 使用历史样本评估当前值是否偏离基线, 评估后将当前值追加为新的样本
+func evalAnomaly(ctx *ctx.Context, rule models.AlertRule, fingerprint string, value float64) process.AnomalyResult {
+	cfg := rule.PrometheusConfig.Anomaly
+	history := ctx.Redis.Baseline().List(rule.TenantId, rule.RuleId, fingerprint)
+	res := process.EvalAnomaly(cfg, value, history)
+	ctx.Redis.Baseline().Push(rule.TenantId, rule.RuleId, fingerprint, value, cfg.GetLookback())
+
+	return res
+}
+
+// getAnomalySeverity 获取异常检测的告警等级, 未配置时使用规则的告警等级
+func getAnomalySeverity(rule models.AlertRule) string {
+	if rule.PrometheusConfig.Anomaly.Severity != "" {
+		return rule.PrometheusConfig.Anomaly.Severity
+	}
+	return rule.Severity
+}
+
 func sortRulesByPriority(rules []models.Rules) []models.Rules {
 	sortedRules := make([]models.Rules, len(rules))
 	copy(sortedRules, rules)
@@ -176,151 +207,77 @@ func getPriorityValue(severity string) int {
 	}
 }
 
-// Logs 包含 AliSLS、Loki、ElasticSearch 数据源
+// Logs 日志类数据源, 客户端按 LogsFactoryProvider 查询, 仅 ElasticSearch 查询统计及拨测需单独处理
 func logs(ctx *ctx.Context, datasourceId, datasourceType string, rule models.AlertRule) []string {
 	var (
 		queryRes       []provider.Logs
 		count          int
 		evalOptions    models.EvalCondition
 		externalLabels map[string]interface{}
+		// 数据源返回的查询统计, 目前仅 ElasticSearch
+		queryStats provider.QueryStats
 	)
 
-	pools := ctx.Redis.ProviderPools()
-	switch datasourceType {
-	case provider.LokiDsProviderName:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
+	// 聚合查询按每个分桶的聚合值进行评估
+	isAggregation := datasourceType == provider.ElasticSearchDsProviderName && rule.ElasticSearchConfig.EsQueryType == models.EsQueryTypeAggregation
+	isMultiMetric := datasourceType == provider.ElasticSearchDsProviderName && rule.ElasticSearchConfig.IsMultiMetricAggregation()
+	isHTTPProbe := datasourceType == provider.HTTPProbeDsProviderName
 
-		curAt := time.Now()
-		startsAt := tools.ParserDuration(curAt, rule.LokiConfig.LogScope, "m")
-		queryOptions := provider.LogQueryOptions{
-			Loki: provider.Loki{
-				Query: rule.LokiConfig.LogQL,
-			},
-			StartAt: startsAt.Unix(),
-			EndAt:   curAt.Unix(),
-		}
-		queryRes, count, err = cli.(provider.LokiProvider).Query(queryOptions)
-		if err != nil {
-			logc.Error(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		externalLabels = cli.(provider.LokiProvider).GetExternalLabels()
-		operator, value, err := tools.ProcessRuleExpr(rule.LogEvalCondition)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		evalOptions = models.EvalCondition{
-			Operator:      operator,
-			QueryValue:    float64(count),
-			ExpectedValue: value,
-		}
-	case provider.AliCloudSLSDsProviderName:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
+	cli, err := ctx.Redis.ProviderPools().GetClient(datasourceId)
+	if err != nil {
+		logc.Errorf(ctx.Ctx, err.Error())
+		return []string{}
+	}
+	logsCli, ok := cli.(provider.LogsFactoryProvider)
+	if !ok {
+		logc.Errorf(ctx.Ctx, fmt.Sprintf("Unsupported logs type, type: %s", datasourceType))
+		return []string{}
+	}
 
-		curAt := time.Now()
-		startsAt := tools.ParserDuration(curAt, rule.AliCloudSLSConfig.LogScope, "m")
-		queryOptions := provider.LogQueryOptions{
-			AliCloudSLS: provider.AliCloudSLS{
-				Query:    rule.AliCloudSLSConfig.LogQL,
-				Project:  rule.AliCloudSLSConfig.Project,
-				LogStore: rule.AliCloudSLSConfig.Logstore,
-			},
-			StartAt: int32(startsAt.Unix()),
-			EndAt:   int32(curAt.Unix()),
-		}
-		queryRes, count, err = cli.(provider.AliCloudSlsDsProvider).Query(queryOptions)
+	var searchQL string
+	switch datasourceType {
+	case provider.HTTPProbeDsProviderName:
+		// 拨测每次都需实际发起请求, 不使用查询缓存, 请求失败体现在拨测结果中, 无需重试
+		_, span := tracing.StartQuery(ctx.Ctx, datasourceId, datasourceType, "", "")
+		queryRes, count, err = logsCli.Query(BuildLogQueryOptions(datasourceType, rule, time.Now()))
+		tracing.EndWithCount(span, count, err)
 		if err != nil {
 			logc.Error(ctx.Ctx, err.Error())
 			return []string{}
 		}
-
-		externalLabels = cli.(provider.AliCloudSlsDsProvider).GetExternalLabels()
-		operator, value, err := tools.ProcessRuleExpr(rule.LogEvalCondition)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		evalOptions = models.EvalCondition{
-			Operator:      operator,
-			QueryValue:    float64(count),
-			ExpectedValue: value,
-		}
-	case provider.ElasticSearchDsProviderName:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		curAt := time.Now()
-		startsAt := tools.ParserDuration(curAt, int(rule.ElasticSearchConfig.Scope), "m")
-		queryOptions := provider.LogQueryOptions{
-			ElasticSearch: provider.Elasticsearch{
-				Index:                rule.ElasticSearchConfig.Index,
-				QueryFilter:          rule.ElasticSearchConfig.Filter,
-				QueryFilterCondition: rule.ElasticSearchConfig.FilterCondition,
-				QueryType:            rule.ElasticSearchConfig.EsQueryType,
-				QueryWildcard:        rule.ElasticSearchConfig.QueryWildcard,
-				RawJson:              rule.ElasticSearchConfig.RawJson,
-			},
-			StartAt: tools.FormatTimeToUTC(startsAt.Unix()),
-			EndAt:   tools.FormatTimeToUTC(curAt.Unix()),
+		searchQL = tools.JsonMarshal(rule.HTTPProbeConfig.Conditions)
+	default:
+		queryOptions := BuildLogQueryOptions(datasourceType, rule, provider.AlignQueryTime(time.Now()))
+		isElasticSearch := datasourceType == provider.ElasticSearchDsProviderName
+		if isElasticSearch {
+			queryOptions.Stats = &queryStats
+		}
+		query, index := queryOptions.Statement(datasourceType)
+		spanCtx, span := tracing.StartQuery(ctx.Ctx, datasourceId, datasourceType, query, index)
+		err = provider.RetryQuery(spanCtx, datasourceId, func() error {
+			var err error
+			queryStats = provider.QueryStats{}
+			queryRes, count, err = provider.QueryLogsWithCache(datasourceId, queryOptions, logsCli.Query)
+			return err
+		})
+		if isElasticSearch {
+			tracing.SetQueryStats(span, queryStats.TookMs, queryStats.Warning())
 		}
-		queryRes, count, err = cli.(provider.ElasticSearchDsProvider).Query(queryOptions)
+		tracing.EndWithCount(span, count, err)
 		if err != nil {
 			logc.Error(ctx.Ctx, err.Error())
 			return []string{}
 		}
-
-		externalLabels = cli.(provider.ElasticSearchDsProvider).GetExternalLabels()
-		operator, value, err := tools.ProcessRuleExpr(rule.LogEvalCondition)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		evalOptions = models.EvalCondition{
-			Operator:      operator,
-			QueryValue:    float64(count),
-			ExpectedValue: value,
-		}
-
-	case provider.VictoriaLogsDsProviderName:
-		cli, err := pools.GetClient(datasourceId)
-		if err != nil {
-			logc.Errorf(ctx.Ctx, err.Error())
-			return []string{}
-		}
-
-		curAt := time.Now()
-		startsAt := tools.ParserDuration(curAt, rule.VictoriaLogsConfig.LogScope, "m")
-		queryOptions := provider.LogQueryOptions{
-			VictoriaLogs: provider.VictoriaLogs{
-				Query: rule.VictoriaLogsConfig.LogQL,
-				Limit: rule.VictoriaLogsConfig.Limit,
-			},
-			StartAt: int32(startsAt.Unix()),
-			EndAt:   int32(curAt.Unix()),
-		}
-		queryRes, count, err = cli.(provider.VictoriaLogsProvider).Query(queryOptions)
-		if err != nil {
-			logc.Error(ctx.Ctx, err.Error())
-			return []string{}
+		// 结果不完整时记录, 本轮评估不处理告警恢复, 触发的告警标记查询结果不完整
+		if isElasticSearch {
+			provider.RecordQueryStats(ctx.Ctx, datasourceId, queryStats)
 		}
+		searchQL = query
+	}
+	externalLabels = logsCli.GetExternalLabels()
 
-		externalLabels = cli.(provider.VictoriaLogsProvider).GetExternalLabels()
+	// 多指标聚合及拨测使用各指标的评估条件
+	if !isMultiMetric && !isHTTPProbe {
 		operator, value, err := tools.ProcessRuleExpr(rule.LogEvalCondition)
 		if err != nil {
 			logc.Errorf(ctx.Ctx, err.Error())
@@ -341,10 +298,40 @@ func logs(ctx *ctx.Context, datasourceId, datasourceType string, rule models.Ale
 	var curFingerprints []string
 	for _, v := range queryRes {
 		fingerprint := v.GetFingerprint()
+		options := evalOptions
+		var value interface{} = count
+		valueName := "count"
+		if isAggregation {
+			options.QueryValue = v.GetAggregationValue()
+			value, valueName = options.QueryValue, ""
+		}
+		// 拨测以首个评估条件的字段值作为告警值
+		if isHTTPProbe && len(rule.HTTPProbeConfig.Conditions) > 0 {
+			value, valueName = v.GetAggregationMetricValue(rule.HTTPProbeConfig.Conditions[0].Field), rule.HTTPProbeConfig.Conditions[0].Field
+		}
+		// 配置告警值字段时以该字段的值作为告警值, 字段不存在时沿用默认告警值
+		if rule.LogValue.Field != "" {
+			if fieldValue, ok := v.GetFieldValue(rule.LogValue.Field); ok {
+				value, valueName = fieldValue, rule.LogValue.GetName()
+			} else {
+				logc.Errorf(ctx.Ctx, "规则 %s 的告警值字段 %s 不存在或不是数值, 使用默认告警值", rule.RuleName, rule.LogValue.Field)
+			}
+		}
+
 		event := func() *models.AlertCurEvent {
 			event := process.BuildEvent(rule, func() map[string]interface{} {
 				metric := v.GetMetric()
-				metric["value"] = count
+				metric["value"] = value
+				if isMultiMetric {
+					for _, m := range rule.ElasticSearchConfig.Aggregation.Metrics {
+						metric[m.Name] = v.GetAggregationMetricValue(m.Name)
+					}
+				}
+				if isHTTPProbe {
+					for _, field := range []string{models.HTTPProbeFieldStatusCode, models.HTTPProbeFieldLatency, models.HTTPProbeFieldBodyMatch} {
+						metric[field] = v.GetAggregationMetricValue(field)
+					}
+				}
 				metric["severity"] = rule.Severity
 				metric["fingerprint"] = fingerprint
 				for ek, ev := range externalLabels {
@@ -359,21 +346,13 @@ func logs(ctx *ctx.Context, datasourceId, datasourceType string, rule models.Ale
 			event.DatasourceId = datasourceId
 			event.Fingerprint = fingerprint
 			event.Log = v.GetAnnotations()[0]
+			event.LogSamples = getLogSamples(v.GetAnnotations(), rule.GetLogSampleSize())
+			event.LogCount = count
+			event.ValueName = valueName
+			event.QueryTookMs = queryStats.TookMs
+			event.QueryWarning = queryStats.Warning()
 
-			switch datasourceType {
-			case provider.LokiDsProviderName:
-				event.SearchQL = rule.LokiConfig.LogQL
-			case provider.AliCloudSLSDsProviderName:
-				event.SearchQL = rule.AliCloudSLSConfig.LogQL
-			case provider.ElasticSearchDsProviderName:
-				if rule.ElasticSearchConfig.RawJson != "" {
-					event.SearchQL = rule.ElasticSearchConfig.RawJson
-				} else {
-					event.SearchQL = tools.JsonMarshal(rule.ElasticSearchConfig.Filter)
-				}
-			case provider.VictoriaLogsDsProviderName:
-				event.SearchQL = rule.VictoriaLogsConfig.LogQL
-			}
+			event.SearchQL = searchQL
 
 			curFingerprints = append(curFingerprints, event.Fingerprint)
 
@@ -381,7 +360,22 @@ func logs(ctx *ctx.Context, datasourceId, datasourceType string, rule models.Ale
 		}
 
 		// 评估告警条件
-		if process.EvalCondition(evalOptions) {
+		if isMultiMetric || isHTTPProbe {
+			metrics, relation := rule.ElasticSearchConfig.Aggregation.Metrics, rule.ElasticSearchConfig.Aggregation.MetricsCondition
+			if isHTTPProbe {
+				metrics, relation = rule.HTTPProbeConfig.GetMetrics(), rule.HTTPProbeConfig.ConditionRelation
+			}
+			matched, err := process.EvalMetricsCondition(metrics, relation, v.GetAggregationMetricValue)
+			if err != nil {
+				logc.Errorf(ctx.Ctx, err.Error())
+				return []string{}
+			}
+			if matched {
+				process.PushEventToFaultCenter(ctx, event())
+			}
+			continue
+		}
+		if process.EvalCondition(options) {
 			process.PushEventToFaultCenter(ctx, event())
 		}
 	}
@@ -389,6 +383,175 @@ func logs(ctx *ctx.Context, datasourceId, datasourceType string, rule models.Ale
 	return curFingerprints
 }
 
+// getLogSamples 按查询结果的顺序保留前 size 条日志作为样本, 相同的查询结果得到相同的样本, 避免事件缓存及通知消息过大
+func getLogSamples(msgs []map[string]interface{}, size int) []map[string]interface{} {
+	if len(msgs) > size {
+		return msgs[:size]
+	}
+	return msgs
+}
+
+// LogQueryOptionsBuilder 根据告警规则构建日志数据源的查询参数
+type LogQueryOptionsBuilder func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions
+
+var (
+	logQueryOptionsMux      sync.RWMutex
+	logQueryOptionsBuilders = make(map[string]LogQueryOptionsBuilder)
+)
+
+// RegisterLogQueryOptions 注册日志数据源的查询参数构建函数, 新增日志数据源类型时与 provider.Register 一同在 init 中注册, 无需修改评估逻辑;
+// 类型名为空、构建函数为空或重复注册时 panic
+func RegisterLogQueryOptions(name string, builder LogQueryOptionsBuilder) {
+	logQueryOptionsMux.Lock()
+	defer logQueryOptionsMux.Unlock()
+
+	if name == "" || builder == nil {
+		panic("eval: RegisterLogQueryOptions name or builder is empty")
+	}
+	if _, exists := logQueryOptionsBuilders[name]; exists {
+		panic("eval: RegisterLogQueryOptions called twice for " + name)
+	}
+	logQueryOptionsBuilders[name] = builder
+}
+
+// BuildLogQueryOptions 根据告警规则构建日志查询参数, 告警评估与规则预览共用
+func BuildLogQueryOptions(datasourceType string, rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+	logQueryOptionsMux.RLock()
+	builder, ok := logQueryOptionsBuilders[datasourceType]
+	logQueryOptionsMux.RUnlock()
+	if !ok {
+		return provider.LogQueryOptions{}
+	}
+	return builder(rule, curAt)
+}
+
+func init() {
+	RegisterLogQueryOptions(provider.LokiDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.LokiConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			Loki: provider.Loki{
+				Query: rule.LokiConfig.LogQL,
+			},
+			StartAt:     startsAt.Unix(),
+			EndAt:       curAt.Unix(),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.AliCloudSLSDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.AliCloudSLSConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			AliCloudSLS: provider.AliCloudSLS{
+				Query:    rule.AliCloudSLSConfig.LogQL,
+				Project:  rule.AliCloudSLSConfig.Project,
+				LogStore: rule.AliCloudSLSConfig.Logstore,
+			},
+			StartAt:     int32(startsAt.Unix()),
+			EndAt:       int32(curAt.Unix()),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.ElasticSearchDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, int(rule.ElasticSearchConfig.Scope), "m")
+		return provider.LogQueryOptions{
+			ElasticSearch: provider.Elasticsearch{
+				Index:                rule.ElasticSearchConfig.Index,
+				QueryFilter:          rule.ElasticSearchConfig.Filter,
+				QueryFilterCondition: rule.ElasticSearchConfig.FilterCondition,
+				QueryType:            rule.ElasticSearchConfig.EsQueryType,
+				QueryWildcard:        rule.ElasticSearchConfig.QueryWildcard,
+				RawJson:              rule.ElasticSearchConfig.RawJson,
+				Aggregation:          rule.ElasticSearchConfig.Aggregation,
+				MaxLogs:              rule.ElasticSearchConfig.MaxLogs,
+				TimestampField:       rule.ElasticSearchConfig.TimestampField,
+				IndexPattern:         rule.ElasticSearchConfig.IndexPattern,
+				Highlight:            rule.ElasticSearchConfig.Highlight,
+				SortField:            rule.ElasticSearchConfig.SortField,
+				SortAscending:        rule.ElasticSearchConfig.SortAscending,
+				Size:                 rule.ElasticSearchConfig.Size,
+				Clusters:             rule.ElasticSearchConfig.Clusters,
+				MaxResultWindow:      rule.ElasticSearchConfig.MaxResultWindow,
+				AutoPaginate:         rule.ElasticSearchConfig.AutoPaginate,
+				FieldTypes:           rule.ElasticSearchConfig.FieldTypes,
+				CountOnly:            rule.ElasticSearchConfig.CountOnly,
+				RuntimeFields:        rule.ElasticSearchConfig.RuntimeFields,
+				BypassQueryGuard:     rule.ElasticSearchConfig.BypassQueryGuard,
+			},
+			StartAt:     tools.FormatTimeToUTC(startsAt.Unix()),
+			EndAt:       tools.FormatTimeToUTC(curAt.Unix()),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.VictoriaLogsDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.VictoriaLogsConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			VictoriaLogs: provider.VictoriaLogs{
+				Query: rule.VictoriaLogsConfig.LogQL,
+				Limit: rule.VictoriaLogsConfig.Limit,
+			},
+			StartAt:     int32(startsAt.Unix()),
+			EndAt:       int32(curAt.Unix()),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.ClickHouseDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.ClickHouseConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			ClickHouse: provider.ClickHouse{
+				QueryType:      rule.ClickHouseConfig.QueryType,
+				Table:          rule.ClickHouseConfig.Table,
+				TimestampField: rule.ClickHouseConfig.TimestampField,
+				Where:          rule.ClickHouseConfig.Where,
+				RawSQL:         rule.ClickHouseConfig.RawSQL,
+				Limit:          rule.ClickHouseConfig.Limit,
+			},
+			StartAt:     startsAt.Unix(),
+			EndAt:       curAt.Unix(),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.GraylogDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.GraylogConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			Graylog: provider.Graylog{
+				QueryType:            rule.GraylogConfig.QueryType,
+				Query:                rule.GraylogConfig.Query,
+				QueryFilter:          rule.GraylogConfig.Filter,
+				QueryFilterCondition: rule.GraylogConfig.FilterCondition,
+				QueryWildcard:        rule.GraylogConfig.QueryWildcard,
+				Streams:              rule.GraylogConfig.Streams,
+				Limit:                rule.GraylogConfig.Limit,
+			},
+			StartAt:     startsAt.Unix(),
+			EndAt:       curAt.Unix(),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.SQLDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		startsAt := tools.ParserDuration(curAt, rule.SQLConfig.LogScope, "m")
+		return provider.LogQueryOptions{
+			SQL: provider.SQL{
+				Query: rule.SQLConfig.SQL,
+				Limit: rule.SQLConfig.Limit,
+			},
+			StartAt:     startsAt.Unix(),
+			EndAt:       curAt.Unix(),
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+
+	RegisterLogQueryOptions(provider.HTTPProbeDsProviderName, func(rule models.AlertRule, curAt time.Time) provider.LogQueryOptions {
+		return provider.LogQueryOptions{
+			LabelFields: rule.LogLabelFields,
+		}
+	})
+}
+
 // Traces 包含 Jaeger 数据源
 func traces(ctx *ctx.Context, datasourceId, datasourceType string, rule models.AlertRule) []string {
 	var (
@@ -409,12 +572,19 @@ func traces(ctx *ctx.Context, datasourceId, datasourceType string, rule models.A
 		}
 
 		queryOptions := provider.TraceQueryOptions{
-			Tags:    rule.JaegerConfig.Tags,
-			Service: rule.JaegerConfig.Service,
-			StartAt: startsAt.UnixMicro(),
-			EndAt:   curAt.UnixMicro(),
-		}
-		queryRes, err = cli.(provider.JaegerDsProvider).Query(queryOptions)
+			Tags:      rule.JaegerConfig.Tags,
+			Service:   rule.JaegerConfig.Service,
+			Operation: rule.JaegerConfig.Operation,
+			StartAt:   startsAt.UnixMicro(),
+			EndAt:     curAt.UnixMicro(),
+		}
+		spanCtx, span := tracing.StartQuery(ctx.Ctx, datasourceId, datasourceType, rule.JaegerConfig.Tags, rule.JaegerConfig.Service)
+		err = provider.RetryQuery(spanCtx, datasourceId, func() error {
+			var err error
+			queryRes, err = cli.(provider.JaegerDsProvider).Query(queryOptions)
+			return err
+		})
+		tracing.EndWithCount(span, len(queryRes), err)
 		if err != nil {
 			logc.Error(ctx.Ctx, err.Error())
 			return []string{}
@@ -423,8 +593,30 @@ func traces(ctx *ctx.Context, datasourceId, datasourceType string, rule models.A
 		externalLabels = cli.(provider.JaegerDsProvider).GetExternalLabels()
 	}
 
+	// 配置错误 Span 数评估条件时, 仅满足条件的链路触发告警
+	var (
+		errorOperator string
+		errorValue    float64
+	)
+	if rule.JaegerConfig.ErrorCondition != "" {
+		var err error
+		errorOperator, errorValue, err = tools.ProcessRuleExpr(rule.JaegerConfig.ErrorCondition)
+		if err != nil {
+			logc.Errorf(ctx.Ctx, err.Error())
+			return []string{}
+		}
+	}
+
 	var curFingerprints []string
 	for _, v := range queryRes {
+		if errorOperator != "" && !process.EvalCondition(models.EvalCondition{
+			Operator:      errorOperator,
+			QueryValue:    float64(v.ErrorCount),
+			ExpectedValue: errorValue,
+		}) {
+			continue
+		}
+
 		fingerprint := v.GetFingerprint()
 		event := process.BuildEvent(rule, func() map[string]interface{} {
 			metric := v.GetMetric()
@@ -442,7 +634,7 @@ func traces(ctx *ctx.Context, datasourceId, datasourceType string, rule models.A
 		event.DatasourceId = datasourceId
 		event.Fingerprint = fingerprint
 		event.SearchQL = rule.JaegerConfig.Tags
-		event.Annotations = fmt.Sprintf("服务: %s 链路中存在异常状态码接口, TraceId: %s", rule.JaegerConfig.Service, v.TraceId)
+		event.Annotations = fmt.Sprintf("服务: %s 链路中存在异常状态码接口, TraceId: %s, Span 数: %d, 错误 Span 数: %d", rule.JaegerConfig.Service, v.TraceId, v.SpanCount, v.ErrorCount)
 
 		curFingerprints = append(curFingerprints, event.Fingerprint)
 		process.PushEventToFaultCenter(ctx, &event)
@@ -478,7 +670,9 @@ func cloudWatch(ctx *ctx.Context, datasourceId string, rule models.AlertRule) []
 			Form:       startsAt,
 			To:         curAt,
 		}
+		_, span := tracing.StartQuery(ctx.Ctx, datasourceId, rule.DatasourceType, query.Namespace+"/"+query.MetricName, endpoint)
 		_, values := cloudwatch.MetricDataQuery(cli, query)
+		tracing.EndWithCount(span, len(values), nil)
 		if len(values) == 0 {
 			return []string{}
 		}
@@ -529,11 +723,14 @@ func kubernetesEvent(ctx *ctx.Context, datasourceId string, rule models.AlertRul
 		return []string{}
 	}
 
+	_, span := tracing.StartQuery(ctx.Ctx, datasourceId, rule.DatasourceType, rule.KubernetesConfig.Reason, "")
 	k8sEvent, err := cli.(provider.KubernetesClient).GetWarningEvent(rule.KubernetesConfig.Reason, rule.KubernetesConfig.Scope)
 	if err != nil {
+		tracing.End(span, err)
 		logc.Error(ctx.Ctx, err.Error())
 		return []string{}
 	}
+	tracing.EndWithCount(span, len(k8sEvent.Items), nil)
 
 	externalLabels = cli.(provider.KubernetesClient).GetExternalLabels()
 
@@ -0,0 +1,93 @@
+package mute
+
+import (
+	"fmt"
+	models "watchAlert/internal/models"
+)
+
+// InhibitedBy 获取抑制当前告警的源告警, 与 Alertmanager 的抑制语义一致, 仅对未恢复的告警生效
+func InhibitedBy(rules []models.InhibitRule, event *models.AlertCurEvent, events map[string]*models.AlertCurEvent) []models.InhibitSource {
+	if len(rules) == 0 || event.IsRecovered {
+		return nil
+	}
+
+	var sources []models.InhibitSource
+	for _, rule := range rules {
+		if !evalCondition(event.Metric, rule.TargetMatchers) {
+			continue
+		}
+		// 同时匹配源及目标条件的告警, 不能被同样匹配两者的告警抑制, 避免互相抑制
+		targetIsSource := evalCondition(event.Metric, rule.SourceMatchers)
+
+		for _, source := range events {
+			if source.Fingerprint == event.Fingerprint || !isInhibitSourceActive(source) {
+				continue
+			}
+			if !evalCondition(source.Metric, rule.SourceMatchers) {
+				continue
+			}
+			if targetIsSource && evalCondition(source.Metric, rule.TargetMatchers) {
+				continue
+			}
+			if !equalLabels(rule.Equal, source.Metric, event.Metric) {
+				continue
+			}
+
+			sources = append(sources, models.InhibitSource{
+				InhibitRule: rule.Name,
+				Fingerprint: source.Fingerprint,
+				RuleName:    source.RuleName,
+				Metric:      source.Metric,
+			})
+		}
+	}
+
+	return sources
+}
+
+// isInhibitSourceActive 源告警触发中 (含静默、待恢复) 时生效, 预告警及已恢复的告警不抑制其他告警
+func isInhibitSourceActive(event *models.AlertCurEvent) bool {
+	if event.IsRecovered {
+		return false
+	}
+
+	switch event.Status {
+	case models.StateAlerting, models.StateSilenced, models.StatePendingRecovery:
+		return true
+	default:
+		return false
+	}
+}
+
+// equalLabels 比较两组告警的标签值, 标签均不存在时视为相同
+func equalLabels(keys []string, a, b map[string]interface{}) bool {
+	for _, key := range keys {
+		var va, vb string
+		if v, ok := a[key]; ok {
+			va = fmt.Sprintf("%v", v)
+		}
+		if v, ok := b[key]; ok {
+			vb = fmt.Sprintf("%v", v)
+		}
+		if va != vb {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateInhibitRules 校验抑制规则
+func ValidateInhibitRules(rules []models.InhibitRule) error {
+	for _, rule := range rules {
+		if len(rule.SourceMatchers) == 0 || len(rule.TargetMatchers) == 0 {
+			return fmt.Errorf("抑制规则 %s 的源告警及目标告警条件不能为空", rule.Name)
+		}
+		if err := ValidateSilenceLabels(rule.SourceMatchers); err != nil {
+			return fmt.Errorf("抑制规则 %s 的源告警条件错误: %s", rule.Name, err.Error())
+		}
+		if err := ValidateSilenceLabels(rule.TargetMatchers); err != nil {
+			return fmt.Errorf("抑制规则 %s 的目标告警条件错误: %s", rule.Name, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package mute
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestInhibitedBy(t *testing.T) {
+	rules := []models.InhibitRule{{
+		Name:           "node-down",
+		SourceMatchers: []models.SilenceLabel{{Key: "alertname", Operator: "==", Value: "NodeDown"}},
+		TargetMatchers: []models.SilenceLabel{{Key: "severity", Operator: "=~", Value: "P1|P2"}},
+		Equal:          []string{"instance"},
+	}}
+	newEvent := func(fingerprint string, status models.AlertStatus, metric map[string]interface{}) *models.AlertCurEvent {
+		return &models.AlertCurEvent{Fingerprint: fingerprint, RuleName: fingerprint, Status: status, Metric: metric}
+	}
+	target := newEvent("target", models.StateAlerting, map[string]interface{}{"alertname": "HighLatency", "severity": "P2", "instance": "node-1"})
+
+	tests := []struct {
+		name   string
+		rules  []models.InhibitRule
+		event  *models.AlertCurEvent
+		events []*models.AlertCurEvent
+		want   []string
+	}{
+		{
+			name:   "active source with equal labels",
+			rules:  rules,
+			event:  target,
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"})},
+			want:   []string{"src"},
+		},
+		{
+			name:  "silenced and pending recovery sources inhibit",
+			rules: rules,
+			event: target,
+			events: []*models.AlertCurEvent{
+				newEvent("silenced", models.StateSilenced, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"}),
+				newEvent("pending", models.StatePendingRecovery, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"}),
+			},
+			want: []string{"pending", "silenced"},
+		},
+		{
+			name:  "pre alert and recovered sources do not inhibit",
+			rules: rules,
+			event: target,
+			events: []*models.AlertCurEvent{
+				newEvent("pre", models.StatePreAlert, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"}),
+				newEvent("recovered", models.StateRecovered, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"}),
+			},
+		},
+		{
+			name:   "different equal label",
+			rules:  rules,
+			event:  target,
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown", "instance": "node-2"})},
+		},
+		{
+			name:   "equal label missing on both",
+			rules:  rules,
+			event:  newEvent("target", models.StateAlerting, map[string]interface{}{"severity": "P1"}),
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown"})},
+			want:   []string{"src"},
+		},
+		{
+			name:   "target matchers not matched",
+			rules:  rules,
+			event:  newEvent("target", models.StateAlerting, map[string]interface{}{"severity": "P0", "instance": "node-1"}),
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"})},
+		},
+		{
+			name:   "recovered target is not inhibited",
+			rules:  rules,
+			event:  &models.AlertCurEvent{Fingerprint: "target", IsRecovered: true, Metric: target.Metric},
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"})},
+		},
+		{
+			name:   "event does not inhibit itself",
+			rules:  []models.InhibitRule{{SourceMatchers: []models.SilenceLabel{{Key: "severity", Operator: "==", Value: "P2"}}, TargetMatchers: rules[0].TargetMatchers}},
+			event:  target,
+			events: []*models.AlertCurEvent{target},
+		},
+		{
+			name: "alerts matching source and target do not inhibit each other",
+			rules: []models.InhibitRule{{
+				SourceMatchers: []models.SilenceLabel{{Key: "severity", Operator: "=~", Value: "P.*"}},
+				TargetMatchers: []models.SilenceLabel{{Key: "severity", Operator: "=~", Value: "P.*"}},
+			}},
+			event:  target,
+			events: []*models.AlertCurEvent{newEvent("other", models.StateAlerting, map[string]interface{}{"severity": "P1"})},
+		},
+		{
+			name:   "no rules",
+			event:  target,
+			events: []*models.AlertCurEvent{newEvent("src", models.StateAlerting, map[string]interface{}{"alertname": "NodeDown", "instance": "node-1"})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(map[string]*models.AlertCurEvent, len(tt.events))
+			for _, e := range tt.events {
+				events[e.Fingerprint] = e
+			}
+
+			var got []string
+			for _, source := range InhibitedBy(tt.rules, tt.event, events) {
+				got = append(got, source.Fingerprint)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InhibitedBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package mute
 
 import (
+	"container/list"
+	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
+	"regexp"
+	"sync"
 	"time"
 	models "watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
@@ -10,7 +14,7 @@ import (
 
 type MuteParams struct {
 	EffectiveTime models.EffectiveTime
-	RecoverNotify *bool
+	RecoverNotify bool
 	IsRecovered   bool
 	TenantId      string
 	Metrics       map[string]interface{}
@@ -54,52 +58,72 @@ func NotInTheEffectiveTime(mp MuteParams) bool {
 
 // RecoverNotify 判断是否推送恢复通知
 func RecoverNotify(mp MuteParams) bool {
-	return mp.IsRecovered && !*mp.RecoverNotify
+	return mp.IsRecovered && !mp.RecoverNotify
 }
 
 // IsSilence 判断是否静默
 func IsSilence(mute MuteParams) bool {
+	return len(MatchSilences(mute.TenantId, mute.FaultCenterId, mute.Metrics)) > 0
+}
+
+// MatchSilences 获取当前生效且与告警标签匹配的静默规则
+func MatchSilences(tenantId, faultCenterId string, metrics map[string]interface{}) []models.AlertSilences {
 	silenceCtx := ctx.Redis.Silence()
 	// 获取静默列表中所有的id
-	ids, err := silenceCtx.GetAlertMutes(mute.TenantId, mute.FaultCenterId)
+	ids, err := silenceCtx.GetAlertMutes(tenantId, faultCenterId)
 	if err != nil {
 		logc.Errorf(ctx.Ctx, err.Error())
-		return false
+		return nil
 	}
 
+	now := time.Now().Unix()
+	var matched []models.AlertSilences
 	// 根据ID获取到详细的静默规则
 	for _, id := range ids {
-		muteRule, err := silenceCtx.WithIdGetMuteFromCache(mute.TenantId, mute.FaultCenterId, id)
+		muteRule, err := silenceCtx.WithIdGetMuteFromCache(tenantId, faultCenterId, id)
 		if err != nil {
 			logc.Errorf(ctx.Ctx, err.Error())
-			return false
+			continue
 		}
 
-		if muteRule.Status != 1 {
+		// 状态由定时任务更新, 这里同时校验时间窗口, 避免状态更新前的误判
+		if !muteRule.IsActive(now) {
 			continue
 		}
 
-		if evalCondition(mute.Metrics, muteRule.Labels) {
-			return true
+		if evalCondition(metrics, muteRule.Labels) {
+			matched = append(matched, *muteRule)
 		}
 	}
 
-	return false
+	return matched
+}
+
+// MatchLabels 告警标签是否满足全部匹配条件, 匹配语义与静默规则一致
+func MatchLabels(metrics map[string]interface{}, labels []models.SilenceLabel) bool {
+	return evalCondition(metrics, labels)
 }
 
 func evalCondition(metrics map[string]interface{}, muteLabels []models.SilenceLabel) bool {
 	for _, muteLabel := range muteLabels {
-		val, exists := metrics[muteLabel.Key]
-		if !exists {
-			return false
+		v, exists := metrics[muteLabel.Key]
+		var val string
+		if exists {
+			val = fmt.Sprintf("%v", v)
 		}
 
 		var matched bool
 		switch muteLabel.Operator {
-		case "==", "=":
-			matched = val == muteLabel.Value
-		case "!=":
-			matched = val != muteLabel.Value
+		case models.SilenceOperatorEqual, "=":
+			// 标签不存在时不匹配, 与此前的静默语义保持一致
+			matched = exists && val == muteLabel.Value
+		case models.SilenceOperatorNotEqual:
+			matched = exists && val != muteLabel.Value
+		case models.SilenceOperatorRegex:
+			// 正则按空值匹配不存在的标签, 与 Alertmanager 一致, 如 env!~"prod" 可匹配未携带 env 标签的告警
+			matched = matchRegex(muteLabel.Value, val)
+		case models.SilenceOperatorNotRegex:
+			matched = !matchRegex(muteLabel.Value, val)
 		default:
 			matched = false
 		}
@@ -111,3 +135,107 @@ func evalCondition(metrics map[string]interface{}, muteLabels []models.SilenceLa
 
 	return true
 }
+
+// regexCacheSize 已编译正则的缓存上限, 超出时淘汰最久未使用的表达式
+const regexCacheSize = 1024
+
+// regexCache 已编译的匹配正则, 按表达式缓存, 每条告警匹配静默规则时不再重复编译, 无效的表达式缓存为 nil
+var regexCache = newRegexLRU(regexCacheSize)
+
+type regexEntry struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+// regexLRU 按最近使用淘汰的正则缓存
+type regexLRU struct {
+	mux     sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+func newRegexLRU(size int) *regexLRU {
+	return &regexLRU{size: size, ll: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get 获取表达式编译后的正则, 未缓存时编译并缓存
+func (c *regexLRU) get(expr string) *regexp.Regexp {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if e, ok := c.entries[expr]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*regexEntry).re
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		re = nil
+	}
+	c.entries[expr] = c.ll.PushFront(&regexEntry{expr: expr, re: re})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexEntry).expr)
+	}
+	return re
+}
+
+func (c *regexLRU) remove(expr string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if e, ok := c.entries[expr]; ok {
+		c.ll.Remove(e)
+		delete(c.entries, expr)
+	}
+}
+
+func (c *regexLRU) len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.ll.Len()
+}
+
+// matchRegex 正则需完整匹配标签值, 与 Prometheus / Alertmanager 的语义保持一致
+func matchRegex(expr, val string) bool {
+	re := regexCache.get(expr)
+	return re != nil && re.MatchString(val)
+}
+
+// ForgetSilence 静默规则删除、变更或失效时清理其正则缓存, 其他规则仍在使用的表达式在下次匹配时重新编译
+func ForgetSilence(silence models.AlertSilences) {
+	for _, label := range silence.Labels {
+		switch label.Operator {
+		case models.SilenceOperatorRegex, models.SilenceOperatorNotRegex:
+			regexCache.remove(label.Value)
+		}
+	}
+}
+
+// ValidateSilenceLabels 校验静默标签的匹配方式及正则表达式
+func ValidateSilenceLabels(labels []models.SilenceLabel) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("静默标签不能为空")
+	}
+
+	for _, label := range labels {
+		if label.Key == "" {
+			return fmt.Errorf("静默标签 Key 不能为空")
+		}
+
+		switch label.Operator {
+		case models.SilenceOperatorEqual, "=", models.SilenceOperatorNotEqual:
+		case models.SilenceOperatorRegex, models.SilenceOperatorNotRegex:
+			if _, err := regexp.Compile("^(?:" + label.Value + ")$"); err != nil {
+				return fmt.Errorf("静默标签 %s 的正则表达式错误: %s", label.Key, err.Error())
+			}
+		default:
+			return fmt.Errorf("不支持的匹配方式: %s", label.Operator)
+		}
+	}
+
+	return nil
+}
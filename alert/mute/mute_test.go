@@ -0,0 +1,65 @@
+package mute
+
+import (
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestEvalCondition(t *testing.T) {
+	metrics := map[string]interface{}{"env": "prod", "instance": "10.0.0.1:9100"}
+
+	tests := []struct {
+		name   string
+		labels []models.SilenceLabel
+		want   bool
+	}{
+		{name: "equal", labels: []models.SilenceLabel{{Key: "env", Operator: "==", Value: "prod"}}, want: true},
+		{name: "legacy equal", labels: []models.SilenceLabel{{Key: "env", Operator: "=", Value: "prod"}}, want: true},
+		{name: "equal mismatch", labels: []models.SilenceLabel{{Key: "env", Operator: "==", Value: "dev"}}, want: false},
+		{name: "not equal", labels: []models.SilenceLabel{{Key: "env", Operator: "!=", Value: "dev"}}, want: true},
+		{name: "equal missing label", labels: []models.SilenceLabel{{Key: "team", Operator: "==", Value: ""}}, want: false},
+		{name: "not equal missing label", labels: []models.SilenceLabel{{Key: "team", Operator: "!=", Value: "sre"}}, want: false},
+		{name: "regex full match", labels: []models.SilenceLabel{{Key: "instance", Operator: "=~", Value: `10\.0\..*`}}, want: true},
+		{name: "regex partial", labels: []models.SilenceLabel{{Key: "instance", Operator: "=~", Value: `10\.0`}}, want: false},
+		{name: "regex missing label matches empty", labels: []models.SilenceLabel{{Key: "team", Operator: "=~", Value: ".*"}}, want: true},
+		{name: "not regex", labels: []models.SilenceLabel{{Key: "env", Operator: "!~", Value: "dev|test"}}, want: true},
+		{name: "not regex missing label", labels: []models.SilenceLabel{{Key: "team", Operator: "!~", Value: "sre"}}, want: true},
+		{name: "invalid regex", labels: []models.SilenceLabel{{Key: "env", Operator: "=~", Value: "("}}, want: false},
+		{name: "unknown operator", labels: []models.SilenceLabel{{Key: "env", Operator: ">", Value: "prod"}}, want: false},
+		{
+			name: "all must match",
+			labels: []models.SilenceLabel{
+				{Key: "env", Operator: "==", Value: "prod"},
+				{Key: "instance", Operator: "=~", Value: "192.*"},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evalCondition(metrics, tt.labels); got != tt.want {
+				t.Errorf("evalCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegexLRU(t *testing.T) {
+	c := newRegexLRU(2)
+	if c.get("a.*") == nil || c.get("(") != nil {
+		t.Fatal("get() should compile valid expressions and cache invalid ones as nil")
+	}
+	// 访问 a.* 后, 新增表达式淘汰最久未使用的 (
+	c.get("a.*")
+	c.get("b.*")
+	if _, ok := c.entries["("]; ok || c.len() != 2 {
+		t.Errorf("entries = %v, want the least recently used expression evicted", c.entries)
+	}
+
+	regexCache = newRegexLRU(regexCacheSize)
+	matchRegex("prod|staging", "prod")
+	ForgetSilence(models.AlertSilences{Labels: []models.SilenceLabel{{Key: "env", Operator: "=~", Value: "prod|staging"}}})
+	if regexCache.len() != 0 {
+		t.Errorf("ForgetSilence() left %d cached expressions", regexCache.len())
+	}
+}
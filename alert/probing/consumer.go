@@ -10,7 +10,6 @@ import (
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/sender"
 	"watchAlert/pkg/templates"
-	"watchAlert/pkg/tools"
 )
 
 type ConsumeProbing struct {
@@ -120,6 +119,13 @@ func (m *ConsumeProbing) sendAlert(alert models.ProbingEvent) {
 		Email:       noticeData.Email,
 		Content:     m.getContent(alert, noticeData),
 		Sign:        noticeData.DefaultSign,
+		Telegram:    noticeData.Telegram,
+		PagerDuty:   noticeData.PagerDuty,
+		OpsGenie:    noticeData.OpsGenie,
+		WebHook:     noticeData.WebHook,
+		Slack:       noticeData.Slack,
+		Fingerprint: alert.Fingerprint,
+		RateLimit:   noticeData.RateLimit,
 	})
 	if err != nil {
 		logc.Errorf(ctx.Ctx, err.Error())
@@ -129,7 +135,7 @@ func (m *ConsumeProbing) sendAlert(alert models.ProbingEvent) {
 
 func (m *ConsumeProbing) getContent(alert models.ProbingEvent, noticeData models.AlertNotice) string {
 	if noticeData.NoticeType == "CustomHook" {
-		return tools.JsonMarshal(alert)
+		return process.GenerateWebHookContent(m.ctx, alert, noticeData)
 	} else {
 		return templates.NewTemplate(m.ctx, buildEvent(alert), noticeData).CardContentMsg
 	}
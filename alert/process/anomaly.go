@@ -0,0 +1,54 @@
+package process
+
+import (
+	"math"
+	"watchAlert/internal/models"
+)
+
+// AnomalyResult 异常检测结果
+type AnomalyResult struct {
+	Mean   float64
+	Stddev float64
+	Upper  float64
+	Lower  float64
+	// 历史样本不足, 处于预热期, 不告警
+	WarmingUp bool
+	// 当前值偏离基线
+	Deviated bool
+}
+
+// EvalAnomaly 以历史样本的均值及标准差作为基线, 当前值超出 mean ± k·stddev 时视为偏离
+func EvalAnomaly(cfg models.AnomalyConfig, value float64, history []float64) AnomalyResult {
+	var res AnomalyResult
+	if int64(len(history)) < cfg.GetWarmUp() || len(history) == 0 {
+		res.WarmingUp = true
+		return res
+	}
+
+	var sum float64
+	for _, v := range history {
+		sum += v
+	}
+	res.Mean = sum / float64(len(history))
+
+	var variance float64
+	for _, v := range history {
+		variance += (v - res.Mean) * (v - res.Mean)
+	}
+	res.Stddev = math.Sqrt(variance / float64(len(history)))
+
+	k := cfg.GetK()
+	res.Upper = res.Mean + k*res.Stddev
+	res.Lower = res.Mean - k*res.Stddev
+
+	switch cfg.GetDirection() {
+	case models.AnomalyDirectionUp:
+		res.Deviated = value > res.Upper
+	case models.AnomalyDirectionDown:
+		res.Deviated = value < res.Lower
+	default:
+		res.Deviated = value > res.Upper || value < res.Lower
+	}
+
+	return res
+}
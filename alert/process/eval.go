@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
 )
 
 type ConditionEvaluator func(condition models.EvalCondition) bool
@@ -40,3 +41,36 @@ func EvalCondition(ec models.EvalCondition) bool {
 
 	return evaluator(ec)
 }
+
+// EvalMetricsCondition 评估多指标聚合的组合条件, 未配置评估条件的指标不参与评估
+func EvalMetricsCondition(metrics []models.EsAggregationMetric, condition models.EsFilterCondition, getValue func(name string) float64) (bool, error) {
+	var evaluated int
+	for _, metric := range metrics {
+		if metric.Condition == "" {
+			continue
+		}
+
+		operator, value, err := tools.ProcessRuleExpr(metric.Condition)
+		if err != nil {
+			return false, fmt.Errorf("指标 %s 的评估条件错误: %s", metric.Name, err.Error())
+		}
+
+		evaluated++
+		matched := EvalCondition(models.EvalCondition{
+			Operator:      operator,
+			QueryValue:    getValue(metric.Name),
+			ExpectedValue: value,
+		})
+		if condition == models.EsFilterConditionOr && matched {
+			return true, nil
+		}
+		if condition != models.EsFilterConditionOr && !matched {
+			return false, nil
+		}
+	}
+
+	if evaluated == 0 {
+		return false, fmt.Errorf("多指标聚合未配置评估条件")
+	}
+	return condition != models.EsFilterConditionOr, nil
+}
@@ -6,11 +6,14 @@ import (
 	"golang.org/x/sync/errgroup"
 	"strings"
 	"time"
+	"watchAlert/alert/enrich"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/logger"
 	"watchAlert/pkg/sender"
 	"watchAlert/pkg/templates"
 	"watchAlert/pkg/tools"
+	"watchAlert/pkg/tracing"
 )
 
 // HandleAlert 处理告警逻辑
@@ -24,6 +27,8 @@ func HandleAlert(ctx *ctx.Context, faultCenter models.FaultCenter, noticeId stri
 		logc.Error(ctx.Ctx, fmt.Sprintf("Failed to get notice data: %v", err))
 		return err
 	}
+	// 标签丰富配置每批次仅查询一次, 避免在每条通知的发送路径上查询数据库
+	enrichConfig := getEnrichConfig(ctx)
 
 	// 按告警等级分组
 	severityGroups := make(map[string][]*models.AlertCurEvent)
@@ -39,42 +44,15 @@ func HandleAlert(ctx *ctx.Context, faultCenter models.FaultCenter, noticeId stri
 				return nil
 			}
 
-			// 获取当前事件等级对应的 Hook 和 Sign
-			Hook, Sign := getNoticeHookUrlAndSign(noticeData, severity)
-
 			for _, event := range events {
+				// 通知日志及 Span 关联到告警评估时的链路
+				ctx := ctx.WithContext(tracing.WithTraceParent(logger.WithTraceId(ctx.Ctx, event.TraceId), event.TraceParent))
 				if !event.IsRecovered {
 					event.LastSendTime = curTime
-					ctx.Redis.Alert().PushAlertEvent(event)
+					ctx.Redis.Alert().SetLastSendTime(event.TenantId, event.FaultCenterId, event.Fingerprint, curTime)
 				}
 
-				phoneNumber := func() []string {
-					if len(event.DutyUserPhoneNumber) > 0 {
-						return event.DutyUserPhoneNumber
-					}
-					if len(noticeData.PhoneNumber) > 0 {
-						return noticeData.PhoneNumber
-					}
-					return []string{}
-				}()
-
-				event.DutyUser = GetDutyUser(ctx, noticeData)
-				event.DutyUserPhoneNumber = GetDutyUserPhoneNumber(ctx, noticeData)
-				content := generateAlertContent(ctx, event, noticeData)
-				return sender.Sender(ctx, sender.SendParams{
-					TenantId:    event.TenantId,
-					RuleName:    event.RuleName,
-					Severity:    event.Severity,
-					NoticeType:  noticeData.NoticeType,
-					NoticeId:    noticeId,
-					NoticeName:  noticeData.Name,
-					IsRecovered: event.IsRecovered,
-					Hook:        Hook,
-					Email:       getNoticeEmail(noticeData, severity),
-					Content:     content,
-					PhoneNumber: phoneNumber,
-					Sign:        Sign,
-				})
+				return sender.Sender(ctx, newSendParams(ctx, noticeData, severity, event, enrichConfig))
 			}
 			return nil
 		})
@@ -83,6 +61,100 @@ func HandleAlert(ctx *ctx.Context, faultCenter models.FaultCenter, noticeId stri
 	return g.Wait()
 }
 
+// newSendParams 根据通知对象及事件等级生成发送参数, 渲染通知模版
+func newSendParams(ctx *ctx.Context, noticeData models.AlertNotice, severity string, event *models.AlertCurEvent, enrichConfig models.EnrichConfig) sender.SendParams {
+	// 获取当前事件等级对应的 Hook 和 Sign
+	Hook, Sign := getNoticeHookUrlAndSign(noticeData, severity)
+
+	phoneNumber := func() []string {
+		if len(event.DutyUserPhoneNumber) > 0 {
+			return event.DutyUserPhoneNumber
+		}
+		if len(noticeData.PhoneNumber) > 0 {
+			return noticeData.PhoneNumber
+		}
+		return []string{}
+	}()
+
+	event.DutyUser = GetDutyUser(ctx, noticeData)
+	event.DutyUserPhoneNumber = GetDutyUserPhoneNumber(ctx, noticeData)
+	event.Metric = enrich.Labels(ctx.Ctx, enrichConfig, event.Metric)
+	content := generateAlertContent(ctx, event, noticeData)
+	return sender.SendParams{
+		TenantId:    event.TenantId,
+		RuleName:    event.RuleName,
+		Severity:    event.Severity,
+		NoticeType:  noticeData.NoticeType,
+		NoticeId:    noticeData.Uuid,
+		NoticeName:  noticeData.Name,
+		IsRecovered: event.IsRecovered,
+		Hook:        Hook,
+		Email:       getNoticeEmail(noticeData, severity),
+		Content:     content,
+		PhoneNumber: phoneNumber,
+		Sign:        Sign,
+		Telegram:    noticeData.Telegram,
+		PagerDuty:   noticeData.PagerDuty,
+		OpsGenie:    noticeData.OpsGenie,
+		WebHook:     noticeData.WebHook,
+		Slack:       noticeData.Slack,
+		Fingerprint: event.Fingerprint,
+		LogSamples:  event.LogSamples,
+		RateLimit:   noticeData.RateLimit,
+	}
+}
+
+// SendToNotice 通过指定的通知对象发送单条告警, 用于用户订阅的个人通知, 不影响原事件的值班人员等信息
+func SendToNotice(ctx *ctx.Context, noticeId string, alert models.AlertCurEvent) error {
+	noticeData, err := getNoticeData(ctx, alert.TenantId, noticeId)
+	if err != nil {
+		return fmt.Errorf("获取通知对象失败, noticeId: %s, err: %s", noticeId, err.Error())
+	}
+
+	event := alert
+	return sender.Sender(ctx, newSendParams(ctx, noticeData, event.Severity, &event, getEnrichConfig(ctx)))
+}
+
+// getEnrichConfig 获取告警标签丰富配置, 通知前合并 CMDB 查询到的标签; 查询失败时仅记录日志, 本次通知不丰富标签
+func getEnrichConfig(ctx *ctx.Context) models.EnrichConfig {
+	setting, err := ctx.DB.Setting().Get()
+	if err != nil {
+		logc.Error(ctx.Ctx, fmt.Sprintf("获取标签丰富配置失败, err: %s", err.Error()))
+		return models.EnrichConfig{}
+	}
+	return setting.EnrichConfig
+}
+
+// SendTestNotice 通过通知对象发送一条测试告警, 与告警通知使用相同的模版渲染及发送逻辑, 返回渲染后的通知内容
+func SendTestNotice(ctx *ctx.Context, noticeData models.AlertNotice, severity string, isRecovered bool) (string, error) {
+	if severity == "" {
+		severity = "P2"
+	}
+
+	curTime := time.Now()
+	event := &models.AlertCurEvent{
+		TenantId:         noticeData.TenantId,
+		RuleId:           "test",
+		RuleName:         "WatchAlert 测试告警",
+		DatasourceType:   "Test",
+		Fingerprint:      "test-" + noticeData.Uuid,
+		Severity:         severity,
+		Metric:           map[string]interface{}{"alertname": "WatchAlert 测试告警", "instance": "watchalert-test", "severity": severity},
+		Annotations:      "这是一条测试通知, 用于验证通知对象配置是否可用, 请忽略",
+		IsRecovered:      isRecovered,
+		FirstTriggerTime: curTime.Unix(),
+		LastEvalTime:     curTime.Unix(),
+		Status:           models.StateAlerting,
+	}
+	if isRecovered {
+		event.RecoverTime = curTime.Unix()
+		event.Status = models.StateRecovered
+	}
+
+	params := newSendParams(ctx, noticeData, severity, event, getEnrichConfig(ctx))
+	return params.Content, sender.SendTest(ctx, params)
+}
+
 // alarmAggregation 告警聚合
 func alarmAggregation(ctx *ctx.Context, faultCenter models.FaultCenter, alertGroups map[string][]*models.AlertCurEvent) map[string][]*models.AlertCurEvent {
 	curTime := time.Now().Unix()
@@ -92,6 +164,10 @@ func alarmAggregation(ctx *ctx.Context, faultCenter models.FaultCenter, alertGro
 		for severity, events := range alertGroups {
 			newAlertGroups[severity] = withRuleGroupByAlerts(ctx, curTime, events)
 		}
+	case models.AggregationTypeLabels:
+		for severity, events := range alertGroups {
+			newAlertGroups[severity] = withLabelsGroupByAlerts(ctx, curTime, events)
+		}
 	default:
 		return alertGroups
 	}
@@ -115,13 +191,59 @@ func withRuleGroupByAlerts(ctx *ctx.Context, timeInt int64, alerts []*models.Ale
 
 		if !alert.IsRecovered {
 			alert.LastSendTime = timeInt
-			ctx.Redis.Alert().PushAlertEvent(alert)
+			ctx.Redis.Alert().SetLastSendTime(alert.TenantId, alert.FaultCenterId, alert.Fingerprint, timeInt)
 		}
 	}
 
 	return []*models.AlertCurEvent{aggregatedAlert}
 }
 
+// maxGroupInstances 分组聚合通知中最多展示的实例数
+const maxGroupInstances = 10
+
+// withLabelsGroupByAlerts 按标签分组聚合, 同一分组的告警合并为一条通知, 并附带告警数量及受影响的实例
+func withLabelsGroupByAlerts(ctx *ctx.Context, timeInt int64, alerts []*models.AlertCurEvent) []*models.AlertCurEvent {
+	if len(alerts) <= 1 {
+		return alerts
+	}
+
+	var instances []string
+	seen := make(map[string]struct{})
+	for _, alert := range alerts {
+		if !alert.IsRecovered {
+			alert.LastSendTime = timeInt
+			ctx.Redis.Alert().SetLastSendTime(alert.TenantId, alert.FaultCenterId, alert.Fingerprint, timeInt)
+		}
+
+		instance, ok := alert.Metric["instance"]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%v", instance)
+		if _, exists := seen[key]; !exists {
+			seen[key] = struct{}{}
+			instances = append(instances, key)
+		}
+	}
+
+	// 复制一份作为通知内容, 避免聚合信息写入缓存中的事件
+	aggregatedAlert := *alerts[len(alerts)-1]
+	aggregatedAlert.Annotations += fmt.Sprintf("\n聚合 %d 条告警\n", len(alerts))
+	if len(instances) > 0 {
+		shown := instances
+		if len(shown) > maxGroupInstances {
+			shown = shown[:maxGroupInstances]
+		}
+		aggregatedAlert.Annotations += fmt.Sprintf("受影响实例 (%d): %s", len(instances), strings.Join(shown, ", "))
+		if len(instances) > maxGroupInstances {
+			aggregatedAlert.Annotations += " ..."
+		}
+		aggregatedAlert.Annotations += "\n"
+	}
+
+	return []*models.AlertCurEvent{&aggregatedAlert}
+}
+
 // getNoticeData 获取 Notice 数据
 func getNoticeData(ctx *ctx.Context, tenantId, noticeId string) (models.AlertNotice, error) {
 	return ctx.DB.Notice().Get(models.NoticeQuery{
@@ -148,9 +270,10 @@ func getNoticeEmail(notice models.AlertNotice, severity string) models.Email {
 		for _, route := range notice.Routes {
 			if route.Severity == severity {
 				return models.Email{
-					Subject: notice.Email.Subject,
-					To:      route.To,
-					CC:      route.CC,
+					Subject:    notice.Email.Subject,
+					To:         route.To,
+					CC:         route.CC,
+					AttachLogs: notice.Email.AttachLogs,
 				}
 			}
 		}
@@ -161,7 +284,19 @@ func getNoticeEmail(notice models.AlertNotice, severity string) models.Email {
 // generateAlertContent 生成告警内容
 func generateAlertContent(ctx *ctx.Context, alert *models.AlertCurEvent, noticeData models.AlertNotice) string {
 	if noticeData.NoticeType == "CustomHook" {
-		return tools.JsonMarshal(alert)
+		return GenerateWebHookContent(ctx, alert, noticeData)
 	}
 	return templates.NewTemplate(ctx, *alert, noticeData).CardContentMsg
 }
+
+// GenerateWebHookContent 生成自定义 Hook 请求体, 未配置模版或渲染失败时发送告警事件 JSON
+func GenerateWebHookContent(ctx *ctx.Context, data interface{}, noticeData models.AlertNotice) string {
+	if noticeData.WebHook.BodyTemplate != "" {
+		content, err := templates.RenderWebHookBody(noticeData.WebHook.BodyTemplate, data)
+		if err == nil {
+			return content
+		}
+		logc.Error(ctx.Ctx, fmt.Sprintf("自定义 Hook 请求体渲染失败, noticeId: %s, err: %s", noticeData.Uuid, err.Error()))
+	}
+	return tools.JsonMarshal(data)
+}
@@ -6,23 +6,37 @@ import (
 	"watchAlert/alert/mute"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/logger"
 	"watchAlert/pkg/tools"
+	"watchAlert/pkg/tracing"
+
+	"github.com/zeromicro/go-zero/core/logc"
 )
 
+// BuildEvent 构建告警事件, 标签按规则的标签重写规则处理
 func BuildEvent(rule models.AlertRule, metric func() map[string]interface{}) models.AlertCurEvent {
+	labels, keep := Relabel(metric(), rule.RelabelConfigs)
+	if labels == nil {
+		labels = map[string]interface{}{}
+	}
+
 	return models.AlertCurEvent{
 		TenantId:             rule.TenantId,
 		DatasourceType:       rule.DatasourceType,
 		RuleId:               rule.RuleId,
 		RuleName:             rule.RuleName,
-		Metric:               metric(),
+		Metric:               labels,
 		EvalInterval:         rule.EvalInterval,
-		ForDuration:          rule.PrometheusConfig.ForDuration,
+		ForDuration:          rule.GetForDuration(),
 		IsRecovered:          false,
 		RepeatNoticeInterval: rule.RepeatNoticeInterval,
 		Severity:             rule.Severity,
 		EffectiveTime:        rule.EffectiveTime,
 		FaultCenterId:        rule.FaultCenterId,
+		MessageTemplate:      rule.MessageTemplate,
+		EscalationPolicy:     rule.EscalationPolicy,
+		RecoverNotify:        rule.RecoverNotify,
+		RelabelDropped:       !keep,
 	}
 }
 
@@ -35,11 +49,24 @@ func PushEventToFaultCenter(ctx *ctx.Context, event *models.AlertCurEvent) {
 
 	cache := ctx.Redis
 
+	// 被标签重写规则丢弃的事件不再告警, 移除规则调整前已产生的事件
+	if event.RelabelDropped {
+		if cache.Alert().GetEventStatus(event.TenantId, event.FaultCenterId, event.Fingerprint) != "" {
+			logc.Info(ctx.Ctx, fmt.Sprintf("事件被标签重写规则丢弃, 移除告警事件, Rule: %s, Fingerprint: %s", event.RuleName, event.Fingerprint))
+			cache.Alert().RemoveAlertEvent(event.TenantId, event.FaultCenterId, event.Fingerprint)
+		}
+		return
+	}
+
 	// 获取基础信息
+	event.TraceId = logger.GetTraceId(ctx.Ctx)
+	event.TraceParent = tracing.TraceParent(ctx.Ctx)
 	event.FirstTriggerTime = cache.Alert().GetFirstTime(event.TenantId, event.FaultCenterId, event.Fingerprint)
 	event.LastEvalTime = cache.Alert().GetLastEvalTime()
 	event.LastSendTime = cache.Alert().GetLastSendTime(event.TenantId, event.FaultCenterId, event.Fingerprint)
 	event.UpgradeState = cache.Alert().GetLastUpgradeState(event.TenantId, event.FaultCenterId, event.Fingerprint)
+	event.LastSeenTime = event.LastEvalTime
+	event.OccurrenceCount = cache.Alert().GetOccurrenceCount(event.TenantId, event.FaultCenterId, event.Fingerprint) + 1
 	event.FaultCenter = cache.FaultCenter().GetFaultCenterInfo(models.BuildFaultCenterInfoCacheKey(event.TenantId, event.FaultCenterId))
 
 	// 获取当前缓存中的状态
@@ -77,6 +104,8 @@ func PushEventToFaultCenter(ctx *ctx.Context, event *models.AlertCurEvent) {
 		}
 	}
 
+	RecordTransition(ctx, *event, string(currentStatus), string(event.Status), models.TransitionActorSystem)
+
 	// 更新缓存
 	cache.Alert().PushAlertEvent(event)
 }
@@ -114,15 +143,15 @@ func getRecoverWaitList(ctx *ctx.Context, rule models.AlertRule) []string {
 }
 
 func GetDutyUser(ctx *ctx.Context, noticeData models.AlertNotice) string {
-	user, ok := ctx.DB.DutyCalendar().GetDutyUserInfo(noticeData.DutyId, time.Now().Format("2006-1-2"))
+	user, ok := ctx.DB.DutyCalendar().GetOnCallUser(noticeData.TenantId, noticeData.DutyId, time.Now())
 	if ok {
 		switch noticeData.NoticeType {
 		case "FeiShu":
 			return fmt.Sprintf("<at id=%s></at>", user.DutyUserId)
 		case "DingDing":
 			return fmt.Sprintf("%s", user.DutyUserId)
-		case "Email", "WeChat", "CustomHook":
-			return fmt.Sprintf("@%s", user.UserName)
+		case "Email", "WeChat", "CustomHook", "Telegram", "Teams", "Slack":
+			return fmt.Sprintf("@%s", user.Username)
 		}
 	}
 
@@ -131,13 +160,18 @@ func GetDutyUser(ctx *ctx.Context, noticeData models.AlertNotice) string {
 
 // GetDutyUserPhoneNumber 获取当班人员手机号
 func GetDutyUserPhoneNumber(ctx *ctx.Context, noticeData models.AlertNotice) []string {
-	user, ok := ctx.DB.DutyCalendar().GetDutyUserInfo(noticeData.DutyId, time.Now().Format("2006-1-2"))
+	user, ok := ctx.DB.DutyCalendar().GetOnCallUser(noticeData.TenantId, noticeData.DutyId, time.Now())
 	if ok {
 		switch noticeData.NoticeType {
 		case "PhoneCall":
 			if len(user.DutyUserId) > 1 {
 				return []string{user.Phone}
 			}
+		case "DingDing", "WeChat":
+			// 钉钉、企业微信通过手机号 @ 值班人员
+			if user.Phone != "" {
+				return []string{user.Phone}
+			}
 		}
 	}
 	return []string{}
@@ -164,6 +198,8 @@ func RecordAlertHisEvent(ctx *ctx.Context, alert models.AlertCurEvent) error {
 		RecoverTime:      alert.RecoverTime,
 		FaultCenterId:    alert.FaultCenterId,
 		UpgradeState:     alert.UpgradeState,
+		LastSeenTime:     alert.LastSeenTime,
+		OccurrenceCount:  alert.OccurrenceCount,
 	}
 
 	err := ctx.DB.Event().CreateHistoryEvent(hisData)
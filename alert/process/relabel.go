@@ -0,0 +1,107 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"watchAlert/internal/models"
+)
+
+// relabelReservedLabels 由评估流程生成的标签, 不受标签重写规则影响
+var relabelReservedLabels = []string{"severity", "fingerprint", "rule_name", "value", "recover_value"}
+
+// Relabel 按顺序执行标签重写规则, 返回重写后的标签, 事件被 keep/drop 规则丢弃时返回 false;
+// 规则在保存时已校验, 此处正则编译失败的规则直接跳过
+func Relabel(labels map[string]interface{}, configs []models.RelabelConfig) (map[string]interface{}, bool) {
+	if len(configs) == 0 {
+		return labels, true
+	}
+
+	result := make(map[string]interface{}, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+
+	for _, cfg := range configs {
+		regex, err := cfg.CompileRegex()
+		if err != nil {
+			continue
+		}
+
+		var values []string
+		for _, name := range cfg.SourceLabels {
+			values = append(values, labelValue(result[name]))
+		}
+		value := strings.Join(values, cfg.GetSeparator())
+
+		switch cfg.GetAction() {
+		case models.RelabelReplace:
+			indexes := regex.FindStringSubmatchIndex(value)
+			if indexes == nil {
+				continue
+			}
+			target := string(regex.ExpandString(nil, cfg.TargetLabel, value, indexes))
+			replaced := string(regex.ExpandString(nil, cfg.GetReplacement(), value, indexes))
+			if target == "" || isReservedLabel(target) {
+				continue
+			}
+			if replaced == "" {
+				delete(result, target)
+				continue
+			}
+			result[target] = replaced
+		case models.RelabelKeep:
+			if !regex.MatchString(value) {
+				return nil, false
+			}
+		case models.RelabelDrop:
+			if regex.MatchString(value) {
+				return nil, false
+			}
+		case models.RelabelLabelMap:
+			mapped := make(map[string]interface{})
+			for name, v := range result {
+				if isReservedLabel(name) || !regex.MatchString(name) {
+					continue
+				}
+				if target := regex.ReplaceAllString(name, cfg.GetReplacement()); target != "" && !isReservedLabel(target) {
+					mapped[target] = v
+				}
+			}
+			for name, v := range mapped {
+				result[name] = v
+			}
+		case models.RelabelLabelDrop:
+			for name := range result {
+				if !isReservedLabel(name) && regex.MatchString(name) {
+					delete(result, name)
+				}
+			}
+		case models.RelabelLabelKeep:
+			for name := range result {
+				if !isReservedLabel(name) && !regex.MatchString(name) {
+					delete(result, name)
+				}
+			}
+		}
+	}
+	return result, true
+}
+
+func isReservedLabel(name string) bool {
+	for _, reserved := range relabelReservedLabels {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+func labelValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
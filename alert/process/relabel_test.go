@@ -0,0 +1,112 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestRelabel(t *testing.T) {
+	labels := map[string]interface{}{
+		"instance":    "10.0.0.1:9100",
+		"job":         "node",
+		"__meta_zone": "cn-east",
+		"severity":    "P1",
+		"code":        500,
+	}
+
+	tests := []struct {
+		name     string
+		configs  []models.RelabelConfig
+		want     map[string]interface{}
+		wantKeep bool
+	}{
+		{name: "no configs", want: labels, wantKeep: true},
+		{
+			name:     "replace with capture group",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"instance"}, Regex: `([^:]+):\d+`, TargetLabel: "host"}},
+			want:     map[string]interface{}{"instance": "10.0.0.1:9100", "job": "node", "__meta_zone": "cn-east", "severity": "P1", "code": 500, "host": "10.0.0.1"},
+			wantKeep: true,
+		},
+		{
+			name:     "replace joins source labels with separator",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"job", "code"}, Separator: "/", TargetLabel: "key"}},
+			want:     map[string]interface{}{"instance": "10.0.0.1:9100", "job": "node", "__meta_zone": "cn-east", "severity": "P1", "code": 500, "key": "node/500"},
+			wantKeep: true,
+		},
+		{
+			name:     "replace without full match is skipped",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"instance"}, Regex: `10\.0`, TargetLabel: "host"}},
+			want:     labels,
+			wantKeep: true,
+		},
+		{
+			name:     "replace ignores reserved target",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"job"}, TargetLabel: "severity"}},
+			want:     labels,
+			wantKeep: true,
+		},
+		{
+			name:     "keep matching",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"job"}, Regex: "node|mysql", Action: models.RelabelKeep}},
+			want:     labels,
+			wantKeep: true,
+		},
+		{
+			name:    "keep not matching drops event",
+			configs: []models.RelabelConfig{{SourceLabels: []string{"job"}, Regex: "mysql", Action: models.RelabelKeep}},
+		},
+		{
+			name:    "drop matching drops event",
+			configs: []models.RelabelConfig{{SourceLabels: []string{"job"}, Regex: "node", Action: models.RelabelDrop}},
+		},
+		{
+			name:     "labelmap copies matching label names",
+			configs:  []models.RelabelConfig{{Regex: "__meta_(.+)", Action: models.RelabelLabelMap}},
+			want:     map[string]interface{}{"instance": "10.0.0.1:9100", "job": "node", "__meta_zone": "cn-east", "severity": "P1", "code": 500, "zone": "cn-east"},
+			wantKeep: true,
+		},
+		{
+			name:     "labeldrop keeps reserved labels",
+			configs:  []models.RelabelConfig{{Regex: "__meta_.*|severity", Action: models.RelabelLabelDrop}},
+			want:     map[string]interface{}{"instance": "10.0.0.1:9100", "job": "node", "severity": "P1", "code": 500},
+			wantKeep: true,
+		},
+		{
+			name:     "labelkeep keeps reserved labels",
+			configs:  []models.RelabelConfig{{Regex: "job", Action: models.RelabelLabelKeep}},
+			want:     map[string]interface{}{"job": "node", "severity": "P1"},
+			wantKeep: true,
+		},
+		{
+			name: "rules apply in order",
+			configs: []models.RelabelConfig{
+				{SourceLabels: []string{"instance"}, Regex: `([^:]+):\d+`, TargetLabel: "host"},
+				{Regex: "host", Action: models.RelabelLabelKeep},
+			},
+			want:     map[string]interface{}{"host": "10.0.0.1", "severity": "P1"},
+			wantKeep: true,
+		},
+		{
+			name:     "invalid regex is skipped",
+			configs:  []models.RelabelConfig{{SourceLabels: []string{"job"}, Regex: "(", Action: models.RelabelDrop}},
+			want:     labels,
+			wantKeep: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, keep := Relabel(labels, tt.configs)
+			if keep != tt.wantKeep {
+				t.Fatalf("Relabel() keep = %v, want %v", keep, tt.wantKeep)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Relabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := labels["host"]; ok {
+		t.Error("Relabel() must not modify the input labels")
+	}
+}
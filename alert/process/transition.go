@@ -0,0 +1,46 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"sync"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/tools"
+)
+
+// transitionWg 未写入完成的状态变更记录
+var transitionWg sync.WaitGroup
+
+// WaitTransitions 服务退出时等待状态变更记录写入完成
+func WaitTransitions(ctx context.Context) error {
+	return tools.WaitWithContext(ctx, &transitionWg)
+}
+
+// RecordTransition 异步记录告警状态变更, 状态未变化时不记录, 写入失败不影响告警处理
+func RecordTransition(ctx *ctx.Context, event models.AlertCurEvent, from, to, actor string) {
+	if from == to {
+		return
+	}
+
+	// 复制标签, 避免记录写入前事件被修改
+	metric := make(map[string]interface{}, len(event.Metric))
+	for k, v := range event.Metric {
+		metric[k] = v
+	}
+	event.Metric = metric
+
+	transition := models.NewAlertTransition(event, from, to, actor)
+	transition.ID = "at-" + tools.RandId()
+	transition.CreatedAt = time.Now().Unix()
+
+	transitionWg.Add(1)
+	go func() {
+		defer transitionWg.Done()
+		if err := ctx.DB.Event().CreateTransition(transition); err != nil {
+			logc.Error(ctx.Ctx, fmt.Sprintf("记录告警状态变更失败, rule: %s, fingerprint: %s, err: %s", transition.RuleName, transition.Fingerprint, err.Error()))
+		}
+	}()
+}
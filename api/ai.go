@@ -14,6 +14,8 @@ func (a AiController) API(gin *gin.RouterGroup) {
 	ai.Use(
 		middleware.Cors(),
 		middleware.Auth(),
+		middleware.Permission(),
+		middleware.ParseTenant(),
 	)
 	{
 		ai.POST("chat", a.Chat)
@@ -28,6 +30,9 @@ func (a AiController) Chat(ctx *gin.Context) {
 	r.Deep = ctx.PostForm("deep")
 	r.SearchQL = ctx.PostForm("search_ql")
 
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.AiService.Chat(r)
 	})
@@ -14,6 +14,7 @@ func (ac AuditLogController) API(gin *gin.RouterGroup) {
 	auditLog.Use(
 		middleware.Cors(),
 		middleware.Auth(),
+		middleware.Permission(),
 		middleware.ParseTenant(),
 	)
 	{
@@ -28,6 +28,9 @@ func (a AWSCloudWatchRDSController) API(gin *gin.RouterGroup) {
 func (a AWSCloudWatchRDSController) GetRdsInstanceIdentifier(ctx *gin.Context) {
 	req := new(types.RdsInstanceReq)
 	BindQuery(ctx, req)
+
+	tid, _ := ctx.Get("TenantID")
+	req.TenantId = tid.(string)
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.AWSCloudWatchRdsService.GetDBInstanceIdentifier(req)
 	})
@@ -36,6 +39,9 @@ func (a AWSCloudWatchRDSController) GetRdsInstanceIdentifier(ctx *gin.Context) {
 func (a AWSCloudWatchRDSController) GetRdsClusterIdentifier(ctx *gin.Context) {
 	req := new(types.RdsClusterReq)
 	BindQuery(ctx, req)
+
+	tid, _ := ctx.Get("TenantID")
+	req.TenantId = tid.(string)
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.AWSCloudWatchRdsService.GetDBClusterIdentifier(req)
 	})
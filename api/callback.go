@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"html/template"
 	"log"
+	"net/http"
+	"time"
 	"watchAlert/internal/global"
+	"watchAlert/internal/models"
+	"watchAlert/internal/services"
 	"watchAlert/pkg/tools"
 )
 
@@ -24,10 +29,64 @@ func (cc *CallbackController) FeiShuEvent(ctx *gin.Context) {
 
 	jsonData, _ := json.Marshal(challengeInfo)
 	body := bytes.NewReader(jsonData)
-	_, err := tools.Post(nil, "http://127.0.0.1:"+global.Config.Server.Port+"/api/v1/alert/createSilence?uuid="+uuid, body, 10)
+	_, err := tools.Post(nil, "http://127.0.0.1:"+global.GetConfig().Server.Port+"/api/v1/alert/createSilence?uuid="+uuid, body, 10)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
 }
+
+// ackConfirmPage 认领确认页面, 链接预览及安全扫描等仅发起 GET 请求, 不会触发认领
+var ackConfirmPage = template.Must(template.New("ack").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>认领告警</title></head>
+<body>
+<p>确认认领告警 {{.Fingerprint}} ?</p>
+<form method="POST" action="">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">确认认领</button>
+</form>
+</body>
+</html>`))
+
+// AckAlertConfirm 通知卡片中的认领链接, 校验 Token 后返回确认页面, 由页面提交 POST 请求完成认领
+func (cc *CallbackController) AckAlertConfirm(ctx *gin.Context) {
+	token := ctx.Query("token")
+	claims, err := tools.ParseAlertActionToken(token)
+	if err != nil {
+		ctx.String(http.StatusForbidden, "认领失败, 链接无效或已过期: %s", err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ackConfirmPage.Execute(&buf, map[string]string{"Fingerprint": claims.Fingerprint, "Token": token}); err != nil {
+		ctx.String(http.StatusInternalServerError, "渲染确认页面失败: %s", err.Error())
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// AckAlert 认领确认页面提交的认领请求
+func (cc *CallbackController) AckAlert(ctx *gin.Context) {
+	claims, err := tools.ParseAlertActionToken(ctx.PostForm("token"))
+	if err != nil {
+		ctx.String(http.StatusForbidden, "认领失败, 链接无效或已过期: %s", err.Error())
+		return
+	}
+
+	_, errMsg := services.EventService.ProcessAlertEvent(&models.ProcessAlertEvent{
+		TenantId:      claims.TenantId,
+		State:         1,
+		FaultCenterId: claims.FaultCenterId,
+		Fingerprints:  []string{claims.Fingerprint},
+		Time:          time.Now().Unix(),
+		Username:      "飞书卡片",
+	})
+	if errMsg != nil {
+		ctx.String(http.StatusInternalServerError, "认领失败: %v", errMsg)
+		return
+	}
+
+	ctx.String(http.StatusOK, "告警已认领")
+}
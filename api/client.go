@@ -25,6 +25,9 @@ func (cc ClientController) GetJaegerService(ctx *gin.Context) {
 	r := new(models.DatasourceQuery)
 	BindQuery(ctx, r)
 
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.ClientService.GetJaegerService(r)
 	})
@@ -17,6 +17,7 @@ func (di DashboardInfoController) API(gin *gin.RouterGroup) {
 	system := gin.Group("system")
 	system.Use(
 		middleware.Auth(),
+		middleware.Permission(),
 		middleware.ParseTenant(),
 	)
 	{
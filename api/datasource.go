@@ -48,7 +48,11 @@ func (dc DatasourceController) API(gin *gin.RouterGroup) {
 		datasourceB.GET("dataSourceSearch", dc.Search)
 		datasourceB.GET("promQuery", dc.PromQuery)
 		datasourceB.POST("dataSourcePing", dc.Ping)
+		datasourceB.GET("dataSourceRetryStats", dc.RetryStats)
+		datasourceB.GET("dataSourceBreakerStates", dc.BreakerStates)
 		datasourceB.POST("searchViewLogsContent", dc.SearchViewLogsContent)
+		datasourceB.GET("dataSourceFields", dc.Fields)
+		datasourceB.GET("dataSourceIndices", dc.Indices)
 	}
 
 }
@@ -129,6 +133,9 @@ func (dc DatasourceController) PromQuery(ctx *gin.Context) {
 	r := new(models.PromQueryReq)
 	BindQuery(ctx, r)
 
+	tid, _ := ctx.Get("TenantID")
+	tenantId := tid.(string)
+
 	Service(ctx, func() (interface{}, interface{}) {
 		var ress []provider.QueryResponse
 		path := "/api/v1/query"
@@ -145,7 +152,8 @@ func (dc DatasourceController) PromQuery(ctx *gin.Context) {
 		for _, id := range ids {
 			var res provider.QueryResponse
 			source, err := ctx2.DO().DB.Datasource().Get(models.DatasourceQuery{
-				Id: id,
+				TenantId: tenantId,
+				Id:       id,
 			})
 			if err != nil {
 				return nil, err
@@ -171,8 +179,18 @@ func (dc DatasourceController) Ping(ctx *gin.Context) {
 	r := new(models.AlertDataSource)
 	BindJson(ctx, r)
 
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
 	Service(ctx, func() (interface{}, interface{}) {
-		ok, err := provider.CheckDatasourceHealth(*r)
+		// 测试成功时按数据源 ID 关闭熔断, 仅允许关闭当前租户的数据源
+		if r.Id != "" {
+			if _, err := services.DatasourceService.Get(&models.DatasourceQuery{TenantId: r.TenantId, Id: r.Id}); err != nil {
+				r.Id = ""
+			}
+		}
+		// 手动测试不受熔断限制, 测试成功时关闭熔断
+		ok, err := provider.CheckDatasourceHealth(provider.WithBreakerBypass(ctx.Request.Context()), *r)
 		if !ok {
 			return "", fmt.Errorf("数据源不可达, err: %s", err.Error())
 		}
@@ -180,14 +198,70 @@ func (dc DatasourceController) Ping(ctx *gin.Context) {
 	})
 }
 
+// RetryStats 各数据源查询及健康检查的重试统计, 重试次数持续增长说明数据源不稳定
+func (dc DatasourceController) RetryStats(ctx *gin.Context) {
+	r := new(models.DatasourceQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DatasourceService.RetryStats(r)
+	})
+}
+
+// BreakerStates 各数据源的熔断状态, open 表示数据源连续失败, 冷却期内的查询直接失败
+func (dc DatasourceController) BreakerStates(ctx *gin.Context) {
+	r := new(models.DatasourceQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DatasourceService.BreakerStates(r)
+	})
+}
+
+// Fields 日志数据源索引映射中的字段, 用于规则编辑时的字段自动补全
+func (dc DatasourceController) Fields(ctx *gin.Context) {
+	r := new(models.DatasourceSchemaQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DatasourceService.ListFields(r)
+	})
+}
+
+// Indices 日志数据源中匹配的索引, 用于规则编辑时的索引自动补全
+func (dc DatasourceController) Indices(ctx *gin.Context) {
+	r := new(models.DatasourceSchemaQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DatasourceService.ListIndices(r)
+	})
+}
+
 // SearchViewLogsContent Logs 数据预览
 func (dc DatasourceController) SearchViewLogsContent(ctx *gin.Context) {
 	r := new(models.SearchLogsContentReq)
 	BindJson(ctx, r)
 
+	tid, _ := ctx.Get("TenantID")
+	tenantId := tid.(string)
+
 	Service(ctx, func() (interface{}, interface{}) {
 		data, err := services.DatasourceService.Get(&models.DatasourceQuery{
-			Id: r.DatasourceId,
+			TenantId: tenantId,
+			Id:       r.DatasourceId,
 		})
 		if err != nil {
 			return nil, err
@@ -195,10 +269,15 @@ func (dc DatasourceController) SearchViewLogsContent(ctx *gin.Context) {
 
 		datasource := data.(models.AlertDataSource)
 
-		var (
-			client  provider.LogsFactoryProvider
-			options provider.LogQueryOptions
-		)
+		// 按已注册客户端实现的接口判断是否支持日志查询, 新增的日志数据源无需修改此处的客户端创建
+		cli, err := provider.NewClient(ctx, datasource)
+		if err != nil {
+			return nil, err
+		}
+		client, ok := cli.(provider.LogsFactoryProvider)
+		if !ok {
+			return nil, fmt.Errorf("数据源类型 %s 不支持日志查询", datasource.Type)
+		}
 
 		// 使用 base64.StdEncoding 进行解码
 		decodedBytes, err := base64.StdEncoding.DecodeString(r.Query)
@@ -208,24 +287,24 @@ func (dc DatasourceController) SearchViewLogsContent(ctx *gin.Context) {
 		// 将解码后的字节转换为字符串
 		QueryStr := string(decodedBytes)
 
-		switch r.Type {
+		// 查询参数按数据源实际的类型构建
+		var options provider.LogQueryOptions
+		switch datasource.Type {
 		case provider.VictoriaLogsDsProviderName:
-			client, err = provider.NewVictoriaLogsClient(ctx, datasource)
-			if err != nil {
-				return nil, err
-			}
-
 			options = provider.LogQueryOptions{
 				VictoriaLogs: provider.VictoriaLogs{
 					Query: QueryStr,
 				},
 			}
-		case provider.ElasticSearchDsProviderName:
-			client, err = provider.NewElasticSearchClient(ctx, datasource)
-			if err != nil {
-				return nil, err
+		case provider.AliCloudSLSDsProviderName:
+			// Index 为空时使用数据源默认的 LogStore
+			options = provider.LogQueryOptions{
+				AliCloudSLS: provider.AliCloudSLS{
+					Query:    QueryStr,
+					LogStore: r.Index,
+				},
 			}
-
+		case provider.ElasticSearchDsProviderName:
 			options = provider.LogQueryOptions{
 				ElasticSearch: provider.Elasticsearch{
 					Index:     r.GetElasticSearchIndexName(),
@@ -233,6 +312,28 @@ func (dc DatasourceController) SearchViewLogsContent(ctx *gin.Context) {
 					RawJson:   QueryStr,
 				},
 			}
+		case provider.ClickHouseDsProviderName:
+			options = provider.LogQueryOptions{
+				ClickHouse: provider.ClickHouse{
+					QueryType: models.ClickHouseQueryTypeRawSQL,
+					RawSQL:    QueryStr,
+				},
+			}
+		case provider.GraylogDsProviderName:
+			options = provider.LogQueryOptions{
+				Graylog: provider.Graylog{
+					QueryType: models.GraylogQueryTypeRawQuery,
+					Query:     QueryStr,
+				},
+			}
+		case provider.SQLDsProviderName:
+			options = provider.LogQueryOptions{
+				SQL: provider.SQL{
+					Query: QueryStr,
+				},
+			}
+		default:
+			return nil, fmt.Errorf("数据源类型 %s 不支持日志预览", datasource.Type)
 		}
 
 		query, _, err := client.Query(options)
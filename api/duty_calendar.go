@@ -5,6 +5,7 @@ import (
 	middleware "watchAlert/internal/middleware"
 	"watchAlert/internal/models"
 	"watchAlert/internal/services"
+	jwtUtils "watchAlert/pkg/tools"
 )
 
 type DutyCalendarController struct{}
@@ -24,6 +25,8 @@ func (dc DutyCalendarController) API(gin *gin.RouterGroup) {
 	{
 		calendarA.POST("calendarCreate", dc.Create)
 		calendarA.POST("calendarUpdate", dc.Update)
+		calendarA.POST("overrideCreate", dc.CreateOverride)
+		calendarA.POST("overrideDelete", dc.DeleteOverride)
 	}
 
 	calendarB := gin.Group("calendar")
@@ -35,6 +38,8 @@ func (dc DutyCalendarController) API(gin *gin.RouterGroup) {
 	{
 		calendarB.GET("calendarSearch", dc.Search)
 		calendarB.GET("getCalendarUsers", dc.GetCalendarUsers)
+		calendarB.GET("getOnCallUser", dc.GetOnCallUser)
+		calendarB.GET("overrideList", dc.ListOverrides)
 	}
 }
 
@@ -85,3 +90,52 @@ func (dc DutyCalendarController) GetCalendarUsers(ctx *gin.Context) {
 		return services.DutyCalendarService.GetCalendarUsers(r)
 	})
 }
+
+func (dc DutyCalendarController) GetOnCallUser(ctx *gin.Context) {
+	r := new(models.DutyScheduleQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DutyCalendarService.GetOnCallUser(r)
+	})
+}
+
+func (dc DutyCalendarController) CreateOverride(ctx *gin.Context) {
+	r := new(models.DutyOverride)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+	r.CreateBy = jwtUtils.GetUser(ctx.Request.Header.Get("Authorization"))
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DutyCalendarService.CreateOverride(r)
+	})
+}
+
+func (dc DutyCalendarController) DeleteOverride(ctx *gin.Context) {
+	r := new(models.DutyOverrideQuery)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DutyCalendarService.DeleteOverride(r)
+	})
+}
+
+func (dc DutyCalendarController) ListOverrides(ctx *gin.Context) {
+	r := new(models.DutyOverrideQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.DutyCalendarService.ListOverrides(r)
+	})
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"watchAlert/internal/models"
 	"watchAlert/pkg/response"
 )
 
@@ -34,6 +36,7 @@ type ApiGroup struct {
 	ProbingController
 	FaultCenterController
 	AiController
+	ReceiverController
 }
 
 var ApiGroupApp = new(ApiGroup)
@@ -41,6 +44,11 @@ var ApiGroupApp = new(ApiGroup)
 func Service(ctx *gin.Context, fu func() (interface{}, interface{})) {
 	data, err := fu()
 	if err != nil {
+		if errors.Is(err.(error), models.ErrRuleUidConflict) {
+			response.Conflict(ctx, err.(error).Error(), "failed")
+			ctx.Abort()
+			return
+		}
 		response.Fail(ctx, err.(error).Error(), "failed")
 		ctx.Abort()
 		return
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/zeromicro/go-zero/core/logc"
+	"net/http"
 	"time"
 	middleware "watchAlert/internal/middleware"
 	"watchAlert/internal/models"
@@ -27,6 +30,8 @@ func (e AlertEventController) API(gin *gin.RouterGroup) {
 		event.GET("curEvent", e.ListCurrentEvent)
 		event.GET("hisEvent", e.ListHistoryEvent)
 		event.POST("processAlertEvent", e.ProcessAlertEvent)
+		event.GET("transition", e.ListTransition)
+		event.GET("transitionExport", e.ExportTransition)
 	}
 }
 
@@ -74,3 +79,44 @@ func (e AlertEventController) ListHistoryEvent(ctx *gin.Context) {
 		return services.EventService.ListHistoryEvent(r)
 	})
 }
+
+func (e AlertEventController) ListTransition(ctx *gin.Context) {
+	r := new(models.AlertTransitionQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.EventService.ListTransition(r)
+	})
+}
+
+func (e AlertEventController) ExportTransition(ctx *gin.Context) {
+	r := new(models.AlertTransitionExport)
+	BindQuery(ctx, r)
+	if ctx.IsAborted() {
+		return
+	}
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	if err := r.Validate(); err != nil {
+		response.Fail(ctx, err.Error(), "failed")
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if r.Format == models.TransitionExportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=alert-transitions-%d-%d.%s", r.StartAt, r.EndAt, r.Format))
+	ctx.Status(http.StatusOK)
+
+	// 响应已开始写入, 导出失败时只能记录日志
+	if err := services.EventService.ExportTransition(r, ctx.Writer); err != nil {
+		logc.Error(ctx.Request.Context(), fmt.Sprintf("导出告警状态变更记录失败, err: %s", err.Error()))
+	}
+}
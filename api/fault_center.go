@@ -22,6 +22,7 @@ func (fcc FaultCenterController) API(gin *gin.RouterGroup) {
 		faultCenterA.POST("faultCenterUpdate", fcc.Update)
 		faultCenterA.POST("faultCenterDelete", fcc.Delete)
 		faultCenterA.POST("faultCenterReset", fcc.Reset)
+		faultCenterA.POST("faultCenterResetReceiverToken", fcc.ResetReceiverToken)
 	}
 
 	faultCenterB := gin.Group("faultCenter")
@@ -107,3 +108,16 @@ func (fcc FaultCenterController) Reset(ctx *gin.Context) {
 		return services.FaultCenterService.Reset(r)
 	})
 }
+
+// ResetReceiverToken 重新生成故障中心的外部告警接入 Token, 旧 Token 立即失效
+func (fcc FaultCenterController) ResetReceiverToken(ctx *gin.Context) {
+	r := new(models.FaultCenterQuery)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.FaultCenterService.ResetReceiverToken(r)
+	})
+}
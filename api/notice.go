@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"strconv"
 	middleware "watchAlert/internal/middleware"
 	"watchAlert/internal/models"
 	"watchAlert/internal/services"
@@ -25,6 +26,7 @@ func (nc NoticeController) API(gin *gin.RouterGroup) {
 		noticeA.POST("noticeCreate", nc.Create)
 		noticeA.POST("noticeUpdate", nc.Update)
 		noticeA.POST("noticeDelete", nc.Delete)
+		noticeA.POST("noticeTest", nc.Test)
 	}
 
 	noticeB := gin.Group("notice")
@@ -38,6 +40,7 @@ func (nc NoticeController) API(gin *gin.RouterGroup) {
 		noticeB.GET("noticeSearch", nc.Search)
 		noticeB.GET("noticeRecordList", nc.ListRecord)
 		noticeB.GET("noticeRecordMetric", nc.GetRecordMetric)
+		noticeB.GET("noticeFailedList", nc.ListFailedRecord)
 	}
 }
 
@@ -138,6 +141,20 @@ func (nc NoticeController) ListRecord(ctx *gin.Context) {
 	})
 }
 
+// ListFailedRecord 已达到最大尝试次数仍发送失败的通知
+func (nc NoticeController) ListFailedRecord(ctx *gin.Context) {
+	r := new(models.NoticeQuery)
+	BindQuery(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+	r.Status = strconv.Itoa(models.NoticeRecordStatusFailed)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.NoticeService.ListRecord(r)
+	})
+}
+
 func (nc NoticeController) GetRecordMetric(ctx *gin.Context) {
 	r := new(models.NoticeQuery)
 	BindQuery(ctx, r)
@@ -149,3 +166,15 @@ func (nc NoticeController) GetRecordMetric(ctx *gin.Context) {
 		return services.NoticeService.GetRecordMetric(r)
 	})
 }
+
+func (nc NoticeController) Test(ctx *gin.Context) {
+	r := new(models.RequestNoticeTest)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.NoticeService.Test(r)
+	})
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"watchAlert/internal/models"
+	"watchAlert/internal/services"
+)
+
+type ReceiverController struct{}
+
+// Alertmanager 接收 Alertmanager webhook 推送的告警, Token 通过 Authorization: Bearer 请求头或 token 参数传递
+func (rc ReceiverController) Alertmanager(ctx *gin.Context) {
+	r := new(models.AlertmanagerReceiveReq)
+	BindJson(ctx, &r.Webhook)
+
+	r.Token = strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if r.Token == "" {
+		r.Token = ctx.Query("token")
+	}
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.ReceiverService.Alertmanager(r)
+	})
+}
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
 	middleware "watchAlert/internal/middleware"
 	"watchAlert/internal/models"
 	"watchAlert/internal/services"
+	"watchAlert/pkg/response"
 	"watchAlert/pkg/tools"
 )
 
@@ -26,6 +30,8 @@ func (rc RuleController) API(gin *gin.RouterGroup) {
 		ruleA.POST("ruleCreate", rc.Create)
 		ruleA.POST("ruleUpdate", rc.Update)
 		ruleA.POST("ruleDelete", rc.Delete)
+		ruleA.POST("ruleImport", rc.Import)
+		ruleA.POST("rulePromImport", rc.PromImport)
 	}
 	ruleB := gin.Group("rule")
 	ruleB.Use(
@@ -36,6 +42,8 @@ func (rc RuleController) API(gin *gin.RouterGroup) {
 	{
 		ruleB.GET("ruleList", rc.List)
 		ruleB.GET("ruleSearch", rc.Search)
+		ruleB.POST("rulePreview", rc.Preview)
+		ruleB.GET("ruleExport", rc.Export)
 	}
 }
 
@@ -99,3 +107,82 @@ func (rc RuleController) Search(ctx *gin.Context) {
 		return services.RuleService.Search(r)
 	})
 }
+
+func (rc RuleController) Preview(ctx *gin.Context) {
+	r := new(models.RulePreviewReq)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.Rule.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.RuleService.Preview(r)
+	})
+}
+
+// Export 导出规则为 YAML 文件, ruleIds 可重复指定以导出部分规则
+func (rc RuleController) Export(ctx *gin.Context) {
+	r := new(models.RuleExportQuery)
+	BindQuery(ctx, r)
+	if ctx.IsAborted() {
+		return
+	}
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	content, err := services.RuleService.Export(r)
+	if err != nil {
+		response.Fail(ctx, err.(error).Error(), "failed")
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=rules-%s.yaml", r.TenantId))
+	ctx.Data(http.StatusOK, "application/x-yaml; charset=utf-8", content.([]byte))
+}
+
+// Import 导入规则, 请求体为 YAML 文档, dryRun / prune / ruleGroupId 通过查询参数指定
+func (rc RuleController) Import(ctx *gin.Context) {
+	r := new(models.RuleImportReq)
+	BindQuery(ctx, r)
+	if ctx.IsAborted() {
+		return
+	}
+
+	content, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		response.Fail(ctx, err.Error(), "failed")
+		return
+	}
+	r.Content = content
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.RuleService.Import(r)
+	})
+}
+
+// PromImport 导入 Prometheus 告警规则文件, 请求体为规则文件, 其余参数通过查询参数指定
+func (rc RuleController) PromImport(ctx *gin.Context) {
+	r := new(models.PromRuleImportReq)
+	BindQuery(ctx, r)
+	if ctx.IsAborted() {
+		return
+	}
+
+	content, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		response.Fail(ctx, err.Error(), "failed")
+		return
+	}
+	r.Content = content
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.RuleService.PromImport(r)
+	})
+}
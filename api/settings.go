@@ -5,6 +5,7 @@ import (
 	"watchAlert/internal/middleware"
 	"watchAlert/internal/models"
 	"watchAlert/internal/services"
+	"watchAlert/pkg/tools"
 )
 
 type SettingsController struct{}
@@ -18,6 +19,7 @@ func (a SettingsController) API(gin *gin.RouterGroup) {
 	)
 	{
 		settingA.POST("saveSystemSetting", a.Save)
+		settingA.POST("setMaintenance", a.SetMaintenance)
 	}
 
 	settingB := gin.Group("setting")
@@ -39,6 +41,16 @@ func (a SettingsController) Save(ctx *gin.Context) {
 	})
 }
 
+func (a SettingsController) SetMaintenance(ctx *gin.Context) {
+	r := new(models.MaintenanceConfig)
+	BindJson(ctx, r)
+
+	r.UpdateBy = tools.GetUser(ctx.Request.Header.Get("Authorization"))
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.SettingService.SetMaintenance(r)
+	})
+}
+
 func (a SettingsController) Get(ctx *gin.Context) {
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.SettingService.Get()
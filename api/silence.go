@@ -36,7 +36,7 @@ func (sc SilenceController) API(gin *gin.RouterGroup) {
 	)
 	{
 		silenceB.GET("silenceList", sc.List)
-
+		silenceB.POST("silenceMatch", sc.Match)
 	}
 }
 
@@ -93,3 +93,15 @@ func (sc SilenceController) List(ctx *gin.Context) {
 		return services.SilenceService.List(r)
 	})
 }
+
+func (sc SilenceController) Match(ctx *gin.Context) {
+	r := new(models.SilenceMatchReq)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.TenantId = tid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.SilenceService.Match(r)
+	})
+}
@@ -20,6 +20,7 @@ func (sc SubscribeController) API(gin *gin.RouterGroup) {
 	{
 		subscribeA.POST("createSubscribe", sc.Create)
 		subscribeA.POST("deleteSubscribe", sc.Delete)
+		subscribeA.POST("unsubscribe", sc.Unsubscribe)
 	}
 
 	subscribeB := gin.Group("subscribe")
@@ -85,3 +86,17 @@ func (sc SubscribeController) Delete(ctx *gin.Context) {
 		return services.SubscribeService.Delete(r)
 	})
 }
+
+func (sc SubscribeController) Unsubscribe(ctx *gin.Context) {
+	r := new(models.AlertSubscribeQuery)
+	BindJson(ctx, r)
+
+	tid, _ := ctx.Get("TenantID")
+	r.STenantId = tid.(string)
+	uid, _ := ctx.Get("UserId")
+	r.SUserId = uid.(string)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.SubscribeService.Unsubscribe(r)
+	})
+}
@@ -2,9 +2,11 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"net/http"
 	middleware "watchAlert/internal/middleware"
 	"watchAlert/internal/models"
 	"watchAlert/internal/services"
+	"watchAlert/pkg/response"
 	jwtUtils "watchAlert/pkg/tools"
 )
 
@@ -72,15 +74,68 @@ func (uc UserController) Get(ctx *gin.Context) {
 	})
 }
 
+// Login 登陆, 兼容已有客户端仅返回 Token 字符串, 需要刷新 Token 时使用 /api/auth/login
 func (uc UserController) Login(ctx *gin.Context) {
 	r := new(models.Member)
 	BindJson(ctx, r)
 
+	Service(ctx, func() (interface{}, interface{}) {
+		data, err := services.UserService.Login(r)
+		if token, ok := data.(models.LoginToken); ok {
+			return token.Token, err
+		}
+		return data, err
+	})
+}
+
+// LoginWithRefresh 登陆, 返回 Token、刷新 Token 及 Token 有效期
+func (uc UserController) LoginWithRefresh(ctx *gin.Context) {
+	r := new(models.Member)
+	BindJson(ctx, r)
+
 	Service(ctx, func() (interface{}, interface{}) {
 		return services.UserService.Login(r)
 	})
 }
 
+func (uc UserController) Refresh(ctx *gin.Context) {
+	r := new(models.RefreshTokenReq)
+	BindJson(ctx, r)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.UserService.Refresh(r)
+	})
+}
+
+// OidcAuthorize 跳转至身份提供方的授权页面
+func (uc UserController) OidcAuthorize(ctx *gin.Context) {
+	authURL, err := services.OidcService.Authorize()
+	if err != nil {
+		response.Fail(ctx, err.Error(), "failed")
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+func (uc UserController) OidcLogin(ctx *gin.Context) {
+	r := new(models.OidcLoginReq)
+	BindJson(ctx, r)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.OidcService.Login(r)
+	})
+}
+
+func (uc UserController) Logout(ctx *gin.Context) {
+	r := new(models.RefreshTokenReq)
+	BindJson(ctx, r)
+
+	Service(ctx, func() (interface{}, interface{}) {
+		return services.UserService.Logout(r)
+	})
+}
+
 func (uc UserController) Register(ctx *gin.Context) {
 	r := new(models.Member)
 	BindJson(ctx, r)
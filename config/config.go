@@ -1,8 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type App struct {
@@ -12,11 +17,34 @@ type App struct {
 	Jwt    Jwt    `json:"Jwt"`
 	Jaeger Jaeger `json:"Jaeger"`
 	Ldap   Ldap   `json:"ldap"`
+	Oidc   Oidc   `json:"oidc"`
+	Retry  Retry  `json:"Retry"`
+	Log    Log    `json:"Log"`
+	// 数据源查询结果缓存
+	QueryCache QueryCache `json:"QueryCache"`
+	// 数据源并发查询限制
+	QueryLimit QueryLimit `json:"QueryLimit"`
+	// 数据源熔断
+	CircuitBreaker CircuitBreaker `json:"CircuitBreaker"`
+	// 通知发送失败后的重试策略
+	NoticeRetry NoticeRetry `json:"NoticeRetry"`
 }
 
 type Server struct {
 	Mode string `json:"mode"`
 	Port string `json:"port"`
+	// WatchAlert 的外部访问地址, 用于通知消息中的跳转及操作链接
+	ExternalURL string `json:"externalURL"`
+	// 优雅退出的超时时间, 单位秒, 默认 30
+	ShutdownTimeout int64 `json:"shutdownTimeout"`
+}
+
+// GetShutdownTimeout 获取优雅退出的超时时间
+func (s Server) GetShutdownTimeout() time.Duration {
+	if s.ShutdownTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.ShutdownTimeout) * time.Second
 }
 
 type MySQL struct {
@@ -26,6 +54,56 @@ type MySQL struct {
 	Pass    string `json:"pass"`
 	DBName  string `json:"dbName"`
 	Timeout string `json:"timeout"`
+	// 最大连接数, 默认 100
+	MaxOpenConns int `json:"maxOpenConns"`
+	// 最大空闲连接数, 默认 10
+	MaxIdleConns int `json:"maxIdleConns"`
+	// 连接最大存活时间, 单位秒, 默认 3600
+	ConnMaxLifetime int64 `json:"connMaxLifetime"`
+	// 只读副本的 DSN, 如 user:pass@tcp(10.0.0.2:3306)/watchalert, 告警历史、规则列表等读多的查询路由至副本
+	Replicas []string `json:"replicas"`
+	// 启动时自动执行未执行的数据库迁移, 关闭时需通过 migrate up 手动执行
+	AutoMigrate bool `json:"autoMigrate"`
+}
+
+// GetTimeout 获取连接超时时间, 支持 10s 等时间格式及纯数字 (单位秒), 默认 10s
+func (m MySQL) GetTimeout() (time.Duration, error) {
+	if m.Timeout == "" {
+		return 10 * time.Second, nil
+	}
+	if seconds, err := strconv.ParseInt(m.Timeout, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	timeout, err := time.ParseDuration(m.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("MySQL.timeout 格式错误, 如 10s, 当前: %s", m.Timeout)
+	}
+	return timeout, nil
+}
+
+// GetMaxOpenConns 获取最大连接数, 未配置时默认 100
+func (m MySQL) GetMaxOpenConns() int {
+	if m.MaxOpenConns <= 0 {
+		return 100
+	}
+	return m.MaxOpenConns
+}
+
+// GetMaxIdleConns 获取最大空闲连接数, 未配置时默认 10, 不超过最大连接数
+func (m MySQL) GetMaxIdleConns() int {
+	idle := m.MaxIdleConns
+	if idle <= 0 {
+		idle = 10
+	}
+	return min(idle, m.GetMaxOpenConns())
+}
+
+// GetConnMaxLifetime 获取连接最大存活时间, 未配置时默认 1h
+func (m MySQL) GetConnMaxLifetime() time.Duration {
+	if m.ConnMaxLifetime <= 0 {
+		return time.Hour
+	}
+	return time.Duration(m.ConnMaxLifetime) * time.Second
 }
 
 type Redis struct {
@@ -33,18 +111,282 @@ type Redis struct {
 	Port     string `json:"port"`
 	Pass     string `json:"pass"`
 	Database int    `json:"database"`
+	// 部署模式: single / sentinel / cluster, 默认 single
+	Mode string `json:"mode"`
+	// Sentinel 模式的主节点名称
+	MasterName string `json:"masterName"`
+	// Sentinel 节点地址, 多个地址以逗号分隔, 如 10.0.0.1:26379,10.0.0.2:26379
+	SentinelAddrs string `json:"sentinelAddrs"`
+	// Cluster 模式的种子节点地址, 多个地址以逗号分隔; 集群模式不支持 database
+	ClusterAddrs string `json:"clusterAddrs"`
+}
+
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// GetMode 获取 Redis 部署模式
+func (r Redis) GetMode() string {
+	if r.Mode == "" {
+		return RedisModeSingle
+	}
+	return r.Mode
+}
+
+// GetSentinelAddrs 获取 Sentinel 节点地址列表
+func (r Redis) GetSentinelAddrs() []string {
+	return splitAddresses(r.SentinelAddrs)
+}
+
+// GetClusterAddrs 获取 Cluster 种子节点地址列表
+func (r Redis) GetClusterAddrs() []string {
+	return splitAddresses(r.ClusterAddrs)
 }
 
 type Jwt struct {
+	// Token 失效时间, 单位秒
 	Expire int64 `json:"expire"`
+	// 刷新 Token 失效时间, 单位秒, 默认 7 天
+	RefreshExpire int64 `json:"refreshExpire"`
+	// 签名算法, 支持 HS256 / RS256, 默认 HS256
+	Algorithm string `json:"algorithm"`
+	// HS256 签名密钥
+	Secret string `json:"secret"`
+	// HS256 轮换前使用的密钥, 仅用于校验已签发的 Token
+	PreviousSecrets []string `json:"previousSecrets"`
+	// RS256 私钥路径 (PEM)
+	PrivateKey string `json:"privateKey"`
+	// RS256 轮换前使用的公钥路径 (PEM), 仅用于校验已签发的 Token
+	PreviousPublicKeys []string `json:"previousPublicKeys"`
+}
+
+const (
+	JwtAlgorithmHS256 = "HS256"
+	JwtAlgorithmRS256 = "RS256"
+)
+
+// GetRefreshExpire 获取刷新 Token 失效时间, 未配置时默认 7 天
+func (j Jwt) GetRefreshExpire() int64 {
+	if j.RefreshExpire <= 0 {
+		return 7 * 24 * 3600
+	}
+	return j.RefreshExpire
+}
+
+// GetAlgorithm 获取签名算法, 未配置时默认 HS256
+func (j Jwt) GetAlgorithm() string {
+	if j.Algorithm == "" {
+		return JwtAlgorithmHS256
+	}
+	return strings.ToUpper(j.Algorithm)
 }
 
+// Jaeger 链路追踪配置, URL 为空时不启用
 type Jaeger struct {
+	// 上报地址, jaeger 为 Collector 地址 (如 http://jaeger:14268/api/traces), otlphttp / otlpgrpc 为 host:port
 	URL string `json:"url"`
+	// 上报协议, 支持 jaeger / otlphttp / otlpgrpc, 默认 jaeger
+	Batcher string `json:"batcher"`
+	// 采样率 0~1, 默认 1
+	Sampler float64 `json:"sampler"`
+	// 服务名称, 默认 watchalert
+	ServiceName string `json:"serviceName"`
+}
+
+const (
+	TraceBatcherJaeger   = "jaeger"
+	TraceBatcherOtlpHttp = "otlphttp"
+	TraceBatcherOtlpGrpc = "otlpgrpc"
+)
+
+// GetBatcher 获取上报协议, 未配置时默认 jaeger
+func (j Jaeger) GetBatcher() string {
+	if j.Batcher == "" {
+		return TraceBatcherJaeger
+	}
+	return strings.ToLower(j.Batcher)
+}
+
+// GetSampler 获取采样率, 未配置时默认全部采样
+func (j Jaeger) GetSampler() float64 {
+	if j.Sampler <= 0 {
+		return 1
+	}
+	return j.Sampler
+}
+
+// GetServiceName 获取服务名称, 未配置时默认 watchalert
+func (j Jaeger) GetServiceName() string {
+	if j.ServiceName == "" {
+		return "watchalert"
+	}
+	return j.ServiceName
+}
+
+// Retry 数据源查询及健康检查的重试策略, 仅对超时、5xx、连接失败等临时错误重试
+type Retry struct {
+	// 最大尝试次数 (含首次), 默认 3, 1 表示不重试
+	MaxAttempts int `json:"maxAttempts"`
+	// 首次重试等待时间, 单位毫秒, 默认 200, 之后按指数递增
+	BaseDelay int64 `json:"baseDelay"`
+	// 单次等待时间上限, 单位毫秒, 默认 5000
+	MaxDelay int64 `json:"maxDelay"`
+	// 随机抖动比例 0~1
+	Jitter float64 `json:"jitter"`
+}
+
+// GetMaxAttempts 获取最大尝试次数, 未配置时默认 3
+func (r Retry) GetMaxAttempts() int {
+	if r.MaxAttempts <= 0 {
+		return 3
+	}
+	return r.MaxAttempts
+}
+
+// GetBaseDelay 获取首次重试等待时间, 未配置时默认 200ms
+func (r Retry) GetBaseDelay() time.Duration {
+	if r.BaseDelay <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(r.BaseDelay) * time.Millisecond
+}
+
+// GetMaxDelay 获取单次等待时间上限, 未配置时默认 5s
+func (r Retry) GetMaxDelay() time.Duration {
+	if r.MaxDelay <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(r.MaxDelay) * time.Millisecond
+}
+
+// QueryCache 数据源查询结果缓存, 相同数据源 + 查询条件在缓存时长内复用结果, 降低数据源压力
+type QueryCache struct {
+	// 缓存时长, 单位秒, 0 表示关闭; 开启后查询时间窗口按该时长对齐
+	TTL int64 `json:"ttl"`
+}
+
+// GetTTL 获取缓存时长
+func (q QueryCache) GetTTL() time.Duration {
+	if q.TTL <= 0 {
+		return 0
+	}
+	return time.Duration(q.TTL) * time.Second
+}
+
+// QueryLimit 数据源并发查询限制, 避免大量规则同时查询同一数据源时压垮数据源
+type QueryLimit struct {
+	// 每个数据源的最大并发查询数, 数据源未单独配置时使用, 0 表示不限制
+	MaxConcurrent int `json:"maxConcurrent"`
+	// 等待并发额度的超时时间, 单位毫秒, 默认 10000, 超时后本轮评估跳过该查询
+	AcquireTimeout int64 `json:"acquireTimeout"`
+}
+
+// GetAcquireTimeout 获取等待并发额度的超时时间, 未配置时默认 10s
+func (q QueryLimit) GetAcquireTimeout() time.Duration {
+	if q.AcquireTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(q.AcquireTimeout) * time.Millisecond
+}
+
+// CircuitBreaker 数据源熔断, 数据源连续失败后在冷却期内直接失败, 避免规则评估反复等待超时
+type CircuitBreaker struct {
+	// 连续失败次数达到该值后熔断, 默认 5, 小于 0 表示关闭熔断
+	FailureThreshold int `json:"failureThreshold"`
+	// 熔断冷却时长, 单位秒, 默认 30, 结束后放行一个请求探测数据源是否恢复
+	Cooldown int64 `json:"cooldown"`
+}
+
+// GetFailureThreshold 获取熔断的连续失败次数, 未配置时默认 5, 关闭时返回 0
+func (c CircuitBreaker) GetFailureThreshold() int {
+	if c.FailureThreshold < 0 {
+		return 0
+	}
+	if c.FailureThreshold == 0 {
+		return 5
+	}
+	return c.FailureThreshold
+}
+
+// GetCooldown 获取熔断冷却时长, 未配置时默认 30s
+func (c CircuitBreaker) GetCooldown() time.Duration {
+	if c.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.Cooldown) * time.Second
+}
+
+// NoticeRetry 通知发送失败后的重试策略, 失败的通知进入 Redis 重试队列, 按指数退避重试
+type NoticeRetry struct {
+	// 最大尝试次数 (含首次), 默认 5, 1 表示不重试
+	MaxAttempts int `json:"maxAttempts"`
+	// 首次重试等待时间, 单位秒, 默认 30, 之后按指数递增
+	BaseDelay int64 `json:"baseDelay"`
+	// 单次等待时间上限, 单位秒, 默认 600
+	MaxDelay int64 `json:"maxDelay"`
+}
+
+// GetMaxAttempts 获取最大尝试次数, 未配置时默认 5
+func (n NoticeRetry) GetMaxAttempts() int {
+	if n.MaxAttempts <= 0 {
+		return 5
+	}
+	return n.MaxAttempts
+}
+
+// GetBaseDelay 获取首次重试等待时间, 未配置时默认 30s
+func (n NoticeRetry) GetBaseDelay() time.Duration {
+	if n.BaseDelay <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(n.BaseDelay) * time.Second
+}
+
+// GetMaxDelay 获取单次等待时间上限, 未配置时默认 10m
+func (n NoticeRetry) GetMaxDelay() time.Duration {
+	if n.MaxDelay <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(n.MaxDelay) * time.Second
+}
+
+// Log 日志配置
+type Log struct {
+	// 日志级别, 支持 debug / info / error / severe, 默认 info
+	Level string `json:"level"`
+	// 日志格式, 支持 json / plain, 为空时 release 模式使用 json, 其余模式使用 plain
+	Encoding string `json:"encoding"`
+}
+
+const (
+	LogEncodingJson  = "json"
+	LogEncodingPlain = "plain"
+)
+
+// GetLevel 获取日志级别, 未配置时默认 info
+func (l Log) GetLevel() string {
+	if l.Level == "" {
+		return "info"
+	}
+	return strings.ToLower(l.Level)
+}
+
+// GetEncoding 获取日志格式, 未配置时根据 Server.mode 决定
+func (l Log) GetEncoding(mode string) string {
+	if l.Encoding != "" {
+		return strings.ToLower(l.Encoding)
+	}
+	if mode == "release" {
+		return LogEncodingJson
+	}
+	return LogEncodingPlain
 }
 
 type Ldap struct {
-	Enabled         bool   `json:"enabled"`
+	Enabled bool `json:"enabled"`
+	// 多个地址以逗号分隔, 按顺序尝试连接
 	Address         string `json:"address"`
 	BaseDN          string `json:"baseDN"`
 	UserDN          string `json:"userDN"`
@@ -53,22 +395,176 @@ type Ldap struct {
 	UserPrefix      string `json:"userPrefix"`
 	DefaultUserRole string `json:"defaultUserRole"`
 	Cronjob         string `json:"cronjob"`
+	// LDAP 用户加入的默认租户, 默认 default
+	DefaultTenant string `json:"defaultTenant"`
+	// LDAP 组与租户、用户角色的映射, 按优先级从高到低排列
+	GroupRoleMap []LdapGroupRole `json:"groupRoleMap"`
+	// 使用 LDAPS 连接
+	UseSSL bool `json:"useSSL"`
+	// 明文连接后通过 StartTLS 升级为加密连接, UseSSL 开启时忽略
+	StartTLS bool `json:"startTLS"`
+	// CA 证书路径, 为空时使用系统证书
+	CACert string `json:"caCert"`
+	// 跳过证书校验, 仅用于测试环境
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// 用户的额外过滤条件, 刷新 Token 时校验用户仍满足该条件, 如 AD 排除已禁用的账户
+	UserFilter string `json:"userFilter"`
+}
+
+type LdapGroupRole struct {
+	Group string `json:"group"` // 组 DN
+	Role  string `json:"role"`  // 用户角色 ID
+	// 租户 ID, 为空时为默认租户
+	Tenant string `json:"tenant"`
+}
+
+// GetDefaultTenant 获取 LDAP 用户的默认租户, 未配置时为 default
+func (l Ldap) GetDefaultTenant() string {
+	if l.DefaultTenant == "" {
+		return "default"
+	}
+	return l.DefaultTenant
+}
+
+// GetUserTenantRoles 根据用户所属的组 (memberOf) 获取用户在各租户中优先级最高的角色, key 为租户 ID; 默认租户未匹配时使用默认角色
+func (l Ldap) GetUserTenantRoles(memberOf []string) map[string]string {
+	return mapGroupTenantRoles(l.GroupRoleMap, l.GetDefaultTenant(), l.DefaultUserRole, memberOf)
+}
+
+// mapGroupTenantRoles 按优先级匹配用户所属的组, 每个租户取优先级最高的角色
+func mapGroupTenantRoles(mappings []LdapGroupRole, defaultTenant, defaultRole string, groups []string) map[string]string {
+	roles := make(map[string]string)
+	for _, gr := range mappings {
+		tenant := gr.Tenant
+		if tenant == "" {
+			tenant = defaultTenant
+		}
+		if _, ok := roles[tenant]; ok {
+			continue
+		}
+		for _, group := range groups {
+			if strings.EqualFold(strings.TrimSpace(group), strings.TrimSpace(gr.Group)) {
+				roles[tenant] = gr.Role
+				break
+			}
+		}
+	}
+	if _, ok := roles[defaultTenant]; !ok {
+		roles[defaultTenant] = defaultRole
+	}
+	return roles
+}
+
+// Oidc OIDC 单点登陆 (授权码模式), 如 Okta、Google、Keycloak
+type Oidc struct {
+	Enabled bool `json:"enabled"`
+	// 签发方地址, 通过 {issuer}/.well-known/openid-configuration 获取授权及 Token 地址
+	Issuer       string `json:"issuer"`
+	ClientId     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	// 授权后的回调地址, 一般为前端的 OIDC 登陆页面, 需在身份提供方注册
+	RedirectURL string `json:"redirectURL"`
+	// 申请的 scope, 多个以逗号分隔, 默认 openid,profile,email
+	Scopes string `json:"scopes"`
+	// 用户名对应的声明, 默认 preferred_username, 不存在时使用 email; 仅用于创建用户, 登陆时按 iss + sub 关联账号
+	UsernameClaim string `json:"usernameClaim"`
+	// 用户组对应的声明, 默认 groups
+	GroupsClaim string `json:"groupsClaim"`
+	// 首次登陆时自动创建用户
+	AutoCreateUser  bool   `json:"autoCreateUser"`
+	DefaultUserRole string `json:"defaultUserRole"`
+	// 自动创建的用户加入的默认租户, 默认 default
+	DefaultTenant string `json:"defaultTenant"`
+	// 用户组与租户、用户角色的映射, 与 LDAP 的 groupRoleMap 一致
+	GroupRoleMap []LdapGroupRole `json:"groupRoleMap"`
+}
+
+// GetScopes 获取申请的 scope, openid 为必须项
+func (o Oidc) GetScopes() []string {
+	scopes := splitAddresses(o.Scopes)
+	if len(scopes) == 0 {
+		return []string{"openid", "profile", "email"}
+	}
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return scopes
+}
+
+// GetUsernameClaim 获取用户名对应的声明
+func (o Oidc) GetUsernameClaim() string {
+	if o.UsernameClaim == "" {
+		return "preferred_username"
+	}
+	return o.UsernameClaim
+}
+
+// GetGroupsClaim 获取用户组对应的声明
+func (o Oidc) GetGroupsClaim() string {
+	if o.GroupsClaim == "" {
+		return "groups"
+	}
+	return o.GroupsClaim
+}
+
+// GetDefaultTenant 获取默认租户, 未配置时为 default
+func (o Oidc) GetDefaultTenant() string {
+	if o.DefaultTenant == "" {
+		return "default"
+	}
+	return o.DefaultTenant
+}
+
+// GetUserTenantRoles 根据用户组获取用户在各租户中的角色, key 为租户 ID
+func (o Oidc) GetUserTenantRoles(groups []string) map[string]string {
+	return mapGroupTenantRoles(o.GroupRoleMap, o.GetDefaultTenant(), o.DefaultUserRole, groups)
+}
+
+// GetAddresses 获取 LDAP 服务地址列表
+func (l Ldap) GetAddresses() []string {
+	return splitAddresses(l.Address)
+}
+
+// splitAddresses 解析以逗号分隔的地址列表, 忽略空项
+func splitAddresses(s string) []string {
+	var addresses []string
+	for _, address := range strings.Split(s, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
 }
 
 var (
 	configFile = "config/config.yaml"
 )
 
-func InitConfig() App {
+func InitConfig() *Watcher {
+	app, err := load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return newWatcher(app)
+}
+
+// load 读取配置文件并应用环境变量覆盖, 每次使用新的 viper 实例, 避免残留上一次的覆盖值
+func load() (App, error) {
 	v := viper.New()
 	v.SetConfigFile(configFile)
 	v.SetConfigType("yaml")
 	if err := v.ReadInConfig(); err != nil {
-		log.Fatal("配置读取失败:", err)
+		return App{}, fmt.Errorf("配置读取失败: %w", err)
+	}
+	if err := withEnvOverrides(v); err != nil {
+		return App{}, fmt.Errorf("环境变量配置读取失败: %w", err)
 	}
 	var config App
 	if err := v.Unmarshal(&config); err != nil {
-		log.Fatal("配置解析失败:", err)
+		return App{}, fmt.Errorf("配置解析失败: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return App{}, err
 	}
-	return config
+	return config, nil
 }
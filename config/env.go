@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// envPrefix 环境变量前缀, 如 MySQL.pass 对应 WA_MYSQL_PASS
+	envPrefix = "WA"
+	// envFileSuffix 以文件形式挂载的密钥, 如 WA_MYSQL_PASS_FILE=/run/secrets/mysql_pass
+	envFileSuffix = "_FILE"
+)
+
+// envPlaceholder 匹配配置值中的 ${ENV} 占位符
+var envPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// withEnvOverrides 开启环境变量覆盖, 优先级: WA_XXX > WA_XXX_FILE > 配置文件 (支持 ${ENV} 占位符)
+func withEnvOverrides(v *viper.Viper) error {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// AutomaticEnv 只对配置文件中已存在的 key 生效, 需显式绑定结构体中的全部 key
+	keys := configKeys(reflect.TypeOf(App{}), "")
+	for _, key := range keys {
+		if err := v.BindEnv(key); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		envKey := getEnvKey(key)
+		if _, ok := os.LookupEnv(envKey); ok {
+			continue
+		}
+
+		if path, ok := os.LookupEnv(envKey + envFileSuffix); ok {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("读取 %s 失败: %s", envKey+envFileSuffix, err.Error())
+			}
+			v.Set(key, strings.TrimSpace(string(content)))
+			continue
+		}
+
+		if value, ok := v.Get(key).(string); ok && envPlaceholder.MatchString(value) {
+			v.Set(key, expandEnvPlaceholder(value))
+		}
+	}
+
+	return nil
+}
+
+// expandEnvPlaceholder 替换 ${ENV} 占位符, 环境变量不存在时替换为空, 其余 $ 字符保持原样
+func expandEnvPlaceholder(value string) string {
+	return envPlaceholder.ReplaceAllStringFunc(value, func(s string) string {
+		return os.Getenv(envPlaceholder.FindStringSubmatch(s)[1])
+	})
+}
+
+func getEnvKey(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys 递归获取结构体对应的配置 key, 如 mysql.pass
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.ToLower(field.Name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, key)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate 校验必填配置, 返回所有不合法字段
+func (a App) Validate() error {
+	var errs []error
+	required := func(field, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s 不能为空", field))
+		}
+	}
+
+	required("Server.port", a.Server.Port)
+
+	required("MySQL.host", a.MySQL.Host)
+	required("MySQL.user", a.MySQL.User)
+	required("MySQL.dbName", a.MySQL.DBName)
+	if _, err := a.MySQL.GetTimeout(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch a.Redis.GetMode() {
+	case RedisModeSingle:
+		required("Redis.host", a.Redis.Host)
+		required("Redis.port", a.Redis.Port)
+	case RedisModeSentinel:
+		required("Redis.masterName", a.Redis.MasterName)
+		if len(a.Redis.GetSentinelAddrs()) == 0 {
+			errs = append(errs, fmt.Errorf("Redis.sentinelAddrs 不能为空"))
+		}
+	case RedisModeCluster:
+		if len(a.Redis.GetClusterAddrs()) == 0 {
+			errs = append(errs, fmt.Errorf("Redis.clusterAddrs 不能为空"))
+		}
+		if a.Redis.Database != 0 {
+			errs = append(errs, fmt.Errorf("Redis.database 在 cluster 模式下仅支持 0, 当前: %d", a.Redis.Database))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Redis.mode 仅支持 %s / %s / %s, 当前: %s", RedisModeSingle, RedisModeSentinel, RedisModeCluster, a.Redis.Mode))
+	}
+
+	if a.Jwt.Expire <= 0 {
+		errs = append(errs, fmt.Errorf("Jwt.expire 必须大于 0, 当前: %d", a.Jwt.Expire))
+	}
+
+	switch a.Jwt.GetAlgorithm() {
+	case JwtAlgorithmHS256:
+	case JwtAlgorithmRS256:
+		required("Jwt.privateKey", a.Jwt.PrivateKey)
+	default:
+		errs = append(errs, fmt.Errorf("Jwt.algorithm 仅支持 %s / %s, 当前: %s", JwtAlgorithmHS256, JwtAlgorithmRS256, a.Jwt.Algorithm))
+	}
+
+	if a.Retry.Jitter < 0 || a.Retry.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("Retry.jitter 取值范围为 0~1, 当前: %v", a.Retry.Jitter))
+	}
+
+	if a.Jaeger.URL != "" {
+		switch a.Jaeger.GetBatcher() {
+		case TraceBatcherJaeger, TraceBatcherOtlpHttp, TraceBatcherOtlpGrpc:
+		default:
+			errs = append(errs, fmt.Errorf("Jaeger.batcher 仅支持 %s / %s / %s, 当前: %s", TraceBatcherJaeger, TraceBatcherOtlpHttp, TraceBatcherOtlpGrpc, a.Jaeger.Batcher))
+		}
+		if a.Jaeger.Sampler > 1 {
+			errs = append(errs, fmt.Errorf("Jaeger.sampler 取值范围为 0~1, 当前: %v", a.Jaeger.Sampler))
+		}
+	}
+
+	switch a.Log.GetLevel() {
+	case "debug", "info", "error", "severe":
+	default:
+		errs = append(errs, fmt.Errorf("Log.level 仅支持 debug / info / error / severe, 当前: %s", a.Log.Level))
+	}
+
+	switch a.Log.GetEncoding(a.Server.Mode) {
+	case LogEncodingJson, LogEncodingPlain:
+	default:
+		errs = append(errs, fmt.Errorf("Log.encoding 仅支持 %s / %s, 当前: %s", LogEncodingJson, LogEncodingPlain, a.Log.Encoding))
+	}
+
+	if a.Ldap.Enabled {
+		if len(a.Ldap.GetAddresses()) == 0 {
+			errs = append(errs, fmt.Errorf("ldap.address 不能为空"))
+		}
+		required("ldap.baseDN", a.Ldap.BaseDN)
+	}
+
+	if a.Oidc.Enabled {
+		required("oidc.issuer", a.Oidc.Issuer)
+		required("oidc.clientId", a.Oidc.ClientId)
+		required("oidc.redirectURL", a.Oidc.RedirectURL)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
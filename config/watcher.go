@@ -0,0 +1,94 @@
+package config
+
+import (
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Watcher 配置热加载, 配置文件变更后重新解析并通知订阅者
+type Watcher struct {
+	mux         sync.RWMutex
+	app         App
+	subscribers []func(old, new App)
+}
+
+func newWatcher(app App) *Watcher {
+	return &Watcher{app: app}
+}
+
+// Get 获取当前配置
+func (w *Watcher) Get() App {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	return w.app
+}
+
+// Subscribe 订阅配置变更, 回调在配置更新后依次执行
+func (w *Watcher) Subscribe(fn func(old, new App)) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Watch 监听配置文件变更
+func (w *Watcher) Watch() {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		log.Println("配置热加载启动失败:", err)
+		return
+	}
+	v.OnConfigChange(func(e fsnotify.Event) {
+		w.reload()
+	})
+	v.WatchConfig()
+}
+
+func (w *Watcher) reload() {
+	app, err := load()
+	if err != nil {
+		log.Println("配置热加载失败, 继续使用当前配置:", err)
+		return
+	}
+
+	w.mux.Lock()
+	old := w.app
+	app = keepImmutable(old, app)
+	if reflect.DeepEqual(old, app) {
+		w.mux.Unlock()
+		return
+	}
+	w.app = app
+	subscribers := make([]func(old, new App), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mux.Unlock()
+
+	log.Println("配置已重新加载")
+	for _, fn := range subscribers {
+		fn(old, app)
+	}
+}
+
+// keepImmutable 服务端口、数据库及 Redis 连接配置需重启生效, 变更时忽略并告警
+func keepImmutable(old, new App) App {
+	if !reflect.DeepEqual(old.Server, new.Server) {
+		log.Println("Server 配置变更需重启服务后生效, 已忽略")
+		new.Server = old.Server
+	}
+	if !reflect.DeepEqual(old.MySQL, new.MySQL) {
+		log.Println("MySQL 配置变更需重启服务后生效, 已忽略")
+		new.MySQL = old.MySQL
+	}
+	if !reflect.DeepEqual(old.Redis, new.Redis) {
+		log.Println("Redis 配置变更需重启服务后生效, 已忽略")
+		new.Redis = old.Redis
+	}
+	return new
+}
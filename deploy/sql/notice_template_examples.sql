@@ -9,10 +9,14 @@ INSERT ignore INTO watchalert.notice_template_examples (id,name,description,temp
 {{- end }}
 
 {{- define "TitleColor" -}}
-{{- if not .IsRecovered -}}
+{{- if .IsRecovered -}}
+green
+{{- else if eq .Severity "P0" -}}
 red
+{{- else if eq .Severity "P1" -}}
+orange
 {{- else -}}
-green
+yellow
 {{- end -}}
 {{- end }}
 
@@ -33,6 +37,7 @@ green
 **🖥 报警主机:** ${metric.instance}
 **🕘 开始时间:** ${first_trigger_time_format}
 **🕘 恢复时间:** ${recover_time_format}
+**⏱ 持续时长:** ${firing_duration}
 **👤 值班人员:** ${duty_user}
 **📝 报警事件:** ${annotations}
 [查看事件](http://localhost:3000/events?query=${rule_name})
@@ -345,7 +350,7 @@ green
             {
               "tag": "div",
               "text": {
-                "content": "**🕘 恢复时间：**\n${recover_time_format}",
+                "content": "**🕘 恢复时间：**\n${recover_time_format}\n**⏱ 持续时长：**\n${firing_duration}",
                 "tag": "lark_md"
               }
             }
@@ -502,6 +507,7 @@ green
 <strong>☘️ 业务环境:</strong> ${metric.namespace}<br>
 <strong>🕘 开始时间:</strong> ${first_trigger_time_format}<br>
 <strong>🕘 恢复时间:</strong> ${recover_time_format}<br>
+<strong>⏱ 持续时长:</strong> ${firing_duration}<br>
 <strong>👤 值班人员:</strong> ${duty_user}<br>
 <strong>📝 报警事件:</strong> ${annotations}<br>
 [查看事件](http://localhost:3000/events?query=${rule_name})
@@ -540,6 +546,7 @@ green
 **🖥 报警主机:** ${metric.instance}</br>
 **🕘 开始时间:** ${first_trigger_time_format}</br>
 **🕘 恢复时间:** ${recover_time_format}</br>
+**⏱ 持续时长:** ${firing_duration}</br>
 **👤 值班人员:** ${duty_user}</br>
 **📝 报警事件:** ${annotations}</br>
 [查看事件](http://localhost:3000/events?query=${rule_name})
@@ -582,6 +589,7 @@ green
 >**🖥 报警主机:** ${metric.instance}
 >**🕘 开始时间:** ${first_trigger_time_format}
 >**🕘 恢复时间:** ${recover_time_format}
+>**⏱ 持续时长:** ${firing_duration}
 >**👤 值班人员:** ${duty_user}
 >**📝 报警事件:** ${annotations}
 [查看事件](http://localhost:3000/events?query=${rule_name})
@@ -600,10 +608,14 @@ green
 {{- end }}
 
 {{- define "TitleColor" -}}
-{{- if not .IsRecovered -}}
+{{- if .IsRecovered -}}
+green
+{{- else if eq .Severity "P0" -}}
 red
+{{- else if eq .Severity "P1" -}}
+orange
 {{- else -}}
-green
+yellow
 {{- end -}}
 {{- end }}
 
@@ -621,6 +633,7 @@ green
 **📌 报警等级:** ${severity}</br>
 **🕘 开始时间:** ${first_trigger_time_format}</br>
 **🕘 恢复时间:** ${recover_time_format}</br>
+**⏱ 持续时长:** ${firing_duration}</br>
 **👤 值班人员:** ${duty_user}</br>
 {{- end -}}
 {{ end }}
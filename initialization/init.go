@@ -9,19 +9,52 @@ import (
 	"watchAlert/config"
 	"watchAlert/internal/cache"
 	"watchAlert/internal/global"
+	"watchAlert/internal/migration"
 	"watchAlert/internal/models"
 	"watchAlert/internal/repo"
 	"watchAlert/internal/services"
 	"watchAlert/pkg/ai"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/logger"
+	"watchAlert/pkg/provider"
+	"watchAlert/pkg/sender"
+	"watchAlert/pkg/tracing"
 )
 
 func InitBasic() {
 
 	// 初始化配置
-	global.Config = config.InitConfig()
+	global.ConfigWatcher = config.InitConfig()
+	global.SetConfig(global.ConfigWatcher.Get())
+	cfg := global.GetConfig()
+	// 初始化日志, 日志格式仅在启动时生效, 日志级别支持热更新
+	if err := logger.Setup(cfg.Log.GetLevel(), cfg.Log.GetEncoding(cfg.Server.Mode)); err != nil {
+		logc.Error(context.Background(), fmt.Sprintf("初始化日志失败: %s", err.Error()))
+	}
+	// 初始化链路追踪, 未配置 Jaeger.url 时不启用
+	tracing.Setup(cfg.Jaeger.GetServiceName(), cfg.Jaeger.URL, cfg.Jaeger.GetBatcher(), cfg.Jaeger.GetSampler())
+	provider.SetRetryPolicy(newRetryPolicy(cfg.Retry))
+	provider.SetQueryCacheTTL(cfg.QueryCache.GetTTL())
+	provider.SetQueryLimit(cfg.QueryLimit.MaxConcurrent, cfg.QueryLimit.GetAcquireTimeout())
+	provider.SetBreakerPolicy(cfg.CircuitBreaker.GetFailureThreshold(), cfg.CircuitBreaker.GetCooldown())
+	sender.SetRetryPolicy(cfg.NoticeRetry.GetMaxAttempts(), cfg.NoticeRetry.GetBaseDelay(), cfg.NoticeRetry.GetMaxDelay())
+	global.ConfigWatcher.Subscribe(func(_, new config.App) {
+		global.SetConfig(new)
+		provider.SetRetryPolicy(newRetryPolicy(new.Retry))
+		provider.SetQueryCacheTTL(new.QueryCache.GetTTL())
+		provider.SetQueryLimit(new.QueryLimit.MaxConcurrent, new.QueryLimit.GetAcquireTimeout())
+		provider.SetBreakerPolicy(new.CircuitBreaker.GetFailureThreshold(), new.CircuitBreaker.GetCooldown())
+		sender.SetRetryPolicy(new.NoticeRetry.GetMaxAttempts(), new.NoticeRetry.GetBaseDelay(), new.NoticeRetry.GetMaxDelay())
+		logger.SetLevel(new.Log.GetLevel())
+	})
+	global.ConfigWatcher.Watch()
 
 	dbRepo := repo.NewRepoEntry()
+	// 校验数据库版本, 版本不匹配时拒绝启动
+	if err := migration.Prepare(dbRepo.DB(), cfg.MySQL.AutoMigrate); err != nil {
+		logc.Error(context.Background(), err.Error())
+		panic(err)
+	}
 	rCache := cache.NewEntryCache()
 	ctx := ctx.NewContext(context.Background(), dbRepo, rCache)
 
@@ -39,7 +72,10 @@ func InitBasic() {
 	// 导入数据源 Client 到存储池
 	importClientPools(ctx)
 
-	if global.Config.Ldap.Enabled {
+	// 重试发送失败的通知
+	go sender.StartRetryWorker(ctx)
+
+	if cfg.Ldap.Enabled {
 		// 定时同步LDAP用户任务
 		go services.LdapService.SyncUsersCronjob()
 	}
@@ -50,6 +86,9 @@ func InitBasic() {
 		return
 	}
 
+	// 恢复维护模式, 重启前缓存的通知不再补发
+	sender.SetMaintenance(ctx, r.MaintenanceConfig.Enabled)
+
 	if r.AiConfig.GetEnable() {
 		client, err := ai.NewAiClient(&r.AiConfig)
 		if err != nil {
@@ -60,6 +99,15 @@ func InitBasic() {
 	}
 }
 
+func newRetryPolicy(r config.Retry) provider.RetryPolicy {
+	return provider.RetryPolicy{
+		MaxAttempts: r.GetMaxAttempts(),
+		BaseDelay:   r.GetBaseDelay(),
+		MaxDelay:    r.GetMaxDelay(),
+		Jitter:      r.Jitter,
+	}
+}
+
 func importClientPools(ctx *ctx.Context) {
 	list, err := ctx.DB.Datasource().List(models.DatasourceQuery{})
 	if err != nil {
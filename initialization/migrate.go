@@ -0,0 +1,78 @@
+package initialization
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+	"watchAlert/config"
+	"watchAlert/internal/global"
+	"watchAlert/internal/migration"
+	"watchAlert/pkg/client"
+)
+
+const migrateUsage = "用法: w8t migrate up [version] | down [steps] | status"
+
+// RunMigrate 执行数据库迁移命令, 仅连接数据库, 不启动告警服务
+func RunMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	global.SetConfig(config.InitConfig().Get())
+	db := client.InitDB()
+	if db == nil {
+		return fmt.Errorf("数据库连接失败")
+	}
+	defer client.CloseReadReplicas()
+
+	arg := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("参数错误: %s, %s", args[1], migrateUsage)
+		}
+		arg = n
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migration.Up(db, arg); err != nil {
+			return err
+		}
+	case "down":
+		// 默认回滚最近一个迁移
+		if arg == 0 {
+			arg = 1
+		}
+		if err := migration.Down(db, arg); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return printMigrateStatus(db)
+}
+
+func printMigrateStatus(db *gorm.DB) error {
+	current, err := migration.Current(db)
+	if err != nil {
+		return err
+	}
+	list, err := migration.List(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("数据库版本: %d, 程序支持的版本: %d\n", current, migration.Latest())
+	for _, s := range list {
+		appliedAt := "未执行"
+		if s.Applied {
+			appliedAt = time.Unix(s.AppliedAt, 0).Format(time.DateTime)
+		}
+		fmt.Printf("%4d  %-30s %s\n", s.Version, s.Name, appliedAt)
+	}
+	return nil
+}
@@ -2,8 +2,13 @@ package initialization
 
 import (
 	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/zeromicro/go-zero/core/logc"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"watchAlert/internal/global"
 	"watchAlert/internal/middleware"
 	"watchAlert/internal/routers"
@@ -13,7 +18,7 @@ import (
 func InitRoute() {
 	logc.Info(context.Background(), "服务启动")
 
-	mode := global.Config.Server.Mode
+	mode := global.GetConfig().Server.Mode
 	if mode == "" {
 		mode = gin.DebugMode
 	}
@@ -24,6 +29,8 @@ func InitRoute() {
 	ginEngine.Use(
 		// 启用CORS中间件
 		middleware.Cors(),
+		// 生成请求 ID
+		middleware.RequestId(),
 		// 自定义请求日志格式
 		middleware.GinZapLogger(),
 		gin.Recovery(),
@@ -31,11 +38,28 @@ func InitRoute() {
 	)
 	allRouter(ginEngine)
 
-	err := ginEngine.Run(":" + global.Config.Server.Port)
-	if err != nil {
+	srv := &http.Server{
+		Addr:    ":" + global.GetConfig().Server.Port,
+		Handler: ginEngine,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
 		logc.Error(context.Background(), "服务启动失败:", err)
 		return
+	case sig := <-quit:
+		logc.Infof(context.Background(), "收到信号 %s, 开始优雅退出", sig)
 	}
+
+	Shutdown(srv)
 }
 
 func allRouter(engine *gin.Engine) {
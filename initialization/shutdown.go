@@ -0,0 +1,59 @@
+package initialization
+
+import (
+	"context"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"net/http"
+	"watchAlert/alert"
+	"watchAlert/alert/process"
+	"watchAlert/internal/global"
+	"watchAlert/pkg/client"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/provider"
+	"watchAlert/pkg/sender"
+	"watchAlert/pkg/tracing"
+)
+
+// Shutdown 优雅退出: 停止接收请求及新的评估, 在超时时间内等待进行中的评估与通知完成并发送分组等待及限流队列中的告警, 最后关闭各客户端
+func Shutdown(srv *http.Server) {
+	c, cancel := context.WithTimeout(context.Background(), global.GetConfig().Server.GetShutdownTimeout())
+	defer cancel()
+
+	if err := srv.Shutdown(c); err != nil {
+		logc.Error(c, fmt.Sprintf("HTTP 服务关闭失败: %s", err.Error()))
+	}
+
+	if err := alert.Shutdown(c); err != nil {
+		logc.Error(c, fmt.Sprintf("告警任务未能在超时时间内完成: %s", err.Error()))
+	}
+
+	if err := process.WaitTransitions(c); err != nil {
+		logc.Error(c, fmt.Sprintf("告警状态变更记录未能在超时时间内写入: %s", err.Error()))
+	}
+
+	if err := sender.Shutdown(c); err != nil {
+		logc.Error(c, fmt.Sprintf("限流队列中的通知未能在超时时间内发送: %s", err.Error()))
+	}
+
+	closeClients()
+	tracing.Shutdown()
+	logc.Info(context.Background(), "服务已退出")
+}
+
+// closeClients 关闭数据源、MySQL 及 Redis 连接
+func closeClients() {
+	provider.CloseAllSQLClients()
+	provider.CloseAllElasticSearchClients()
+
+	if db, err := ctx.DB.DB().DB(); err == nil {
+		if err := db.Close(); err != nil {
+			logc.Error(context.Background(), fmt.Sprintf("关闭 MySQL 连接失败: %s", err.Error()))
+		}
+	}
+	client.CloseReadReplicas()
+
+	if err := ctx.Redis.Redis().Close(); err != nil {
+		logc.Error(context.Background(), fmt.Sprintf("关闭 Redis 连接失败: %s", err.Error()))
+	}
+}
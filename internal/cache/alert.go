@@ -14,7 +14,7 @@ import (
 type (
 	// AlertCache 用于管理告警事件缓存操作
 	AlertCache struct {
-		rc *redis.Client
+		rc redis.UniversalClient
 		sync.RWMutex
 	}
 
@@ -27,15 +27,17 @@ type (
 		GetFirstTime(tenantId, faultCenterId, fingerprint string) int64
 		GetLastEvalTime() int64
 		GetLastSendTime(tenantId, faultCenterId, fingerprint string) int64
+		SetLastSendTime(tenantId, faultCenterId, fingerprint string, sendTime int64)
 		GetEventStatus(tenantId, faultCenterId, fingerprint string) models.AlertStatus
 		GetLastFiringValue(tenantId, faultCenterId, fingerprint string) float64
 		GetEventFromCache(tenantId, faultCenterId, fingerprint string) (models.AlertCurEvent, error)
 		GetLastUpgradeState(tenantId, faultCenterId, fingerprint string) models.UpgradeState
+		GetOccurrenceCount(tenantId, faultCenterId, fingerprint string) int64
 	}
 )
 
 // newAlertCacheInterface 创建一个新的 AlertCache 实例
-func newAlertCacheInterface(r *redis.Client) AlertCacheInterface {
+func newAlertCacheInterface(r redis.UniversalClient) AlertCacheInterface {
 	return &AlertCache{
 		rc: r,
 	}
@@ -47,10 +49,11 @@ func (a *AlertCache) PushAlertEvent(event *models.AlertCurEvent) {
 	a.setEventCacheHash(key, event.Fingerprint, tools.JsonMarshal(event))
 }
 
-// RemoveAlertEvent 从故障中心的缓存中移除事件
+// RemoveAlertEvent 从故障中心的缓存中移除事件, 同时清除最近通知时间, 再次触发时立即通知
 func (a *AlertCache) RemoveAlertEvent(tenantId, faultCenterId, fingerprint string) {
 	key := models.BuildAlertEventCacheKey(tenantId, faultCenterId)
 	a.deleteEventCacheHash(key, fingerprint)
+	a.rc.HDel(string(models.BuildAlertSendCacheKey(tenantId, faultCenterId)), fingerprint)
 }
 
 // GetAllEvents 获取故障中心的所有事件
@@ -123,8 +126,13 @@ func (a *AlertCache) GetLastEvalTime() int64 {
 	return time.Now().Unix()
 }
 
-// GetLastSendTime 获取故障中心事件的最后发送时间
+// GetLastSendTime 获取故障中心事件的最后发送时间, 未记录时兼容读取事件中的发送时间
 func (a *AlertCache) GetLastSendTime(tenantId, faultCenterId, fingerprint string) int64 {
+	sendTime, err := a.rc.HGet(string(models.BuildAlertSendCacheKey(tenantId, faultCenterId)), fingerprint).Int64()
+	if err == nil {
+		return sendTime
+	}
+
 	event, err := a.GetEventFromCache(tenantId, faultCenterId, fingerprint)
 	if err != nil {
 		return 0
@@ -132,6 +140,11 @@ func (a *AlertCache) GetLastSendTime(tenantId, faultCenterId, fingerprint string
 	return event.LastSendTime
 }
 
+// SetLastSendTime 记录故障中心事件的最后发送时间
+func (a *AlertCache) SetLastSendTime(tenantId, faultCenterId, fingerprint string, sendTime int64) {
+	a.rc.HSet(string(models.BuildAlertSendCacheKey(tenantId, faultCenterId)), fingerprint, sendTime)
+}
+
 // GetEventStatus 获取事件状态
 func (a *AlertCache) GetEventStatus(tenantId, faultCenterId, fingerprint string) models.AlertStatus {
 	event, err := a.GetEventFromCache(tenantId, faultCenterId, fingerprint)
@@ -164,6 +177,15 @@ func (a *AlertCache) GetLastUpgradeState(tenantId, faultCenterId, fingerprint st
 	return event.UpgradeState
 }
 
+// GetOccurrenceCount 获取事件本次告警期间的触发次数, 已恢复的事件再次触发时重新计数
+func (a *AlertCache) GetOccurrenceCount(tenantId, faultCenterId, fingerprint string) int64 {
+	event, err := a.GetEventFromCache(tenantId, faultCenterId, fingerprint)
+	if err != nil || event.Status == models.StateRecovered {
+		return 0
+	}
+	return event.OccurrenceCount
+}
+
 // 封装 Redis 操作
 func (a *AlertCache) getEventCache(key models.AlertEventCacheKey) (string, error) {
 	return a.rc.Get(string(key)).Result()
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/go-redis/redis"
+	"strconv"
+	"time"
+)
+
+type (
+	// BaselineCache 用于存储异常检测的历史样本
+	BaselineCache struct {
+		rc redis.UniversalClient
+	}
+
+	// BaselineCacheInterface 定义了异常检测历史样本缓存的操作接口
+	BaselineCacheInterface interface {
+		Push(tenantId, ruleId, fingerprint string, value float64, size int64)
+		List(tenantId, ruleId, fingerprint string) []float64
+	}
+
+	BaselineCacheKey string
+)
+
+// baselineExpiration 历史样本的过期时间, 序列不再出现后自动清理
+const baselineExpiration = 24 * time.Hour
+
+// newBaselineCacheInterface 创建一个新的 BaselineCache 实例
+func newBaselineCacheInterface(r redis.UniversalClient) BaselineCacheInterface {
+	return &BaselineCache{
+		rc: r,
+	}
+}
+
+// Push 追加样本, 仅保留最近 size 个
+func (b *BaselineCache) Push(tenantId, ruleId, fingerprint string, value float64, size int64) {
+	key := string(BuildBaselineCacheKey(tenantId, ruleId, fingerprint))
+	b.rc.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.RPush(key, value)
+		pipe.LTrim(key, -size, -1)
+		pipe.Expire(key, baselineExpiration)
+		return nil
+	})
+}
+
+// List 获取历史样本, 按写入顺序排列
+func (b *BaselineCache) List(tenantId, ruleId, fingerprint string) []float64 {
+	result, err := b.rc.LRange(string(BuildBaselineCacheKey(tenantId, ruleId, fingerprint)), 0, -1).Result()
+	if err != nil {
+		return nil
+	}
+
+	values := make([]float64, 0, len(result))
+	for _, v := range result {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, f)
+	}
+
+	return values
+}
+
+func BuildBaselineCacheKey(tenantId, ruleId, fingerprint string) BaselineCacheKey {
+	return BaselineCacheKey(fmt.Sprintf("w8t:%s:baseline:%s:%s", tenantId, ruleId, fingerprint))
+}
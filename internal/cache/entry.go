@@ -7,18 +7,20 @@ import (
 
 type (
 	entryCache struct {
-		redis    *redis.Client
+		redis    redis.UniversalClient
 		provider *ProviderPoolStore
 	}
 
 	InterEntryCache interface {
-		Redis() *redis.Client
+		Redis() redis.UniversalClient
 		Silence() SilenceCacheInterface
 		Alert() AlertCacheInterface
 		Probing() ProbingCacheInterface
 		ProviderPools() *ProviderPoolStore
 		FaultCenter() FaultCenterCacheInterface
 		PendingRecover() PendingRecoverCacheInterface
+		Baseline() BaselineCacheInterface
+		NoticeRetry() NoticeRetryCacheInterface
 	}
 )
 
@@ -32,7 +34,7 @@ func NewEntryCache() InterEntryCache {
 	}
 }
 
-func (e entryCache) Redis() *redis.Client              { return e.redis }
+func (e entryCache) Redis() redis.UniversalClient      { return e.redis }
 func (e entryCache) Silence() SilenceCacheInterface    { return newSilenceCacheInterface(e.redis) }
 func (e entryCache) Alert() AlertCacheInterface        { return newAlertCacheInterface(e.redis) }
 func (e entryCache) Probing() ProbingCacheInterface    { return newProbingCacheInterface(e.redis) }
@@ -43,3 +45,9 @@ func (e entryCache) FaultCenter() FaultCenterCacheInterface {
 func (e entryCache) PendingRecover() PendingRecoverCacheInterface {
 	return newPendingRecoverCacheInterface(e.redis)
 }
+func (e entryCache) Baseline() BaselineCacheInterface {
+	return newBaselineCacheInterface(e.redis)
+}
+func (e entryCache) NoticeRetry() NoticeRetryCacheInterface {
+	return newNoticeRetryCacheInterface(e.redis)
+}
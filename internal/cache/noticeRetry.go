@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"strconv"
+
+	"github.com/go-redis/redis"
+	"watchAlert/internal/models"
+)
+
+type (
+	// NoticeRetryCache 发送失败的通知重试队列, 保存在 Redis 中, 服务重启后继续重试
+	NoticeRetryCache struct {
+		rc redis.UniversalClient
+	}
+
+	// NoticeRetryCacheInterface 定义了通知重试队列的操作接口
+	NoticeRetryCacheInterface interface {
+		Push(payload string, retryAt int64) error
+		PopDue(now int64, limit int64) ([]string, error)
+	}
+)
+
+// newNoticeRetryCacheInterface 创建一个新的 NoticeRetryCache 实例
+func newNoticeRetryCacheInterface(r redis.UniversalClient) NoticeRetryCacheInterface {
+	return &NoticeRetryCache{
+		rc: r,
+	}
+}
+
+// Push 加入重试队列, retryAt 为下次重试时间
+func (n *NoticeRetryCache) Push(payload string, retryAt int64) error {
+	return n.rc.ZAdd(models.BuildNoticeRetryCacheKey(), redis.Z{
+		Score:  float64(retryAt),
+		Member: payload,
+	}).Err()
+}
+
+// PopDue 取出已到重试时间的通知, 删除成功的才返回, 避免多个协程重复发送
+func (n *NoticeRetryCache) PopDue(now int64, limit int64) ([]string, error) {
+	key := models.BuildNoticeRetryCacheKey()
+	members, err := n.rc.ZRangeByScore(key, redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now, 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var due []string
+	for _, member := range members {
+		if removed, err := n.rc.ZRem(key, member).Result(); err == nil && removed > 0 {
+			due = append(due, member)
+		}
+	}
+	return due, nil
+}
@@ -10,7 +10,7 @@ import (
 type (
 	// PendingRecoverCache 用于管理待恢复的告警事件
 	PendingRecoverCache struct {
-		rc    *redis.Client
+		rc    redis.UniversalClient
 		mutex sync.RWMutex
 	}
 
@@ -31,7 +31,7 @@ type (
 )
 
 // newPendingRecoverCacheInterface 创建一个新的 PendingRecoverCache 实例
-func newPendingRecoverCacheInterface(r *redis.Client) PendingRecoverCacheInterface {
+func newPendingRecoverCacheInterface(r redis.UniversalClient) PendingRecoverCacheInterface {
 	return &PendingRecoverCache{
 		rc: r,
 	}
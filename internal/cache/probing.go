@@ -12,7 +12,7 @@ import (
 type (
 	// ProbingCache 用于管理拨测事件缓存操作
 	ProbingCache struct {
-		rc *redis.Client
+		rc redis.UniversalClient
 		sync.RWMutex
 	}
 
@@ -27,7 +27,7 @@ type (
 )
 
 // newProbingCacheInterface 创建一个新的 ProbingCache 实例
-func newProbingCacheInterface(r *redis.Client) ProbingCacheInterface {
+func newProbingCacheInterface(r redis.UniversalClient) ProbingCacheInterface {
 	return &ProbingCache{
 		rc: r,
 	}
@@ -2,13 +2,31 @@ package global
 
 import (
 	"github.com/spf13/viper"
+	"sync/atomic"
 	"watchAlert/config"
 )
 
 var (
 	Layout  = "2006-01-02 15:04:05"
-	Config  config.App
 	Version string
+	// ConfigWatcher 配置热加载, 可订阅配置变更
+	ConfigWatcher *config.Watcher
 	// StSignKey 签发的秘钥
 	StSignKey = []byte(viper.GetString("jwt.WatchAlert"))
+
+	// appConfig 当前配置, 热加载时整体替换指针, 读取方无需加锁
+	appConfig atomic.Pointer[config.App]
 )
+
+// GetConfig 获取当前配置, 未初始化时返回零值
+func GetConfig() config.App {
+	if c := appConfig.Load(); c != nil {
+		return *c
+	}
+	return config.App{}
+}
+
+// SetConfig 替换当前配置, 配置热加载时调用
+func SetConfig(c config.App) {
+	appConfig.Store(&c)
+}
@@ -0,0 +1,42 @@
+package global
+
+import (
+	"sync"
+	"testing"
+	"watchAlert/config"
+)
+
+// TestConfig_ConcurrentReload 热加载替换配置时并发读取, 需配合 -race 运行
+func TestConfig_ConcurrentReload(t *testing.T) {
+	SetConfig(config.App{Jwt: config.Jwt{Expire: 1}})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if cfg := GetConfig(); cfg.Jwt.Expire <= 0 {
+					t.Errorf("expire -> %d", cfg.Jwt.Expire)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := int64(1); i <= 1000; i++ {
+		SetConfig(config.App{Jwt: config.Jwt{Expire: i}})
+	}
+	close(stop)
+	wg.Wait()
+
+	if GetConfig().Jwt.Expire != 1000 {
+		t.Errorf("final expire -> %d", GetConfig().Jwt.Expire)
+	}
+}
@@ -22,13 +22,11 @@ func Auth() gin.HandlerFunc {
 		}
 
 		// 校验 Token
-		code, ok := IsTokenValid(ctx.DO(), tokenStr)
+		_, ok := IsTokenValid(ctx.DO(), tokenStr)
 		if !ok {
-			if code == 401 {
-				response.TokenFail(context)
-				context.Abort()
-				return
-			}
+			response.TokenFail(context)
+			context.Abort()
+			return
 		}
 
 	}
@@ -36,6 +34,9 @@ func Auth() gin.HandlerFunc {
 
 func IsTokenValid(ctx *ctx.Context, tokenStr string) (int64, bool) {
 	// Bearer Token, 获取 Token 值
+	if len(tokenStr) <= len(tools.TokenType)+1 {
+		return 400, false
+	}
 	tokenStr = tokenStr[len(tools.TokenType)+1:]
 	token, err := tools.ParseToken(tokenStr)
 	if err != nil {
@@ -1,23 +1,20 @@
 package middleware
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/zeromicro/go-zero/core/logc"
 	"gorm.io/gorm"
+	"slices"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/response"
 	utils2 "watchAlert/pkg/tools"
 )
 
+// Permission 根据用户在当前租户中的角色校验接口权限, 权限不足时返回 403 及缺少的权限
 func Permission() gin.HandlerFunc {
 	return func(context *gin.Context) {
-		tid := context.Request.Header.Get(TenantIDHeaderKey)
-		if tid == "null" || tid == "" {
-			return
-		}
 		// 获取 Token
 		tokenStr := context.Request.Header.Get("Authorization")
 		if tokenStr == "" {
@@ -36,7 +33,7 @@ func Permission() gin.HandlerFunc {
 			logc.Errorf(c.Ctx, fmt.Sprintf("用户不存在, uid: %s", userId))
 		}
 		if err != nil {
-			response.PermissionFail(context)
+			response.PermissionDenied(context, "用户不存在")
 			context.Abort()
 			return
 		}
@@ -44,19 +41,32 @@ func Permission() gin.HandlerFunc {
 		context.Set("UserId", user.UserId)
 		context.Set("UserEmail", user.Email)
 
+		urlPath := context.Request.URL.Path
+		tid := context.Request.Header.Get(TenantIDHeaderKey)
+		if tid == "null" || tid == "" {
+			// 未选择租户时不存在租户角色, 仅允许访问无需租户的接口
+			if !allowTenantless(user.UserId, urlPath) {
+				response.PermissionDenied(context, "未指定租户, 请选择租户后重试")
+				context.Abort()
+			}
+			return
+		}
+
 		// 获取租户用户角色
-		tenantUserInfo, _ := c.DB.Tenant().GetTenantLinkedUserInfo(models.GetTenantLinkedUserInfo{ID: tid, UserID: userId})
+		tenantUserInfo, err := c.DB.Tenant().GetTenantLinkedUserInfo(models.GetTenantLinkedUserInfo{ID: tid, UserID: userId})
 		if err != nil {
 			logc.Errorf(c.Ctx, fmt.Sprintf("获取租户用户角色失败 %s", err.Error()))
 			response.TokenFail(context)
 			context.Abort()
 			return
 		}
+		if tenantUserInfo.UserID == "" {
+			response.PermissionDenied(context, "用户不属于当前租户")
+			context.Abort()
+			return
+		}
 
-		var (
-			role       models.UserRole
-			permission []models.UserPermissions
-		)
+		var role models.UserRole
 		// 根据用户角色获取权限
 		err = c.DB.DB().Model(&models.UserRole{}).Where("id = ?", tenantUserInfo.UserRole).First(&role).Error
 		if err != nil {
@@ -65,21 +75,31 @@ func Permission() gin.HandlerFunc {
 			context.Abort()
 			return
 		}
-		_ = json.Unmarshal([]byte(utils2.JsonMarshal(role.Permissions)), &permission)
-
-		urlPath := context.Request.URL.Path
 
-		var pass bool
-		for _, v := range permission {
-			if urlPath == v.API {
-				pass = true
-				break
-			}
-		}
-		if !pass {
-			response.PermissionFail(context)
+		if err := checkPermission(role, urlPath); err != nil {
+			response.PermissionDenied(context, err.Error())
 			context.Abort()
 			return
 		}
 	}
 }
+
+// allowTenantless 未指定租户时, admin 用户可访问所有接口, 其他用户仅可访问 TenantlessAPIs
+func allowTenantless(userId, urlPath string) bool {
+	return userId == "admin" || slices.Contains(models.TenantlessAPIs, urlPath)
+}
+
+// checkPermission 校验角色是否拥有接口对应的权限
+func checkPermission(role models.UserRole, urlPath string) error {
+	for _, v := range role.Permissions {
+		if urlPath == v.API {
+			return nil
+		}
+	}
+
+	permission, ok := models.GetPermissionByAPI(urlPath)
+	if !ok {
+		return fmt.Errorf("接口 %s 未配置权限", urlPath)
+	}
+	return fmt.Errorf("角色 %s 缺少「%s」权限", role.Name, permission.Key)
+}
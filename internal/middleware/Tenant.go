@@ -7,6 +7,7 @@ import (
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/response"
+	"watchAlert/pkg/tools"
 )
 
 const TenantIDHeaderKey = "TenantID"
@@ -42,6 +43,17 @@ func ParseTenant() gin.HandlerFunc {
 			return
 		}
 
+		// 仅允许访问用户所属的租户, admin 用户可访问所有租户
+		userId := tools.GetUserID(context.Request.Header.Get("Authorization"))
+		if userId != "admin" {
+			info, err := c.DB.Tenant().GetTenantLinkedUserInfo(models.GetTenantLinkedUserInfo{ID: tid, UserID: userId})
+			if err != nil || info.UserID == "" {
+				response.PermissionDenied(context, "用户不属于当前租户")
+				context.Abort()
+				return
+			}
+		}
+
 		context.Set(TenantIDHeaderKey, tid)
 		context.Next()
 	}
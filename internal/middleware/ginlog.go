@@ -3,12 +3,12 @@ package middleware
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/zeromicro/go-zero/core/logc"
 	"github.com/zeromicro/go-zero/core/logx"
 	"io/ioutil"
 	"time"
+	"watchAlert/pkg/logger"
 )
 
 // GinZapLogger returns a gin.HandlerFunc that logs requests using zap
@@ -31,7 +31,7 @@ func GinZapLogger() gin.HandlerFunc {
 		clientIP := c.ClientIP()
 		message := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		ctx := logx.ContextWithFields(context.Background(),
+		ctx := logx.ContextWithFields(c.Request.Context(),
 			logx.Field("method", method),
 			logx.Field("path", path),
 			logx.Field("status", status),
@@ -42,6 +42,19 @@ func GinZapLogger() gin.HandlerFunc {
 	}
 }
 
+// RequestId 为每个请求生成请求 ID, 优先使用客户端携带的 X-Request-Id, 写入响应 Header 及请求上下文
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(logger.RequestIdHeader)
+		if requestId == "" {
+			requestId = logger.NewTraceId()
+		}
+		c.Header(logger.RequestIdHeader, requestId)
+		c.Request = c.Request.WithContext(logger.WithTraceId(c.Request.Context(), requestId))
+		c.Next()
+	}
+}
+
 // LoggingMiddleware 打印请求的body和query params
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -54,9 +67,11 @@ func LoggingMiddleware() gin.HandlerFunc {
 		}
 		// 将body复制回原位
 		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-		// 打印body和query params
-		fmt.Println("Body:", string(bodyBytes))
-		fmt.Println("Query Params:", c.Request.URL.Query())
+		// 打印body和query params, 仅在 debug 级别输出
+		logc.Debugw(c.Request.Context(), "request params",
+			logc.Field("body", string(bodyBytes)),
+			logc.Field("query", c.Request.URL.Query()),
+		)
 		// 处理请求
 		c.Next()
 	}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestCheckPermission(t *testing.T) {
+	role := models.UserRole{
+		Name: "viewer",
+		Permissions: []models.UserPermissions{
+			{Key: "搜索告警规则", API: "/api/w8t/rule/ruleSearch"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		urlPath string
+		wantErr string
+	}{
+		{name: "granted", urlPath: "/api/w8t/rule/ruleSearch"},
+		{name: "missing permission", urlPath: "/api/w8t/ai/chat", wantErr: "角色 viewer 缺少「AI 分析」权限"},
+		{name: "dashboard info requires permission", urlPath: "/api/w8t/system/getDashboardInfo", wantErr: "角色 viewer 缺少「查看首页概览」权限"},
+		{name: "unregistered api", urlPath: "/api/w8t/unknown/api", wantErr: "接口 /api/w8t/unknown/api 未配置权限"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPermission(role, tt.urlPath)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkPermission() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("checkPermission() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowTenantless(t *testing.T) {
+	tests := []struct {
+		name    string
+		userId  string
+		urlPath string
+		want    bool
+	}{
+		{name: "admin any api", userId: "admin", urlPath: "/api/w8t/rule/ruleSearch", want: true},
+		{name: "user tenant list", userId: "u1", urlPath: "/api/w8t/tenant/getTenantList", want: true},
+		{name: "user system setting", userId: "u1", urlPath: "/api/w8t/setting/getSystemSetting", want: true},
+		{name: "user tenant scoped api", userId: "u1", urlPath: "/api/w8t/rule/ruleSearch", want: false},
+		{name: "user dashboard info", userId: "u1", urlPath: "/api/w8t/system/getDashboardInfo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowTenantless(tt.userId, tt.urlPath); got != tt.want {
+				t.Errorf("allowTenantless() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
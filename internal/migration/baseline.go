@@ -0,0 +1,467 @@
+package migration
+
+// 基线版本的表结构快照, 与引入版本迁移时的模型一致, 模型后续的变更需通过新的迁移追加, 不能修改此文件
+// 序列化为 JSON 的字段统一为 string, 与模型建表时的 longtext 一致
+
+type baseDutySchedule struct {
+	TenantId string `gorm:"column:tenant_id"`
+	DutyId   string `gorm:"column:duty_id"`
+	Time     string `gorm:"column:time"`
+	UserId   string `gorm:"column:user_id"`
+	Username string `gorm:"column:username"`
+}
+
+func (baseDutySchedule) TableName() string { return "duty_schedules" }
+
+type baseDutyManagement struct {
+	TenantId     string `gorm:"column:tenant_id"`
+	ID           string `gorm:"column:id"`
+	Name         string `gorm:"column:name"`
+	Manager      string `gorm:"column:manager"`
+	Description  string `gorm:"column:description"`
+	CurDutyUser  string `gorm:"column:cur_duty_user"`
+	CreateBy     string `gorm:"column:create_by"`
+	CreateAt     int64  `gorm:"column:create_at"`
+	Timezone     string `gorm:"column:timezone"`
+	HandoverTime int    `gorm:"column:handover_time"`
+}
+
+func (baseDutyManagement) TableName() string { return "duty_managements" }
+
+type baseDutyOverride struct {
+	TenantId string `gorm:"column:tenant_id"`
+	ID       string `gorm:"column:id"`
+	DutyId   string `gorm:"column:duty_id"`
+	UserId   string `gorm:"column:user_id"`
+	Username string `gorm:"column:username"`
+	StartsAt int64  `gorm:"column:starts_at"`
+	EndsAt   int64  `gorm:"column:ends_at"`
+	Reason   string `gorm:"column:reason"`
+	CreateBy string `gorm:"column:create_by"`
+	CreateAt int64  `gorm:"column:create_at"`
+}
+
+func (baseDutyOverride) TableName() string { return "duty_overrides" }
+
+type baseAlertNotice struct {
+	TenantId     string `gorm:"column:tenant_id"`
+	Uuid         string `gorm:"column:uuid"`
+	Name         string `gorm:"column:name"`
+	DutyId       string `gorm:"column:duty_id"`
+	NoticeType   string `gorm:"column:notice_type"`
+	NoticeTmplId string `gorm:"column:notice_tmpl_id"`
+	DefaultHook  string `gorm:"column:hook"`
+	DefaultSign  string `gorm:"column:sign"`
+	Routes       string `gorm:"column:routes"`
+	Email        string `gorm:"column:email"`
+	PhoneNumber  string `gorm:"column:phone_number"`
+	Telegram     string `gorm:"column:telegram"`
+	PagerDuty    string `gorm:"column:pager_duty"`
+	OpsGenie     string `gorm:"column:ops_genie"`
+	WebHook      string `gorm:"column:web_hook"`
+	Slack        string `gorm:"column:slack"`
+	RateLimit    int    `gorm:"column:rate_limit"`
+}
+
+func (baseAlertNotice) TableName() string { return "alert_notices" }
+
+type baseAlertDataSource struct {
+	TenantId         string `gorm:"column:tenant_id"`
+	Id               string `gorm:"column:id"`
+	Name             string `gorm:"column:name"`
+	Labels           string `gorm:"column:labels"`
+	Type             string `gorm:"column:type"`
+	HTTP             string `gorm:"column:http"`
+	Auth             string `gorm:"column:auth"`
+	DsAliCloudConfig string `gorm:"column:ds_ali_cloud_config"`
+	AWSCloudWatch    string `gorm:"column:aws_cloud_watch"`
+	SQLConfig        string `gorm:"column:sql_config"`
+	HTTPProbeConfig  string `gorm:"column:http_probe_config"`
+	Description      string `gorm:"column:description"`
+	KubeConfig       string `gorm:"column:kube_config"`
+	Enabled          *bool  `gorm:"column:enabled"`
+}
+
+func (baseAlertDataSource) TableName() string { return "alert_data_sources" }
+
+type baseAlertRule struct {
+	TenantId             string `gorm:"column:tenant_id"`
+	RuleId               string `gorm:"column:rule_id"`
+	RuleGroupId          string `gorm:"column:rule_group_id"`
+	ExternalLabels       string `gorm:"column:external_labels"`
+	DatasourceType       string `gorm:"column:datasource_type"`
+	DatasourceIdList     string `gorm:"column:datasource_id_list"`
+	RuleName             string `gorm:"column:rule_name"`
+	EvalInterval         int64  `gorm:"column:eval_interval"`
+	EvalTimeType         string `gorm:"column:eval_time_type"`
+	RepeatNoticeInterval int64  `gorm:"column:repeat_notice_interval"`
+	Description          string `gorm:"column:description"`
+	EffectiveTime        string `gorm:"column:effective_time"`
+	Severity             string `gorm:"column:severity"`
+	PrometheusConfig     string `gorm:"column:prometheus_config"`
+	AliCloudSLSConfig    string `gorm:"column:ali_cloud_sls_config"`
+	LokiConfig           string `gorm:"column:loki_config"`
+	VictoriaLogsConfig   string `gorm:"column:victoria_logs_config"`
+	JaegerConfig         string `gorm:"column:jaeger_config"`
+	CloudWatchConfig     string `gorm:"column:cloud_watch_config"`
+	KubernetesConfig     string `gorm:"column:kubernetes_config"`
+	ElasticSearchConfig  string `gorm:"column:elastic_search_config"`
+	ClickHouseConfig     string `gorm:"column:click_house_config"`
+	GraylogConfig        string `gorm:"column:graylog_config"`
+	SQLConfig            string `gorm:"column:sql_config"`
+	HTTPProbeConfig      string `gorm:"column:http_probe_config"`
+	LogEvalCondition     string `gorm:"column:log_eval_condition"`
+	LogLabelFields       string `gorm:"column:log_label_fields"`
+	MessageTemplate      string `gorm:"column:message_template;type:text"`
+	EscalationPolicy     string `gorm:"column:escalationPolicy"`
+	RecoverNotify        *bool  `gorm:"column:recoverNotify"`
+	FailoverDatasources  string `gorm:"column:failoverDatasources"`
+	EvalJitter           int64  `gorm:"column:evalJitter"`
+	ForDuration          int64  `gorm:"column:forDuration"`
+	FaultCenterId        string `gorm:"column:fault_center_id"`
+	Enabled              *bool  `gorm:"column:enabled"`
+}
+
+func (baseAlertRule) TableName() string { return "alert_rules" }
+
+type baseAlertCurEvent struct {
+	TenantId             string `gorm:"column:tenant_id"`
+	RuleId               string `gorm:"column:rule_id"`
+	RuleName             string `gorm:"column:rule_name"`
+	DatasourceType       string `gorm:"column:datasource_type"`
+	DatasourceId         string `gorm:"column:datasource_id"`
+	Fingerprint          string `gorm:"column:fingerprint"`
+	Severity             string `gorm:"column:severity"`
+	Metric               string `gorm:"column:metric"`
+	Log                  string `gorm:"column:log"`
+	EvalInterval         int64  `gorm:"column:eval_interval"`
+	ForDuration          int64  `gorm:"column:for_duration"`
+	FirstTriggerTime     int64  `gorm:"column:first_trigger_time"`
+	RepeatNoticeInterval int64  `gorm:"column:repeat_notice_interval"`
+	EffectiveTime        string `gorm:"column:effective_time"`
+	FaultCenterId        string `gorm:"column:fault_center_id"`
+}
+
+func (baseAlertCurEvent) TableName() string { return "alert_cur_events" }
+
+type baseAlertHisEvent struct {
+	TenantId         string `gorm:"column:tenant_id"`
+	DatasourceId     string `gorm:"column:datasource_id"`
+	DatasourceType   string `gorm:"column:datasource_type"`
+	Fingerprint      string `gorm:"column:fingerprint"`
+	RuleId           string `gorm:"column:rule_id"`
+	RuleName         string `gorm:"column:rule_name"`
+	Severity         string `gorm:"column:severity"`
+	Metric           string `gorm:"column:metric"`
+	Log              string `gorm:"column:log"`
+	EvalInterval     int64  `gorm:"column:eval_interval"`
+	Annotations      string `gorm:"column:annotations"`
+	FirstTriggerTime int64  `gorm:"column:first_trigger_time"`
+	LastEvalTime     int64  `gorm:"column:last_eval_time"`
+	LastSendTime     int64  `gorm:"column:last_send_time"`
+	RecoverTime      int64  `gorm:"column:recover_time"`
+	FaultCenterId    string `gorm:"column:fault_center_id"`
+	UpgradeState     string `gorm:"column:upgrade_state"`
+}
+
+func (baseAlertHisEvent) TableName() string { return "alert_his_events" }
+
+type baseAlertSilences struct {
+	TenantId      string `gorm:"column:tenant_id"`
+	Name          string `gorm:"column:name"`
+	Id            string `gorm:"column:id"`
+	Labels        string `gorm:"column:labels"`
+	StartsAt      int64  `gorm:"column:starts_at"`
+	UpdateBy      string `gorm:"column:update_by"`
+	EndsAt        int64  `gorm:"column:ends_at"`
+	UpdateAt      int64  `gorm:"column:update_at"`
+	FaultCenterId string `gorm:"column:fault_center_id"`
+	Comment       string `gorm:"column:comment"`
+	Status        int    `gorm:"column:status"`
+	Recurrence    string `gorm:"column:recurrence"`
+}
+
+func (baseAlertSilences) TableName() string { return "alert_silences" }
+
+type baseMember struct {
+	UserId     string `gorm:"column:user_id"`
+	UserName   string `gorm:"column:user_name"`
+	Email      string `gorm:"column:email"`
+	Phone      string `gorm:"column:phone"`
+	Password   string `gorm:"column:password"`
+	Role       string `gorm:"column:role"`
+	CreateBy   string `gorm:"column:create_by"`
+	CreateAt   int64  `gorm:"column:create_at"`
+	JoinDuty   string `gorm:"column:join_duty"`
+	DutyUserId string `gorm:"column:duty_user_id"`
+	Tenants    string `gorm:"column:tenants"`
+}
+
+func (baseMember) TableName() string { return "members" }
+
+type baseUserRole struct {
+	ID          string `gorm:"column:id"`
+	Name        string `gorm:"column:name"`
+	Description string `gorm:"column:description"`
+	Permissions string `gorm:"column:permissions"`
+	CreateAt    int64  `gorm:"column:create_at"`
+}
+
+func (baseUserRole) TableName() string { return "user_roles" }
+
+type baseUserPermissions struct {
+	Key string `gorm:"column:key"`
+	API string `gorm:"column:api"`
+}
+
+func (baseUserPermissions) TableName() string { return "user_permissions" }
+
+type baseNoticeTemplateExample struct {
+	Id                   string `gorm:"column:id"`
+	Name                 string `gorm:"column:name"`
+	NoticeType           string `gorm:"column:notice_type"`
+	Description          string `gorm:"column:description"`
+	Template             string `gorm:"column:template"`
+	TemplateFiring       string `gorm:"column:template_firing"`
+	TemplateRecover      string `gorm:"column:template_recover"`
+	EnableFeiShuJsonCard *bool  `gorm:"column:enable_fei_shu_json_card"`
+}
+
+func (baseNoticeTemplateExample) TableName() string { return "notice_template_examples" }
+
+type baseRuleGroups struct {
+	TenantId    string `gorm:"column:tenant_id"`
+	ID          string `gorm:"column:id"`
+	Name        string `gorm:"column:name"`
+	Number      int    `gorm:"column:number"`
+	Description string `gorm:"column:description"`
+}
+
+func (baseRuleGroups) TableName() string { return "rule_groups" }
+
+type baseRuleTemplateGroup struct {
+	Name        string `gorm:"column:name;not null;type:varchar(255)"`
+	Number      int    `gorm:"column:number"`
+	Type        string `gorm:"column:type"`
+	Description string `gorm:"column:description"`
+}
+
+func (baseRuleTemplateGroup) TableName() string { return "rule_template_groups" }
+
+type baseRuleTemplate struct {
+	Type                 string `gorm:"column:type"`
+	RuleGroupName        string `gorm:"column:rule_group_name"`
+	RuleName             string `gorm:"column:rule_name;not null;type:varchar(255)"`
+	DatasourceType       string `gorm:"column:datasource_type"`
+	EvalInterval         int64  `gorm:"column:eval_interval"`
+	ForDuration          int64  `gorm:"column:for_duration"`
+	RepeatNoticeInterval int64  `gorm:"column:repeat_notice_interval"`
+	Description          string `gorm:"column:description"`
+	EffectiveTime        string `gorm:"column:effective_time"`
+	PrometheusConfig     string `gorm:"column:prometheus_config"`
+	AliCloudSLSConfig    string `gorm:"column:ali_cloud_sls_config"`
+	LokiConfig           string `gorm:"column:loki_config"`
+	JaegerConfig         string `gorm:"column:jaeger_config"`
+	KubernetesConfig     string `gorm:"column:kubernetes_config"`
+	ElasticSearchConfig  string `gorm:"column:elastic_search_config"`
+}
+
+func (baseRuleTemplate) TableName() string { return "rule_templates" }
+
+type baseTenant struct {
+	ID               string `gorm:"column:id"`
+	Name             string `gorm:"column:name"`
+	CreateAt         int64  `gorm:"column:create_at"`
+	CreateBy         string `gorm:"column:create_by"`
+	Manager          string `gorm:"column:manager"`
+	Description      string `gorm:"column:description"`
+	UserNumber       int64  `gorm:"column:user_number"`
+	RuleNumber       int64  `gorm:"column:rule_number"`
+	DutyNumber       int64  `gorm:"column:duty_number"`
+	NoticeNumber     int64  `gorm:"column:notice_number"`
+	RemoveProtection *bool  `gorm:"column:remove_protection;type:BOOL"`
+}
+
+func (baseTenant) TableName() string { return "tenants" }
+
+type baseDashboard struct {
+	TenantId    string `gorm:"column:tenant_id"`
+	ID          string `gorm:"column:id"`
+	Name        string `gorm:"column:name;unique"`
+	URL         string `gorm:"column:url"`
+	FolderId    string `gorm:"column:folder_id"`
+	Description string `gorm:"column:description"`
+}
+
+func (baseDashboard) TableName() string { return "dashboards" }
+
+type baseAuditLog struct {
+	TenantId   string `gorm:"column:tenant_id"`
+	ID         string `gorm:"column:id"`
+	Username   string `gorm:"column:username"`
+	IPAddress  string `gorm:"column:ip_address"`
+	Method     string `gorm:"column:method"`
+	Path       string `gorm:"column:path"`
+	CreatedAt  int64  `gorm:"column:created_at"`
+	StatusCode int    `gorm:"column:status_code"`
+	Body       string `gorm:"column:body"`
+	AuditType  string `gorm:"column:audit_type"`
+}
+
+func (baseAuditLog) TableName() string { return "audit_logs" }
+
+type baseSettings struct {
+	IsInit          int    `gorm:"column:is_init"`
+	EmailConfig     string `gorm:"column:email_config"`
+	PhoneCallConfig string `gorm:"column:phone_call_config"`
+	AiConfig        string `gorm:"column:ai_config"`
+	EnrichConfig    string `gorm:"column:enrich_config"`
+}
+
+func (baseSettings) TableName() string { return "settings" }
+
+type baseTenantLinkedUsers struct {
+	ID    string `gorm:"column:id"`
+	Users string `gorm:"column:users"`
+}
+
+func (baseTenantLinkedUsers) TableName() string { return "tenant_linked_users" }
+
+type baseDashboardFolders struct {
+	TenantId        string `gorm:"column:tenant_id"`
+	ID              string `gorm:"column:id"`
+	Name            string `gorm:"column:name"`
+	Theme           string `gorm:"column:theme"`
+	GrafanaVersion  string `gorm:"column:grafana_version"`
+	GrafanaHost     string `gorm:"column:grafana_host"`
+	GrafanaFolderId string `gorm:"column:grafana_folder_id"`
+}
+
+func (baseDashboardFolders) TableName() string { return "dashboard_folders" }
+
+type baseAlertSubscribe struct {
+	SId               string `gorm:"column:s_id"`
+	STenantId         string `gorm:"column:s_tenant_id"`
+	SUserId           string `gorm:"column:s_user_id"`
+	SUserEmail        string `gorm:"column:s_user_email"`
+	SRuleId           string `gorm:"column:s_rule_id"`
+	SRuleName         string `gorm:"column:s_rule_name"`
+	SRuleType         string `gorm:"column:s_rule_type"`
+	SRuleSeverity     string `gorm:"column:s_rule_severity"`
+	SNoticeSubject    string `gorm:"column:s_notice_subject"`
+	SNoticeTemplateId string `gorm:"column:s_notice_template_id"`
+	SFilter           string `gorm:"column:s_filter"`
+	SCreateAt         int64  `gorm:"column:s_create_at"`
+	SLabelMatchers    string `gorm:"column:s_label_matchers"`
+	SNoticeId         string `gorm:"column:s_notice_id"`
+}
+
+func (baseAlertSubscribe) TableName() string { return "alert_subscribes" }
+
+type baseNoticeRecord struct {
+	Date     string `gorm:"column:date"`
+	CreateAt int64  `gorm:"column:create_at"`
+	TenantId string `gorm:"column:tenant_id"`
+	RuleName string `gorm:"column:rule_name"`
+	NType    string `gorm:"column:n_type"`
+	NObj     string `gorm:"column:n_obj"`
+	Severity string `gorm:"column:severity"`
+	Status   int    `gorm:"column:status"`
+	AlarmMsg string `gorm:"column:alarm_msg"`
+	ErrMsg   string `gorm:"column:err_msg"`
+}
+
+func (baseNoticeRecord) TableName() string { return "notice_records" }
+
+type baseProbingRule struct {
+	TenantId              string `gorm:"column:tenant_id"`
+	RuleName              string `gorm:"column:rule_name"`
+	RuleId                string `gorm:"column:rule_id"`
+	RuleType              string `gorm:"column:rule_type"`
+	RepeatNoticeInterval  int64  `gorm:"column:repeat_notice_interval"`
+	ProbingEndpointConfig string `gorm:"column:probing_endpoint_config"`
+	NoticeId              string `gorm:"column:notice_id"`
+	Annotations           string `gorm:"column:annotations"`
+	RecoverNotify         *bool  `gorm:"column:recover_notify"`
+	Enabled               *bool  `gorm:"column:enabled"`
+}
+
+func (baseProbingRule) TableName() string { return "w8t_probing_rule" }
+
+type baseFaultCenter struct {
+	TenantId             string `gorm:"column:tenant_id"`
+	ID                   string `gorm:"column:id"`
+	Name                 string `gorm:"column:name"`
+	Description          string `gorm:"column:description"`
+	NoticeIds            string `gorm:"column:noticeIds"`
+	NoticeRoutes         string `gorm:"column:notice_routes"`
+	RepeatNoticeInterval int64  `gorm:"column:repeat_notice_interval"`
+	RecoverNotify        *bool  `gorm:"column:recover_notify"`
+	AggregationType      string `gorm:"column:aggregation_type"`
+	CreateAt             int64  `gorm:"column:create_at"`
+	RecoverWaitTime      int64  `gorm:"column:recover_wait_time"`
+	IsUpgradeEnabled     *bool  `gorm:"column:isUpgradeEnabled"`
+	UpgradableSeverity   string `gorm:"column:upgradableSeverity"`
+	UpgradeStrategy      string `gorm:"column:upgradeStrategy"`
+	GroupBy              string `gorm:"column:groupBy"`
+	GroupWait            int64  `gorm:"column:group_wait"`
+	GroupInterval        int64  `gorm:"column:group_interval"`
+	InhibitRules         string `gorm:"column:inhibitRules"`
+}
+
+func (baseFaultCenter) TableName() string { return "w8t_fault_center" }
+
+type baseAiContentRecord struct {
+	RuleId  string `gorm:"column:rule_id"`
+	Content string `gorm:"column:content"`
+}
+
+func (baseAiContentRecord) TableName() string { return "w8t_ai_content_record" }
+
+type baseAlertTransition struct {
+	TenantId      string `gorm:"column:tenant_id;index"`
+	ID            string `gorm:"column:id"`
+	FaultCenterId string `gorm:"column:fault_center_id"`
+	RuleId        string `gorm:"column:rule_id;index"`
+	RuleName      string `gorm:"column:rule_name"`
+	Fingerprint   string `gorm:"column:fingerprint"`
+	Severity      string `gorm:"column:severity"`
+	FromState     string `gorm:"column:from_state"`
+	ToState       string `gorm:"column:to_state"`
+	Metric        string `gorm:"column:metric"`
+	Actor         string `gorm:"column:actor"`
+	CreatedAt     int64  `gorm:"column:created_at;index"`
+}
+
+func (baseAlertTransition) TableName() string { return "alert_transitions" }
+
+var baselineModels = []interface{}{
+	&baseDutySchedule{},
+	&baseDutyManagement{},
+	&baseDutyOverride{},
+	&baseAlertNotice{},
+	&baseAlertDataSource{},
+	&baseAlertRule{},
+	&baseAlertCurEvent{},
+	&baseAlertHisEvent{},
+	&baseAlertSilences{},
+	&baseMember{},
+	&baseUserRole{},
+	&baseUserPermissions{},
+	&baseNoticeTemplateExample{},
+	&baseRuleGroups{},
+	&baseRuleTemplateGroup{},
+	&baseRuleTemplate{},
+	&baseTenant{},
+	&baseDashboard{},
+	&baseAuditLog{},
+	&baseSettings{},
+	&baseTenantLinkedUsers{},
+	&baseDashboardFolders{},
+	&baseAlertSubscribe{},
+	&baseNoticeRecord{},
+	&baseProbingRule{},
+	&baseFaultCenter{},
+	&baseAiContentRecord{},
+	&baseAlertTransition{},
+}
@@ -0,0 +1,189 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+	"watchAlert/internal/models"
+)
+
+// Migration 数据库版本迁移, Version 递增且不可修改已发布的迁移
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	// 为空时不支持回滚
+	Down func(tx *gorm.DB) error
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt int64
+}
+
+// Latest 当前程序支持的最新版本
+func Latest() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Current 获取数据库当前版本, 未执行过迁移时为 0
+func Current(db *gorm.DB) (int, error) {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return 0, fmt.Errorf("创建迁移记录表失败: %s", err.Error())
+	}
+
+	var version int
+	err := db.Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Prepare 启动时校验数据库版本, 版本高于当前程序时拒绝启动; 存在未执行的迁移时, 开启 autoMigrate 则自动执行, 否则拒绝启动
+// 已有表结构但没有迁移记录的数据库视为基线版本
+func Prepare(db *gorm.DB, autoMigrate bool) error {
+	if db == nil {
+		return errors.New("数据库连接失败")
+	}
+
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	if current == 0 && db.Migrator().HasTable(&baseAlertRule{}) {
+		// 引入版本迁移前的数据库每次启动自动迁移且没有迁移记录, 执行基线迁移补齐表结构并记录版本, 与此前启动时的行为一致
+		if err := Up(db, 1); err != nil {
+			return err
+		}
+		current = 1
+	}
+
+	switch latest := Latest(); {
+	case current > latest:
+		return fmt.Errorf("数据库版本 %d 高于当前程序支持的版本 %d, 请升级程序或执行 migrate down 回滚", current, latest)
+	case current < latest && !autoMigrate:
+		return fmt.Errorf("数据库版本 %d 低于当前程序要求的版本 %d, 请执行 migrate up 或开启 MySQL.autoMigrate", current, latest)
+	case current < latest:
+		return Up(db, 0)
+	}
+
+	return nil
+}
+
+// Up 执行未执行的迁移至目标版本, target 为 0 时迁移至最新版本
+func Up(db *gorm.DB, target int) error {
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	if target == 0 {
+		target = Latest()
+	}
+	if target > Latest() {
+		return fmt.Errorf("目标版本 %d 高于当前程序支持的版本 %d", target, Latest())
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now().Unix(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("执行迁移 %d_%s 失败: %s", m.Version, m.Name, err.Error())
+		}
+		logc.Info(context.Background(), fmt.Sprintf("已执行迁移 %d_%s", m.Version, m.Name))
+	}
+
+	return nil
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移
+func Down(db *gorm.DB, steps int) error {
+	if _, err := Current(db); err != nil {
+		return err
+	}
+
+	var applied []models.SchemaMigration
+	if err := db.Order("version desc").Limit(steps).Find(&applied).Error; err != nil {
+		return err
+	}
+
+	for _, record := range applied {
+		m, ok := find(record.Version)
+		if !ok {
+			return fmt.Errorf("迁移 %d_%s 不在当前程序中, 请使用对应版本的程序回滚", record.Version, record.Name)
+		}
+		if m.Down == nil {
+			return fmt.Errorf("迁移 %d_%s 不支持回滚", m.Version, m.Name)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, "version = ?", m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("回滚迁移 %d_%s 失败: %s", m.Version, m.Name, err.Error())
+		}
+		logc.Info(context.Background(), fmt.Sprintf("已回滚迁移 %d_%s", m.Version, m.Name))
+	}
+
+	return nil
+}
+
+// List 获取所有迁移及执行状态, 包括数据库中存在但当前程序未包含的版本
+func List(db *gorm.DB) ([]Status, error) {
+	if _, err := Current(db); err != nil {
+		return nil, err
+	}
+
+	var applied []models.SchemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	appliedSet := make(map[int]models.SchemaMigration)
+	for _, record := range applied {
+		appliedSet[record.Version] = record
+	}
+
+	var list []Status
+	for _, m := range migrations {
+		record, ok := appliedSet[m.Version]
+		delete(appliedSet, m.Version)
+		list = append(list, Status{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: record.AppliedAt})
+	}
+	for _, record := range appliedSet {
+		list = append(list, Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+func find(version int) (Migration, bool) {
+	for _, m := range migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,293 @@
+package migration
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"watchAlert/internal/models"
+)
+
+// migrations 按版本递增排列, 新的表结构变更需追加迁移, 不能修改已发布的迁移
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		// 基线版本使用冻结的表结构快照, 与此前启动时的自动迁移一致, 已有的数据库执行后只会补齐缺失的表和列
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineModels...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "fault_center_receiver_token",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&models.FaultCenter{}, "ReceiverTokenHash") {
+				if err := m.AddColumn(&models.FaultCenter{}, "ReceiverTokenHash"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&models.FaultCenter{}, "ReceiverTokenHash") {
+				return m.CreateIndex(&models.FaultCenter{}, "ReceiverTokenHash")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&models.FaultCenter{}, "ReceiverTokenHash") {
+				if err := m.DropIndex(&models.FaultCenter{}, "ReceiverTokenHash"); err != nil {
+					return err
+				}
+			}
+			return m.DropColumn(&models.FaultCenter{}, "ReceiverTokenHash")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "datasource_max_concurrent_queries",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.AlertDataSource{}, "MaxConcurrentQueries") {
+				return nil
+			}
+			return m.AddColumn(&models.AlertDataSource{}, "MaxConcurrentQueries")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertDataSource{}, "MaxConcurrentQueries")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "rule_log_sample_size",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.AlertRule{}, "LogSampleSize") {
+				return nil
+			}
+			return m.AddColumn(&models.AlertRule{}, "LogSampleSize")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertRule{}, "LogSampleSize")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "history_event_occurrence",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range []string{"LastSeenTime", "OccurrenceCount"} {
+				if m.HasColumn(&models.AlertHisEvent{}, column) {
+					continue
+				}
+				if err := m.AddColumn(&models.AlertHisEvent{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range []string{"LastSeenTime", "OccurrenceCount"} {
+				if err := m.DropColumn(&models.AlertHisEvent{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "settings_maintenance_config",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.Settings{}, "MaintenanceConfig") {
+				return nil
+			}
+			return m.AddColumn(&models.Settings{}, "MaintenanceConfig")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Settings{}, "MaintenanceConfig")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "notice_record_delivery",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range []string{"DeliveryId", "Attempt", "NextRetryAt"} {
+				if m.HasColumn(&models.NoticeRecord{}, column) {
+					continue
+				}
+				if err := m.AddColumn(&models.NoticeRecord{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range []string{"DeliveryId", "Attempt", "NextRetryAt"} {
+				if err := m.DropColumn(&models.NoticeRecord{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "rule_relabel_configs",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.AlertRule{}, "RelabelConfigs") {
+				return nil
+			}
+			return m.AddColumn(&models.AlertRule{}, "RelabelConfigs")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertRule{}, "RelabelConfigs")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "fault_center_route_tree",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.FaultCenter{}, "RouteTree") {
+				return nil
+			}
+			return m.AddColumn(&models.FaultCenter{}, "RouteTree")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.FaultCenter{}, "RouteTree")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "rule_uid",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&models.AlertRule{}, "Uid") {
+				if err := m.AddColumn(&models.AlertRule{}, "Uid"); err != nil {
+					return err
+				}
+			}
+			// 模型中 UID 的索引已改为租户内唯一索引, 此处按名称创建原有的普通索引, 由版本 13 替换
+			if m.HasIndex(&models.AlertRule{}, "idx_alert_rules_uid") {
+				return nil
+			}
+			return tx.Exec("CREATE INDEX idx_alert_rules_uid ON alert_rules (uid)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertRule{}, "Uid")
+		},
+	},
+	{
+		Version: 11,
+		Name:    "datasource_es_query_guard",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.AlertDataSource{}, "EsQueryGuard") {
+				return nil
+			}
+			return m.AddColumn(&models.AlertDataSource{}, "EsQueryGuard")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertDataSource{}, "EsQueryGuard")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "rule_log_value",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasColumn(&models.AlertRule{}, "LogValue") {
+				return nil
+			}
+			return m.AddColumn(&models.AlertRule{}, "LogValue")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.AlertRule{}, "LogValue")
+		},
+	},
+	{
+		Version: 13,
+		Name:    "rule_uid_unique",
+		// UID 租户内唯一, 未设置 UID 的规则以 NULL 写入, 不参与唯一约束
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.AlterColumn(&models.AlertRule{}, "TenantId"); err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE alert_rules SET uid = NULL WHERE uid = ''").Error; err != nil {
+				return err
+			}
+
+			var duplicate struct {
+				TenantId string
+				Uid      string
+			}
+			err := tx.Raw("SELECT tenant_id, uid FROM alert_rules WHERE uid IS NOT NULL GROUP BY tenant_id, uid HAVING COUNT(*) > 1 LIMIT 1").Scan(&duplicate).Error
+			if err != nil {
+				return err
+			}
+			if duplicate.Uid != "" {
+				return fmt.Errorf("租户 %s 下存在重复的规则 UID %s, 请修改后重新执行迁移", duplicate.TenantId, duplicate.Uid)
+			}
+
+			if m.HasIndex(&models.AlertRule{}, "idx_alert_rules_uid") {
+				if err := m.DropIndex(&models.AlertRule{}, "idx_alert_rules_uid"); err != nil {
+					return err
+				}
+			}
+			if m.HasIndex(&models.AlertRule{}, "idx_rule_tenant_uid") {
+				return nil
+			}
+			return m.CreateIndex(&models.AlertRule{}, "idx_rule_tenant_uid")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&models.AlertRule{}, "idx_rule_tenant_uid") {
+				if err := m.DropIndex(&models.AlertRule{}, "idx_rule_tenant_uid"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("UPDATE alert_rules SET uid = '' WHERE uid IS NULL").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX idx_alert_rules_uid ON alert_rules (uid)").Error
+		},
+	},
+	{
+		Version: 14,
+		Name:    "user_oidc_subject",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"OidcIssuer", "OidcSubject"} {
+				if m.HasColumn(&models.Member{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&models.Member{}, field); err != nil {
+					return err
+				}
+			}
+			if m.HasIndex(&models.Member{}, "idx_member_oidc_subject") {
+				return nil
+			}
+			return m.CreateIndex(&models.Member{}, "idx_member_oidc_subject")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&models.Member{}, "idx_member_oidc_subject") {
+				if err := m.DropIndex(&models.Member{}, "idx_member_oidc_subject"); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"OidcIssuer", "OidcSubject"} {
+				if err := m.DropColumn(&models.Member{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
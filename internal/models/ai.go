@@ -3,6 +3,7 @@ package models
 import "fmt"
 
 type AiParams struct {
+	TenantId string `json:"-" form:"-"`
 	// 规则名称，用来分析告警时，更明确当前是一个什么规则
 	RuleName string `json:"ruleName" form:"ruleName"`
 	RuleId   string `json:"RuleId" form:"ruleId"`
@@ -21,34 +21,57 @@ const (
 )
 
 type AlertCurEvent struct {
-	TenantId               string                 `json:"tenantId"`
-	RuleId                 string                 `json:"rule_id"`
-	RuleName               string                 `json:"rule_name"`
-	DatasourceType         string                 `json:"datasource_type"`
-	DatasourceId           string                 `json:"datasource_id" gorm:"datasource_id"`
-	Fingerprint            string                 `json:"fingerprint"`
-	Severity               string                 `json:"severity"`
-	Metric                 map[string]interface{} `json:"metric" gorm:"metric;serializer:json"`
-	Log                    map[string]interface{} `json:"log" gorm:"log;serializer:json"`
-	SearchQL               string                 `json:"searchQL" gorm:"-"`
-	EvalInterval           int64                  `json:"eval_interval"`
-	ForDuration            int64                  `json:"for_duration"`
-	Annotations            string                 `json:"annotations" gorm:"-"`
-	IsRecovered            bool                   `json:"is_recovered" gorm:"-"`
-	FirstTriggerTime       int64                  `json:"first_trigger_time"` // 第一次触发时间
-	FirstTriggerTimeFormat string                 `json:"first_trigger_time_format" gorm:"-"`
-	RepeatNoticeInterval   int64                  `json:"repeat_notice_interval"`  // 重复通知间隔时间
-	LastEvalTime           int64                  `json:"last_eval_time" gorm:"-"` // 上一次评估时间
-	LastSendTime           int64                  `json:"last_send_time" gorm:"-"` // 上一次发送时间
-	RecoverTime            int64                  `json:"recover_time" gorm:"-"`   // 恢复时间
-	RecoverTimeFormat      string                 `json:"recover_time_format" gorm:"-"`
-	DutyUser               string                 `json:"duty_user" gorm:"-"`
-	DutyUserPhoneNumber    []string               `json:"duty_user_phone_number" gorm:"-"`
-	EffectiveTime          EffectiveTime          `json:"effectiveTime" gorm:"effectiveTime;serializer:json"`
-	FaultCenterId          string                 `json:"faultCenterId"`
-	FaultCenter            FaultCenter            `json:"faultCenter" gorm:"-"`
-	UpgradeState           UpgradeState           `json:"upgradeState" gorm:"-"`
-	Status                 AlertStatus            `json:"status" gorm:"-"` // 事件状态
+	TenantId               string                   `json:"tenantId"`
+	RuleId                 string                   `json:"rule_id"`
+	RuleName               string                   `json:"rule_name"`
+	DatasourceType         string                   `json:"datasource_type"`
+	DatasourceId           string                   `json:"datasource_id" gorm:"datasource_id"`
+	Fingerprint            string                   `json:"fingerprint"`
+	Severity               string                   `json:"severity"`
+	Metric                 map[string]interface{}   `json:"metric" gorm:"metric;serializer:json"`
+	Log                    map[string]interface{}   `json:"log" gorm:"log;serializer:json"`
+	SearchQL               string                   `json:"searchQL" gorm:"-"`
+	EvalInterval           int64                    `json:"eval_interval"`
+	ForDuration            int64                    `json:"for_duration"`
+	Annotations            string                   `json:"annotations" gorm:"-"`
+	IsRecovered            bool                     `json:"is_recovered" gorm:"-"`
+	FirstTriggerTime       int64                    `json:"first_trigger_time"` // 第一次触发时间
+	FirstTriggerTimeFormat string                   `json:"first_trigger_time_format" gorm:"-"`
+	RepeatNoticeInterval   int64                    `json:"repeat_notice_interval"`  // 重复通知间隔时间
+	LastEvalTime           int64                    `json:"last_eval_time" gorm:"-"` // 上一次评估时间
+	LastSendTime           int64                    `json:"last_send_time" gorm:"-"` // 上一次发送时间
+	RecoverTime            int64                    `json:"recover_time" gorm:"-"`   // 恢复时间
+	RecoverTimeFormat      string                   `json:"recover_time_format" gorm:"-"`
+	DutyUser               string                   `json:"duty_user" gorm:"-"`
+	DutyUserPhoneNumber    []string                 `json:"duty_user_phone_number" gorm:"-"`
+	EffectiveTime          EffectiveTime            `json:"effectiveTime" gorm:"effectiveTime;serializer:json"`
+	FaultCenterId          string                   `json:"faultCenterId"`
+	FaultCenter            FaultCenter              `json:"faultCenter" gorm:"-"`
+	UpgradeState           UpgradeState             `json:"upgradeState" gorm:"-"`
+	Status                 AlertStatus              `json:"status" gorm:"-"`                     // 事件状态
+	MessageTemplate        string                   `json:"message_template,omitempty" gorm:"-"` // 规则消息模版
+	EscalationPolicy       []EscalationLevel        `json:"escalationPolicy,omitempty" gorm:"-"` // 规则升级策略
+	RecoverNotify          *bool                    `json:"recoverNotify,omitempty" gorm:"-"`    // 规则恢复通知开关, 为空时沿用故障中心配置
+	FiringDuration         string                   `json:"firing_duration" gorm:"-"`            // 告警持续时长, 恢复通知中使用
+	TraceId                string                   `json:"traceId,omitempty" gorm:"-"`          // 最近一次评估的链路 ID
+	TraceParent            string                   `json:"traceParent,omitempty" gorm:"-"`      // 最近一次评估的 W3C traceparent, 用于关联通知 Span
+	LogSamples             []map[string]interface{} `json:"log_samples,omitempty" gorm:"-"`      // 最近一次评估命中的日志样本, 用于邮件附件
+	InhibitedBy            []InhibitSource          `json:"inhibitedBy,omitempty" gorm:"-"`      // 抑制当前告警的源告警, 查询当前告警时计算
+	// 最近一次评估命中的日志总数, LogSamples 仅保留其中的前 N 条
+	LogCount int `json:"log_count,omitempty" gorm:"-"`
+	// 告警值 (Metric 中的 value) 的名称, 如 error_rate, 日志类规则未配置告警值字段时为 count
+	ValueName string `json:"value_name,omitempty" gorm:"-"`
+	// 最近一次评估的数据源查询耗时, 单位毫秒, 目前仅 ElasticSearch 返回
+	QueryTookMs int64 `json:"query_took_ms,omitempty" gorm:"-"`
+	// 最近一次评估的查询结果不完整 (部分分片失败或远程集群被跳过) 的说明, 此时告警基于部分数据, 可能不准确
+	QueryWarning string `json:"query_warning,omitempty" gorm:"-"`
+	// 最近一次触发时间, 首次触发时间即 FirstTriggerTime
+	LastSeenTime       int64  `json:"last_seen_time" gorm:"-"`
+	LastSeenTimeFormat string `json:"last_seen_time_format" gorm:"-"`
+	// 本次告警期间条件满足的评估次数, 恢复后再次触发时重新计数
+	OccurrenceCount int64 `json:"occurrence_count" gorm:"-"`
+	// 标签重写规则 (keep/drop) 丢弃了该事件, 不再推送至故障中心
+	RelabelDropped bool `json:"-" gorm:"-"`
 }
 
 type UpgradeState struct {
@@ -61,6 +84,9 @@ type UpgradeState struct {
 	HandleOkTime   int64  `json:"HandleOkTime"`   // 点击处理时间
 	HandleSendTime int64  `json:"handleSendTime"` // 处理超时通知时间
 	WhoAreHandle   string `json:"whoAreHandle"`
+
+	EscalationLevel    int   `json:"escalationLevel"`    // 已升级到的级别, 0 表示未升级
+	EscalationSendTime int64 `json:"escalationSendTime"` // 最近一次升级通知时间
 }
 
 type AlertCurEventQuery struct {
@@ -74,6 +100,8 @@ type AlertCurEventQuery struct {
 	Scope          int64  `json:"scope" form:"scope"`
 	Severity       string `json:"severity" form:"severity"`
 	FaultCenterId  string `json:"faultCenterId" form:"faultCenterId"`
+	// 仅查询被抑制的告警
+	Inhibited bool `json:"inhibited" form:"inhibited"`
 	Page
 }
 
@@ -151,6 +179,40 @@ func (alert *AlertCurEvent) validateTransition(newState AlertStatus) error {
 	return nil
 }
 
+// IsRecoverNotify 是否发送恢复通知, 规则配置优先, 未配置时沿用故障中心配置
+func (alert *AlertCurEvent) IsRecoverNotify(faultCenter FaultCenter) bool {
+	if alert.RecoverNotify != nil {
+		return *alert.RecoverNotify
+	}
+	return faultCenter.GetRecoverNotify()
+}
+
+// GetRepeatNoticeInterval 获取重复通知间隔 (分钟), 优先使用故障中心配置, 其次为规则配置, 均未配置时使用默认值
+func (alert *AlertCurEvent) GetRepeatNoticeInterval(faultCenter FaultCenter) int64 {
+	if faultCenter.RepeatNoticeInterval > 0 {
+		return faultCenter.RepeatNoticeInterval
+	}
+	if alert.RepeatNoticeInterval > 0 {
+		return alert.RepeatNoticeInterval
+	}
+	return DefaultRepeatNoticeInterval
+}
+
+// GetFiringDuration 获取告警持续时长 (秒), 从首次触发到恢复, 未恢复时计算到当前
+func (alert *AlertCurEvent) GetFiringDuration() int64 {
+	if alert.FirstTriggerTime <= 0 {
+		return 0
+	}
+	end := alert.RecoverTime
+	if end <= 0 {
+		end = time.Now().Unix()
+	}
+	if end < alert.FirstTriggerTime {
+		return 0
+	}
+	return end - alert.FirstTriggerTime
+}
+
 // handleStateTransition 处理状态转换时的附加操作
 func (alert *AlertCurEvent) handleStateTransition(newState AlertStatus) error {
 	now := time.Now().Unix()
@@ -181,7 +243,7 @@ func (e StateTransitionError) Error() string {
 	return fmt.Sprintf("invalid transition from %s to %s: %s", e.FromState, e.ToState, e.Reason)
 }
 
-// IsArriveForDuration 比对持续时间
+// IsArriveForDuration 比对持续时间, 与 Prometheus 一致, 条件持续满足的时长达到持续时间即告警
 func (alert *AlertCurEvent) IsArriveForDuration() bool {
-	return alert.LastEvalTime-alert.FirstTriggerTime > alert.ForDuration
+	return alert.LastEvalTime-alert.FirstTriggerTime >= alert.ForDuration
 }
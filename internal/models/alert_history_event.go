@@ -19,6 +19,10 @@ type AlertHisEvent struct {
 	RecoverTime      int64                  `json:"recover_time"`       // 恢复时间
 	FaultCenterId    string                 `json:"faultCenterId"`
 	UpgradeState     UpgradeState           `json:"upgradeState" gorm:"metric;serializer:json"`
+
+	// 最近一次触发时间及告警期间条件满足的评估次数
+	LastSeenTime    int64 `json:"last_seen_time"`
+	OccurrenceCount int64 `json:"occurrence_count"`
 }
 
 type AlertHisEventQuery struct {
@@ -0,0 +1,93 @@
+package models
+
+import "fmt"
+
+const (
+	// TransitionAcked 告警被认领, 认领及处理不改变告警状态, 仅记录变更
+	TransitionAcked = "acked"
+	// TransitionHandled 告警被处理
+	TransitionHandled = "handled"
+
+	// TransitionActorSystem 告警评估产生的状态变更
+	TransitionActorSystem = "system"
+)
+
+// AlertTransition 告警状态变更记录, 用于统计规则的告警次数、认领人及故障复盘
+type AlertTransition struct {
+	TenantId      string                 `json:"tenantId" gorm:"index"`
+	ID            string                 `json:"id"`
+	FaultCenterId string                 `json:"faultCenterId"`
+	RuleId        string                 `json:"ruleId" gorm:"index"`
+	RuleName      string                 `json:"ruleName"`
+	Fingerprint   string                 `json:"fingerprint"`
+	Severity      string                 `json:"severity"`
+	FromState     string                 `json:"fromState"`
+	ToState       string                 `json:"toState"`
+	Metric        map[string]interface{} `json:"metric" gorm:"metric;serializer:json"`
+	// 变更人, 告警评估产生的变更为 system
+	Actor     string `json:"actor"`
+	CreatedAt int64  `json:"createdAt" gorm:"index"`
+}
+
+type AlertTransitionQuery struct {
+	TenantId      string `json:"tenantId" form:"tenantId"`
+	FaultCenterId string `json:"faultCenterId" form:"faultCenterId"`
+	RuleId        string `json:"ruleId" form:"ruleId"`
+	Severity      string `json:"severity" form:"severity"`
+	Fingerprint   string `json:"fingerprint" form:"fingerprint"`
+	State         string `json:"state" form:"state"`
+	Actor         string `json:"actor" form:"actor"`
+	StartAt       int64  `json:"startAt" form:"startAt"`
+	EndAt         int64  `json:"endAt" form:"endAt"`
+	Page
+}
+
+const (
+	TransitionExportFormatCSV    = "csv"
+	TransitionExportFormatNDJSON = "ndjson"
+)
+
+// AlertTransitionExport 导出告警状态变更记录, 须指定时间范围
+type AlertTransitionExport struct {
+	AlertTransitionQuery
+	// 导出格式 csv / ndjson, 默认 csv
+	Format string `json:"format" form:"format"`
+}
+
+// Validate 校验导出参数, 格式为空时使用 csv
+func (r *AlertTransitionExport) Validate() error {
+	if r.StartAt == 0 || r.EndAt == 0 || r.StartAt > r.EndAt {
+		return fmt.Errorf("导出须指定有效的时间范围")
+	}
+
+	switch r.Format {
+	case "":
+		r.Format = TransitionExportFormatCSV
+	case TransitionExportFormatCSV, TransitionExportFormatNDJSON:
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", r.Format)
+	}
+
+	return nil
+}
+
+type AlertTransitionResponse struct {
+	List []AlertTransition `json:"list"`
+	Page
+}
+
+// NewAlertTransition 根据告警事件生成状态变更记录
+func NewAlertTransition(event AlertCurEvent, from, to, actor string) AlertTransition {
+	return AlertTransition{
+		TenantId:      event.TenantId,
+		FaultCenterId: event.FaultCenterId,
+		RuleId:        event.RuleId,
+		RuleName:      event.RuleName,
+		Fingerprint:   event.Fingerprint,
+		Severity:      event.Severity,
+		FromState:     from,
+		ToState:       to,
+		Metric:        event.Metric,
+		Actor:         actor,
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// AlertmanagerDatasourceType 通过 Alertmanager webhook 接入的告警事件的数据源类型
+const AlertmanagerDatasourceType = "Alertmanager"
+
+const (
+	AlertmanagerStatusFiring   = "firing"
+	AlertmanagerStatusResolved = "resolved"
+)
+
+// AlertmanagerWebhook Alertmanager webhook 通知内容, 见 https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type AlertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AlertmanagerReceiveReq 接收 Alertmanager 推送的告警, Token 对应接入的故障中心
+type AlertmanagerReceiveReq struct {
+	Token   string
+	Webhook AlertmanagerWebhook
+}
+
+// AlertmanagerReceiveResult 接收结果
+type AlertmanagerReceiveResult struct {
+	Firing   int `json:"firing"`
+	Resolved int `json:"resolved"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/base64"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -15,19 +17,138 @@ type AlertDataSource struct {
 	Auth             Auth                   `json:"Auth" gorm:"auth;serializer:json"`
 	DsAliCloudConfig DsAliCloudConfig       `json:"dsAliCloudConfig" gorm:"dsAliCloudConfig;serializer:json"`
 	AWSCloudWatch    AWSCloudWatch          `json:"awsCloudwatch" gorm:"awsCloudwatch;serializer:json"`
+	SQLConfig        DsSQLConfig            `json:"sqlConfig" gorm:"sqlConfig;serializer:json"`
+	HTTPProbeConfig  DsHTTPProbeConfig      `json:"httpProbeConfig" gorm:"httpProbeConfig;serializer:json"`
 	Description      string                 `json:"description"`
 	KubeConfig       string                 `json:"kubeConfig"`
 	Enabled          *bool                  `json:"enabled" `
+	// 告警评估时对该数据源的最大并发查询数, 0 表示使用全局配置 QueryLimit.maxConcurrent
+	MaxConcurrentQueries int `json:"maxConcurrentQueries"`
+	// ElasticSearch 查询成本限制, 查询发送前校验
+	EsQueryGuard EsQueryGuard `json:"esQueryGuard" gorm:"esQueryGuard;serializer:json"`
+}
+
+const (
+	// EsQueryGuardModeReject 超出限制时拒绝查询
+	EsQueryGuardModeReject = "reject"
+	// EsQueryGuardModeWarn 超出限制时仅记录日志, 查询照常执行
+	EsQueryGuardModeWarn = "warn"
+)
+
+// EsQueryGuard ElasticSearch 查询成本限制, 避免前导通配符、超长时间范围或超大返回条数的查询拖垮集群, 各项为 0 时不限制
+type EsQueryGuard struct {
+	Enabled bool `json:"enabled"`
+	// 超出限制时的处理方式, 默认 reject
+	Mode string `json:"mode"`
+	// 查询时间范围上限, 单位分钟
+	MaxScope int64 `json:"maxScope"`
+	// 单次查询返回或分页拉取的日志条数上限
+	MaxSize int `json:"maxSize"`
+	// 禁止分词字段上的前导通配符 (如 *foo*) 及前导 .* 的正则匹配, 以 .keyword 结尾的字段不视为分词字段
+	DenyLeadingWildcard bool `json:"denyLeadingWildcard"`
+	// 允许规则通过 bypassQueryGuard 跳过限制
+	AllowBypass bool `json:"allowBypass"`
+}
+
+func (g EsQueryGuard) GetMode() string {
+	if g.Mode == "" {
+		return EsQueryGuardModeReject
+	}
+	return g.Mode
+}
+
+// Validate 校验查询成本限制
+func (g EsQueryGuard) Validate() error {
+	switch g.GetMode() {
+	case EsQueryGuardModeReject, EsQueryGuardModeWarn:
+	default:
+		return fmt.Errorf("查询成本限制的处理方式无效: %s, 可选 reject / warn", g.Mode)
+	}
+	if g.MaxScope < 0 || g.MaxSize < 0 {
+		return fmt.Errorf("查询成本限制的时间范围及返回条数上限不能小于 0")
+	}
+	return nil
 }
 
 type HTTP struct {
 	URL     string `json:"url"`
 	Timeout int64  `json:"timeout"`
+	TLS     TLS    `json:"tls"`
+}
+
+// TLS 自定义证书配置
+type TLS struct {
+	CACert             string `json:"caCert"`             // CA 证书, PEM 格式
+	ClientCert         string `json:"clientCert"`         // 客户端证书, PEM 格式
+	ClientKey          string `json:"clientKey"`          // 客户端私钥, PEM 格式
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"` // 跳过证书校验
 }
 
 type Auth struct {
-	User string `json:"user"`
-	Pass string `json:"pass"`
+	// 认证方式, 为空时根据 User/Pass 使用 Basic 认证
+	AuthType string `json:"authType"`
+	User     string `json:"user"`
+	Pass     string `json:"pass"`
+	// ApiKey / Bearer 认证使用的 Token
+	Token string `json:"token"`
+	// SigV4 AWS OpenSearch 请求签名配置
+	SigV4 SigV4 `json:"sigV4"`
+}
+
+// SigV4 AWS SigV4 签名配置, AccessKey 为空时使用 AWS 默认凭证链 (环境变量 / 共享配置 / IAM 角色)
+type SigV4 struct {
+	Region    string `json:"region"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	// 签名的服务名, OpenSearch 托管集群为 es, OpenSearch Serverless 为 aoss, 为空时默认 es
+	Service string `json:"service"`
+}
+
+const (
+	AuthTypeBasic  = "Basic"
+	AuthTypeApiKey = "ApiKey"
+	AuthTypeBearer = "Bearer"
+	AuthTypeSigV4  = "SigV4"
+)
+
+const (
+	SigV4ServiceOpenSearch           = "es"
+	SigV4ServiceOpenSearchServerless = "aoss"
+)
+
+// GetService 获取签名的服务名, 未配置时默认 es
+func (s SigV4) GetService() string {
+	if s.Service == "" {
+		return SigV4ServiceOpenSearch
+	}
+	return s.Service
+}
+
+// GetAuthorization 获取 Authorization 请求头的值, 未配置认证信息时返回空
+func (a Auth) GetAuthorization() string {
+	switch a.AuthType {
+	case AuthTypeApiKey:
+		return "ApiKey " + a.Token
+	case AuthTypeBearer:
+		return "Bearer " + a.Token
+	case AuthTypeSigV4:
+		// SigV4 按请求签名, 由 HTTP Transport 生成 Authorization
+		return ""
+	default:
+		if a.User == "" && a.Pass == "" {
+			return ""
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.User+":"+a.Pass))
+	}
+}
+
+// GetAuthHeader 获取认证请求头
+func (a Auth) GetAuthHeader() map[string]string {
+	header := make(map[string]string)
+	if authorization := a.GetAuthorization(); authorization != "" {
+		header["Authorization"] = authorization
+	}
+	return header
 }
 
 type DatasourceQuery struct {
@@ -37,10 +158,45 @@ type DatasourceQuery struct {
 	Query    string `json:"query" form:"query"`
 }
 
+// DatasourceSchemaQuery 查询日志数据源的字段及索引, 用于规则编辑时的自动补全
+type DatasourceSchemaQuery struct {
+	TenantId     string `json:"tenantId" form:"tenantId"`
+	DatasourceId string `json:"datasourceId" form:"datasourceId"`
+	// 查询字段时为索引名称, 查询索引时为索引匹配模式, 支持通配符
+	Index string `json:"index" form:"index"`
+}
+
 type DsAliCloudConfig struct {
 	AliCloudEndpoint string `json:"alicloudEndpoint"`
 	AliCloudAk       string `json:"alicloudAk"`
 	AliCloudSk       string `json:"alicloudSk"`
+	// 默认的 Project / LogStore, 规则未配置时使用, 同时用于健康检查
+	Project  string `json:"project"`
+	LogStore string `json:"logstore"`
+}
+
+// DsSQLConfig SQL 数据源连接配置, 账号密码使用 Auth.User / Auth.Pass
+type DsSQLConfig struct {
+	Driver   string `json:"driver"` // mysql / postgres
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Database string `json:"database"`
+	// 附加连接参数, 如 charset=utf8mb4&loc=Local 或 sslmode=disable
+	Params string `json:"params"`
+}
+
+const (
+	SQLDriverMySQL    = "mysql"
+	SQLDriverPostgres = "postgres"
+)
+
+// DsHTTPProbeConfig HTTP 拨测数据源配置, 拨测地址、超时及 TLS 使用 HTTP 配置
+type DsHTTPProbeConfig struct {
+	Method string            `json:"method"` // 默认 GET
+	Header map[string]string `json:"header"`
+	Body   string            `json:"body"`
+	// 响应内容匹配的正则, 配置后 body_match 为 1 表示匹配成功
+	BodyMatch string `json:"bodyMatch"`
 }
 
 type AWSCloudWatch struct {
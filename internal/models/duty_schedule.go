@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -35,6 +37,50 @@ type DutyManagement struct {
 	CurDutyUser string `json:"curDutyUser"`
 	CreateBy    string `json:"create_by"`
 	CreateAt    int64  `json:"create_at"`
+	// 值班表所在时区, 如 Asia/Shanghai, 为空时使用服务端时区
+	Timezone string `json:"timezone"`
+	// 每日交接班时间, 当天零点起的秒数, 如 09:00 交接为 32400, 交接前仍由前一天的值班人员负责
+	HandoverTime int `json:"handoverTime"`
+}
+
+// GetDutyDate 获取指定时间对应的值班日期, 格式与值班表一致, 如 2024-1-2
+func (d DutyManagement) GetDutyDate(t time.Time) string {
+	if loc, err := time.LoadLocation(d.Timezone); d.Timezone != "" && err == nil {
+		t = t.In(loc)
+	}
+	return t.Add(-time.Duration(d.HandoverTime) * time.Second).Format("2006-1-2")
+}
+
+// DutyOverride 临时换班, 有效期内优先于值班表生效
+type DutyOverride struct {
+	TenantId string `json:"tenantId"`
+	ID       string `json:"id"`
+	DutyId   string `json:"dutyId"`
+	Users
+	StartsAt int64  `json:"startsAt"`
+	EndsAt   int64  `json:"endsAt"`
+	Reason   string `json:"reason"`
+	CreateBy string `json:"createBy"`
+	CreateAt int64  `json:"createAt"`
+}
+
+type DutyOverrideQuery struct {
+	TenantId string `json:"tenantId" form:"tenantId"`
+	ID       string `json:"id" form:"id"`
+	DutyId   string `json:"dutyId" form:"dutyId"`
+}
+
+// OnCallUser 当前值班人员
+type OnCallUser struct {
+	DutyId     string `json:"dutyId"`
+	DutyDate   string `json:"dutyDate"`
+	UserId     string `json:"userid"`
+	Username   string `json:"username"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+	DutyUserId string `json:"dutyUserId"`
+	// 命中的临时换班 ID, 为空表示按值班表排班
+	OverrideId string `json:"overrideId"`
 }
 
 type DutyManagementQuery struct {
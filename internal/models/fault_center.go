@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"slices"
+	"strings"
 )
 
 // 常量定义
@@ -12,6 +13,14 @@ const (
 	HandleStatus      = 2
 )
 
+// 告警聚合方式
+const (
+	// AggregationTypeRule 按规则聚合
+	AggregationTypeRule = "Rule"
+	// AggregationTypeLabels 按标签分组聚合, 标签值相同的告警合并为一条通知
+	AggregationTypeLabels = "Labels"
+)
+
 type FaultCenter struct {
 	TenantId              string            `json:"tenantId"`
 	ID                    string            `json:"id"`
@@ -31,6 +40,31 @@ type FaultCenter struct {
 	IsUpgradeEnabled      *bool             `json:"isUpgradeEnabled" gorm:"column:isUpgradeEnabled"`
 	UpgradableSeverity    []string          `json:"upgradableSeverity" gorm:"column:upgradableSeverity;serializer:json"`
 	UpgradeStrategy       []UpgradeStrategy `json:"upgradeStrategy" gorm:"column:upgradeStrategy;serializer:json"`
+	GroupBy               []string          `json:"groupBy" gorm:"column:groupBy;serializer:json"` // 按标签分组聚合时的标签
+	GroupWait             int64             `json:"groupWait"`                                     // 新分组首次通知前的等待时间, 单位秒
+	GroupInterval         int64             `json:"groupInterval"`                                 // 同一分组两次通知的最小间隔, 单位秒
+	InhibitRules          []InhibitRule     `json:"inhibitRules" gorm:"column:inhibitRules;serializer:json"`
+	// 告警路由树, 配置子路由后替代 NoticeRoutes, 未匹配任何子路由的告警发送至 NoticeIds
+	RouteTree NoticeRouteNode `json:"routeTree" gorm:"column:routeTree;serializer:json"`
+	// 外部告警接入 Token 的 SHA-256, Token 仅在生成时返回
+	ReceiverTokenHash string `json:"-" gorm:"column:receiverTokenHash;index"`
+}
+
+// InhibitRule 抑制规则, 源告警触发期间, 与其 Equal 标签值相同的目标告警不发送通知
+type InhibitRule struct {
+	Name           string         `json:"name"`
+	SourceMatchers []SilenceLabel `json:"sourceMatchers"`
+	TargetMatchers []SilenceLabel `json:"targetMatchers"`
+	// 源告警与目标告警需要相同的标签, 如 instance
+	Equal []string `json:"equal"`
+}
+
+// InhibitSource 抑制当前告警的源告警
+type InhibitSource struct {
+	InhibitRule string                 `json:"inhibitRule"`
+	Fingerprint string                 `json:"fingerprint"`
+	RuleName    string                 `json:"ruleName"`
+	Metric      map[string]interface{} `json:"metric"`
 }
 
 type UpgradeStrategy struct {
@@ -92,6 +126,22 @@ type NoticeRoute struct {
 	NoticeIds []string `json:"noticeIds" gorm:"column:noticeIds;serializer:json"`
 }
 
+// NoticeRouteNode 告警路由节点, 与 Alertmanager 的 route 语义一致: 告警自根节点逐级匹配子路由, 命中的最深层路由决定通知对象;
+// 子路由未配置通知对象时继承上级路由, 未开启 Continue 时命中后不再匹配后续的同级路由
+type NoticeRouteNode struct {
+	Name string `json:"name"`
+	// 匹配条件, 语义与静默规则一致, 为空时匹配所有告警, 根节点的匹配条件不生效
+	Matchers  []SilenceLabel    `json:"matchers"`
+	NoticeIds []string          `json:"noticeIds"`
+	Continue  bool              `json:"continue"`
+	Routes    []NoticeRouteNode `json:"routes"`
+}
+
+// IsRouteTreeEnabled 是否配置了告警路由树
+func (f *FaultCenter) IsRouteTreeEnabled() bool {
+	return len(f.RouteTree.Routes) > 0
+}
+
 func (f *FaultCenter) TableName() string {
 	return "w8t_fault_center"
 }
@@ -110,15 +160,41 @@ func (f *FaultCenter) GetRecoverNotify() bool {
 	return *f.RecoverNotify
 }
 
+// DefaultRepeatNoticeInterval 未配置重复通知间隔时的默认值, 单位分钟
+const DefaultRepeatNoticeInterval int64 = 60
+
 func (f *FaultCenter) GetAlarmAggregationType() string {
 	return f.AggregationType
 }
 
+// IsLabelsAggregation 是否按标签分组聚合
+func (f *FaultCenter) IsLabelsAggregation() bool {
+	return f.AggregationType == AggregationTypeLabels && len(f.GroupBy) > 0
+}
+
+// GetGroupKey 根据分组标签计算分组 Key, 如 cluster=prod,service=api
+func (f *FaultCenter) GetGroupKey(metric map[string]interface{}) string {
+	pairs := make([]string, 0, len(f.GroupBy))
+	for _, label := range f.GroupBy {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", label, metric[label]))
+	}
+	return strings.Join(pairs, ",")
+}
+
 type FaultCenterQuery struct {
 	TenantId string `form:"tenantId"`
 	ID       string `form:"id"`
 	Name     string `form:"name"`
 	Query    string `from:"query"`
+	// 按外部告警接入 Token 的 SHA-256 查询
+	ReceiverTokenHash string `json:"-" form:"-"`
+}
+
+// FaultCenterReceiverToken 外部告警接入 Token, 推送时通过 Authorization: Bearer <token> 携带
+type FaultCenterReceiverToken struct {
+	Token string `json:"token"`
+	// Alertmanager webhook 接入地址
+	AlertmanagerPath string `json:"alertmanagerPath"`
 }
 
 type AlertEventCacheKey string
@@ -127,6 +203,13 @@ func BuildAlertEventCacheKey(tenantId, faultCenterId string) AlertEventCacheKey
 	return AlertEventCacheKey(fmt.Sprintf("w8t:%s:%s:%s.events", tenantId, FaultCenterPrefix, faultCenterId))
 }
 
+// AlertSendCacheKey 按指纹记录事件最近一次通知时间, 用于计算重复通知间隔
+type AlertSendCacheKey string
+
+func BuildAlertSendCacheKey(tenantId, faultCenterId string) AlertSendCacheKey {
+	return AlertSendCacheKey(fmt.Sprintf("w8t:%s:%s:%s.send", tenantId, FaultCenterPrefix, faultCenterId))
+}
+
 type AlertMuteCacheKey string
 
 func BuildAlertMuteCacheKey(tenantId, faultCenterId string) AlertMuteCacheKey {
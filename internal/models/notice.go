@@ -5,17 +5,24 @@ import (
 )
 
 type AlertNotice struct {
-	TenantId     string   `json:"tenantId"`
-	Uuid         string   `json:"uuid"`
-	Name         string   `json:"name"`
-	DutyId       string   `json:"dutyId"`
-	NoticeType   string   `json:"noticeType"`
-	NoticeTmplId string   `json:"noticeTmplId"`
-	DefaultHook  string   `json:"hook" gorm:"column:hook"`
-	DefaultSign  string   `json:"sign" gorm:"column:sign"`
-	Routes       []Route  `json:"routes" gorm:"column:routes;serializer:json"`
-	Email        Email    `json:"email" gorm:"email;serializer:json"`
-	PhoneNumber  []string `json:"phoneNumber" gorm:"phoneNumber;serializer:json"`
+	TenantId     string    `json:"tenantId"`
+	Uuid         string    `json:"uuid"`
+	Name         string    `json:"name"`
+	DutyId       string    `json:"dutyId"`
+	NoticeType   string    `json:"noticeType"`
+	NoticeTmplId string    `json:"noticeTmplId"`
+	DefaultHook  string    `json:"hook" gorm:"column:hook"`
+	DefaultSign  string    `json:"sign" gorm:"column:sign"`
+	Routes       []Route   `json:"routes" gorm:"column:routes;serializer:json"`
+	Email        Email     `json:"email" gorm:"email;serializer:json"`
+	PhoneNumber  []string  `json:"phoneNumber" gorm:"phoneNumber;serializer:json"`
+	Telegram     Telegram  `json:"telegram" gorm:"telegram;serializer:json"`
+	PagerDuty    PagerDuty `json:"pagerDuty" gorm:"pagerDuty;serializer:json"`
+	OpsGenie     OpsGenie  `json:"opsGenie" gorm:"opsGenie;serializer:json"`
+	WebHook      WebHook   `json:"webHook" gorm:"webHook;serializer:json"`
+	Slack        Slack     `json:"slack" gorm:"slack;serializer:json"`
+	// 限流, 每分钟最多发送的消息数, 超出后合并为汇总消息, 0 表示不限流
+	RateLimit int `json:"rateLimit"`
 }
 
 type Route struct {
@@ -35,6 +42,50 @@ type Email struct {
 	Subject string   `json:"subject"`
 	To      []string `json:"to" gorm:"column:to;serializer:json"`
 	CC      []string `json:"cc" gorm:"column:cc;serializer:json"`
+	// 日志类告警以 CSV 附件发送命中的日志样本
+	AttachLogs bool `json:"attachLogs"`
+}
+
+// Telegram Telegram Bot 通知配置
+type Telegram struct {
+	BotToken string `json:"botToken"`
+	ChatId   string `json:"chatId"`
+	// Bot API 地址, 默认 https://api.telegram.org, 可配置为代理地址
+	ApiURL string `json:"apiUrl"`
+}
+
+// PagerDuty PagerDuty Events API v2 通知配置
+type PagerDuty struct {
+	RoutingKey string `json:"routingKey"`
+	// Events API 地址, 默认 https://events.pagerduty.com/v2/enqueue
+	ApiURL string `json:"apiUrl"`
+}
+
+// Slack Slack Bot 通知配置, 需授予 chat:write 权限
+type Slack struct {
+	BotToken string `json:"botToken"`
+	Channel  string `json:"channel"`
+	// Web API 地址, 默认 https://slack.com/api
+	ApiURL string `json:"apiUrl"`
+}
+
+// WebHook 自定义 Hook 通知配置, 地址使用 Hook 字段
+type WebHook struct {
+	// 请求方法, 默认 POST
+	Method string `json:"method"`
+	// 自定义请求头, 如 Authorization
+	Headers map[string]string `json:"headers"`
+	// 请求体模版 (Go text/template), 为空时发送告警事件 JSON
+	BodyTemplate string `json:"bodyTemplate"`
+	// 签名密钥, 配置后使用 HMAC-SHA256 对请求体签名
+	Secret string `json:"secret"`
+}
+
+// OpsGenie OpsGenie 通知配置
+type OpsGenie struct {
+	ApiKey string `json:"apiKey"`
+	// API 地址, 默认 https://api.opsgenie.com, 欧洲区为 https://api.eu.opsgenie.com
+	ApiURL string `json:"apiUrl"`
 }
 
 type AlertRecord struct {
@@ -68,6 +119,15 @@ type NoticeQuery struct {
 	Page
 }
 
+// RequestNoticeTest 测试通知请求, 未填写通知类型时使用 Uuid 对应的已保存通知对象
+type RequestNoticeTest struct {
+	AlertNotice
+	// 测试告警的等级, 用于匹配通知路由, 默认 P2
+	Severity string `json:"severity"`
+	// 是否发送恢复通知
+	IsRecovered bool `json:"isRecovered"`
+}
+
 type NoticeTemplateExampleQuery struct {
 	Id         string `json:"id" form:"id"`
 	Name       string `json:"name" form:"name"`
@@ -83,9 +143,29 @@ type NoticeRecord struct {
 	NType    string `json:"nType"`    // 通知类型
 	NObj     string `json:"nObj"`     // 通知对象
 	Severity string `json:"severity"` // 告警等级
-	Status   int    `json:"status"`   // 通知状态 0 成功 1 失败
+	Status   int    `json:"status"`   // 通知状态 0 成功 1 失败 2 重试中
 	AlarmMsg string `json:"alarmMsg"` // 告警信息
 	ErrMsg   string `json:"errMsg"`   // 错误信息
+
+	// 同一条通知的各次发送尝试使用相同的 DeliveryId, Attempt 为第几次尝试
+	DeliveryId string `json:"deliveryId"`
+	Attempt    int    `json:"attempt"`
+	// 发送失败后的下次重试时间, 仅重试中的记录有值
+	NextRetryAt int64 `json:"nextRetryAt"`
+}
+
+const (
+	// NoticeRecordStatusDelivered 通知对象返回 2xx 且响应内容确认发送成功
+	NoticeRecordStatusDelivered = 0
+	// NoticeRecordStatusFailed 发送失败且已达到最大尝试次数
+	NoticeRecordStatusFailed = 1
+	// NoticeRecordStatusRetrying 发送失败, 已加入重试队列
+	NoticeRecordStatusRetrying = 2
+)
+
+// BuildNoticeRetryCacheKey 通知重试队列, 按下次重试时间排序
+func BuildNoticeRetryCacheKey() string {
+	return "w8t:notice:retry.queue"
 }
 
 type CountRecord struct {
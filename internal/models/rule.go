@@ -1,8 +1,17 @@
 package models
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"regexp"
+)
+
 type AlertRule struct {
 	//gorm.Model
-	TenantId             string            `json:"tenantId"`
+	TenantId             string            `json:"tenantId" gorm:"size:191;uniqueIndex:idx_rule_tenant_uid,priority:1"`
 	RuleId               string            `json:"ruleId" gorm:"ruleId"`
 	RuleGroupId          string            `json:"ruleGroupId"`
 	ExternalLabels       map[string]string `json:"externalLabels" gorm:"externalLabels;serializer:json"`
@@ -37,27 +46,353 @@ type AlertRule struct {
 
 	ElasticSearchConfig ElasticSearchConfig `json:"elasticSearchConfig" gorm:"elasticSearchConfig;serializer:json"`
 
+	ClickHouseConfig ClickHouseConfig `json:"clickHouseConfig" gorm:"clickHouseConfig;serializer:json"`
+
+	GraylogConfig GraylogConfig `json:"graylogConfig" gorm:"graylogConfig;serializer:json"`
+
+	SQLConfig SQLConfig `json:"sqlConfig" gorm:"sqlConfig;serializer:json"`
+
+	HTTPProbeConfig HTTPProbeConfig `json:"httpProbeConfig" gorm:"httpProbeConfig;serializer:json"`
+
 	LogEvalCondition string `json:"logEvalCondition" gorm:"logEvalCondition;serializer:json"`
+	// 提升为告警标签的日志字段, 为空时取所有日志共有的键值对
+	LogLabelFields []string `json:"logLabelFields" gorm:"logLabelFields;serializer:json"`
+	// 告警事件及通知中保留的日志样本条数, 按查询结果的顺序 (ElasticSearch 默认按时间倒序) 取前 N 条, 0 表示默认 20 条
+	LogSampleSize int `json:"logSampleSize" gorm:"column:logSampleSize"`
+	// 日志类规则的告警值, 指定字段或聚合指标作为告警值 (如 error_rate), 未配置时为命中的日志总数
+	LogValue LogValueConfig `json:"logValue" gorm:"column:logValue;serializer:json"`
+
+	// 消息模版 (Go text/template), 配置后替换通知模版中的告警内容, 可使用 .Labels、.Value、.ValueName、.LogSamples、.LogCount、.OccurrenceCount、.LastSeenTime 及 humanizeDuration、toJson 等函数
+	MessageTemplate string `json:"messageTemplate" gorm:"type:text"`
+
+	// 升级策略, 告警未认领时超时后逐级通知, 认领或恢复后停止升级
+	EscalationPolicy []EscalationLevel `json:"escalationPolicy" gorm:"column:escalationPolicy;serializer:json"`
+
+	// 恢复通知开关, 为空时沿用故障中心的配置, 用于屏蔽频繁抖动规则的恢复通知
+	RecoverNotify *bool `json:"recoverNotify" gorm:"column:recoverNotify"`
+
+	// 备用数据源, 主数据源 ID -> 同类型的备用数据源 ID, 主数据源不可用时切换评估, 恢复后切回
+	FailoverDatasources map[string]string `json:"failoverDatasources" gorm:"column:failoverDatasources;serializer:json"`
+
+	// 客户端提供的稳定标识, 租户内唯一, GitOps 等工具重复提交同一 UID 的规则时原地更新, 不再重复创建; 未设置时以 NULL 写入, 不参与唯一约束
+	Uid string `json:"uid" gorm:"column:uid;size:191;serializer:nullstring;uniqueIndex:idx_rule_tenant_uid,priority:2"`
+
+	// 标签重写规则, 按顺序作用于告警事件的标签, 在路由、聚合及通知前统一标签名称, 事件指纹仍按重写前的标签计算
+	RelabelConfigs []RelabelConfig `json:"relabelConfigs" gorm:"column:relabelConfigs;serializer:json"`
+
+	// 评估抖动, 单位与评估间隔相同, 规则启动时随机延迟 0 ~ EvalJitter 后开始评估, 分散各规则的评估时间, 不超过评估间隔
+	EvalJitter int64 `json:"evalJitter" gorm:"column:evalJitter"`
+
+	// 持续时间 (秒), 告警条件在连续评估中持续满足该时长后才由预告警转为告警, 为 0 时立即告警
+	ForDuration int64 `json:"forDuration" gorm:"column:forDuration"`
 
 	FaultCenterId string `json:"faultCenterId"`
 	Enabled       *bool  `json:"enabled" gorm:"enabled"`
 }
 
+const (
+	// MaxRuleUidLength 规则 UID 的最大长度
+	MaxRuleUidLength = 128
+)
+
+// ErrRuleUidConflict 规则 UID 已被其他规则使用
+var ErrRuleUidConflict = errors.New("规则 UID 冲突")
+
+func init() {
+	schema.RegisterSerializer("nullstring", NullStringSerializer{})
+}
+
+// NullStringSerializer 空字符串以 NULL 写入, 读取 NULL 时为空字符串, 用于允许为空的唯一索引列
+type NullStringSerializer struct{}
+
+func (NullStringSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (NullStringSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if value, _ := fieldValue.(string); value != "" {
+		return value, nil
+	}
+	return nil, nil
+}
+
+const (
+	// DefaultLogSampleSize 默认保留的日志样本条数
+	DefaultLogSampleSize = 20
+	// MaxLogSampleSize 日志样本条数上限, 避免通知消息超出渠道的长度限制
+	MaxLogSampleSize = 100
+)
+
+// LogValueConfig 日志类规则告警值的提取方式, 仅影响告警值的展示, 不改变告警条件的评估
+type LogValueConfig struct {
+	// 作为告警值的字段, 聚合查询为指标名称, 其余为首条日志中的字段, 嵌套字段以 . 分隔
+	Field string `json:"field"`
+	// 告警值名称, 通知中展示为 "名称 = 告警值", 为空时取 Field
+	Name string `json:"name"`
+}
+
+// GetName 获取告警值名称, 未配置字段时返回空
+func (c LogValueConfig) GetName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Field
+}
+
+// GetLogSampleSize 获取日志样本条数, 未配置时默认 20 条
+func (a AlertRule) GetLogSampleSize() int {
+	if a.LogSampleSize <= 0 {
+		return DefaultLogSampleSize
+	}
+	return min(a.LogSampleSize, MaxLogSampleSize)
+}
+
 type ElasticSearchConfig struct {
 	Index           string            `json:"index"`
 	Scope           int64             `json:"scope"`
 	Filter          []EsQueryFilter   `json:"filter"`
 	FilterCondition EsFilterCondition `json:"filterCondition"`
 	EsQueryType     EsQueryType       `json:"queryType"`
-	QueryWildcard   int64             `json:"queryWildcard"` // 0 精准匹配，1 模糊匹配
+	QueryWildcard   int64             `json:"queryWildcard"` // 0 精准匹配，1 模糊匹配，2 正则匹配，3 短语匹配
 	RawJson         string            `json:"rawJson"`
+	Aggregation     EsAggregation     `json:"aggregation"`
+	MaxLogs         int               `json:"maxLogs"`        // 最大拉取日志条数, 0 表示不分页
+	TimestampField  string            `json:"timestampField"` // 时间字段, 默认 @timestamp
+	IndexPattern    bool              `json:"indexPattern"`   // 索引模式, 按查询时间范围展开日期索引并忽略不存在的索引
+	Highlight       EsHighlight       `json:"highlight"`
+	SortField       string            `json:"sortField"`       // 排序字段, 默认时间字段
+	SortAscending   bool              `json:"sortAscending"`   // 默认降序, 最新的日志在前
+	Size            int               `json:"size"`            // 返回的日志条数, 0 使用 ES 默认值, 分页拉取时以 maxLogs 为准
+	Clusters        []string          `json:"clusters"`        // 跨集群搜索的远程集群别名, _local 表示本地集群
+	MaxResultWindow int               `json:"maxResultWindow"` // 索引的 index.max_result_window, 默认 10000
+	AutoPaginate    bool              `json:"autoPaginate"`    // size 超出 maxResultWindow 时自动切换为分页拉取
+	FieldTypes      []EsFieldType     `json:"fieldTypes"`      // 字段类型声明, 按声明转换 _source 中的字段值
+	CountOnly       bool              `json:"countOnly"`       // 仅统计命中的文档数, 通过 _count 查询, 不拉取日志内容
+	// 运行时字段, 查询时通过脚本计算, 可在过滤条件、排序及聚合中引用, 并返回至日志内容中
+	RuntimeFields []EsRuntimeField `json:"runtimeFields"`
+	// 跳过数据源的查询成本限制, 仅在数据源允许 (allowBypass) 时生效
+	BypassQueryGuard bool `json:"bypassQueryGuard"`
+}
+
+// EsLocalCluster 跨集群搜索时表示本地集群
+const EsLocalCluster = "_local"
+
+// IsMultiMetricAggregation 是否为多指标聚合查询
+func (e ElasticSearchConfig) IsMultiMetricAggregation() bool {
+	return e.EsQueryType == EsQueryTypeAggregation && len(e.Aggregation.Metrics) > 0
+}
+
+// EsHighlight 高亮配置, 命中片段写入日志的 _highlight 字段
+type EsHighlight struct {
+	Enabled      bool     `json:"enabled"`
+	Fields       []string `json:"fields"`       // 高亮字段, 为空时高亮所有命中查询条件的字段
+	FragmentSize int      `json:"fragmentSize"` // 片段长度, 默认 100
+	Fragments    int      `json:"fragments"`    // 每个字段返回的片段数, 默认 3
+}
+
+// EsFieldType 日志字段的类型声明, 字段值类型与声明不一致时进行转换, 无法转换时查询失败
+type EsFieldType struct {
+	// 字段名, 嵌套字段使用 . 分隔, 例如 http.status
+	Field string          `json:"field"`
+	Type  EsFieldTypeKind `json:"type"`
+}
+
+type EsFieldTypeKind string
+
+const (
+	// EsFieldTypeNumber 数值, 字符串按浮点数解析
+	EsFieldTypeNumber EsFieldTypeKind = "number"
+	// EsFieldTypeTime 时间, 支持秒、毫秒、微秒、纳秒时间戳及 RFC3339 字符串, 转换为 RFC3339 格式的 UTC 时间
+	EsFieldTypeTime EsFieldTypeKind = "time"
+	// EsFieldTypeString 字符串, 数值及布尔值转换为字符串
+	EsFieldTypeString EsFieldTypeKind = "string"
+)
+
+// EsRuntimeField ElasticSearch 运行时字段 (runtime_mappings), 需 ElasticSearch 7.11 及以上版本
+type EsRuntimeField struct {
+	// 字段名, 与已有字段同名时覆盖该字段
+	Name string `json:"name"`
+	// 字段类型, 默认 keyword
+	Type EsRuntimeFieldType `json:"type"`
+	// painless 脚本, 通过 emit 输出字段值, 例如 emit(doc['bytes'].value / 1024)
+	Script string `json:"script"`
+}
+
+type EsRuntimeFieldType string
+
+const (
+	EsRuntimeFieldTypeKeyword EsRuntimeFieldType = "keyword"
+	EsRuntimeFieldTypeLong    EsRuntimeFieldType = "long"
+	EsRuntimeFieldTypeDouble  EsRuntimeFieldType = "double"
+	EsRuntimeFieldTypeDate    EsRuntimeFieldType = "date"
+	EsRuntimeFieldTypeBoolean EsRuntimeFieldType = "boolean"
+	EsRuntimeFieldTypeIp      EsRuntimeFieldType = "ip"
+)
+
+// GetType 获取运行时字段类型, 未配置时为 keyword
+func (f EsRuntimeField) GetType() EsRuntimeFieldType {
+	if f.Type == "" {
+		return EsRuntimeFieldTypeKeyword
+	}
+	return f.Type
+}
+
+type ClickHouseConfig struct {
+	QueryType      ClickHouseQueryType `json:"queryType"`
+	Table          string              `json:"table"`          // 表名, 支持 db.table
+	TimestampField string              `json:"timestampField"` // 时间字段, 默认 timestamp
+	Where          string              `json:"where"`          // 附加过滤条件
+	RawSQL         string              `json:"rawSQL"`         // 可使用 {start:UInt32}、{end:UInt32} 引用查询时间范围
+	LogScope       int                 `json:"logScope"`
+	Limit          int                 `json:"limit"`
+}
+
+type ClickHouseQueryType string
+
+const (
+	ClickHouseQueryTypeField  ClickHouseQueryType = "Field"
+	ClickHouseQueryTypeRawSQL ClickHouseQueryType = "RawSQL"
+)
+
+type GraylogConfig struct {
+	QueryType       GraylogQueryType  `json:"queryType"`
+	Query           string            `json:"query"` // Graylog 搜索语法, 如 level:ERROR AND source:api
+	Filter          []EsQueryFilter   `json:"filter"`
+	FilterCondition EsFilterCondition `json:"filterCondition"`
+	QueryWildcard   int64             `json:"queryWildcard"` // 0 精准匹配，1 模糊匹配，2 正则匹配，3 短语匹配
+	Streams         []string          `json:"streams"`       // 限定查询的 Stream ID, 为空时查询全部
+	LogScope        int               `json:"logScope"`
+	Limit           int               `json:"limit"`
+}
+
+type GraylogQueryType string
+
+const (
+	GraylogQueryTypeField    GraylogQueryType = "Field"
+	GraylogQueryTypeRawQuery GraylogQueryType = "RawQuery"
+)
+
+type SQLConfig struct {
+	// 只读查询语句, 可使用 {start}、{end} (时间) 及 {start_ts}、{end_ts} (Unix 秒) 引用查询时间范围
+	SQL      string `json:"sql"`
+	LogScope int    `json:"logScope"`
+	Limit    int    `json:"limit"`
+}
+
+type HTTPProbeConfig struct {
+	// 评估条件, 如 status_code !=200、latency >2000
+	Conditions []HTTPProbeCondition `json:"conditions"`
+	// 评估条件之间的关系, And 或 Or, 默认 And
+	ConditionRelation EsFilterCondition `json:"conditionRelation"`
+}
+
+// HTTPProbeCondition 拨测结果的评估条件
+type HTTPProbeCondition struct {
+	// 拨测结果字段, status_code / latency (单位毫秒) / body_match
+	Field     string `json:"field"`
+	Condition string `json:"condition"`
+}
+
+const (
+	HTTPProbeFieldStatusCode = "status_code"
+	HTTPProbeFieldLatency    = "latency"
+	HTTPProbeFieldBodyMatch  = "body_match"
+)
+
+// GetMetrics 转换为多指标评估条件, 与 ElasticSearch 多指标聚合共用评估逻辑
+func (h HTTPProbeConfig) GetMetrics() []EsAggregationMetric {
+	metrics := make([]EsAggregationMetric, 0, len(h.Conditions))
+	for _, c := range h.Conditions {
+		metrics = append(metrics, EsAggregationMetric{Name: c.Field, Condition: c.Condition})
+	}
+	return metrics
 }
 
 type EsQueryType string
 
 const (
-	EsQueryTypeRawJson EsQueryType = "RawJson"
-	EsQueryTypeField   EsQueryType = "Field"
+	EsQueryTypeRawJson     EsQueryType = "RawJson"
+	EsQueryTypeField       EsQueryType = "Field"
+	EsQueryTypeAggregation EsQueryType = "Aggregation"
+)
+
+type EsAggregationType string
+
+const (
+	EsAggregationTypeCount EsAggregationType = "count"
+	EsAggregationTypeAvg   EsAggregationType = "avg"
+	EsAggregationTypeSum   EsAggregationType = "sum"
+	EsAggregationTypeMax   EsAggregationType = "max"
+	EsAggregationTypeMin   EsAggregationType = "min"
+	// 以下类型仅用于多指标聚合
+	EsAggregationTypeValueCount  EsAggregationType = "value_count"
+	EsAggregationTypeCardinality EsAggregationType = "cardinality"
+	EsAggregationTypePercentiles EsAggregationType = "percentiles"
+)
+
+// EsAggregation 聚合查询配置
+type EsAggregation struct {
+	// 聚合类型, count/avg/sum/max/min
+	Type EsAggregationType `json:"type"`
+	// 聚合字段, count 类型可为空
+	Field string `json:"field"`
+	// 分桶字段, 按该字段的值进行 terms 分桶
+	BucketField string `json:"bucketField"`
+	// 时间分桶间隔, 例如 1m、5m, 未设置分桶字段时按时间分桶
+	Interval string `json:"interval"`
+	// 分桶数量上限, 默认 10
+	Size int `json:"size"`
+	// 多指标聚合, 配置后在一次查询中计算全部指标, 忽略 Type 及 Field, 按各指标的评估条件进行组合判断
+	Metrics []EsAggregationMetric `json:"metrics"`
+	// 多指标评估条件之间的关系, And 或 Or, 默认 And
+	MetricsCondition EsFilterCondition `json:"metricsCondition"`
+	// 组合聚合, 通过 composite 聚合按 after_key 翻页拉取分桶字段的全部分桶, 适用于高基数字段, 忽略 Size 及 Interval
+	Composite bool `json:"composite"`
+	// 组合聚合的分桶字段, 可配置多个, 为空时使用 BucketField
+	BucketFields []string `json:"bucketFields"`
+	// 组合聚合拉取的分桶数上限, 默认 10000, 超出时停止翻页并提示查询结果不完整
+	MaxBuckets int `json:"maxBuckets"`
+}
+
+// GetCompositeFields 获取组合聚合的分桶字段
+func (a EsAggregation) GetCompositeFields() []string {
+	if len(a.BucketFields) > 0 {
+		return a.BucketFields
+	}
+	if a.BucketField != "" {
+		return []string{a.BucketField}
+	}
+	return nil
+}
+
+// EsAggregationMetric 多指标聚合中的单个指标
+type EsAggregationMetric struct {
+	// 指标名称, 作为聚合结果及告警标签的 Key, 如 error_count
+	Name string `json:"name"`
+	// 聚合类型, count/value_count/cardinality/avg/sum/max/min/percentiles
+	Type EsAggregationType `json:"type"`
+	// 聚合字段, count 类型可为空
+	Field string `json:"field"`
+	// 百分位, 如 99, 仅 percentiles 类型使用
+	Percent float64 `json:"percent"`
+	// 评估条件, 如 >100, 为空时仅展示不参与评估
+	Condition string `json:"condition"`
+}
+
+// QueryWildcard 字段匹配模式
+const (
+	EsQueryWildcardMatch    int64 = 0 // 精准匹配
+	EsQueryWildcardWildcard int64 = 1 // 模糊匹配, *value*
+	EsQueryWildcardRegexp   int64 = 2 // 正则匹配
+	EsQueryWildcardPhrase   int64 = 3 // 短语匹配
 )
 
 type EsFilterCondition string
@@ -68,9 +403,12 @@ const (
 	EsFilterConditionNot EsFilterCondition = "Not"
 )
 
+// EsQueryFilter 过滤条件, Filters 不为空时表示一个嵌套分组, 分组内按 Condition 组合
 type EsQueryFilter struct {
-	Field string `json:"field"`
-	Value string `json:"value"`
+	Field     string            `json:"field"`
+	Value     string            `json:"value"`
+	Condition EsFilterCondition `json:"condition,omitempty"`
+	Filters   []EsQueryFilter   `json:"filters,omitempty"`
 }
 
 type KubernetesConfig struct {
@@ -82,9 +420,12 @@ type KubernetesConfig struct {
 }
 
 type JaegerConfig struct {
-	Service string `json:"service"`
-	Scope   int    `json:"scope"`
-	Tags    string `json:"tags"`
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	Scope     int    `json:"scope"`
+	Tags      string `json:"tags"`
+	// 单条链路错误 Span 数的评估条件, 如 ">0"; 为空时命中的链路均触发告警
+	ErrorCondition string `json:"errorCondition"`
 }
 
 type PrometheusConfig struct {
@@ -92,6 +433,86 @@ type PrometheusConfig struct {
 	Annotations string  `json:"annotations"`
 	ForDuration int64   `json:"forDuration"`
 	Rules       []Rules `json:"rules"`
+	// 评估模式, threshold 静态阈值 (默认), anomaly 基于历史基线的异常检测
+	EvalMode string        `json:"evalMode"`
+	Anomaly  AnomalyConfig `json:"anomaly"`
+}
+
+const (
+	PromEvalModeThreshold = "threshold"
+	PromEvalModeAnomaly   = "anomaly"
+
+	AnomalyDirectionUp   = "up"
+	AnomalyDirectionDown = "down"
+	AnomalyDirectionBoth = "both"
+
+	defaultAnomalyK        = 3
+	defaultAnomalyLookback = 60
+)
+
+// AnomalyConfig 异常检测配置, 以最近 N 个评估窗口的 mean ± k·stddev 作为基线, 当前值偏离基线时告警
+type AnomalyConfig struct {
+	// 标准差倍数 k, 默认 3
+	K float64 `json:"k"`
+	// 基线的历史窗口数 N, 每次评估为一个窗口, 默认 60
+	Lookback int64 `json:"lookback"`
+	// 预热窗口数, 历史样本少于该值时不告警, 默认与 Lookback 相同
+	WarmUp int64 `json:"warmUp"`
+	// 偏离方向 up / down / both, 默认 both
+	Direction string `json:"direction"`
+	// 告警等级
+	Severity string `json:"severity"`
+}
+
+func (p PrometheusConfig) IsAnomalyMode() bool {
+	return p.EvalMode == PromEvalModeAnomaly
+}
+
+func (a AnomalyConfig) GetK() float64 {
+	if a.K <= 0 {
+		return defaultAnomalyK
+	}
+	return a.K
+}
+
+func (a AnomalyConfig) GetLookback() int64 {
+	if a.Lookback <= 0 {
+		return defaultAnomalyLookback
+	}
+	return a.Lookback
+}
+
+func (a AnomalyConfig) GetWarmUp() int64 {
+	if a.WarmUp <= 0 || a.WarmUp > a.GetLookback() {
+		return a.GetLookback()
+	}
+	return a.WarmUp
+}
+
+func (a AnomalyConfig) GetDirection() string {
+	if a.Direction == "" {
+		return AnomalyDirectionBoth
+	}
+	return a.Direction
+}
+
+// Validate 校验异常检测配置
+func (a AnomalyConfig) Validate() error {
+	if a.K < 0 {
+		return fmt.Errorf("异常检测的标准差倍数不能小于 0")
+	}
+	if a.Lookback < 0 || a.Lookback == 1 {
+		return fmt.Errorf("异常检测的历史窗口数需大于 1")
+	}
+	if a.WarmUp < 0 || a.WarmUp > a.GetLookback() {
+		return fmt.Errorf("异常检测的预热窗口数需在 0 ~ %d 之间", a.GetLookback())
+	}
+	switch a.GetDirection() {
+	case AnomalyDirectionUp, AnomalyDirectionDown, AnomalyDirectionBoth:
+	default:
+		return fmt.Errorf("异常检测的偏离方向无效: %s", a.Direction)
+	}
+	return nil
 }
 
 type Rules struct {
@@ -99,6 +520,114 @@ type Rules struct {
 	Expr     string `json:"expr"`
 }
 
+// EscalationLevel 告警升级级别, 按顺序依次为第 1、2... 级, 第 0 级为故障中心的常规通知
+type EscalationLevel struct {
+	Timeout  int64  `json:"timeout"`  // 自首次触发起未认领的超时时间, 单位分钟
+	NoticeId string `json:"noticeId"` // 通知对象ID
+}
+
+// ValidateEscalationPolicy 校验升级策略, 各级超时时间需依次递增
+func (a AlertRule) ValidateEscalationPolicy() error {
+	var lastTimeout int64
+	for i, level := range a.EscalationPolicy {
+		if level.NoticeId == "" {
+			return fmt.Errorf("第 %d 级升级的通知对象不能为空", i+1)
+		}
+		if level.Timeout <= lastTimeout {
+			return fmt.Errorf("第 %d 级升级的超时时间需大于 %d 分钟", i+1, lastTimeout)
+		}
+		lastTimeout = level.Timeout
+	}
+	return nil
+}
+
+// RelabelAction 标签重写动作, 与 Prometheus relabel_configs 的语义一致
+type RelabelAction string
+
+const (
+	// RelabelReplace 源标签值匹配正则时, 将替换结果写入目标标签
+	RelabelReplace RelabelAction = "replace"
+	// RelabelKeep 源标签值不匹配正则时丢弃该告警事件
+	RelabelKeep RelabelAction = "keep"
+	// RelabelDrop 源标签值匹配正则时丢弃该告警事件
+	RelabelDrop RelabelAction = "drop"
+	// RelabelLabelMap 标签名匹配正则时, 按替换结果复制为新的标签名
+	RelabelLabelMap RelabelAction = "labelmap"
+	// RelabelLabelDrop 删除标签名匹配正则的标签
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	// RelabelLabelKeep 仅保留标签名匹配正则的标签
+	RelabelLabelKeep RelabelAction = "labelkeep"
+)
+
+const (
+	defaultRelabelSeparator   = ";"
+	defaultRelabelRegex       = "(.*)"
+	defaultRelabelReplacement = "$1"
+)
+
+// RelabelConfig 标签重写规则, 正则需完整匹配, 为空时使用 Prometheus 的默认值
+type RelabelConfig struct {
+	SourceLabels []string      `json:"sourceLabels"`
+	Separator    string        `json:"separator"`
+	Regex        string        `json:"regex"`
+	TargetLabel  string        `json:"targetLabel"`
+	Replacement  string        `json:"replacement"`
+	Action       RelabelAction `json:"action"`
+}
+
+func (r RelabelConfig) GetAction() RelabelAction {
+	if r.Action == "" {
+		return RelabelReplace
+	}
+	return r.Action
+}
+
+func (r RelabelConfig) GetSeparator() string {
+	if r.Separator == "" {
+		return defaultRelabelSeparator
+	}
+	return r.Separator
+}
+
+func (r RelabelConfig) GetReplacement() string {
+	if r.Replacement == "" {
+		return defaultRelabelReplacement
+	}
+	return r.Replacement
+}
+
+// CompileRegex 编译完整匹配的正则
+func (r RelabelConfig) CompileRegex() (*regexp.Regexp, error) {
+	expr := r.Regex
+	if expr == "" {
+		expr = defaultRelabelRegex
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// ValidateRelabelConfigs 校验标签重写规则
+func (a AlertRule) ValidateRelabelConfigs() error {
+	for i, r := range a.RelabelConfigs {
+		if _, err := r.CompileRegex(); err != nil {
+			return fmt.Errorf("第 %d 条标签重写规则的正则无效, err: %s", i+1, err.Error())
+		}
+		switch r.GetAction() {
+		case RelabelReplace:
+			if r.TargetLabel == "" {
+				return fmt.Errorf("第 %d 条标签重写规则的目标标签不能为空", i+1)
+			}
+		case RelabelKeep, RelabelDrop:
+			if len(r.SourceLabels) == 0 {
+				return fmt.Errorf("第 %d 条标签重写规则的源标签不能为空", i+1)
+			}
+		case RelabelLabelMap, RelabelLabelDrop, RelabelLabelKeep:
+		default:
+			return fmt.Errorf("第 %d 条标签重写规则的动作无效: %s", i+1, r.Action)
+		}
+	}
+	return nil
+}
+
 type EffectiveTime struct {
 	Week      []string `json:"week"`
 	StartTime int      `json:"startTime"`
@@ -159,6 +688,18 @@ type AlertRuleQuery struct {
 	Page
 }
 
+// RulePreviewReq 规则预览, 使用未保存的规则对数据源执行一次查询
+type RulePreviewReq struct {
+	DatasourceId string    `json:"datasourceId"`
+	Rule         AlertRule `json:"rule"`
+}
+
+type RulePreviewResponse struct {
+	Total   int         `json:"total"`
+	Matched bool        `json:"matched"` // 是否满足告警条件
+	Logs    interface{} `json:"logs"`
+}
+
 type RuleResponse struct {
 	List []AlertRule `json:"list"`
 	Page
@@ -166,6 +707,14 @@ type RuleResponse struct {
 
 func (a *AlertRule) GetRuleType() string { return a.DatasourceType }
 
+// GetForDuration 获取持续时间, 未配置时兼容 Prometheus 规则中的持续时间
+func (a *AlertRule) GetForDuration() int64 {
+	if a.ForDuration > 0 {
+		return a.ForDuration
+	}
+	return a.PrometheusConfig.ForDuration
+}
+
 func (a *AlertRule) GetEnabled() *bool {
 	if a.Enabled == nil {
 		isOk := false
@@ -0,0 +1,92 @@
+package models
+
+// RuleBundleKind 规则导入导出文档的类型
+const RuleBundleKind = "AlertRules"
+
+// RuleBundle 规则导入导出的 YAML 文档, 字段与规则的 JSON 字段一致
+type RuleBundle struct {
+	Kind  string      `json:"kind"`
+	Rules []AlertRule `json:"rules"`
+}
+
+// RuleExportQuery 规则导出, 未指定规则 ID 时导出规则组 (为空时为租户) 内的全部规则
+type RuleExportQuery struct {
+	TenantId    string   `json:"tenantId" form:"tenantId"`
+	RuleGroupId string   `json:"ruleGroupId" form:"ruleGroupId"`
+	RuleIds     []string `json:"ruleIds" form:"ruleIds"`
+}
+
+// RuleImportReq 规则导入, 请求体为 RuleBundle 文档
+type RuleImportReq struct {
+	TenantId string `json:"tenantId" form:"tenantId"`
+	// 文档中未指定规则组的规则使用该规则组, 同时限定 Prune 的范围
+	RuleGroupId string `json:"ruleGroupId" form:"ruleGroupId"`
+	// 仅校验并返回差异, 不写入
+	DryRun bool `json:"dryRun" form:"dryRun"`
+	// 删除范围内 (规则组, 为空时为租户) 文档中不存在的规则
+	Prune   bool   `json:"prune" form:"prune"`
+	Content []byte `json:"-" form:"-"`
+}
+
+const (
+	RuleDiffCreate    = "create"
+	RuleDiffUpdate    = "update"
+	RuleDiffDelete    = "delete"
+	RuleDiffUnchanged = "unchanged"
+	RuleDiffInvalid   = "invalid"
+)
+
+// RuleDiff 单条规则的导入差异
+type RuleDiff struct {
+	RuleId   string            `json:"ruleId"`
+	RuleName string            `json:"ruleName"`
+	Action   string            `json:"action"`
+	Changes  []RuleFieldChange `json:"changes,omitempty"`
+	// 校验或写入失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// RuleFieldChange 规则字段的变更, 值为 JSON 格式
+type RuleFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RuleImportResult 规则导入结果, 存在校验失败的规则时不写入任何规则
+type RuleImportResult struct {
+	DryRun  bool       `json:"dryRun"`
+	Applied bool       `json:"applied"`
+	Items   []RuleDiff `json:"items"`
+}
+
+// PromRuleImportReq 导入 Prometheus 告警规则, 请求体为 Prometheus 规则文件, 转换后的规则按规则名称匹配已有规则
+type PromRuleImportReq struct {
+	TenantId    string `json:"tenantId" form:"tenantId"`
+	RuleGroupId string `json:"ruleGroupId" form:"ruleGroupId"`
+	// Prometheus 或 VictoriaMetrics 数据源
+	DatasourceId  string `json:"datasourceId" form:"datasourceId"`
+	FaultCenterId string `json:"faultCenterId" form:"faultCenterId"`
+	// 是否启用导入的规则
+	Enabled bool `json:"enabled" form:"enabled"`
+	// 规则组未配置 interval 时的评估间隔, 单位秒, 默认 60
+	EvalInterval int64  `json:"evalInterval" form:"evalInterval"`
+	DryRun       bool   `json:"dryRun" form:"dryRun"`
+	Prune        bool   `json:"prune" form:"prune"`
+	Content      []byte `json:"-" form:"-"`
+}
+
+// PromRuleIssue Prometheus 规则转换时不支持或做了调整的配置
+type PromRuleIssue struct {
+	Group   string `json:"group"`
+	Alert   string `json:"alert"`
+	Message string `json:"message"`
+	// 规则未导入
+	Skipped bool `json:"skipped"`
+}
+
+// PromRuleImportResult Prometheus 规则导入结果
+type PromRuleImportResult struct {
+	RuleImportResult
+	Issues []PromRuleIssue `json:"issues"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// SchemaMigration 已执行的数据库迁移记录
+type SchemaMigration struct {
+	Version   int    `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string `json:"name"`
+	AppliedAt int64  `json:"appliedAt"`
+}
+
+func (s SchemaMigration) TableName() string {
+	return "w8t_schema_migrations"
+}
@@ -6,6 +6,20 @@ type Settings struct {
 	AppVersion      string          `json:"appVersion" gorm:"-"`
 	PhoneCallConfig phoneCallConfig `json:"phoneCallConfig" gorm:"phoneCallConfig;serializer:json"`
 	AiConfig        AiConfig        `json:"aiConfig" gorm:"aiConfig;serializer:json"`
+	EnrichConfig    EnrichConfig    `json:"enrichConfig" gorm:"enrichConfig;serializer:json"`
+
+	// 维护模式, 仅通过维护模式接口修改, 保存系统配置时保持不变
+	MaintenanceConfig MaintenanceConfig `json:"maintenanceConfig" gorm:"maintenanceConfig;serializer:json"`
+}
+
+// MaintenanceConfig 维护模式, 开启期间规则照常评估并记录告警状态, 但不发送任何通知, 结束时按通知对象发送一条汇总
+type MaintenanceConfig struct {
+	Enabled bool `json:"enabled"`
+	// 开启或关闭的原因
+	Reason string `json:"reason"`
+	// 最近一次切换的操作人及时间
+	UpdateBy string `json:"updateBy"`
+	UpdateAt int64  `json:"updateAt"`
 }
 
 type emailConfig struct {
@@ -13,6 +27,12 @@ type emailConfig struct {
 	Port          int    `json:"port"`
 	Email         string `json:"email"`
 	Token         string `json:"token"`
+	// 登录用户, 为空时使用发件邮箱
+	User string `json:"user"`
+	// TLS 模式: none / starttls / implicit, 为空时服务端支持 STARTTLS 则自动升级
+	TLSMode string `json:"tlsMode"`
+	// 跳过证书校验
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
 }
 
 type phoneCallConfig struct {
@@ -42,3 +62,54 @@ func (a AiConfig) GetEnable() bool {
 
 	return *a.Enable
 }
+
+const (
+	// EnrichTypeHTTP 通过 HTTP 接口查询 CMDB
+	EnrichTypeHTTP = "http"
+	// EnrichTypeMapping 通过静态映射表查询
+	EnrichTypeMapping = "mapping"
+)
+
+// EnrichConfig 告警标签丰富, 通知前根据指定标签查询 CMDB, 将返回的属性 (如负责团队、所属服务) 合并到告警标签
+type EnrichConfig struct {
+	Enable *bool `json:"enable"`
+	// 查询方式: http / mapping
+	Type string `json:"type"`
+	// 用于查询的标签, 如 instance / ip / host, 按顺序使用第一个存在的标签
+	LabelKeys []string `json:"labelKeys"`
+	// HTTP 查询地址, ${value} 替换为标签值, 如 http://cmdb/api/hosts?ip=${value}, 接口返回 JSON 对象
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// 需要合并的属性, 为空时合并返回的所有字符串 / 数值类型的属性
+	Fields []string `json:"fields"`
+	// 映射表, key 为标签值, value 为需要合并的标签
+	Mapping map[string]map[string]string `json:"mapping"`
+	// 是否覆盖告警中已存在的同名标签, 默认不覆盖
+	Override bool `json:"override"`
+	// 查询超时时间 (秒), 默认 2s
+	Timeout int `json:"timeout"`
+	// 查询结果缓存时长 (秒), 默认 300s
+	CacheTTL int `json:"cacheTTL"`
+}
+
+func (e EnrichConfig) GetEnable() bool {
+	if e.Enable == nil {
+		return false
+	}
+
+	return *e.Enable
+}
+
+func (e EnrichConfig) GetTimeout() int {
+	if e.Timeout <= 0 {
+		return 2
+	}
+	return e.Timeout
+}
+
+func (e EnrichConfig) GetCacheTTL() int {
+	if e.CacheTTL <= 0 {
+		return 300
+	}
+	return e.CacheTTL
+}
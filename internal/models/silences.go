@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type AlertSilences struct {
 	TenantId      string         `json:"tenantId"`
 	Name          string         `json:"name"`
@@ -12,8 +14,77 @@ type AlertSilences struct {
 	FaultCenterId string         `json:"faultCenterId"`
 	Comment       string         `json:"comment"`
 	Status        int            `json:"status"` // 0 未生效, 1 进行中, 2 已失效
+	// 周期性维护窗口, 为空时为一次性静默; 设置后仅在 StartsAt~EndsAt 有效期内的窗口时段静默, EndsAt 为 0 表示长期有效
+	Recurrence *SilenceRecurrence `json:"recurrence" gorm:"column:recurrence;serializer:json"`
+}
+
+// SilenceRecurrence 按星期及每日时段重复的维护窗口, 如每天 01:00~03:00
+type SilenceRecurrence struct {
+	Week      []string `json:"week"`      // 生效的星期, 如 Monday, 为空时每天生效
+	StartTime int      `json:"startTime"` // 每日开始时间, 当天零点起的秒数
+	EndTime   int      `json:"endTime"`   // 每日结束时间, 小于开始时间时表示跨天, 如 22:00~02:00
+	Timezone  string   `json:"timezone"`  // 时区, 如 Asia/Shanghai, 为空时使用服务端时区
+}
+
+// InWindow 判断时间是否处于维护窗口内, 跨天窗口的后半段按前一天的星期判断
+func (r SilenceRecurrence) InWindow(t time.Time) bool {
+	if loc, err := time.LoadLocation(r.Timezone); r.Timezone != "" && err == nil {
+		t = t.In(loc)
+	}
+
+	seconds := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	if r.StartTime <= r.EndTime {
+		return seconds >= r.StartTime && seconds < r.EndTime && r.matchWeekday(t.Weekday())
+	}
+
+	if seconds >= r.StartTime {
+		return r.matchWeekday(t.Weekday())
+	}
+	if seconds < r.EndTime {
+		return r.matchWeekday(t.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func (r SilenceRecurrence) matchWeekday(weekday time.Weekday) bool {
+	if len(r.Week) == 0 {
+		return true
+	}
+	for _, w := range r.Week {
+		if w == weekday.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// 静默状态
+const (
+	SilenceStatusPending = 0
+	SilenceStatusActive  = 1
+	SilenceStatusExpired = 2
+)
+
+// IsActive 当前时间是否处于静默时间窗口内
+func (a AlertSilences) IsActive(now int64) bool {
+	if a.Status == SilenceStatusExpired || now < a.StartsAt {
+		return false
+	}
+
+	if a.Recurrence != nil {
+		return (a.EndsAt == 0 || now < a.EndsAt) && a.Recurrence.InWindow(time.Unix(now, 0))
+	}
+	return now < a.EndsAt
 }
 
+// 静默标签匹配方式
+const (
+	SilenceOperatorEqual    = "=="
+	SilenceOperatorNotEqual = "!="
+	SilenceOperatorRegex    = "=~"
+	SilenceOperatorNotRegex = "!~"
+)
+
 type SilenceLabel struct {
 	Key      string `json:"key"`
 	Value    string `json:"value"`
@@ -29,6 +100,13 @@ type AlertSilenceQuery struct {
 	Page
 }
 
+// SilenceMatchReq 根据告警标签查询命中的静默规则
+type SilenceMatchReq struct {
+	TenantId      string                 `json:"tenantId"`
+	FaultCenterId string                 `json:"faultCenterId"`
+	Labels        map[string]interface{} `json:"labels"`
+}
+
 type SilenceResponse struct {
 	List []AlertSilences `json:"list"`
 	Page
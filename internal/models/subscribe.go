@@ -13,6 +13,11 @@ type AlertSubscribe struct {
 	SNoticeTemplateId string   `json:"sNoticeTemplateId"`                                  // 发送订阅消息的通知模版 ID
 	SFilter           []string `json:"sFilter" gorm:"sFilter;serializer:json"`             // 过滤
 	SCreateAt         int64    `json:"sCreateAt"`
+
+	// 订阅的标签匹配条件, 全部满足时发送; 未指定规则时按标签订阅租户下所有规则的告警
+	SLabelMatchers []SilenceLabel `json:"sLabelMatchers" gorm:"sLabelMatchers;serializer:json"`
+	// 个人通知对象 ID, 为空时发送邮件至订阅用户的邮箱
+	SNoticeId string `json:"sNoticeId"`
 }
 
 type AlertSubscribeQuery struct {
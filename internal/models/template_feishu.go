@@ -16,10 +16,10 @@ type Actions struct {
 	Tag      string      `json:"tag"`
 	Text     ActionsText `json:"text"`
 	Type     string      `json:"type"`
-	Value    interface{} `json:"value"`
-	Confirm  Confirms    `json:"confirm"`
-	URL      string      `json:"url"`
-	MultiURL *MultiURLs  `json:"multi_url"`
+	Value    interface{} `json:"value,omitempty"`
+	Confirm  *Confirms   `json:"confirm,omitempty"`
+	URL      string      `json:"url,omitempty"`
+	MultiURL *MultiURLs  `json:"multi_url,omitempty"`
 }
 
 type MultiURLs struct {
@@ -48,9 +48,16 @@ type Elements struct {
 	Tag            string             `json:"tag"`
 	FlexMode       string             `json:"flexMode"`
 	BackgroupStyle string             `json:"background_style"`
-	Text           Texts              `json:"text"`
+	Text           *Texts             `json:"text,omitempty"`
 	Columns        []Columns          `json:"columns"`
 	Elements       []ElementsElements `json:"elements"`
+	Fields         []Fields           `json:"fields,omitempty"`
+	Actions        []Actions          `json:"actions,omitempty"`
+}
+
+type Fields struct {
+	IsShort bool  `json:"is_short"`
+	Text    Texts `json:"text"`
 }
 
 type ElementsElements struct {
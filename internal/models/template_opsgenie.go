@@ -0,0 +1,20 @@
+package models
+
+// OpsGenieAlert OpsGenie 创建告警请求
+type OpsGenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Priority    string            `json:"priority,omitempty"` // P1 ~ P5
+	Note        string            `json:"note,omitempty"`
+}
+
+// OpsGenieCloseAlert OpsGenie 关闭告警请求
+type OpsGenieCloseAlert struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
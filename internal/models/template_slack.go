@@ -0,0 +1,41 @@
+package models
+
+import "fmt"
+
+// SlackMessage Slack chat.postMessage 请求体, Blocks 放在 Attachment 中以显示告警等级颜色
+type SlackMessage struct {
+	Channel     string            `json:"channel"`
+	Text        string            `json:"text"`
+	ThreadTs    string            `json:"thread_ts,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+type SlackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type   string      `json:"type"`
+	Text   *SlackText  `json:"text,omitempty"`
+	Fields []SlackText `json:"fields,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackResponse Slack Web API 响应
+type SlackResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+	Ts    string `json:"ts"`
+}
+
+type SlackThreadCacheKey string
+
+// BuildSlackThreadCacheKey 告警首条 Slack 消息的 ts, 后续通知及恢复通知回复到该消息的线程中
+func BuildSlackThreadCacheKey(noticeId, fingerprint string) SlackThreadCacheKey {
+	return SlackThreadCacheKey(fmt.Sprintf("w8t:slack:%s:%s.thread", noticeId, fingerprint))
+}
@@ -0,0 +1,35 @@
+package models
+
+// TeamsMessageCard Microsoft Teams Incoming Webhook 消息卡片
+type TeamsMessageCard struct {
+	Type            string                 `json:"@type"`
+	Context         string                 `json:"@context"`
+	ThemeColor      string                 `json:"themeColor"`
+	Summary         string                 `json:"summary"`
+	Title           string                 `json:"title"`
+	Sections        []TeamsSection         `json:"sections"`
+	PotentialAction []TeamsPotentialAction `json:"potentialAction,omitempty"`
+}
+
+type TeamsSection struct {
+	ActivityTitle string      `json:"activityTitle,omitempty"`
+	Facts         []TeamsFact `json:"facts,omitempty"`
+	Text          string      `json:"text,omitempty"`
+	Markdown      bool        `json:"markdown"`
+}
+
+type TeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type TeamsPotentialAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []TeamsTarget `json:"targets"`
+}
+
+type TeamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
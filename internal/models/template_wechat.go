@@ -7,4 +7,17 @@ type WeChatMsgTemplate struct {
 
 type WeChatMarkDown struct {
 	Content string `json:"content"`
+	// 需要 @ 的手机号, markdown 消息不支持提醒, 发送时以文本消息单独提醒
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+// WeChatTextMsg 企业微信文本消息, 用于 @ 值班人员
+type WeChatTextMsg struct {
+	MsgType string     `json:"msgtype"`
+	Text    WeChatText `json:"text"`
+}
+
+type WeChatText struct {
+	Content             string   `json:"content"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
 }
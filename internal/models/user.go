@@ -12,6 +12,9 @@ type Member struct {
 	JoinDuty   string    `json:"joinDuty" `
 	DutyUserId string    `json:"dutyUserId"`
 	Tenants    []string `json:"tenants" gorm:"tenants;serializer:json"`
+	// OIDC 用户在身份提供方的签发方及用户标识 (iss + sub), 用于关联账号, 不随用户名或邮箱变更
+	OidcIssuer  string `json:"-" gorm:"column:oidcIssuer;size:191;index:idx_member_oidc_subject"`
+	OidcSubject string `json:"-" gorm:"column:oidcSubject;size:191;index:idx_member_oidc_subject"`
 }
 
 type MemberQuery struct {
@@ -22,3 +25,20 @@ type MemberQuery struct {
 	Query    string `json:"query" form:"query"`
 	JoinDuty string `json:"joinDuty" form:"joinDuty"`
 }
+
+// LoginToken 登陆及刷新 Token 的返回结果
+type LoginToken struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // Token 有效期, 单位秒
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// OidcLoginReq OIDC 授权回调的参数
+type OidcLoginReq struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
@@ -11,6 +11,22 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "搜索告警规则",
 			API: "/api/w8t/rule/ruleSearch",
 		},
+		"rulePreview": {
+			Key: "预览告警规则",
+			API: "/api/w8t/rule/rulePreview",
+		},
+		"ruleExport": {
+			Key: "导出告警规则",
+			API: "/api/w8t/rule/ruleExport",
+		},
+		"ruleImport": {
+			Key: "导入告警规则",
+			API: "/api/w8t/rule/ruleImport",
+		},
+		"rulePromImport": {
+			Key: "导入 Prometheus 告警规则",
+			API: "/api/w8t/rule/rulePromImport",
+		},
 		"calendarCreate": {
 			Key: "发布日历表",
 			API: "/api/w8t/calendar/calendarCreate",
@@ -27,6 +43,22 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "更新日历表",
 			API: "/api/w8t/calendar/calendarUpdate",
 		},
+		"getOnCallUser": {
+			Key: "获取当前值班人员",
+			API: "/api/w8t/calendar/getOnCallUser",
+		},
+		"overrideCreate": {
+			Key: "创建临时换班",
+			API: "/api/w8t/calendar/overrideCreate",
+		},
+		"overrideDelete": {
+			Key: "删除临时换班",
+			API: "/api/w8t/calendar/overrideDelete",
+		},
+		"overrideList": {
+			Key: "查看临时换班",
+			API: "/api/w8t/calendar/overrideList",
+		},
 		"createDashboard": {
 			Key: "创建仪表盘",
 			API: "/api/w8t/dashboard/createDashboard",
@@ -103,6 +135,14 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "查看历史告警",
 			API: "/api/w8t/event/hisEvent",
 		},
+		"eventTransition": {
+			Key: "查看告警状态变更记录",
+			API: "/api/w8t/event/transition",
+		},
+		"eventTransitionExport": {
+			Key: "导出告警状态变更记录",
+			API: "/api/w8t/event/transitionExport",
+		},
 		"listDashboard": {
 			Key: "查看仪表盘",
 			API: "/api/w8t/dashboard/listDashboard",
@@ -115,6 +155,10 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "删除通知对象",
 			API: "/api/w8t/notice/noticeDelete",
 		},
+		"noticeTest": {
+			Key: "测试通知对象",
+			API: "/api/w8t/notice/noticeTest",
+		},
 		"noticeList": {
 			Key: "查看通知对象",
 			API: "/api/w8t/notice/noticeList",
@@ -251,6 +295,10 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "查看静默规则",
 			API: "/api/w8t/silence/silenceList",
 		},
+		"silenceMatch": {
+			Key: "查看告警命中的静默规则",
+			API: "/api/w8t/silence/silenceMatch",
+		},
 		"silenceUpdate": {
 			Key: "更新静默规则",
 			API: "/api/w8t/silence/silenceUpdate",
@@ -299,6 +347,10 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "获取系统配置",
 			API: "/api/w8t/setting/getSystemSetting",
 		},
+		"setMaintenance": {
+			Key: "切换维护模式",
+			API: "/api/w8t/setting/setMaintenance",
+		},
 		"promQuery": {
 			Key: "Prometheus指标查询",
 			API: "/api/w8t/datasource/promQuery",
@@ -399,6 +451,18 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "搜索告警订阅",
 			API: "/api/w8t/subscribe/getSubscribe",
 		},
+		"listAuditLog": {
+			Key: "查看审计日志",
+			API: "/api/w8t/auditLog/listAuditLog",
+		},
+		"searchAuditLog": {
+			Key: "搜索审计日志",
+			API: "/api/w8t/auditLog/searchAuditLog",
+		},
+		"unsubscribe": {
+			Key: "取消订阅",
+			API: "/api/w8t/subscribe/unsubscribe",
+		},
 		"noticeRecordList": {
 			Key: "获取通知记录列表",
 			API: "/api/w8t/notice/noticeRecordList",
@@ -407,10 +471,30 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "获取通知记录指标",
 			API: "/api/w8t/notice/noticeRecordMetric",
 		},
+		"noticeFailedList": {
+			Key: "获取发送失败的通知列表",
+			API: "/api/w8t/notice/noticeFailedList",
+		},
 		"dataSourcePing": {
 			Key: "数据源连接测试",
 			API: "/api/w8t/datasource/dataSourcePing",
 		},
+		"dataSourceRetryStats": {
+			Key: "查看数据源重试统计",
+			API: "/api/w8t/datasource/dataSourceRetryStats",
+		},
+		"dataSourceBreakerStates": {
+			Key: "查看数据源熔断状态",
+			API: "/api/w8t/datasource/dataSourceBreakerStates",
+		},
+		"dataSourceFields": {
+			Key: "查看数据源字段",
+			API: "/api/w8t/datasource/dataSourceFields",
+		},
+		"dataSourceIndices": {
+			Key: "查看数据源索引",
+			API: "/api/w8t/datasource/dataSourceIndices",
+		},
 		"faultCenterList": {
 			Key: "获取故障中心列表",
 			API: "/api/w8t/faultCenter/faultCenterList",
@@ -435,6 +519,10 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "修改故障中心基本信息",
 			API: "/api/w8t/faultCenter/faultCenterReset",
 		},
+		"faultCenterResetReceiverToken": {
+			Key: "重置故障中心告警接入 Token",
+			API: "/api/w8t/faultCenter/faultCenterResetReceiverToken",
+		},
 		"searchViewLogsContent": {
 			Key: "搜索VictoriaLogs数据源内容",
 			API: "/api/w8t/datasource/searchViewLogsContent",
@@ -443,5 +531,29 @@ func PermissionsInfo() map[string]UserPermissions {
 			Key: "认领/处理告警",
 			API: "/api/w8t/event/processAlertEvent",
 		},
+		"chat": {
+			Key: "AI 分析",
+			API: "/api/w8t/ai/chat",
+		},
+		"getDashboardInfo": {
+			Key: "查看首页概览",
+			API: "/api/w8t/system/getDashboardInfo",
+		},
 	}
 }
+
+// GetPermissionByAPI 根据接口路径获取对应的权限
+func GetPermissionByAPI(api string) (UserPermissions, bool) {
+	for _, p := range PermissionsInfo() {
+		if p.API == api {
+			return p, true
+		}
+	}
+	return UserPermissions{}, false
+}
+
+// TenantlessAPIs 未选择租户时 (如首次登录) 所有用户均可访问的接口, 其余无租户的请求仅限 admin 用户
+var TenantlessAPIs = []string{
+	"/api/w8t/tenant/getTenantList",
+	"/api/w8t/setting/getSystemSetting",
+}
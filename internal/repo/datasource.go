@@ -70,6 +70,9 @@ func (ds DatasourceRepo) Search(r models.DatasourceQuery) ([]models.AlertDataSou
 
 func (ds DatasourceRepo) Get(r models.DatasourceQuery) (models.AlertDataSource, error) {
 	db := ds.db.Model(&models.AlertDataSource{})
+	if r.TenantId != "" {
+		db.Where("tenant_id = ?", r.TenantId)
+	}
 	db.Where("id = ?", r.Id)
 
 	var data models.AlertDataSource
@@ -102,7 +105,15 @@ func (ds DatasourceRepo) Update(r models.AlertDataSource) error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// 按结构体更新时忽略零值, 单独更新以支持恢复为全局并发限制
+	return ds.g.Updates(Updates{
+		Table: models.AlertDataSource{},
+		Where: data.Where,
+		Updates: map[string]interface{}{
+			"max_concurrent_queries": r.MaxConcurrentQueries,
+		},
+	})
 }
 
 func (ds DatasourceRepo) Delete(r models.DatasourceQuery) error {
@@ -60,10 +60,10 @@ func (d DutyRepo) List(r models.DutyManagementQuery) ([]models.DutyManagement, e
 		return nil, err
 	}
 
+	calendar := newDutyCalendarInterface(d.db, d.g)
 	for index, value := range data {
-		var dutySchedule models.DutySchedule
-		d.DB().Model(models.DutySchedule{}).Where("duty_id = ? and time = ?", value.ID, time.Now().Format("2006-1-2")).Find(&dutySchedule)
-		data[index].CurDutyUser = dutySchedule.Username
+		onCall, _ := calendar.GetOnCallUser(value.TenantId, value.ID, time.Now())
+		data[index].CurDutyUser = onCall.Username
 	}
 
 	return data, nil
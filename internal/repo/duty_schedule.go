@@ -20,6 +20,10 @@ type (
 		Update(r models.DutySchedule) error
 		Search(r models.DutyScheduleQuery) ([]models.DutySchedule, error)
 		GetCalendarUsers(r models.DutyScheduleQuery) ([]models.Users, error)
+		GetOnCallUser(tenantId, dutyId string, now time.Time) (models.OnCallUser, bool)
+		CreateOverride(r models.DutyOverride) error
+		DeleteOverride(r models.DutyOverrideQuery) error
+		ListOverrides(r models.DutyOverrideQuery) ([]models.DutyOverride, error)
 	}
 )
 
@@ -128,3 +132,67 @@ func (dc DutyCalendarRepo) GetCalendarUsers(r models.DutyScheduleQuery) ([]model
 
 	return users, nil
 }
+
+// GetOnCallUser 获取当前值班人员, 临时换班优先, 否则按值班表所在时区及交接班时间确定值班日期
+func (dc DutyCalendarRepo) GetOnCallUser(tenantId, dutyId string, now time.Time) (models.OnCallUser, bool) {
+	onCall := models.OnCallUser{DutyId: dutyId}
+
+	var override models.DutyOverride
+	err := dc.db.Model(&models.DutyOverride{}).
+		Where("tenant_id = ? AND duty_id = ? AND starts_at <= ? AND ends_at > ?", tenantId, dutyId, now.Unix(), now.Unix()).
+		Order("create_at DESC").
+		First(&override).Error
+	if err == nil {
+		var user models.Member
+		if err := dc.db.Model(models.Member{}).Where("user_id = ?", override.UserId).First(&user).Error; err != nil {
+			return onCall, false
+		}
+		onCall.OverrideId = override.ID
+		return fillOnCallUser(onCall, user), true
+	}
+
+	var duty models.DutyManagement
+	dc.db.Model(&models.DutyManagement{}).Where("tenant_id = ? AND id = ?", tenantId, dutyId).First(&duty)
+	onCall.DutyDate = duty.GetDutyDate(now)
+
+	user, ok := dc.GetDutyUserInfo(dutyId, onCall.DutyDate)
+	if !ok {
+		return onCall, false
+	}
+	return fillOnCallUser(onCall, user), true
+}
+
+func fillOnCallUser(onCall models.OnCallUser, user models.Member) models.OnCallUser {
+	onCall.UserId = user.UserId
+	onCall.Username = user.UserName
+	onCall.Phone = user.Phone
+	onCall.Email = user.Email
+	onCall.DutyUserId = user.DutyUserId
+	return onCall
+}
+
+func (dc DutyCalendarRepo) CreateOverride(r models.DutyOverride) error {
+	return dc.g.Create(models.DutyOverride{}, r)
+}
+
+func (dc DutyCalendarRepo) DeleteOverride(r models.DutyOverrideQuery) error {
+	return dc.g.Delete(Delete{
+		Table: models.DutyOverride{},
+		Where: map[string]interface{}{
+			"tenant_id = ?": r.TenantId,
+			"id = ?":        r.ID,
+		},
+	})
+}
+
+// ListOverrides 获取未结束的临时换班
+func (dc DutyCalendarRepo) ListOverrides(r models.DutyOverrideQuery) ([]models.DutyOverride, error) {
+	var overrides []models.DutyOverride
+	db := dc.db.Model(&models.DutyOverride{})
+	db.Where("tenant_id = ? AND duty_id = ? AND ends_at > ?", r.TenantId, r.DutyId, time.Now().Unix())
+	db.Order("starts_at ASC")
+	if err := db.Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
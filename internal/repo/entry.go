@@ -74,3 +74,6 @@ func (e *entryRepo) Subscribe() InterSubscribeRepo     { return newInterSubscrib
 func (e *entryRepo) Probing() InterProbingRepo         { return newProbingRepoInterface(e.db, e.g) }
 func (e *entryRepo) FaultCenter() InterFaultCenterRepo { return newInterFaultCenterRepo(e.db, e.g) }
 func (e *entryRepo) Ai() InterAiRepo                   { return newAiRepoInterface(e.db, e.g) }
+
+// replica 查询路由至只读副本, 用于可容忍复制延迟的列表查询
+func (e *entryRepo) replica() *gorm.DB { return e.db.Set(client.ReadReplicaKey, true) }
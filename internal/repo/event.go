@@ -13,6 +13,9 @@ type (
 	InterEventRepo interface {
 		GetHistoryEvent(r models.AlertHisEventQuery) (models.HistoryEventResponse, error)
 		CreateHistoryEvent(r models.AlertHisEvent) error
+		CreateTransition(r models.AlertTransition) error
+		ListTransition(r models.AlertTransitionQuery) (models.AlertTransitionResponse, error)
+		ExportTransition(r models.AlertTransitionQuery, fn func(models.AlertTransition) error) error
 	}
 )
 
@@ -29,7 +32,7 @@ func (e EventRepo) GetHistoryEvent(r models.AlertHisEventQuery) (models.HistoryE
 	var data []models.AlertHisEvent
 	var count int64
 
-	db := e.DB().Model(&models.AlertHisEvent{})
+	db := e.replica().Model(&models.AlertHisEvent{})
 	db.Where("tenant_id = ?", r.TenantId)
 	db.Where("fault_center_id = ?", r.FaultCenterId)
 
@@ -75,3 +78,96 @@ func (e EventRepo) CreateHistoryEvent(r models.AlertHisEvent) error {
 
 	return nil
 }
+
+func (e EventRepo) CreateTransition(r models.AlertTransition) error {
+	err := e.g.Create(models.AlertTransition{}, r)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (e EventRepo) ListTransition(r models.AlertTransitionQuery) (models.AlertTransitionResponse, error) {
+	var data []models.AlertTransition
+	var count int64
+
+	db := e.transitionQuery(r)
+	if err := db.Count(&count).Error; err != nil {
+		return models.AlertTransitionResponse{}, err
+	}
+
+	if err := db.Limit(int(r.Page.Size)).Offset(int((r.Page.Index - 1) * r.Page.Size)).Order("created_at desc").Find(&data).Error; err != nil {
+		return models.AlertTransitionResponse{}, err
+	}
+
+	return models.AlertTransitionResponse{
+		List: data,
+		Page: models.Page{
+			Index: r.Page.Index,
+			Size:  r.Page.Size,
+			Total: count,
+		},
+	}, nil
+}
+
+// ExportTransition 按创建时间逐条读取状态变更记录, 避免一次性加载全部数据
+func (e EventRepo) ExportTransition(r models.AlertTransitionQuery, fn func(models.AlertTransition) error) error {
+	db := e.transitionQuery(r)
+	rows, err := db.Order("created_at asc").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transition models.AlertTransition
+		if err := db.ScanRows(rows, &transition); err != nil {
+			return err
+		}
+		if err := fn(transition); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (e EventRepo) transitionQuery(r models.AlertTransitionQuery) *gorm.DB {
+	db := e.replica().Model(&models.AlertTransition{})
+	db.Where("tenant_id = ?", r.TenantId)
+
+	if r.FaultCenterId != "" {
+		db = db.Where("fault_center_id = ?", r.FaultCenterId)
+	}
+
+	if r.RuleId != "" {
+		db = db.Where("rule_id = ?", r.RuleId)
+	}
+
+	if r.Severity != "" {
+		db = db.Where("severity = ?", r.Severity)
+	}
+
+	if r.Fingerprint != "" {
+		db = db.Where("fingerprint = ?", r.Fingerprint)
+	}
+
+	if r.State != "" {
+		db = db.Where("to_state = ?", r.State)
+	}
+
+	if r.Actor != "" {
+		db = db.Where("actor = ?", r.Actor)
+	}
+
+	if r.StartAt != 0 {
+		db = db.Where("created_at >= ?", r.StartAt)
+	}
+
+	if r.EndAt != 0 {
+		db = db.Where("created_at <= ?", r.EndAt)
+	}
+
+	return db
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/zeromicro/go-zero/core/logc"
 	"gorm.io/gorm"
 	"watchAlert/internal/models"
@@ -19,6 +20,7 @@ type (
 		List(params models.FaultCenterQuery) ([]models.FaultCenter, error)
 		Get(params models.FaultCenterQuery) (models.FaultCenter, error)
 		Reset(params models.FaultCenter) error
+		SetReceiverTokenHash(tenantId, id, tokenHash string) error
 	}
 )
 
@@ -95,12 +97,18 @@ func (f faultCenterRepo) List(params models.FaultCenterQuery) ([]models.FaultCen
 func (f faultCenterRepo) Get(params models.FaultCenterQuery) (models.FaultCenter, error) {
 	var db = f.db.Model(&models.FaultCenter{})
 	var data models.FaultCenter
+	if params.TenantId != "" {
+		db.Where("tenant_id = ?", params.TenantId)
+	}
 	if params.Name != "" {
 		db.Where("name = ?", params.Name)
 	}
 	if params.ID != "" {
 		db.Where("id = ?", params.ID)
 	}
+	if params.ReceiverTokenHash != "" {
+		db.Where("receiverTokenHash = ?", params.ReceiverTokenHash)
+	}
 	err := db.First(&data).Error
 	if err != nil {
 		return data, err
@@ -122,11 +130,32 @@ func (f faultCenterRepo) Reset(params models.FaultCenter) error {
 		update = []string{"aggregation_type", params.AggregationType}
 	}
 
+	// 按标签分组聚合时同步更新分组配置
+	if params.AggregationType == models.AggregationTypeLabels {
+		groupBy, _ := json.Marshal(params.GroupBy)
+		err := f.g.Updates(Updates{
+			Table: &models.FaultCenter{},
+			Where: map[string]interface{}{
+				"tenant_id = ?": params.TenantId,
+				"id = ?":        params.ID,
+			},
+			Updates: map[string]interface{}{
+				"groupBy":        string(groupBy),
+				"group_wait":     params.GroupWait,
+				"group_interval": params.GroupInterval,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	if update != nil {
 		err := f.g.Update(Update{
 			Table: &models.FaultCenter{},
 			Where: map[string]interface{}{
-				"id = ?": params.ID,
+				"tenant_id = ?": params.TenantId,
+				"id = ?":        params.ID,
 			},
 			Update: update,
 		})
@@ -136,3 +165,17 @@ func (f faultCenterRepo) Reset(params models.FaultCenter) error {
 	}
 	return nil
 }
+
+// SetReceiverTokenHash 更新外部告警接入 Token, 原 Token 随即失效
+func (f faultCenterRepo) SetReceiverTokenHash(tenantId, id, tokenHash string) error {
+	return f.g.Updates(Updates{
+		Table: &models.FaultCenter{},
+		Where: map[string]interface{}{
+			"tenant_id = ?": tenantId,
+			"id = ?":        id,
+		},
+		Updates: map[string]interface{}{
+			"receiverTokenHash": tokenHash,
+		},
+	})
+}
@@ -71,7 +71,7 @@ func (g GormDBCli) executeTransaction(operation func(tx *gorm.DB) error, errorMe
 
 	if err := operation(tx); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("%s -> %s", errorMessage, err)
+		return fmt.Errorf("%s -> %w", errorMessage, err)
 	}
 
 	if err := tx.Commit().Error; err != nil {
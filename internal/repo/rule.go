@@ -1,6 +1,9 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"watchAlert/internal/models"
 )
@@ -19,6 +22,9 @@ type (
 		Delete(r models.AlertRuleQuery) error
 		GetRuleIsExist(ruleId string) bool
 		GetRuleObject(ruleId string) models.AlertRule
+		ListAll(r models.RuleExportQuery) ([]models.AlertRule, error)
+		Replace(r models.AlertRule) error
+		GetByUid(tenantId, uid string) (models.AlertRule, bool, error)
 	}
 )
 
@@ -53,7 +59,7 @@ func (rr RuleRepo) GetQuota(id string) bool {
 func (rr RuleRepo) Search(r models.AlertRuleQuery) (models.AlertRule, error) {
 	var data models.AlertRule
 
-	db := rr.db.Model(&models.AlertRule{})
+	db := rr.replica().Model(&models.AlertRule{})
 	db.Where("tenant_id = ? AND rule_group_id = ? AND rule_id = ?", r.TenantId, r.RuleGroupId, r.RuleId)
 	err := db.First(&data).Error
 	if err != nil {
@@ -116,7 +122,7 @@ func (rr RuleRepo) List(r models.AlertRuleQuery) (models.RuleResponse, error) {
 func (rr RuleRepo) Create(r models.AlertRule) error {
 	err := rr.g.Create(models.AlertRule{}, r)
 	if err != nil {
-		return err
+		return wrapRuleUidConflict(err, r.Uid)
 	}
 
 	return nil
@@ -134,7 +140,7 @@ func (rr RuleRepo) Update(r models.AlertRule) error {
 
 	err := rr.g.Updates(u)
 	if err != nil {
-		return err
+		return wrapRuleUidConflict(err, r.Uid)
 	}
 
 	return nil
@@ -178,3 +184,57 @@ func (rr RuleRepo) GetRuleObject(ruleId string) models.AlertRule {
 
 	return data
 }
+
+// ListAll 获取租户下的全部规则, 用于规则导入导出, 不分页
+func (rr RuleRepo) ListAll(r models.RuleExportQuery) ([]models.AlertRule, error) {
+	var data []models.AlertRule
+	db := rr.db.Model(&models.AlertRule{})
+	db.Where("tenant_id = ?", r.TenantId)
+	if r.RuleGroupId != "" {
+		db.Where("rule_group_id = ?", r.RuleGroupId)
+	}
+	if len(r.RuleIds) > 0 {
+		db.Where("rule_id IN ?", r.RuleIds)
+	}
+
+	err := db.Order("rule_group_id, rule_id").Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Replace 更新规则的全部字段, 零值字段同样写入, 用于规则导入时与文档保持一致
+func (rr RuleRepo) Replace(r models.AlertRule) error {
+	err := rr.db.Model(&models.AlertRule{}).
+		Where("tenant_id = ? AND rule_id = ?", r.TenantId, r.RuleId).
+		Select("*").
+		Updates(&r).Error
+	return wrapRuleUidConflict(err, r.Uid)
+}
+
+// GetByUid 按客户端提供的 UID 获取租户下的规则, 不存在时返回 false
+func (rr RuleRepo) GetByUid(tenantId, uid string) (models.AlertRule, bool, error) {
+	var data models.AlertRule
+	err := rr.db.Model(&models.AlertRule{}).
+		Where("tenant_id = ? AND uid = ?", tenantId, uid).
+		First(&data).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return data, false, nil
+		}
+		return data, false, err
+	}
+
+	return data, true, nil
+}
+
+// wrapRuleUidConflict 违反租户内 UID 唯一索引时返回 ErrRuleUidConflict
+func wrapRuleUidConflict(err error, uid string) error {
+	var mysqlErr *mysqlDriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return fmt.Errorf("%w, UID %s 已被其他规则使用", models.ErrRuleUidConflict, uid)
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"gorm.io/gorm"
 	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
 )
 
 type (
@@ -15,6 +16,7 @@ type (
 		Update(r models.Settings) error
 		Get() (models.Settings, error)
 		Check() bool
+		UpdateMaintenance(r models.MaintenanceConfig) error
 	}
 )
 
@@ -63,6 +65,19 @@ func (a settingRepo) Get() (models.Settings, error) {
 	return data, nil
 }
 
+// UpdateMaintenance 更新维护模式, serializer 字段按 map 更新时需自行序列化
+func (a settingRepo) UpdateMaintenance(r models.MaintenanceConfig) error {
+	return a.g.Updates(Updates{
+		Table: models.Settings{},
+		Where: map[string]interface{}{
+			"is_init = ?": 1,
+		},
+		Updates: map[string]interface{}{
+			"maintenance_config": tools.JsonMarshal(r),
+		},
+	})
+}
+
 func (a settingRepo) Check() bool {
 	var data models.Settings
 	db := a.db.Model(models.Settings{})
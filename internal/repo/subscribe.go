@@ -12,6 +12,7 @@ type (
 
 	InterSubscribeRepo interface {
 		List(r models.AlertSubscribeQuery) ([]models.AlertSubscribe, error)
+		ListMatched(tenantId, ruleId string) ([]models.AlertSubscribe, error)
 		Get(r models.AlertSubscribeQuery) (models.AlertSubscribe, bool, error)
 		Create(r models.AlertSubscribe) error
 		Delete(r models.AlertSubscribeQuery) error
@@ -49,6 +50,19 @@ func (s subscribeRepo) List(r models.AlertSubscribeQuery) ([]models.AlertSubscri
 	return data, nil
 }
 
+// ListMatched 获取订阅了该规则及未指定规则 (按标签订阅) 的订阅列表
+func (s subscribeRepo) ListMatched(tenantId, ruleId string) ([]models.AlertSubscribe, error) {
+	var data []models.AlertSubscribe
+	err := s.db.Model(models.AlertSubscribe{}).
+		Where("s_tenant_id = ? AND (s_rule_id = ? OR s_rule_id = '')", tenantId, ruleId).
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
 func (s subscribeRepo) Get(r models.AlertSubscribeQuery) (models.AlertSubscribe, bool, error) {
 	var (
 		data models.AlertSubscribe
@@ -85,12 +99,18 @@ func (s subscribeRepo) Create(r models.AlertSubscribe) error {
 }
 
 func (s subscribeRepo) Delete(r models.AlertSubscribeQuery) error {
+	where := map[string]interface{}{
+		"s_tenant_id": r.STenantId,
+		"s_id":        r.SId,
+	}
+	// 指定用户时仅删除该用户自己的订阅
+	if r.SUserId != "" {
+		where["s_user_id"] = r.SUserId
+	}
+
 	err := s.g.Delete(Delete{
 		Table: models.AlertSubscribe{},
-		Where: map[string]interface{}{
-			"s_tenant_id": r.STenantId,
-			"s_id":        r.SId,
-		},
+		Where: where,
 	})
 	if err != nil {
 		return err
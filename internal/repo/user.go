@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"gorm.io/gorm"
@@ -20,6 +21,8 @@ type (
 		Search(r models.MemberQuery) ([]models.Member, error)
 		List() ([]models.Member, error)
 		Get(r models.MemberQuery) (models.Member, bool, error)
+		GetByOidcSubject(issuer, subject string) (models.Member, bool, error)
+		GetUnboundOidcUserByEmail(email string) (models.Member, bool, error)
 		Create(r models.Member) error
 		Update(r models.Member) error
 		Delete(r models.MemberQuery) error
@@ -91,6 +94,38 @@ func (ur UserRepo) Get(r models.MemberQuery) (models.Member, bool, error) {
 	return data, true, nil
 }
 
+// GetByOidcSubject 按身份提供方的签发方及用户标识获取 OIDC 用户
+func (ur UserRepo) GetByOidcSubject(issuer, subject string) (models.Member, bool, error) {
+	var data models.Member
+	err := ur.db.Model(models.Member{}).
+		Where("oidcIssuer = ? AND oidcSubject = ?", issuer, subject).
+		First(&data).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return data, false, nil
+		}
+		return data, false, err
+	}
+
+	return data, true, nil
+}
+
+// GetUnboundOidcUserByEmail 按邮箱获取尚未关联身份提供方用户标识的 OIDC 用户, 用于关联此前按用户名创建的账号
+func (ur UserRepo) GetUnboundOidcUserByEmail(email string) (models.Member, bool, error) {
+	var data models.Member
+	err := ur.db.Model(models.Member{}).
+		Where("email = ? AND create_by = ? AND (oidcSubject = '' OR oidcSubject IS NULL)", email, "OIDC").
+		First(&data).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return data, false, nil
+		}
+		return data, false, err
+	}
+
+	return data, true, nil
+}
+
 func (ur UserRepo) Create(r models.Member) error {
 	err := ur.g.Create(models.Member{}, r)
 	if err != nil {
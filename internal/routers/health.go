@@ -1,13 +1,55 @@
 package routers
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
+	"sync"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/provider"
+)
+
+const (
+	healthStatusOk       = "ok"
+	healthStatusDegraded = "degraded"
+	healthStatusFail     = "fail"
+
+	// readyCheckTimeout 就绪检查的超时时间, 超时未返回的数据源视为异常
+	readyCheckTimeout = 5 * time.Second
+)
+
+type (
+	// dependencyStatus 依赖组件的检查结果
+	dependencyStatus struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// datasourceStatus 数据源的检查结果
+	datasourceStatus struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+		dependencyStatus
+	}
+
+	readyResponse struct {
+		Status      string             `json:"status"`
+		MySQL       dependencyStatus   `json:"mysql"`
+		Redis       dependencyStatus   `json:"redis"`
+		Datasources []datasourceStatus `json:"datasources"`
+	}
 )
 
 func HealthCheck(gin *gin.Engine) {
 
 	gin.GET("hello", health)
+	gin.GET("healthz", health)
+	gin.GET("readyz", ready)
+	gin.GET("metrics", metricsHandler)
 
 }
 
@@ -18,3 +60,118 @@ func health(ctx *gin.Context) {
 	})
 
 }
+
+// metricsHandler 暴露 WatchAlert 自身运行指标
+func metricsHandler(c *gin.Context) {
+	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// ready 就绪检查, MySQL / Redis 异常时返回 503, 数据源异常仅标记为 degraded
+func ready(c *gin.Context) {
+	timeoutCtx, cancel := context.WithTimeout(c.Request.Context(), readyCheckTimeout)
+	defer cancel()
+
+	res := readyResponse{
+		Status:      healthStatusOk,
+		MySQL:       checkMySQL(timeoutCtx),
+		Redis:       checkRedis(),
+		Datasources: []datasourceStatus{},
+	}
+
+	if res.MySQL.Status == healthStatusOk {
+		res.Datasources = checkDatasources(timeoutCtx)
+	}
+
+	for _, ds := range res.Datasources {
+		if ds.Status != healthStatusOk {
+			res.Status = healthStatusDegraded
+			break
+		}
+	}
+
+	code := http.StatusOK
+	if res.MySQL.Status != healthStatusOk || res.Redis.Status != healthStatusOk {
+		res.Status = healthStatusFail
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, res)
+}
+
+func checkMySQL(timeoutCtx context.Context) dependencyStatus {
+	if ctx.DB == nil {
+		return dependencyStatus{Status: healthStatusFail, Error: "数据库未初始化"}
+	}
+
+	sqlDB, err := ctx.DB.DB().DB()
+	if err == nil {
+		err = sqlDB.PingContext(timeoutCtx)
+	}
+	if err != nil {
+		return dependencyStatus{Status: healthStatusFail, Error: err.Error()}
+	}
+	return dependencyStatus{Status: healthStatusOk}
+}
+
+func checkRedis() dependencyStatus {
+	if ctx.Redis == nil {
+		return dependencyStatus{Status: healthStatusFail, Error: "Redis 未初始化"}
+	}
+
+	if err := ctx.Redis.Redis().Ping().Err(); err != nil {
+		return dependencyStatus{Status: healthStatusFail, Error: err.Error()}
+	}
+	return dependencyStatus{Status: healthStatusOk}
+}
+
+// checkDatasources 并发检查所有已启用的数据源
+func checkDatasources(timeoutCtx context.Context) []datasourceStatus {
+	list, err := ctx.DB.Datasource().List(models.DatasourceQuery{})
+	if err != nil {
+		return []datasourceStatus{{dependencyStatus: dependencyStatus{Status: healthStatusFail, Error: err.Error()}}}
+	}
+
+	var enabled []models.AlertDataSource
+	for _, ds := range list {
+		if *ds.GetEnabled() {
+			enabled = append(enabled, ds)
+		}
+	}
+
+	results := make([]datasourceStatus, len(enabled))
+	var wg sync.WaitGroup
+	for i, ds := range enabled {
+		results[i] = datasourceStatus{
+			Id:               ds.Id,
+			Name:             ds.Name,
+			Type:             ds.Type,
+			dependencyStatus: dependencyStatus{Status: healthStatusFail, Error: "检查超时"},
+		}
+
+		done := make(chan dependencyStatus, 1)
+		go func(ds models.AlertDataSource) {
+			ok, err := provider.CheckDatasourceHealth(timeoutCtx, ds)
+			switch {
+			case err != nil:
+				done <- dependencyStatus{Status: healthStatusFail, Error: err.Error()}
+			case !ok:
+				done <- dependencyStatus{Status: healthStatusFail}
+			default:
+				done <- dependencyStatus{Status: healthStatusOk}
+			}
+		}(ds)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case status := <-done:
+				results[i].dependencyStatus = status
+			case <-timeoutCtx.Done():
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
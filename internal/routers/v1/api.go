@@ -16,6 +16,29 @@ func Router(engine *gin.Engine) {
 			system.GET("checkUser", Auth.CheckUser)
 			system.GET("checkNoticeStatus", Notice.Check)
 			system.GET("userInfo", Auth.Get)
+			// OIDC 单点登陆, 跳转至身份提供方授权后由前端回调页面提交授权码
+			system.GET("oidc/authorize", Auth.OidcAuthorize)
+			system.POST("oidc/login", Auth.OidcLogin)
+		}
+
+		// 通知消息中的操作链接, 通过链接中的签名 Token 鉴权
+		callback := v1.Group("callback")
+		{
+			callback.GET("ackAlert", Callback.AckAlertConfirm)
+			callback.POST("ackAlert", Callback.AckAlert)
+		}
+
+		// 外部告警接入, 通过故障中心的接入 Token 鉴权
+		receiver := v1.Group("receiver")
+		{
+			receiver.POST("alertmanager", Receiver.Alertmanager)
+		}
+
+		auth := v1.Group("auth")
+		{
+			auth.POST("login", Auth.LoginWithRefresh)
+			auth.POST("refresh", Auth.Refresh)
+			auth.POST("logout", Auth.Logout)
 		}
 
 		w8t := v1.Group("w8t")
@@ -32,4 +32,6 @@ var (
 	Probing        = api.ApiGroupApp.ProbingController
 	FaultCenter    = api.ApiGroupApp.FaultCenterController
 	Ai             = api.ApiGroupApp.AiController
+	Callback       = api.ApiGroupApp.CallbackController
+	Receiver       = api.ApiGroupApp.ReceiverController
 )
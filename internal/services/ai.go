@@ -40,6 +40,10 @@ func (a aiService) Chat(req interface{}) (interface{}, interface{}) {
 	if err != nil {
 		return nil, err
 	}
+	// 分析结果按规则缓存, 仅允许读取当前租户规则的分析结果
+	if r.RuleId != "" && a.ctx.DB.Rule().GetRuleObject(r.RuleId).TenantId != r.TenantId {
+		return nil, fmt.Errorf("规则不存在, ruleId: %s", r.RuleId)
+	}
 
 	client, err := a.ctx.Redis.ProviderPools().GetClient("AiClient")
 	if err != nil {
@@ -21,6 +21,10 @@ type InterDatasourceService interface {
 	Search(req interface{}) (interface{}, interface{})
 	WithAddClientToProviderPools(datasource models.AlertDataSource) error
 	WithRemoveClientForProviderPools(datasourceId string)
+	BreakerStates(req interface{}) (interface{}, interface{})
+	RetryStats(req interface{}) (interface{}, interface{})
+	ListFields(req interface{}) (interface{}, interface{})
+	ListIndices(req interface{}) (interface{}, interface{})
 }
 
 func newInterDatasourceService(ctx *ctx.Context) InterDatasourceService {
@@ -31,6 +35,9 @@ func newInterDatasourceService(ctx *ctx.Context) InterDatasourceService {
 
 func (ds datasourceService) Create(req interface{}) (interface{}, interface{}) {
 	dataSource := req.(*models.AlertDataSource)
+	if err := dataSource.EsQueryGuard.Validate(); err != nil {
+		return nil, err
+	}
 
 	id := "ds-" + tools.RandId()
 	data := dataSource
@@ -51,6 +58,9 @@ func (ds datasourceService) Create(req interface{}) (interface{}, interface{}) {
 
 func (ds datasourceService) Update(req interface{}) (interface{}, interface{}) {
 	dataSource := req.(*models.AlertDataSource)
+	if err := dataSource.EsQueryGuard.Validate(); err != nil {
+		return nil, err
+	}
 
 	err := ds.ctx.DB.Datasource().Update(*dataSource)
 	if err != nil {
@@ -110,41 +120,112 @@ func (ds datasourceService) Search(req interface{}) (interface{}, interface{}) {
 }
 
 func (ds datasourceService) WithAddClientToProviderPools(datasource models.AlertDataSource) error {
-	var (
-		cli interface{}
-		err error
-	)
 	pools := ds.ctx.Redis.ProviderPools()
-	switch datasource.Type {
-	case provider.PrometheusDsProvider:
-		cli, err = provider.NewPrometheusClient(datasource)
-	case provider.VictoriaMetricsDsProvider:
-		cli, err = provider.NewVictoriaMetricsClient(datasource)
-	case provider.LokiDsProviderName:
-		cli, err = provider.NewLokiClient(datasource)
-	case provider.AliCloudSLSDsProviderName:
-		cli, err = provider.NewAliCloudSlsClient(datasource)
-	case provider.ElasticSearchDsProviderName:
-		cli, err = provider.NewElasticSearchClient(ctx.Ctx, datasource)
-	case provider.VictoriaLogsDsProviderName:
-		cli, err = provider.NewVictoriaLogsClient(ctx.Ctx, datasource)
-	case provider.JaegerDsProviderName:
-		cli, err = provider.NewJaegerClient(datasource)
-	case "Kubernetes":
-		cli, err = provider.NewKubernetesClient(ds.ctx.Ctx, datasource.KubeConfig, datasource.Labels)
-	case "CloudWatch":
-		cli, err = provider.NewAWSCredentialCfg(datasource.AWSCloudWatch.Region, datasource.AWSCloudWatch.AccessKey, datasource.AWSCloudWatch.SecretKey, datasource.Labels)
-	}
-
+	cli, err := provider.NewClient(ds.ctx.Ctx, datasource)
 	if err != nil {
 		return fmt.Errorf("New %s client failed, err: %s", datasource.Type, err.Error())
 	}
 
 	pools.SetClient(datasource.Id, cli)
+	provider.SetDatasourceQueryLimit(datasource.Id, datasource.MaxConcurrentQueries)
+	// 配置变更后重新探测数据源
+	provider.RemoveBreaker(datasource.Id)
 	return nil
 }
 
 func (ds datasourceService) WithRemoveClientForProviderPools(datasourceId string) {
 	pools := ds.ctx.Redis.ProviderPools()
 	pools.RemoveClient(datasourceId)
+	provider.CloseElasticSearchClient(datasourceId)
+	provider.CloseSQLClient(datasourceId)
+	provider.RemoveRetryStats(datasourceId)
+	provider.RemoveDatasourceQueryLimit(datasourceId)
+	provider.RemoveBreaker(datasourceId)
+}
+
+// BreakerStates 获取租户内各数据源的熔断状态, 用于展示数据源不可用
+func (ds datasourceService) BreakerStates(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DatasourceQuery)
+	list, err := ds.ctx.DB.Datasource().List(models.DatasourceQuery{TenantId: r.TenantId})
+	if err != nil {
+		return nil, err
+	}
+
+	states := provider.GetBreakerStates()
+	data := make(map[string]provider.BreakerState, len(list))
+	for _, d := range list {
+		state, ok := states[d.Id]
+		if !ok {
+			state = provider.BreakerState{State: provider.BreakerClosed}
+		}
+		data[d.Id] = state
+	}
+
+	return data, nil
+}
+
+// RetryStats 获取租户内各数据源的重试统计, 未发生重试的数据源不返回
+func (ds datasourceService) RetryStats(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DatasourceQuery)
+	list, err := ds.ctx.DB.Datasource().List(models.DatasourceQuery{TenantId: r.TenantId})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := provider.GetRetryStats()
+	data := make(map[string]provider.RetryStats, len(list))
+	for _, d := range list {
+		if s, ok := stats[d.Id]; ok {
+			data[d.Id] = s
+		}
+	}
+
+	return data, nil
+}
+
+// ListFields 获取日志数据源索引映射中的字段, 数据源不支持时返回错误
+func (ds datasourceService) ListFields(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DatasourceSchemaQuery)
+	cli, err := ds.newLogsClient(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := provider.ListLogFields(cli, r.Index)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ListIndices 获取日志数据源中匹配的索引, 数据源不支持时返回错误
+func (ds datasourceService) ListIndices(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DatasourceSchemaQuery)
+	cli, err := ds.newLogsClient(r)
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := provider.ListLogIndices(cli, r.Index)
+	if err != nil {
+		return nil, err
+	}
+	return indices, nil
+}
+
+func (ds datasourceService) newLogsClient(r *models.DatasourceSchemaQuery) (provider.LogsFactoryProvider, error) {
+	datasource, err := ds.ctx.DB.Datasource().Get(models.DatasourceQuery{TenantId: r.TenantId, Id: r.DatasourceId})
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := provider.NewClient(ds.ctx.Ctx, datasource)
+	if err != nil {
+		return nil, err
+	}
+	logsCli, ok := cli.(provider.LogsFactoryProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s 不是日志数据源", provider.ErrNotSupported, datasource.Type)
+	}
+	return logsCli, nil
 }
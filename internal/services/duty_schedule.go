@@ -19,6 +19,10 @@ type InterDutyCalendarService interface {
 	Update(req interface{}) (interface{}, interface{})
 	Search(req interface{}) (interface{}, interface{})
 	GetCalendarUsers(req interface{}) (interface{}, interface{})
+	GetOnCallUser(req interface{}) (interface{}, interface{})
+	CreateOverride(req interface{}) (interface{}, interface{})
+	DeleteOverride(req interface{}) (interface{}, interface{})
+	ListOverrides(req interface{}) (interface{}, interface{})
 }
 
 func newInterDutyCalendarService(ctx *ctx.Context) InterDutyCalendarService {
@@ -75,6 +79,59 @@ func (dms dutyCalendarService) GetCalendarUsers(req interface{}) (interface{}, i
 	return data, nil
 }
 
+// GetOnCallUser 获取当前值班人员
+func (dms dutyCalendarService) GetOnCallUser(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DutyScheduleQuery)
+	onCall, ok := dms.ctx.DB.DutyCalendar().GetOnCallUser(r.TenantId, r.DutyId, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("当前暂无值班人员")
+	}
+
+	return onCall, nil
+}
+
+// CreateOverride 创建临时换班
+func (dms dutyCalendarService) CreateOverride(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DutyOverride)
+	if r.DutyId == "" || r.UserId == "" {
+		return nil, fmt.Errorf("值班表及换班人员不能为空")
+	}
+	if r.EndsAt <= r.StartsAt {
+		return nil, fmt.Errorf("换班结束时间需晚于开始时间")
+	}
+
+	r.ID = "do-" + tools.RandId()
+	r.CreateAt = time.Now().Unix()
+	err := dms.ctx.DB.DutyCalendar().CreateOverride(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// DeleteOverride 删除临时换班
+func (dms dutyCalendarService) DeleteOverride(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DutyOverrideQuery)
+	err := dms.ctx.DB.DutyCalendar().DeleteOverride(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// ListOverrides 获取未结束的临时换班
+func (dms dutyCalendarService) ListOverrides(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.DutyOverrideQuery)
+	data, err := dms.ctx.DB.DutyCalendar().ListOverrides(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
 func (dms dutyCalendarService) generateDutySchedule(dutyInfo models.DutyScheduleCreate) ([]models.DutySchedule, error) {
 	curYear, curMonth, _ := tools.ParseTime(dutyInfo.Month)
 	dutyDays := dms.calculateDutyDays(dutyInfo.DateType, dutyInfo.DutyPeriod)
@@ -32,10 +32,12 @@ var (
 	SettingService          InterSettingService
 	ClientService           InterClientService
 	LdapService             InterLdapService
+	OidcService             InterOidcService
 	SubscribeService        InterAlertSubscribeService
 	ProbingService          InterProbingService
 	FaultCenterService      InterFaultCenterService
 	AiService               InterAiService
+	ReceiverService         InterReceiverService
 )
 
 func NewServices(ctx *ctx.Context) {
@@ -63,8 +65,10 @@ func NewServices(ctx *ctx.Context) {
 	SettingService = newInterSettingService(ctx)
 	ClientService = newInterClientService(ctx)
 	LdapService = newInterLdapService(ctx)
+	OidcService = newInterOidcService(ctx)
 	SubscribeService = newInterAlertSubscribe(ctx)
 	ProbingService = newInterProbingService(ctx, &alert.ProductProbing, &alert.ConsumeProbing)
 	FaultCenterService = newInterFaultCenterService(ctx)
 	AiService = newInterAiService(ctx)
+	ReceiverService = newInterReceiverService(ctx)
 }
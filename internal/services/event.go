@@ -1,9 +1,14 @@
 package services
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
 	"strings"
 	"sync"
 	"time"
+	"watchAlert/alert/mute"
+	"watchAlert/alert/process"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/tools"
@@ -17,6 +22,8 @@ type InterEventService interface {
 	ListCurrentEvent(req interface{}) (interface{}, interface{})
 	ListHistoryEvent(req interface{}) (interface{}, interface{})
 	ProcessAlertEvent(req interface{}) (interface{}, interface{})
+	ListTransition(req interface{}) (interface{}, interface{})
+	ExportTransition(r *models.AlertTransitionExport, w io.Writer) error
 }
 
 func newInterEventService(ctx *ctx.Context) InterEventService {
@@ -38,6 +45,7 @@ func (e eventService) ProcessAlertEvent(req interface{}) (interface{}, interface
 				return
 			}
 
+			var transition string
 			switch r.State {
 			case 1:
 				if cache.UpgradeState.IsConfirm {
@@ -47,6 +55,7 @@ func (e eventService) ProcessAlertEvent(req interface{}) (interface{}, interface
 				cache.UpgradeState.IsConfirm = true
 				cache.UpgradeState.WhoAreConfirm = r.Username
 				cache.UpgradeState.ConfirmOkTime = r.Time
+				transition = models.TransitionAcked
 			case 2:
 				if !cache.UpgradeState.IsConfirm && cache.UpgradeState.IsHandle {
 					return
@@ -55,9 +64,13 @@ func (e eventService) ProcessAlertEvent(req interface{}) (interface{}, interface
 				cache.UpgradeState.IsHandle = true
 				cache.UpgradeState.WhoAreHandle = r.Username
 				cache.UpgradeState.HandleOkTime = r.Time
+				transition = models.TransitionHandled
 			}
 
 			e.ctx.Redis.Alert().PushAlertEvent(&cache)
+			if transition != "" {
+				process.RecordTransition(e.ctx, cache, string(cache.Status), transition, r.Username)
+			}
 		}(fingerprint)
 	}
 
@@ -72,9 +85,17 @@ func (e eventService) ListCurrentEvent(req interface{}) (interface{}, interface{
 		return nil, err
 	}
 
+	// 计算被抑制的告警及其源告警
+	inhibitRules := e.ctx.Redis.FaultCenter().GetFaultCenterInfo(models.BuildFaultCenterInfoCacheKey(r.TenantId, r.FaultCenterId)).InhibitRules
+
 	var dataList []models.AlertCurEvent
 	for _, alert := range center {
-		dataList = append(dataList, *alert)
+		event := *alert
+		event.InhibitedBy = mute.InhibitedBy(inhibitRules, alert, center)
+		if r.Inhibited && len(event.InhibitedBy) == 0 {
+			continue
+		}
+		dataList = append(dataList, event)
 	}
 
 	if r.DatasourceType != "" {
@@ -192,3 +213,65 @@ func pageSlice(data []models.AlertCurEvent, index, size int) []models.AlertCurEv
 
 	return data[offset:limit]
 }
+
+func (e eventService) ListTransition(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.AlertTransitionQuery)
+	data, err := e.ctx.DB.Event().ListTransition(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// transitionExportFlushSize 导出时每写入多少条记录刷新一次响应
+const transitionExportFlushSize = 500
+
+var transitionCSVHeader = []string{"id", "faultCenterId", "ruleId", "ruleName", "fingerprint", "severity", "fromState", "toState", "actor", "createdAt", "metric"}
+
+// ExportTransition 以 CSV 或 NDJSON 格式流式导出状态变更记录
+func (e eventService) ExportTransition(r *models.AlertTransitionExport, w io.Writer) error {
+	flush := func() {
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+
+	var n int
+	if r.Format == models.TransitionExportFormatNDJSON {
+		encoder := json.NewEncoder(w)
+		return e.ctx.DB.Event().ExportTransition(r.AlertTransitionQuery, func(t models.AlertTransition) error {
+			if err := encoder.Encode(t); err != nil {
+				return err
+			}
+			if n++; n%transitionExportFlushSize == 0 {
+				flush()
+			}
+			return nil
+		})
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(transitionCSVHeader); err != nil {
+		return err
+	}
+	err := e.ctx.DB.Event().ExportTransition(r.AlertTransitionQuery, func(t models.AlertTransition) error {
+		err := cw.Write([]string{
+			t.ID, t.FaultCenterId, t.RuleId, t.RuleName, t.Fingerprint, t.Severity, t.FromState, t.ToState, t.Actor,
+			time.Unix(t.CreatedAt, 0).Format(time.RFC3339), tools.JsonMarshal(t.Metric),
+		})
+		if err != nil {
+			return err
+		}
+		if n++; n%transitionExportFlushSize == 0 {
+			cw.Flush()
+			flush()
+		}
+		return cw.Error()
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
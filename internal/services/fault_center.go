@@ -1,8 +1,11 @@
 package services
 
 import (
+	"fmt"
 	"time"
 	"watchAlert/alert"
+	"watchAlert/alert/consumer"
+	"watchAlert/alert/mute"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/tools"
@@ -20,6 +23,7 @@ type (
 		List(req interface{}) (data interface{}, err interface{})
 		Get(req interface{}) (data interface{}, err interface{})
 		Reset(req interface{}) (data interface{}, err interface{})
+		ResetReceiverToken(req interface{}) (data interface{}, err interface{})
 	}
 )
 
@@ -31,6 +35,13 @@ func newInterFaultCenterService(ctx *ctx.Context) InterFaultCenterService {
 
 func (f faultCenterService) Create(req interface{}) (data interface{}, err interface{}) {
 	r := req.(*models.FaultCenter)
+	if err := mute.ValidateInhibitRules(r.InhibitRules); err != nil {
+		return nil, err
+	}
+	if err := consumer.ValidateRouteTree(r.RouteTree); err != nil {
+		return nil, err
+	}
+
 	r.ID = "fc-" + tools.RandId()
 	r.CreateAt = time.Now().Unix()
 	err = f.ctx.DB.FaultCenter().Create(*r)
@@ -46,6 +57,13 @@ func (f faultCenterService) Create(req interface{}) (data interface{}, err inter
 
 func (f faultCenterService) Update(req interface{}) (data interface{}, err interface{}) {
 	r := req.(*models.FaultCenter)
+	if err := mute.ValidateInhibitRules(r.InhibitRules); err != nil {
+		return nil, err
+	}
+	if err := consumer.ValidateRouteTree(r.RouteTree); err != nil {
+		return nil, err
+	}
+
 	err = f.ctx.DB.FaultCenter().Update(*r)
 	if err != nil {
 		return nil, err
@@ -122,9 +140,36 @@ func (f faultCenterService) Reset(req interface{}) (data interface{}, err interf
 	}
 
 	alert.ConsumerWork.Stop(r.ID)
-	data, err = f.ctx.DB.FaultCenter().Get(models.FaultCenterQuery{ID: r.ID})
+	data, err = f.ctx.DB.FaultCenter().Get(models.FaultCenterQuery{TenantId: r.TenantId, ID: r.ID})
 	f.ctx.Redis.FaultCenter().PushFaultCenterInfo(data.(models.FaultCenter))
 	alert.ConsumerWork.Submit(data.(models.FaultCenter))
 
 	return nil, nil
 }
+
+// ResetReceiverToken 生成故障中心的外部告警接入 Token, 仅保存其哈希, 原 Token 随即失效
+func (f faultCenterService) ResetReceiverToken(req interface{}) (data interface{}, err interface{}) {
+	r := req.(*models.FaultCenterQuery)
+	if r.ID == "" {
+		return nil, fmt.Errorf("故障中心 ID 不能为空")
+	}
+	if _, e := f.ctx.DB.FaultCenter().Get(models.FaultCenterQuery{TenantId: r.TenantId, ID: r.ID}); e != nil {
+		return nil, fmt.Errorf("故障中心不存在")
+	}
+
+	random, e := tools.RandToken()
+	if e != nil {
+		return nil, e
+	}
+	token := receiverTokenPrefix + random
+
+	e = f.ctx.DB.FaultCenter().SetReceiverTokenHash(r.TenantId, r.ID, hashReceiverToken(token))
+	if e != nil {
+		return nil, e
+	}
+
+	return models.FaultCenterReceiverToken{
+		Token:            token,
+		AlertmanagerPath: "/api/receiver/alertmanager",
+	}, nil
+}
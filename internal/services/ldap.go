@@ -1,11 +1,15 @@
 package services
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/robfig/cron/v3"
 	"github.com/zeromicro/go-zero/core/logc"
 	"gopkg.in/ldap.v2"
+	"net"
+	"os"
 	"time"
+	"watchAlert/config"
 	"watchAlert/internal/global"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
@@ -20,6 +24,7 @@ type InterLdapService interface {
 	ListUsers() ([]ldapUser, error)
 	SyncUserToW8t()
 	Login(username, password string) error
+	CheckUser(username string) error
 	SyncUsersCronjob()
 }
 
@@ -29,35 +34,124 @@ func newInterLdapService(ctx *ctx.Context) InterLdapService {
 	}
 }
 
+// ldapDialTimeout 单个 LDAP 服务的连接超时时间, 超时后尝试下一个地址
+const ldapDialTimeout = 5 * time.Second
+
+// getAdminAuth 按顺序尝试连接 LDAP 服务并完成管理员绑定, 直到其中一个成功
 func (l ldapService) getAdminAuth() (*ldap.Conn, error) {
-	ls, err := ldap.Dial("tcp", global.Config.Ldap.Address)
+	lc := global.GetConfig().Ldap
+	addresses := lc.GetAddresses()
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("LDAP 服务地址为空")
+	}
+
+	var lastErr error
+	for _, address := range addresses {
+		ls, err := l.dial(address)
+		if err != nil {
+			logc.Errorf(l.ctx.Ctx, fmt.Sprintf("无法连接 LDAP 服务器, Address: %s, err: %s", address, err.Error()))
+			lastErr = err
+			continue
+		}
+
+		err = ls.Bind(lc.AdminUser, lc.AdminPass)
+		if err != nil {
+			ls.Close()
+			logc.Errorf(l.ctx.Ctx, fmt.Sprintf("LDAP 管理员绑定失败, Address: %s, err: %s", address, err.Error()))
+			lastErr = err
+			continue
+		}
+
+		logc.Infof(l.ctx.Ctx, "LDAP 管理员绑定成功, Address: %s", address)
+		return ls, nil
+	}
+
+	return nil, lastErr
+}
+
+func (l ldapService) dial(address string) (*ldap.Conn, error) {
+	lc := global.GetConfig().Ldap
+
+	var tlsConfig *tls.Config
+	if lc.UseSSL || lc.StartTLS {
+		var err error
+		tlsConfig, err = newLdapTLSConfig(lc, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, ldapDialTimeout)
 	if err != nil {
-		logc.Errorf(l.ctx.Ctx, fmt.Sprintf("无法连接 LDAP 服务器, Address: %s, err: %s", global.Config.Ldap.Address, err.Error()))
 		return nil, err
 	}
 
-	err = ls.Bind(global.Config.Ldap.AdminUser, global.Config.Ldap.AdminPass)
+	if lc.UseSSL {
+		tlsConn := tls.Client(conn, tlsConfig)
+		_ = tlsConn.SetDeadline(time.Now().Add(ldapDialTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAPS 握手失败: %s", err.Error())
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+
+		ls := ldap.NewConn(tlsConn, true)
+		ls.Start()
+		return ls, nil
+	}
+
+	ls := ldap.NewConn(conn, false)
+	ls.Start()
+	if lc.StartTLS {
+		if err := ls.StartTLS(tlsConfig); err != nil {
+			ls.Close()
+			return nil, fmt.Errorf("LDAP StartTLS 失败: %s", err.Error())
+		}
+	}
+	return ls, nil
+}
+
+// newLdapTLSConfig 创建 LDAP 加密连接的 TLS 配置, 默认校验服务端证书
+func newLdapTLSConfig(lc config.Ldap, address string) (*tls.Config, error) {
+	var caCert string
+	if lc.CACert != "" {
+		content, err := os.ReadFile(lc.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 LDAP CA 证书失败: %s", err.Error())
+		}
+		caCert = string(content)
+	}
+
+	tlsConfig, err := tools.NewTLSConfig(caCert, "", "", lc.InsecureSkipVerify)
 	if err != nil {
-		logc.Errorf(l.ctx.Ctx, fmt.Sprintf("LDAP 管理员绑定失败 err: %s", err.Error()))
 		return nil, err
 	}
 
-	return ls, nil
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	tlsConfig.ServerName = host
+	return tlsConfig, nil
 }
 
 type ldapUser struct {
-	Uid    string `json:"uid"`
-	Mobile string `json:"mobile"`
-	Mail   string `json:"mail"`
+	Uid      string   `json:"uid"`
+	Mobile   string   `json:"mobile"`
+	Mail     string   `json:"mail"`
+	MemberOf []string `json:"memberOf"`
 }
 
+// ldapUserAttributes 需要读取的用户属性, memberOf 为操作属性, 需显式指定才会返回
+var ldapUserAttributes = []string{"uid", "mobile", "mail", "memberOf"}
+
 func (l ldapService) ListUsers() ([]ldapUser, error) {
-	lc := global.Config.Ldap
+	lc := global.GetConfig().Ldap
 	searchRequest := ldap.NewSearchRequest(
 		lc.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		"(&(objectClass=*))",
-		[]string{},
+		ldapUserAttributes,
 		nil,
 	)
 
@@ -79,9 +173,10 @@ func (l ldapService) ListUsers() ([]ldapUser, error) {
 			continue
 		}
 		users = append(users, ldapUser{
-			Uid:    entry.GetAttributeValue("uid"),
-			Mobile: entry.GetAttributeValue("mobile"),
-			Mail:   entry.GetAttributeValue("mail"),
+			Uid:      entry.GetAttributeValue("uid"),
+			Mobile:   entry.GetAttributeValue("mobile"),
+			Mail:     entry.GetAttributeValue("mail"),
+			MemberOf: entry.GetAttributeValues("memberOf"),
 		})
 	}
 
@@ -108,7 +203,6 @@ func (l ldapService) SyncUserToW8t() {
 			Phone:    u.Mobile,
 			CreateBy: "LDAP",
 			CreateAt: time.Now().Unix(),
-			Tenants:  []string{"default"},
 		}
 		err = l.ctx.DB.User().Create(m)
 		if err != nil {
@@ -116,20 +210,8 @@ func (l ldapService) SyncUserToW8t() {
 			return
 		}
 
-		err = l.ctx.DB.Tenant().AddTenantLinkedUsers(models.TenantLinkedUsers{
-			ID:       "default",
-			UserRole: global.Config.Ldap.DefaultUserRole,
-			Users: []models.TenantUser{
-				{
-					UserID:   uid,
-					UserName: u.Mail,
-				},
-			},
-		})
-		if err != nil {
-			logc.Errorf(l.ctx.Ctx, err.Error())
-			return
-		}
+		// 按组映射加入对应的租户
+		syncUserTenants(l.ctx, m, global.GetConfig().Ldap.GetUserTenantRoles(u.MemberOf))
 	}
 }
 
@@ -139,26 +221,116 @@ func (l ldapService) Login(username, password string) error {
 		logc.Errorf(l.ctx.Ctx, err.Error())
 		return err
 	}
+	defer auth.Close()
+
+	// 用户绑定后连接身份会切换为该用户, 需先以管理员身份读取用户所属的组
+	lc := global.GetConfig().Ldap
+	var memberOf []string
+	if len(lc.GroupRoleMap) > 0 {
+		memberOf, err = l.getUserMemberOf(auth, username)
+		if err != nil {
+			logc.Errorf(l.ctx.Ctx, fmt.Sprintf("LDAP 用户组获取失败, err: %s", err.Error()))
+		}
+	}
 
-	userDn := fmt.Sprintf("%s=%s,%s", global.Config.Ldap.UserPrefix, username, global.Config.Ldap.UserDN)
+	userDn := fmt.Sprintf("%s=%s,%s", lc.UserPrefix, username, lc.UserDN)
 	err = auth.Bind(userDn, password)
 	if err != nil {
 		logc.Errorf(l.ctx.Ctx, fmt.Sprintf("LDAP 用户登陆失败, err: %s", err.Error()))
 		return err
 	}
 
+	if len(lc.GroupRoleMap) > 0 && memberOf != nil {
+		if user, ok, err := l.ctx.DB.User().Get(models.MemberQuery{UserName: username}); err == nil && ok {
+			syncUserTenants(l.ctx, user, lc.GetUserTenantRoles(memberOf))
+		}
+	}
+
 	return nil
 }
 
+// CheckUser 通过管理员账号校验用户仍存在于 UserDN 下且满足 userFilter, 用于刷新 Token 时拒绝已删除或禁用的用户
+func (l ldapService) CheckUser(username string) error {
+	auth, err := l.getAdminAuth()
+	if err != nil {
+		return err
+	}
+	defer auth.Close()
+
+	lc := global.GetConfig().Ldap
+	searchRequest := ldap.NewSearchRequest(
+		lc.UserDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 0, false,
+		getLdapUserFilter(lc, username),
+		[]string{"dn"},
+		nil,
+	)
+	sr, err := auth.Search(searchRequest)
+	if err != nil {
+		return err
+	}
+	if len(sr.Entries) == 0 {
+		return fmt.Errorf("LDAP 用户 %s 不存在或已禁用", username)
+	}
+	return nil
+}
+
+// getLdapUserFilter 按用户名查找用户的过滤条件, 配置 userFilter 时同时需满足该条件
+func getLdapUserFilter(lc config.Ldap, username string) string {
+	filter := fmt.Sprintf("(%s=%s)", lc.UserPrefix, ldap.EscapeFilter(username))
+	if lc.UserFilter == "" {
+		return filter
+	}
+	return fmt.Sprintf("(&%s%s)", filter, lc.UserFilter)
+}
+
+func (l ldapService) getUserMemberOf(conn *ldap.Conn, username string) ([]string, error) {
+	lc := global.GetConfig().Ldap
+	searchRequest := ldap.NewSearchRequest(
+		lc.UserDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(%s=%s)", lc.UserPrefix, ldap.EscapeFilter(username)),
+		[]string{"memberOf"},
+		nil,
+	)
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) == 0 {
+		return []string{}, nil
+	}
+
+	return sr.Entries[0].GetAttributeValues("memberOf"), nil
+}
+
 func (l ldapService) SyncUsersCronjob() {
 	c := cron.New()
-	_, err := c.AddFunc(global.Config.Ldap.Cronjob, func() {
+	entryId, err := c.AddFunc(global.GetConfig().Ldap.Cronjob, func() {
 		l.SyncUserToW8t()
 	})
 	if err != nil {
 		logc.Errorf(ctx.Ctx, err.Error())
 		return
 	}
+
+	// 同步周期变更后重新调度
+	if global.ConfigWatcher != nil {
+		global.ConfigWatcher.Subscribe(func(old, new config.App) {
+			if old.Ldap.Cronjob == new.Ldap.Cronjob {
+				return
+			}
+			id, err := c.AddFunc(new.Ldap.Cronjob, func() {
+				l.SyncUserToW8t()
+			})
+			if err != nil {
+				logc.Errorf(ctx.Ctx, fmt.Sprintf("LDAP 同步周期更新失败, cronjob: %s, err: %s", new.Ldap.Cronjob, err.Error()))
+				return
+			}
+			c.Remove(entryId)
+			entryId = id
+		})
+	}
 	c.Start()
 	defer c.Stop()
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"time"
+	"watchAlert/alert/process"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/templates"
 	"watchAlert/pkg/tools"
 )
 
@@ -23,6 +25,7 @@ type InterNoticeService interface {
 	Search(req interface{}) (interface{}, interface{})
 	ListRecord(req interface{}) (interface{}, interface{})
 	GetRecordMetric(req interface{}) (interface{}, interface{})
+	Test(req interface{}) (interface{}, interface{})
 }
 
 func newInterAlertNoticeService(ctx *ctx.Context) InterNoticeService {
@@ -42,6 +45,10 @@ func (n noticeService) List(req interface{}) (interface{}, interface{}) {
 
 func (n noticeService) Create(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertNotice)
+	if err := templates.ValidateWebHookTemplate(r.WebHook.BodyTemplate); err != nil {
+		return nil, fmt.Errorf("请求体模版解析失败, err: %s", err.Error())
+	}
+
 	ok := n.ctx.DB.Notice().GetQuota(r.TenantId)
 	if !ok {
 		return models.AlertNotice{}, fmt.Errorf("创建失败, 配额不足")
@@ -58,6 +65,10 @@ func (n noticeService) Create(req interface{}) (interface{}, interface{}) {
 
 func (n noticeService) Update(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertNotice)
+	if err := templates.ValidateWebHookTemplate(r.WebHook.BodyTemplate); err != nil {
+		return nil, fmt.Errorf("请求体模版解析失败, err: %s", err.Error())
+	}
+
 	err := n.ctx.DB.Notice().Update(*r)
 	if err != nil {
 		return nil, err
@@ -169,3 +180,30 @@ func (n noticeService) GetRecordMetric(req interface{}) (interface{}, interface{
 		},
 	}, nil
 }
+
+// Test 发送测试通知, 返回渲染后的通知内容, 发送失败时返回下游的响应错误
+func (n noticeService) Test(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RequestNoticeTest)
+	notice := r.AlertNotice
+	if notice.NoticeType == "" {
+		if notice.Uuid == "" {
+			return nil, fmt.Errorf("通知类型为空")
+		}
+		data, err := n.ctx.DB.Notice().Get(models.NoticeQuery{TenantId: r.TenantId, Uuid: r.Uuid})
+		if err != nil {
+			return nil, err
+		}
+		notice = data
+	}
+
+	if err := templates.ValidateWebHookTemplate(notice.WebHook.BodyTemplate); err != nil {
+		return nil, fmt.Errorf("请求体模版解析失败, err: %s", err.Error())
+	}
+
+	content, err := process.SendTestNotice(n.ctx, notice, r.Severity, r.IsRecovered)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"content": content}, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"golang.org/x/oauth2"
+	"sync"
+	"time"
+	"watchAlert/config"
+	"watchAlert/internal/global"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/client"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	oidcService struct {
+		ctx *ctx.Context
+	}
+
+	InterOidcService interface {
+		Authorize() (string, error)
+		Login(req interface{}) (interface{}, interface{})
+	}
+)
+
+func newInterOidcService(ctx *ctx.Context) InterOidcService {
+	return &oidcService{
+		ctx: ctx,
+	}
+}
+
+// oidcStateTTL 授权请求的有效期, 超时后需重新发起登陆
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState 授权请求的 nonce 及 PKCE verifier, 以 state 为 key 存储在 Redis 中
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func getOidcStateKey(state string) string {
+	return "oidc-state-" + state
+}
+
+var (
+	oidcClientMux sync.Mutex
+	oidcClient    *client.OIDCClient
+	oidcClientCfg client.OIDCConfig
+)
+
+// getOidcClient 获取 OIDC 客户端, 配置变更后重新获取身份提供方的配置
+func getOidcClient(c context.Context, cfg config.Oidc) (*client.OIDCClient, error) {
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("未开启 OIDC 登陆")
+	}
+
+	clientCfg := client.OIDCConfig{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientId,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.GetScopes(),
+	}
+
+	oidcClientMux.Lock()
+	defer oidcClientMux.Unlock()
+	if oidcClient != nil && tools.JsonMarshal(oidcClientCfg) == tools.JsonMarshal(clientCfg) {
+		return oidcClient, nil
+	}
+
+	cli, err := client.NewOIDCClient(c, clientCfg)
+	if err != nil {
+		return nil, err
+	}
+	oidcClient, oidcClientCfg = cli, clientCfg
+	return cli, nil
+}
+
+// Authorize 生成身份提供方的授权地址
+func (o oidcService) Authorize() (string, error) {
+	cli, err := getOidcClient(o.ctx.Ctx, global.GetConfig().Oidc)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := tools.RandToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := tools.RandToken()
+	if err != nil {
+		return "", err
+	}
+	s := oidcState{Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	if err := o.ctx.Redis.Redis().Set(getOidcStateKey(state), tools.JsonMarshal(s), oidcStateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return cli.AuthCodeURL(state, s.Nonce, s.Verifier), nil
+}
+
+// Login 使用授权码完成登陆, 签发与账号密码登陆相同的 Token
+func (o oidcService) Login(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.OidcLoginReq)
+	cfg := global.GetConfig().Oidc
+	cli, err := getOidcClient(o.ctx.Ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// state 仅可使用一次
+	key := getOidcStateKey(r.State)
+	result, err := o.ctx.Redis.Redis().Get(key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("登陆请求无效或已过期, 请重新登陆")
+	}
+	o.ctx.Redis.Redis().Del(key)
+
+	var s oidcState
+	if err := json.Unmarshal([]byte(result), &s); err != nil {
+		return nil, fmt.Errorf("登陆请求无效或已过期, 请重新登陆")
+	}
+
+	claims, err := cli.Exchange(o.ctx.Ctx, r.Code, s.Nonce, s.Verifier)
+	if err != nil {
+		logc.Error(o.ctx.Ctx, fmt.Sprintf("OIDC 登陆失败, err: %s", err.Error()))
+		return nil, fmt.Errorf("OIDC 登陆失败, err: %s", err.Error())
+	}
+
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+	if issuer == "" || subject == "" {
+		return nil, fmt.Errorf("ID Token 中不包含 iss 或 sub")
+	}
+	email, _ := claims["email"].(string)
+	username, _ := claims[cfg.GetUsernameClaim()].(string)
+	if username == "" {
+		username = email
+	}
+
+	user, ok, err := o.getOidcUser(issuer, subject, email, isEmailVerified(claims))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if !cfg.AutoCreateUser {
+			return nil, fmt.Errorf("OIDC 用户 %s 未关联账号, 请联系管理员创建", username)
+		}
+		if username == "" {
+			return nil, fmt.Errorf("ID Token 中不包含用户名 (%s) 及邮箱", cfg.GetUsernameClaim())
+		}
+		// 避免身份提供方中的同名用户登陆本地、LDAP 或其他 OIDC 用户的账号
+		if _, exist, _ := o.ctx.DB.User().Get(models.MemberQuery{UserName: username}); exist {
+			return nil, fmt.Errorf("用户 %s 已存在且未关联当前 OIDC 用户", username)
+		}
+
+		user = models.Member{
+			UserId:      tools.RandUid(),
+			UserName:    username,
+			Email:       email,
+			CreateBy:    "OIDC",
+			CreateAt:    time.Now().Unix(),
+			OidcIssuer:  issuer,
+			OidcSubject: subject,
+		}
+		if err := o.ctx.DB.User().Create(user); err != nil {
+			return nil, err
+		}
+		logc.Info(o.ctx.Ctx, fmt.Sprintf("OIDC 用户 %s 首次登陆, 已自动创建", username))
+	}
+
+	// 首次登陆或配置了组映射时同步租户及角色
+	if !ok || len(cfg.GroupRoleMap) > 0 {
+		syncUserTenants(o.ctx, user, cfg.GetUserTenantRoles(getClaimStrings(claims, cfg.GetGroupsClaim())))
+		if latest, exist, err := o.ctx.DB.User().Get(models.MemberQuery{UserId: user.UserId}); err == nil && exist {
+			user = latest
+		}
+	}
+
+	return userService{ctx: o.ctx}.issueToken(user)
+}
+
+// getOidcUser 按 iss + sub 获取关联的账号; 未关联时仅在邮箱已验证的情况下按邮箱关联此前创建的 OIDC 账号, 邮箱未验证时可能被冒用
+func (o oidcService) getOidcUser(issuer, subject, email string, emailVerified bool) (models.Member, bool, error) {
+	user, ok, err := o.ctx.DB.User().GetByOidcSubject(issuer, subject)
+	if err != nil || ok {
+		return user, ok, err
+	}
+	if email == "" || !emailVerified {
+		return user, false, nil
+	}
+
+	user, ok, err = o.ctx.DB.User().GetUnboundOidcUserByEmail(email)
+	if err != nil || !ok {
+		return user, false, err
+	}
+	if err := o.ctx.DB.User().Update(models.Member{UserId: user.UserId, OidcIssuer: issuer, OidcSubject: subject}); err != nil {
+		return user, false, err
+	}
+	user.OidcIssuer, user.OidcSubject = issuer, subject
+	logc.Info(o.ctx.Ctx, fmt.Sprintf("OIDC 用户 %s 已按邮箱关联账号 %s", subject, user.UserName))
+
+	return user, true, nil
+}
+
+// isEmailVerified 部分身份提供方 (如 AWS Cognito) 以字符串返回 email_verified
+func isEmailVerified(claims map[string]interface{}) bool {
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// getClaimStrings 获取字符串或字符串数组类型的声明
+func getClaimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
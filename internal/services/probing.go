@@ -52,7 +52,7 @@ func (m probingService) Create(req interface{}) (interface{}, interface{}) {
 
 func (m probingService) Update(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.ProbingRule)
-	_, err := m.ctx.DB.Probing().Search(models.ProbingRuleQuery{RuleId: r.RuleId})
+	_, err := m.ctx.DB.Probing().Search(models.ProbingRuleQuery{TenantId: r.TenantId, RuleId: r.RuleId})
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +74,7 @@ func (m probingService) Update(req interface{}) (interface{}, interface{}) {
 
 func (m probingService) Delete(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.ProbingRuleQuery)
-	res, err := m.ctx.DB.Probing().Search(models.ProbingRuleQuery{RuleId: r.RuleId})
+	res, err := m.ctx.DB.Probing().Search(models.ProbingRuleQuery{TenantId: r.TenantId, RuleId: r.RuleId})
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +86,11 @@ func (m probingService) Delete(req interface{}) (interface{}, interface{}) {
 
 	m.ProductTask.Stop(r.RuleId)
 	m.ConsumerTask.Stop(r.RuleId)
-	err = m.ctx.Redis.Redis().Del(string(models.BuildProbingEventCacheKey(res.TenantId, res.RuleId)), string(models.BuildProbingValueCacheKey(res.TenantId, res.RuleId))).Err()
-	if err != nil {
-		return nil, err
+	// 逐个删除, 集群模式下多个 key 可能不在同一个 slot
+	for _, key := range []string{string(models.BuildProbingEventCacheKey(res.TenantId, res.RuleId)), string(models.BuildProbingValueCacheKey(res.TenantId, res.RuleId))} {
+		if err := m.ctx.Redis.Redis().Del(key).Err(); err != nil {
+			return nil, err
+		}
 	}
 
 	return nil, nil
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/prometheus/common/model"
+	"github.com/zeromicro/go-zero/core/logc"
+	"watchAlert/alert/process"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/provider"
+)
+
+// receiverTokenPrefix 外部告警接入 Token 的前缀, 便于识别
+const receiverTokenPrefix = "w8tr_"
+
+type (
+	receiverService struct {
+		ctx *ctx.Context
+	}
+
+	InterReceiverService interface {
+		Alertmanager(req interface{}) (data interface{}, err interface{})
+	}
+)
+
+func newInterReceiverService(ctx *ctx.Context) InterReceiverService {
+	return &receiverService{
+		ctx: ctx,
+	}
+}
+
+// Alertmanager 接收 Alertmanager webhook 推送的告警, 转换为告警事件后进入故障中心, 与规则产生的事件一样进行聚合、静默及通知
+func (r receiverService) Alertmanager(req interface{}) (data interface{}, err interface{}) {
+	rq := req.(*models.AlertmanagerReceiveReq)
+	if rq.Token == "" {
+		return nil, fmt.Errorf("缺少接入 Token")
+	}
+
+	fc, e := r.ctx.DB.FaultCenter().Get(models.FaultCenterQuery{ReceiverTokenHash: hashReceiverToken(rq.Token)})
+	if e != nil {
+		return nil, fmt.Errorf("接入 Token 无效")
+	}
+
+	var result models.AlertmanagerReceiveResult
+	for _, alert := range rq.Webhook.Alerts {
+		event := buildAlertmanagerEvent(fc, alert)
+		if alert.Status == models.AlertmanagerStatusResolved {
+			r.resolve(event)
+			result.Resolved++
+			continue
+		}
+
+		process.PushEventToFaultCenter(r.ctx, &event)
+		result.Firing++
+	}
+
+	return result, nil
+}
+
+// resolve 恢复告警, Alertmanager 已按 resolve_timeout 判定恢复, 不再等待故障中心的恢复等待时间
+func (r receiverService) resolve(event models.AlertCurEvent) {
+	cache := r.ctx.Redis.Alert()
+	cached, err := cache.GetEventFromCache(event.TenantId, event.FaultCenterId, event.Fingerprint)
+	if err != nil {
+		// 未接收过的告警或已归档
+		return
+	}
+
+	from := cached.Status
+	switch cached.Status {
+	case models.StatePreAlert, models.StateSilenced:
+		// 未发送过告警通知, 无需恢复通知
+		cache.RemoveAlertEvent(event.TenantId, event.FaultCenterId, event.Fingerprint)
+		return
+	case models.StateAlerting:
+		cached.TransitionStatus(models.StatePendingRecovery)
+	case models.StatePendingRecovery:
+	default:
+		return
+	}
+
+	// 以最新推送的注解及标签发送恢复通知
+	cached.Annotations = event.Annotations
+	cached.Metric = event.Metric
+	if err := cached.TransitionStatus(models.StateRecovered); err != nil {
+		logc.Error(r.ctx.Ctx, fmt.Sprintf("Alertmanager 告警恢复失败, fingerprint: %s, err: %s", event.Fingerprint, err.Error()))
+		return
+	}
+
+	process.RecordTransition(r.ctx, cached, string(from), string(cached.Status), models.TransitionActorSystem)
+	cache.PushAlertEvent(&cached)
+}
+
+// buildAlertmanagerEvent 将 Alertmanager 告警转换为告警事件, 同一 alertname 的告警归为同一规则, 指纹沿用 Alertmanager 的指纹
+func buildAlertmanagerEvent(fc models.FaultCenter, alert models.AlertmanagerAlert) models.AlertCurEvent {
+	alertName := alert.Labels[model.AlertNameLabel]
+	if alertName == "" {
+		alertName = models.AlertmanagerDatasourceType
+	}
+
+	fingerprint := alert.Fingerprint
+	if fingerprint == "" {
+		labels := make(model.LabelSet, len(alert.Labels))
+		for k, v := range alert.Labels {
+			labels[model.LabelName(k)] = model.LabelValue(v)
+		}
+		fingerprint = labels.Fingerprint().String()
+	}
+
+	severity, _ := provider.MapPromSeverity(alert.Labels["severity"])
+
+	metric := make(map[string]interface{}, len(alert.Labels)+3)
+	for k, v := range alert.Labels {
+		metric[k] = v
+	}
+	metric["severity"] = severity
+	metric["fingerprint"] = fingerprint
+	metric["rule_name"] = alertName
+
+	return models.AlertCurEvent{
+		TenantId:       fc.TenantId,
+		DatasourceType: models.AlertmanagerDatasourceType,
+		RuleId:         "am-" + alertName,
+		RuleName:       alertName,
+		Fingerprint:    fingerprint,
+		Severity:       severity,
+		Metric:         metric,
+		Annotations:    provider.JoinPromAnnotations(alert.Annotations),
+		SearchQL:       alert.GeneratorURL,
+		FaultCenterId:  fc.ID,
+	}
+}
+
+func hashReceiverToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,9 +3,16 @@ package services
 import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
+	"slices"
+	"time"
 	"watchAlert/alert"
+	"watchAlert/alert/eval"
+	"watchAlert/alert/process"
 	models "watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/provider"
+	"watchAlert/pkg/templates"
+	"watchAlert/pkg/tools"
 )
 
 type ruleService struct {
@@ -18,6 +25,10 @@ type InterRuleService interface {
 	Delete(req interface{}) (interface{}, interface{})
 	List(req interface{}) (interface{}, interface{})
 	Search(req interface{}) (interface{}, interface{})
+	Preview(req interface{}) (interface{}, interface{})
+	Export(req interface{}) (interface{}, interface{})
+	Import(req interface{}) (interface{}, interface{})
+	PromImport(req interface{}) (interface{}, interface{})
 }
 
 func newInterRuleService(ctx *ctx.Context) InterRuleService {
@@ -28,23 +39,65 @@ func newInterRuleService(ctx *ctx.Context) InterRuleService {
 
 func (rs ruleService) Create(req interface{}) (interface{}, interface{}) {
 	rule := req.(*models.AlertRule)
+	if err := rs.validate(*rule); err != nil {
+		return nil, err
+	}
+
+	// 已存在相同 UID 的规则时原地更新, 重复提交不会创建重复的规则
+	if rule.Uid != "" {
+		old, exist, err := rs.ctx.DB.Rule().GetByUid(rule.TenantId, rule.Uid)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			rule.RuleId = old.RuleId
+			rule.Enabled = rule.GetEnabled()
+			rs.reload(rule)
+			if err := rs.ctx.DB.Rule().Replace(*rule); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
+	}
+
 	ok := rs.ctx.DB.Rule().GetQuota(rule.TenantId)
 	if !ok {
 		return nil, fmt.Errorf("创建失败, 配额不足")
 	}
 
-	alert.AlertRule.Submit(*rule)
-
+	// 并发提交相同 UID 时由唯一索引保证只创建一条, 写入成功后再启动评估
 	err := rs.ctx.DB.Rule().Create(*rule)
 	if err != nil {
 		return nil, err
 	}
 
+	alert.AlertRule.Submit(*rule)
+
 	return nil, nil
 }
 
 func (rs ruleService) Update(req interface{}) (interface{}, interface{}) {
 	rule := req.(*models.AlertRule)
+	if err := rs.validate(*rule); err != nil {
+		return nil, err
+	}
+	if err := rs.checkUidOwner(*rule); err != nil {
+		return nil, err
+	}
+
+	rs.reload(rule)
+
+	// 更新数据
+	err := rs.ctx.DB.Rule().Update(*rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// reload 按规则的新旧状态重启或停止 Worker 进程, 并清理迁移故障中心或禁用后的告警缓存
+func (rs ruleService) reload(rule *models.AlertRule) {
 	oldRule := models.AlertRule{}
 	rs.ctx.DB.DB().Model(&models.AlertRule{}).
 		Where("tenant_id = ? AND rule_id = ?", rule.TenantId, rule.RuleId).
@@ -79,14 +132,75 @@ func (rs ruleService) Update(req interface{}) (interface{}, interface{}) {
 			rs.ctx.Redis.Alert().RemoveAlertEvent(rule.TenantId, rule.FaultCenterId, fingerprint)
 		}
 	}
+}
 
-	// 更新数据
-	err := rs.ctx.DB.Rule().Update(*rule)
+// validate 校验规则的消息模版、升级策略、标签重写规则、备用数据源及异常检测配置
+func (rs ruleService) validate(rule models.AlertRule) error {
+	if err := templates.ValidateTemplate(rule.MessageTemplate); err != nil {
+		return fmt.Errorf("消息模版解析失败, err: %s", err.Error())
+	}
+	if err := rule.ValidateEscalationPolicy(); err != nil {
+		return err
+	}
+	if err := rule.ValidateRelabelConfigs(); err != nil {
+		return err
+	}
+	if err := rs.validateFailoverDatasources(rule); err != nil {
+		return err
+	}
+	if len(rule.Uid) > models.MaxRuleUidLength {
+		return fmt.Errorf("规则 UID 长度不能超过 %d", models.MaxRuleUidLength)
+	}
+	if rule.LogSampleSize < 0 || rule.LogSampleSize > models.MaxLogSampleSize {
+		return fmt.Errorf("日志样本条数需在 0 ~ %d 之间", models.MaxLogSampleSize)
+	}
+	if rule.PrometheusConfig.IsAnomalyMode() {
+		if err := rule.PrometheusConfig.Anomaly.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUidOwner 校验规则 UID 未被租户内的其他规则使用
+func (rs ruleService) checkUidOwner(rule models.AlertRule) error {
+	if rule.Uid == "" {
+		return nil
+	}
+
+	owner, exist, err := rs.ctx.DB.Rule().GetByUid(rule.TenantId, rule.Uid)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if exist && owner.RuleId != rule.RuleId {
+		return fmt.Errorf("%w, UID %s 已被规则 %s (%s) 使用", models.ErrRuleUidConflict, rule.Uid, owner.RuleName, owner.RuleId)
+	}
+	return nil
+}
+
+// validateFailoverDatasources 校验备用数据源, 主数据源须属于规则, 备用数据源须存在且与规则数据源类型一致
+func (rs ruleService) validateFailoverDatasources(rule models.AlertRule) error {
+	for primaryId, secondaryId := range rule.FailoverDatasources {
+		if secondaryId == "" {
+			continue
+		}
+		if !slices.Contains(rule.DatasourceIdList, primaryId) {
+			return fmt.Errorf("备用数据源配置错误, 主数据源 %s 不属于当前规则", primaryId)
+		}
+		if secondaryId == primaryId {
+			return fmt.Errorf("备用数据源配置错误, 备用数据源不能与主数据源 %s 相同", primaryId)
+		}
+
+		instance, err := rs.ctx.DB.Datasource().GetInstance(secondaryId)
+		if err != nil {
+			return fmt.Errorf("备用数据源 %s 不存在, err: %s", secondaryId, err.Error())
+		}
+		if instance.Type != rule.DatasourceType {
+			return fmt.Errorf("备用数据源 %s 的类型 %s 与规则数据源类型 %s 不一致", secondaryId, instance.Type, rule.DatasourceType)
+		}
 	}
 
-	return nil, nil
+	return nil
 }
 
 func (rs ruleService) Delete(req interface{}) (interface{}, interface{}) {
@@ -136,3 +250,95 @@ func (rs ruleService) Search(req interface{}) (interface{}, interface{}) {
 
 	return data, nil
 }
+
+// Preview 规则预览, 与告警评估使用相同的查询参数及 Provider 查询, 不产生告警事件
+func (rs ruleService) Preview(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RulePreviewReq)
+	rule := r.Rule
+
+	// 仅允许预览当前租户的数据源
+	datasource, err := rs.ctx.DB.Datasource().Get(models.DatasourceQuery{TenantId: rule.TenantId, Id: r.DatasourceId})
+	if err != nil {
+		return nil, fmt.Errorf("数据源不存在, datasourceId: %s", r.DatasourceId)
+	}
+	if datasource.Type != rule.DatasourceType {
+		return nil, fmt.Errorf("数据源类型 %s 与规则的数据源类型 %s 不一致", datasource.Type, rule.DatasourceType)
+	}
+
+	cli, err := rs.ctx.Redis.ProviderPools().GetClient(datasource.Id)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据源客户端失败, 请确认数据源已启用, err: %s", err.Error())
+	}
+
+	logsCli, ok := cli.(provider.LogsFactoryProvider)
+	if !ok {
+		return nil, fmt.Errorf("数据源类型 %s 暂不支持预览", rule.DatasourceType)
+	}
+
+	// 与告警评估相同, 经并发额度、重试、熔断及查询缓存执行查询, 拨测每次实际发起请求
+	var (
+		queryRes []provider.Logs
+		count    int
+	)
+	queryOptions := eval.BuildLogQueryOptions(rule.DatasourceType, rule, provider.AlignQueryTime(time.Now()))
+	if rule.DatasourceType == provider.HTTPProbeDsProviderName {
+		queryRes, count, err = logsCli.Query(queryOptions)
+	} else {
+		err = provider.RetryQuery(rs.ctx.Ctx, datasource.Id, func() error {
+			var err error
+			queryRes, count, err = provider.QueryLogsWithCache(datasource.Id, queryOptions, logsCli.Query)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := models.RulePreviewResponse{
+		Total: count,
+		Logs:  queryRes,
+	}
+	if count <= 0 {
+		return res, nil
+	}
+
+	// 多指标聚合按各指标的评估条件组合判断, 任一分桶满足条件即视为触发
+	if rule.DatasourceType == provider.ElasticSearchDsProviderName && rule.ElasticSearchConfig.IsMultiMetricAggregation() {
+		agg := rule.ElasticSearchConfig.Aggregation
+		for _, v := range queryRes {
+			matched, err := process.EvalMetricsCondition(agg.Metrics, agg.MetricsCondition, v.GetAggregationMetricValue)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				res.Matched = true
+				break
+			}
+		}
+		return res, nil
+	}
+
+	if rule.LogEvalCondition == "" {
+		return res, nil
+	}
+
+	operator, value, err := tools.ProcessRuleExpr(rule.LogEvalCondition)
+	if err != nil {
+		return nil, err
+	}
+
+	// 聚合查询按每个分桶的聚合值进行评估, 任一分桶满足条件即视为触发
+	isAggregation := rule.DatasourceType == provider.ElasticSearchDsProviderName && rule.ElasticSearchConfig.EsQueryType == models.EsQueryTypeAggregation
+	for _, v := range queryRes {
+		queryValue := float64(count)
+		if isAggregation {
+			queryValue = v.GetAggregationValue()
+		}
+		if process.EvalCondition(models.EvalCondition{Operator: operator, QueryValue: queryValue, ExpectedValue: value}) {
+			res.Matched = true
+			break
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"sort"
+	models "watchAlert/internal/models"
+	"watchAlert/pkg/provider"
+	"watchAlert/pkg/tools"
+)
+
+// Export 导出规则为 YAML 文档, 不包含租户 ID, 可直接用于导入
+func (rs ruleService) Export(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RuleExportQuery)
+	rules, err := rs.ctx.DB.Rule().ListAll(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := models.RuleBundle{Kind: models.RuleBundleKind, Rules: make([]models.AlertRule, 0, len(rules))}
+	for _, rule := range rules {
+		rule.TenantId = ""
+		bundle.Rules = append(bundle.Rules, rule)
+	}
+
+	content, err := yaml.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("规则导出失败, err: %s", err.Error())
+	}
+
+	return content, nil
+}
+
+// ruleImportPlan 单条规则的导入计划
+type ruleImportPlan struct {
+	diff *models.RuleDiff
+	rule models.AlertRule
+	old  *models.AlertRule
+}
+
+// Import 按 YAML 文档导入规则, 以规则 ID (未指定时为规则组内的规则名称) 匹配已有规则, 新增、更新或删除规则使之与文档一致;
+// 全部规则校验通过后才写入, 重复导入同一文档不产生变更
+func (rs ruleService) Import(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RuleImportReq)
+
+	var bundle models.RuleBundle
+	if err := yaml.UnmarshalStrict(r.Content, &bundle); err != nil {
+		return nil, fmt.Errorf("规则文档解析失败, err: %s", err.Error())
+	}
+	if bundle.Kind != "" && bundle.Kind != models.RuleBundleKind {
+		return nil, fmt.Errorf("规则文档类型错误, 期望 %s, 实际 %s", models.RuleBundleKind, bundle.Kind)
+	}
+
+	result, err := rs.importRules(r, bundle.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importRules 校验规则并与已有规则比较差异, 非 DryRun 且全部校验通过时写入
+func (rs ruleService) importRules(r *models.RuleImportReq, rules []models.AlertRule) (models.RuleImportResult, error) {
+	existing, err := rs.ctx.DB.Rule().ListAll(models.RuleExportQuery{TenantId: r.TenantId})
+	if err != nil {
+		return models.RuleImportResult{}, err
+	}
+	existingById := make(map[string]*models.AlertRule, len(existing))
+	for i := range existing {
+		existingById[existing[i].RuleId] = &existing[i]
+	}
+
+	var (
+		result      = models.RuleImportResult{DryRun: r.DryRun}
+		plans       []ruleImportPlan
+		matched     = make(map[string]bool)
+		matchedUids = make(map[string]bool)
+		invalid     bool
+	)
+	for _, rule := range rules {
+		rule.TenantId = r.TenantId
+		if rule.RuleGroupId == "" {
+			rule.RuleGroupId = r.RuleGroupId
+		}
+		rule.Enabled = rule.GetEnabled()
+
+		if rule.RuleId == "" && rule.Uid != "" {
+			for i := range existing {
+				if existing[i].Uid == rule.Uid {
+					rule.RuleId = existing[i].RuleId
+					break
+				}
+			}
+		}
+		if rule.RuleId == "" {
+			for i := range existing {
+				if existing[i].RuleGroupId == rule.RuleGroupId && existing[i].RuleName == rule.RuleName {
+					rule.RuleId = existing[i].RuleId
+					break
+				}
+			}
+		}
+
+		diff := models.RuleDiff{RuleId: rule.RuleId, RuleName: rule.RuleName}
+		old := existingById[rule.RuleId]
+		err := rs.validateImportRule(r, rule, old, matched, matchedUids)
+		if rule.RuleId != "" {
+			matched[rule.RuleId] = true
+		}
+		if rule.Uid != "" {
+			matchedUids[rule.Uid] = true
+		}
+		switch {
+		case err != nil:
+			invalid = true
+			diff.Action = models.RuleDiffInvalid
+			diff.Error = err.Error()
+		case old == nil:
+			diff.Action = models.RuleDiffCreate
+		default:
+			diff.Changes = diffRule(*old, rule)
+			diff.Action = models.RuleDiffUpdate
+			if len(diff.Changes) == 0 {
+				diff.Action = models.RuleDiffUnchanged
+			}
+		}
+
+		result.Items = append(result.Items, diff)
+		plans = append(plans, ruleImportPlan{rule: rule, old: old})
+	}
+
+	if r.Prune {
+		for i := range existing {
+			old := existing[i]
+			if matched[old.RuleId] || (r.RuleGroupId != "" && old.RuleGroupId != r.RuleGroupId) {
+				continue
+			}
+			result.Items = append(result.Items, models.RuleDiff{RuleId: old.RuleId, RuleName: old.RuleName, Action: models.RuleDiffDelete})
+			plans = append(plans, ruleImportPlan{old: &existing[i]})
+		}
+	}
+
+	// 计划与差异一一对应
+	for i := range plans {
+		plans[i].diff = &result.Items[i]
+	}
+
+	if invalid || r.DryRun {
+		return result, nil
+	}
+
+	rs.applyImport(plans)
+	result.Applied = true
+
+	return result, nil
+}
+
+// PromImport 导入 Prometheus 告警规则文件, 转换为指标规则后按规则名称与规则组内的已有规则匹配
+func (rs ruleService) PromImport(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.PromRuleImportReq)
+	if r.RuleGroupId == "" || r.FaultCenterId == "" {
+		return nil, fmt.Errorf("规则组及故障中心不能为空")
+	}
+
+	instance, err := rs.ctx.DB.Datasource().GetInstance(r.DatasourceId)
+	if err != nil || instance.TenantId != r.TenantId {
+		return nil, fmt.Errorf("数据源 %s 不存在", r.DatasourceId)
+	}
+	if instance.Type != provider.PrometheusDsProvider && instance.Type != provider.VictoriaMetricsDsProvider {
+		return nil, fmt.Errorf("数据源 %s 的类型为 %s, 仅支持 Prometheus 及 VictoriaMetrics 数据源", r.DatasourceId, instance.Type)
+	}
+
+	file, err := provider.ParsePromRuleFile(r.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	evalInterval := r.EvalInterval
+	if evalInterval <= 0 {
+		evalInterval = 60
+	}
+	rules, issues := provider.ConvertPromRules(file, evalInterval)
+	for i := range rules {
+		rules[i].RuleGroupId = r.RuleGroupId
+		rules[i].DatasourceType = instance.Type
+		rules[i].DatasourceIdList = []string{instance.Id}
+		rules[i].FaultCenterId = r.FaultCenterId
+		rules[i].Enabled = &r.Enabled
+	}
+
+	result, err := rs.importRules(&models.RuleImportReq{
+		TenantId:    r.TenantId,
+		RuleGroupId: r.RuleGroupId,
+		DryRun:      r.DryRun,
+		Prune:       r.Prune,
+	}, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.PromRuleImportResult{RuleImportResult: result, Issues: issues}, nil
+}
+
+// validateImportRule 校验导入的规则, 在常规校验的基础上检查数据源及查询语句
+func (rs ruleService) validateImportRule(r *models.RuleImportReq, rule models.AlertRule, old *models.AlertRule, matched, matchedUids map[string]bool) error {
+	if rule.RuleName == "" {
+		return fmt.Errorf("规则名称不能为空")
+	}
+	if rule.RuleGroupId == "" {
+		return fmt.Errorf("规则组不能为空")
+	}
+	if r.RuleGroupId != "" && rule.RuleGroupId != r.RuleGroupId {
+		return fmt.Errorf("规则组 %s 与导入的规则组 %s 不一致", rule.RuleGroupId, r.RuleGroupId)
+	}
+	if rule.RuleId != "" {
+		if matched[rule.RuleId] {
+			return fmt.Errorf("规则 ID %s 重复", rule.RuleId)
+		}
+		if old == nil && rs.ctx.DB.Rule().GetRuleObject(rule.RuleId).TenantId != "" {
+			return fmt.Errorf("规则 ID %s 已被其他租户使用", rule.RuleId)
+		}
+	}
+	if rule.Uid != "" {
+		if matchedUids[rule.Uid] {
+			return fmt.Errorf("规则 UID %s 重复", rule.Uid)
+		}
+		if err := rs.checkUidOwner(rule); err != nil {
+			return err
+		}
+	}
+	if len(rule.DatasourceIdList) == 0 {
+		return fmt.Errorf("数据源不能为空")
+	}
+
+	var sqlDriver string
+	for _, dsId := range rule.DatasourceIdList {
+		instance, err := rs.ctx.DB.Datasource().GetInstance(dsId)
+		if err != nil || instance.TenantId != rule.TenantId {
+			return fmt.Errorf("数据源 %s 不存在", dsId)
+		}
+		if instance.Type != rule.DatasourceType {
+			return fmt.Errorf("数据源 %s 的类型 %s 与规则数据源类型 %s 不一致", dsId, instance.Type, rule.DatasourceType)
+		}
+		sqlDriver = instance.SQLConfig.Driver
+	}
+
+	if err := provider.ValidateRuleQuery(rule, sqlDriver); err != nil {
+		return err
+	}
+
+	return rs.validate(rule)
+}
+
+// applyImport 依次执行删除、更新及新增, 先删除以释放规则配额; 单条规则写入失败时记录原因并继续
+func (rs ruleService) applyImport(plans []ruleImportPlan) {
+	for _, plan := range plans {
+		if plan.diff.Action != models.RuleDiffDelete {
+			continue
+		}
+		_, err := rs.Delete(&models.AlertRuleQuery{TenantId: plan.old.TenantId, RuleGroupId: plan.old.RuleGroupId, RuleId: plan.old.RuleId})
+		setImportError(plan.diff, err)
+	}
+
+	for _, plan := range plans {
+		if plan.diff.Action != models.RuleDiffUpdate {
+			continue
+		}
+		rule := plan.rule
+		rs.reload(&rule)
+		setImportError(plan.diff, rs.ctx.DB.Rule().Replace(rule))
+	}
+
+	for _, plan := range plans {
+		if plan.diff.Action != models.RuleDiffCreate {
+			continue
+		}
+		rule := plan.rule
+		if rule.RuleId == "" {
+			rule.RuleId = "a-" + tools.RandId()
+			plan.diff.RuleId = rule.RuleId
+		}
+		_, err := rs.Create(&rule)
+		setImportError(plan.diff, err)
+	}
+}
+
+func setImportError(diff *models.RuleDiff, err interface{}) {
+	if err != nil {
+		diff.Error = err.(error).Error()
+	}
+}
+
+// diffRule 按 JSON 字段比较规则, 忽略租户 ID, 空值 (null、空列表及空对象) 视为相同
+func diffRule(old, new models.AlertRule) []models.RuleFieldChange {
+	oldFields, newFields := ruleFields(old), ruleFields(new)
+
+	keys := make([]string, 0, len(newFields))
+	for k := range newFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var changes []models.RuleFieldChange
+	for _, k := range keys {
+		if k == "tenantId" || jsonValueEqual(oldFields[k], newFields[k]) {
+			continue
+		}
+		changes = append(changes, models.RuleFieldChange{
+			Field: k,
+			Old:   tools.JsonMarshal(oldFields[k]),
+			New:   tools.JsonMarshal(newFields[k]),
+		})
+	}
+
+	return changes
+}
+
+func ruleFields(rule models.AlertRule) map[string]interface{} {
+	var fields map[string]interface{}
+	_ = json.Unmarshal([]byte(tools.JsonMarshal(rule)), &fields)
+	return fields
+}
+
+func jsonValueEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeJSONValue(a), normalizeJSONValue(b))
+}
+
+func normalizeJSONValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+		for i := range val {
+			val[i] = normalizeJSONValue(val[i])
+		}
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+		for k := range val {
+			val[k] = normalizeJSONValue(val[k])
+		}
+	}
+	return v
+}
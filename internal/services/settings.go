@@ -1,10 +1,15 @@
 package services
 
 import (
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"strings"
+	"time"
 	"watchAlert/internal/global"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ai"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/sender"
 )
 
 type (
@@ -15,6 +20,7 @@ type (
 	InterSettingService interface {
 		Save(req interface{}) (interface{}, interface{})
 		Get() (interface{}, interface{})
+		SetMaintenance(req interface{}) (interface{}, interface{})
 	}
 )
 
@@ -26,6 +32,12 @@ func newInterSettingService(ctx *ctx.Context) InterSettingService {
 
 func (a settingService) Save(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.Settings)
+	if err := validateEnrichConfig(r.EnrichConfig); err != nil {
+		return nil, err
+	}
+
+	// 维护模式仅通过维护模式接口切换, 避免使用过期的配置覆盖
+	r.MaintenanceConfig = models.MaintenanceConfig{}
 	if a.ctx.DB.Setting().Check() {
 		err := a.ctx.DB.Setting().Update(*r)
 		if err != nil {
@@ -58,3 +70,52 @@ func (a settingService) Get() (interface{}, interface{}) {
 
 	return get, nil
 }
+
+// SetMaintenance 开启或关闭维护模式, 记录操作人、时间及原因, 关闭时发送维护期间的通知汇总
+func (a settingService) SetMaintenance(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.MaintenanceConfig)
+	if strings.TrimSpace(r.Reason) == "" {
+		return nil, fmt.Errorf("请填写开启或关闭维护模式的原因")
+	}
+	r.UpdateAt = time.Now().Unix()
+
+	if a.ctx.DB.Setting().Check() {
+		if err := a.ctx.DB.Setting().UpdateMaintenance(*r); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := a.ctx.DB.Setting().Create(models.Settings{MaintenanceConfig: *r}); err != nil {
+			return nil, err
+		}
+	}
+
+	sender.SetMaintenance(a.ctx, r.Enabled)
+	action := "关闭"
+	if r.Enabled {
+		action = "开启"
+	}
+	logc.Infof(a.ctx.Ctx, "维护模式已%s, 操作人: %s, 原因: %s", action, r.UpdateBy, r.Reason)
+	return r, nil
+}
+
+// validateEnrichConfig 校验标签丰富配置
+func validateEnrichConfig(cfg models.EnrichConfig) error {
+	if !cfg.GetEnable() {
+		return nil
+	}
+	if len(cfg.LabelKeys) == 0 {
+		return fmt.Errorf("标签丰富: 查询标签不能为空")
+	}
+
+	switch cfg.Type {
+	case models.EnrichTypeHTTP:
+		if cfg.Url == "" {
+			return fmt.Errorf("标签丰富: 查询地址不能为空")
+		}
+	case models.EnrichTypeMapping:
+	default:
+		return fmt.Errorf("标签丰富: 不支持的查询方式 %s", cfg.Type)
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"fmt"
 	"time"
+	"watchAlert/alert/mute"
 	models "watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/tools"
@@ -17,6 +19,7 @@ type InterSilenceService interface {
 	Update(req interface{}) (interface{}, interface{})
 	Delete(req interface{}) (interface{}, interface{})
 	List(req interface{}) (interface{}, interface{})
+	Match(req interface{}) (interface{}, interface{})
 }
 
 func newInterSilenceService(ctx *ctx.Context) InterSilenceService {
@@ -27,6 +30,10 @@ func newInterSilenceService(ctx *ctx.Context) InterSilenceService {
 
 func (ass alertSilenceService) Create(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertSilences)
+	if err := validateSilence(*r); err != nil {
+		return nil, err
+	}
+
 	updateAt := time.Now().Unix()
 	silenceEvent := models.AlertSilences{
 		TenantId:      r.TenantId,
@@ -39,11 +46,12 @@ func (ass alertSilenceService) Create(req interface{}) (interface{}, interface{}
 		FaultCenterId: r.FaultCenterId,
 		Labels:        r.Labels,
 		Comment:       r.Comment,
+		Recurrence:    r.Recurrence,
 		Status:        1,
 	}
 
 	if r.StartsAt > updateAt {
-		r.Status = 0
+		silenceEvent.Status = models.SilenceStatusPending
 	}
 
 	ass.ctx.Redis.Silence().PushAlertMute(silenceEvent)
@@ -57,16 +65,31 @@ func (ass alertSilenceService) Create(req interface{}) (interface{}, interface{}
 
 func (ass alertSilenceService) Update(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertSilences)
+	if err := validateSilence(*r); err != nil {
+		return nil, err
+	}
+
 	updateAt := time.Now().Unix()
 	r.UpdateAt = updateAt
 
-	if r.StartsAt > updateAt {
-		r.Status = 0
-	} else {
-		r.Status = 1
+	switch {
+	case r.EndsAt > 0 && r.EndsAt <= updateAt:
+		r.Status = models.SilenceStatusExpired
+	case r.StartsAt > updateAt:
+		r.Status = models.SilenceStatusPending
+	default:
+		r.Status = models.SilenceStatusActive
 	}
 
-	ass.ctx.Redis.Silence().PushAlertMute(*r)
+	// 变更前的匹配条件不再使用, 清理其正则缓存
+	if old, err := ass.ctx.Redis.Silence().WithIdGetMuteFromCache(r.TenantId, r.FaultCenterId, r.Id); err == nil {
+		mute.ForgetSilence(*old)
+	}
+	if r.Status == models.SilenceStatusExpired {
+		ass.ctx.Redis.Silence().RemoveAlertMute(r.TenantId, r.FaultCenterId, r.Id)
+	} else {
+		ass.ctx.Redis.Silence().PushAlertMute(*r)
+	}
 	err := ass.ctx.DB.Silence().Update(*r)
 	if err != nil {
 		return nil, err
@@ -77,6 +100,9 @@ func (ass alertSilenceService) Update(req interface{}) (interface{}, interface{}
 
 func (ass alertSilenceService) Delete(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertSilenceQuery)
+	if silence, err := ass.ctx.Redis.Silence().WithIdGetMuteFromCache(r.TenantId, r.FaultCenterId, r.Id); err == nil {
+		mute.ForgetSilence(*silence)
+	}
 	ass.ctx.Redis.Silence().RemoveAlertMute(r.TenantId, r.FaultCenterId, r.Id)
 	err := ass.ctx.DB.Silence().Delete(*r)
 	if err != nil {
@@ -95,3 +121,63 @@ func (ass alertSilenceService) List(req interface{}) (interface{}, interface{})
 
 	return data, nil
 }
+
+// Match 获取与告警标签匹配的生效中静默规则, 用于展示告警被哪些规则静默
+func (ass alertSilenceService) Match(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.SilenceMatchReq)
+	if r.FaultCenterId == "" {
+		return nil, fmt.Errorf("故障中心 ID 不能为空")
+	}
+
+	matched := mute.MatchSilences(r.TenantId, r.FaultCenterId, r.Labels)
+	if matched == nil {
+		matched = []models.AlertSilences{}
+	}
+
+	return matched, nil
+}
+
+func validateSilence(r models.AlertSilences) error {
+	if r.Recurrence != nil {
+		if err := validateSilenceRecurrence(*r.Recurrence); err != nil {
+			return err
+		}
+	}
+
+	// 周期性静默可不设置结束时间
+	if (r.Recurrence == nil || r.EndsAt != 0) && r.EndsAt <= r.StartsAt {
+		return fmt.Errorf("静默结束时间需晚于开始时间")
+	}
+	return mute.ValidateSilenceLabels(r.Labels)
+}
+
+func validateSilenceRecurrence(r models.SilenceRecurrence) error {
+	const daySeconds = 24 * 3600
+	if r.StartTime < 0 || r.StartTime >= daySeconds || r.EndTime < 0 || r.EndTime >= daySeconds {
+		return fmt.Errorf("维护窗口时间需在 00:00~23:59 之间")
+	}
+	if r.StartTime == r.EndTime {
+		return fmt.Errorf("维护窗口开始时间与结束时间不能相同")
+	}
+
+	if r.Timezone != "" {
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("时区 %s 错误: %s", r.Timezone, err.Error())
+		}
+	}
+
+	for _, w := range r.Week {
+		valid := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if w == d.String() {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("星期 %s 错误", w)
+		}
+	}
+
+	return nil
+}
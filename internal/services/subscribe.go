@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gorm.io/gorm"
 	"time"
+	"watchAlert/alert/mute"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/tools"
@@ -19,6 +20,7 @@ type (
 		Get(req interface{}) (interface{}, interface{})
 		Create(req interface{}) (interface{}, interface{})
 		Delete(req interface{}) (interface{}, interface{})
+		Unsubscribe(req interface{}) (interface{}, interface{})
 	}
 )
 
@@ -50,17 +52,32 @@ func (s alertSubscribeService) Get(req interface{}) (interface{}, interface{}) {
 
 func (s alertSubscribeService) Create(req interface{}) (interface{}, interface{}) {
 	r := req.(*models.AlertSubscribe)
-	_, b, err := s.ctx.DB.Subscribe().Get(models.AlertSubscribeQuery{STenantId: r.STenantId, SUserId: r.SUserId, SRuleId: r.SRuleId})
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return nil, err
+	if r.SRuleId == "" && len(r.SLabelMatchers) == 0 {
+		return nil, fmt.Errorf("订阅规则及标签匹配条件不能同时为空")
+	}
+	if err := mute.ValidateSilenceLabels(r.SLabelMatchers); err != nil {
+		return nil, fmt.Errorf("标签匹配条件错误: %s", err.Error())
 	}
-	if b {
-		return nil, fmt.Errorf("用户已订阅该规则, 请勿重复创建!")
+	if r.SNoticeId != "" {
+		if _, err := s.ctx.DB.Notice().Get(models.NoticeQuery{TenantId: r.STenantId, Uuid: r.SNoticeId}); err != nil {
+			return nil, fmt.Errorf("通知对象 %s 不存在", r.SNoticeId)
+		}
+	}
+
+	// 按标签订阅时允许同一规则存在多个订阅
+	if r.SRuleId != "" && len(r.SLabelMatchers) == 0 {
+		_, b, err := s.ctx.DB.Subscribe().Get(models.AlertSubscribeQuery{STenantId: r.STenantId, SUserId: r.SUserId, SRuleId: r.SRuleId})
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		if b {
+			return nil, fmt.Errorf("用户已订阅该规则, 请勿重复创建!")
+		}
 	}
 
 	r.SId = "as-" + tools.RandId()
 	r.SCreateAt = time.Now().Unix()
-	err = s.ctx.DB.Subscribe().Create(*r)
+	err := s.ctx.DB.Subscribe().Create(*r)
 	if err != nil {
 		return nil, err
 	}
@@ -77,3 +94,22 @@ func (s alertSubscribeService) Delete(req interface{}) (interface{}, interface{}
 
 	return nil, nil
 }
+
+// Unsubscribe 取消当前用户自己的订阅
+func (s alertSubscribeService) Unsubscribe(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.AlertSubscribeQuery)
+	get, b, err := s.ctx.DB.Subscribe().Get(models.AlertSubscribeQuery{STenantId: r.STenantId, SId: r.SId})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if !b || get.SUserId != r.SUserId {
+		return nil, fmt.Errorf("订阅不存在或不属于当前用户")
+	}
+
+	err = s.ctx.DB.Subscribe().Delete(models.AlertSubscribeQuery{STenantId: r.STenantId, SId: r.SId, SUserId: r.SUserId})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
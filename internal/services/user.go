@@ -3,6 +3,7 @@ package services
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"time"
@@ -25,6 +26,8 @@ type InterUserService interface {
 	Register(req interface{}) (interface{}, interface{})
 	Delete(req interface{}) (interface{}, interface{})
 	ChangePass(req interface{}) (interface{}, interface{})
+	Refresh(req interface{}) (interface{}, interface{})
+	Logout(req interface{}) (interface{}, interface{})
 }
 
 func newInterUserService(ctx *ctx.Context) InterUserService {
@@ -74,7 +77,7 @@ func (us userService) Login(req interface{}) (interface{}, interface{}) {
 
 	switch data.CreateBy {
 	case "LDAP":
-		if global.Config.Ldap.Enabled {
+		if global.GetConfig().Ldap.Enabled {
 			err := LdapService.Login(r.UserName, r.Password)
 			if err != nil {
 				logc.Error(us.ctx.Ctx, fmt.Sprintf("LDAP 用户登陆失败, err: %s", err.Error()))
@@ -91,15 +94,118 @@ func (us userService) Login(req interface{}) (interface{}, interface{}) {
 	}
 
 	r.UserId = data.UserId
-	tokenData, err := tools.GenerateToken(r.UserId, r.UserName, r.Password)
+	r.Tenants = data.Tenants
+	if data.CreateBy == "LDAP" {
+		// LDAP 登陆时会按组映射同步租户, 重新获取用户所属的租户
+		if latest, ok, err := us.ctx.DB.User().Get(q); err == nil && ok {
+			r.Tenants = latest.Tenants
+		}
+	}
+	return us.issueToken(*r)
+}
+
+// refreshTokenSession 刷新 Token 对应的登陆会话
+type refreshTokenSession struct {
+	UserId   string `json:"userId"`
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+func getRefreshTokenKey(refreshToken string) string {
+	return "refresh-" + refreshToken
+}
+
+// issueToken 签发 Token 及刷新 Token, 刷新 Token 存储在 Redis 中以便注销时吊销
+func (us userService) issueToken(r models.Member) (interface{}, interface{}) {
+	tokenData, err := tools.GenerateToken(r.UserId, r.UserName, r.Password, r.Tenants)
+	if err != nil {
+		return nil, err
+	}
+
+	jc := global.GetConfig().Jwt
+	duration := time.Duration(jc.Expire) * time.Second
+	us.ctx.Redis.Redis().Set("uid-"+r.UserId, tools.JsonMarshal(r), duration)
+
+	refreshToken, err := tools.RandToken()
 	if err != nil {
 		return nil, err
 	}
+	session := refreshTokenSession{
+		UserId:   r.UserId,
+		UserName: r.UserName,
+		Password: r.Password,
+	}
+	refreshDuration := time.Duration(jc.GetRefreshExpire()) * time.Second
+	err = us.ctx.Redis.Redis().Set(getRefreshTokenKey(refreshToken), tools.JsonMarshal(session), refreshDuration).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.LoginToken{
+		Token:        tokenData,
+		RefreshToken: refreshToken,
+		ExpiresIn:    jc.Expire,
+	}, nil
+}
+
+// Refresh 使用刷新 Token 换取新的 Token, 旧的刷新 Token 随即失效
+func (us userService) Refresh(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RefreshTokenReq)
+	if r.RefreshToken == "" {
+		return nil, fmt.Errorf("刷新 Token 不能为空")
+	}
+
+	key := getRefreshTokenKey(r.RefreshToken)
+	result, err := us.ctx.Redis.Redis().Get(key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("刷新 Token 无效或已过期")
+	}
+	// 以删除成功作为使用刷新 Token 的凭据, 并发使用同一刷新 Token 时仅有一个请求成功, 避免被盗用的刷新 Token 重放
+	deleted, err := us.ctx.Redis.Redis().Del(key).Result()
+	if err != nil || deleted != 1 {
+		return nil, fmt.Errorf("刷新 Token 无效或已过期")
+	}
+
+	var session refreshTokenSession
+	if err := json.Unmarshal([]byte(result), &session); err != nil {
+		return nil, fmt.Errorf("刷新 Token 无效或已过期")
+	}
 
-	duration := time.Duration(global.Config.Jwt.Expire) * time.Second
-	us.ctx.Redis.Redis().Set("uid-"+data.UserId, tools.JsonMarshal(r), duration)
+	data, ok, err := us.ctx.DB.User().Get(models.MemberQuery{UserId: session.UserId})
+	if err != nil || !ok {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	switch data.CreateBy {
+	case "LDAP":
+		// LDAP 用户密码不在本地存储, 校验用户仍存在于 LDAP 中且未被禁用
+		if !global.GetConfig().Ldap.Enabled {
+			return nil, fmt.Errorf("请先开启 LDAP 功能!")
+		}
+		if err := LdapService.CheckUser(data.UserName); err != nil {
+			logc.Error(us.ctx.Ctx, fmt.Sprintf("LDAP 用户校验失败, err: %s", err.Error()))
+			return nil, fmt.Errorf("刷新 Token 无效或已过期")
+		}
+	default:
+		// 修改密码后已签发的刷新 Token 失效
+		if data.Password != session.Password {
+			return nil, fmt.Errorf("刷新 Token 无效或已过期")
+		}
+	}
 
-	return tokenData, nil
+	data.Password = session.Password
+	return us.issueToken(data)
+}
+
+// Logout 注销登陆, 吊销刷新 Token
+func (us userService) Logout(req interface{}) (interface{}, interface{}) {
+	r := req.(*models.RefreshTokenReq)
+	if r.RefreshToken == "" {
+		return nil, nil
+	}
+
+	us.ctx.Redis.Redis().Del(getRefreshTokenKey(r.RefreshToken))
+	return nil, nil
 }
 
 func (us userService) Register(req interface{}) (interface{}, interface{}) {
@@ -181,3 +287,38 @@ func (us userService) ChangePass(req interface{}) (interface{}, interface{}) {
 
 	return nil, nil
 }
+
+// syncUserTenants 将 LDAP / OIDC 用户按组映射加入对应的租户并更新角色, 不移除用户已加入的其他租户
+func syncUserTenants(c *ctx.Context, user models.Member, roles map[string]string) {
+	for tenantId, role := range roles {
+		info, err := c.DB.Tenant().GetTenantLinkedUserInfo(models.GetTenantLinkedUserInfo{ID: tenantId, UserID: user.UserId})
+		if err != nil {
+			logc.Errorf(c.Ctx, fmt.Sprintf("用户 %s 的租户 %s 不存在, err: %s", user.UserName, tenantId, err.Error()))
+			continue
+		}
+
+		switch {
+		case info.UserID == "":
+			// 加入租户时同步更新用户所属的租户列表
+			err = c.DB.Tenant().AddTenantLinkedUsers(models.TenantLinkedUsers{
+				ID:       tenantId,
+				UserRole: role,
+				Users: []models.TenantUser{
+					{
+						UserID:   user.UserId,
+						UserName: user.Email,
+					},
+				},
+			})
+		case info.UserRole != role:
+			err = c.DB.Tenant().ChangeTenantUserRole(models.ChangeTenantUserRole{
+				ID:       tenantId,
+				UserID:   user.UserId,
+				UserRole: role,
+			})
+		}
+		if err != nil {
+			logc.Errorf(c.Ctx, fmt.Sprintf("用户租户角色更新失败, user: %s, tenant: %s, err: %s", user.UserName, tenantId, err.Error()))
+		}
+	}
+}
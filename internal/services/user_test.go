@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"watchAlert/config"
+	"watchAlert/internal/cache"
+	"watchAlert/internal/global"
+	"watchAlert/internal/models"
+	"watchAlert/internal/repo"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/tools"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+type fakeCache struct {
+	cache.InterEntryCache
+	redis redis.UniversalClient
+}
+
+func (f fakeCache) Redis() redis.UniversalClient { return f.redis }
+
+type fakeRepo struct {
+	repo.InterEntryRepo
+	users map[string]models.Member
+}
+
+func (f fakeRepo) User() repo.InterUserRepo { return fakeUserRepo{users: f.users} }
+
+type fakeUserRepo struct {
+	repo.InterUserRepo
+	users map[string]models.Member
+}
+
+func (f fakeUserRepo) Get(r models.MemberQuery) (models.Member, bool, error) {
+	user, ok := f.users[r.UserId]
+	return user, ok, nil
+}
+
+func TestUserService_Refresh(t *testing.T) {
+	global.SetConfig(config.App{Jwt: config.Jwt{Secret: "s1", Expire: 60, RefreshExpire: 600}})
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	member := models.Member{UserId: "u1", UserName: "alice", Password: "hash"}
+	newService := func(users map[string]models.Member) userService {
+		return userService{ctx: &ctx.Context{DB: fakeRepo{users: users}, Redis: fakeCache{redis: client}, Ctx: context.Background()}}
+	}
+	login := func(t *testing.T) string {
+		t.Helper()
+		res, err := newService(nil).issueToken(member)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.(models.LoginToken).RefreshToken
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		users   map[string]models.Member
+		wantErr bool
+	}{
+		{name: "valid token", token: login, users: map[string]models.Member{"u1": member}},
+		{name: "empty token", token: func(t *testing.T) string { return "" }, wantErr: true},
+		{name: "unknown token", token: func(t *testing.T) string { return "unknown" }, users: map[string]models.Member{"u1": member}, wantErr: true},
+		{
+			name: "expired token",
+			token: func(t *testing.T) string {
+				token := login(t)
+				mr.FastForward(601 * time.Second)
+				return token
+			},
+			users:   map[string]models.Member{"u1": member},
+			wantErr: true,
+		},
+		{
+			name: "revoked by logout",
+			token: func(t *testing.T) string {
+				token := login(t)
+				newService(nil).Logout(&models.RefreshTokenReq{RefreshToken: token})
+				return token
+			},
+			users:   map[string]models.Member{"u1": member},
+			wantErr: true,
+		},
+		{name: "password changed", token: login, users: map[string]models.Member{"u1": {UserId: "u1", UserName: "alice", Password: "changed"}}, wantErr: true},
+		{name: "user deleted", token: login, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token(t)
+			us := newService(tt.users)
+
+			res, err := us.Refresh(&models.RefreshTokenReq{RefreshToken: token})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Refresh() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			loginToken := res.(models.LoginToken)
+			if loginToken.RefreshToken == "" || loginToken.RefreshToken == token || loginToken.ExpiresIn != 60 {
+				t.Errorf("Refresh() = %+v", loginToken)
+			}
+			if claims, err := tools.ParseToken(loginToken.Token); err != nil || claims.ID != "u1" || claims.Pass != "hash" {
+				t.Errorf("ParseToken() = %+v, %v", claims, err)
+			}
+			// 刷新 Token 仅可使用一次, 新签发的刷新 Token 可继续使用
+			if _, err := us.Refresh(&models.RefreshTokenReq{RefreshToken: token}); err == nil {
+				t.Error("Refresh() accepted a used refresh token")
+			}
+			if _, err := us.Refresh(&models.RefreshTokenReq{RefreshToken: loginToken.RefreshToken}); err != nil {
+				t.Errorf("Refresh() with rotated token error = %v", err)
+			}
+		})
+	}
+}
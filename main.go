@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"watchAlert/initialization"
 	"watchAlert/internal/global"
 )
@@ -9,6 +11,14 @@ var Version string
 
 func main() {
 	global.Version = Version
+	// 数据库迁移: w8t migrate up [version] | down [steps] | status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := initialization.RunMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	initialization.InitBasic()
 	initialization.InitRoute()
 }
@@ -2,13 +2,16 @@ package client
 
 import (
 	"context"
-	"fmt"
+	stdsql "database/sql"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/zeromicro/go-zero/core/logc"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"net"
+	"time"
+	"watchAlert/config"
 	"watchAlert/internal/global"
-	"watchAlert/internal/models"
 )
 
 func InitDB() *gorm.DB {
@@ -16,14 +19,12 @@ func InitDB() *gorm.DB {
 	// 初始化本地 test.db 数据库文件
 	//db, err := gorm.Open(sqlite.Open("data/sql.db"), &gorm.Config{})
 
-	sql := global.Config.MySQL
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4,utf8&parseTime=True&loc=Local&timeout=%s",
-		sql.User,
-		sql.Pass,
-		sql.Host,
-		sql.Port,
-		sql.DBName,
-		sql.Timeout)
+	sql := global.GetConfig().MySQL
+	dsn, err := buildMySQLDSN(sql)
+	if err != nil {
+		logc.Errorf(context.Background(), "failed to connect database: %s", err.Error())
+		return nil
+	}
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 
 	if err != nil {
@@ -31,41 +32,19 @@ func InitDB() *gorm.DB {
 		return nil
 	}
 
-	// 检查 Product 结构是否变化，变化则进行迁移
-	err = db.AutoMigrate(
-		&models.DutySchedule{},
-		&models.DutyManagement{},
-		&models.AlertNotice{},
-		&models.AlertDataSource{},
-		&models.AlertRule{},
-		&models.AlertCurEvent{},
-		&models.AlertHisEvent{},
-		&models.AlertSilences{},
-		&models.Member{},
-		&models.UserRole{},
-		&models.UserPermissions{},
-		&models.NoticeTemplateExample{},
-		&models.RuleGroups{},
-		&models.RuleTemplateGroup{},
-		&models.RuleTemplate{},
-		&models.Tenant{},
-		&models.Dashboard{},
-		&models.AuditLog{},
-		&models.Settings{},
-		&models.TenantLinkedUsers{},
-		&models.DashboardFolders{},
-		&models.AlertSubscribe{},
-		&models.NoticeRecord{},
-		&models.ProbingRule{},
-		&models.FaultCenter{},
-		&models.AiContentRecord{},
-	)
+	sqlDB, err := db.DB()
 	if err != nil {
-		logc.Error(context.Background(), err.Error())
+		logc.Errorf(context.Background(), "failed to connect database: %s", err.Error())
 		return nil
 	}
+	setConnPool(sqlDB, sql)
 
-	if global.Config.Server.Mode == "debug" {
+	if err := registerReadReplicas(db, sql); err != nil {
+		logc.Errorf(context.Background(), "failed to connect read replicas: %s", err.Error())
+		return nil
+	}
+
+	if global.GetConfig().Server.Mode == "debug" {
 		db.Debug()
 	} else {
 		db.Logger = logger.Default.LogMode(logger.Silent)
@@ -73,3 +52,41 @@ func InitDB() *gorm.DB {
 
 	return db
 }
+
+// buildMySQLDSN 根据配置生成主库 DSN, 用户名及密码中的特殊字符无需转义
+func buildMySQLDSN(sql config.MySQL) (string, error) {
+	timeout, err := sql.GetTimeout()
+	if err != nil {
+		return "", err
+	}
+
+	c := mysqlDriver.NewConfig()
+	c.User = sql.User
+	c.Passwd = sql.Pass
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(sql.Host, sql.Port)
+	c.DBName = sql.DBName
+	c.Timeout = timeout
+	applyDSNDefaults(c)
+
+	return c.FormatDSN(), nil
+}
+
+// applyDSNDefaults 主库与副本使用相同的字符集及时间解析方式
+func applyDSNDefaults(c *mysqlDriver.Config) {
+	c.ParseTime = true
+	c.Loc = time.Local
+	if c.Params == nil {
+		c.Params = make(map[string]string)
+	}
+	if _, ok := c.Params["charset"]; !ok {
+		c.Params["charset"] = "utf8mb4,utf8"
+	}
+}
+
+// setConnPool 设置连接池参数
+func setConnPool(db *stdsql.DB, sql config.MySQL) {
+	db.SetMaxOpenConns(sql.GetMaxOpenConns())
+	db.SetMaxIdleConns(sql.GetMaxIdleConns())
+	db.SetConnMaxLifetime(sql.GetConnMaxLifetime())
+}
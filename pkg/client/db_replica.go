@@ -0,0 +1,69 @@
+package client
+
+import (
+	stdsql "database/sql"
+	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"sync/atomic"
+	"watchAlert/config"
+)
+
+// ReadReplicaKey 通过 db.Set(ReadReplicaKey, true) 标记的查询路由至只读副本, 事务内的查询仍使用主库
+const ReadReplicaKey = "w8t:read_replica"
+
+type readReplicas struct {
+	pools []*stdsql.DB
+	next  atomic.Uint64
+}
+
+var replicas = &readReplicas{}
+
+// registerReadReplicas 连接只读副本并注册查询路由, 未配置副本时所有查询使用主库
+func registerReadReplicas(db *gorm.DB, sql config.MySQL) error {
+	for i, dsn := range sql.Replicas {
+		c, err := mysqlDriver.ParseDSN(dsn)
+		if err != nil {
+			return fmt.Errorf("MySQL.replicas[%d] 格式错误: %s", i, err.Error())
+		}
+		applyDSNDefaults(c)
+
+		pool, err := stdsql.Open("mysql", c.FormatDSN())
+		if err != nil {
+			return err
+		}
+		if err := pool.Ping(); err != nil {
+			pool.Close()
+			return fmt.Errorf("MySQL.replicas[%d] 连接失败: %s", i, err.Error())
+		}
+		setConnPool(pool, sql)
+		replicas.pools = append(replicas.pools, pool)
+	}
+	if len(replicas.pools) == 0 {
+		return nil
+	}
+
+	if err := db.Callback().Query().Before("gorm:query").Register("w8t:read_replica", replicas.route); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register("w8t:read_replica", replicas.route)
+}
+
+// route 轮询选择只读副本
+func (r *readReplicas) route(tx *gorm.DB) {
+	if v, ok := tx.Get(ReadReplicaKey); !ok || v != true {
+		return
+	}
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+
+	tx.Statement.ConnPool = r.pools[r.next.Add(1)%uint64(len(r.pools))]
+}
+
+// CloseReadReplicas 关闭所有只读副本连接
+func CloseReadReplicas() {
+	for _, pool := range replicas.pools {
+		pool.Close()
+	}
+}
@@ -1,43 +1,198 @@
 package client
 
 import (
+	"bytes"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/jordan-wright/email"
+	"net"
+	"net/mail"
 	"net/smtp"
 	"strconv"
+	"time"
 )
 
+const (
+	// EmailTLSModeNone 不加密, net/smtp 仅允许本机服务在明文连接上进行 PLAIN 认证
+	EmailTLSModeNone = "none"
+	// EmailTLSModeStartTLS 明文连接后通过 STARTTLS 升级, 服务端不支持时报错
+	EmailTLSModeStartTLS = "starttls"
+	// EmailTLSModeImplicit 直接建立 TLS 连接, 一般为 465 端口
+	EmailTLSModeImplicit = "implicit"
+)
+
+var (
+	// ErrEmailConnection 无法连接邮件服务器或 TLS 握手失败
+	ErrEmailConnection = errors.New("邮件服务器连接失败, 请检查服务地址、端口及 TLS 模式")
+	// ErrEmailAuth 邮件服务器认证失败
+	ErrEmailAuth = errors.New("邮件服务器认证失败, 请检查用户名及密码 (授权码)")
+)
+
+// emailDialTimeout 连接邮件服务器的超时时间
+const emailDialTimeout = 10 * time.Second
+
 type EmailClient struct {
 	ServerAddr string
 	Port       int
 	Email      *email.Email
 	Auth       smtp.Auth
+	// TLS 模式, 为空时服务端支持 STARTTLS 则自动升级
+	TLSMode            string
+	InsecureSkipVerify bool
+}
+
+// EmailConfig SMTP 连接配置
+type EmailConfig struct {
+	ServerAddr string
+	Port       int
+	// 发件人地址
+	From string
+	// 登录用户, 为空时使用发件人地址
+	User     string
+	Password string
+	TLSMode  string
+	// 跳过证书校验
+	InsecureSkipVerify bool
+}
+
+// EmailAttachment 邮件附件
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
 }
 
 func NewEmailClient(serverAddr, username, password string, port int) EmailClient {
+	return NewSMTPEmailClient(EmailConfig{
+		ServerAddr: serverAddr,
+		Port:       port,
+		From:       username,
+		Password:   password,
+	})
+}
+
+// NewSMTPEmailClient 根据 SMTP 配置创建邮件客户端
+func NewSMTPEmailClient(cfg EmailConfig) EmailClient {
 	e := email.NewEmail()
-	auth := smtp.PlainAuth("", username, password, serverAddr)
-	e.From = fmt.Sprintf("WatchAlert<%s>", username)
+	e.From = fmt.Sprintf("WatchAlert<%s>", cfg.From)
+
+	user := cfg.User
+	if user == "" {
+		user = cfg.From
+	}
+
+	var auth smtp.Auth
+	if cfg.Password != "" {
+		auth = smtp.PlainAuth("", user, cfg.Password, cfg.ServerAddr)
+	}
 
 	return EmailClient{
-		Email:      e,
-		Auth:       auth,
-		ServerAddr: serverAddr,
-		Port:       port,
+		Email:              e,
+		Auth:               auth,
+		ServerAddr:         cfg.ServerAddr,
+		Port:               cfg.Port,
+		TLSMode:            cfg.TLSMode,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
 	}
 }
 
-func (a EmailClient) Send(to, cc []string, subject string, msg []byte) error {
+func (a EmailClient) Send(to, cc []string, subject string, msg []byte, attachments ...EmailAttachment) error {
 	a.Email.To = to
 	a.Email.Cc = cc
 	a.Email.HTML = msg
 	a.Email.Subject = subject
-	port := strconv.FormatInt(int64(a.Port), 10)
-	//err := a.Email.SendWithTLS(a.ServerAddr+":"+port, a.Auth, &tls.Config{ServerName: a.ServerAddr})
-	err := a.Email.Send(a.ServerAddr+":"+port, a.Auth)
+	for _, attachment := range attachments {
+		if _, err := a.Email.Attach(bytes.NewReader(attachment.Content), attachment.Filename, attachment.ContentType); err != nil {
+			return fmt.Errorf("添加附件 %s 失败, err: %s", attachment.Filename, err.Error())
+		}
+	}
+
+	raw, err := a.Email.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// MAIL FROM 仅使用地址部分
+	from, err := mail.ParseAddress(a.Email.From)
 	if err != nil {
+		return fmt.Errorf("发件人地址无效: %s", a.Email.From)
+	}
+
+	return a.send(from.Address, append(append([]string{}, to...), cc...), raw)
+}
+
+// send 按 TLS 模式建立连接并投递邮件, 连接及认证失败分别返回 ErrEmailConnection / ErrEmailAuth
+func (a EmailClient) send(from string, rcpts []string, raw []byte) error {
+	if len(rcpts) == 0 {
+		return errors.New("收件人为空")
+	}
+
+	addr := net.JoinHostPort(a.ServerAddr, strconv.Itoa(a.Port))
+	tlsConfig := &tls.Config{ServerName: a.ServerAddr, InsecureSkipVerify: a.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if a.TLSMode == EmailTLSModeImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("%w, addr: %s, err: %s", ErrEmailConnection, addr, err.Error())
+	}
+
+	c, err := smtp.NewClient(conn, a.ServerAddr)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("%w, addr: %s, err: %s", ErrEmailConnection, addr, err.Error())
+	}
+	defer c.Close()
+
+	switch a.TLSMode {
+	case EmailTLSModeNone, EmailTLSModeImplicit:
+	default:
+		ok, _ := c.Extension("STARTTLS")
+		if !ok && a.TLSMode == EmailTLSModeStartTLS {
+			return fmt.Errorf("%w, addr: %s, err: 服务器不支持 STARTTLS", ErrEmailConnection, addr)
+		}
+		if ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("%w, addr: %s, err: %s", ErrEmailConnection, addr, err.Error())
+			}
+		}
+	}
+
+	if a.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(a.Auth); err != nil {
+				return fmt.Errorf("%w, err: %s", ErrEmailAuth, err.Error())
+			}
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range rcpts {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("收件人 %s 被拒绝, err: %s", rcpt, err.Error())
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	return c.Quit()
 }
@@ -1,7 +1,9 @@
 package client
 
 import (
+	"errors"
 	"github.com/sirupsen/logrus"
+	"net"
 	"testing"
 )
 
@@ -40,3 +42,18 @@ func TestEmailClient_Send(t *testing.T) {
 		return
 	}
 }
+
+func TestEmailClient_SendConnectionError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	eCli := NewSMTPEmailClient(EmailConfig{ServerAddr: "127.0.0.1", Port: port, From: "alert@example.com", Password: "xxx", TLSMode: EmailTLSModeImplicit})
+	err = eCli.Send([]string{"ops@example.com"}, nil, "test", []byte("test"), EmailAttachment{Filename: "logs.csv", ContentType: "text/csv", Content: []byte("a,b\n1,2\n")})
+	if !errors.Is(err, ErrEmailConnection) {
+		t.Fatalf("err = %v, want %v", err, ErrEmailConnection)
+	}
+}
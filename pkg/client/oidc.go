@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"net/http"
+	"time"
+)
+
+// oidcHTTPTimeout 请求身份提供方 (发现、JWKS、Token) 的超时时间
+const oidcHTTPTimeout = 10 * time.Second
+
+// OIDCConfig OIDC 客户端配置
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCClient OIDC 授权码模式客户端, 负责生成授权地址、换取及校验 ID Token
+type OIDCClient struct {
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// NewOIDCClient 通过 Issuer 的 /.well-known/openid-configuration 获取授权、Token 及 JWKS 地址, 签发方与配置不一致时返回错误
+func NewOIDCClient(ctx context.Context, cfg OIDCConfig) (*OIDCClient, error) {
+	provider, err := oidc.NewProvider(withOIDCHTTPClient(ctx), cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("获取 OIDC 配置失败: %s", err.Error())
+	}
+
+	return &OIDCClient{
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			Endpoint:     provider.Endpoint(),
+		},
+	}, nil
+}
+
+// AuthCodeURL 生成授权地址, 使用 PKCE 防止授权码被截获后使用
+func (o *OIDCClient) AuthCodeURL(state, nonce, verifier string) string {
+	return o.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange 使用授权码换取 ID Token 并校验, 返回 ID Token 中的声明
+func (o *OIDCClient) Exchange(ctx context.Context, code, nonce, verifier string) (map[string]interface{}, error) {
+	ctx = withOIDCHTTPClient(ctx)
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取 Token 失败: %s", err.Error())
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("响应中不包含 id_token")
+	}
+
+	return o.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+// VerifyIDToken 校验 ID Token 的签名、签发方、受众、有效期及 nonce, 签名公钥按 kid 从 JWKS 获取并缓存, 未找到时重新获取以支持密钥轮换
+func (o *OIDCClient) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (map[string]interface{}, error) {
+	idToken, err := o.verifier.Verify(withOIDCHTTPClient(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %s", err.Error())
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID Token nonce 不一致")
+	}
+
+	claims := make(map[string]interface{})
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("ID Token 解析失败: %s", err.Error())
+	}
+	return claims, nil
+}
+
+func withOIDCHTTPClient(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, &http.Client{Timeout: oidcHTTPTimeout})
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOIDCClient_VerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 issuer,
+				"authorization_endpoint": issuer + "/authorize",
+				"token_endpoint":         issuer + "/token",
+				"jwks_uri":               issuer + "/keys",
+			})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kid": "k1",
+					"kty": "RSA",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+
+	cli, err := NewOIDCClient(context.Background(), OIDCConfig{Issuer: issuer, ClientID: "w8t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	claims := func(aud interface{}, nonce string, exp int64) jwt.MapClaims {
+		return jwt.MapClaims{"iss": issuer, "sub": "u1", "aud": aud, "nonce": nonce, "exp": exp, "email": "u@example.com"}
+	}
+	wrongIssuer := claims("w8t", "n1", time.Now().Add(time.Minute).Unix())
+	wrongIssuer["iss"] = "https://other.example.com"
+	exp := time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(claims("w8t", "n1", exp))},
+		{name: "audience list", token: sign(claims([]string{"other", "w8t"}, "n1", exp))},
+		{name: "wrong audience", token: sign(claims("other", "n1", exp)), wantErr: true},
+		{name: "wrong nonce", token: sign(claims("w8t", "n2", exp)), wantErr: true},
+		{name: "wrong issuer", token: sign(wrongIssuer), wantErr: true},
+		{name: "expired", token: sign(claims("w8t", "n1", time.Now().Add(-time.Minute).Unix())), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cli.VerifyIDToken(context.Background(), tt.token, "n1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyIDToken() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got["email"] != "u@example.com" || got["sub"] != "u1") {
+				t.Errorf("VerifyIDToken() claims = %v", got)
+			}
+		})
+	}
+}
@@ -4,18 +4,15 @@ import (
 	"fmt"
 	"github.com/go-redis/redis"
 	"log"
+	"watchAlert/config"
 	"watchAlert/internal/global"
 )
 
-var Redis *redis.Client
+var Redis redis.UniversalClient
 
-func InitRedis() *redis.Client {
+func InitRedis() redis.UniversalClient {
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", global.Config.Redis.Host, global.Config.Redis.Port),
-		Password: global.Config.Redis.Pass,
-		DB:       global.Config.Redis.Database, // 使用默认的数据库
-	})
+	client := newRedisClient(global.GetConfig().Redis)
 
 	// 尝试连接到 Redis 服务器
 	_, err := client.Ping().Result()
@@ -29,3 +26,27 @@ func InitRedis() *redis.Client {
 	return client
 
 }
+
+// newRedisClient 根据部署模式创建单节点、Sentinel 或 Cluster 客户端
+func newRedisClient(c config.Redis) redis.UniversalClient {
+	switch c.GetMode() {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    c.MasterName,
+			SentinelAddrs: c.GetSentinelAddrs(),
+			Password:      c.Pass,
+			DB:            c.Database,
+		})
+	case config.RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    c.GetClusterAddrs(),
+			Password: c.Pass,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", c.Host, c.Port),
+			Password: c.Pass,
+			DB:       c.Database, // 使用默认的数据库
+		})
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"watchAlert/internal/models"
 	"watchAlert/pkg/community/aws/cloudwatch/types"
 	"watchAlert/pkg/ctx"
 	"watchAlert/pkg/provider"
@@ -27,7 +28,7 @@ func NewInterAWSRdsService(ctx *ctx.Context) InterAwsRdsService {
 
 func (a awsRdsService) GetDBInstanceIdentifier(req interface{}) (interface{}, interface{}) {
 	r := req.(*types.RdsInstanceReq)
-	datasourceObj, err := a.ctx.DB.Datasource().GetInstance(r.DatasourceId)
+	datasourceObj, err := a.ctx.DB.Datasource().Get(models.DatasourceQuery{TenantId: r.TenantId, Id: r.DatasourceId})
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +55,7 @@ func (a awsRdsService) GetDBInstanceIdentifier(req interface{}) (interface{}, in
 
 func (a awsRdsService) GetDBClusterIdentifier(req interface{}) (interface{}, interface{}) {
 	r := req.(*types.RdsClusterReq)
-	datasourceObj, err := a.ctx.DB.Datasource().GetInstance(r.DatasourceId)
+	datasourceObj, err := a.ctx.DB.Datasource().Get(models.DatasourceQuery{TenantId: r.TenantId, Id: r.DatasourceId})
 	if err != nil {
 		return nil, err
 	}
@@ -66,10 +66,12 @@ type MetricNamesQuery struct {
 }
 
 type RdsInstanceReq struct {
+	TenantId     string `json:"tenantId" form:"tenantId"`
 	DatasourceId string `json:"datasourceId" form:"datasourceId"`
 }
 
 type RdsClusterReq struct {
+	TenantId     string `json:"tenantId" form:"tenantId"`
 	DatasourceId string `json:"datasourceId" form:"datasourceId"`
 }
 
@@ -11,7 +11,7 @@ type Context struct {
 	DB                 repo.InterEntryRepo
 	Redis              cache.InterEntryCache
 	Ctx                context.Context
-	Mux                sync.RWMutex
+	Mux                *sync.RWMutex
 	ConsumerContextMap map[string]context.CancelFunc
 }
 
@@ -29,14 +29,27 @@ func NewContext(ctx context.Context, db repo.InterEntryRepo, redis cache.InterEn
 		DB:                 db,
 		Redis:              redis,
 		Ctx:                ctx,
+		Mux:                &sync.RWMutex{},
 		ConsumerContextMap: make(map[string]context.CancelFunc),
 	}
 }
 
+// WithContext 基于新的 context 派生 Context, 共享存储与锁, 用于携带 traceId 等日志字段
+func (c *Context) WithContext(child context.Context) *Context {
+	return &Context{
+		DB:                 c.DB,
+		Redis:              c.Redis,
+		Ctx:                child,
+		Mux:                c.Mux,
+		ConsumerContextMap: c.ConsumerContextMap,
+	}
+}
+
 func DO() *Context {
 	return &Context{
 		DB:    DB,
 		Redis: Redis,
 		Ctx:   Ctx,
+		Mux:   &sync.RWMutex{},
 	}
 }
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"github.com/rs/xid"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// TraceIdField 日志中链路 ID 的字段名
+	TraceIdField = "traceId"
+	// RequestIdHeader 请求 ID 的 Header, 客户端未携带时自动生成
+	RequestIdHeader = "X-Request-Id"
+)
+
+type traceIdKey struct{}
+
+// Setup 初始化日志, level 支持 debug / info / error / severe, encoding 支持 json / plain
+func Setup(level, encoding string) error {
+	return logx.SetUp(logx.LogConf{
+		Mode:     "console",
+		Encoding: encoding,
+		Level:    level,
+	})
+}
+
+// SetLevel 动态调整日志级别, 用于配置热更新
+func SetLevel(level string) {
+	switch level {
+	case "debug":
+		logx.SetLevel(logx.DebugLevel)
+	case "info":
+		logx.SetLevel(logx.InfoLevel)
+	case "error":
+		logx.SetLevel(logx.ErrorLevel)
+	case "severe":
+		logx.SetLevel(logx.SevereLevel)
+	}
+}
+
+// NewTraceId 生成链路 ID
+func NewTraceId() string {
+	return xid.New().String()
+}
+
+// WithTraceId 将链路 ID 写入上下文, 通过 logc 输出的日志会自动携带 traceId 字段
+func WithTraceId(ctx context.Context, traceId string) context.Context {
+	if traceId == "" || GetTraceId(ctx) == traceId {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, traceIdKey{}, traceId)
+	return logx.ContextWithFields(ctx, logx.Field(TraceIdField, traceId))
+}
+
+// GetTraceId 获取上下文中的链路 ID, 不存在时返回空
+func GetTraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceId, _ := ctx.Value(traceIdKey{}).(string)
+	return traceId
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// WatchAlert 自身运行指标, 通过 /metrics 暴露
+var (
+	// RuleEvalDuration 单次规则评估耗时
+	RuleEvalDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "watchalert",
+		Name:      "rule_evaluation_duration_seconds",
+		Help:      "规则单次评估耗时",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"datasource_type"})
+
+	// RuleEvalTotal 规则评估次数
+	RuleEvalTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "watchalert",
+		Name:      "rule_evaluations_total",
+		Help:      "规则评估次数",
+	}, []string{"datasource_type"})
+
+	// RuleEvalSkippedTotal 上一次评估耗时超过评估间隔而跳过的评估次数
+	RuleEvalSkippedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "watchalert",
+		Name:      "rule_evaluations_skipped_total",
+		Help:      "评估耗时超过评估间隔而跳过的评估次数",
+	}, []string{"datasource_type"})
+
+	// DatasourceErrorsTotal 数据源查询及健康检查失败次数 (重试后仍失败)
+	DatasourceErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "watchalert",
+		Name:      "datasource_query_errors_total",
+		Help:      "数据源查询失败次数",
+	}, []string{"datasource_id"})
+
+	// DatasourceBusyTotal 等待数据源并发额度超时的查询次数
+	DatasourceBusyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "watchalert",
+		Name:      "datasource_query_busy_total",
+		Help:      "数据源并发查询数达到上限而放弃的查询次数",
+	}, []string{"datasource_id"})
+
+	// DatasourceCircuitOpen 数据源是否处于熔断状态, 1 为熔断
+	DatasourceCircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "watchalert",
+		Name:      "datasource_circuit_open",
+		Help:      "数据源是否处于熔断状态",
+	}, []string{"datasource_id"})
+
+	// NotificationsTotal 通知发送次数, status 为 success 或 failed
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "watchalert",
+		Name:      "notifications_total",
+		Help:      "通知发送次数",
+	}, []string{"notice_type", "status"})
+
+	// ActiveAlerts 故障中心当前告警中的事件数
+	ActiveAlerts = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "watchalert",
+		Name:      "active_alerts",
+		Help:      "当前告警中的事件数",
+	}, []string{"tenant_id", "fault_center_id"})
+)
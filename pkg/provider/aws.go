@@ -6,13 +6,22 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"watchAlert/internal/models"
 )
 
+const CloudWatchDsProviderName string = "CloudWatch"
+
 type AwsConfig struct {
 	ExternalLabels map[string]interface{}
 	cfg            aws.Config
 }
 
+func init() {
+	Register(CloudWatchDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewAWSCredentialCfg(ds.AWSCloudWatch.Region, ds.AWSCloudWatch.AccessKey, ds.AWSCloudWatch.SecretKey, ds.Labels)
+	})
+}
+
 func NewAWSCredentialCfg(region, ak, sk string, labels map[string]interface{}) (AwsConfig, error) {
 	cfg, err := config.LoadDefaultConfig(context.Background(),
 		func(options *config.LoadOptions) error {
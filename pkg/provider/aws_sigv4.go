@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"io"
+	"net/http"
+	"time"
+	"watchAlert/internal/models"
+)
+
+// amzContentSha256Header OpenSearch Serverless 要求携带请求体的 SHA256
+const amzContentSha256Header = "X-Amz-Content-Sha256"
+
+// sigV4Transport 对每个请求进行 AWS SigV4 签名, 用于访问 AWS OpenSearch / OpenSearch Serverless
+type sigV4Transport struct {
+	next        http.RoundTripper
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	service     string
+}
+
+// newSigV4Transport 创建 SigV4 签名 Transport, 未配置 AccessKey 时使用 AWS 默认凭证链
+func newSigV4Transport(next http.RoundTripper, cfg models.SigV4) (http.RoundTripper, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("SigV4 认证需要配置 Region")
+	}
+
+	credentials, err := newSigV4Credentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sigV4Transport{
+		next:        next,
+		signer:      v4.NewSigner(),
+		credentials: credentials,
+		region:      cfg.Region,
+		service:     cfg.GetService(),
+	}, nil
+}
+
+func newSigV4Credentials(cfg models.SigV4) (aws.CredentialsProvider, error) {
+	if cfg.AccessKey != "" {
+		return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     cfg.AccessKey,
+				SecretAccessKey: cfg.SecretKey,
+			}, nil
+		}), nil
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("加载 AWS 默认凭证失败, err: %s", err.Error())
+	}
+	return awsCfg.Credentials, nil
+}
+
+func (t *sigV4Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 签名会修改请求头, 按 RoundTripper 约定复制请求后再处理
+	signed := req.Clone(req.Context())
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	signed.Header.Set(amzContentSha256Header, payloadHash)
+
+	credentials, err := t.credentials.Retrieve(signed.Context())
+	if err != nil {
+		return nil, fmt.Errorf("获取 AWS 凭证失败, err: %s", err.Error())
+	}
+
+	if err := t.signer.SignHTTP(signed.Context(), credentials, signed, payloadHash, t.service, t.region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(signed)
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"watchAlert/pkg/metrics"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+const (
+	// BreakerClosed 数据源正常, 请求正常发送
+	BreakerClosed = "closed"
+	// BreakerOpen 数据源连续失败, 冷却期内的请求直接失败
+	BreakerOpen = "open"
+	// BreakerHalfOpen 冷却期结束, 放行一个请求探测数据源是否恢复
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerState 数据源熔断状态
+type BreakerState struct {
+	State string `json:"state"`
+	// 连续失败次数
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// 最近一次失败的错误
+	LastError string `json:"lastError"`
+	// 熔断时间
+	OpenedAt int64 `json:"openedAt"`
+	// 冷却期结束时间, 之后放行探测请求
+	RetryAt int64 `json:"retryAt"`
+	// 探测请求的截止时间, 探测请求未返回结果 (如执行中 panic) 时, 超过该时间后放行新的探测请求
+	ProbeDeadline int64 `json:"probeDeadline,omitempty"`
+}
+
+type breakerBypassKey struct{}
+
+var (
+	// 连续失败达到该次数后熔断, 小于等于 0 时不熔断
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+	breakers         = make(map[string]*BreakerState)
+	breakerMux       sync.Mutex
+)
+
+// SetBreakerPolicy 设置熔断的连续失败次数及冷却时长, 支持配置热加载时更新
+func SetBreakerPolicy(threshold int, cooldown time.Duration) {
+	breakerMux.Lock()
+	defer breakerMux.Unlock()
+	breakerThreshold = threshold
+	breakerCooldown = cooldown
+}
+
+// WithBreakerBypass 返回不受熔断限制的上下文, 用于手动连接测试, 请求成功时同样会关闭熔断
+func WithBreakerBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, breakerBypassKey{}, true)
+}
+
+// GetBreakerStates 获取各数据源的熔断状态, 未记录的数据源处于 closed 状态
+func GetBreakerStates() map[string]BreakerState {
+	breakerMux.Lock()
+	defer breakerMux.Unlock()
+
+	states := make(map[string]BreakerState, len(breakers))
+	for k, v := range breakers {
+		states[k] = *v
+	}
+	return states
+}
+
+// RemoveBreaker 数据源删除或配置变更时清理熔断状态
+func RemoveBreaker(key string) {
+	breakerMux.Lock()
+	defer breakerMux.Unlock()
+	delete(breakers, key)
+	metrics.DatasourceCircuitOpen.DeleteLabelValues(key)
+}
+
+// allowRequest 判断是否放行请求, 熔断期间直接返回 ErrCircuitOpen, 冷却期结束后仅放行一个探测请求
+func allowRequest(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+	if bypass, _ := ctx.Value(breakerBypassKey{}).(bool); bypass {
+		return nil
+	}
+
+	breakerMux.Lock()
+	defer breakerMux.Unlock()
+
+	b, ok := breakers[key]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	switch b.State {
+	case BreakerOpen:
+		if now.Unix() >= b.RetryAt {
+			b.State = BreakerHalfOpen
+			b.ProbeDeadline = now.Add(breakerCooldown).Unix()
+			return nil
+		}
+		return fmt.Errorf("%w, 连续失败 %d 次, 将于 %s 后探测恢复, datasourceId: %s, 最近错误: %s",
+			ErrCircuitOpen, b.ConsecutiveFailures, time.Until(time.Unix(b.RetryAt, 0)).Round(time.Second), key, b.LastError)
+	case BreakerHalfOpen:
+		// 探测请求超时仍未记录结果, 放行新的探测请求, 避免一直处于 half_open
+		if now.Unix() >= b.ProbeDeadline {
+			b.ProbeDeadline = now.Add(breakerCooldown).Unix()
+			return nil
+		}
+		return fmt.Errorf("%w, 正在探测数据源是否恢复, datasourceId: %s", ErrCircuitOpen, key)
+	default:
+		return nil
+	}
+}
+
+// recordBreakerResult 记录请求结果, 仅临时错误 (连接失败、超时、5xx) 计为失败, 认证及查询语句错误说明数据源可达
+func recordBreakerResult(key string, err error) {
+	if key == "" {
+		return
+	}
+
+	breakerMux.Lock()
+	defer breakerMux.Unlock()
+
+	b, ok := breakers[key]
+	if errors.Is(err, context.Canceled) {
+		// 请求被取消, 无法判断数据源是否恢复, 由下一个请求重新探测
+		if ok && b.State == BreakerHalfOpen {
+			b.State = BreakerOpen
+		}
+		return
+	}
+
+	if !IsTransientError(err) {
+		if ok {
+			if b.State != BreakerClosed {
+				logc.Infof(context.Background(), "数据源已恢复, 关闭熔断, datasourceId: %s", key)
+			}
+			delete(breakers, key)
+			metrics.DatasourceCircuitOpen.WithLabelValues(key).Set(0)
+		}
+		return
+	}
+
+	if !ok {
+		b = &BreakerState{State: BreakerClosed}
+		breakers[key] = b
+	}
+	b.ConsecutiveFailures++
+	b.LastError = err.Error()
+	if breakerThreshold <= 0 || (b.State == BreakerClosed && b.ConsecutiveFailures < breakerThreshold) {
+		return
+	}
+
+	now := time.Now()
+	b.State = BreakerOpen
+	b.OpenedAt = now.Unix()
+	b.RetryAt = now.Add(breakerCooldown).Unix()
+	metrics.DatasourceCircuitOpen.WithLabelValues(key).Set(1)
+	logc.Errorf(context.Background(), "数据源连续失败 %d 次, 熔断 %s, datasourceId: %s, err: %s", b.ConsecutiveFailures, breakerCooldown, key, b.LastError)
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	SetBreakerPolicy(2, time.Second)
+	defer SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second, Jitter: 0.2})
+	defer SetBreakerPolicy(5, 30*time.Second)
+	defer RemoveBreaker("ds-breaker")
+
+	ctx := context.Background()
+	var calls int
+	failing := func() error {
+		calls++
+		return ErrConnection
+	}
+
+	Retry(ctx, "ds-breaker", failing)
+	if state := GetBreakerStates()["ds-breaker"]; state.State != BreakerClosed || state.ConsecutiveFailures != 1 {
+		t.Fatalf("state = %+v, want closed", state)
+	}
+	Retry(ctx, "ds-breaker", failing)
+	if state := GetBreakerStates()["ds-breaker"]; state.State != BreakerOpen {
+		t.Fatalf("state = %+v, want open", state)
+	}
+
+	if err := Retry(ctx, "ds-breaker", failing); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+
+	// 手动测试不受熔断限制, 成功后关闭熔断
+	if err := Retry(WithBreakerBypass(ctx), "ds-breaker", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetBreakerStates()["ds-breaker"]; ok {
+		t.Fatal("breaker should be closed")
+	}
+
+	// 冷却结束后探测失败重新熔断
+	Retry(ctx, "ds-breaker", failing)
+	Retry(ctx, "ds-breaker", failing)
+	breakerMux.Lock()
+	breakers["ds-breaker"].RetryAt = time.Now().Unix()
+	breakerMux.Unlock()
+	if err := Retry(ctx, "ds-breaker", failing); !errors.Is(err, ErrConnection) {
+		t.Fatalf("probe err = %v", err)
+	}
+	if state := GetBreakerStates()["ds-breaker"]; state.State != BreakerOpen || state.ConsecutiveFailures != 3 {
+		t.Fatalf("state = %+v, want open", state)
+	}
+}
+
+func TestBreaker_ProbeDeadline(t *testing.T) {
+	SetBreakerPolicy(1, time.Second)
+	defer SetBreakerPolicy(5, 30*time.Second)
+	defer RemoveBreaker("ds-probe")
+
+	ctx := WithQueryErrorRecorder(context.Background())
+	recordBreakerResult("ds-probe", ErrConnection)
+
+	// 熔断期间快速失败并记录查询错误, 评估据此跳过告警恢复
+	if err := Retry(ctx, "ds-probe", func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want circuit open", err)
+	}
+	if err := QueryError(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("query error = %v, want circuit open", err)
+	}
+
+	// 探测请求未返回结果, 截止时间前拒绝新的探测, 之后重新放行
+	breakerMux.Lock()
+	breakers["ds-probe"].RetryAt = time.Now().Unix()
+	breakerMux.Unlock()
+	if err := allowRequest(context.Background(), "ds-probe"); err != nil {
+		t.Fatalf("probe err = %v", err)
+	}
+	if err := allowRequest(context.Background(), "ds-probe"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe err = %v, want circuit open", err)
+	}
+	breakerMux.Lock()
+	breakers["ds-probe"].ProbeDeadline = time.Now().Unix()
+	breakerMux.Unlock()
+	if err := allowRequest(context.Background(), "ds-probe"); err != nil {
+		t.Fatalf("probe after deadline err = %v", err)
+	}
+	if state := GetBreakerStates()["ds-probe"]; state.State != BreakerHalfOpen {
+		t.Fatalf("state = %+v, want half_open", state)
+	}
+}
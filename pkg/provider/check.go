@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"watchAlert/internal/models"
+	"watchAlert/pkg/tracing"
 )
 
 // HealthChecker 统一健康检查接口
@@ -12,66 +13,32 @@ type HealthChecker interface {
 	Check() (bool, error)
 }
 
-// ClientFactory 客户端工厂函数类型
-type ClientFactory func(models.AlertDataSource) (HealthChecker, error)
-
-// 注册所有数据源类型的工厂方法
-var datasourceFactories = map[string]ClientFactory{
-	"Prometheus": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewPrometheusClient(ds)
-	},
-	"VictoriaMetrics": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewVictoriaMetricsClient(ds)
-	},
-	"Kubernetes": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewKubernetesClient(context.Background(), ds.KubeConfig, ds.Labels)
-	},
-	"ElasticSearch": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewElasticSearchClient(context.Background(), ds)
-	},
-	"AliCloudSLS": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewAliCloudSlsClient(ds)
-	},
-	"Loki": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewLokiClient(ds)
-	},
-	"Jaeger": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewJaegerClient(ds)
-	},
-	"CloudWatch": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return &CloudWatchDummyChecker{}, nil
-	},
-	"VictoriaLogs": func(ds models.AlertDataSource) (HealthChecker, error) {
-		return NewVictoriaLogsClient(context.Background(), ds)
-	},
-}
-
-// CloudWatchDummyChecker 云监控哑检查器
-type CloudWatchDummyChecker struct{}
-
-func (c *CloudWatchDummyChecker) Check() (bool, error) {
-	return true, nil
-}
-
 // CheckDatasourceHealth 统一健康检查入口
-func CheckDatasourceHealth(datasource models.AlertDataSource) (bool, error) {
-	// 获取对应的工厂方法
-	factory, ok := datasourceFactories[datasource.Type]
-	if !ok {
-		err := fmt.Errorf("unsupported datasource type: %s", datasource.Type)
-		logDatasourceError(datasource, err)
-		return false, err
-	}
-
-	// 创建客户端
-	client, err := factory(datasource)
+func CheckDatasourceHealth(ctx context.Context, datasource models.AlertDataSource) (healthy bool, err error) {
+	ctx, span := tracing.Start(ctx, "datasource.check",
+		tracing.AttrDatasourceId.String(datasource.Id),
+		tracing.AttrDatasourceType.String(datasource.Type),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// 按数据源类型创建客户端
+	cli, err := NewClient(context.Background(), datasource)
 	if err != nil {
 		logDatasourceError(datasource, fmt.Errorf("client creation failed: %w", err))
 		return false, err
 	}
+	// 未实现健康检查的客户端 (如 CloudWatch) 视为健康
+	client, ok := cli.(HealthChecker)
+	if !ok {
+		return true, nil
+	}
 
-	// 执行健康检查
-	healthy, err := client.Check()
+	// 执行健康检查, 临时错误按重试策略重试
+	err = Retry(ctx, datasource.Id, func() error {
+		var err error
+		healthy, err = client.Check()
+		return err
+	})
 	if err != nil || !healthy {
 		logDatasourceError(datasource, fmt.Errorf("health check failed: %w", err))
 		return false, err
@@ -82,7 +49,7 @@ func CheckDatasourceHealth(datasource models.AlertDataSource) (bool, error) {
 
 // 统一日志记录方法
 func logDatasourceError(ds models.AlertDataSource, err error) {
-	logc.Errorf(context.Background(), "Datasource error",
+	logc.Error(context.Background(), "Datasource error",
 		map[string]interface{}{
 			"id":   ds.Id,
 			"name": ds.Name,
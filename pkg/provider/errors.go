@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/olivere/elastic/v7"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// 数据源查询及健康检查的错误类型, 调用方可通过 errors.Is 判断是否需要重试或标记数据源异常
+var (
+	// ErrAuth 认证失败, 账号密码或 Token 错误、权限不足
+	ErrAuth = errors.New("认证失败")
+	// ErrConnection 连接失败, 地址不可达、超时等, 通常可重试
+	ErrConnection = errors.New("连接失败")
+	// ErrBadQuery 查询语句或查询参数错误, 重试无意义
+	ErrBadQuery = errors.New("查询语句错误")
+	// ErrUnavailable 服务端异常, 返回 5xx 等非预期状态码
+	ErrUnavailable = errors.New("服务不可用")
+	// ErrResultWindow 查询的日志条数超出 ElasticSearch 索引的 max_result_window, 属于查询参数错误
+	ErrResultWindow = errors.New("查询结果超出 max_result_window 限制, 请开启分页拉取 (maxLogs / autoPaginate) 或缩小返回条数及查询时间范围")
+	// ErrQueryTooExpensive 查询超出数据源配置的查询成本限制, 属于查询参数错误
+	ErrQueryTooExpensive = errors.New("查询超出数据源的查询成本限制")
+	// ErrNotSupported 数据源不支持该操作, 如字段及索引查询
+	ErrNotSupported = errors.New("数据源不支持该操作")
+	// ErrBusy 数据源并发查询数已达上限且等待超时, 不重试
+	ErrBusy = errors.New("数据源繁忙")
+	// ErrCircuitOpen 数据源连续失败已熔断, 冷却期内直接失败, 不重试
+	ErrCircuitOpen = errors.New("数据源不可用, 已熔断")
+)
+
+// newStatusError 根据 HTTP 状态码归类错误
+func newStatusError(statusCode int, detail string) error {
+	var kind error
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrAuth
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusNotFound || statusCode == http.StatusUnprocessableEntity:
+		kind = ErrBadQuery
+	default:
+		kind = ErrUnavailable
+	}
+
+	if detail == "" {
+		return fmt.Errorf("%w, 状态码: %d", kind, statusCode)
+	}
+	return fmt.Errorf("%w, 状态码: %d, %s", kind, statusCode, detail)
+}
+
+// newConnectionError 包装请求发送阶段的错误
+func newConnectionError(err error) error {
+	return fmt.Errorf("%w: %w", ErrConnection, err)
+}
+
+// newBadQueryError 包装查询参数校验错误
+func newBadQueryError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrBadQuery, fmt.Sprintf(format, args...))
+}
+
+// wrapEsError 将 ElasticSearch 客户端返回的错误归类
+func wrapEsError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var esErr *elastic.Error
+	if errors.As(err, &esErr) {
+		if isEsResultWindowError(esErr) {
+			return fmt.Errorf("%w, %w: %w", ErrBadQuery, ErrResultWindow, err)
+		}
+		if reason := getEsScriptErrorReason(esErr); reason != "" {
+			return fmt.Errorf("%w, 脚本执行失败: %s: %w", ErrBadQuery, reason, err)
+		}
+		return fmt.Errorf("%w: %w", newStatusError(esErr.Status, ""), err)
+	}
+	if elastic.IsConnErr(err) || errors.Is(err, context.DeadlineExceeded) {
+		return newConnectionError(err)
+	}
+	return err
+}
+
+// isEsResultWindowError 判断是否为 from + size 超出 index.max_result_window 的错误
+func isEsResultWindowError(esErr *elastic.Error) bool {
+	if esErr.Details == nil {
+		return false
+	}
+	details := append([]*elastic.ErrorDetails{esErr.Details}, esErr.Details.RootCause...)
+	for _, d := range details {
+		if d != nil && strings.Contains(d.Reason, "Result window is too large") {
+			return true
+		}
+	}
+	return false
+}
+
+// getEsScriptErrorReason 获取运行时字段等 painless 脚本的错误原因, 包含具体异常及出错的脚本位置, 非脚本错误时返回空
+func getEsScriptErrorReason(esErr *elastic.Error) string {
+	if esErr.Details == nil {
+		return ""
+	}
+
+	// 分片失败的原因中包含 caused_by, 即脚本中实际抛出的异常, 优先于 root_cause 查找
+	details := []*elastic.ErrorDetails{esErr.Details}
+	for _, shard := range esErr.Details.FailedShards {
+		if reason, ok := shard["reason"].(map[string]interface{}); ok {
+			if b, err := json.Marshal(reason); err == nil {
+				d := new(elastic.ErrorDetails)
+				if json.Unmarshal(b, d) == nil {
+					details = append(details, d)
+				}
+			}
+		}
+	}
+	details = append(details, esErr.Details.RootCause...)
+
+	for _, d := range details {
+		if d == nil || d.Type != "script_exception" {
+			continue
+		}
+		reason := d.Reason
+		if cause, ok := d.CausedBy["reason"].(string); ok && cause != "" {
+			reason += ", " + cause
+		}
+		if len(d.ScriptStack) > 0 {
+			reason += ", script_stack: " + strings.Join(d.ScriptStack, " ")
+		}
+		return reason
+	}
+	return ""
+}
+
+// wrapSlsError 将阿里云 SLS SDK 返回的错误归类
+func wrapSlsError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sdkErr *tea.SDKError
+	if errors.As(err, &sdkErr) && sdkErr.StatusCode != nil {
+		return fmt.Errorf("%w: %w", newStatusError(tea.IntValue(sdkErr.StatusCode), tea.StringValue(sdkErr.Code)), err)
+	}
+	return newConnectionError(err)
+}
+
+// wrapPromError 将 Prometheus 客户端返回的错误归类, 其余 4xx (client_error) 不归类, 不会被重试
+func wrapPromError(err error) error {
+	var promErr *v1.Error
+	if !errors.As(err, &promErr) {
+		return err
+	}
+
+	switch promErr.Type {
+	case v1.ErrBadData, v1.ErrExec:
+		return fmt.Errorf("%w: %w", ErrBadQuery, err)
+	case v1.ErrServer, v1.ErrBadResponse:
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	case v1.ErrTimeout:
+		return newConnectionError(err)
+	default:
+		return err
+	}
+}
@@ -9,14 +9,23 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"os"
 	"time"
+	"watchAlert/internal/models"
 )
 
+const KubernetesDsProviderName string = "Kubernetes"
+
 type KubernetesClient struct {
 	ExternalLabels map[string]interface{}
 	Cli            *kubernetes.Clientset
 	Ctx            context.Context
 }
 
+func init() {
+	Register(KubernetesDsProviderName, func(ctx context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewKubernetesClient(ctx, ds.KubeConfig, ds.Labels)
+	})
+}
+
 func NewKubernetesClient(ctx context.Context, kubeConfigContent string, labels map[string]interface{}) (KubernetesClient, error) {
 	// 如果配置内容为空，则去默认目录下取配置文件的内容
 	if kubeConfigContent == "" {
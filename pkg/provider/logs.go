@@ -4,6 +4,8 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"watchAlert/internal/models"
@@ -15,6 +17,10 @@ const (
 	AliCloudSLSDsProviderName   string = "AliCloudSLS"
 	ElasticSearchDsProviderName string = "ElasticSearch"
 	VictoriaLogsDsProviderName  string = "VictoriaLogs"
+	ClickHouseDsProviderName    string = "ClickHouse"
+	GraylogDsProviderName       string = "Graylog"
+	SQLDsProviderName           string = "SQL"
+	HTTPProbeDsProviderName     string = "HTTPProbe"
 )
 
 type LogsFactoryProvider interface {
@@ -23,13 +29,99 @@ type LogsFactoryProvider interface {
 	GetExternalLabels() map[string]interface{}
 }
 
+// LogsSchemaProvider 日志数据源可选实现的元数据查询, 用于规则编辑时字段及索引的自动补全, 目前支持 ElasticSearch
+type LogsSchemaProvider interface {
+	// Fields 获取索引映射中的字段, index 支持通配符及逗号分隔的多个索引
+	Fields(index string) ([]LogField, error)
+	// Indices 获取匹配的索引, pattern 为空时返回全部索引
+	Indices(pattern string) ([]string, error)
+}
+
+// LogField 日志字段及其类型
+type LogField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ListLogFields 获取日志数据源的字段, 数据源不支持时返回 ErrNotSupported
+func ListLogFields(cli LogsFactoryProvider, index string) ([]LogField, error) {
+	schema, ok := cli.(LogsSchemaProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return schema.Fields(index)
+}
+
+// ListLogIndices 获取日志数据源的索引, 数据源不支持时返回 ErrNotSupported
+func ListLogIndices(cli LogsFactoryProvider, pattern string) ([]string, error) {
+	schema, ok := cli.(LogsSchemaProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return schema.Indices(pattern)
+}
+
 type LogQueryOptions struct {
 	AliCloudSLS   AliCloudSLS
 	Loki          Loki
 	ElasticSearch Elasticsearch
 	VictoriaLogs  VictoriaLogs
+	ClickHouse    ClickHouse
+	Graylog       Graylog
+	SQL           SQL
 	StartAt       interface{} // 查询的开始时间。
 	EndAt         interface{} // 查询的结束时间。
+	Timeout       int64       // 查询超时时间（单位秒），为 0 时使用数据源的超时配置。
+	LabelFields   []string    // 提升为标签的日志字段，为空时取所有日志共有的键值对。
+	// 查询统计, 不为空时由数据源回填查询耗时及分片失败信息, 目前支持 ElasticSearch
+	Stats *QueryStats `json:"-"`
+}
+
+// defaultLogQueryTimeout 默认查询超时时间（单位秒）
+const defaultLogQueryTimeout int64 = 10
+
+// GetTimeout 获取查询超时时间，优先使用查询参数，其次使用数据源配置
+func (o LogQueryOptions) GetTimeout(dsTimeout int64) int64 {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	if dsTimeout > 0 {
+		return dsTimeout
+	}
+	return defaultLogQueryTimeout
+}
+
+// Statement 获取查询语句及索引 (表), 用于链路追踪及告警事件的查询语句
+func (o LogQueryOptions) Statement(datasourceType string) (query, index string) {
+	switch datasourceType {
+	case LokiDsProviderName:
+		return o.Loki.Query, ""
+	case AliCloudSLSDsProviderName:
+		return o.AliCloudSLS.Query, o.AliCloudSLS.Project + "/" + o.AliCloudSLS.LogStore
+	case ElasticSearchDsProviderName:
+		if o.ElasticSearch.RawJson != "" {
+			return o.ElasticSearch.RawJson, o.ElasticSearch.Index
+		}
+		if o.ElasticSearch.QueryType == models.EsQueryTypeAggregation {
+			return tools.JsonMarshal(o.ElasticSearch.Aggregation), o.ElasticSearch.Index
+		}
+		return tools.JsonMarshal(o.ElasticSearch.QueryFilter), o.ElasticSearch.Index
+	case VictoriaLogsDsProviderName:
+		return o.VictoriaLogs.Query, ""
+	case ClickHouseDsProviderName:
+		if o.ClickHouse.QueryType == models.ClickHouseQueryTypeRawSQL {
+			return o.ClickHouse.RawSQL, o.ClickHouse.Table
+		}
+		return o.ClickHouse.Where, o.ClickHouse.Table
+	case GraylogDsProviderName:
+		if o.Graylog.QueryType == models.GraylogQueryTypeRawQuery {
+			return o.Graylog.Query, strings.Join(o.Graylog.Streams, ",")
+		}
+		return tools.JsonMarshal(o.Graylog.QueryFilter), strings.Join(o.Graylog.Streams, ",")
+	case SQLDsProviderName:
+		return o.SQL.Query, ""
+	}
+	return "", ""
 }
 
 type Loki struct {
@@ -57,6 +149,36 @@ type Elasticsearch struct {
 	QueryWildcard int64
 	// 查询sql
 	RawJson string
+	// 聚合查询配置
+	Aggregation models.EsAggregation
+	// 最大拉取日志条数, 大于 0 时通过 PIT + search_after 分页拉取
+	MaxLogs int
+	// 时间字段, 默认 @timestamp
+	TimestampField string
+	// 索引模式, 开启后按查询时间范围展开日期索引, 并忽略不存在的索引
+	IndexPattern bool
+	// 高亮配置
+	Highlight models.EsHighlight
+	// 远程集群别名, 配置后通过跨集群搜索查询各集群的索引, _local 表示本地集群
+	Clusters []string
+	// 排序字段, 默认时间字段
+	SortField string
+	// 是否升序, 默认降序
+	SortAscending bool
+	// 返回的日志条数
+	Size int
+	// 索引的 index.max_result_window, 默认 10000
+	MaxResultWindow int
+	// 返回条数超出 MaxResultWindow 时自动切换为 PIT + search_after 分页拉取
+	AutoPaginate bool
+	// 字段类型声明, 构建 Logs.Message 时按声明转换字段值
+	FieldTypes []models.EsFieldType
+	// 仅统计命中的文档数, 通过 _count 查询, 返回的 Logs 不包含日志内容
+	CountOnly bool
+	// 运行时字段, 附加至全部查询请求, 计算结果合并至 Logs.Message
+	RuntimeFields []models.EsRuntimeField
+	// 跳过数据源的查询成本限制
+	BypassQueryGuard bool
 }
 
 // VictoriaLogs victoriaMetrics数据源配置
@@ -65,16 +187,216 @@ type VictoriaLogs struct {
 	Limit int    // 要返回的最大条目数
 }
 
+// ClickHouse ClickHouse数据源配置
+type ClickHouse struct {
+	// 查询类型, 条件查询与 SQL 语句查询
+	QueryType models.ClickHouseQueryType
+	// 表名, 支持 db.table
+	Table string
+	// 时间字段, 默认 timestamp
+	TimestampField string
+	// 附加过滤条件
+	Where string
+	// 查询sql
+	RawSQL string
+	// 要返回的最大条目数
+	Limit int
+}
+
+// Graylog Graylog数据源配置
+type Graylog struct {
+	// 查询类型, 条件查询与搜索语句查询
+	QueryType models.GraylogQueryType
+	// 搜索语句, Graylog (Lucene) 语法
+	Query string
+	// 过滤条件
+	QueryFilter []models.EsQueryFilter
+	// filter关系，与或非
+	QueryFilterCondition models.EsFilterCondition
+	// 匹配模式, 与 ElasticSearch 一致
+	QueryWildcard int64
+	// 限定查询的 Stream ID
+	Streams []string
+	// 要返回的最大条目数
+	Limit int
+}
+
+// SQL MySQL / PostgreSQL 数据源配置
+type SQL struct {
+	// 只读查询语句, 支持 {start}、{end}、{start_ts}、{end_ts} 命名参数
+	Query string
+	// 要返回的最大条目数
+	Limit int
+}
+
+// GetTimestampField 获取时间字段, 未配置时默认 @timestamp
+func (e Elasticsearch) GetTimestampField() string {
+	if e.TimestampField == "" {
+		return "@timestamp"
+	}
+	return e.TimestampField
+}
+
+// esDefaultMaxResultWindow ES 索引 index.max_result_window 的默认值
+const esDefaultMaxResultWindow = 10000
+
+func (e Elasticsearch) GetMaxResultWindow() int {
+	if e.MaxResultWindow <= 0 {
+		return esDefaultMaxResultWindow
+	}
+	return e.MaxResultWindow
+}
+
 func (e Elasticsearch) GetIndexName() string {
-	if strings.Contains(e.Index, "YYYY") && strings.Contains(e.Index, "MM") && strings.Contains(e.Index, "dd") {
-		indexName := e.Index
+	indexName := e.Index
+	if strings.Contains(indexName, "YYYY") && strings.Contains(indexName, "MM") && strings.Contains(indexName, "dd") {
 		indexName = strings.ReplaceAll(indexName, "YYYY", time.Now().Format("2006"))
 		indexName = strings.ReplaceAll(indexName, "MM", time.Now().Format("01"))
 		indexName = strings.ReplaceAll(indexName, "dd", time.Now().Format("02"))
+	}
+
+	if len(e.Clusters) == 0 {
 		return indexName
 	}
+	return strings.Join(e.qualifyIndices(strings.Split(indexName, ",")), ",")
+}
+
+// qualifyIndices 为未指定集群的索引加上远程集群前缀, 如 cluster_a:logs-*, 已指定集群的索引原样保留
+func (e Elasticsearch) qualifyIndices(indices []string) []string {
+	if len(e.Clusters) == 0 {
+		return indices
+	}
 
-	return e.Index
+	var res []string
+	for _, index := range indices {
+		index = strings.TrimSpace(index)
+		if index == "" {
+			continue
+		}
+		if cluster, _ := splitEsClusterPrefix(index); cluster != "" {
+			res = append(res, index)
+			continue
+		}
+		for _, cluster := range e.Clusters {
+			switch cluster = strings.TrimSpace(cluster); cluster {
+			case "":
+				continue
+			case models.EsLocalCluster:
+				res = append(res, index)
+			default:
+				res = append(res, cluster+":"+index)
+			}
+		}
+	}
+	return res
+}
+
+// splitEsClusterPrefix 拆分索引的远程集群前缀, 日期运算索引名中的冒号 (如时区) 不视为集群前缀
+func splitEsClusterPrefix(index string) (cluster, name string) {
+	i := strings.Index(index, ":")
+	if i <= 0 {
+		return "", index
+	}
+	if j := strings.Index(index, "<"); j >= 0 && j < i {
+		return "", index
+	}
+	return index[:i], index[i+1:]
+}
+
+const (
+	// esIndexPatternMaxDays 索引模式下最多展开的天数, 超出时仅保留最近的索引
+	esIndexPatternMaxDays = 92
+	// esDateMathDefaultFormat ES 日期运算索引名默认的日期格式 yyyy.MM.dd
+	esDateMathDefaultFormat = "2006.01.02"
+)
+
+// esDateMathIndex 匹配 <logs-{now/d}>、<logs-{now/d{yyyy.MM.dd}}> 形式的日期运算索引名
+var esDateMathIndex = regexp.MustCompile(`^<(.*)\{now/d(?:\{([^}]*)\})?\}(.*)>$`)
+
+// GetIndexNames 获取查询的索引列表, 开启索引模式时按 startAt..endAt 展开每天对应的索引,
+// 支持 YYYY/MM/dd 占位符及日期运算索引名, 通配符 (logs-*) 与滚动别名原样交给 ES 解析, 多个索引以逗号分隔
+func (e Elasticsearch) GetIndexNames(startAt, endAt interface{}) []string {
+	if !e.IndexPattern {
+		return []string{e.GetIndexName()}
+	}
+
+	days := getIndexPatternDays(startAt, endAt)
+
+	var (
+		indices []string
+		seen    = make(map[string]struct{})
+	)
+	for _, pattern := range strings.Split(e.Index, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		cluster, pattern := splitEsClusterPrefix(pattern)
+		for _, index := range expandIndexPattern(pattern, days) {
+			if cluster != "" {
+				index = cluster + ":" + index
+			}
+			if _, ok := seen[index]; ok {
+				continue
+			}
+			seen[index] = struct{}{}
+			indices = append(indices, index)
+		}
+	}
+
+	return e.qualifyIndices(indices)
+}
+
+// getIndexPatternDays 获取查询时间范围覆盖的日期, 日志索引通常按 UTC 日期滚动
+func getIndexPatternDays(startAt, endAt interface{}) []time.Time {
+	end := time.Now().UTC()
+	if ts, ok := toUnixSeconds(endAt); ok {
+		end = time.Unix(ts, 0).UTC()
+	}
+
+	start := end
+	if ts, ok := toUnixSeconds(startAt); ok && ts <= end.Unix() {
+		start = time.Unix(ts, 0).UTC()
+	}
+
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	if minDay := endDay.AddDate(0, 0, -(esIndexPatternMaxDays - 1)); day.Before(minDay) {
+		day = minDay
+	}
+
+	var days []time.Time
+	for ; !day.After(endDay); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// expandIndexPattern 将单个索引模式按日期展开, 不包含日期占位符时原样返回
+func expandIndexPattern(pattern string, days []time.Time) []string {
+	var format func(day time.Time) string
+	if m := esDateMathIndex.FindStringSubmatch(pattern); m != nil {
+		layout := esDateMathDefaultFormat
+		if m[2] != "" {
+			layout = strings.NewReplacer("yyyy", "2006", "MM", "01", "dd", "02").Replace(m[2])
+		}
+		format = func(day time.Time) string {
+			return m[1] + day.Format(layout) + m[3]
+		}
+	} else if strings.Contains(pattern, "YYYY") && strings.Contains(pattern, "MM") && strings.Contains(pattern, "dd") {
+		format = func(day time.Time) string {
+			return strings.NewReplacer("YYYY", day.Format("2006"), "MM", day.Format("01"), "dd", day.Format("02")).Replace(pattern)
+		}
+	} else {
+		return []string{pattern}
+	}
+
+	indices := make([]string, 0, len(days))
+	for _, day := range days {
+		indices = append(indices, format(day))
+	}
+	return indices
 }
 
 type Logs struct {
@@ -99,6 +421,51 @@ func (l Logs) GetAnnotations() []map[string]interface{} {
 	return l.Message
 }
 
+// GetAggregationValue 获取聚合查询结果的分桶值
+func (l Logs) GetAggregationValue() float64 {
+	if len(l.Message) == 0 {
+		return 0
+	}
+	value, _ := l.Message[0]["value"].(float64)
+	return value
+}
+
+// GetAggregationMetricValue 获取多指标聚合结果中指定指标的值
+func (l Logs) GetAggregationMetricValue(name string) float64 {
+	if len(l.Message) == 0 {
+		return 0
+	}
+	value, _ := l.Message[0][name].(float64)
+	return value
+}
+
+// GetFieldValue 获取首条日志或聚合结果中指定字段的数值, 嵌套字段以 . 分隔, 字段不存在或不是数值时返回 false
+func (l Logs) GetFieldValue(field string) (float64, bool) {
+	if len(l.Message) == 0 || field == "" {
+		return 0, false
+	}
+
+	container, key, ok := lookupEsField(l.Message[0], field)
+	if !ok {
+		return 0, false
+	}
+	switch v := container[key].(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	default:
+		value, err := coerceEsNumber(v)
+		return value, err == nil
+	}
+}
+
 func commonKeyValuePairs(maps []map[string]interface{}) map[string]interface{} {
 	// 初始化一个map，用于记录每个key-value对的出现次数
 	counts := make(map[string]int)
@@ -136,3 +503,76 @@ func commonKeyValuePairs(maps []map[string]interface{}) map[string]interface{} {
 
 	return common
 }
+
+// toUnixSeconds 将查询时间转换为 Unix 秒, 支持整型时间戳、time.Time 及 RFC3339 字符串
+func toUnixSeconds(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case int64:
+		return t, t > 0
+	case int32:
+		return int64(t), t > 0
+	case int:
+		return int64(t), t > 0
+	case float64:
+		return int64(t), t > 0
+	case time.Time:
+		return t.Unix(), !t.IsZero()
+	case string:
+		if t == "" {
+			return 0, false
+		}
+		if ts, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return ts, ts > 0
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return 0, false
+		}
+		return parsed.Unix(), true
+	default:
+		return 0, false
+	}
+}
+
+// getLogsMetric 提取日志标签, 配置 labelFields 时仅提升指定字段, 否则使用 fallback 计算
+func getLogsMetric(msgs []map[string]interface{}, labelFields []string, fallback func([]map[string]interface{}) map[string]interface{}) map[string]interface{} {
+	if len(labelFields) == 0 {
+		return fallback(msgs)
+	}
+
+	return labelFieldPairs(msgs, labelFields)
+}
+
+// labelFieldPairs 按字段提取标签, 取首条包含该字段的日志的值, 支持 a.b 形式访问嵌套字段
+func labelFieldPairs(msgs []map[string]interface{}, labelFields []string) map[string]interface{} {
+	metric := make(map[string]interface{})
+	for _, field := range labelFields {
+		if field == "" {
+			continue
+		}
+		for _, msg := range msgs {
+			if v, ok := lookupField(msg, field); ok {
+				metric[field] = v
+				break
+			}
+		}
+	}
+
+	return metric
+}
+
+func lookupField(msg map[string]interface{}, field string) (interface{}, bool) {
+	if v, ok := msg[field]; ok {
+		return v, true
+	}
+
+	key, rest, found := strings.Cut(field, ".")
+	if !found {
+		return nil, false
+	}
+	sub, ok := msg[key].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupField(sub, rest)
+}
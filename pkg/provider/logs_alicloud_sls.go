@@ -1,26 +1,40 @@
 package provider
 
 import (
-	"github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	"context"
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	sls20201230 "github.com/alibabacloud-go/sls-20201230/v6/client"
 	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"strings"
+	"time"
 	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
 )
 
+// slsDefaultLine 单次查询返回的最大日志条数, SLS 接口上限为 100
+const slsDefaultLine = 100
+
 type AliCloudSlsDsProvider struct {
 	client         *sls20201230.Client
+	project        string
+	logStore       string
+	timeout        int64
 	ExternalLabels map[string]interface{}
 }
 
+func init() {
+	Register(AliCloudSLSDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewAliCloudSlsClient(ds)
+	})
+}
+
 func NewAliCloudSlsClient(source models.AlertDataSource) (LogsFactoryProvider, error) {
 	config := &openapi.Config{
-		AccessKeyId:     &source.DsAliCloudConfig.AliCloudAk,
-		AccessKeySecret: &source.DsAliCloudConfig.AliCloudSk,
+		AccessKeyId:     tea.String(source.DsAliCloudConfig.AliCloudAk),
+		AccessKeySecret: tea.String(source.DsAliCloudConfig.AliCloudSk),
+		Endpoint:        tea.String(source.DsAliCloudConfig.AliCloudEndpoint),
 	}
-	config.Endpoint = tea.String(source.DsAliCloudConfig.AliCloudEndpoint)
 	result, err := sls20201230.NewClient(config)
 	if err != nil {
 		return AliCloudSlsDsProvider{}, err
@@ -28,32 +42,85 @@ func NewAliCloudSlsClient(source models.AlertDataSource) (LogsFactoryProvider, e
 
 	return AliCloudSlsDsProvider{
 		client:         result,
+		project:        source.DsAliCloudConfig.Project,
+		logStore:       source.DsAliCloudConfig.LogStore,
+		timeout:        source.HTTP.Timeout,
 		ExternalLabels: source.Labels,
 	}, nil
 }
 
-func (a AliCloudSlsDsProvider) Query(query LogQueryOptions) ([]Logs, int, error) {
-	var err error
-	getLogsRequest := &sls20201230.GetLogsRequest{
-		To:    tea.Int32(query.EndAt.(int32)),
-		From:  tea.Int32(query.StartAt.(int32)),
-		Query: tea.String(query.AliCloudSLS.Query),
-	}
-	runtime := &util.RuntimeOptions{}
-	headers := make(map[string]*string)
+func (a AliCloudSlsDsProvider) Query(query LogQueryOptions) (data []Logs, count int, err error) {
 	defer func() {
 		if r := tea.Recover(recover()); r != nil {
-			err = r
+			data, count, err = nil, 0, r
 		}
 	}()
 
-	res, err := a.client.GetLogsWithOptions(tea.String(query.AliCloudSLS.Project), tea.String(query.AliCloudSLS.LogStore), getLogsRequest, headers, runtime)
+	project, logStore := a.getLogStore(query.AliCloudSLS)
+	if project == "" || logStore == "" {
+		return nil, 0, newBadQueryError("Project / LogStore 为空")
+	}
+
+	curTime := time.Now()
+	startAt, ok := toUnixSeconds(query.StartAt)
+	if !ok {
+		startAt = tools.ParserDuration(curTime, 5, "m").Unix()
+	}
+	endAt, ok := toUnixSeconds(query.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
+	}
+
+	// 查询分析语句 (query | select ...) 返回的每一行为一条分析结果, 普通查询返回原始日志, 均按最新的日志优先
+	getLogsRequest := &sls20201230.GetLogsRequest{
+		From:    tea.Int32(int32(startAt)),
+		To:      tea.Int32(int32(endAt)),
+		Query:   tea.String(query.AliCloudSLS.Query),
+		Line:    tea.Int64(slsDefaultLine),
+		Reverse: tea.Bool(true),
+	}
+
+	res, err := a.client.GetLogsWithOptions(tea.String(project), tea.String(logStore), getLogsRequest, map[string]*string{}, a.runtime(query.GetTimeout(a.timeout)))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, wrapSlsError(err)
 	}
+	if len(res.Body) == 0 {
+		return nil, 0, nil
+	}
+
+	data = append(data, Logs{
+		ProviderName: AliCloudSLSDsProviderName,
+		Metric:       getLogsMetric(res.Body, query.LabelFields, slsTagLabels),
+		Message:      res.Body,
+	})
+
+	return data, len(res.Body), nil
+}
+
+// getLogStore 获取查询的 Project / LogStore, 规则未配置时使用数据源的默认配置
+func (a AliCloudSlsDsProvider) getLogStore(options AliCloudSLS) (string, string) {
+	project, logStore := options.Project, options.LogStore
+	if project == "" {
+		project = a.project
+	}
+	if logStore == "" {
+		logStore = a.logStore
+	}
+	return project, logStore
+}
 
+// runtime 设置请求超时时间 (单位秒)
+func (a AliCloudSlsDsProvider) runtime(timeout int64) *util.RuntimeOptions {
+	return &util.RuntimeOptions{
+		ReadTimeout:    tea.Int(int(timeout) * 1000),
+		ConnectTimeout: tea.Int(int(timeout) * 1000),
+	}
+}
+
+// slsTagLabels 提取日志中的 __tag__ 字段作为标签
+func slsTagLabels(body []map[string]interface{}) map[string]interface{} {
 	var metric = map[string]interface{}{}
-	for _, content := range res.Body {
+	for _, content := range body {
 		for k, v := range content {
 			// 过滤掉不带 tag 标签的，或者带 tag 又带 id 标识的（这个 id 标识是阿里云随机生成的，会导致相同日志指纹不同）
 			if !strings.Contains(k, "__tag__") || (strings.Contains(k, "__tag__") && strings.Contains(k, "id")) {
@@ -69,25 +136,60 @@ func (a AliCloudSlsDsProvider) Query(query LogQueryOptions) ([]Logs, int, error)
 		}
 	}
 
-	var data []Logs
-	data = append(data, Logs{
-		ProviderName: AliCloudSLSDsProviderName,
-		Metric:       metric,
-		Message:      res.Body,
-	})
-
-	return data, len(res.Body), nil
+	return metric
 }
 
-func (a AliCloudSlsDsProvider) Check() (bool, error) {
-	err := a.client.CheckConfig(&client.Config{})
+// Check 列举 Project 下的 LogStore 校验 Endpoint 及 AccessKey 权限, 未配置默认 Project 时列举 Project
+func (a AliCloudSlsDsProvider) Check() (ok bool, err error) {
+	defer func() {
+		if r := tea.Recover(recover()); r != nil {
+			ok, err = false, r
+		}
+	}()
+
+	runtime := a.runtime(a.getCheckTimeout())
+	if a.project == "" {
+		_, err = a.client.ListProjectWithOptions(&sls20201230.ListProjectRequest{Size: tea.Int32(1)}, map[string]*string{}, runtime)
+		if err != nil {
+			return false, wrapSlsError(err)
+		}
+		return true, nil
+	}
+
+	// LogstoreName 为模糊匹配, 需再精确比对
+	request := &sls20201230.ListLogStoresRequest{Size: tea.Int32(500)}
+	if a.logStore != "" {
+		request.LogstoreName = tea.String(a.logStore)
+	}
+	res, err := a.client.ListLogStoresWithOptions(tea.String(a.project), request, map[string]*string{}, runtime)
 	if err != nil {
-		return false, err
+		return false, wrapSlsError(err)
+	}
+
+	if a.logStore != "" {
+		var names []*string
+		if res.Body != nil {
+			names = res.Body.Logstores
+		}
+		for _, name := range names {
+			if tea.StringValue(name) == a.logStore {
+				return true, nil
+			}
+		}
+		return false, newBadQueryError("Project %s 下不存在 LogStore %s", a.project, a.logStore)
 	}
 
 	return true, nil
 }
 
+// getCheckTimeout 获取健康检查超时时间, 未配置时默认 10s
+func (a AliCloudSlsDsProvider) getCheckTimeout() int64 {
+	if a.timeout > 0 {
+		return a.timeout
+	}
+	return defaultLogQueryTimeout
+}
+
 func (a AliCloudSlsDsProvider) GetExternalLabels() map[string]interface{} {
 	return a.ExternalLabels
 }
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"errors"
+	"github.com/alibabacloud-go/tea/tea"
+	"testing"
+)
+
+func TestWrapSlsError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unauthorized", err: &tea.SDKError{StatusCode: tea.Int(403), Code: tea.String("Unauthorized")}, want: ErrAuth},
+		{name: "bad query", err: &tea.SDKError{StatusCode: tea.Int(400), Code: tea.String("ParameterInvalid")}, want: ErrBadQuery},
+		{name: "server error", err: &tea.SDKError{StatusCode: tea.Int(500), Code: tea.String("InternalServerError")}, want: ErrUnavailable},
+		{name: "no status code", err: errors.New("dial tcp: i/o timeout"), want: ErrConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := wrapSlsError(tt.err); !errors.Is(err, tt.want) || !errors.Is(err, tt.err) {
+				t.Errorf("wrapSlsError() = %v, want %v wrapping the original error", err, tt.want)
+			}
+		})
+	}
+
+	if err := wrapSlsError(nil); err != nil {
+		t.Errorf("wrapSlsError(nil) = %v", err)
+	}
+}
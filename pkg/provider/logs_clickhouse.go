@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// ClickHouseDsProvider 基于 ClickHouse HTTP 接口查询日志
+type ClickHouseDsProvider struct {
+	url            string
+	auth           models.Auth
+	timeout        int64
+	ExternalLabels map[string]interface{}
+}
+
+const (
+	clickHouseDefaultTimestampField = "timestamp"
+	clickHouseDefaultLimit          = 500
+)
+
+func init() {
+	Register(ClickHouseDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewClickHouseClient(ds)
+	})
+}
+
+func NewClickHouseClient(datasource models.AlertDataSource) (LogsFactoryProvider, error) {
+	return ClickHouseDsProvider{
+		url:            strings.TrimSuffix(datasource.HTTP.URL, "/"),
+		auth:           datasource.Auth,
+		timeout:        datasource.HTTP.Timeout,
+		ExternalLabels: datasource.Labels,
+	}, nil
+}
+
+func (c ClickHouseDsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
+	curTime := time.Now()
+
+	startAt, ok := toUnixSeconds(options.StartAt)
+	if !ok {
+		startAt = tools.ParserDuration(curTime, 30, "m").Unix()
+	}
+
+	endAt, ok := toUnixSeconds(options.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
+	}
+
+	// 查询参数通过 param_<name> 传递, 由 ClickHouse 服务端完成替换, 避免拼接 SQL
+	params := url.Values{}
+	params.Set("default_format", "JSONEachRow")
+	params.Set("param_start", strconv.FormatInt(startAt, 10))
+	params.Set("param_end", strconv.FormatInt(endAt, 10))
+	// 规则中的 SQL 及 Where 条件由用户填写, 以只读模式执行, 禁止 DDL / DML 及修改设置
+	params.Set("readonly", "1")
+
+	timeout := int(options.GetTimeout(c.timeout))
+	var (
+		query string
+		// 条件查询额外执行 count(), 命中数不受 Limit 限制
+		countQuery string
+	)
+	switch options.ClickHouse.QueryType {
+	case models.ClickHouseQueryTypeRawSQL:
+		if options.ClickHouse.RawSQL == "" {
+			return nil, 0, newBadQueryError("RawSQL 为空")
+		}
+		query = options.ClickHouse.RawSQL
+	default:
+		var err error
+		query, countQuery, err = buildClickHouseFieldQuery(options.ClickHouse, params)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	msgs, err := c.execute(query, params, timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// RawSQL 的命中数为返回的行数
+	count := len(msgs)
+	if countQuery != "" && count > 0 {
+		rows, err := c.execute(countQuery, params, timeout)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(rows) == 0 {
+			return nil, 0, fmt.Errorf("ClickHouse - count() 未返回结果")
+		}
+		total, err := coerceEsNumber(rows[0]["total"])
+		if err != nil {
+			return nil, 0, fmt.Errorf("ClickHouse - 解析 count() 结果失败: %s", err.Error())
+		}
+		count = int(total)
+	}
+
+	var data []Logs
+	data = append(data, Logs{
+		ProviderName: ClickHouseDsProviderName,
+		Metric:       getLogsMetric(msgs, options.LabelFields, commonKeyValuePairs),
+		Message:      msgs,
+	})
+
+	return data, count, nil
+}
+
+// buildClickHouseFieldQuery 构建带时间范围过滤的查询语句及统计命中数的 count() 语句, 表名及时间字段以 Identifier 参数传递
+func buildClickHouseFieldQuery(opts ClickHouse, params url.Values) (string, string, error) {
+	if opts.Table == "" {
+		return "", "", newBadQueryError("ClickHouse 表名为空")
+	}
+
+	var table string
+	if db, tb, found := strings.Cut(opts.Table, "."); found {
+		params.Set("param_db", db)
+		params.Set("param_table", tb)
+		table = "{db:Identifier}.{table:Identifier}"
+	} else {
+		params.Set("param_table", opts.Table)
+		table = "{table:Identifier}"
+	}
+
+	timestampField := opts.TimestampField
+	if timestampField == "" {
+		timestampField = clickHouseDefaultTimestampField
+	}
+	params.Set("param_ts", timestampField)
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = clickHouseDefaultLimit
+	}
+	params.Set("param_limit", strconv.Itoa(limit))
+
+	condition := fmt.Sprintf("FROM %s WHERE {ts:Identifier} >= toDateTime({start:UInt32}) AND {ts:Identifier} <= toDateTime({end:UInt32})", table)
+	if opts.Where != "" {
+		condition += fmt.Sprintf(" AND (%s)", opts.Where)
+	}
+
+	query := "SELECT * " + condition + " ORDER BY {ts:Identifier} DESC LIMIT {limit:UInt32}"
+	countQuery := "SELECT count() AS total " + condition
+	return query, countQuery, nil
+}
+
+// execute 执行查询并按行解析 JSONEachRow 格式的结果
+func (c ClickHouseDsProvider) execute(query string, params url.Values, timeout int) ([]map[string]interface{}, error) {
+	requestURL := fmt.Sprintf("%s/?%s", c.url, params.Encode())
+	res, err := tools.Post(c.auth.GetAuthHeader(), requestURL, bytes.NewReader([]byte(query)), timeout)
+	if err != nil {
+		return nil, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, newStatusError(res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var msgs []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("ClickHouse - 解析行失败: %s, 内容: %s", err.Error(), string(line))
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+func (c ClickHouseDsProvider) Check() (bool, error) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = defaultLogQueryTimeout
+	}
+
+	res, err := tools.Post(c.auth.GetAuthHeader(), c.url+"/", bytes.NewReader([]byte("SELECT 1")), int(timeout))
+	if err != nil {
+		return false, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return false, newStatusError(res.StatusCode, "")
+	}
+
+	return true, nil
+}
+
+func (c ClickHouseDsProvider) GetExternalLabels() map[string]interface{} {
+	return c.ExternalLabels
+}
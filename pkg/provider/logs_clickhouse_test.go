@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestClickHouse_Query(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("readonly") != "1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		queries = append(queries, string(b))
+		if strings.Contains(string(b), "count()") {
+			_, _ = w.Write([]byte(`{"total":"1200"}` + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"level":"error","msg":"a"}` + "\n" + `{"level":"error","msg":"b"}` + "\n"))
+	}))
+	defer srv.Close()
+
+	cli, _ := NewClickHouseClient(models.AlertDataSource{HTTP: models.HTTP{URL: srv.URL}})
+	res, count, err := cli.Query(LogQueryOptions{ClickHouse: ClickHouse{Table: "logs.app", Where: "level = 'error'", Limit: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1200 || len(res) != 1 || len(res[0].Message) != 2 {
+		t.Errorf("count -> %d, %+v", count, res)
+	}
+	if len(queries) != 2 || !strings.HasPrefix(queries[1], "SELECT count() AS total FROM {db:Identifier}.{table:Identifier} WHERE") || strings.Contains(queries[1], "LIMIT") {
+		t.Errorf("queries -> %q", queries)
+	}
+
+	// RawSQL 的命中数为返回的行数, 不额外执行 count()
+	queries = nil
+	_, count, err = cli.Query(LogQueryOptions{ClickHouse: ClickHouse{QueryType: models.ClickHouseQueryTypeRawSQL, RawSQL: "SELECT * FROM logs.app"}})
+	if err != nil || count != 2 || len(queries) != 1 {
+		t.Errorf("raw -> %d, %v, %q", count, err, queries)
+	}
+}
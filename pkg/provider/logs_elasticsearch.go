@@ -2,150 +2,838 @@ package provider
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/olivere/elastic/v7"
+	"github.com/zeromicro/go-zero/core/logc"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/tools"
 )
 
 type ElasticSearchDsProvider struct {
 	cli            *elastic.Client
+	httpClient     *http.Client
+	ctx            context.Context
 	url            string
-	username       string
-	password       string
+	auth           models.Auth
+	timeout        int64
+	guard          models.EsQueryGuard
+	mappings       *esMappingCache
 	ExternalLabels map[string]interface{}
 }
 
+func init() {
+	Register(ElasticSearchDsProviderName, func(ctx context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewElasticSearchClient(ctx, ds)
+	})
+}
+
 func NewElasticSearchClient(ctx context.Context, ds models.AlertDataSource) (LogsFactoryProvider, error) {
-	client, err := elastic.NewClient(
-		elastic.SetURL(ds.HTTP.URL),
-		elastic.SetBasicAuth(ds.Auth.User, ds.Auth.Pass),
-		elastic.SetSniff(false),
-	)
+	// 相同数据源复用已建立的客户端, 连接配置变更时自动重建
+	pooled, err := esClients.get(ds)
 	if err != nil {
 		return ElasticSearchDsProvider{}, err
 	}
 
 	return ElasticSearchDsProvider{
-		cli:            client,
+		cli:            pooled.cli,
+		httpClient:     pooled.httpClient,
+		ctx:            ctx,
 		url:            ds.HTTP.URL,
-		username:       ds.Auth.User,
-		password:       ds.Auth.Pass,
+		auth:           ds.Auth,
+		timeout:        ds.HTTP.Timeout,
+		guard:          ds.EsQueryGuard,
+		mappings:       pooled.mappings,
 		ExternalLabels: ds.Labels,
 	}, nil
 }
 
+// newElasticSearchHttpClient 创建带 TLS 配置的 HTTP 客户端, Query 与 Check 共用, SigV4 认证时对请求签名
+func newElasticSearchHttpClient(cfg models.TLS, auth models.Auth) (*http.Client, error) {
+	tlsConfig, err := tools.NewTLSConfig(cfg.CACert, cfg.ClientCert, cfg.ClientKey, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	if auth.AuthType == models.AuthTypeSigV4 {
+		transport, err = newSigV4Transport(transport, auth.SigV4)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// withElasticSearchAuth 根据认证方式设置客户端认证, ApiKey / Bearer 通过请求头传递
+func withElasticSearchAuth(auth models.Auth) elastic.ClientOptionFunc {
+	switch auth.AuthType {
+	case models.AuthTypeApiKey, models.AuthTypeBearer:
+		header := http.Header{}
+		header.Set("Authorization", auth.GetAuthorization())
+		return elastic.SetHeaders(header)
+	case models.AuthTypeSigV4:
+		// 签名由 HTTP Transport 完成, OpenSearch Serverless 不支持根路径请求, 关闭客户端自带的节点健康检查
+		return elastic.SetHealthcheck(false)
+	default:
+		return elastic.SetBasicAuth(auth.User, auth.Pass)
+	}
+}
+
 type esQueryResponse struct {
-	Source map[string]interface{} `json:"_source"`
+	Index     string                 `json:"_index"`
+	Id        string                 `json:"_id"`
+	Source    map[string]interface{} `json:"_source"`
+	Highlight map[string][]string    `json:"highlight"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+const (
+	// 文档所在索引及文档 ID, 便于通知模版拼接 Kibana 文档链接
+	esDocIndexKey = "_index"
+	esDocIdKey    = "_id"
+	// 高亮片段, 字段名 -> 命中片段
+	esDocHighlightKey = "_highlight"
+)
+
+// esSearchTarget 查询的索引及运行时字段, 索引模式下展开的日期索引可能不存在, 需忽略
+type esSearchTarget struct {
+	indices           []string
+	ignoreUnavailable bool
+	runtimeFields     []models.EsRuntimeField
+}
+
+func (t esSearchTarget) String() string {
+	return strings.Join(t.indices, ",")
+}
+
+// recordEsResult 记录查询耗时及分片失败信息至 stats (可为空), 并记录部分失败的查询日志;
+// 跨集群搜索中远程集群开启 skip_unavailable 后不可达时会被跳过, 查询仍返回其余集群的结果
+func recordEsResult(ctx context.Context, target esSearchTarget, res *elastic.SearchResult, stats *QueryStats) {
+	if stats != nil {
+		stats.TookMs += res.TookInMillis
+		if res.Shards != nil {
+			stats.ShardsTotal = max(stats.ShardsTotal, res.Shards.Total)
+			stats.ShardsFailed = max(stats.ShardsFailed, res.Shards.Failed)
+			for _, f := range res.Shards.Failures {
+				if len(stats.Failures) >= queryStatsFailureLimit {
+					break
+				}
+				stats.Failures = append(stats.Failures, fmt.Sprintf("%s: %v", f.Index, f.Reason["reason"]))
+			}
+		}
+		if res.Clusters != nil {
+			stats.SkippedClusters = max(stats.SkippedClusters, res.Clusters.Skipped)
+		}
+	}
+
+	if res.Clusters != nil && res.Clusters.Skipped > 0 {
+		logc.Errorf(ctx, "ElasticSearch 跨集群查询部分集群不可用已跳过, index: %s, skipped: %d/%d", target, res.Clusters.Skipped, res.Clusters.Total)
+	}
+	if res.Shards != nil && res.Shards.Failed > 0 {
+		var reasons []string
+		for _, f := range res.Shards.Failures {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", f.Index, f.Reason["reason"]))
+		}
+		logc.Errorf(ctx, "ElasticSearch 查询部分分片失败, index: %s, failed: %d/%d, reasons: %s", target, res.Shards.Failed, res.Shards.Total, strings.Join(reasons, "; "))
+	}
+}
+
+// search 创建指定索引的查询
+func (e ElasticSearchDsProvider) search(target esSearchTarget) *elastic.SearchService {
+	search := e.cli.Search().Index(target.indices...)
+	if target.ignoreUnavailable {
+		search = search.IgnoreUnavailable(true).AllowNoIndices(true)
+	}
+	return withEsRuntimeFields(search, target.runtimeFields)
 }
 
 func (e ElasticSearchDsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
-	indexName := options.ElasticSearch.GetIndexName()
+	target := esSearchTarget{
+		indices:           options.ElasticSearch.GetIndexNames(options.StartAt, options.EndAt),
+		ignoreUnavailable: options.ElasticSearch.IndexPattern,
+		runtimeFields:     options.ElasticSearch.RuntimeFields,
+	}
+	if len(target.indices) == 0 {
+		return nil, 0, newBadQueryError("索引名称为空")
+	}
+	if err := e.checkQueryGuard(options); err != nil {
+		return nil, 0, err
+	}
 	var query elastic.Query
 
+	// 查询超时或上层 Context 取消时中断请求, 避免慢查询堆积
+	ctx, cancel := context.WithTimeout(e.getContext(), time.Duration(options.GetTimeout(e.timeout))*time.Second)
+	defer cancel()
+
 	switch options.ElasticSearch.QueryType {
 	case models.EsQueryTypeRawJson:
 		if options.ElasticSearch.RawJson == "" {
-			return nil, 0, errors.New("RawJson 为空")
+			return nil, 0, newBadQueryError("RawJson 为空")
 		}
 		query = elastic.NewRawStringQuery(options.ElasticSearch.RawJson)
 	case models.EsQueryTypeField:
-		conditionQuery := elastic.NewBoolQuery()
-		if len(options.ElasticSearch.QueryFilter) > 0 {
-			subQueries := make([]elastic.Query, 0, len(options.ElasticSearch.QueryFilter))
-			for _, filter := range options.ElasticSearch.QueryFilter {
-				var q elastic.Query
-				switch options.ElasticSearch.QueryWildcard {
-				case 0:
-					// 精准匹配
-					q = elastic.NewMatchQuery(filter.Field, filter.Value)
-				case 1:
-					// 模糊匹配
-					q = elastic.NewWildcardQuery(filter.Field, fmt.Sprintf("*%v*", filter.Value))
-				default:
-					return nil, 0, errors.New("undefined QueryWildcard")
-				}
-				subQueries = append(subQueries, q)
-			}
-			switch options.ElasticSearch.QueryFilterCondition {
-			case models.EsFilterConditionOr:
-				// 表示"或"关系，至少有一个子查询需要匹配
-				conditionQuery = conditionQuery.Should(subQueries...).MinimumNumberShouldMatch(1)
-			case models.EsFilterConditionAnd:
-				// 表示"与"关系，所有子查询都必须匹配
-				conditionQuery = conditionQuery.Must(subQueries...)
-			case models.EsFilterConditionNot:
-				// 表示"非"关系，所有子查询都不能匹配
-				conditionQuery = conditionQuery.MustNot(subQueries...)
-			default:
-				return nil, 0, errors.New("undefined QueryFilterCondition")
-			}
+		conditionQuery, err := buildFieldQuery(options)
+		if err != nil {
+			return nil, 0, err
 		}
-		conditionQuery.Must(elastic.NewRangeQuery("@timestamp").Gte(options.StartAt.(string)).Lte(options.EndAt.(string)))
 		query = conditionQuery
+	case models.EsQueryTypeAggregation:
+		conditionQuery, err := buildFieldQuery(options)
+		if err != nil {
+			return nil, 0, err
+		}
+		return e.aggregationQuery(ctx, target, conditionQuery, options.ElasticSearch.GetTimestampField(), options.ElasticSearch.Aggregation, options.Stats)
 	default:
-		return nil, 0, fmt.Errorf("undefined QueryType, type: %s", options.ElasticSearch.QueryType)
+		return nil, 0, newBadQueryError("undefined QueryType, type: %s", options.ElasticSearch.QueryType)
+	}
+
+	if options.ElasticSearch.CountOnly {
+		return e.countQuery(ctx, target, query, options.Stats)
+	}
+
+	maxLogs := options.ElasticSearch.MaxLogs
+	if maxLogs <= 0 && options.ElasticSearch.Size > options.ElasticSearch.GetMaxResultWindow() {
+		if !options.ElasticSearch.AutoPaginate {
+			return nil, 0, fmt.Errorf("%w, %w: size %d 超出 max_result_window %d", ErrBadQuery, ErrResultWindow, options.ElasticSearch.Size, options.ElasticSearch.GetMaxResultWindow())
+		}
+		maxLogs = options.ElasticSearch.Size
 	}
 
-	res, err := e.cli.Search().
-		Index(indexName).
+	if maxLogs > 0 {
+		msgs, total, err := e.pitQuery(ctx, target, query, newEsSort(options.ElasticSearch), newEsHighlight(options.ElasticSearch), maxLogs, options.Stats)
+		if err != nil {
+			return nil, 0, err
+		}
+		return newEsLogs(msgs, total, options)
+	}
+
+	search := e.search(target).
 		Query(query).
+		SortBy(newEsSort(options.ElasticSearch))
+	if options.ElasticSearch.Size > 0 {
+		search = search.Size(options.ElasticSearch.Size)
+	}
+	if highlight := newEsHighlight(options.ElasticSearch); highlight != nil {
+		search = search.Highlight(highlight)
+	}
+
+	res, err := search.
+		TrackTotalHits(true).
 		Pretty(true).
-		Do(context.Background())
+		Do(ctx)
 	if err != nil {
+		err = wrapEsError(err)
+		// 索引实际的 max_result_window 小于配置值时, 开启自动分页则改为分页拉取
+		if errors.Is(err, ErrResultWindow) && options.ElasticSearch.AutoPaginate && options.ElasticSearch.Size > 0 {
+			msgs, total, err := e.pitQuery(ctx, target, query, newEsSort(options.ElasticSearch), newEsHighlight(options.ElasticSearch), options.ElasticSearch.Size, options.Stats)
+			if err != nil {
+				return nil, 0, err
+			}
+			return newEsLogs(msgs, total, options)
+		}
 		return nil, 0, err
 	}
+	recordEsResult(ctx, target, res, options.Stats)
 
-	var response []esQueryResponse
-	marshalHits, err := json.Marshal(res.Hits.Hits)
+	msgs, err := decodeEsHits(res.Hits.Hits)
 	if err != nil {
 		return nil, 0, err
 	}
-	err = json.Unmarshal(marshalHits, &response)
+
+	return newEsLogs(msgs, getEsTotalHits(res), options)
+}
+
+const (
+	// esPitKeepAlive PIT 保持时间
+	esPitKeepAlive = "1m"
+	// esPitPageSize 每页拉取的日志条数
+	esPitPageSize = 1000
+	// esMaxLogsCeiling 单次查询拉取日志条数的硬上限, 避免 OOM
+	esMaxLogsCeiling = 50000
+)
+
+// pitQuery 通过 PIT + search_after 分页拉取日志, 最多拉取 maxLogs 条
+func (e ElasticSearchDsProvider) pitQuery(ctx context.Context, target esSearchTarget, query elastic.Query, sort elastic.Sorter, highlight *elastic.Highlight, maxLogs int, stats *QueryStats) ([]map[string]interface{}, int, error) {
+	if maxLogs > esMaxLogsCeiling {
+		maxLogs = esMaxLogsCeiling
+	}
+
+	pitService := e.cli.OpenPointInTime(target.indices...).KeepAlive(esPitKeepAlive)
+	if target.ignoreUnavailable {
+		pitService = pitService.IgnoreUnavailable(true)
+	}
+	pit, err := pitService.Do(ctx)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, fmt.Errorf("打开 PIT 失败, index: %s, err: %w", target, wrapEsError(err))
 	}
 
+	pitId := pit.Id
+	defer func() {
+		// 无论查询成功与否都需要释放 PIT, 查询 Context 可能已取消, 因此使用独立的 Context
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer closeCancel()
+		if _, err := e.cli.ClosePointInTime(pitId).Do(closeCtx); err != nil {
+			logc.Error(context.Background(), fmt.Sprintf("释放 PIT 失败, index: %s, err: %s", target, err.Error()))
+		}
+	}()
+
 	var (
-		data []Logs
-		msgs []map[string]interface{}
+		total       int
+		msgs        []map[string]interface{}
+		searchAfter []interface{}
 	)
+	for len(msgs) < maxLogs {
+		size := min(esPitPageSize, maxLogs-len(msgs))
+		search := e.cli.Search().
+			Query(query).
+			Size(size).
+			PointInTime(elastic.NewPointInTimeWithKeepAlive(pitId, esPitKeepAlive)).
+			// _shard_doc 作为 search_after 的唯一排序键
+			SortBy(sort, elastic.NewFieldSort("_shard_doc").Asc()).
+			TrackTotalHits(true)
+		if highlight != nil {
+			search = search.Highlight(highlight)
+		}
+		if searchAfter != nil {
+			search = search.SearchAfter(searchAfter...)
+		}
+		// PIT 查询不能指定索引, 运行时字段需单独附加
+		search = withEsRuntimeFields(search, target.runtimeFields)
+
+		res, err := search.Do(ctx)
+		if err != nil {
+			return nil, 0, wrapEsError(err)
+		}
+		recordEsResult(ctx, target, res, stats)
+		if res.PitId != "" {
+			pitId = res.PitId
+		}
+		total = getEsTotalHits(res)
+
+		page, err := decodeEsHits(res.Hits.Hits)
+		if err != nil {
+			return nil, 0, err
+		}
+		msgs = append(msgs, page...)
+
+		if len(res.Hits.Hits) < size {
+			break
+		}
+		searchAfter = res.Hits.Hits[len(res.Hits.Hits)-1].Sort
+	}
+
+	return msgs, total, nil
+}
+
+// countQuery 仅统计命中的文档数, 通过 size 0 + track_total_hits 查询, 不返回文档内容, 同时可获取分片失败信息;
+// 返回一条不含日志内容的 Logs, 评估条件直接比较命中数
+func (e ElasticSearchDsProvider) countQuery(ctx context.Context, target esSearchTarget, query elastic.Query, stats *QueryStats) ([]Logs, int, error) {
+	res, err := e.search(target).
+		Query(query).
+		Size(0).
+		TrackTotalHits(true).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, wrapEsError(err)
+	}
+	recordEsResult(ctx, target, res, stats)
+
+	total := getEsTotalHits(res)
+	return []Logs{newEsAggregationLogs("", nil, nil, int64(total))}, total, nil
+}
+
+// decodeEsHits 解析命中的文档
+func decodeEsHits(hits []*elastic.SearchHit) ([]map[string]interface{}, error) {
+	var response []esQueryResponse
+	marshalHits, err := json.Marshal(hits)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(marshalHits, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []map[string]interface{}
 	for _, v := range response {
+		if v.Source == nil {
+			v.Source = make(map[string]interface{})
+		}
+		v.Source[esDocIndexKey] = v.Index
+		v.Source[esDocIdKey] = v.Id
+		if len(v.Highlight) > 0 {
+			v.Source[esDocHighlightKey] = v.Highlight
+		}
+		mergeEsRuntimeFields(v.Source, v.Fields)
 		msgs = append(msgs, v.Source)
 	}
+	return msgs, nil
+}
+
+// newEsLogs 构建日志查询结果, 配置字段类型声明时先转换字段值
+func newEsLogs(msgs []map[string]interface{}, total int, options LogQueryOptions) ([]Logs, int, error) {
+	if err := coerceEsFields(msgs, options.ElasticSearch.FieldTypes); err != nil {
+		return nil, 0, err
+	}
+
+	// 索引与文档 ID 不参与标签计算, 避免影响告警指纹
+	metric := getLogsMetric(msgs, options.LabelFields, commonKeyValuePairs)
+	delete(metric, esDocIndexKey)
+	delete(metric, esDocIdKey)
+	delete(metric, esDocHighlightKey)
 
+	var data []Logs
 	data = append(data, Logs{
 		ProviderName: ElasticSearchDsProviderName,
-		Metric:       commonKeyValuePairs(msgs),
+		Metric:       metric,
 		Message:      msgs,
 	})
+	return data, total, nil
+}
+
+// getEsTotalHits 获取命中的文档总数
+func getEsTotalHits(res *elastic.SearchResult) int {
+	if res.Hits == nil || res.Hits.TotalHits == nil {
+		return 0
+	}
+	return int(res.Hits.TotalHits.Value)
+}
+
+// newEsSort 构建排序, 未配置排序字段时按时间字段降序
+func newEsSort(es Elasticsearch) elastic.Sorter {
+	if es.SortField == "" {
+		// 部分索引可能不存在时间字段, 指定 unmapped_type 避免查询报错
+		return elastic.NewFieldSort(es.GetTimestampField()).Order(es.SortAscending).UnmappedType("date")
+	}
+	return elastic.NewFieldSort(es.SortField).Order(es.SortAscending)
+}
+
+const (
+	esHighlightDefaultFragmentSize = 100
+	esHighlightDefaultFragments    = 3
+)
+
+// newEsHighlight 构建高亮配置, 未开启时返回 nil
+func newEsHighlight(es Elasticsearch) *elastic.Highlight {
+	if !es.Highlight.Enabled {
+		return nil
+	}
+
+	fragmentSize := es.Highlight.FragmentSize
+	if fragmentSize <= 0 {
+		fragmentSize = esHighlightDefaultFragmentSize
+	}
+	fragments := es.Highlight.Fragments
+	if fragments <= 0 {
+		fragments = esHighlightDefaultFragments
+	}
+
+	fields := es.Highlight.Fields
+	if len(fields) == 0 {
+		// require_field_match 默认开启, * 仅返回命中查询条件的字段
+		fields = []string{"*"}
+	}
+
+	highlight := elastic.NewHighlight().
+		FragmentSize(fragmentSize).
+		NumOfFragments(fragments)
+	for _, field := range fields {
+		highlight = highlight.Fields(elastic.NewHighlighterField(field))
+	}
+	return highlight
+}
+
+// buildFieldQuery 根据过滤条件及查询时间范围构建条件查询
+func buildFieldQuery(options LogQueryOptions) (*elastic.BoolQuery, error) {
+	conditionQuery := elastic.NewBoolQuery()
+	if len(options.ElasticSearch.QueryFilter) > 0 {
+		err := applyFilterGroup(conditionQuery, options.ElasticSearch.QueryFilter, options.ElasticSearch.QueryFilterCondition, options.ElasticSearch.QueryWildcard)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conditionQuery.Must(elastic.NewRangeQuery(options.ElasticSearch.GetTimestampField()).Gte(options.StartAt.(string)).Lte(options.EndAt.(string)))
+	return conditionQuery, nil
+}
+
+// applyFilterGroup 将一组过滤条件按 condition 组合到 boolQuery 中, 嵌套分组递归构建子 BoolQuery
+func applyFilterGroup(boolQuery *elastic.BoolQuery, filters []models.EsQueryFilter, condition models.EsFilterCondition, wildcard int64) error {
+	subQueries := make([]elastic.Query, 0, len(filters))
+	for _, filter := range filters {
+		if len(filter.Filters) > 0 {
+			group := elastic.NewBoolQuery()
+			if err := applyFilterGroup(group, filter.Filters, filter.Condition, wildcard); err != nil {
+				return err
+			}
+			subQueries = append(subQueries, group)
+			continue
+		}
+
+		q, err := buildFilterQuery(filter, wildcard)
+		if err != nil {
+			return err
+		}
+		subQueries = append(subQueries, q)
+	}
+
+	switch condition {
+	case models.EsFilterConditionOr:
+		// 表示"或"关系，至少有一个子查询需要匹配
+		boolQuery.Should(subQueries...).MinimumNumberShouldMatch(1)
+	case models.EsFilterConditionAnd:
+		// 表示"与"关系，所有子查询都必须匹配
+		boolQuery.Must(subQueries...)
+	case models.EsFilterConditionNot:
+		// 表示"非"关系，所有子查询都不能匹配
+		boolQuery.MustNot(subQueries...)
+	default:
+		return newBadQueryError("undefined QueryFilterCondition")
+	}
+	return nil
+}
+
+// buildFilterQuery 根据匹配模式构建单个字段的查询
+func buildFilterQuery(filter models.EsQueryFilter, wildcard int64) (elastic.Query, error) {
+	switch wildcard {
+	case models.EsQueryWildcardMatch:
+		return elastic.NewMatchQuery(filter.Field, filter.Value), nil
+	case models.EsQueryWildcardWildcard:
+		return elastic.NewWildcardQuery(filter.Field, fmt.Sprintf("*%v*", filter.Value)), nil
+	case models.EsQueryWildcardRegexp:
+		// ES 使用 Lucene 正则语法 (始终整体匹配, 支持 <1-100>、& 等), 与 RE2 不兼容, 不在本地校验, 语法错误由 ES 返回
+		return elastic.NewRegexpQuery(filter.Field, filter.Value), nil
+	case models.EsQueryWildcardPhrase:
+		return elastic.NewMatchPhraseQuery(filter.Field, filter.Value), nil
+	default:
+		return nil, newBadQueryError("undefined QueryWildcard")
+	}
+}
+
+const (
+	// esAggregationBucketName 分桶聚合名称
+	esAggregationBucketName = "buckets"
+	// esAggregationValueName 指标聚合名称
+	esAggregationValueName = "value"
+	// esAggregationDefaultSize 默认分桶数量
+	esAggregationDefaultSize = 10
+)
+
+// aggregationQuery 聚合查询, 每个分桶对应一条 Logs, Metric 为分桶 Key, Message 为聚合值
+func (e ElasticSearchDsProvider) aggregationQuery(ctx context.Context, target esSearchTarget, query elastic.Query, timestampField string, agg models.EsAggregation, stats *QueryStats) ([]Logs, int, error) {
+	valueAggs, err := newEsMetricAggregations(agg)
+	if err != nil {
+		return nil, 0, err
+	}
+	if agg.Composite {
+		return e.compositeAggregationQuery(ctx, target, query, agg, valueAggs, stats)
+	}
+
+	size := agg.Size
+	if size <= 0 {
+		size = esAggregationDefaultSize
+	}
+
+	search := e.search(target).
+		Query(query).
+		Size(0).
+		TrackTotalHits(true)
+
+	switch {
+	case agg.BucketField != "":
+		bucketAgg := elastic.NewTermsAggregation().Field(agg.BucketField).Size(size)
+		for name, valueAgg := range valueAggs {
+			bucketAgg = bucketAgg.SubAggregation(name, valueAgg)
+		}
+		search = search.Aggregation(esAggregationBucketName, bucketAgg)
+	case agg.Interval != "":
+		bucketAgg := elastic.NewDateHistogramAggregation().Field(timestampField).FixedInterval(agg.Interval).MinDocCount(1)
+		for name, valueAgg := range valueAggs {
+			bucketAgg = bucketAgg.SubAggregation(name, valueAgg)
+		}
+		search = search.Aggregation(esAggregationBucketName, bucketAgg)
+	default:
+		for name, valueAgg := range valueAggs {
+			search = search.Aggregation(name, valueAgg)
+		}
+	}
+
+	res, err := search.Do(ctx)
+	if err != nil {
+		return nil, 0, wrapEsError(err)
+	}
+	recordEsResult(ctx, target, res, stats)
+
+	var data []Logs
+	switch {
+	case agg.BucketField != "":
+		items, ok := res.Aggregations.Terms(esAggregationBucketName)
+		if !ok {
+			return nil, 0, fmt.Errorf("聚合结果中不存在分桶数据, bucketField: %s", agg.BucketField)
+		}
+		for _, bucket := range items.Buckets {
+			key := bucket.Key
+			if bucket.KeyAsString != nil {
+				key = *bucket.KeyAsString
+			}
+			data = append(data, newEsAggregationLogs(agg.BucketField, key, getEsAggregationValues(agg, bucket.Aggregations, bucket.DocCount), bucket.DocCount))
+		}
+	case agg.Interval != "":
+		items, ok := res.Aggregations.DateHistogram(esAggregationBucketName)
+		if !ok {
+			return nil, 0, fmt.Errorf("聚合结果中不存在分桶数据, interval: %s", agg.Interval)
+		}
+		for _, bucket := range items.Buckets {
+			var key interface{} = bucket.Key
+			if bucket.KeyAsString != nil {
+				key = *bucket.KeyAsString
+			}
+			data = append(data, newEsAggregationLogs(timestampField, key, getEsAggregationValues(agg, bucket.Aggregations, bucket.DocCount), bucket.DocCount))
+		}
+	default:
+		docCount := int64(getEsTotalHits(res))
+		data = append(data, newEsAggregationLogs("", nil, getEsAggregationValues(agg, res.Aggregations, docCount), docCount))
+	}
+
+	return data, len(data), nil
+}
+
+// newEsValueAggregation 根据聚合类型创建指标聚合, count 类型及未指定类型时直接使用分桶的 doc_count
+func newEsValueAggregation(agg models.EsAggregation) (elastic.Aggregation, error) {
+	if agg.Type == models.EsAggregationTypeCount || agg.Type == "" {
+		return nil, nil
+	}
+	if agg.Field == "" {
+		return nil, newBadQueryError("聚合字段为空, type: %s", agg.Type)
+	}
+
+	switch agg.Type {
+	case models.EsAggregationTypeAvg:
+		return elastic.NewAvgAggregation().Field(agg.Field), nil
+	case models.EsAggregationTypeSum:
+		return elastic.NewSumAggregation().Field(agg.Field), nil
+	case models.EsAggregationTypeMax:
+		return elastic.NewMaxAggregation().Field(agg.Field), nil
+	case models.EsAggregationTypeMin:
+		return elastic.NewMinAggregation().Field(agg.Field), nil
+	default:
+		return nil, newBadQueryError("undefined AggregationType, type: %s", agg.Type)
+	}
+}
 
-	return data, len(response), nil
+// newEsMetricAggregations 创建指标聚合, 未配置多指标时仅包含名为 value 的单个指标
+func newEsMetricAggregations(agg models.EsAggregation) (map[string]elastic.Aggregation, error) {
+	aggs := make(map[string]elastic.Aggregation)
+	if len(agg.Metrics) == 0 {
+		valueAgg, err := newEsValueAggregation(agg)
+		if err != nil {
+			return nil, err
+		}
+		if valueAgg != nil {
+			aggs[esAggregationValueName] = valueAgg
+		}
+		return aggs, nil
+	}
+
+	names := make(map[string]struct{}, len(agg.Metrics))
+	for _, metric := range agg.Metrics {
+		if metric.Name == "" || metric.Name == esAggregationValueName || metric.Name == "doc_count" || metric.Name == agg.BucketField || slices.Contains(agg.BucketFields, metric.Name) {
+			return nil, newBadQueryError("指标名称为空或与保留字段冲突, name: %s", metric.Name)
+		}
+		if _, exists := names[metric.Name]; exists {
+			return nil, newBadQueryError("指标名称重复, name: %s", metric.Name)
+		}
+		names[metric.Name] = struct{}{}
+		if metric.Type != models.EsAggregationTypeCount && metric.Type != "" && metric.Field == "" {
+			return nil, newBadQueryError("聚合字段为空, name: %s", metric.Name)
+		}
+
+		var (
+			metricAgg elastic.Aggregation
+			err       error
+		)
+		switch metric.Type {
+		case models.EsAggregationTypeValueCount:
+			metricAgg = elastic.NewValueCountAggregation().Field(metric.Field)
+		case models.EsAggregationTypeCardinality:
+			metricAgg = elastic.NewCardinalityAggregation().Field(metric.Field)
+		case models.EsAggregationTypePercentiles:
+			if metric.Percent <= 0 || metric.Percent >= 100 {
+				return nil, newBadQueryError("百分位需在 0~100 之间, name: %s", metric.Name)
+			}
+			metricAgg = elastic.NewPercentilesAggregation().Field(metric.Field).Percentiles(metric.Percent)
+		default:
+			metricAgg, err = newEsValueAggregation(models.EsAggregation{Type: metric.Type, Field: metric.Field})
+		}
+		if err != nil {
+			return nil, err
+		}
+		// count 类型直接使用分桶的 doc_count
+		if metricAgg != nil {
+			aggs[metric.Name] = metricAgg
+		}
+	}
+
+	return aggs, nil
+}
+
+// getEsAggregationValues 获取分桶的全部指标值, 多指标时 value 取第一个指标的值
+func getEsAggregationValues(agg models.EsAggregation, aggs elastic.Aggregations, docCount int64) map[string]float64 {
+	if len(agg.Metrics) == 0 {
+		return map[string]float64{esAggregationValueName: getEsAggregationValue(agg, aggs, docCount)}
+	}
+
+	values := make(map[string]float64, len(agg.Metrics)+1)
+	for i, metric := range agg.Metrics {
+		value := getEsMetricValue(metric, aggs, docCount)
+		values[metric.Name] = value
+		if i == 0 {
+			values[esAggregationValueName] = value
+		}
+	}
+	return values
+}
+
+// getEsMetricValue 获取多指标聚合中单个指标的值
+func getEsMetricValue(metric models.EsAggregationMetric, aggs elastic.Aggregations, docCount int64) float64 {
+	var (
+		value *elastic.AggregationValueMetric
+		ok    bool
+	)
+	switch metric.Type {
+	case models.EsAggregationTypeAvg:
+		value, ok = aggs.Avg(metric.Name)
+	case models.EsAggregationTypeSum:
+		value, ok = aggs.Sum(metric.Name)
+	case models.EsAggregationTypeMax:
+		value, ok = aggs.Max(metric.Name)
+	case models.EsAggregationTypeMin:
+		value, ok = aggs.Min(metric.Name)
+	case models.EsAggregationTypeValueCount:
+		value, ok = aggs.ValueCount(metric.Name)
+	case models.EsAggregationTypeCardinality:
+		value, ok = aggs.Cardinality(metric.Name)
+	case models.EsAggregationTypePercentiles:
+		percentiles, found := aggs.Percentiles(metric.Name)
+		if !found {
+			return 0
+		}
+		// 只请求了一个百分位, 取唯一的结果
+		for _, v := range percentiles.Values {
+			return v
+		}
+		return 0
+	default:
+		return float64(docCount)
+	}
+
+	if !ok || value.Value == nil {
+		return 0
+	}
+	return *value.Value
+}
+
+// getEsAggregationValue 获取分桶的聚合值
+func getEsAggregationValue(agg models.EsAggregation, aggs elastic.Aggregations, docCount int64) float64 {
+	var (
+		metric *elastic.AggregationValueMetric
+		ok     bool
+	)
+	switch agg.Type {
+	case models.EsAggregationTypeAvg:
+		metric, ok = aggs.Avg(esAggregationValueName)
+	case models.EsAggregationTypeSum:
+		metric, ok = aggs.Sum(esAggregationValueName)
+	case models.EsAggregationTypeMax:
+		metric, ok = aggs.Max(esAggregationValueName)
+	case models.EsAggregationTypeMin:
+		metric, ok = aggs.Min(esAggregationValueName)
+	default:
+		return float64(docCount)
+	}
+
+	if !ok || metric.Value == nil {
+		return 0
+	}
+	return *metric.Value
+}
+
+func newEsAggregationLogs(bucketField string, bucketKey interface{}, values map[string]float64, docCount int64) Logs {
+	metric := map[string]interface{}{}
+	msg := map[string]interface{}{
+		"doc_count": docCount,
+	}
+	for name, value := range values {
+		msg[name] = value
+	}
+	if bucketField != "" {
+		metric[bucketField] = bucketKey
+		msg[bucketField] = bucketKey
+	}
+
+	return Logs{
+		ProviderName: ElasticSearchDsProviderName,
+		Metric:       metric,
+		Message:      []map[string]interface{}{msg},
+	}
 }
 
 func (e ElasticSearchDsProvider) Check() (bool, error) {
-	header := make(map[string]string)
-	url := fmt.Sprintf("%s/_cat/health", e.url)
-	if e.username != "" {
-		auth := e.username + ":" + e.password
-		basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-		header["Authorization"] = basicAuth
-		url = fmt.Sprintf("%s/_cat/health", e.url)
-	}
-	res, err := tools.Get(header, url, 10)
+	ctx, cancel := context.WithTimeout(e.getContext(), time.Duration(e.getCheckTimeout())*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+e.getCheckPath(), nil)
 	if err != nil {
 		return false, err
 	}
+	for k, v := range e.auth.GetAuthHeader() {
+		request.Header.Set(k, v)
+	}
+
+	res, err := e.httpClient.Do(request)
+	if err != nil {
+		return false, newConnectionError(err)
+	}
+	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return false, fmt.Errorf("状态码非200, 当前: %d", res.StatusCode)
+	if res.StatusCode != http.StatusOK {
+		return false, newStatusError(res.StatusCode, "")
 	}
 	return true, nil
 }
 
+func (e ElasticSearchDsProvider) getContext() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// getCheckPath 获取健康检查的接口, OpenSearch Serverless 不提供集群健康接口, 改为检查索引列表
+func (e ElasticSearchDsProvider) getCheckPath() string {
+	if e.auth.AuthType == models.AuthTypeSigV4 && e.auth.SigV4.GetService() == models.SigV4ServiceOpenSearchServerless {
+		return "/_cat/indices"
+	}
+	return "/_cat/health"
+}
+
+// getCheckTimeout 获取健康检查超时时间, 未配置时默认 10s
+func (e ElasticSearchDsProvider) getCheckTimeout() int64 {
+	if e.timeout > 0 {
+		return e.timeout
+	}
+	return defaultLogQueryTimeout
+}
+
 func (e ElasticSearchDsProvider) GetExternalLabels() map[string]interface{} {
 	return e.ExternalLabels
 }
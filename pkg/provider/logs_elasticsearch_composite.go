@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"watchAlert/internal/models"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+const (
+	// esCompositePageSize 组合聚合每页拉取的分桶数
+	esCompositePageSize = 1000
+	// esCompositeDefaultMaxBuckets 组合聚合默认的分桶数上限
+	esCompositeDefaultMaxBuckets = 10000
+	// esCompositeMaxBucketsCeiling 组合聚合分桶数的硬上限, 避免 OOM
+	esCompositeMaxBucketsCeiling = 100000
+)
+
+func getEsCompositeMaxBuckets(agg models.EsAggregation) int {
+	if agg.MaxBuckets <= 0 {
+		return esCompositeDefaultMaxBuckets
+	}
+	return min(agg.MaxBuckets, esCompositeMaxBucketsCeiling)
+}
+
+// compositeAggregationQuery 组合聚合查询, 按 after_key 翻页拉取全部分桶, 每个分桶对应一条 Logs, Metric 为各分桶字段的值;
+// 分桶数达到上限时停止翻页, 记录至查询统计, 评估结果仅包含已拉取的分桶
+func (e ElasticSearchDsProvider) compositeAggregationQuery(ctx context.Context, target esSearchTarget, query elastic.Query, agg models.EsAggregation, valueAggs map[string]elastic.Aggregation, stats *QueryStats) ([]Logs, int, error) {
+	fields := agg.GetCompositeFields()
+	if len(fields) == 0 {
+		return nil, 0, newBadQueryError("组合聚合的分桶字段为空")
+	}
+
+	sources := make([]elastic.CompositeAggregationValuesSource, 0, len(fields))
+	for _, field := range fields {
+		sources = append(sources, elastic.NewCompositeAggregationTermsValuesSource(field).Field(field))
+	}
+
+	var (
+		data       []Logs
+		after      map[string]interface{}
+		maxBuckets = getEsCompositeMaxBuckets(agg)
+		truncated  bool
+	)
+	for {
+		// 多请求一个分桶, 用于判断是否超出上限
+		remaining := maxBuckets - len(data)
+		pageSize := min(esCompositePageSize, remaining+1)
+
+		compositeAgg := elastic.NewCompositeAggregation().Size(pageSize).Sources(sources...)
+		for name, valueAgg := range valueAggs {
+			compositeAgg = compositeAgg.SubAggregation(name, valueAgg)
+		}
+		if after != nil {
+			compositeAgg = compositeAgg.AggregateAfter(after)
+		}
+
+		res, err := e.search(target).
+			Query(query).
+			Size(0).
+			Aggregation(esAggregationBucketName, compositeAgg).
+			Do(ctx)
+		if err != nil {
+			return nil, 0, wrapEsError(err)
+		}
+		recordEsResult(ctx, target, res, stats)
+
+		items, ok := res.Aggregations.Composite(esAggregationBucketName)
+		if !ok {
+			return nil, 0, fmt.Errorf("聚合结果中不存在分桶数据, bucketFields: %v", fields)
+		}
+
+		buckets := items.Buckets
+		if len(buckets) > remaining {
+			buckets, truncated = buckets[:remaining], true
+		}
+		for _, bucket := range buckets {
+			data = append(data, newEsCompositeLogs(bucket, getEsAggregationValues(agg, bucket.Aggregations, bucket.DocCount)))
+		}
+
+		if truncated || len(items.Buckets) < pageSize || len(items.AfterKey) == 0 {
+			break
+		}
+		after = items.AfterKey
+	}
+
+	if truncated {
+		if stats != nil {
+			stats.BucketLimit = maxBuckets
+		}
+		logc.Errorf(ctx, "ElasticSearch 组合聚合的分桶数超出上限, 仅返回前 %d 个分桶, index: %s, bucketFields: %v", maxBuckets, target, fields)
+	}
+
+	return data, len(data), nil
+}
+
+func newEsCompositeLogs(bucket *elastic.AggregationBucketCompositeItem, values map[string]float64) Logs {
+	logs := newEsAggregationLogs("", nil, values, bucket.DocCount)
+	for field, key := range bucket.Key {
+		logs.Metric[field] = key
+		logs.Message[0][field] = key
+	}
+	return logs
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+)
+
+// coerceEsFields 按字段类型声明转换文档中的字段值, 字段不存在或为 null 时跳过, 无法转换时返回错误, 避免数值比较时静默得到零值
+func coerceEsFields(msgs []map[string]interface{}, fieldTypes []models.EsFieldType) error {
+	if len(fieldTypes) == 0 {
+		return nil
+	}
+
+	for _, msg := range msgs {
+		for _, ft := range fieldTypes {
+			container, key, ok := lookupEsField(msg, ft.Field)
+			if !ok || container[key] == nil {
+				continue
+			}
+
+			value, err := coerceEsValue(container[key], ft.Type)
+			if err != nil {
+				return newBadQueryError("文档 %v/%v 的字段 %s 无法转换为 %s: %s", msg[esDocIndexKey], msg[esDocIdKey], ft.Field, ft.Type, err.Error())
+			}
+			container[key] = value
+		}
+	}
+	return nil
+}
+
+// lookupEsField 查找字段所在的对象及字段名, 优先匹配包含 . 的扁平字段名, 其次按 . 逐级查找嵌套字段
+func lookupEsField(doc map[string]interface{}, field string) (map[string]interface{}, string, bool) {
+	if _, ok := doc[field]; ok {
+		return doc, field, true
+	}
+
+	parts := strings.Split(field, ".")
+	container := doc
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := container[part].(map[string]interface{})
+		if !ok {
+			return nil, "", false
+		}
+		container = next
+	}
+
+	key := parts[len(parts)-1]
+	if _, ok := container[key]; !ok {
+		return nil, "", false
+	}
+	return container, key, true
+}
+
+func coerceEsValue(value interface{}, kind models.EsFieldTypeKind) (interface{}, error) {
+	switch kind {
+	case models.EsFieldTypeNumber:
+		return coerceEsNumber(value)
+	case models.EsFieldTypeTime:
+		return coerceEsTime(value)
+	case models.EsFieldTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		default:
+			return nil, fmt.Errorf("不支持的值类型 %T", value)
+		}
+	default:
+		return nil, fmt.Errorf("未知的字段类型 %q", kind)
+	}
+}
+
+func coerceEsNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("%q 不是有效的数值", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("不支持的值类型 %T", value)
+	}
+}
+
+// coerceEsTime 将时间戳或时间字符串转换为 RFC3339 格式的 UTC 时间, 时间戳按数量级判断单位
+func coerceEsTime(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		s = strings.TrimSpace(s)
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return "", fmt.Errorf("%q 不是时间戳或 RFC3339 时间", s)
+		}
+	}
+
+	epoch, err := coerceEsNumber(value)
+	if err != nil {
+		return "", err
+	}
+	if epoch < 0 {
+		return "", fmt.Errorf("时间戳 %v 无效", value)
+	}
+
+	var t time.Time
+	switch {
+	case epoch >= 1e17:
+		t = time.Unix(0, int64(epoch))
+	case epoch >= 1e14:
+		t = time.UnixMicro(int64(epoch))
+	case epoch >= 1e11:
+		t = time.UnixMilli(int64(epoch))
+	default:
+		sec, frac := math.Modf(epoch)
+		t = time.Unix(int64(sec), int64(frac*1e9))
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
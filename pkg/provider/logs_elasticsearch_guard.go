@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"watchAlert/internal/models"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+// checkQueryGuard 按数据源的查询成本限制校验查询, warn 模式或规则跳过限制时仅记录日志
+func (e ElasticSearchDsProvider) checkQueryGuard(options LogQueryOptions) error {
+	if !e.guard.Enabled {
+		return nil
+	}
+
+	var analyzed func(field string) bool
+	if e.guard.DenyLeadingWildcard {
+		analyzed = newEsAnalyzedFieldFunc(e.fieldTypes(options.ElasticSearch.Index))
+	}
+
+	violations := esQueryGuardViolations(options, e.guard, analyzed)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	reason := strings.Join(violations, "; ")
+	switch {
+	case options.ElasticSearch.BypassQueryGuard && e.guard.AllowBypass:
+		logc.Infof(e.getContext(), "ElasticSearch 查询超出成本限制, 规则已跳过限制, index: %s, %s", options.ElasticSearch.Index, reason)
+		return nil
+	case e.guard.GetMode() == models.EsQueryGuardModeWarn:
+		logc.Errorf(e.getContext(), "ElasticSearch 查询超出成本限制, index: %s, %s", options.ElasticSearch.Index, reason)
+		return nil
+	default:
+		return fmt.Errorf("%w, %w: %s", ErrBadQuery, ErrQueryTooExpensive, reason)
+	}
+}
+
+// esQueryGuardViolations 获取查询超出的成本限制项, analyzed 判断字段是否为分词字段
+func esQueryGuardViolations(options LogQueryOptions, guard models.EsQueryGuard, analyzed func(field string) bool) []string {
+	var violations []string
+	es := options.ElasticSearch
+
+	if guard.MaxScope > 0 {
+		start, okStart := toUnixSeconds(options.StartAt)
+		end, okEnd := toUnixSeconds(options.EndAt)
+		if okStart && okEnd && end-start > guard.MaxScope*60 {
+			violations = append(violations, fmt.Sprintf("查询时间范围 %d 分钟超出上限 %d 分钟", (end-start)/60, guard.MaxScope))
+		}
+	}
+
+	if guard.MaxSize > 0 && !es.CountOnly && es.QueryType != models.EsQueryTypeAggregation {
+		size := es.Size
+		if es.MaxLogs > 0 {
+			size = min(es.MaxLogs, esMaxLogsCeiling)
+		}
+		if size > guard.MaxSize {
+			violations = append(violations, fmt.Sprintf("返回条数 %d 超出上限 %d", size, guard.MaxSize))
+		}
+	}
+
+	if guard.DenyLeadingWildcard {
+		var fields []string
+		switch es.QueryType {
+		case models.EsQueryTypeRawJson:
+			fields = rawJsonLeadingWildcardFields(es.RawJson, analyzed)
+		default:
+			fields = filterLeadingWildcardFields(es.QueryFilter, es.QueryWildcard, analyzed)
+		}
+		if len(fields) > 0 {
+			violations = append(violations, fmt.Sprintf("分词字段 %s 使用了前导通配符", strings.Join(fields, ", ")))
+		}
+	}
+
+	return violations
+}
+
+// newEsAnalyzedFieldFunc 按索引映射中的字段类型判断是否为分词字段, 字段名包含通配符时任一匹配的字段为分词字段即视为分词字段;
+// 映射中不存在的字段不会命中文档, 不视为分词字段; 无法获取映射 (types 为 nil) 时以 .keyword 结尾的字段视为 keyword 字段, 其余视为分词字段
+func newEsAnalyzedFieldFunc(types map[string]string) func(field string) bool {
+	if types == nil {
+		return func(field string) bool {
+			return !strings.HasSuffix(field, ".keyword")
+		}
+	}
+
+	return func(field string) bool {
+		if !strings.ContainsAny(field, "*?") {
+			return isEsAnalyzedType(types[field])
+		}
+		for name, typ := range types {
+			if matched, _ := path.Match(field, name); matched && isEsAnalyzedType(typ) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func isEsAnalyzedType(typ string) bool {
+	return typ == "text" || typ == "match_only_text"
+}
+
+func hasLeadingWildcard(pattern string) bool {
+	return strings.HasPrefix(pattern, "*") || strings.HasPrefix(pattern, "?")
+}
+
+func hasLeadingRegexpWildcard(pattern string) bool {
+	return strings.HasPrefix(pattern, ".*") || strings.HasPrefix(pattern, ".+")
+}
+
+// filterLeadingWildcardFields 条件查询中使用前导通配符的分词字段, 模糊匹配按 *value* 构建, 始终包含前导通配符
+func filterLeadingWildcardFields(filters []models.EsQueryFilter, wildcard int64, analyzed func(field string) bool) []string {
+	var fields []string
+	for _, filter := range filters {
+		if len(filter.Filters) > 0 {
+			fields = append(fields, filterLeadingWildcardFields(filter.Filters, wildcard, analyzed)...)
+			continue
+		}
+		if !analyzed(filter.Field) {
+			continue
+		}
+		switch wildcard {
+		case models.EsQueryWildcardWildcard:
+			fields = append(fields, filter.Field)
+		case models.EsQueryWildcardRegexp:
+			if hasLeadingRegexpWildcard(filter.Value) {
+				fields = append(fields, filter.Field)
+			}
+		}
+	}
+	return fields
+}
+
+// rawJsonLeadingWildcardFields RawJson 查询中 wildcard、regexp 及 query_string 查询使用前导通配符的分词字段
+func rawJsonLeadingWildcardFields(raw string, analyzed func(field string) bool) []string {
+	var query interface{}
+	if err := json.Unmarshal([]byte(raw), &query); err != nil {
+		return nil
+	}
+
+	var fields []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch node := v.(type) {
+		case []interface{}:
+			for _, item := range node {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for key, value := range node {
+				switch key {
+				case "wildcard":
+					fields = append(fields, termLeadingWildcardFields(value, hasLeadingWildcard, analyzed)...)
+				case "regexp":
+					fields = append(fields, termLeadingWildcardFields(value, hasLeadingRegexpWildcard, analyzed)...)
+				case "query_string":
+					fields = append(fields, queryStringLeadingWildcardFields(value, analyzed)...)
+				default:
+					walk(value)
+				}
+			}
+		}
+	}
+	walk(query)
+	return fields
+}
+
+// termLeadingWildcardFields 解析 {"field": "pattern"} 或 {"field": {"value": "pattern"}} 形式的查询
+func termLeadingWildcardFields(v interface{}, leading func(string) bool, analyzed func(field string) bool) []string {
+	terms, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	for field, value := range terms {
+		pattern, ok := value.(string)
+		if !ok {
+			if params, isMap := value.(map[string]interface{}); isMap {
+				pattern, _ = params["value"].(string)
+				if pattern == "" {
+					pattern, _ = params["wildcard"].(string)
+				}
+			}
+		}
+		if analyzed(field) && leading(pattern) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// queryStringLeadingWildcardFields 检查 query_string 中以通配符开头的词项, 关闭 allow_leading_wildcard 时由 ES 拒绝, 不再检查
+func queryStringLeadingWildcardFields(v interface{}, analyzed func(field string) bool) []string {
+	params, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if allow, ok := params["allow_leading_wildcard"].(bool); ok && !allow {
+		return nil
+	}
+	query, _ := params["query"].(string)
+	defaultField, _ := params["default_field"].(string)
+	if defaultField == "" {
+		defaultField = "*"
+	}
+
+	var fields []string
+	for _, token := range strings.Fields(query) {
+		token = strings.TrimLeft(token, "(+-!")
+		field, pattern := defaultField, token
+		if i := strings.Index(token, ":"); i > 0 {
+			field, pattern = token[:i], token[i+1:]
+		}
+		if analyzed(field) && hasLeadingWildcard(pattern) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	// 配置变更或数据源删除后, 旧客户端可能仍被进行中的查询使用, 延迟释放
+	esClientRetireDelay = 5 * time.Minute
+	// 未保存的数据源 (如连通性测试) 按 URL 复用, 空闲超过该时长后释放
+	esClientIdleTTL = 10 * time.Minute
+)
+
+// esClientPool ElasticSearch 客户端复用池, 避免每次查询或健康检查都新建客户端及 TCP 连接
+type esClientPool struct {
+	clients map[string]esPooledClient
+	mux     sync.Mutex
+	// 旧客户端延迟释放的等待时长, 测试时可调整
+	retireDelay time.Duration
+}
+
+type esPooledClient struct {
+	// 数据源连接配置的指纹, 配置变更后重建客户端
+	fingerprint string
+	cli         *elastic.Client
+	httpClient  *http.Client
+	mappings    *esMappingCache
+	// 按 URL 复用的客户端, 空闲超时后释放
+	byURL    bool
+	lastUsed time.Time
+}
+
+var esClients = &esClientPool{
+	clients:     make(map[string]esPooledClient),
+	retireDelay: esClientRetireDelay,
+}
+
+// getEsClientKey 优先使用数据源 ID, 未保存的数据源 (如连通性测试) 使用 URL
+func getEsClientKey(ds models.AlertDataSource) string {
+	if ds.Id != "" {
+		return ds.Id
+	}
+	return ds.HTTP.URL
+}
+
+// getEsClientFingerprint 计算影响连接的配置指纹
+func getEsClientFingerprint(ds models.AlertDataSource) string {
+	h := md5.New()
+	h.Write([]byte(tools.JsonMarshal(ds.HTTP)))
+	h.Write([]byte(tools.JsonMarshal(ds.Auth)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get 获取可复用的客户端, 不存在或配置已变更时新建; 新建客户端需要请求 ES, 在锁外执行, 写入前再次检查避免覆盖并发新建的客户端
+func (p *esClientPool) get(ds models.AlertDataSource) (esPooledClient, error) {
+	key := getEsClientKey(ds)
+	fingerprint := getEsClientFingerprint(ds)
+
+	if c, ok := p.lookup(key, fingerprint); ok {
+		return c, nil
+	}
+
+	c, err := newEsPooledClient(ds, fingerprint)
+	if err != nil {
+		return esPooledClient{}, err
+	}
+	c.byURL = ds.Id == ""
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if existing, exists := p.clients[key]; exists {
+		if existing.fingerprint == fingerprint {
+			c.close()
+			existing.lastUsed = time.Now()
+			p.clients[key] = existing
+			return existing, nil
+		}
+		p.retire(existing)
+	}
+	c.lastUsed = time.Now()
+	p.clients[key] = c
+
+	return c, nil
+}
+
+// lookup 获取配置未变更的客户端, 同时释放空闲超时的 URL 客户端
+func (p *esClientPool) lookup(key, fingerprint string) (esPooledClient, bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	now := time.Now()
+	for k, c := range p.clients {
+		if c.byURL && k != key && now.Sub(c.lastUsed) > esClientIdleTTL {
+			c.close()
+			delete(p.clients, k)
+		}
+	}
+
+	c, exists := p.clients[key]
+	if !exists || c.fingerprint != fingerprint {
+		return esPooledClient{}, false
+	}
+	c.lastUsed = now
+	p.clients[key] = c
+
+	return c, true
+}
+
+// remove 移除客户端, 进行中的查询结束后释放
+func (p *esClientPool) remove(key string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if c, exists := p.clients[key]; exists {
+		p.retire(c)
+		delete(p.clients, key)
+	}
+}
+
+// closeAll 服务退出时立即释放所有客户端
+func (p *esClientPool) closeAll() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for key, c := range p.clients {
+		c.close()
+		delete(p.clients, key)
+	}
+}
+
+// retire 延迟释放已被替换或移除的客户端
+func (p *esClientPool) retire(c esPooledClient) {
+	time.AfterFunc(p.retireDelay, c.close)
+}
+
+func newEsPooledClient(ds models.AlertDataSource, fingerprint string) (esPooledClient, error) {
+	httpClient, err := newElasticSearchHttpClient(ds.HTTP.TLS, ds.Auth)
+	if err != nil {
+		return esPooledClient{}, err
+	}
+
+	client, err := elastic.NewClient(
+		elastic.SetURL(ds.HTTP.URL),
+		elastic.SetSniff(false),
+		elastic.SetHttpClient(httpClient),
+		withElasticSearchAuth(ds.Auth),
+	)
+	if err != nil {
+		httpClient.CloseIdleConnections()
+		return esPooledClient{}, err
+	}
+
+	return esPooledClient{
+		fingerprint: fingerprint,
+		cli:         client,
+		httpClient:  httpClient,
+		mappings:    newEsMappingCache(),
+	}, nil
+}
+
+func (c esPooledClient) close() {
+	c.cli.Stop()
+	c.httpClient.CloseIdleConnections()
+}
+
+// CloseElasticSearchClient 数据源删除时释放对应的 ElasticSearch 客户端
+func CloseElasticSearchClient(datasourceId string) {
+	esClients.remove(datasourceId)
+}
+
+// CloseAllElasticSearchClients 服务退出时释放所有 ElasticSearch 客户端
+func CloseAllElasticSearchClients() {
+	esClients.closeAll()
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"github.com/olivere/elastic/v7"
+	"watchAlert/internal/models"
+)
+
+// newEsRuntimeMappings 构建运行时字段的 runtime_mappings, 未配置时返回 nil
+func newEsRuntimeMappings(fields []models.EsRuntimeField) elastic.RuntimeMappings {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	mappings := make(elastic.RuntimeMappings, len(fields))
+	for _, f := range fields {
+		mappings[f.Name] = map[string]interface{}{
+			"type": f.GetType(),
+			"script": map[string]interface{}{
+				"source": f.Script,
+			},
+		}
+	}
+	return mappings
+}
+
+// withEsRuntimeFields 为查询附加运行时字段, 运行时字段不在 _source 中, 通过 docvalue_fields 返回计算结果
+func withEsRuntimeFields(search *elastic.SearchService, fields []models.EsRuntimeField) *elastic.SearchService {
+	if len(fields) == 0 {
+		return search
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return search.RuntimeMappings(newEsRuntimeMappings(fields)).DocvalueFields(names...)
+}
+
+// mergeEsRuntimeFields 将 fields 中返回的运行时字段值合并至文档, 单值字段展开为标量, 便于评估条件直接引用
+func mergeEsRuntimeFields(doc map[string]interface{}, fields map[string]interface{}) {
+	for name, value := range fields {
+		if values, ok := value.([]interface{}); ok && len(values) == 1 {
+			doc[name] = values[0]
+			continue
+		}
+		doc[name] = value
+	}
+}
@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/olivere/elastic/v7"
+	"github.com/zeromicro/go-zero/core/logc"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// esSchemaMaxIndices 返回的索引数上限
+const esSchemaMaxIndices = 1000
+
+// Indices 通过 _cat/indices 获取匹配的索引, 不包含 . 开头的系统及隐藏索引
+func (e ElasticSearchDsProvider) Indices(pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	ctx, cancel := context.WithTimeout(e.getContext(), time.Duration(e.getCheckTimeout())*time.Second)
+	defer cancel()
+
+	rows, err := e.cli.CatIndices().Index(pattern).Columns("index").Do(ctx)
+	if err != nil {
+		return nil, wrapEsError(err)
+	}
+
+	indices := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Index == "" || strings.HasPrefix(row.Index, ".") {
+			continue
+		}
+		indices = append(indices, row.Index)
+	}
+	sort.Strings(indices)
+	if len(indices) > esSchemaMaxIndices {
+		indices = indices[:esSchemaMaxIndices]
+	}
+	return indices, nil
+}
+
+// Fields 获取索引映射中的字段, 嵌套对象按 . 展开, 包含 multi-fields (如 message.keyword) 及映射中的运行时字段;
+// 多个索引中的同名字段类型不一致时取首个索引 (按名称排序) 的类型
+func (e ElasticSearchDsProvider) Fields(index string) ([]LogField, error) {
+	if index == "" {
+		return nil, newBadQueryError("索引名称为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.getContext(), time.Duration(e.getCheckTimeout())*time.Second)
+	defer cancel()
+
+	// GetMapping 的请求路径包含类型 (/{index}/_mapping/_all), ES 8 已不支持, 直接请求 /{index}/_mapping
+	res, err := e.cli.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/" + url.PathEscape(index) + "/_mapping",
+		Params: url.Values{"ignore_unavailable": {"true"}, "allow_no_indices": {"true"}},
+	})
+	if err != nil {
+		return nil, wrapEsError(err)
+	}
+
+	var mappings map[string]interface{}
+	if err := json.Unmarshal(res.Body, &mappings); err != nil {
+		return nil, fmt.Errorf("解析索引映射失败, err: %s", err.Error())
+	}
+
+	indexNames := make([]string, 0, len(mappings))
+	for name := range mappings {
+		indexNames = append(indexNames, name)
+	}
+	sort.Strings(indexNames)
+
+	types := make(map[string]string)
+	for _, name := range indexNames {
+		indexMapping, _ := mappings[name].(map[string]interface{})
+		mapping, _ := indexMapping["mappings"].(map[string]interface{})
+		if properties, ok := mapping["properties"].(map[string]interface{}); ok {
+			collectEsFields(types, "", properties)
+		}
+		if runtime, ok := mapping["runtime"].(map[string]interface{}); ok {
+			collectEsFields(types, "", runtime)
+		}
+	}
+
+	fields := make([]LogField, 0, len(types))
+	for name, typ := range types {
+		fields = append(fields, LogField{Name: name, Type: typ})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields, nil
+}
+
+// collectEsFields 展开映射中的字段, object 及 nested 类型仅展开子字段
+func collectEsFields(types map[string]string, prefix string, properties map[string]interface{}) {
+	for name, value := range properties {
+		property, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		field := prefix + name
+		typ, _ := property["type"].(string)
+		if sub, ok := property["properties"].(map[string]interface{}); ok {
+			collectEsFields(types, field+".", sub)
+			if typ == "" || typ == "object" || typ == "nested" {
+				continue
+			}
+		}
+		if typ == "" {
+			continue
+		}
+		if _, exists := types[field]; !exists {
+			types[field] = typ
+		}
+		if multiFields, ok := property["fields"].(map[string]interface{}); ok {
+			collectEsFields(types, field+".", multiFields)
+		}
+	}
+}
+
+// esMappingCacheTTL 索引字段类型的缓存时长
+const esMappingCacheTTL = 5 * time.Minute
+
+// esMappingCache 索引字段类型缓存, 随复用的客户端创建, 连接配置变更重建客户端时一并失效
+type esMappingCache struct {
+	mux     sync.Mutex
+	entries map[string]esMappingEntry
+}
+
+type esMappingEntry struct {
+	types     map[string]string
+	expiresAt time.Time
+}
+
+func newEsMappingCache() *esMappingCache {
+	return &esMappingCache{entries: make(map[string]esMappingEntry)}
+}
+
+func (c *esMappingCache) get(index string) (map[string]string, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry, ok := c.entries[index]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.types, true
+}
+
+func (c *esMappingCache) set(index string, types map[string]string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[index] = esMappingEntry{types: types, expiresAt: now.Add(esMappingCacheTTL)}
+}
+
+// fieldTypes 获取索引的字段名及类型, 优先使用缓存; 获取映射失败时返回 nil, 不缓存
+func (e ElasticSearchDsProvider) fieldTypes(index string) map[string]string {
+	if e.mappings != nil {
+		if types, ok := e.mappings.get(index); ok {
+			return types
+		}
+	}
+
+	fields, err := e.Fields(index)
+	if err != nil {
+		logc.Errorf(e.getContext(), "获取 ElasticSearch 索引映射失败, index: %s, err: %s", index, err.Error())
+		return nil
+	}
+
+	types := make(map[string]string, len(fields))
+	for _, f := range fields {
+		types[f.Name] = f.Type
+	}
+	if e.mappings != nil {
+		e.mappings.set(index, types)
+	}
+	return types
+}
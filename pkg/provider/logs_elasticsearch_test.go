@@ -3,9 +3,18 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/olivere/elastic/v7"
 	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	"watchAlert/internal/models"
 )
 
@@ -45,6 +54,19 @@ func TestElasticsearch_GetIndexName(t *testing.T) {
 	}
 }
 
+func TestElasticsearch_GetIndexNames(t *testing.T) {
+	es := Elasticsearch{
+		Index:        "logs-YYYY.MM.dd, <app-{now/d}>, <audit-{now/d{yyyyMMdd}}>, logs-alias",
+		IndexPattern: true,
+	}
+
+	indices := es.GetIndexNames("2024-01-01T23:00:00Z", "2024-01-02T01:00:00Z")
+	want := []string{"logs-2024.01.01", "logs-2024.01.02", "app-2024.01.01", "app-2024.01.02", "audit-20240101", "audit-20240102", "logs-alias"}
+	if fmt.Sprint(indices) != fmt.Sprint(want) {
+		t.Errorf("indices -> %v, want %v", indices, want)
+	}
+}
+
 func TestElasticSearch_Query(t *testing.T) {
 	client, err := NewElasticSearchClient(context.Background(), models.AlertDataSource{
 		HTTP: models.HTTP{
@@ -69,3 +91,651 @@ func TestElasticSearch_Query(t *testing.T) {
 	fmt.Println("query->", string(json))
 
 }
+
+func TestElasticsearch_GetIndexNames_Clusters(t *testing.T) {
+	es := Elasticsearch{Index: "logs-*,cluster_c:audit-*", Clusters: []string{"cluster_a", models.EsLocalCluster}}
+	if got, want := es.GetIndexName(), "cluster_a:logs-*,logs-*,cluster_c:audit-*"; got != want {
+		t.Errorf("index -> %s, want %s", got, want)
+	}
+
+	es = Elasticsearch{Index: "cluster_a:logs-YYYY.MM.dd", Clusters: []string{"cluster_b"}, IndexPattern: true}
+	indices := es.GetIndexNames("2024-01-01T23:00:00Z", "2024-01-02T01:00:00Z")
+	if want := []string{"cluster_a:logs-2024.01.01", "cluster_a:logs-2024.01.02"}; fmt.Sprint(indices) != fmt.Sprint(want) {
+		t.Errorf("indices -> %v, want %v", indices, want)
+	}
+}
+
+func TestNewEsValueAggregation(t *testing.T) {
+	tests := []struct {
+		name    string
+		agg     models.EsAggregation
+		want    string
+		wantErr bool
+	}{
+		{name: "count uses doc_count", agg: models.EsAggregation{Type: models.EsAggregationTypeCount, BucketField: "service"}},
+		{name: "no type and no field uses doc_count", agg: models.EsAggregation{BucketField: "service"}},
+		{name: "avg", agg: models.EsAggregation{Type: models.EsAggregationTypeAvg, Field: "latency", BucketField: "service"}, want: `{"avg":{"field":"latency"}}`},
+		{name: "sum", agg: models.EsAggregation{Type: models.EsAggregationTypeSum, Field: "bytes", Interval: "1m"}, want: `{"sum":{"field":"bytes"}}`},
+		{name: "max", agg: models.EsAggregation{Type: models.EsAggregationTypeMax, Field: "latency"}, want: `{"max":{"field":"latency"}}`},
+		{name: "min", agg: models.EsAggregation{Type: models.EsAggregationTypeMin, Field: "latency"}, want: `{"min":{"field":"latency"}}`},
+		{name: "avg without field", agg: models.EsAggregation{Type: models.EsAggregationTypeAvg}, wantErr: true},
+		{name: "undefined type", agg: models.EsAggregation{Type: "median", Field: "latency"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueAgg, err := newEsValueAggregation(tt.agg)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadQuery) {
+					t.Fatalf("error -> %v, want ErrBadQuery", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if valueAgg != nil {
+					t.Fatalf("aggregation -> %s, want doc_count", esQuerySource(t, valueAgg))
+				}
+				return
+			}
+			if valueAgg == nil {
+				t.Fatalf("aggregation -> doc_count, want %s", tt.want)
+			}
+			if got := esQuerySource(t, valueAgg); got != tt.want {
+				t.Errorf("aggregation -> %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// 未指定类型时取分桶的 doc_count
+	if value := getEsAggregationValue(models.EsAggregation{}, elastic.Aggregations{}, 42); value != 42 {
+		t.Errorf("value -> %v, want doc_count 42", value)
+	}
+}
+
+func TestNewEsMetricAggregations(t *testing.T) {
+	agg := models.EsAggregation{
+		BucketField: "service",
+		Metrics: []models.EsAggregationMetric{
+			{Name: "error_count", Type: models.EsAggregationTypeCount, Condition: ">100"},
+			{Name: "latency_p99", Type: models.EsAggregationTypePercentiles, Field: "latency", Percent: 99, Condition: ">500"},
+		},
+	}
+	aggs, err := newEsMetricAggregations(agg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggs) != 1 || aggs["latency_p99"] == nil {
+		t.Errorf("count metric should use doc_count, got %d aggregations", len(aggs))
+	}
+	if got, want := esQuerySource(t, aggs["latency_p99"]), `{"percentiles":{"field":"latency","percents":[99]}}`; got != want {
+		t.Errorf("latency_p99 -> %s, want %s", got, want)
+	}
+
+	agg.Metrics = append(agg.Metrics, models.EsAggregationMetric{Name: "error_count", Type: models.EsAggregationTypeSum, Field: "bytes"})
+	if _, err := newEsMetricAggregations(agg); err == nil {
+		t.Errorf("duplicate metric name should fail")
+	}
+}
+
+// esQuerySource 序列化查询的 source, 用于与期望的查询 JSON 比较
+func esQuerySource(t *testing.T, q elastic.Query) string {
+	t.Helper()
+	src, err := q.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestBuildFilterQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		wildcard int64
+		value    string
+		want     string
+	}{
+		{name: "match", wildcard: models.EsQueryWildcardMatch, value: "timeout", want: `{"match":{"message":{"query":"timeout"}}}`},
+		{name: "wildcard", wildcard: models.EsQueryWildcardWildcard, value: "timeout", want: `{"wildcard":{"message":{"value":"*timeout*"}}}`},
+		{name: "regexp", wildcard: models.EsQueryWildcardRegexp, value: "timeout.*", want: `{"regexp":{"message":{"value":"timeout.*"}}}`},
+		// Lucene 正则语法, RE2 无法解析, 原样交给 ES
+		{name: "lucene numeric range", wildcard: models.EsQueryWildcardRegexp, value: "code<500-599>", want: `{"regexp":{"message":{"value":"code\u003c500-599\u003e"}}}`},
+		{name: "phrase", wildcard: models.EsQueryWildcardPhrase, value: "connection timeout", want: `{"match_phrase":{"message":{"query":"connection timeout"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := buildFilterQuery(models.EsQueryFilter{Field: "message", Value: tt.value}, tt.wildcard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := esQuerySource(t, q); got != tt.want {
+				t.Errorf("query -> %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := buildFilterQuery(models.EsQueryFilter{Field: "message", Value: "timeout"}, 99); !errors.Is(err, ErrBadQuery) {
+		t.Errorf("undefined wildcard -> %v", err)
+	}
+}
+
+func TestBuildFieldQuery_NestedGroup(t *testing.T) {
+	const timeRange = `{"range":{"@timestamp":{"from":"2025-01-01T00:00:00Z","include_lower":true,"include_upper":true,"to":"2025-01-01T00:05:00Z"}}}`
+	tests := []struct {
+		name      string
+		condition models.EsFilterCondition
+		filters   []models.EsQueryFilter
+		want      string
+		wantErr   bool
+	}{
+		{
+			// (level = error AND service = api) OR (message = timeout)
+			name:      "nested and group in or",
+			condition: models.EsFilterConditionOr,
+			filters: []models.EsQueryFilter{
+				{
+					Condition: models.EsFilterConditionAnd,
+					Filters: []models.EsQueryFilter{
+						{Field: "level", Value: "error"},
+						{Field: "service", Value: "api"},
+					},
+				},
+				{Field: "message", Value: "timeout"},
+			},
+			want: `{"bool":{"minimum_should_match":"1","must":` + timeRange + `,"should":[{"bool":{"must":[{"match":{"level":{"query":"error"}}},{"match":{"service":{"query":"api"}}}]}},{"match":{"message":{"query":"timeout"}}}]}}`,
+		},
+		{
+			// level = error AND NOT (service = api OR service = web)
+			name:      "nested not group",
+			condition: models.EsFilterConditionAnd,
+			filters: []models.EsQueryFilter{
+				{Field: "level", Value: "error"},
+				{
+					Condition: models.EsFilterConditionNot,
+					Filters: []models.EsQueryFilter{
+						{Field: "service", Value: "api"},
+						{Field: "service", Value: "web"},
+					},
+				},
+			},
+			want: `{"bool":{"must":[{"match":{"level":{"query":"error"}}},{"bool":{"must_not":[{"match":{"service":{"query":"api"}}},{"match":{"service":{"query":"web"}}}]}},` + timeRange + `]}}`,
+		},
+		{
+			name:      "no filters",
+			condition: models.EsFilterConditionAnd,
+			want:      `{"bool":{"must":` + timeRange + `}}`,
+		},
+		{
+			name:      "undefined nested condition",
+			condition: models.EsFilterConditionAnd,
+			filters: []models.EsQueryFilter{
+				{Condition: "Xor", Filters: []models.EsQueryFilter{{Field: "level", Value: "error"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := buildFieldQuery(LogQueryOptions{
+				ElasticSearch: Elasticsearch{
+					QueryFilterCondition: tt.condition,
+					QueryFilter:          tt.filters,
+				},
+				StartAt: "2025-01-01T00:00:00Z",
+				EndAt:   "2025-01-01T00:05:00Z",
+			})
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadQuery) {
+					t.Fatalf("error -> %v, want ErrBadQuery", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := esQuerySource(t, query); got != tt.want {
+				t.Errorf("query -> %s\nwant     %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEsClientPool_Fingerprint(t *testing.T) {
+	ds := models.AlertDataSource{Id: "ds-1", HTTP: models.HTTP{URL: "http://127.0.0.1:9200"}}
+	before := getEsClientFingerprint(ds)
+
+	ds.Auth.Pass = "changed"
+	if before == getEsClientFingerprint(ds) {
+		t.Fatal("fingerprint should change when credentials change")
+	}
+}
+
+func TestEsClientPool_Get(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	pool := &esClientPool{clients: make(map[string]esPooledClient), retireDelay: 50 * time.Millisecond}
+	ds := models.AlertDataSource{Id: "ds-1", HTTP: models.HTTP{URL: srv.URL}}
+
+	var wg sync.WaitGroup
+	clients := make([]*elastic.Client, 8)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := pool.get(ds)
+			if err != nil {
+				t.Errorf("get -> %s", err.Error())
+				return
+			}
+			clients[i] = c.cli
+		}(i)
+	}
+	wg.Wait()
+	for _, cli := range clients {
+		if cli != clients[0] {
+			t.Fatal("concurrent get should share one client")
+		}
+	}
+
+	// 配置变更后旧客户端在延迟时间内仍可用
+	ds.Auth.Pass = "changed"
+	c, err := pool.get(ds)
+	if err != nil {
+		t.Fatalf("get -> %s", err.Error())
+	}
+	if c.cli == clients[0] {
+		t.Fatal("fingerprint change should rebuild the client")
+	}
+	if !clients[0].IsRunning() {
+		t.Fatal("replaced client should not be stopped immediately")
+	}
+	deadline := time.Now().Add(time.Second)
+	for clients[0].IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if clients[0].IsRunning() {
+		t.Fatal("replaced client should be stopped after the retire delay")
+	}
+
+	// 空闲超时的 URL 客户端在下次获取时释放
+	byURL, err := pool.get(models.AlertDataSource{HTTP: models.HTTP{URL: srv.URL}})
+	if err != nil {
+		t.Fatalf("get -> %s", err.Error())
+	}
+	pool.mux.Lock()
+	idle := pool.clients[srv.URL]
+	idle.lastUsed = time.Now().Add(-2 * esClientIdleTTL)
+	pool.clients[srv.URL] = idle
+	pool.mux.Unlock()
+	if _, err := pool.get(ds); err != nil {
+		t.Fatalf("get -> %s", err.Error())
+	}
+	if _, exists := pool.clients[srv.URL]; exists || byURL.cli.IsRunning() {
+		t.Fatal("idle url client should be evicted")
+	}
+
+	pool.remove(ds.Id)
+}
+
+func TestNewStatusError(t *testing.T) {
+	var cases = map[int]error{
+		401: ErrAuth,
+		403: ErrAuth,
+		400: ErrBadQuery,
+		503: ErrUnavailable,
+	}
+	for code, want := range cases {
+		if err := newStatusError(code, ""); !errors.Is(err, want) {
+			t.Fatalf("status %d -> %v, want %v", code, err, want)
+		}
+	}
+
+	if err := wrapEsError(&elastic.Error{Status: 401}); !errors.Is(err, ErrAuth) {
+		t.Fatalf("elastic 401 -> %v, want %v", err, ErrAuth)
+	}
+}
+
+func TestWrapEsError_ResultWindow(t *testing.T) {
+	err := wrapEsError(&elastic.Error{
+		Status: 500,
+		Details: &elastic.ErrorDetails{
+			Type: "search_phase_execution_exception",
+			RootCause: []*elastic.ErrorDetails{{
+				Type:   "illegal_argument_exception",
+				Reason: "Result window is too large, from + size must be less than or equal to: [10000] but was [20000].",
+			}},
+		},
+	})
+	if !errors.Is(err, ErrResultWindow) || !errors.Is(err, ErrBadQuery) {
+		t.Fatalf("result window -> %v, want %v", err, ErrResultWindow)
+	}
+	if IsTransientError(err) {
+		t.Fatalf("result window error should not be retried")
+	}
+
+	// 未开启自动分页时在请求 ES 之前直接返回
+	_, _, err = ElasticSearchDsProvider{}.Query(LogQueryOptions{
+		ElasticSearch: Elasticsearch{Index: "test", QueryType: models.EsQueryTypeRawJson, RawJson: `{"match_all":{}}`, Size: 20000},
+	})
+	if !errors.Is(err, ErrResultWindow) {
+		t.Fatalf("size over window -> %v, want %v", err, ErrResultWindow)
+	}
+}
+
+func TestElasticSearch_CheckSigV4(t *testing.T) {
+	var authorization, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, path = r.Header.Get("Authorization"), r.URL.Path
+	}))
+	defer srv.Close()
+
+	ds := models.AlertDataSource{
+		HTTP: models.HTTP{URL: srv.URL},
+		Auth: models.Auth{
+			AuthType: models.AuthTypeSigV4,
+			SigV4:    models.SigV4{Region: "us-east-1", AccessKey: "ak", SecretKey: "sk", Service: models.SigV4ServiceOpenSearchServerless},
+		},
+	}
+	client, err := NewElasticSearchClient(context.Background(), ds)
+	if err != nil {
+		t.Fatalf("client -> %s", err.Error())
+	}
+	defer CloseElasticSearchClient(srv.URL)
+
+	if ok, err := client.Check(); !ok || err != nil {
+		t.Fatalf("check -> %v, %v", ok, err)
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=ak/") || !strings.Contains(authorization, "/us-east-1/aoss/") {
+		t.Errorf("authorization -> %s", authorization)
+	}
+	if path != "/_cat/indices" {
+		t.Errorf("path -> %s", path)
+	}
+}
+
+func TestCoerceEsFields(t *testing.T) {
+	fieldTypes := []models.EsFieldType{
+		{Field: "latency", Type: models.EsFieldTypeNumber},
+		{Field: "http.status", Type: models.EsFieldTypeNumber},
+		{Field: "ts", Type: models.EsFieldTypeTime},
+		{Field: "code", Type: models.EsFieldTypeString},
+		{Field: "missing", Type: models.EsFieldTypeNumber},
+	}
+	msgs := []map[string]interface{}{
+		{"latency": " 12.5", "http": map[string]interface{}{"status": "503"}, "ts": float64(1700000000123), "code": float64(42)},
+		{"latency": float64(3), "http.status": "200", "ts": "2023-11-14T22:13:20+08:00", "code": "E42"},
+	}
+	if err := coerceEsFields(msgs, fieldTypes); err != nil {
+		t.Fatal(err)
+	}
+
+	if msgs[0]["latency"] != 12.5 || msgs[0]["http"].(map[string]interface{})["status"] != float64(503) || msgs[0]["code"] != "42" {
+		t.Errorf("msgs[0] -> %v", msgs[0])
+	}
+	if msgs[0]["ts"] != "2023-11-14T22:13:20.123Z" || msgs[1]["ts"] != "2023-11-14T14:13:20Z" || msgs[1]["http.status"] != float64(200) {
+		t.Errorf("msgs -> %v", msgs)
+	}
+	if _, ok := msgs[0]["missing"]; ok {
+		t.Error("missing field should not be created")
+	}
+
+	err := coerceEsFields([]map[string]interface{}{{"_index": "app", "_id": "1", "latency": "slow"}}, fieldTypes)
+	if !errors.Is(err, ErrBadQuery) || !strings.Contains(err.Error(), "app/1 的字段 latency") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// newTestEsClient 启动模拟 ES 的 httptest 服务并创建客户端, 创建客户端时的 /_cat/indices 健康检查直接返回,
+// 其余请求交给 handler 处理; 测试结束时关闭服务及客户端
+func newTestEsClient(t *testing.T, handler http.HandlerFunc) ElasticSearchDsProvider {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_cat/indices" {
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewElasticSearchClient(context.Background(), models.AlertDataSource{HTTP: models.HTTP{URL: srv.URL}})
+	if err != nil {
+		t.Fatalf("client -> %s", err.Error())
+	}
+	t.Cleanup(func() { CloseElasticSearchClient(srv.URL) })
+
+	return client.(ElasticSearchDsProvider)
+}
+
+func TestElasticSearch_CountOnly(t *testing.T) {
+	var path, body string
+	client := newTestEsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(b)
+		_, _ = w.Write([]byte(`{"took":35,"hits":{"total":{"value":42,"relation":"eq"},"hits":[]},"_shards":{"total":3,"successful":2,"failed":1,"failures":[{"index":"app","reason":{"reason":"shard unavailable"}}]}}`))
+	})
+
+	var stats QueryStats
+	res, count, err := client.Query(LogQueryOptions{ElasticSearch: Elasticsearch{
+		Index:     "app",
+		QueryType: models.EsQueryTypeRawJson,
+		RawJson:   `{"match":{"level":"error"}}`,
+		CountOnly: true,
+	}, Stats: &stats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 42 || len(res) != 1 || res[0].Message[0]["doc_count"] != int64(42) {
+		t.Errorf("count -> %d, %+v", count, res)
+	}
+	if path != "/app/_search" || !strings.Contains(body, `"size":0`) || !strings.Contains(body, `"track_total_hits":true`) {
+		t.Errorf("request -> %s %s", path, body)
+	}
+	if stats.TookMs != 35 || !stats.Partial() || stats.Warning() != "查询结果不完整, 1/3 个分片查询失败, 原因: app: shard unavailable" {
+		t.Errorf("stats -> %+v, %s", stats, stats.Warning())
+	}
+}
+
+func TestElasticSearch_RuntimeFields(t *testing.T) {
+	var body string
+	scriptErr := false
+	client := newTestEsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if scriptErr {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"type":"search_phase_execution_exception","reason":"all shards failed","root_cause":[{"type":"script_exception","reason":"runtime error"}],"failed_shards":[{"shard":0,"index":"app","reason":{"type":"script_exception","reason":"runtime error","script_stack":["doc['bytes'].value"],"caused_by":{"type":"illegal_argument_exception","reason":"No field found for [bytes] in mapping"}}}]},"status":400}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"app","_id":"1","_source":{"level":"error"},"fields":{"kb":[2.5]}}]}}`))
+	})
+
+	options := LogQueryOptions{ElasticSearch: Elasticsearch{
+		Index:     "app",
+		QueryType: models.EsQueryTypeRawJson,
+		RawJson:   `{"range":{"kb":{"gte":1}}}`,
+		RuntimeFields: []models.EsRuntimeField{
+			{Name: "kb", Type: models.EsRuntimeFieldTypeDouble, Script: "emit(doc['bytes'].value / 1024.0)"},
+		},
+	}}
+	res, _, err := client.Query(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"runtime_mappings":{"kb":{"script":{"source":"emit(doc['bytes'].value / 1024.0)"},"type":"double"}}`) || !strings.Contains(body, `"docvalue_fields":["kb"]`) {
+		t.Errorf("request -> %s", body)
+	}
+	if res[0].Message[0]["kb"] != 2.5 || res[0].Message[0]["level"] != "error" {
+		t.Errorf("message -> %+v", res[0].Message)
+	}
+
+	scriptErr = true
+	_, _, err = client.Query(options)
+	if !errors.Is(err, ErrBadQuery) || !strings.Contains(err.Error(), "脚本执行失败: runtime error, No field found for [bytes] in mapping, script_stack: doc['bytes'].value") {
+		t.Errorf("script error -> %v", err)
+	}
+}
+
+func TestElasticSearch_QueryGuard(t *testing.T) {
+	guard := models.EsQueryGuard{Enabled: true, MaxScope: 60, MaxSize: 1000, DenyLeadingWildcard: true}
+	options := LogQueryOptions{
+		ElasticSearch: Elasticsearch{
+			Index:         "app",
+			QueryType:     models.EsQueryTypeField,
+			QueryWildcard: models.EsQueryWildcardWildcard,
+			QueryFilter: []models.EsQueryFilter{
+				{Field: "message", Value: "timeout"},
+				{Field: "service.keyword", Value: "api"},
+			},
+			MaxLogs: 5000,
+		},
+		StartAt: "2024-01-01T00:00:00Z",
+		EndAt:   "2024-01-01T03:00:00Z",
+	}
+
+	mapping := map[string]string{
+		"message":         "text",
+		"service":         "text",
+		"service.keyword": "keyword",
+		"host":            "text",
+		"path":            "text",
+		"path.keyword":    "keyword",
+		"msg":             "match_only_text",
+		"level":           "keyword",
+		"trace_id":        "keyword",
+	}
+	analyzed := newEsAnalyzedFieldFunc(mapping)
+	violations := esQueryGuardViolations(options, guard, analyzed)
+	if len(violations) != 3 || !strings.Contains(violations[2], "message") || strings.Contains(violations[2], "service.keyword") {
+		t.Fatalf("violations -> %v", violations)
+	}
+
+	// 字段类型以映射为准, 而不是字段名是否以 .keyword 结尾
+	keywordOnly := options
+	keywordOnly.ElasticSearch.QueryFilter = []models.EsQueryFilter{{Field: "trace_id", Value: "abc"}, {Field: "missing", Value: "x"}}
+	if violations := esQueryGuardViolations(keywordOnly, guard, analyzed); len(violations) != 2 {
+		t.Errorf("keyword field violations -> %v", violations)
+	}
+	if violations := esQueryGuardViolations(keywordOnly, guard, newEsAnalyzedFieldFunc(nil)); len(violations) != 3 {
+		t.Errorf("fallback without mapping -> %v", violations)
+	}
+	if !analyzed("ser*") || analyzed("lev*") {
+		t.Errorf("wildcard field name should match analyzed fields in mapping")
+	}
+
+	raw := options
+	raw.ElasticSearch = Elasticsearch{
+		Index:     "app",
+		QueryType: models.EsQueryTypeRawJson,
+		RawJson:   `{"bool":{"must":[{"wildcard":{"host":{"value":"*web"}}},{"regexp":{"path.keyword":".*api"}},{"query_string":{"query":"level:error AND msg:*fail"}}]}}`,
+	}
+	raw.EndAt = "2024-01-01T00:30:00Z"
+	fields := rawJsonLeadingWildcardFields(raw.ElasticSearch.RawJson, analyzed)
+	if len(fields) != 2 || !slices.Contains(fields, "host") || !slices.Contains(fields, "msg") {
+		t.Errorf("raw json fields -> %v", fields)
+	}
+
+	e := ElasticSearchDsProvider{guard: guard, mappings: newEsMappingCache()}
+	e.mappings.set("app", mapping)
+	if err := e.checkQueryGuard(options); !errors.Is(err, ErrBadQuery) || !errors.Is(err, ErrQueryTooExpensive) {
+		t.Errorf("reject -> %v", err)
+	}
+
+	options.ElasticSearch.BypassQueryGuard = true
+	if err := e.checkQueryGuard(options); err == nil {
+		t.Errorf("bypass without allowBypass should be rejected")
+	}
+	e.guard.AllowBypass = true
+	if err := e.checkQueryGuard(options); err != nil {
+		t.Errorf("bypass -> %v", err)
+	}
+
+	options.ElasticSearch.BypassQueryGuard = false
+	e.guard.Mode = models.EsQueryGuardModeWarn
+	if err := e.checkQueryGuard(options); err != nil {
+		t.Errorf("warn -> %v", err)
+	}
+}
+
+func TestElasticSearch_CompositeAggregation(t *testing.T) {
+	var requests []string
+	e := newTestEsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_search") {
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(b))
+		if !strings.Contains(string(b), `"after"`) {
+			_, _ = w.Write([]byte(`{"hits":{"total":{"value":3}},"aggregations":{"buckets":{"after_key":{"host":"b"},"buckets":[{"key":{"host":"a"},"doc_count":5},{"key":{"host":"b"},"doc_count":3}]}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"hits":{"total":{"value":3}},"aggregations":{"buckets":{"buckets":[{"key":{"host":"c"},"doc_count":1}]}}}`))
+	})
+	target := esSearchTarget{indices: []string{"app"}}
+	agg := models.EsAggregation{Type: models.EsAggregationTypeCount, BucketFields: []string{"host"}, Composite: true, MaxBuckets: 1}
+
+	// 上限为 1 时每页请求 2 个分桶, 超出上限后不再翻页
+	stats := &QueryStats{}
+	logs, _, err := e.aggregationQuery(context.Background(), target, elastic.NewMatchAllQuery(), "@timestamp", agg, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || !strings.Contains(requests[0], `"size":2`) || len(logs) != 1 || logs[0].Metric["host"] != "a" || stats.BucketLimit != 1 || !stats.Partial() {
+		t.Errorf("truncated -> requests: %d, logs: %+v, stats: %+v", len(requests), logs, stats)
+	}
+
+	requests = nil
+	agg.MaxBuckets = 0
+	stats = &QueryStats{}
+	logs, _, err = e.aggregationQuery(context.Background(), target, elastic.NewMatchAllQuery(), "@timestamp", agg, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || len(logs) != 2 || stats.Partial() {
+		t.Errorf("single page -> requests: %d, logs: %+v, stats: %+v", len(requests), logs, stats)
+	}
+}
+
+func TestElasticSearch_Schema(t *testing.T) {
+	client := newTestEsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/indices/*":
+			_, _ = w.Write([]byte(`[{"index":"app-2"},{"index":".kibana"},{"index":"app-1"}]`))
+		case "/app-1/_mapping":
+			_, _ = w.Write([]byte(`{"app-1":{"mappings":{
+				"properties":{
+					"message":{"type":"text","fields":{"keyword":{"type":"keyword"}}},
+					"kubernetes":{"properties":{"pod":{"properties":{"name":{"type":"keyword"}}}}},
+					"spans":{"type":"nested","properties":{"duration":{"type":"long"}}}
+				},
+				"runtime":{"latency_ms":{"type":"double"}}
+			}}}`))
+		}
+	})
+
+	indices, err := ListLogIndices(client, "")
+	if err != nil || !slices.Equal(indices, []string{"app-1", "app-2"}) {
+		t.Errorf("indices -> %v, %v", indices, err)
+	}
+
+	fields, err := ListLogFields(client, "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LogField{
+		{Name: "kubernetes.pod.name", Type: "keyword"},
+		{Name: "latency_ms", Type: "double"},
+		{Name: "message", Type: "text"},
+		{Name: "message.keyword", Type: "keyword"},
+		{Name: "spans.duration", Type: "long"},
+	}
+	if !slices.Equal(fields, want) {
+		t.Errorf("fields -> %+v", fields)
+	}
+}
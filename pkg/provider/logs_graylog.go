@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// GraylogDsProvider 基于 Graylog REST API 查询日志
+type GraylogDsProvider struct {
+	url            string
+	auth           models.Auth
+	timeout        int64
+	ExternalLabels map[string]interface{}
+}
+
+const (
+	graylogDefaultLimit = 100
+	// graylogTimeLayout Graylog 绝对时间查询使用的时间格式
+	graylogTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+type graylogSearchResponse struct {
+	Messages []struct {
+		Message map[string]interface{} `json:"message"`
+		Index   string                 `json:"index"`
+	} `json:"messages"`
+	TotalResults int `json:"total_results"`
+}
+
+func init() {
+	Register(GraylogDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewGraylogClient(ds)
+	})
+}
+
+func NewGraylogClient(datasource models.AlertDataSource) (LogsFactoryProvider, error) {
+	return GraylogDsProvider{
+		url:            strings.TrimSuffix(strings.TrimSuffix(datasource.HTTP.URL, "/"), "/api"),
+		auth:           datasource.Auth,
+		timeout:        datasource.HTTP.Timeout,
+		ExternalLabels: datasource.Labels,
+	}, nil
+}
+
+func (g GraylogDsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
+	curTime := time.Now()
+
+	startAt, ok := toUnixSeconds(options.StartAt)
+	if !ok {
+		startAt = tools.ParserDuration(curTime, 30, "m").Unix()
+	}
+
+	endAt, ok := toUnixSeconds(options.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
+	}
+
+	var query string
+	switch options.Graylog.QueryType {
+	case models.GraylogQueryTypeRawQuery:
+		query = options.Graylog.Query
+	default:
+		var err error
+		query, err = buildGraylogFieldQuery(options.Graylog.QueryFilter, options.Graylog.QueryFilterCondition, options.Graylog.QueryWildcard)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		query = "*"
+	}
+
+	limit := options.Graylog.Limit
+	if limit <= 0 {
+		limit = graylogDefaultLimit
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("from", time.Unix(startAt, 0).UTC().Format(graylogTimeLayout))
+	params.Set("to", time.Unix(endAt, 0).UTC().Format(graylogTimeLayout))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("sort", "timestamp:desc")
+	params.Set("decorate", "false")
+	if filter := buildGraylogStreamFilter(options.Graylog.Streams); filter != "" {
+		params.Set("filter", filter)
+	}
+
+	res, err := tools.Get(g.getHeader(), fmt.Sprintf("%s/api/search/universal/absolute?%s", g.url, params.Encode()), int(options.GetTimeout(g.timeout)))
+	if err != nil {
+		return nil, 0, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, 0, newStatusError(res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var resultData graylogSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&resultData); err != nil {
+		return nil, 0, fmt.Errorf("Graylog - 解析查询结果失败: %s", err.Error())
+	}
+	if len(resultData.Messages) == 0 {
+		return nil, 0, nil
+	}
+
+	msgs := make([]map[string]interface{}, 0, len(resultData.Messages))
+	for _, m := range resultData.Messages {
+		msg := m.Message
+		if msg == nil {
+			msg = map[string]interface{}{}
+		}
+		// 消息所在索引, 便于通知模版拼接 Graylog 消息链接
+		msg[esDocIndexKey] = m.Index
+		msgs = append(msgs, msg)
+	}
+
+	var data []Logs
+	data = append(data, Logs{
+		ProviderName: GraylogDsProviderName,
+		Metric:       getLogsMetric(msgs, options.LabelFields, commonKeyValuePairs),
+		Message:      msgs,
+	})
+
+	return data, resultData.TotalResults, nil
+}
+
+// graylogSpecialChars Lucene 查询语法中需要转义的字符
+var graylogSpecialChars = regexp.MustCompile(`([+\-&|!(){}\[\]^"~*?:\\/\s])`)
+
+// buildGraylogFieldQuery 将过滤条件转换为 Graylog 搜索语句, 与 ElasticSearch 条件查询的组合方式一致
+func buildGraylogFieldQuery(filters []models.EsQueryFilter, condition models.EsFilterCondition, wildcard int64) (string, error) {
+	if len(filters) == 0 {
+		return "", nil
+	}
+
+	subQueries := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		if len(filter.Filters) > 0 {
+			group, err := buildGraylogFieldQuery(filter.Filters, filter.Condition, wildcard)
+			if err != nil {
+				return "", err
+			}
+			subQueries = append(subQueries, "("+group+")")
+			continue
+		}
+
+		q, err := buildGraylogFilterQuery(filter, wildcard)
+		if err != nil {
+			return "", err
+		}
+		subQueries = append(subQueries, q)
+	}
+
+	switch condition {
+	case models.EsFilterConditionOr:
+		return strings.Join(subQueries, " OR "), nil
+	case models.EsFilterConditionAnd:
+		return strings.Join(subQueries, " AND "), nil
+	case models.EsFilterConditionNot:
+		// 所有子查询都不能匹配, 以 * 作为基础结果集
+		return "* AND NOT " + strings.Join(subQueries, " AND NOT "), nil
+	default:
+		return "", newBadQueryError("undefined QueryFilterCondition")
+	}
+}
+
+// buildGraylogFilterQuery 根据匹配模式构建单个字段的查询
+func buildGraylogFilterQuery(filter models.EsQueryFilter, wildcard int64) (string, error) {
+	if filter.Field == "" {
+		return "", newBadQueryError("过滤字段为空")
+	}
+
+	switch wildcard {
+	case models.EsQueryWildcardMatch:
+		return fmt.Sprintf("%s:%s", filter.Field, graylogSpecialChars.ReplaceAllString(filter.Value, `\$1`)), nil
+	case models.EsQueryWildcardWildcard:
+		return fmt.Sprintf("%s:*%s*", filter.Field, graylogSpecialChars.ReplaceAllString(filter.Value, `\$1`)), nil
+	case models.EsQueryWildcardRegexp:
+		if _, err := regexp.Compile(filter.Value); err != nil {
+			return "", newBadQueryError("字段 %s 的正则表达式无效: %s", filter.Field, err.Error())
+		}
+		return fmt.Sprintf("%s:/%s/", filter.Field, strings.ReplaceAll(filter.Value, "/", `\/`)), nil
+	case models.EsQueryWildcardPhrase:
+		return fmt.Sprintf(`%s:"%s"`, filter.Field, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filter.Value)), nil
+	default:
+		return "", newBadQueryError("undefined QueryWildcard")
+	}
+}
+
+// buildGraylogStreamFilter 构建 Stream 过滤条件, 多个 Stream 之间为"或"关系
+func buildGraylogStreamFilter(streams []string) string {
+	var filters []string
+	for _, stream := range streams {
+		if stream = strings.TrimSpace(stream); stream != "" {
+			filters = append(filters, "streams:"+stream)
+		}
+	}
+	return strings.Join(filters, " OR ")
+}
+
+// getHeader 获取请求头, API Token 以 <token>:token 的形式进行 Basic 认证
+func (g GraylogDsProvider) getHeader() map[string]string {
+	header := map[string]string{"Accept": "application/json"}
+	switch g.auth.AuthType {
+	case models.AuthTypeApiKey, models.AuthTypeBearer:
+		header["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(g.auth.Token+":token"))
+	default:
+		for k, v := range g.auth.GetAuthHeader() {
+			header[k] = v
+		}
+	}
+	return header
+}
+
+func (g GraylogDsProvider) Check() (bool, error) {
+	timeout := g.timeout
+	if timeout <= 0 {
+		timeout = defaultLogQueryTimeout
+	}
+
+	res, err := tools.Get(g.getHeader(), g.url+"/api/system", int(timeout))
+	if err != nil {
+		return false, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return false, newStatusError(res.StatusCode, "")
+	}
+
+	return true, nil
+}
+
+func (g GraylogDsProvider) GetExternalLabels() map[string]interface{} {
+	return g.ExternalLabels
+}
@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestBuildGraylogFieldQuery(t *testing.T) {
+	filters := []models.EsQueryFilter{
+		{Field: "level", Value: "ERROR"},
+		{
+			Condition: models.EsFilterConditionOr,
+			Filters: []models.EsQueryFilter{
+				{Field: "source", Value: "api-1"},
+				{Field: "source", Value: "web server"},
+			},
+		},
+	}
+
+	query, err := buildGraylogFieldQuery(filters, models.EsFilterConditionAnd, models.EsQueryWildcardMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `level:ERROR AND (source:api\-1 OR source:web\ server)`; query != want {
+		t.Errorf("query -> %s, want %s", query, want)
+	}
+
+	query, _ = buildGraylogFieldQuery(filters[:1], models.EsFilterConditionNot, models.EsQueryWildcardPhrase)
+	if want := `* AND NOT level:"ERROR"`; query != want {
+		t.Errorf("query -> %s, want %s", query, want)
+	}
+
+	if _, err := buildGraylogFieldQuery(filters[:1], models.EsFilterConditionAnd, models.EsQueryWildcardRegexp+10); err == nil {
+		t.Error("undefined QueryWildcard should fail")
+	}
+}
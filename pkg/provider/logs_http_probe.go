@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// HTTPProbeDsProvider HTTP 拨测数据源, 每次查询对目标地址发起一次请求, 以状态码、耗时及响应内容匹配结果作为告警评估的值
+type HTTPProbeDsProvider struct {
+	url            string
+	timeout        int64
+	tls            models.TLS
+	auth           models.Auth
+	config         models.DsHTTPProbeConfig
+	bodyMatch      *regexp.Regexp
+	ExternalLabels map[string]interface{}
+}
+
+// httpProbeMaxBodySize 响应内容匹配时最多读取的字节数
+const httpProbeMaxBodySize = 1 << 20
+
+func init() {
+	Register(HTTPProbeDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewHTTPProbeClient(ds)
+	})
+}
+
+func NewHTTPProbeClient(datasource models.AlertDataSource) (LogsFactoryProvider, error) {
+	if datasource.HTTP.URL == "" {
+		return HTTPProbeDsProvider{}, newBadQueryError("拨测地址为空")
+	}
+
+	var bodyMatch *regexp.Regexp
+	if datasource.HTTPProbeConfig.BodyMatch != "" {
+		var err error
+		bodyMatch, err = regexp.Compile(datasource.HTTPProbeConfig.BodyMatch)
+		if err != nil {
+			return HTTPProbeDsProvider{}, newBadQueryError("响应内容匹配的正则表达式无效: %s", err.Error())
+		}
+	}
+
+	return HTTPProbeDsProvider{
+		url:            datasource.HTTP.URL,
+		timeout:        datasource.HTTP.Timeout,
+		tls:            datasource.HTTP.TLS,
+		auth:           datasource.Auth,
+		config:         datasource.HTTPProbeConfig,
+		bodyMatch:      bodyMatch,
+		ExternalLabels: datasource.Labels,
+	}, nil
+}
+
+// Query 执行拨测, 请求失败时 status_code 为 0 并记录 error, 不返回错误, 以便规则对目标不可达进行告警
+func (h HTTPProbeDsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
+	msg := h.probe(options.GetTimeout(h.timeout))
+
+	return []Logs{{
+		ProviderName: HTTPProbeDsProviderName,
+		Metric:       map[string]interface{}{"address": h.url},
+		Message:      []map[string]interface{}{msg},
+	}}, 1, nil
+}
+
+func (h HTTPProbeDsProvider) probe(timeout int64) map[string]interface{} {
+	msg := map[string]interface{}{
+		"address":                       h.url,
+		models.HTTPProbeFieldStatusCode: float64(0),
+		models.HTTPProbeFieldLatency:    float64(0),
+		models.HTTPProbeFieldBodyMatch:  float64(0),
+	}
+
+	start := time.Now()
+	res, err := h.do(timeout)
+	if err != nil {
+		msg[models.HTTPProbeFieldLatency] = float64(time.Since(start).Milliseconds())
+		msg["error"] = err.Error()
+		return msg
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, httpProbeMaxBodySize))
+	msg[models.HTTPProbeFieldLatency] = float64(time.Since(start).Milliseconds())
+	msg[models.HTTPProbeFieldStatusCode] = float64(res.StatusCode)
+	if err != nil {
+		msg["error"] = err.Error()
+		return msg
+	}
+
+	// 未配置匹配规则时视为匹配成功
+	if h.bodyMatch == nil || h.bodyMatch.Match(body) {
+		msg[models.HTTPProbeFieldBodyMatch] = float64(1)
+	}
+
+	return msg
+}
+
+func (h HTTPProbeDsProvider) do(timeout int64) (*http.Response, error) {
+	tlsConfig, err := tools.NewTLSConfig(h.tls.CACert, h.tls.ClientCert, h.tls.ClientKey, h.tls.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+		},
+	}
+
+	method := strings.ToUpper(h.config.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if h.config.Body != "" {
+		body = bytes.NewReader([]byte(h.config.Body))
+	}
+
+	request, err := http.NewRequest(method, h.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.auth.GetAuthHeader() {
+		request.Header.Set(k, v)
+	}
+	for k, v := range h.config.Header {
+		request.Header.Set(k, v)
+	}
+
+	return client.Do(request)
+}
+
+// Check 目标地址可以建立连接并返回响应时视为正常, 不校验状态码
+func (h HTTPProbeDsProvider) Check() (bool, error) {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = defaultLogQueryTimeout
+	}
+
+	res, err := h.do(timeout)
+	if err != nil {
+		return false, newConnectionError(err)
+	}
+	res.Body.Close()
+
+	return true, nil
+}
+
+func (h HTTPProbeDsProvider) GetExternalLabels() map[string]interface{} {
+	return h.ExternalLabels
+}
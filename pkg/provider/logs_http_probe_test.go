@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestHTTPProbeDsProvider_Query(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Probe") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"status":"UP"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewHTTPProbeClient(models.AlertDataSource{
+		HTTP: models.HTTP{URL: srv.URL},
+		HTTPProbeConfig: models.DsHTTPProbeConfig{
+			Method:    "post",
+			Header:    map[string]string{"X-Probe": "1"},
+			BodyMatch: `"status":"UP"`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, count, err := client.Query(LogQueryOptions{})
+	if err != nil || count != 1 {
+		t.Fatalf("query -> %d, %v", count, err)
+	}
+	if code := res[0].GetAggregationMetricValue(models.HTTPProbeFieldStatusCode); code != 200 {
+		t.Errorf("status_code -> %v", code)
+	}
+	if match := res[0].GetAggregationMetricValue(models.HTTPProbeFieldBodyMatch); match != 1 {
+		t.Errorf("body_match -> %v", match)
+	}
+
+	srv.Close()
+	res, _, err = client.Query(LogQueryOptions{Timeout: 1})
+	if err != nil || res[0].GetAggregationMetricValue(models.HTTPProbeFieldStatusCode) != 0 || res[0].Message[0]["error"] == nil {
+		t.Errorf("unreachable -> %v, %v", res[0].Message[0], err)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 	"watchAlert/internal/models"
 	"watchAlert/pkg/tools"
@@ -20,6 +22,12 @@ type LokiProvider struct {
 	ExternalLabels map[string]interface{}
 }
 
+func init() {
+	Register(LokiDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewLokiClient(ds)
+	})
+}
+
 func NewLokiClient(datasource models.AlertDataSource) (LogsFactoryProvider, error) {
 	return LokiProvider{
 		url:            datasource.HTTP.URL,
@@ -57,20 +65,29 @@ func (l LokiProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
 		options.Loki.Limit = 100
 	}
 
-	if options.StartAt == "" {
+	// 未指定查询时间范围时默认查询最近 1 小时
+	startAt, ok := toUnixSeconds(options.StartAt)
+	if !ok {
 		duration, _ := time.ParseDuration(strconv.Itoa(1) + "h")
-		options.StartAt = curTime.Add(-duration).Format(time.RFC3339Nano)
+		startAt = curTime.Add(-duration).Unix()
 	}
 
-	if options.EndAt == "" {
-		options.EndAt = curTime.Format(time.RFC3339Nano)
+	endAt, ok := toUnixSeconds(options.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
 	}
 
-	args := fmt.Sprintf("/loki/api/v1/query_range?query=%s&direction=%s&limit=%d&start=%d&end=%d", url.QueryEscape(options.Loki.Query), options.Loki.Direction, options.Loki.Limit, options.StartAt.(int64), options.EndAt.(int64))
+	args := fmt.Sprintf("/loki/api/v1/query_range?query=%s&direction=%s&limit=%d&start=%d&end=%d", url.QueryEscape(options.Loki.Query), options.Loki.Direction, options.Loki.Limit, startAt, endAt)
 	requestURL := l.url + args
-	res, err := tools.Get(nil, requestURL, 10)
+	res, err := tools.Get(nil, requestURL, int(options.GetTimeout(l.timeout)))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, 0, newStatusError(res.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	var resultData result
@@ -132,22 +149,33 @@ func (l LokiProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
 
 	data = append(data, Logs{
 		ProviderName: LokiDsProviderName,
-		Metric:       commonKeyValuePairs(streamList),
+		Metric:       l.getMetricLabels(streamList, msgs, options.LabelFields),
 		Message:      msgs,
 	})
 
 	return data, count, nil
 }
 
+// getMetricLabels 配置 LabelFields 时优先从 Stream 标签中提取, 其次从日志内容中提取
+func (l LokiProvider) getMetricLabels(streamList, msgs []map[string]interface{}, labelFields []string) map[string]interface{} {
+	if len(labelFields) == 0 {
+		return commonKeyValuePairs(streamList)
+	}
+
+	return labelFieldPairs(append(streamList, msgs...), labelFields)
+}
+
 func (l LokiProvider) Check() (bool, error) {
 	res, err := tools.Get(nil, l.url+"/loki/api/v1/labels", int(l.timeout))
 	if err != nil {
-		return false, err
+		return false, newConnectionError(err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		logc.Error(context.Background(), fmt.Errorf("unhealthy status: %d", res.StatusCode))
-		return false, fmt.Errorf("unhealthy status: %d", res.StatusCode)
+		err := newStatusError(res.StatusCode, "")
+		logc.Error(context.Background(), err)
+		return false, err
 	}
 
 	return true, nil
@@ -0,0 +1,358 @@
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// SQLDsProvider 在 MySQL / PostgreSQL 中执行只读查询, 用于业务数据表的告警
+type SQLDsProvider struct {
+	db             *sql.DB
+	driver         string
+	timeout        int64
+	ExternalLabels map[string]interface{}
+}
+
+const (
+	sqlDefaultLimit = 500
+	// sqlMaxOpenConns 单个数据源的最大连接数, 避免告警查询占满业务库连接
+	sqlMaxOpenConns = 5
+)
+
+func init() {
+	Register(SQLDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewSQLClient(ds)
+	})
+}
+
+func NewSQLClient(datasource models.AlertDataSource) (LogsFactoryProvider, error) {
+	db, err := sqlClients.get(datasource)
+	if err != nil {
+		return SQLDsProvider{}, err
+	}
+
+	return SQLDsProvider{
+		db:             db,
+		driver:         datasource.SQLConfig.Driver,
+		timeout:        datasource.HTTP.Timeout,
+		ExternalLabels: datasource.Labels,
+	}, nil
+}
+
+func (s SQLDsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
+	curTime := time.Now()
+
+	startAt, ok := toUnixSeconds(options.StartAt)
+	if !ok {
+		startAt = tools.ParserDuration(curTime, 30, "m").Unix()
+	}
+
+	endAt, ok := toUnixSeconds(options.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
+	}
+
+	if err := validateReadOnlySQL(s.driver, options.SQL.Query); err != nil {
+		return nil, 0, err
+	}
+
+	query, args := bindSQLParams(s.driver, options.SQL.Query, map[string]interface{}{
+		"start":    time.Unix(startAt, 0),
+		"end":      time.Unix(endAt, 0),
+		"start_ts": startAt,
+		"end_ts":   endAt,
+	})
+
+	limit := options.SQL.Limit
+	if limit <= 0 {
+		limit = sqlDefaultLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(options.GetTimeout(s.timeout))*time.Second)
+	defer cancel()
+
+	msgs, err := s.execute(ctx, query, args, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(msgs) == 0 {
+		return nil, 0, nil
+	}
+
+	var data []Logs
+	data = append(data, Logs{
+		ProviderName: SQLDsProviderName,
+		Metric:       getLogsMetric(msgs, options.LabelFields, commonKeyValuePairs),
+		Message:      msgs,
+	})
+
+	return data, len(msgs), nil
+}
+
+// execute 在只读事务中执行查询, 最多读取 limit 行
+func (s SQLDsProvider) execute(ctx context.Context, query string, args []interface{}, limit int) ([]map[string]interface{}, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, newConnectionError(err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, newBadQueryError(err.Error())
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []map[string]interface{}
+	for len(msgs) < limit && rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		msg := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			// 字符串类型的字段驱动返回 []byte, 转为字符串便于作为标签及通知内容
+			if b, ok := values[i].([]byte); ok {
+				msg[column] = string(b)
+				continue
+			}
+			msg[column] = values[i]
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+var (
+	// sqlParamPattern 匹配 {start}、{end_ts} 形式的命名参数
+	sqlParamPattern = regexp.MustCompile(`\{(start|end|start_ts|end_ts)\}`)
+	// sqlLiteralPattern 匹配注释、字符串字面量及标识符, 校验语句时忽略其中的内容, MySQL 额外支持 # 注释及反斜杠转义
+	sqlLiteralPattern = map[string]*regexp.Regexp{
+		models.SQLDriverMySQL:    regexp.MustCompile(`(?s)--[^\n]*|#[^\n]*|/\*.*?\*/|'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"` + "|`[^`]*`"),
+		models.SQLDriverPostgres: regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/|'(?:[^']|'')*'|"(?:[^"]|"")*"`),
+	}
+	// sqlWritePattern SELECT / WITH 语句中可能产生写入或加锁的关键字, 如 PostgreSQL 的数据修改 CTE、SELECT INTO、FOR UPDATE
+	sqlWritePattern = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|upsert|create|alter|drop|grant|revoke|into|lock|call|exec|execute|copy)\b`)
+)
+
+// validateReadOnlySQL 校验查询语句, 仅允许单条 SELECT / WITH 语句, 执行时另在只读事务中运行
+func validateReadOnlySQL(driver, query string) error {
+	literal, ok := sqlLiteralPattern[driver]
+	if !ok {
+		return fmt.Errorf("不支持的 SQL 驱动: %s", driver)
+	}
+
+	stmt := strings.TrimSpace(literal.ReplaceAllString(query, " "))
+	stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+	if stmt == "" {
+		return newBadQueryError("SQL 语句为空")
+	}
+	if strings.Contains(stmt, ";") {
+		return newBadQueryError("仅支持执行单条 SQL 语句")
+	}
+
+	keyword := strings.ToLower(strings.Fields(stmt)[0])
+	if keyword != "select" && keyword != "with" {
+		return newBadQueryError("仅支持 SELECT 查询语句")
+	}
+	if m := sqlWritePattern.FindString(stmt); m != "" {
+		return newBadQueryError("SQL 语句包含不允许的关键字: %s", strings.ToUpper(m))
+	}
+
+	return nil
+}
+
+// bindSQLParams 将命名参数替换为驱动的占位符, 参数值通过 args 传递, 避免拼接 SQL
+func bindSQLParams(driver, query string, params map[string]interface{}) (string, []interface{}) {
+	var args []interface{}
+	query = sqlParamPattern.ReplaceAllStringFunc(query, func(m string) string {
+		args = append(args, params[m[1:len(m)-1]])
+		if driver == models.SQLDriverPostgres {
+			return "$" + strconv.Itoa(len(args))
+		}
+		return "?"
+	})
+	return query, args
+}
+
+func (s SQLDsProvider) Check() (bool, error) {
+	timeout := s.timeout
+	if timeout <= 0 {
+		timeout = defaultLogQueryTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	var one int
+	if err := s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return false, newConnectionError(err)
+	}
+
+	return true, nil
+}
+
+func (s SQLDsProvider) GetExternalLabels() map[string]interface{} {
+	return s.ExternalLabels
+}
+
+// sqlClientPool SQL 连接池复用, 避免每次查询或健康检查都新建连接
+type sqlClientPool struct {
+	clients map[string]sqlPooledClient
+	mux     sync.Mutex
+}
+
+type sqlPooledClient struct {
+	// 数据源连接配置的指纹, 配置变更后重建连接池
+	fingerprint string
+	db          *sql.DB
+}
+
+var sqlClients = &sqlClientPool{
+	clients: make(map[string]sqlPooledClient),
+}
+
+func (p *sqlClientPool) get(ds models.AlertDataSource) (*sql.DB, error) {
+	key := ds.Id
+	if key == "" {
+		key = fmt.Sprintf("%s:%d/%s", ds.SQLConfig.Host, ds.SQLConfig.Port, ds.SQLConfig.Database)
+	}
+
+	h := md5.New()
+	h.Write([]byte(tools.JsonMarshal(ds.SQLConfig)))
+	h.Write([]byte(tools.JsonMarshal(ds.Auth)))
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if c, exists := p.clients[key]; exists {
+		if c.fingerprint == fingerprint {
+			return c.db, nil
+		}
+		c.db.Close()
+		delete(p.clients, key)
+	}
+
+	dsn, err := buildSQLDsn(ds)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(ds.SQLConfig.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(sqlMaxOpenConns)
+	db.SetMaxIdleConns(sqlMaxOpenConns)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	p.clients[key] = sqlPooledClient{fingerprint: fingerprint, db: db}
+
+	return db, nil
+}
+
+func (p *sqlClientPool) remove(key string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if c, exists := p.clients[key]; exists {
+		c.db.Close()
+		delete(p.clients, key)
+	}
+}
+
+func (p *sqlClientPool) closeAll() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for key, c := range p.clients {
+		c.db.Close()
+		delete(p.clients, key)
+	}
+}
+
+// CloseAllSQLClients 服务退出时释放所有 SQL 连接池
+func CloseAllSQLClients() {
+	sqlClients.closeAll()
+}
+
+// CloseSQLClient 数据源删除时释放对应的 SQL 连接池
+func CloseSQLClient(datasourceId string) {
+	sqlClients.remove(datasourceId)
+}
+
+// buildSQLDsn 根据驱动生成连接地址
+func buildSQLDsn(ds models.AlertDataSource) (string, error) {
+	cfg := ds.SQLConfig
+	params, err := url.ParseQuery(cfg.Params)
+	if err != nil {
+		return "", fmt.Errorf("连接参数格式错误: %s", err.Error())
+	}
+
+	switch cfg.Driver {
+	case models.SQLDriverMySQL:
+		port := cfg.Port
+		if port == 0 {
+			port = 3306
+		}
+		c := mysql.NewConfig()
+		c.User = ds.Auth.User
+		c.Passwd = ds.Auth.Pass
+		c.Net = "tcp"
+		c.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+		c.DBName = cfg.Database
+		c.ParseTime = true
+		c.Timeout = time.Duration(defaultLogQueryTimeout) * time.Second
+		c.Params = make(map[string]string)
+		for k := range params {
+			c.Params[k] = params.Get(k)
+		}
+		return c.FormatDSN(), nil
+	case models.SQLDriverPostgres:
+		port := cfg.Port
+		if port == 0 {
+			port = 5432
+		}
+		if params.Get("connect_timeout") == "" {
+			params.Set("connect_timeout", strconv.FormatInt(defaultLogQueryTimeout, 10))
+		}
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(ds.Auth.User, ds.Auth.Pass),
+			Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+			Path:     "/" + cfg.Database,
+			RawQuery: params.Encode(),
+		}
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("不支持的 SQL 驱动: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestValidateReadOnlySQL(t *testing.T) {
+	var cases = map[string]bool{
+		"SELECT * FROM orders WHERE status = 'pending' AND created_at < {end};":   true,
+		"with t as (select id from orders) select count(*) from t":                true,
+		"SELECT id, updated_at FROM orders WHERE remark = 'delete; drop table x'": true,
+		"DELETE FROM orders":                                         false,
+		"SELECT 1; DROP TABLE orders":                                false,
+		"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d": false,
+		"SELECT * INTO backup FROM orders":                           false,
+		"SELECT * FROM orders FOR UPDATE":                            false,
+	}
+	for query, ok := range cases {
+		if err := validateReadOnlySQL(models.SQLDriverPostgres, query); (err == nil) != ok {
+			t.Errorf("%s -> %v, want ok %v", query, err, ok)
+		}
+	}
+
+	if err := validateReadOnlySQL(models.SQLDriverMySQL, "SELECT 1 # ; DELETE FROM orders"); err != nil {
+		t.Errorf("mysql comment -> %v", err)
+	}
+	if err := validateReadOnlySQL(models.SQLDriverPostgres, "SELECT 1 # 2; DELETE FROM orders"); err == nil {
+		t.Error("postgres has no # comment")
+	}
+}
+
+func TestBindSQLParams(t *testing.T) {
+	params := map[string]interface{}{"start": 1, "end": 2}
+
+	query, args := bindSQLParams(models.SQLDriverPostgres, "SELECT * FROM t WHERE ts >= {start} AND ts < {end}", params)
+	if query != "SELECT * FROM t WHERE ts >= $1 AND ts < $2" || len(args) != 2 || args[1] != 2 {
+		t.Errorf("postgres -> %s %v", query, args)
+	}
+
+	query, _ = bindSQLParams(models.SQLDriverMySQL, "SELECT * FROM t WHERE ts >= {start}", params)
+	if query != "SELECT * FROM t WHERE ts >= ?" {
+		t.Errorf("mysql -> %s", query)
+	}
+}
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestListLogSchema_NotSupported(t *testing.T) {
+	if _, err := ListLogFields(LokiProvider{}, "app"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("loki fields -> %v", err)
+	}
+	if _, err := ListLogIndices(LokiProvider{}, ""); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("loki indices -> %v", err)
+	}
+}
+
+func TestLogs_GetFieldValue(t *testing.T) {
+	l := Logs{Message: []map[string]interface{}{{
+		"error_rate": 12.4,
+		"count":      int64(3),
+		"latency":    "250",
+		"http":       map[string]interface{}{"status": json.Number("503")},
+		"message":    "timeout",
+	}}}
+
+	cases := map[string]float64{"error_rate": 12.4, "count": 3, "latency": 250, "http.status": 503}
+	for field, want := range cases {
+		if got, ok := l.GetFieldValue(field); !ok || got != want {
+			t.Errorf("%s -> %v, %v", field, got, ok)
+		}
+	}
+	for _, field := range []string{"message", "missing", "http.code", ""} {
+		if _, ok := l.GetFieldValue(field); ok {
+			t.Errorf("%s -> expected not ok", field)
+		}
+	}
+	if _, ok := (Logs{}).GetFieldValue("count"); ok {
+		t.Error("empty logs -> expected not ok")
+	}
+}
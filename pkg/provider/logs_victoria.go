@@ -29,6 +29,12 @@ type (
 	}
 )
 
+func init() {
+	Register(VictoriaLogsDsProviderName, func(ctx context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewVictoriaLogsClient(ctx, ds)
+	})
+}
+
 // NewVictoriaLogsClient 创建一个新的 VictoriaLogsProvider 实例。
 func NewVictoriaLogsClient(ctx context.Context, datasource models.AlertDataSource) (LogsFactoryProvider, error) {
 	return VictoriaLogsProvider{
@@ -44,40 +50,46 @@ func NewVictoriaLogsClient(ctx context.Context, datasource models.AlertDataSourc
 func (v VictoriaLogsProvider) Query(options LogQueryOptions) ([]Logs, int, error) {
 	curTime := time.Now()
 
-	if options.StartAt == "" || options.StartAt == nil {
-		options.StartAt = int32(tools.ParserDuration(curTime, 30, "m").Unix())
+	// 未指定查询时间范围时默认查询最近 30 分钟
+	startAt, ok := toUnixSeconds(options.StartAt)
+	if !ok {
+		startAt = tools.ParserDuration(curTime, 30, "m").Unix()
 	}
 
-	if options.EndAt == "" || options.EndAt == nil {
-		options.EndAt = int32(curTime.Unix())
+	endAt, ok := toUnixSeconds(options.EndAt)
+	if !ok {
+		endAt = curTime.Unix()
 	}
 
 	if options.VictoriaLogs.Limit == 0 {
 		options.VictoriaLogs.Limit = 500
 	}
 
-	args := fmt.Sprintf("/select/logsql/query?query=%s&limit=%d&start=%d&end=%d", url.QueryEscape(options.VictoriaLogs.Query), options.VictoriaLogs.Limit, options.StartAt.(int32), options.EndAt.(int32))
+	args := fmt.Sprintf("/select/logsql/query?query=%s&limit=%d&start=%d&end=%d", url.QueryEscape(options.VictoriaLogs.Query), options.VictoriaLogs.Limit, startAt, endAt)
 	requestURL := v.URL + args
-	res, err := tools.Get(tools.CreateBasicAuthHeader(v.Username, v.Password), requestURL, 10)
+	res, err := tools.Get(tools.CreateBasicAuthHeader(v.Username, v.Password), requestURL, int(options.GetTimeout(v.Timeout)))
 
 	if err != nil {
 		logc.Error(ctx.Ctx, fmt.Sprintf("查询VictoriaLogs失败: %s", err.Error()))
-		return nil, 0, err
+		return nil, 0, newConnectionError(err)
 	}
+	defer res.Body.Close()
 
 	respBody, _ := io.ReadAll(res.Body)
 
 	if res.StatusCode != 200 {
 		errMsg := fmt.Sprintf("查询VictoriaLogs失败: %s", string(respBody))
 		logc.Error(v.Ctx, errMsg)
-		return nil, 0, fmt.Errorf(errMsg)
+		return nil, 0, newStatusError(res.StatusCode, string(respBody))
 	}
 
 	var (
 		msgs  = []map[string]interface{}{}
 		count int
 	)
+	// 返回结果为每行一条日志的流式 JSON, 单行日志可能较大, 放宽扫描缓冲区上限
 	scanner := bufio.NewScanner(bytes.NewReader(respBody))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
@@ -95,7 +107,7 @@ func (v VictoriaLogsProvider) Query(options LogQueryOptions) ([]Logs, int, error
 	var logs []Logs
 	logs = append(logs, Logs{
 		ProviderName: VictoriaLogsDsProviderName,
-		Metric:       v.getMetricLabels(msgs),
+		Metric:       getLogsMetric(msgs, options.LabelFields, v.getMetricLabels),
 		Message:      msgs,
 	})
 
@@ -111,14 +123,16 @@ func (v VictoriaLogsProvider) getMetricLabels(entries []map[string]interface{})
 }
 
 func (v VictoriaLogsProvider) Check() (bool, error) {
-	res, err := tools.Get(nil, v.URL+"/health", int(v.Timeout))
+	res, err := tools.Get(tools.CreateBasicAuthHeader(v.Username, v.Password), v.URL+"/health", int(v.Timeout))
 	if err != nil {
-		return false, err
+		return false, newConnectionError(err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		logc.Error(v.Ctx, fmt.Errorf("unhealthy status: %d", res.StatusCode))
-		return false, fmt.Errorf("unhealthy status: %d", res.StatusCode)
+		err := newStatusError(res.StatusCode, "")
+		logc.Error(v.Ctx, err)
+		return false, err
 	}
 	return true, nil
 }
@@ -3,6 +3,7 @@ package provider
 import (
 	"fmt"
 	"strconv"
+	"time"
 	"watchAlert/pkg/tools"
 )
 
@@ -13,6 +14,8 @@ const (
 
 type MetricsFactoryProvider interface {
 	Query(promQL string) ([]Metrics, error)
+	// QueryRange 范围查询, 返回每条时间序列在 [start, end] 内按 step 采样的数据点
+	QueryRange(promQL string, start, end time.Time, step time.Duration) ([]Series, error)
 	Check() (bool, error)
 	GetExternalLabels() map[string]interface{}
 }
@@ -23,6 +26,21 @@ type Metrics struct {
 	Timestamp float64
 }
 
+// Series 范围查询返回的时间序列
+type Series struct {
+	Metric map[string]interface{}
+	Values []SamplePair
+}
+
+// SamplePair 时间序列中的数据点, Timestamp 单位为秒
+type SamplePair struct {
+	Timestamp float64
+	Value     float64
+}
+
+// rangeQueryTimeout 范围查询超时时间
+const rangeQueryTimeout = 30 * time.Second
+
 func (m Metrics) GetFingerprint() string {
 	if len(m.Metric) == 0 {
 		return strconv.FormatUint(tools.HashNew(), 10)
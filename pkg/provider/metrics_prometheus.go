@@ -14,8 +14,13 @@ import (
 type PrometheusProvider struct {
 	ExternalLabels map[string]interface{}
 	apiV1          v1.API
+	client         api.Client
+	checkTimeout   time.Duration
 }
 
+// promDefaultCheckTimeout 数据源未配置超时时间时, 健康检查的超时时间
+const promDefaultCheckTimeout = 10 * time.Second
+
 // BasicAuthTransport 实现带认证的HTTP传输层
 type BasicAuthTransport struct {
 	Username string
@@ -30,6 +35,12 @@ func (t *BasicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error
 	return t.Base.RoundTrip(req)
 }
 
+func init() {
+	Register(PrometheusDsProvider, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewPrometheusClient(ds)
+	})
+}
+
 func NewPrometheusClient(source models.AlertDataSource) (MetricsFactoryProvider, error) {
 	// 创建基础传输层
 	baseTransport := http.DefaultTransport
@@ -53,8 +64,15 @@ func NewPrometheusClient(source models.AlertDataSource) (MetricsFactoryProvider,
 		return nil, err
 	}
 
+	checkTimeout := promDefaultCheckTimeout
+	if source.HTTP.Timeout > 0 {
+		checkTimeout = time.Duration(source.HTTP.Timeout) * time.Second
+	}
+
 	return PrometheusProvider{
 		apiV1:          v1.NewAPI(client),
+		client:         client,
+		checkTimeout:   checkTimeout,
 		ExternalLabels: source.Labels,
 	}, nil
 }
@@ -64,12 +82,59 @@ func (p PrometheusProvider) Query(promQL string) ([]Metrics, error) {
 	defer cancel()
 	result, _, err := p.apiV1.Query(ctx, promQL, time.Now(), v1.WithTimeout(5*time.Second))
 	if err != nil {
-		return nil, err
+		return nil, wrapPromError(err)
 	}
 
 	return ConvertVectors(result), nil
 }
 
+func (p PrometheusProvider) QueryRange(promQL string, start, end time.Time, step time.Duration) ([]Series, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rangeQueryTimeout)
+	defer cancel()
+	result, _, err := p.apiV1.QueryRange(ctx, promQL, v1.Range{
+		Start: start,
+		End:   end,
+		Step:  step,
+	})
+	if err != nil {
+		return nil, wrapPromError(err)
+	}
+
+	return ConvertMatrix(result), nil
+}
+
+// ConvertMatrix 将范围查询的 Matrix 结果转换为时间序列
+func ConvertMatrix(value model.Value) (lst []Series) {
+	items, ok := value.(model.Matrix)
+	if !ok {
+		return
+	}
+
+	for _, item := range items {
+		var metric = make(map[string]interface{})
+		for k, v := range item.Metric {
+			metric[string(k)] = string(v)
+		}
+
+		values := make([]SamplePair, 0, len(item.Values))
+		for _, point := range item.Values {
+			if math.IsNaN(float64(point.Value)) {
+				continue
+			}
+			values = append(values, SamplePair{
+				Timestamp: float64(point.Timestamp.Unix()),
+				Value:     float64(point.Value),
+			})
+		}
+
+		lst = append(lst, Series{
+			Metric: metric,
+			Values: values,
+		})
+	}
+	return
+}
+
 func ConvertVectors(value model.Value) (lst []Metrics) {
 	items, ok := value.(model.Vector)
 	if !ok {
@@ -95,11 +160,23 @@ func ConvertVectors(value model.Value) (lst []Metrics) {
 	return
 }
 
+// Check 请求 /-/healthy, 与查询共用客户端及认证传输层
 func (p PrometheusProvider) Check() (bool, error) {
-	_, err := p.apiV1.Config(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), p.checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.client.URL("/-/healthy", nil).String(), nil)
 	if err != nil {
 		return false, err
 	}
+	res, _, err := p.client.Do(ctx, req)
+	if err != nil {
+		return false, newConnectionError(err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return false, newStatusError(res.StatusCode, "")
+	}
 
 	return true, nil
 }
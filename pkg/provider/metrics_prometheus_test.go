@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestPrometheus_Check(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/prom/-/healthy" || user != "admin" || pass != "secret" {
+			t.Errorf("request -> %s, auth: %s/%s", r.URL.Path, user, pass)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cli, err := NewPrometheusClient(models.AlertDataSource{
+		HTTP: models.HTTP{URL: srv.URL + "/prom/", Timeout: 1},
+		Auth: models.Auth{User: "admin", Pass: "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cli.Check(); !ok || err != nil {
+		t.Errorf("healthy -> %v, %v", ok, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if ok, err := cli.Check(); ok || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("unhealthy -> %v, %v", ok, err)
+	}
+}
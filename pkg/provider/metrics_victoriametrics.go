@@ -19,6 +19,12 @@ type VictoriaMetricsProvider struct {
 	password       string
 }
 
+func init() {
+	Register(VictoriaMetricsDsProvider, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewVictoriaMetricsClient(ds)
+	})
+}
+
 func NewVictoriaMetricsClient(ds models.AlertDataSource) (MetricsFactoryProvider, error) {
 	return VictoriaMetricsProvider{
 		address:        ds.HTTP.URL,
@@ -41,6 +47,7 @@ type VMData struct {
 type VMResult struct {
 	Metric map[string]interface{} `json:"metric"`
 	Value  []interface{}          `json:"value"`
+	Values [][]interface{}        `json:"values"`
 }
 
 func (v VictoriaMetricsProvider) Query(promQL string) ([]Metrics, error) {
@@ -53,12 +60,12 @@ func (v VictoriaMetricsProvider) Query(promQL string) ([]Metrics, error) {
 	resp, err := utilsHttp.Get(utilsHttp.CreateBasicAuthHeader(v.username, v.password), fullURL, 10)
 	if err != nil {
 		logc.Error(context.Background(), "VictoriaMetrics query failed", "error", err)
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, newConnectionError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newStatusError(resp.StatusCode, "")
 	}
 
 	var vmRespBody QueryResponse
@@ -70,6 +77,75 @@ func (v VictoriaMetricsProvider) Query(promQL string) ([]Metrics, error) {
 	return vmVectors(vmRespBody.VMData.VMResult), nil
 }
 
+func (v VictoriaMetricsProvider) QueryRange(promQL string, start, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Add("query", promQL)
+	params.Add("start", strconv.FormatInt(start.Unix(), 10))
+	params.Add("end", strconv.FormatInt(end.Unix(), 10))
+	params.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	fullURL := fmt.Sprintf("%s%s?%s", v.address, "/api/v1/query_range", params.Encode())
+
+	resp, err := utilsHttp.Get(utilsHttp.CreateBasicAuthHeader(v.username, v.password), fullURL, int(rangeQueryTimeout.Seconds()))
+	if err != nil {
+		logc.Error(context.Background(), "VictoriaMetrics range query failed", "error", err)
+		return nil, newConnectionError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp.StatusCode, "")
+	}
+
+	var vmRespBody QueryResponse
+	if err := utilsHttp.ParseReaderBody(resp.Body, &vmRespBody); err != nil {
+		logc.Error(context.Background(), "Parse response failed", "error", err)
+		return nil, fmt.Errorf("parse response failed: %w", err)
+	}
+
+	return vmMatrix(vmRespBody.VMData.VMResult), nil
+}
+
+// vmSamplePair 解析 [timestamp, "value"] 格式的数据点
+func vmSamplePair(point []interface{}) (SamplePair, bool) {
+	if len(point) < 2 {
+		return SamplePair{}, false
+	}
+
+	timestamp, ok1 := point[0].(float64)
+	valueStr, ok2 := point[1].(string)
+	if !ok1 || !ok2 {
+		return SamplePair{}, false
+	}
+
+	valueFloat, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return SamplePair{}, false
+	}
+
+	return SamplePair{Timestamp: timestamp, Value: valueFloat}, true
+}
+
+func vmMatrix(res []VMResult) []Series {
+	var series []Series
+	for _, item := range res {
+		values := make([]SamplePair, 0, len(item.Values))
+		for _, point := range item.Values {
+			sample, ok := vmSamplePair(point)
+			if !ok {
+				logc.Error(context.Background(), "Invalid value format")
+				continue
+			}
+			values = append(values, sample)
+		}
+
+		series = append(series, Series{
+			Metric: item.Metric,
+			Values: values,
+		})
+	}
+	return series
+}
+
 func vmVectors(res []VMResult) []Metrics {
 	var vectors []Metrics
 	for _, item := range res {
@@ -2,7 +2,9 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
 	"net/http"
 	"time"
 	"watchAlert/pkg/tools"
@@ -45,7 +47,7 @@ func (h HTTPer) Pilot(option EndpointOption) (EndpointValue, error) {
 	end := time.Now()
 	// 计算请求耗时
 	latency := end.Sub(start).Milliseconds()
-	fmt.Println(res.Request.RemoteAddr)
+	logc.Debug(context.Background(), fmt.Sprintf("HTTP 拨测远端地址: %s", res.Request.RemoteAddr))
 
 	return convertHTTPerToEndpointValue(HttperInformation{
 		Address:    res.Request.URL.String(),
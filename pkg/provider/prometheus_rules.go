@@ -0,0 +1,374 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/prometheus/common/model"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// PromRuleFile Prometheus 规则文件
+type PromRuleFile struct {
+	Groups []PromRuleGroup `json:"groups"`
+}
+
+type PromRuleGroup struct {
+	Name        string     `json:"name"`
+	Interval    string     `json:"interval"`
+	Limit       int        `json:"limit"`
+	QueryOffset string     `json:"query_offset"`
+	Rules       []PromRule `json:"rules"`
+}
+
+type PromRule struct {
+	Record        string            `json:"record"`
+	Alert         string            `json:"alert"`
+	Expr          string            `json:"expr"`
+	For           string            `json:"for"`
+	KeepFiringFor string            `json:"keep_firing_for"`
+	Labels        map[string]string `json:"labels"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+// defaultPromRuleSeverity 未配置或无法识别 severity 标签时使用的告警等级
+const defaultPromRuleSeverity = "P1"
+
+var (
+	promLabelTemplatePattern = regexp.MustCompile(`\{\{-?\s*(?:\$labels|\.Labels)\.([a-zA-Z_][a-zA-Z0-9_]*)\s*-?\}\}`)
+	promIndexTemplatePattern = regexp.MustCompile(`\{\{-?\s*index\s+(?:\$labels|\.Labels)\s+"([^"]+)"\s*-?\}\}`)
+	promValueTemplatePattern = regexp.MustCompile(`\{\{-?\s*(?:\$value|\.Value)\s*-?\}\}`)
+	promNumberPattern        = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	promSetOperatorPattern   = regexp.MustCompile(`(?i)\b(and|or|unless)\b`)
+)
+
+// ParsePromRuleFile 解析 Prometheus 规则文件
+func ParsePromRuleFile(content []byte) (PromRuleFile, error) {
+	var file PromRuleFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return file, fmt.Errorf("Prometheus 规则文件解析失败, err: %s", err.Error())
+	}
+	if len(file.Groups) == 0 {
+		return file, fmt.Errorf("Prometheus 规则文件中没有规则组")
+	}
+	return file, nil
+}
+
+// ConvertPromRules 将 Prometheus 告警规则转换为指标规则, 数据源、规则组等由调用方设置;
+// 名称及查询相同、仅告警等级不同的规则合并为一条多等级规则, 不支持的配置记录在返回的问题列表中
+func ConvertPromRules(file PromRuleFile, defaultEvalInterval int64) ([]models.AlertRule, []models.PromRuleIssue) {
+	var (
+		rules  []models.AlertRule
+		issues []models.PromRuleIssue
+		// 合并键 -> rules 下标
+		merged = make(map[string]int)
+		names  = make(map[string]bool)
+	)
+	for _, group := range file.Groups {
+		interval := defaultEvalInterval
+		if group.Interval != "" {
+			d, err := model.ParseDuration(group.Interval)
+			if err != nil || time.Duration(d) < time.Second {
+				issues = append(issues, models.PromRuleIssue{Group: group.Name, Message: fmt.Sprintf("评估间隔 %s 无效, 使用默认值 %d 秒", group.Interval, defaultEvalInterval)})
+			} else {
+				interval = int64(time.Duration(d) / time.Second)
+			}
+		}
+		if group.Limit > 0 {
+			issues = append(issues, models.PromRuleIssue{Group: group.Name, Message: "不支持 limit, 已忽略"})
+		}
+		if group.QueryOffset != "" {
+			issues = append(issues, models.PromRuleIssue{Group: group.Name, Message: "不支持 query_offset, 已忽略"})
+		}
+
+		for _, pr := range group.Rules {
+			issue := func(skipped bool, format string, args ...interface{}) {
+				issues = append(issues, models.PromRuleIssue{Group: group.Name, Alert: pr.Alert, Message: fmt.Sprintf(format, args...), Skipped: skipped})
+			}
+			if pr.Alert == "" {
+				issues = append(issues, models.PromRuleIssue{Group: group.Name, Alert: pr.Record, Message: "不支持记录规则 (record)", Skipped: true})
+				continue
+			}
+			if strings.TrimSpace(pr.Expr) == "" {
+				issue(true, "表达式为空")
+				continue
+			}
+
+			rule, severity, ruleIssues := convertPromRule(group.Name, pr)
+			for _, msg := range ruleIssues {
+				issue(false, "%s", msg)
+			}
+			if rule.PrometheusConfig.PromQL == "" {
+				continue
+			}
+			rule.EvalInterval = interval
+			rule.EvalTimeType = "second"
+
+			key := tools.JsonMarshal([]interface{}{pr.Alert, rule.PrometheusConfig.PromQL, rule.ForDuration, rule.EvalInterval, rule.ExternalLabels, rule.PrometheusConfig.Annotations})
+			if i, ok := merged[key]; ok {
+				if hasPromSeverity(rules[i].PrometheusConfig.Rules, severity) {
+					issue(true, "与同名规则的告警等级 %s 重复", severity)
+					continue
+				}
+				rules[i].PrometheusConfig.Rules = append(rules[i].PrometheusConfig.Rules, rule.PrometheusConfig.Rules...)
+				continue
+			}
+
+			// 同名但无法合并的规则, 以规则组名称区分
+			name := pr.Alert
+			if names[name] {
+				name = fmt.Sprintf("%s [%s]", pr.Alert, group.Name)
+				for i := 2; names[name]; i++ {
+					name = fmt.Sprintf("%s [%s] #%d", pr.Alert, group.Name, i)
+				}
+				issue(false, "存在同名规则, 重命名为 %s", name)
+			}
+			names[name] = true
+			rule.RuleName = name
+
+			merged[key] = len(rules)
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, issues
+}
+
+// convertPromRule 转换单条告警规则, 返回规则、告警等级及转换问题, 无法转换时规则的 PromQL 为空
+func convertPromRule(group string, pr PromRule) (models.AlertRule, string, []string) {
+	var issues []string
+
+	rule := models.AlertRule{
+		RuleName:    pr.Alert,
+		Description: fmt.Sprintf("导入自 Prometheus 规则组 %s", group),
+	}
+
+	if pr.For != "" {
+		d, err := model.ParseDuration(pr.For)
+		if err != nil {
+			return rule, "", []string{fmt.Sprintf("持续时间 for: %s 无效", pr.For)}
+		}
+		rule.ForDuration = int64(time.Duration(d) / time.Second)
+	}
+	if pr.KeepFiringFor != "" {
+		issues = append(issues, fmt.Sprintf("不支持 keep_firing_for: %s, 已忽略", pr.KeepFiringFor))
+	}
+
+	severity, severityIssue := MapPromSeverity(pr.Labels["severity"])
+	if severityIssue != "" {
+		issues = append(issues, severityIssue)
+	}
+
+	for k, v := range pr.Labels {
+		if k == "severity" {
+			continue
+		}
+		if rule.ExternalLabels == nil {
+			rule.ExternalLabels = make(map[string]string)
+		}
+		if strings.Contains(v, "{{") {
+			issues = append(issues, fmt.Sprintf("标签 %s 的模版 %s 不支持, 已保留原文", k, v))
+		}
+		rule.ExternalLabels[k] = v
+	}
+
+	annotations, annotationIssues := convertPromAnnotations(pr.Annotations)
+	issues = append(issues, annotationIssues...)
+
+	expr := stripPromComments(pr.Expr)
+	promQL, condition, ok := SplitPromThreshold(expr)
+	if !ok {
+		// 非数值阈值的表达式, 返回的每条序列均视为触发; 与 NaN 的 != 比较恒为真, 以 bool 输出 1 作为告警值
+		promQL, condition = fmt.Sprintf("(%s) != bool NaN", expr), "==1"
+		issues = append(issues, "表达式不以数值阈值结尾, 查询返回的序列均触发告警, 告警值固定为 1")
+	}
+	if err := validateQueryExpr("PromQL", promQL); err != nil {
+		return rule, "", append(issues, err.Error())
+	}
+
+	rule.PrometheusConfig = models.PrometheusConfig{
+		PromQL:      promQL,
+		Annotations: annotations,
+		Rules:       []models.Rules{{Severity: severity, Expr: condition}},
+	}
+
+	return rule, severity, issues
+}
+
+// stripPromComments 去除表达式中的 # 注释, 引号内的 # 不处理
+func stripPromComments(expr string) string {
+	var (
+		b     strings.Builder
+		quote rune
+	)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' && i+1 < len(runes) {
+				b.WriteRune(c)
+				i++
+				c = runes[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '#':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// SplitPromThreshold 将 "<查询> <比较运算符> <数值>" 形式的表达式拆分为查询及评估条件, 如 up == 0 拆分为 up 与 ==0;
+// 数值在左侧时交换运算符, 表达式含 bool 修饰、集合运算或多个比较运算时不拆分, 表达式不能包含注释
+func SplitPromThreshold(expr string) (string, string, bool) {
+	var (
+		ops   []int
+		depth int
+		quote rune
+	)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '>', '<', '=', '!':
+			if depth != 0 {
+				continue
+			}
+			if c == '=' || c == '!' {
+				if i+1 >= len(runes) || runes[i+1] != '=' {
+					continue
+				}
+			}
+			ops = append(ops, i)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				i++
+			}
+		}
+	}
+	if len(ops) != 1 {
+		return "", "", false
+	}
+
+	pos := ops[0]
+	op := string(runes[pos])
+	if pos+1 < len(runes) && runes[pos+1] == '=' {
+		op += "="
+	}
+	lhs := strings.TrimSpace(string(runes[:pos]))
+	rhs := strings.TrimSpace(string(runes[pos+len(op):]))
+	if strings.HasPrefix(rhs, "bool ") || promSetOperatorPattern.MatchString(lhs) || promSetOperatorPattern.MatchString(rhs) {
+		return "", "", false
+	}
+
+	query, number := lhs, rhs
+	if promNumberPattern.MatchString(lhs) && !promNumberPattern.MatchString(rhs) {
+		query, number = rhs, lhs
+		op = map[string]string{">": "<", "<": ">", ">=": "<=", "<=": ">=", "==": "==", "!=": "!="}[op]
+	}
+	if query == "" || !promNumberPattern.MatchString(number) {
+		return "", "", false
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return "", "", false
+	}
+
+	return query, op + strconv.FormatFloat(value, 'f', -1, 64), true
+}
+
+// mapPromSeverity 将 severity 标签映射为告警等级, 返回映射说明
+func MapPromSeverity(severity string) (string, string) {
+	switch strings.ToLower(severity) {
+	case "p0", "critical", "page", "emergency", "fatal":
+		return "P0", ""
+	case "p1", "warning", "error", "major":
+		return "P1", ""
+	case "p2", "info", "minor", "notice":
+		return "P2", ""
+	case "":
+		return defaultPromRuleSeverity, fmt.Sprintf("未配置 severity 标签, 使用告警等级 %s", defaultPromRuleSeverity)
+	default:
+		return defaultPromRuleSeverity, fmt.Sprintf("无法识别的 severity 标签 %s, 使用告警等级 %s", severity, defaultPromRuleSeverity)
+	}
+}
+
+func hasPromSeverity(rules []models.Rules, severity string) bool {
+	for _, r := range rules {
+		if r.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// convertPromAnnotations 转换注解中的模版, {{ $labels.x }} 及 {{ $value }} 转换为 ${x} 及 ${value}, 其他模版语法保留原文
+func convertPromAnnotations(annotations map[string]string) (string, []string) {
+	var issues []string
+	converted := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		v = promLabelTemplatePattern.ReplaceAllString(v, "$${$1}")
+		v = promIndexTemplatePattern.ReplaceAllString(v, "$${$1}")
+		v = promValueTemplatePattern.ReplaceAllString(v, "$${value}")
+		if strings.Contains(v, "{{") {
+			issues = append(issues, fmt.Sprintf("注解 %s 中的模版语法不支持, 已保留原文", k))
+		}
+		converted[k] = v
+	}
+	sort.Strings(issues)
+
+	return JoinPromAnnotations(converted), issues
+}
+
+// JoinPromAnnotations 将注解合并为告警详情, summary 与 description 在前, 其余按名称排序以 key: value 输出
+func JoinPromAnnotations(annotations map[string]string) string {
+	var keys []string
+	for k := range annotations {
+		if k != "summary" && k != "description" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"summary", "description"}, keys...)
+
+	var lines []string
+	for _, k := range keys {
+		v := strings.TrimSpace(annotations[k])
+		if v == "" {
+			continue
+		}
+		if k != "summary" && k != "description" {
+			v = k + ": " + v
+		}
+		lines = append(lines, v)
+	}
+
+	return strings.Join(lines, "\n")
+}
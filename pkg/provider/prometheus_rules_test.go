@@ -0,0 +1,96 @@
+package provider
+
+import "testing"
+
+func TestSplitPromThreshold(t *testing.T) {
+	var cases = []struct {
+		expr      string
+		query     string
+		condition string
+		ok        bool
+	}{
+		{`up == 0`, `up`, `==0`, true},
+		{`sum(rate(http_requests_total{code=~"5.."}[5m])) by (job) / sum(rate(http_requests_total[5m])) by (job) > 0.05`, `sum(rate(http_requests_total{code=~"5.."}[5m])) by (job) / sum(rate(http_requests_total[5m])) by (job)`, `>0.05`, true},
+		{`1e3 <= node_load1`, `node_load1`, `>=1000`, true},
+		{`node_filesystem_avail_bytes{fstype!="tmpfs"} < -1.5`, `node_filesystem_avail_bytes{fstype!="tmpfs"}`, `<-1.5`, true},
+		{`absent(up{job="api"})`, "", "", false},
+		{`up > bool 0`, "", "", false},
+		{`up == 0 and on() hour() > 8`, "", "", false},
+		{`a > on(job) b`, "", "", false},
+	}
+	for _, c := range cases {
+		query, condition, ok := SplitPromThreshold(c.expr)
+		if ok != c.ok || query != c.query || condition != c.condition {
+			t.Errorf("%s -> %q %q %v", c.expr, query, condition, ok)
+		}
+	}
+}
+
+func TestConvertPromRules(t *testing.T) {
+	file, err := ParsePromRuleFile([]byte(`
+groups:
+  - name: node
+    interval: 30s
+    rules:
+      - record: job:up:sum
+        expr: sum(up) by (job)
+      - alert: HighLoad
+        expr: node_load1 > 10 # load
+        for: 5m
+        labels:
+          severity: warning
+          team: infra
+        annotations:
+          summary: "{{ $labels.instance }} load is {{ $value }}"
+          runbook_url: https://runbook/load
+      - alert: HighLoad
+        expr: node_load1 > 20
+        for: 5m
+        labels:
+          severity: critical
+          team: infra
+        annotations:
+          summary: "{{ $labels.instance }} load is {{ $value }}"
+          runbook_url: https://runbook/load
+      - alert: TargetMissing
+        expr: absent(up{job="api"})
+        keep_firing_for: 10m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, issues := ConvertPromRules(file, 60)
+	if len(rules) != 2 {
+		t.Fatalf("rules = %d, want 2", len(rules))
+	}
+
+	load := rules[0]
+	if load.RuleName != "HighLoad" || load.EvalInterval != 30 || load.ForDuration != 300 || load.ExternalLabels["team"] != "infra" {
+		t.Errorf("HighLoad -> %+v", load)
+	}
+	if load.PrometheusConfig.PromQL != "node_load1" || len(load.PrometheusConfig.Rules) != 2 || load.PrometheusConfig.Rules[1].Severity != "P0" || load.PrometheusConfig.Rules[1].Expr != ">20" {
+		t.Errorf("HighLoad prometheus config -> %+v", load.PrometheusConfig)
+	}
+	if want := "${instance} load is ${value}\nrunbook_url: https://runbook/load"; load.PrometheusConfig.Annotations != want {
+		t.Errorf("annotations -> %q", load.PrometheusConfig.Annotations)
+	}
+
+	missing := rules[1]
+	if missing.PrometheusConfig.PromQL != `(absent(up{job="api"})) != bool NaN` || missing.PrometheusConfig.Rules[0].Expr != "==1" {
+		t.Errorf("TargetMissing -> %+v", missing.PrometheusConfig)
+	}
+
+	var skipped, keepFiring bool
+	for _, issue := range issues {
+		if issue.Alert == "job:up:sum" && issue.Skipped {
+			skipped = true
+		}
+		if issue.Alert == "TargetMissing" && !issue.Skipped && issue.Message == "不支持 keep_firing_for: 10m, 已忽略" {
+			keepFiring = true
+		}
+	}
+	if !skipped || !keepFiring {
+		t.Errorf("issues -> %+v", issues)
+	}
+}
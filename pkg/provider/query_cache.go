@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/zeromicro/go-zero/core/collection"
+	"sync"
+	"time"
+	"watchAlert/pkg/tools"
+)
+
+// queryCacheLimit 查询结果缓存的最大条目数
+const queryCacheLimit = 1000
+
+// logQueryResult 日志查询结果
+type logQueryResult struct {
+	Logs  []Logs
+	Count int
+	Stats QueryStats
+}
+
+var (
+	queryCache    *collection.Cache
+	queryCacheTTL time.Duration
+	queryCacheMux sync.RWMutex
+)
+
+// SetQueryCacheTTL 设置查询结果缓存时长, 0 表示关闭缓存, 支持配置热加载时更新
+func SetQueryCacheTTL(ttl time.Duration) {
+	queryCacheMux.Lock()
+	defer queryCacheMux.Unlock()
+
+	if ttl == queryCacheTTL {
+		return
+	}
+
+	queryCacheTTL = ttl
+	queryCache = nil
+	if ttl <= 0 {
+		return
+	}
+
+	c, err := collection.NewCache(ttl, collection.WithLimit(queryCacheLimit), collection.WithName("datasource-query"))
+	if err != nil {
+		queryCacheTTL = 0
+		return
+	}
+	queryCache = c
+}
+
+func getQueryCache() (*collection.Cache, time.Duration) {
+	queryCacheMux.RLock()
+	defer queryCacheMux.RUnlock()
+	return queryCache, queryCacheTTL
+}
+
+// AlignQueryTime 按缓存时长对齐查询时间, 使同一时间窗口内的相同查询生成一致的 StartAt/EndAt, 未开启缓存时原样返回
+func AlignQueryTime(t time.Time) time.Time {
+	_, ttl := getQueryCache()
+	if ttl <= 0 {
+		return t
+	}
+	return t.Truncate(ttl)
+}
+
+// QueryLogsWithCache 执行日志查询, 开启缓存时相同数据源 + 查询条件 (含时间窗口) 在缓存时长内复用查询结果,
+// 并发的相同查询只会请求一次数据源, 查询失败时不缓存
+func QueryLogsWithCache(datasourceId string, options LogQueryOptions, query func(LogQueryOptions) ([]Logs, int, error)) ([]Logs, int, error) {
+	cache, _ := getQueryCache()
+	if cache == nil {
+		return query(options)
+	}
+
+	key := fmt.Sprintf("%s:%s", datasourceId, tools.Md5Hash([]byte(tools.JsonMarshal(options))))
+	val, err := cache.Take(key, func() (any, error) {
+		// 查询统计随结果缓存, 复用结果的查询同样可获取
+		var stats QueryStats
+		opts := options
+		opts.Stats = &stats
+		res, count, err := query(opts)
+		if err != nil {
+			return nil, err
+		}
+		return logQueryResult{Logs: res, Count: count, Stats: stats}, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := val.(logQueryResult)
+	if options.Stats != nil {
+		*options.Stats = result.Stats
+	}
+	return cloneLogs(result.Logs), result.Count, nil
+}
+
+// cloneLogs 复制查询结果, 避免不同规则修改标签时相互影响
+func cloneLogs(logs []Logs) []Logs {
+	if logs == nil {
+		return nil
+	}
+
+	res := make([]Logs, len(logs))
+	for i, l := range logs {
+		res[i] = Logs{ProviderName: l.ProviderName, Metric: cloneMap(l.Metric)}
+		if l.Message != nil {
+			res[i].Message = make([]map[string]interface{}, len(l.Message))
+			for j, m := range l.Message {
+				res[i].Message[j] = cloneMap(m)
+			}
+		}
+	}
+	return res
+}
+
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryLogsWithCache(t *testing.T) {
+	SetQueryCacheTTL(time.Minute)
+	defer SetQueryCacheTTL(0)
+
+	var calls int
+	query := func(LogQueryOptions) ([]Logs, int, error) {
+		calls++
+		return []Logs{{Metric: map[string]interface{}{"app": "api"}}}, 1, nil
+	}
+
+	options := LogQueryOptions{Loki: Loki{Query: `{app="api"}`}, StartAt: int64(100), EndAt: int64(160)}
+	res, _, _ := QueryLogsWithCache("ds-1", options, query)
+	res[0].Metric["rule_name"] = "rule-a"
+
+	res, count, _ := QueryLogsWithCache("ds-1", options, query)
+	if calls != 1 || count != 1 {
+		t.Errorf("calls -> %d, count -> %d, want 1", calls, count)
+	}
+	if _, ok := res[0].Metric["rule_name"]; ok {
+		t.Errorf("cached result modified -> %v", res[0].Metric)
+	}
+
+	_, _, _ = QueryLogsWithCache("ds-2", options, query)
+	if calls != 2 {
+		t.Errorf("calls -> %d, want 2", calls)
+	}
+
+	at := time.Unix(960, 0)
+	if AlignQueryTime(at.Add(30*time.Second)) != AlignQueryTime(at.Add(10*time.Second)) {
+		t.Errorf("query time not aligned")
+	}
+}
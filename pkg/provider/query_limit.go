@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"watchAlert/pkg/metrics"
+)
+
+var (
+	// 数据源未单独配置时的最大并发查询数, 0 表示不限制
+	defaultQueryLimit   int
+	queryAcquireTimeout = 10 * time.Second
+	// 数据源单独配置的最大并发查询数
+	datasourceQueryLimits = make(map[string]int)
+	// 各数据源的并发额度, 缓冲区大小即最大并发查询数
+	querySemaphores = make(map[string]chan struct{})
+	queryLimitMux   sync.Mutex
+)
+
+// SetQueryLimit 设置全局的数据源最大并发查询数及等待并发额度的超时时间, 支持配置热加载时更新
+func SetQueryLimit(maxConcurrent int, acquireTimeout time.Duration) {
+	queryLimitMux.Lock()
+	defer queryLimitMux.Unlock()
+
+	queryAcquireTimeout = acquireTimeout
+	if maxConcurrent == defaultQueryLimit {
+		return
+	}
+	defaultQueryLimit = maxConcurrent
+	// 限制变更后重新创建并发额度, 进行中的查询结束后归还至原有额度
+	querySemaphores = make(map[string]chan struct{})
+}
+
+// SetDatasourceQueryLimit 设置数据源的最大并发查询数, 0 表示使用全局配置
+func SetDatasourceQueryLimit(datasourceId string, maxConcurrent int) {
+	queryLimitMux.Lock()
+	defer queryLimitMux.Unlock()
+
+	if datasourceQueryLimits[datasourceId] == maxConcurrent {
+		return
+	}
+	if maxConcurrent > 0 {
+		datasourceQueryLimits[datasourceId] = maxConcurrent
+	} else {
+		delete(datasourceQueryLimits, datasourceId)
+	}
+	delete(querySemaphores, datasourceId)
+}
+
+// RemoveDatasourceQueryLimit 数据源删除时清理并发限制
+func RemoveDatasourceQueryLimit(datasourceId string) {
+	queryLimitMux.Lock()
+	defer queryLimitMux.Unlock()
+	delete(datasourceQueryLimits, datasourceId)
+	delete(querySemaphores, datasourceId)
+}
+
+// getQuerySemaphore 获取数据源的并发额度, 未限制时返回 nil
+func getQuerySemaphore(datasourceId string) (chan struct{}, time.Duration) {
+	queryLimitMux.Lock()
+	defer queryLimitMux.Unlock()
+
+	if sem, ok := querySemaphores[datasourceId]; ok {
+		return sem, queryAcquireTimeout
+	}
+
+	limit := defaultQueryLimit
+	if l, ok := datasourceQueryLimits[datasourceId]; ok {
+		limit = l
+	}
+	if limit <= 0 {
+		return nil, queryAcquireTimeout
+	}
+
+	sem := make(chan struct{}, limit)
+	querySemaphores[datasourceId] = sem
+	return sem, queryAcquireTimeout
+}
+
+// AcquireQuery 获取数据源的并发查询额度, 返回的 release 用于归还额度;
+// 超时未获取到时返回 ErrBusy, 由本轮评估跳过该查询, 避免排队的查询持续堆积
+func AcquireQuery(ctx context.Context, datasourceId string) (release func(), err error) {
+	sem, timeout := getQuerySemaphore(datasourceId)
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	release = func() { <-sem }
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		metrics.DatasourceBusyTotal.WithLabelValues(datasourceId).Inc()
+		return nil, fmt.Errorf("%w, 并发查询数已达上限 %d, 等待 %s 后仍未获取到查询额度, datasourceId: %s", ErrBusy, cap(sem), timeout, datasourceId)
+	}
+}
+
+// RetryQuery 获取数据源的并发查询额度后按重试策略执行查询, 重试期间持有额度, 用于告警评估的数据源查询
+func RetryQuery(ctx context.Context, datasourceId string, fn func() error) error {
+	release, err := AcquireQuery(ctx, datasourceId)
+	if err != nil {
+		recordQueryError(ctx, err)
+		return err
+	}
+	defer release()
+
+	return Retry(ctx, datasourceId, fn)
+}
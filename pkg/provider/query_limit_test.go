@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireQuery(t *testing.T) {
+	SetQueryLimit(0, 50*time.Millisecond)
+	defer SetQueryLimit(0, 10*time.Second)
+	SetDatasourceQueryLimit("ds-limit", 1)
+	defer RemoveDatasourceQueryLimit("ds-limit")
+
+	release, err := AcquireQuery(context.Background(), "ds-limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcquireQuery(context.Background(), "ds-limit"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("err = %v, want ErrBusy", err)
+	}
+	if IsTransientError(ErrBusy) {
+		t.Error("ErrBusy should not be retried")
+	}
+
+	// 未限制的数据源不等待
+	if _, err := AcquireQuery(context.Background(), "ds-unlimited"); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	release, err = AcquireQuery(context.Background(), "ds-limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
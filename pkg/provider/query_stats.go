@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// queryStatsFailureLimit 保留的分片失败原因条数
+const queryStatsFailureLimit = 5
+
+// QueryStats 数据源返回的查询统计, 用于判断查询是否过慢或结果是否完整, 目前支持 ElasticSearch
+type QueryStats struct {
+	// 数据源侧的查询耗时, 分页拉取时为各次请求之和
+	TookMs int64 `json:"tookMs"`
+	// 查询的分片数及失败的分片数
+	ShardsTotal  int `json:"shardsTotal"`
+	ShardsFailed int `json:"shardsFailed"`
+	// 跨集群搜索中不可用而被跳过的远程集群数
+	SkippedClusters int `json:"skippedClusters"`
+	// 分片失败原因
+	Failures []string `json:"failures,omitempty"`
+	// 组合聚合的分桶数超出上限时为该上限, 结果仅包含前 BucketLimit 个分桶
+	BucketLimit int `json:"bucketLimit,omitempty"`
+}
+
+// Partial 查询结果是否不完整, 部分分片失败或远程集群被跳过时结果仅包含其余分片的数据, 分桶数超出上限时仅包含部分分桶
+func (s QueryStats) Partial() bool {
+	return s.ShardsFailed > 0 || s.SkippedClusters > 0 || s.BucketLimit > 0
+}
+
+// Warning 查询结果不完整的说明, 结果完整时返回空
+func (s QueryStats) Warning() string {
+	if !s.Partial() {
+		return ""
+	}
+
+	var parts []string
+	if s.ShardsFailed > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d 个分片查询失败", s.ShardsFailed, s.ShardsTotal))
+	}
+	if s.SkippedClusters > 0 {
+		parts = append(parts, fmt.Sprintf("%d 个远程集群不可用已跳过", s.SkippedClusters))
+	}
+	if s.BucketLimit > 0 {
+		parts = append(parts, fmt.Sprintf("分桶数超出上限, 仅包含前 %d 个分桶", s.BucketLimit))
+	}
+	warning := "查询结果不完整, " + strings.Join(parts, ", ")
+	if len(s.Failures) > 0 {
+		warning += ", 原因: " + strings.Join(s.Failures, "; ")
+	}
+	return warning
+}
+
+type queryStatsKey struct{}
+
+// queryStatsRecorder 记录同一次规则评估中结果不完整的数据源查询
+type queryStatsRecorder struct {
+	mux      sync.Mutex
+	warnings []string
+}
+
+// WithQueryStatsRecorder 返回记录查询统计的上下文, 用于在规则评估结束时判断是否存在结果不完整的查询
+func WithQueryStatsRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryStatsKey{}, &queryStatsRecorder{})
+}
+
+// RecordQueryStats 记录数据源查询统计, 仅记录结果不完整的查询
+func RecordQueryStats(ctx context.Context, datasourceId string, stats QueryStats) {
+	r, ok := ctx.Value(queryStatsKey{}).(*queryStatsRecorder)
+	if !ok || !stats.Partial() {
+		return
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.warnings = append(r.warnings, fmt.Sprintf("datasourceId: %s, %s", datasourceId, stats.Warning()))
+}
+
+// IncompleteQueries 获取上下文中记录的结果不完整的查询
+func IncompleteQueries(ctx context.Context) []string {
+	r, ok := ctx.Value(queryStatsKey{}).(*queryStatsRecorder)
+	if !ok {
+		return nil
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]string(nil), r.warnings...)
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"watchAlert/internal/models"
+)
+
+// Factory 数据源客户端的构造函数, 返回的客户端存入客户端池, 由评估逻辑按数据源类型断言为 MetricsFactoryProvider、LogsFactoryProvider 等接口
+type Factory func(ctx context.Context, ds models.AlertDataSource) (interface{}, error)
+
+var (
+	factoriesMux sync.RWMutex
+	factories    = make(map[string]Factory)
+)
+
+// Register 注册数据源类型的客户端构造函数, 各数据源在 init 中按 DsProviderName 注册, 新增数据源类型无需修改客户端池及健康检查;
+// 类型名为空、构造函数为空或重复注册时 panic
+func Register(name string, factory Factory) {
+	factoriesMux.Lock()
+	defer factoriesMux.Unlock()
+
+	if name == "" || factory == nil {
+		panic("provider: Register name or factory is empty")
+	}
+	if _, exists := factories[name]; exists {
+		panic("provider: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// NewClient 按数据源类型创建客户端
+func NewClient(ctx context.Context, ds models.AlertDataSource) (interface{}, error) {
+	factoriesMux.RLock()
+	factory, ok := factories[ds.Type]
+	factoriesMux.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported datasource type: %s", ds.Type)
+	}
+	return factory(ctx, ds)
+}
+
+// Providers 获取已注册的数据源类型
+func Providers() []string {
+	factoriesMux.RLock()
+	defer factoriesMux.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{
+		PrometheusDsProvider, VictoriaMetricsDsProvider, LokiDsProviderName, AliCloudSLSDsProviderName,
+		ElasticSearchDsProviderName, VictoriaLogsDsProviderName, ClickHouseDsProviderName, GraylogDsProviderName,
+		SQLDsProviderName, HTTPProbeDsProviderName, JaegerDsProviderName, KubernetesDsProviderName, CloudWatchDsProviderName,
+	} {
+		if !slices.Contains(Providers(), name) {
+			t.Errorf("provider %s not registered", name)
+		}
+	}
+
+	if _, err := NewClient(context.Background(), models.AlertDataSource{Type: "Unknown"}); err == nil {
+		t.Errorf("unknown type should fail")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("duplicate register should panic")
+			}
+		}()
+		Register(LokiDsProviderName, func(context.Context, models.AlertDataSource) (interface{}, error) { return nil, nil })
+	}()
+}
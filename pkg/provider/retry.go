@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"watchAlert/pkg/metrics"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+// RetryPolicy 数据源查询及健康检查的重试策略
+type RetryPolicy struct {
+	// 最大尝试次数 (含首次), 小于等于 1 时不重试
+	MaxAttempts int
+	// 首次重试等待时间, 之后按指数递增
+	BaseDelay time.Duration
+	// 单次等待时间上限
+	MaxDelay time.Duration
+	// 随机抖动比例 0~1, 避免多个规则同时重试
+	Jitter float64
+}
+
+// RetryStats 数据源重试统计, 用于发现长期不稳定的数据源
+type RetryStats struct {
+	// 累计重试次数
+	Retries int64 `json:"retries"`
+	// 重试耗尽后仍失败的次数
+	Failures int64 `json:"failures"`
+	// 最近一次重试的错误
+	LastError string `json:"lastError"`
+	// 最近一次重试时间
+	LastRetryAt int64 `json:"lastRetryAt"`
+}
+
+var (
+	retryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.2,
+	}
+	retryStats = make(map[string]RetryStats)
+	retryMux   sync.RWMutex
+)
+
+// SetRetryPolicy 设置全局重试策略, 支持配置热加载时更新
+func SetRetryPolicy(policy RetryPolicy) {
+	retryMux.Lock()
+	defer retryMux.Unlock()
+	retryPolicy = policy
+}
+
+func getRetryPolicy() RetryPolicy {
+	retryMux.RLock()
+	defer retryMux.RUnlock()
+	return retryPolicy
+}
+
+// GetRetryStats 获取各数据源的重试统计
+func GetRetryStats() map[string]RetryStats {
+	retryMux.RLock()
+	defer retryMux.RUnlock()
+
+	stats := make(map[string]RetryStats, len(retryStats))
+	for k, v := range retryStats {
+		stats[k] = v
+	}
+	return stats
+}
+
+// IsTransientError 判断是否为可重试的临时错误, 认证失败及查询语句错误不重试
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, ErrAuth) || errors.Is(err, ErrBadQuery) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrConnection) || errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Retry 按重试策略执行 fn, 仅对临时错误重试, key 为数据源 ID, 用于统计重试次数及熔断; ctx 用于日志携带 traceId
+func Retry(ctx context.Context, key string, fn func() error) error {
+	if err := allowRequest(ctx, key); err != nil {
+		recordQueryError(ctx, err)
+		return err
+	}
+
+	policy := getRetryPolicy()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			recordBreakerResult(key, nil)
+			return nil
+		}
+		if !IsTransientError(err) {
+			recordBreakerResult(key, err)
+			metrics.DatasourceErrorsTotal.WithLabelValues(key).Inc()
+			recordQueryError(ctx, err)
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			break
+		}
+
+		delay := policy.backoff(attempt)
+		recordRetry(key, err, false)
+		logc.Errorf(ctx, fmt.Sprintf("数据源请求失败, %s 后进行第 %d 次重试, datasourceId: %s, err: %s", delay, attempt, key, err.Error()))
+
+		// 评估取消 (如服务退出) 时不再等待重试, 不计入熔断
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			recordQueryError(ctx, err)
+			return err
+		}
+	}
+
+	if policy.MaxAttempts > 1 {
+		recordRetry(key, err, true)
+	}
+	recordBreakerResult(key, err)
+	metrics.DatasourceErrorsTotal.WithLabelValues(key).Inc()
+	recordQueryError(ctx, err)
+	return err
+}
+
+// backoff 计算第 attempt 次重试的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (rand.Float64()*2 - 1))
+	}
+	return delay
+}
+
+func recordRetry(key string, err error, exhausted bool) {
+	retryMux.Lock()
+	defer retryMux.Unlock()
+
+	stats := retryStats[key]
+	if exhausted {
+		stats.Failures++
+	} else {
+		stats.Retries++
+		stats.LastRetryAt = time.Now().Unix()
+	}
+	stats.LastError = err.Error()
+	retryStats[key] = stats
+}
+
+// RemoveRetryStats 数据源删除时清理重试统计
+func RemoveRetryStats(key string) {
+	retryMux.Lock()
+	defer retryMux.Unlock()
+	delete(retryStats, key)
+	metrics.DatasourceErrorsTotal.DeleteLabelValues(key)
+}
+
+type queryErrorKey struct{}
+
+// queryErrorRecorder 记录同一上下文中数据源请求最终失败的错误
+type queryErrorRecorder struct {
+	mux sync.Mutex
+	err error
+}
+
+// WithQueryErrorRecorder 返回记录数据源请求错误的上下文, 经 Retry 执行的请求最终失败时记录错误,
+// 用于在查询函数不返回错误时判断本次查询是否失败
+func WithQueryErrorRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryErrorKey{}, &queryErrorRecorder{})
+}
+
+// QueryError 获取上下文中记录的最近一次请求错误, 未记录时返回 nil
+func QueryError(ctx context.Context) error {
+	r, ok := ctx.Value(queryErrorKey{}).(*queryErrorRecorder)
+	if !ok {
+		return nil
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.err
+}
+
+func recordQueryError(ctx context.Context, err error) {
+	r, ok := ctx.Value(queryErrorKey{}).(*queryErrorRecorder)
+	if !ok {
+		return
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.err = err
+}
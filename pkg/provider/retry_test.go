@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+	var attempts int
+	err := Retry(context.Background(), "test-unavailable", func() error {
+		attempts++
+		return newStatusError(http.StatusServiceUnavailable, "")
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("5xx attempts -> %d, want 3", attempts)
+	}
+	if stats := GetRetryStats()["test-unavailable"]; stats.Retries != 2 || stats.Failures != 1 {
+		t.Errorf("stats -> %+v", stats)
+	}
+
+	attempts = 0
+	_ = Retry(context.Background(), "test-bad-query", func() error {
+		attempts++
+		return newStatusError(http.StatusBadRequest, "")
+	})
+	if attempts != 1 {
+		t.Errorf("4xx attempts -> %d, want 1", attempts)
+	}
+}
+
+func TestQueryErrorRecorder(t *testing.T) {
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	ctx := WithQueryErrorRecorder(context.Background())
+	_ = Retry(ctx, "test-recorder", func() error { return nil })
+	if err := QueryError(ctx); err != nil {
+		t.Errorf("success -> %v, want nil", err)
+	}
+
+	_ = Retry(ctx, "test-recorder", func() error {
+		return newStatusError(http.StatusServiceUnavailable, "")
+	})
+	if err := QueryError(ctx); err == nil {
+		t.Errorf("failure -> nil, want error")
+	}
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	defer SetRetryPolicy(getRetryPolicy())
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var attempts int
+	start := time.Now()
+	err := Retry(ctx, "test-canceled", func() error {
+		attempts++
+		return newStatusError(http.StatusServiceUnavailable, "")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("attempts -> %d, err -> %v", attempts, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("canceled retry waited %s", elapsed)
+	}
+}
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// ValidateRuleQuery 按数据源类型静态校验规则的查询配置, 不访问数据源; sqlDriver 为 SQL 数据源的驱动, 其他类型忽略
+func ValidateRuleQuery(rule models.AlertRule, sqlDriver string) error {
+	switch rule.DatasourceType {
+	case PrometheusDsProvider, VictoriaMetricsDsProvider:
+		return validatePromQuery(rule.PrometheusConfig)
+	case LokiDsProviderName:
+		if err := validateQueryExpr("LogQL", rule.LokiConfig.LogQL); err != nil {
+			return err
+		}
+	case VictoriaLogsDsProviderName:
+		if err := validateQueryExpr("LogsQL", rule.VictoriaLogsConfig.LogQL); err != nil {
+			return err
+		}
+	case AliCloudSLSDsProviderName:
+		if rule.AliCloudSLSConfig.LogQL == "" {
+			return newBadQueryError("查询语句为空")
+		}
+	case ElasticSearchDsProviderName:
+		if err := validateEsQuery(rule.ElasticSearchConfig); err != nil {
+			return err
+		}
+		if rule.ElasticSearchConfig.IsMultiMetricAggregation() {
+			return nil
+		}
+	case ClickHouseDsProviderName:
+		switch rule.ClickHouseConfig.QueryType {
+		case models.ClickHouseQueryTypeRawSQL:
+			if rule.ClickHouseConfig.RawSQL == "" {
+				return newBadQueryError("RawSQL 为空")
+			}
+		default:
+			if rule.ClickHouseConfig.Table == "" {
+				return newBadQueryError("表名为空")
+			}
+		}
+	case GraylogDsProviderName:
+		if rule.GraylogConfig.QueryType == models.GraylogQueryTypeRawQuery && rule.GraylogConfig.Query == "" {
+			return newBadQueryError("查询语句为空")
+		}
+	case SQLDsProviderName:
+		if err := validateReadOnlySQL(sqlDriver, rule.SQLConfig.SQL); err != nil {
+			return err
+		}
+	case HTTPProbeDsProviderName:
+		return validateHTTPProbeConditions(rule.HTTPProbeConfig)
+	case JaegerDsProviderName:
+		if rule.JaegerConfig.Service == "" {
+			return newBadQueryError("服务名称为空")
+		}
+		return validateCondition("错误 Span 数的评估条件", rule.JaegerConfig.ErrorCondition)
+	case CloudWatchDsProviderName:
+		if rule.CloudWatchConfig.Namespace == "" || rule.CloudWatchConfig.MetricName == "" {
+			return newBadQueryError("命名空间及指标名称不能为空")
+		}
+		return nil
+	case "KubernetesEvent":
+		if rule.KubernetesConfig.Resource == "" {
+			return newBadQueryError("资源类型为空")
+		}
+		return nil
+	default:
+		return newBadQueryError("不支持的数据源类型: %s", rule.DatasourceType)
+	}
+
+	// 日志类规则的评估条件
+	return validateCondition("评估条件", rule.LogEvalCondition)
+}
+
+func validatePromQuery(config models.PrometheusConfig) error {
+	if err := validateQueryExpr("PromQL", config.PromQL); err != nil {
+		return err
+	}
+	if config.IsAnomalyMode() {
+		return nil
+	}
+	if len(config.Rules) == 0 {
+		return newBadQueryError("告警条件为空")
+	}
+	for _, r := range config.Rules {
+		if r.Severity == "" {
+			return newBadQueryError("告警条件 %s 的告警等级为空", r.Expr)
+		}
+		if err := validateCondition("告警条件", r.Expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateEsQuery(config models.ElasticSearchConfig) error {
+	for _, ft := range config.FieldTypes {
+		if ft.Field == "" {
+			return newBadQueryError("字段类型声明的字段名为空")
+		}
+		switch ft.Type {
+		case models.EsFieldTypeNumber, models.EsFieldTypeTime, models.EsFieldTypeString:
+		default:
+			return newBadQueryError("字段 %s 的类型 %q 不支持, 可选 number / time / string", ft.Field, ft.Type)
+		}
+	}
+
+	names := make(map[string]struct{}, len(config.RuntimeFields))
+	for _, f := range config.RuntimeFields {
+		if f.Name == "" {
+			return newBadQueryError("运行时字段名为空")
+		}
+		if f.Name == esDocIndexKey || f.Name == esDocIdKey || f.Name == esDocHighlightKey {
+			return newBadQueryError("运行时字段名 %s 与保留字段冲突", f.Name)
+		}
+		if _, exists := names[f.Name]; exists {
+			return newBadQueryError("运行时字段名重复, name: %s", f.Name)
+		}
+		names[f.Name] = struct{}{}
+		if strings.TrimSpace(f.Script) == "" {
+			return newBadQueryError("运行时字段 %s 的脚本为空", f.Name)
+		}
+		switch f.GetType() {
+		case models.EsRuntimeFieldTypeKeyword, models.EsRuntimeFieldTypeLong, models.EsRuntimeFieldTypeDouble,
+			models.EsRuntimeFieldTypeDate, models.EsRuntimeFieldTypeBoolean, models.EsRuntimeFieldTypeIp:
+		default:
+			return newBadQueryError("运行时字段 %s 的类型 %q 不支持, 可选 keyword / long / double / date / boolean / ip", f.Name, f.Type)
+		}
+	}
+
+	if config.CountOnly && config.EsQueryType == models.EsQueryTypeAggregation {
+		return newBadQueryError("聚合查询不支持仅统计命中数")
+	}
+
+	switch config.EsQueryType {
+	case models.EsQueryTypeRawJson:
+		if config.RawJson == "" {
+			return newBadQueryError("RawJson 为空")
+		}
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(config.RawJson), &query); err != nil {
+			return newBadQueryError("RawJson 不是有效的 JSON 对象: %s", err.Error())
+		}
+	case models.EsQueryTypeAggregation:
+		if config.Aggregation.Composite {
+			if len(config.Aggregation.GetCompositeFields()) == 0 {
+				return newBadQueryError("组合聚合的分桶字段为空")
+			}
+			if config.Aggregation.MaxBuckets < 0 || config.Aggregation.MaxBuckets > esCompositeMaxBucketsCeiling {
+				return newBadQueryError("组合聚合的分桶数上限需在 0 ~ %d 之间", esCompositeMaxBucketsCeiling)
+			}
+		}
+		for _, metric := range config.Aggregation.Metrics {
+			if metric.Name == "" {
+				return newBadQueryError("聚合指标名称为空")
+			}
+			if err := validateCondition("聚合指标 "+metric.Name+" 的评估条件", metric.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateHTTPProbeConditions(config models.HTTPProbeConfig) error {
+	if len(config.Conditions) == 0 {
+		return newBadQueryError("拨测评估条件为空")
+	}
+	for _, c := range config.Conditions {
+		switch c.Field {
+		case models.HTTPProbeFieldStatusCode, models.HTTPProbeFieldLatency, models.HTTPProbeFieldBodyMatch:
+		default:
+			return newBadQueryError("不支持的拨测结果字段: %s", c.Field)
+		}
+		if c.Condition == "" {
+			return newBadQueryError("拨测结果字段 %s 的评估条件为空", c.Field)
+		}
+		if err := validateCondition("拨测结果字段 "+c.Field+" 的评估条件", c.Condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCondition 校验 >100 形式的评估条件, 为空时不校验
+func validateCondition(name, condition string) error {
+	if condition == "" {
+		return nil
+	}
+	if _, _, err := tools.ProcessRuleExpr(condition); err != nil {
+		return newBadQueryError("%s无效: %s", name, condition)
+	}
+	return nil
+}
+
+// validateQueryExpr 校验查询语句非空, 括号成对且引号闭合, 引号内的内容不参与括号匹配
+func validateQueryExpr(name, expr string) error {
+	if expr == "" {
+		return newBadQueryError("%s 为空", name)
+	}
+
+	var (
+		stack []rune
+		quote rune
+	)
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[c] {
+				return newBadQueryError("%s 的括号不匹配: %s", name, expr)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if quote != 0 {
+		return newBadQueryError("%s 的引号未闭合: %s", name, expr)
+	}
+	if len(stack) > 0 {
+		return newBadQueryError("%s 的括号不匹配: %s", name, expr)
+	}
+
+	return nil
+}
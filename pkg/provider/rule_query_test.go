@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"testing"
+	"watchAlert/internal/models"
+)
+
+func TestValidateQueryExpr(t *testing.T) {
+	var cases = map[string]bool{
+		`sum(rate(http_requests_total{code=~"5.."}[5m])) by (job)`: true,
+		`{app="api"} |= "error (timeout"`:                          true,
+		`sum(rate(http_requests_total[5m])`:                        false,
+		`up{job="api"]`:                                            false,
+		`{app="api} |= "error"`:                                    false,
+		"":                                                         false,
+	}
+	for expr, ok := range cases {
+		if err := validateQueryExpr("PromQL", expr); (err == nil) != ok {
+			t.Errorf("%s -> %v, want ok %v", expr, err, ok)
+		}
+	}
+}
+
+func TestValidateRuleQuery(t *testing.T) {
+	rule := models.AlertRule{
+		DatasourceType: PrometheusDsProvider,
+		PrometheusConfig: models.PrometheusConfig{
+			PromQL: "up == 0",
+			Rules:  []models.Rules{{Severity: "P0", Expr: ">0"}},
+		},
+	}
+	if err := ValidateRuleQuery(rule, ""); err != nil {
+		t.Errorf("prometheus -> %v", err)
+	}
+	rule.PrometheusConfig.Rules[0].Expr = "abc"
+	if err := ValidateRuleQuery(rule, ""); err == nil {
+		t.Error("invalid prometheus condition should fail")
+	}
+
+	rule = models.AlertRule{
+		DatasourceType:   ElasticSearchDsProviderName,
+		LogEvalCondition: ">10",
+		ElasticSearchConfig: models.ElasticSearchConfig{
+			EsQueryType: models.EsQueryTypeRawJson,
+			RawJson:     `{"query": {"match_all": {}}`,
+		},
+	}
+	if err := ValidateRuleQuery(rule, ""); err == nil {
+		t.Error("invalid raw json should fail")
+	}
+
+	rule = models.AlertRule{
+		DatasourceType: SQLDsProviderName,
+		SQLConfig:      models.SQLConfig{SQL: "DELETE FROM orders"},
+	}
+	if err := ValidateRuleQuery(rule, models.SQLDriverMySQL); err == nil {
+		t.Error("write sql should fail")
+	}
+
+	if err := ValidateRuleQuery(models.AlertRule{DatasourceType: "Unknown"}, ""); err == nil {
+		t.Error("unknown datasource type should fail")
+	}
+}
@@ -17,16 +17,19 @@ type TracesFactoryProvider interface {
 }
 
 type TraceQueryOptions struct {
-	Tags    string `json:"tags,omitempty"`    // 查询标签
-	Service string `json:"service,omitempty"` // 服务名称
-	Limit   int64  `json:"limit,omitempty"`   // 要返回的最大条目数
-	StartAt int64  `json:"startAt,omitempty"` // 查询的开始时间，以微秒 Unix 表示。
-	EndAt   int64  `json:"endAt,omitempty"`   // 查询的结束时间，以微秒 Unix 表示。
+	Tags      string `json:"tags,omitempty"`      // 查询标签
+	Service   string `json:"service,omitempty"`   // 服务名称
+	Operation string `json:"operation,omitempty"` // 操作名称
+	Limit     int64  `json:"limit,omitempty"`     // 要返回的最大条目数
+	StartAt   int64  `json:"startAt,omitempty"`   // 查询的开始时间，以微秒 Unix 表示。
+	EndAt     int64  `json:"endAt,omitempty"`     // 查询的结束时间，以微秒 Unix 表示。
 }
 
 type Traces struct {
-	Service string
-	TraceId string
+	Service    string
+	TraceId    string
+	SpanCount  int // 链路中的 Span 数
+	ErrorCount int // 链路中的错误 Span 数
 }
 
 func (t Traces) GetFingerprint() string {
@@ -35,8 +38,10 @@ func (t Traces) GetFingerprint() string {
 
 func (t Traces) GetMetric() map[string]interface{} {
 	return map[string]interface{}{
-		"service": t.Service,
-		"trace":   t.TraceId,
+		"service":     t.Service,
+		"trace":       t.TraceId,
+		"span_count":  t.SpanCount,
+		"error_count": t.ErrorCount,
 	}
 }
 
@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 	"watchAlert/internal/models"
@@ -14,6 +17,12 @@ type JaegerDsProvider struct {
 	url            string
 }
 
+func init() {
+	Register(JaegerDsProviderName, func(_ context.Context, ds models.AlertDataSource) (interface{}, error) {
+		return NewJaegerClient(ds)
+	})
+}
+
 func NewJaegerClient(datasource models.AlertDataSource) (TracesFactoryProvider, error) {
 	_, err := tools.Get(nil, datasource.HTTP.URL, 10)
 	if err != nil {
@@ -31,7 +40,37 @@ type JaegerResult struct {
 }
 
 type JaegerData struct {
-	TraceId string `json:"traceID"`
+	TraceId string       `json:"traceID"`
+	Spans   []JaegerSpan `json:"spans"`
+}
+
+type JaegerSpan struct {
+	SpanId        string      `json:"spanID"`
+	OperationName string      `json:"operationName"`
+	Tags          []JaegerTag `json:"tags"`
+}
+
+type JaegerTag struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// IsError 判断 Span 是否为错误状态, 兼容 Jaeger error 标签及 OpenTelemetry 状态码
+func (s JaegerSpan) IsError() bool {
+	for _, tag := range s.Tags {
+		switch tag.Key {
+		case "error":
+			if v := fmt.Sprintf("%v", tag.Value); v == "true" {
+				return true
+			}
+		case "otel.status_code":
+			if v := fmt.Sprintf("%v", tag.Value); v == "ERROR" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (j JaegerDsProvider) Query(options TraceQueryOptions) ([]Traces, error) {
@@ -43,18 +82,34 @@ func (j JaegerDsProvider) Query(options TraceQueryOptions) ([]Traces, error) {
 
 	if options.StartAt == 0 {
 		duration, _ := time.ParseDuration(strconv.Itoa(1) + "h")
-		options.StartAt = curTime.Add(-duration).UnixNano()
+		options.StartAt = curTime.Add(-duration).UnixMicro()
 	}
 
 	if options.EndAt == 0 {
-		options.EndAt = curTime.UnixNano()
+		options.EndAt = curTime.UnixMicro()
 	}
 
-	args := fmt.Sprintf("/api/traces?service=%s&start=%d&end=%d&limit=%d&tags=%s", options.Service, options.StartAt, options.EndAt, options.Limit, options.Tags)
-	requestURL := j.url + args
+	params := url.Values{}
+	params.Set("service", options.Service)
+	params.Set("start", strconv.FormatInt(options.StartAt, 10))
+	params.Set("end", strconv.FormatInt(options.EndAt, 10))
+	params.Set("limit", strconv.FormatInt(options.Limit, 10))
+	if options.Operation != "" {
+		params.Set("operation", options.Operation)
+	}
+	if options.Tags != "" {
+		params.Set("tags", options.Tags)
+	}
+	requestURL := j.url + "/api/traces?" + params.Encode()
 	res, err := tools.Get(nil, requestURL, 10)
 	if err != nil {
-		return nil, err
+		return nil, newConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		return nil, newStatusError(res.StatusCode, string(b))
 	}
 
 	var jaegerResult JaegerResult
@@ -64,9 +119,17 @@ func (j JaegerDsProvider) Query(options TraceQueryOptions) ([]Traces, error) {
 
 	var data []Traces
 	for _, t := range jaegerResult.Data {
+		var errorCount int
+		for _, span := range t.Spans {
+			if span.IsError() {
+				errorCount++
+			}
+		}
 		data = append(data, Traces{
-			Service: options.Service,
-			TraceId: t.TraceId,
+			Service:    options.Service,
+			TraceId:    t.TraceId,
+			SpanCount:  len(t.Spans),
+			ErrorCount: errorCount,
 		})
 	}
 
@@ -74,13 +137,14 @@ func (j JaegerDsProvider) Query(options TraceQueryOptions) ([]Traces, error) {
 }
 
 func (j JaegerDsProvider) Check() (bool, error) {
-	res, err := tools.Get(nil, j.url, 10)
+	res, err := tools.Get(nil, j.url+"/api/services", 10)
 	if err != nil {
-		return false, err
+		return false, newConnectionError(err)
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return false, err
+	if res.StatusCode != http.StatusOK {
+		return false, newStatusError(res.StatusCode, "")
 	}
 	return true, nil
 }
@@ -90,8 +154,7 @@ type JaegerServiceData struct {
 }
 
 func (j JaegerDsProvider) GetJaegerService() (JaegerServiceData, error) {
-	url := j.url + "/api/services"
-	res, err := tools.Get(nil, url, 10)
+	res, err := tools.Get(nil, j.url+"/api/services", 10)
 	if err != nil {
 		return JaegerServiceData{}, err
 	}
@@ -10,6 +10,7 @@ var CodeInfo = map[int64]string{
 	400: "请求失败",
 	401: "Token鉴权失败",
 	403: "权限不足",
+	409: "资源冲突",
 }
 
 func Response(c *gin.Context, httpStatus int, code int, data interface{}, msg string) {
@@ -29,6 +30,12 @@ func Fail(ctx *gin.Context, data interface{}, msg string) {
 	Response(ctx, code, code, data, msg)
 }
 
+// Conflict 资源冲突, 如规则 UID 已被其他规则使用
+func Conflict(ctx *gin.Context, data interface{}, msg string) {
+	code := 409
+	Response(ctx, code, code, data, msg)
+}
+
 func TokenFail(ctx *gin.Context) {
 	code := 401
 	Response(ctx, code, code, nil, CodeInfo[int64(code)])
@@ -37,4 +44,10 @@ func TokenFail(ctx *gin.Context) {
 func PermissionFail(ctx *gin.Context) {
 	code := 403
 	Response(ctx, code, code, nil, CodeInfo[int64(code)])
+}
+
+// PermissionDenied 权限不足, 返回缺少的权限等具体原因
+func PermissionDenied(ctx *gin.Context, reason string) {
+	code := 403
+	Response(ctx, code, code, nil, CodeInfo[int64(code)]+": "+reason)
 }
\ No newline at end of file
@@ -2,8 +2,15 @@ package sender
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 	"watchAlert/pkg/tools"
 )
 
@@ -22,15 +29,20 @@ func NewDingSender() SendInter {
 }
 
 func (d *DingDingSender) Send(params SendParams) error {
+	hook, err := signDingHook(params.Hook, params.Sign, time.Now())
+	if err != nil {
+		return err
+	}
+
 	cardContentByte := bytes.NewReader([]byte(params.Content))
-	res, err := tools.Post(nil, params.Hook, cardContentByte, 10)
+	res, err := tools.Post(nil, hook, cardContentByte, 10)
 	if err != nil {
 		return err
 	}
 
 	var response DingResponse
-	if err := tools.ParseReaderBody(res.Body, &response); err != nil {
-		return errors.New(fmt.Sprintf("Error unmarshalling Dingding response: %s", err.Error()))
+	if err := readResponse(res, &response); err != nil {
+		return errors.New(fmt.Sprintf("DingDing 发送失败, %s", err.Error()))
 	}
 	if response.Code != 0 {
 		return errors.New(response.Msg)
@@ -38,3 +50,25 @@ func (d *DingDingSender) Send(params SendParams) error {
 
 	return nil
 }
+
+// signDingHook 机器人开启加签时, 在 Hook 地址中追加 timestamp 及 sign 参数, 未配置密钥时原样返回
+func signDingHook(hook, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return hook, nil
+	}
+
+	// 签名内容为 "<毫秒时间戳>\n<密钥>", 以密钥做 HmacSHA256 后 Base64
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	h := hmac.New(sha256.New, []byte(secret))
+	if _, err := h.Write([]byte(timestamp + "\n" + secret)); err != nil {
+		return "", err
+	}
+	sign := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(hook, "?") {
+		sep = "&"
+	}
+
+	return hook + sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign), nil
+}
@@ -1,10 +1,15 @@
 package sender
 
 import (
+	"bytes"
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"sort"
 	"watchAlert/pkg/client"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/tools"
 )
 
 // EmailSender 邮件发送策略
@@ -19,16 +24,85 @@ func (e *EmailSender) Send(params SendParams) error {
 	if err != nil {
 		return errors.New("获取系统配置失败: " + err.Error())
 	}
-	eCli := client.NewEmailClient(setting.EmailConfig.ServerAddress, setting.EmailConfig.Email, setting.EmailConfig.Token, setting.EmailConfig.Port)
+	eCli := client.NewSMTPEmailClient(client.EmailConfig{
+		ServerAddr:         setting.EmailConfig.ServerAddress,
+		Port:               setting.EmailConfig.Port,
+		From:               setting.EmailConfig.Email,
+		User:               setting.EmailConfig.User,
+		Password:           setting.EmailConfig.Token,
+		TLSMode:            setting.EmailConfig.TLSMode,
+		InsecureSkipVerify: setting.EmailConfig.InsecureSkipVerify,
+	})
 	if params.IsRecovered {
 		params.Email.Subject = params.Email.Subject + "「已恢复」"
 	} else {
 		params.Email.Subject = params.Email.Subject + "「报警中」"
 	}
-	err = eCli.Send(params.Email.To, params.Email.CC, params.Email.Subject, []byte(params.Content))
+
+	var attachments []client.EmailAttachment
+	if params.Email.AttachLogs && len(params.LogSamples) > 0 {
+		content, err := buildLogsCSV(params.LogSamples)
+		if err != nil {
+			// 附件生成失败不影响告警通知发送
+			logc.Error(ctx.Ctx, fmt.Sprintf("生成日志样本附件失败, rule: %s, err: %s", params.RuleName, err.Error()))
+		} else {
+			attachments = append(attachments, client.EmailAttachment{
+				Filename:    "logs.csv",
+				ContentType: "text/csv; charset=utf-8",
+				Content:     content,
+			})
+		}
+	}
+
+	err = eCli.Send(params.Email.To, params.Email.CC, params.Email.Subject, []byte(params.Content), attachments...)
 	if err != nil {
+		if errors.Is(err, client.ErrEmailAuth) || errors.Is(err, client.ErrEmailConnection) {
+			logc.Error(ctx.Ctx, fmt.Sprintf("邮件服务配置异常, server: %s:%d, err: %s", setting.EmailConfig.ServerAddress, setting.EmailConfig.Port, err.Error()))
+			return err
+		}
 		return fmt.Errorf("%s, %s", err.Error(), "Content: "+params.Content)
 	}
 
 	return nil
 }
+
+// buildLogsCSV 将日志样本转换为 CSV, 列为所有日志字段的并集, 非字符串的值以 JSON 格式输出
+func buildLogsCSV(samples []map[string]interface{}) ([]byte, error) {
+	var columns []string
+	seen := make(map[string]struct{})
+	for _, sample := range samples {
+		for k := range sample {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	// UTF-8 BOM, 避免 Excel 打开时中文乱码
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			switch v := sample[column].(type) {
+			case nil:
+			case string:
+				row[i] = v
+			default:
+				row[i] = tools.JsonMarshal(v)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
@@ -2,11 +2,15 @@ package sender
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"watchAlert/internal/models"
 	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/metrics"
+	"watchAlert/pkg/tools"
+	"watchAlert/pkg/tracing"
 
 	"github.com/zeromicro/go-zero/core/logc"
 )
@@ -32,32 +36,129 @@ type (
 		Content string
 		// 电话号码
 		PhoneNumber []string
+		// Telegram
+		Telegram models.Telegram
+		// PagerDuty
+		PagerDuty models.PagerDuty
+		// OpsGenie
+		OpsGenie models.OpsGenie
+		// 自定义 Hook
+		WebHook models.WebHook
+		// Slack
+		Slack models.Slack
+		// 告警指纹, 用于 Slack 等按告警关联消息线程
+		Fingerprint string
+		// 命中的日志样本, 邮件开启附件时以 CSV 发送
+		LogSamples []map[string]interface{}
 		// 签名
 		Sign string `json:"sign,omitempty"`
+		// 限流, 每分钟最多发送的消息数, 0 表示不限流
+		RateLimit int
+		// 投递 ID 及第几次尝试, 由 send 生成, 重试时沿用
+		DeliveryId string
+		Attempt    int
+		// 已发送成功的分段数, 消息拆分为多条发送时重试跳过已发送的部分
+		SentParts int
 	}
 
 	// SendInter 发送通知的接口
 	SendInter interface {
 		Send(params SendParams) error
 	}
+
+	// partialSendError 分段发送的消息部分发送成功后失败, sentParts 为累计已发送的分段数
+	partialSendError struct {
+		sentParts int
+		err       error
+	}
 )
 
+func (e *partialSendError) Error() string {
+	return fmt.Sprintf("已发送 %d 段消息后失败, %s", e.sentParts, e.err.Error())
+}
+
+func (e *partialSendError) Unwrap() error {
+	return e.err
+}
+
 // Sender 发送通知的主函数
 func Sender(ctx *ctx.Context, sendParams SendParams) error {
+	// 维护模式下缓存通知, 维护结束后汇总发送
+	if holdForMaintenance(sendParams) {
+		logc.Info(ctx.Ctx, fmt.Sprintf("维护模式中, 通知已缓存, noticeId: %s, rule: %s", sendParams.NoticeId, sendParams.RuleName))
+		return nil
+	}
+
+	// 触发限流时消息进入队列, 由后台合并为汇总消息发送
+	if sendParams.RateLimit > 0 && sendParams.NoticeId != "" {
+		if !getNoticeLimiter(sendParams.NoticeId, sendParams.RateLimit).allow(ctx, sendParams) {
+			logc.Info(ctx.Ctx, fmt.Sprintf("通知触发限流, 已加入汇总队列, noticeId: %s, rule: %s", sendParams.NoticeId, sendParams.RuleName))
+			return nil
+		}
+	}
+
+	return send(ctx, sendParams)
+}
+
+// SendTest 发送测试通知, 与告警通知使用相同的发送器, 不经过限流且不记录通知记录
+func SendTest(ctx *ctx.Context, sendParams SendParams) error {
+	sender, err := senderFactory(sendParams.NoticeType)
+	if err != nil {
+		return err
+	}
+
+	_, span := tracing.Start(ctx.Ctx, "notice.test",
+		tracing.AttrNoticeType.String(sendParams.NoticeType),
+		tracing.AttrNoticeId.String(sendParams.NoticeId),
+	)
+	err = sender.Send(sendParams)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("测试通知发送失败, type: %s, err: %s", sendParams.NoticeType, err.Error())
+	}
+
+	return nil
+}
+
+// send 发送通知并记录每次尝试的结果, 失败时加入重试队列
+func send(ctx *ctx.Context, sendParams SendParams) error {
 	// 根据通知类型获取对应的发送器
 	sender, err := senderFactory(sendParams.NoticeType)
 	if err != nil {
 		return fmt.Errorf("Send alarm failed, %s", err.Error())
 	}
 
+	if sendParams.DeliveryId == "" {
+		sendParams.DeliveryId = tools.RandId()
+	}
+	sendParams.Attempt++
+
 	// 发送通知
-	if err := sender.Send(sendParams); err != nil {
-		addRecord(ctx, sendParams, 1, sendParams.Content, err.Error())
-		return fmt.Errorf("Send alarm failed to %s, err: %s", sendParams.NoticeType, err.Error())
+	_, span := tracing.Start(ctx.Ctx, "notice.send",
+		tracing.AttrNoticeType.String(sendParams.NoticeType),
+		tracing.AttrNoticeId.String(sendParams.NoticeId),
+		tracing.AttrRuleName.String(sendParams.RuleName),
+	)
+	err = sender.Send(sendParams)
+	tracing.End(span, err)
+	if err != nil {
+		metrics.NotificationsTotal.WithLabelValues(sendParams.NoticeType, "failed").Inc()
+		var partialErr *partialSendError
+		if errors.As(err, &partialErr) {
+			sendParams.SentParts = partialErr.sentParts
+		}
+		status := models.NoticeRecordStatusFailed
+		nextRetryAt, ok := scheduleRetry(ctx, sendParams)
+		if ok {
+			status = models.NoticeRecordStatusRetrying
+		}
+		addRecord(ctx, sendParams, status, sendParams.Content, err.Error(), nextRetryAt)
+		return fmt.Errorf("Send alarm failed to %s, attempt: %d, err: %s", sendParams.NoticeType, sendParams.Attempt, err.Error())
 	}
 
 	// 记录成功发送的日志
-	addRecord(ctx, sendParams, 0, sendParams.Content, "")
+	metrics.NotificationsTotal.WithLabelValues(sendParams.NoticeType, "success").Inc()
+	addRecord(ctx, sendParams, models.NoticeRecordStatusDelivered, sendParams.Content, "", 0)
 	logc.Info(ctx.Ctx, fmt.Sprintf("Send alarm ok, msg: %s", sendParams.Content))
 	return nil
 }
@@ -77,13 +178,23 @@ func senderFactory(noticeType string) (SendInter, error) {
 		return NewWebHookSender(), nil
 	case "PhoneCall":
 		return NewPhoneCallSender(), nil
+	case "Telegram":
+		return NewTelegramSender(), nil
+	case "Teams":
+		return NewTeamsSender(), nil
+	case "PagerDuty":
+		return NewPagerDutySender(), nil
+	case "OpsGenie":
+		return NewOpsGenieSender(), nil
+	case "Slack":
+		return NewSlackSender(), nil
 	default:
 		return nil, fmt.Errorf("无效的通知类型: %s", noticeType)
 	}
 }
 
-// addRecord 记录通知发送结果
-func addRecord(ctx *ctx.Context, sendParams SendParams, status int, msg, errMsg string) {
+// addRecord 记录通知发送结果, 失败时 errMsg 包含通知对象返回的状态码及响应内容
+func addRecord(ctx *ctx.Context, sendParams SendParams, status int, msg, errMsg string, nextRetryAt int64) {
 	err := ctx.DB.Notice().AddRecord(models.NoticeRecord{
 		Date:     time.Now().Format("2006-01-02"),
 		CreateAt: time.Now().Unix(),
@@ -95,6 +206,10 @@ func addRecord(ctx *ctx.Context, sendParams SendParams, status int, msg, errMsg
 		Status:   status,
 		AlarmMsg: msg,
 		ErrMsg:   errMsg,
+
+		DeliveryId:  sendParams.DeliveryId,
+		Attempt:     sendParams.Attempt,
+		NextRetryAt: nextRetryAt,
 	})
 	if err != nil {
 		logc.Errorf(ctx.Ctx, fmt.Sprintf("Add notice record failed, err: %s", err.Error()))
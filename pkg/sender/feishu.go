@@ -50,8 +50,8 @@ func (f *FeiShuSender) Send(params SendParams) error {
 	}
 
 	var response FeiShuResponse
-	if err := tools.ParseReaderBody(res.Body, &response); err != nil {
-		return errors.New(fmt.Sprintf("Error unmarshalling Feishu response: %s", err.Error()))
+	if err := readResponse(res, &response); err != nil {
+		return errors.New(fmt.Sprintf("FeiShu 发送失败, %s", err.Error()))
 	}
 	if response.Code != 0 {
 		return errors.New(response.Msg)
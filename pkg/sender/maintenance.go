@@ -0,0 +1,82 @@
+package sender
+
+import (
+	"fmt"
+	"sync"
+	"watchAlert/pkg/ctx"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+// maintenanceState 维护模式期间缓存的通知, 按通知对象分组, 维护结束时合并为汇总消息;
+// 缓存仅保存在内存中, 维护期间重启服务后此前缓存的通知不再补发
+type maintenanceState struct {
+	mux     sync.Mutex
+	enabled bool
+	pending map[string][]SendParams
+	dropped map[string]int
+}
+
+var maintenance = &maintenanceState{}
+
+// SetMaintenance 切换维护模式, 关闭时将维护期间缓存的通知按通知对象发送一条汇总
+func SetMaintenance(c *ctx.Context, enabled bool) {
+	maintenance.mux.Lock()
+	if maintenance.enabled == enabled {
+		maintenance.mux.Unlock()
+		return
+	}
+
+	maintenance.enabled = enabled
+	if enabled {
+		maintenance.pending = make(map[string][]SendParams)
+		maintenance.dropped = make(map[string]int)
+		maintenance.mux.Unlock()
+		return
+	}
+
+	pending, dropped := maintenance.pending, maintenance.dropped
+	maintenance.pending, maintenance.dropped = nil, nil
+	maintenance.mux.Unlock()
+
+	go flushMaintenance(c, pending, dropped)
+}
+
+// InMaintenance 是否处于维护模式
+func InMaintenance() bool {
+	maintenance.mux.Lock()
+	defer maintenance.mux.Unlock()
+	return maintenance.enabled
+}
+
+// holdForMaintenance 维护模式下缓存通知, 返回 false 表示未处于维护模式, 正常发送
+func holdForMaintenance(params SendParams) bool {
+	maintenance.mux.Lock()
+	defer maintenance.mux.Unlock()
+
+	if !maintenance.enabled {
+		return false
+	}
+	if len(maintenance.pending[params.NoticeId]) < pendingMaxSize {
+		maintenance.pending[params.NoticeId] = append(maintenance.pending[params.NoticeId], params)
+	} else {
+		maintenance.dropped[params.NoticeId]++
+	}
+	return true
+}
+
+// flushMaintenance 发送维护期间的汇总通知, 不支持汇总的通知类型 (如电话、PagerDuty) 不再补发
+func flushMaintenance(c *ctx.Context, pending map[string][]SendParams, dropped map[string]int) {
+	for noticeId, batch := range pending {
+		total := len(batch) + dropped[noticeId]
+		summary, ok := buildSummaryParams(batch, dropped[noticeId], "维护汇总", "维护期间")
+		if !ok {
+			logc.Info(c.Ctx, fmt.Sprintf("通知类型 %s 不支持汇总, 维护期间的 %d 条通知不再补发, noticeId: %s", batch[0].NoticeType, total, noticeId))
+			continue
+		}
+
+		if err := send(c, summary); err != nil {
+			logc.Error(c.Ctx, err.Error())
+		}
+	}
+}
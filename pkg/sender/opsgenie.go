@@ -0,0 +1,75 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	// OpsGenieSender OpsGenie 发送策略
+	OpsGenieSender struct{}
+)
+
+const opsGenieDefaultApiURL = "https://api.opsgenie.com"
+
+func NewOpsGenieSender() SendInter {
+	return &OpsGenieSender{}
+}
+
+func (o *OpsGenieSender) Send(params SendParams) error {
+	if params.OpsGenie.ApiKey == "" {
+		return errors.New("OpsGenie ApiKey 为空")
+	}
+
+	var alert models.OpsGenieAlert
+	if err := json.Unmarshal([]byte(params.Content), &alert); err != nil {
+		return fmt.Errorf("OpsGenie 告警解析失败, err: %s", err.Error())
+	}
+	if alert.Alias == "" {
+		return errors.New("OpsGenie alias 为空")
+	}
+
+	apiURL := strings.TrimSuffix(params.OpsGenie.ApiURL, "/")
+	if apiURL == "" {
+		apiURL = opsGenieDefaultApiURL
+	}
+
+	var (
+		requestURL string
+		body       string
+	)
+	if params.IsRecovered {
+		requestURL = fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", apiURL, url.PathEscape(alert.Alias))
+		body = tools.JsonMarshal(models.OpsGenieCloseAlert{
+			Source: alert.Source,
+			Note:   alert.Note,
+		})
+	} else {
+		requestURL = apiURL + "/v2/alerts"
+		body = tools.JsonMarshal(alert)
+	}
+
+	headers := map[string]string{"Authorization": "GenieKey " + params.OpsGenie.ApiKey}
+	res, err := tools.Post(headers, requestURL, bytes.NewReader([]byte(body)), 10)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+
+	bodyByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+	return fmt.Errorf("OpsGenie 发送失败, 状态码: %d, %s", res.StatusCode, string(bodyByte))
+}
@@ -0,0 +1,67 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	// PagerDutySender PagerDuty Events API v2 发送策略
+	PagerDutySender struct{}
+
+	PagerDutyResponse struct {
+		Status   string   `json:"status"`
+		Message  string   `json:"message"`
+		DedupKey string   `json:"dedup_key"`
+		Errors   []string `json:"errors"`
+	}
+)
+
+const pagerDutyDefaultApiURL = "https://events.pagerduty.com/v2/enqueue"
+
+func NewPagerDutySender() SendInter {
+	return &PagerDutySender{}
+}
+
+func (p *PagerDutySender) Send(params SendParams) error {
+	if params.PagerDuty.RoutingKey == "" {
+		return errors.New("PagerDuty RoutingKey 为空")
+	}
+
+	var event models.PagerDutyEvent
+	if err := json.Unmarshal([]byte(params.Content), &event); err != nil {
+		return fmt.Errorf("PagerDuty 事件解析失败, err: %s", err.Error())
+	}
+	event.RoutingKey = params.PagerDuty.RoutingKey
+
+	apiURL := params.PagerDuty.ApiURL
+	if apiURL == "" {
+		apiURL = pagerDutyDefaultApiURL
+	}
+
+	res, err := tools.Post(nil, apiURL, bytes.NewReader([]byte(tools.JsonMarshal(event))), 10)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusAccepted {
+		return nil
+	}
+
+	bodyByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+	var response PagerDutyResponse
+	if err := json.Unmarshal(bodyByte, &response); err != nil || response.Message == "" {
+		return fmt.Errorf("PagerDuty 发送失败, 状态码: %d, %s", res.StatusCode, string(bodyByte))
+	}
+	return fmt.Errorf("PagerDuty 发送失败, 状态码: %d, %s %v", res.StatusCode, response.Message, response.Errors)
+}
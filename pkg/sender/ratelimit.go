@@ -0,0 +1,185 @@
+package sender
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/templates"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+const (
+	// summaryMaxLines 汇总消息中最多列出的告警条数
+	summaryMaxLines = 50
+	// pendingMaxSize 单个通知对象最多缓存的待发送消息数, 超出后仅计数
+	pendingMaxSize = 1000
+)
+
+// noticeLimiter 通知对象的令牌桶限流器, 令牌不足时缓存消息, 待令牌恢复后合并为一条汇总消息发送
+type noticeLimiter struct {
+	mux      sync.Mutex
+	rate     int // 每分钟允许发送的消息数
+	tokens   float64
+	last     time.Time
+	pending  []SendParams
+	dropped  int
+	flushing bool
+}
+
+var (
+	noticeLimiters   = make(map[string]*noticeLimiter)
+	noticeLimitersMu sync.Mutex
+)
+
+func getNoticeLimiter(noticeId string, rate int) *noticeLimiter {
+	noticeLimitersMu.Lock()
+	defer noticeLimitersMu.Unlock()
+
+	l, ok := noticeLimiters[noticeId]
+	if !ok {
+		l = &noticeLimiter{
+			rate:   rate,
+			tokens: float64(rate),
+			last:   time.Now(),
+		}
+		noticeLimiters[noticeId] = l
+	}
+	return l
+}
+
+// refill 按时间补充令牌, 桶容量为每分钟的消息数
+func (l *noticeLimiter) refill(rate int) {
+	now := time.Now()
+	l.rate = rate
+	l.tokens = min(float64(l.rate), l.tokens+now.Sub(l.last).Seconds()*float64(l.rate)/60)
+	l.last = now
+}
+
+// allow 获取令牌成功时立即发送, 否则缓存消息并启动后台汇总发送
+func (l *noticeLimiter) allow(c *ctx.Context, params SendParams) bool {
+	// 服务退出时不再缓存, 直接发送
+	if isStopping() {
+		return true
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.refill(params.RateLimit)
+	if l.tokens >= 1 && len(l.pending) == 0 {
+		l.tokens--
+		return true
+	}
+
+	if len(l.pending) < pendingMaxSize {
+		l.pending = append(l.pending, params)
+	} else {
+		l.dropped++
+	}
+
+	if !l.flushing {
+		l.flushing = true
+		flushing.Add(1)
+		go func() {
+			defer flushing.Done()
+			l.flush(c)
+		}()
+	}
+	return false
+}
+
+// flush 等待令牌恢复后发送缓存的消息, 支持汇总的通知类型合并为一条消息; 服务退出时不再等待令牌, 立即发送剩余的消息
+func (l *noticeLimiter) flush(c *ctx.Context) {
+	for {
+		l.mux.Lock()
+		l.refill(l.rate)
+		if l.tokens < 1 && !isStopping() {
+			wait := time.Duration((1 - l.tokens) * 60 / float64(l.rate) * float64(time.Second))
+			l.mux.Unlock()
+			sleep(wait)
+			continue
+		}
+		if len(l.pending) == 0 {
+			l.flushing = false
+			l.mux.Unlock()
+			return
+		}
+
+		l.tokens--
+		batch, dropped := l.pending, l.dropped
+		l.pending, l.dropped = nil, 0
+
+		summary, ok := buildSummaryParams(batch, dropped, "告警汇总", "限流期间")
+		if !ok {
+			// 不支持汇总的通知类型 (如电话、PagerDuty) 逐条发送
+			summary, l.pending = batch[0], batch[1:]
+		}
+		l.mux.Unlock()
+
+		if err := send(c, summary); err != nil {
+			logc.Error(c.Ctx, err.Error())
+		}
+	}
+}
+
+// buildSummaryParams 将限流或维护期间缓存的消息合并为一条汇总消息, name 为汇总消息的名称, period 为缓存消息的时段
+func buildSummaryParams(batch []SendParams, dropped int, name, period string) (SendParams, bool) {
+	first := batch[0]
+	if len(batch) == 1 && dropped == 0 {
+		return first, true
+	}
+
+	total := len(batch) + dropped
+	title := fmt.Sprintf("%s: %s共 %d 条通知", name, period, total)
+
+	var lines []string
+	for i, p := range batch {
+		if i >= summaryMaxLines {
+			lines = append(lines, fmt.Sprintf("... 其余 %d 条未列出", total-summaryMaxLines))
+			break
+		}
+		status := "告警中"
+		if p.IsRecovered {
+			status = "已恢复"
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s (%s)", p.Severity, p.RuleName, status))
+	}
+	if dropped > 0 && len(batch) <= summaryMaxLines {
+		lines = append(lines, fmt.Sprintf("... 其余 %d 条未列出", dropped))
+	}
+
+	var content string
+	if first.NoticeType == "CustomHook" {
+		events := make([]json.RawMessage, 0, len(batch))
+		for _, p := range batch {
+			if json.Valid([]byte(p.Content)) {
+				events = append(events, json.RawMessage(p.Content))
+			}
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"summary": title,
+			"total":   total,
+			"events":  events,
+		})
+		content = string(data)
+	} else {
+		content = templates.SummaryTemplate(first.NoticeType, title, strings.Join(lines, "\n"))
+	}
+	if content == "" {
+		return SendParams{}, false
+	}
+
+	summary := first
+	summary.RuleName = name
+	summary.Severity = ""
+	summary.IsRecovered = false
+	summary.Content = content
+	summary.Email.Subject = name
+	summary.Fingerprint = ""
+	summary.DeliveryId, summary.Attempt = "", 0
+	return summary, true
+}
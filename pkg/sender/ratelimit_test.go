@@ -0,0 +1,114 @@
+package sender
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoticeLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        int
+		tokens      float64
+		elapsed     time.Duration
+		pending     int
+		sends       int
+		wantAllowed int
+		wantPending int
+		wantDropped int
+	}{
+		{name: "within burst", rate: 3, tokens: 3, sends: 3, wantAllowed: 3},
+		{name: "over burst is queued", rate: 3, tokens: 3, sends: 5, wantAllowed: 3, wantPending: 2},
+		{name: "tokens refill over time", rate: 2, tokens: 0, elapsed: 30 * time.Second, sends: 2, wantAllowed: 1, wantPending: 1},
+		{name: "refill is capped at rate", rate: 2, tokens: 0, elapsed: time.Hour, sends: 3, wantAllowed: 2, wantPending: 1},
+		{name: "queued messages keep order", rate: 3, tokens: 3, pending: 1, sends: 1, wantPending: 2},
+		{name: "queue overflow is counted", rate: 1, tokens: 1, sends: pendingMaxSize + 2, wantAllowed: 1, wantPending: pendingMaxSize, wantDropped: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 标记为汇总发送中, 测试不启动后台发送
+			l := &noticeLimiter{rate: tt.rate, tokens: tt.tokens, last: time.Now().Add(-tt.elapsed), flushing: true}
+			l.pending = make([]SendParams, tt.pending)
+
+			var allowed int
+			for i := 0; i < tt.sends; i++ {
+				if l.allow(nil, SendParams{RateLimit: tt.rate}) {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllowed || len(l.pending) != tt.wantPending || l.dropped != tt.wantDropped {
+				t.Errorf("allowed = %d, pending = %d, dropped = %d, want %d, %d, %d", allowed, len(l.pending), l.dropped, tt.wantAllowed, tt.wantPending, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestBuildSummaryParams(t *testing.T) {
+	alert := SendParams{NoticeType: "DingDing", RuleName: "cpu", Severity: "P1", Content: "cpu high", Fingerprint: "fp-1", DeliveryId: "d-1", Attempt: 2}
+	recovered := SendParams{NoticeType: "DingDing", RuleName: "disk", Severity: "P2", IsRecovered: true, Content: "disk ok"}
+
+	tests := []struct {
+		name         string
+		batch        []SendParams
+		dropped      int
+		wantOk       bool
+		wantContains []string
+		wantSame     bool
+	}{
+		{name: "single message sent as is", batch: []SendParams{alert}, wantOk: true, wantSame: true},
+		{
+			name:         "batch merged into summary",
+			batch:        []SendParams{alert, recovered},
+			wantOk:       true,
+			wantContains: []string{"告警汇总: 限流期间共 2 条通知", "- [P1] cpu (告警中)", "- [P2] disk (已恢复)"},
+		},
+		{
+			name:         "dropped messages are counted",
+			batch:        []SendParams{alert},
+			dropped:      3,
+			wantOk:       true,
+			wantContains: []string{"共 4 条通知", "... 其余 3 条未列出"},
+		},
+		{name: "unsupported notice type", batch: []SendParams{{NoticeType: "PhoneCall"}, {NoticeType: "PhoneCall"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, ok := buildSummaryParams(tt.batch, tt.dropped, "告警汇总", "限流期间")
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if tt.wantSame {
+				if summary.Content != tt.batch[0].Content || summary.DeliveryId != tt.batch[0].DeliveryId {
+					t.Errorf("summary = %+v, want the original message", summary)
+				}
+				return
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(summary.Content, s) {
+					t.Errorf("content %q does not contain %q", summary.Content, s)
+				}
+			}
+			if summary.RuleName != "告警汇总" || summary.Fingerprint != "" || summary.DeliveryId != "" || summary.Attempt != 0 {
+				t.Errorf("summary = %+v", summary)
+			}
+		})
+	}
+
+	hooks := []SendParams{
+		{NoticeType: "CustomHook", Content: `{"rule":"cpu"}`},
+		{NoticeType: "CustomHook", Content: "not json"},
+	}
+	summary, ok := buildSummaryParams(hooks, 0, "告警汇总", "限流期间")
+	var body struct {
+		Total  int               `json:"total"`
+		Events []json.RawMessage `json:"events"`
+	}
+	if !ok || json.Unmarshal([]byte(summary.Content), &body) != nil || body.Total != 2 || len(body.Events) != 1 {
+		t.Errorf("custom hook summary = %s", summary.Content)
+	}
+}
@@ -0,0 +1,109 @@
+package sender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"watchAlert/pkg/ctx"
+	"watchAlert/pkg/tools"
+
+	"github.com/zeromicro/go-zero/core/logc"
+)
+
+const (
+	// retryPollInterval 检查重试队列的间隔
+	retryPollInterval = 5 * time.Second
+	// retryBatchSize 每次从重试队列取出的通知数
+	retryBatchSize = 100
+)
+
+var (
+	retryMaxAttempts = 5
+	retryBaseDelay   = 30 * time.Second
+	retryMaxDelay    = 10 * time.Minute
+	retryMux         sync.RWMutex
+)
+
+// SetRetryPolicy 设置通知发送失败后的最大尝试次数及退避时间, 支持配置热加载时更新
+func SetRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) {
+	retryMux.Lock()
+	defer retryMux.Unlock()
+	retryMaxAttempts = maxAttempts
+	retryBaseDelay = baseDelay
+	retryMaxDelay = maxDelay
+}
+
+// scheduleRetry 未达到最大尝试次数时将通知加入重试队列, 返回下次重试时间
+func scheduleRetry(c *ctx.Context, params SendParams) (int64, bool) {
+	retryMux.RLock()
+	maxAttempts, baseDelay, maxDelay := retryMaxAttempts, retryBaseDelay, retryMaxDelay
+	retryMux.RUnlock()
+
+	if params.Attempt >= maxAttempts {
+		return 0, false
+	}
+
+	delay := baseDelay << (params.Attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	retryAt := time.Now().Add(delay).Unix()
+	if err := c.Redis.NoticeRetry().Push(tools.JsonMarshal(params), retryAt); err != nil {
+		logc.Error(c.Ctx, fmt.Sprintf("通知加入重试队列失败, deliveryId: %s, err: %s", params.DeliveryId, err.Error()))
+		return 0, false
+	}
+	return retryAt, true
+}
+
+// StartRetryWorker 定时从重试队列取出到期的通知重新发送, 维护模式下转入维护汇总
+func StartRetryWorker(c *ctx.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		payloads, err := c.Redis.NoticeRetry().PopDue(time.Now().Unix(), retryBatchSize)
+		if err != nil {
+			logc.Error(c.Ctx, fmt.Sprintf("读取通知重试队列失败, err: %s", err.Error()))
+			continue
+		}
+		for _, payload := range payloads {
+			var params SendParams
+			if err := json.Unmarshal([]byte(payload), &params); err != nil {
+				logc.Error(c.Ctx, fmt.Sprintf("解析重试通知失败, err: %s", err.Error()))
+				continue
+			}
+			if holdForMaintenance(params) {
+				continue
+			}
+			if err := send(c, params); err != nil {
+				logc.Error(c.Ctx, err.Error())
+			}
+		}
+	}
+}
+
+// readResponse 读取通知对象的响应, 非 2xx 时返回包含状态码及响应内容的错误, 未返回 2xx 的请求不视为发送成功
+func readResponse(res *http.Response, v interface{}) error {
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("状态码: %d, %s", res.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("响应解析失败, 状态码: %d, %s, err: %s", res.StatusCode, string(body), err.Error())
+	}
+	return nil
+}
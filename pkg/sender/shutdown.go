@@ -0,0 +1,44 @@
+package sender
+
+import (
+	"context"
+	"sync"
+	"time"
+	"watchAlert/pkg/tools"
+)
+
+var (
+	// stopping 服务退出时关闭, 限流及重试的等待提前结束
+	stopping     = make(chan struct{})
+	stoppingOnce sync.Once
+	// flushing 进行中的限流汇总发送
+	flushing sync.WaitGroup
+)
+
+// Shutdown 结束限流及重试的等待, 立即发送各通知对象限流队列中的消息, 并在 ctx 超时前等待发送完成
+func Shutdown(ctx context.Context) error {
+	stoppingOnce.Do(func() { close(stopping) })
+	return tools.WaitWithContext(ctx, &flushing)
+}
+
+// sleep 等待 d, 服务退出时提前返回 false
+func sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stopping:
+		return false
+	}
+}
+
+func isStopping() bool {
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
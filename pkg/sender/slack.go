@@ -0,0 +1,106 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"watchAlert/internal/models"
+	"watchAlert/pkg/client"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	// SlackSender Slack chat.postMessage 发送策略, 同一告警的后续通知及恢复通知回复到首条消息的线程中
+	SlackSender struct{}
+)
+
+const (
+	slackDefaultApiURL = "https://slack.com/api"
+	// slackThreadTTL 线程 ts 的保留时间, 超过后的通知作为新消息发送
+	slackThreadTTL = 7 * 24 * time.Hour
+)
+
+func NewSlackSender() SendInter {
+	return &SlackSender{}
+}
+
+func (s *SlackSender) Send(params SendParams) error {
+	if params.Slack.BotToken == "" || params.Slack.Channel == "" {
+		return errors.New("Slack BotToken 或 Channel 为空")
+	}
+
+	var msg models.SlackMessage
+	if err := json.Unmarshal([]byte(params.Content), &msg); err != nil {
+		return fmt.Errorf("Slack 消息解析失败, err: %s", err.Error())
+	}
+	msg.Channel = params.Slack.Channel
+
+	var threadKey string
+	// Redis 未初始化 (如测试发送) 时不关联线程
+	if params.Fingerprint != "" && params.NoticeId != "" && client.Redis != nil {
+		threadKey = string(models.BuildSlackThreadCacheKey(params.NoticeId, params.Fingerprint))
+		msg.ThreadTs = getSlackThreadTs(threadKey)
+	}
+
+	ts, err := s.postMessage(params.Slack, msg)
+	if err != nil {
+		return err
+	}
+
+	if threadKey == "" {
+		return nil
+	}
+	switch {
+	case params.IsRecovered:
+		// 告警恢复后生命周期结束, 再次触发时作为新消息发送
+		client.Redis.Del(threadKey)
+	case msg.ThreadTs == "":
+		client.Redis.Set(threadKey, ts, slackThreadTTL)
+	}
+
+	return nil
+}
+
+// postMessage 发送消息并返回消息 ts
+func (s *SlackSender) postMessage(conf models.Slack, msg models.SlackMessage) (string, error) {
+	apiURL := strings.TrimSuffix(conf.ApiURL, "/")
+	if apiURL == "" {
+		apiURL = slackDefaultApiURL
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + conf.BotToken,
+		"Content-Type":  "application/json; charset=utf-8",
+	}
+	res, err := tools.Post(headers, apiURL+"/chat.postMessage", bytes.NewReader([]byte(tools.JsonMarshal(msg))), 10)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	bodyByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+
+	// Slack Web API 业务错误同样返回 200, 需根据 ok 字段判断
+	var response models.SlackResponse
+	if err := json.Unmarshal(bodyByte, &response); err != nil {
+		return "", fmt.Errorf("Slack 发送失败, 状态码: %d, %s", res.StatusCode, string(bodyByte))
+	}
+	if !response.Ok {
+		return "", fmt.Errorf("Slack 发送失败, 状态码: %d, err: %s", res.StatusCode, response.Error)
+	}
+
+	return response.Ts, nil
+}
+
+// getSlackThreadTs 获取告警首条消息的 ts
+func getSlackThreadTs(key string) string {
+	ts, _ := client.Redis.Get(key).Result()
+	return ts
+}
@@ -0,0 +1,41 @@
+package sender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	// TeamsSender Microsoft Teams Incoming Webhook 发送策略
+	TeamsSender struct{}
+)
+
+func NewTeamsSender() SendInter {
+	return &TeamsSender{}
+}
+
+func (t *TeamsSender) Send(params SendParams) error {
+	cardContentByte := bytes.NewReader([]byte(params.Content))
+	res, err := tools.Post(nil, params.Hook, cardContentByte, 10)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	bodyByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+
+	// 旧版 Connector 在请求失败时也会返回 200, 需根据 Body 判断
+	body := strings.TrimSpace(string(bodyByte))
+	if res.StatusCode < 200 || res.StatusCode >= 300 || strings.Contains(body, "returned HTTP error") {
+		return errors.New(fmt.Sprintf("Teams 发送失败, 状态码: %d, %s", res.StatusCode, body))
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"watchAlert/pkg/tools"
+)
+
+type (
+	// TelegramSender Telegram Bot 发送策略
+	TelegramSender struct{}
+
+	telegramMessage struct {
+		ChatId    string `json:"chat_id"`
+		Text      string `json:"text"`
+		ParseMode string `json:"parse_mode"`
+	}
+
+	TelegramResponse struct {
+		Ok          bool   `json:"ok"`
+		ErrorCode   int    `json:"error_code"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+)
+
+// telegramHTTPClient 不使用 tools.Post, 避免请求失败时将包含 BotToken 的地址写入日志
+var telegramHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+const (
+	telegramDefaultApiURL = "https://api.telegram.org"
+	// telegramMaxMessageLength 单条消息最大长度
+	telegramMaxMessageLength = 4096
+	// telegramMaxRetries 触发限流 (429) 时的最大重试次数
+	telegramMaxRetries = 3
+	// telegramMaxRetryAfter 限流等待时间上限, 单位秒
+	telegramMaxRetryAfter = 60
+)
+
+func NewTelegramSender() SendInter {
+	return &TelegramSender{}
+}
+
+func (t *TelegramSender) Send(params SendParams) error {
+	if params.Telegram.BotToken == "" || params.Telegram.ChatId == "" {
+		return errors.New("Telegram BotToken 或 ChatId 为空")
+	}
+
+	apiURL := strings.TrimSuffix(params.Telegram.ApiURL, "/")
+	if apiURL == "" {
+		apiURL = telegramDefaultApiURL
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", apiURL, params.Telegram.BotToken)
+
+	// 超出长度限制的消息拆分为多条发送, 重试时跳过已发送的分段
+	chunks := splitTelegramMessage(params.Content, telegramMaxMessageLength)
+	for i := params.SentParts; i < len(chunks); i++ {
+		err := t.sendMessage(endpoint, telegramMessage{
+			ChatId:    params.Telegram.ChatId,
+			Text:      chunks[i],
+			ParseMode: "MarkdownV2",
+		})
+		if err == nil {
+			continue
+		}
+
+		err = redactTelegramToken(err, params.Telegram.BotToken)
+		if i > 0 {
+			return &partialSendError{sentParts: i, err: err}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// sendMessage 发送单条消息, 触发限流时按 retry_after 等待后重试
+func (t *TelegramSender) sendMessage(endpoint string, msg telegramMessage) error {
+	body := tools.JsonMarshal(msg)
+	for attempt := 0; ; attempt++ {
+		res, err := telegramHTTPClient.Post(endpoint, "application/json", strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		var response TelegramResponse
+		err = tools.ParseReaderBody(res.Body, &response)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling Telegram response: %s", err.Error())
+		}
+		if response.Ok {
+			return nil
+		}
+
+		if response.ErrorCode != http.StatusTooManyRequests || attempt >= telegramMaxRetries {
+			return fmt.Errorf("Telegram 发送失败, code: %d, %s", response.ErrorCode, response.Description)
+		}
+
+		retryAfter := min(max(response.Parameters.RetryAfter, 1), telegramMaxRetryAfter)
+		if !sleep(time.Duration(retryAfter) * time.Second) {
+			return fmt.Errorf("Telegram 发送失败, code: %d, %s, 服务退出, 不再重试", response.ErrorCode, response.Description)
+		}
+	}
+}
+
+// redactTelegramToken 请求地址中包含 BotToken, 返回的错误 (如 *url.Error) 及通知记录中不应出现 BotToken
+func redactTelegramToken(err error, token string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = fmt.Errorf("Telegram 请求发送失败, %s %s", urlErr.Op, urlErr.Err.Error())
+	}
+	if msg := err.Error(); strings.Contains(msg, token) {
+		return errors.New(strings.ReplaceAll(msg, token, "<redacted>"))
+	}
+	return err
+}
+
+// splitTelegramMessage 按长度拆分消息, 优先在换行处拆分, 且不拆开 MarkdownV2 的转义字符
+func splitTelegramMessage(text string, limit int) []string {
+	runes := []rune(text)
+	var chunks []string
+	for len(runes) > limit {
+		cut := limit
+		for i := limit - 1; i > limit/2; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+
+		// 末尾为奇数个反斜杠时, 说明拆开了转义字符
+		var backslashes int
+		for i := cut - 1; i >= 0 && runes[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			cut--
+		}
+
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
@@ -2,9 +2,15 @@ package sender
 
 import (
 	"bytes"
-	"errors"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"watchAlert/pkg/tools"
 )
 
@@ -13,24 +19,72 @@ type (
 	WebHookSender struct{}
 )
 
+const (
+	// webHookMaxAttempts 服务端返回 5xx 或请求失败时的最大尝试次数
+	webHookMaxAttempts = 3
+	// webHookSignatureHeader 请求体签名, 格式为 sha256=<hex>, 签名内容为 "<时间戳>.<请求体>"
+	webHookSignatureHeader = "X-WatchAlert-Signature"
+	// webHookTimestampHeader 签名时间戳 (秒), 接收方可据此拒绝过期请求
+	webHookTimestampHeader = "X-WatchAlert-Timestamp"
+)
+
 func NewWebHookSender() SendInter {
 	return &WebHookSender{}
 }
 
 func (w *WebHookSender) Send(params SendParams) error {
-	cardContentByte := bytes.NewReader([]byte(params.Content))
-	res, err := tools.Post(nil, params.Hook, cardContentByte, 10)
-	if err != nil {
-		return err
+	method := strings.ToUpper(params.WebHook.Method)
+	if method == "" {
+		method = http.MethodPost
 	}
 
-	if res.StatusCode != 200 {
-		bodyByte, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	headers := make(map[string]string, len(params.WebHook.Headers)+2)
+	for k, v := range params.WebHook.Headers {
+		headers[k] = v
+	}
+	if params.WebHook.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[webHookTimestampHeader] = timestamp
+		headers[webHookSignatureHeader] = "sha256=" + signWebHook(params.WebHook.Secret, timestamp, params.Content)
+	}
+
+	var err error
+	for attempt := 1; attempt <= webHookMaxAttempts; attempt++ {
+		var retryable bool
+		retryable, err = w.do(method, headers, params.Hook, params.Content)
+		if err == nil || !retryable {
+			return err
+		}
+		if attempt < webHookMaxAttempts && !sleep(time.Duration(attempt)*time.Second) {
+			break
 		}
-		return errors.New(string(bodyByte))
 	}
 
-	return nil
+	return err
+}
+
+// do 发送请求, 返回错误是否可重试
+func (w *WebHookSender) do(method string, headers map[string]string, hook, content string) (bool, error) {
+	res, err := tools.Request(method, headers, hook, bytes.NewReader([]byte(content)), 10)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+
+	bodyByte, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, fmt.Errorf("读取 Body 失败, err: %s", err.Error())
+	}
+	return res.StatusCode >= 500, fmt.Errorf("状态码: %d, %s", res.StatusCode, string(bodyByte))
+}
+
+// signWebHook 计算 HMAC-SHA256 签名
+func signWebHook(secret, timestamp, content string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + content))
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -2,8 +2,14 @@ package sender
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"watchAlert/internal/models"
 	"watchAlert/pkg/tools"
 )
 
@@ -17,24 +23,150 @@ type (
 	}
 )
 
+const (
+	// wechatMarkdownMaxBytes markdown 消息内容的最大字节数
+	wechatMarkdownMaxBytes = 4096
+	// wechatMaxParts 单条通知最多拆分的消息数, 超出部分截断
+	wechatMaxParts = 3
+	// wechatRobotRateLimit 每个机器人每分钟最多发送的消息数
+	wechatRobotRateLimit = 20
+	// wechatTruncatedSuffix 内容截断后的提示
+	wechatTruncatedSuffix = "\n...(内容过长, 已截断)"
+)
+
 func NewWeChatSender() SendInter {
 	return &WeChatSender{}
 }
 
 func (w *WeChatSender) Send(params SendParams) error {
-	cardContentByte := bytes.NewReader([]byte(params.Content))
-	res, err := tools.Post(nil, params.Hook, cardContentByte, 10)
+	var msg models.WeChatMsgTemplate
+	if err := json.Unmarshal([]byte(params.Content), &msg); err != nil || msg.MsgType != "markdown" {
+		// 非 markdown 消息原样发送
+		return w.post(params.Hook, []byte(params.Content))
+	}
+
+	mobiles := msg.MarkDown.MentionedMobileList
+	msg.MarkDown.MentionedMobileList = nil
+	for _, part := range splitWeChatContent(msg.MarkDown.Content, wechatMarkdownMaxBytes, wechatMaxParts) {
+		msg.MarkDown.Content = part
+		if err := w.post(params.Hook, []byte(tools.JsonMarshal(msg))); err != nil {
+			return err
+		}
+	}
+
+	// markdown 消息不支持 mentioned_mobile_list, 单独发送文本消息提醒值班人员
+	if len(mobiles) > 0 {
+		mention := models.WeChatTextMsg{
+			MsgType: "text",
+			Text: models.WeChatText{
+				Content:             "请值班人员及时处理",
+				MentionedMobileList: mobiles,
+			},
+		}
+		if err := w.post(params.Hook, []byte(tools.JsonMarshal(mention))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *WeChatSender) post(hook string, content []byte) error {
+	getWeChatRobotLimiter(hook).wait()
+
+	res, err := tools.Post(nil, hook, bytes.NewReader(content), 10)
 	if err != nil {
 		return err
 	}
 
 	var response WeChatResponse
-	if err := tools.ParseReaderBody(res.Body, &response); err != nil {
-		return errors.New(fmt.Sprintf("Error unmarshalling Feishu response: %s", err.Error()))
+	if err := readResponse(res, &response); err != nil {
+		return errors.New(fmt.Sprintf("WeChat 发送失败, %s", err.Error()))
 	}
 	if response.Code != 0 {
-		return errors.New(response.Msg)
+		return fmt.Errorf("errcode: %d, errmsg: %s", response.Code, response.Msg)
 	}
 
 	return nil
 }
+
+// splitWeChatContent 按行将内容拆分为不超过 maxBytes 的多段, 最多 maxParts 段, 超出部分截断
+func splitWeChatContent(content string, maxBytes, maxParts int) []string {
+	if len(content) <= maxBytes {
+		return []string{content}
+	}
+
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		// 单行超出上限时按字符截断
+		for len(line) > maxBytes {
+			flush()
+			n := truncateUTF8(line, maxBytes)
+			parts = append(parts, line[:n])
+			line = line[n:]
+		}
+		if cur.Len()+len(line) > maxBytes {
+			flush()
+		}
+		cur.WriteString(line)
+	}
+	flush()
+
+	if len(parts) > maxParts {
+		last := parts[maxParts-1]
+		last = last[:truncateUTF8(last, maxBytes-len(wechatTruncatedSuffix))] + wechatTruncatedSuffix
+		parts = append(parts[:maxParts-1], last)
+	}
+
+	return parts
+}
+
+// truncateUTF8 返回不超过 maxBytes 且不截断多字节字符的长度
+func truncateUTF8(s string, maxBytes int) int {
+	if len(s) <= maxBytes {
+		return len(s)
+	}
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+// wechatRobotLimiter 企业微信机器人限流, 超出每分钟的发送上限时等待
+type wechatRobotLimiter struct {
+	mux  sync.Mutex
+	sent []time.Time
+}
+
+var wechatRobotLimiters sync.Map
+
+func getWeChatRobotLimiter(hook string) *wechatRobotLimiter {
+	l, _ := wechatRobotLimiters.LoadOrStore(hook, &wechatRobotLimiter{})
+	return l.(*wechatRobotLimiter)
+}
+
+// wait 等待至最近一分钟内的发送数低于上限, 同一机器人的消息按顺序发送
+func (l *wechatRobotLimiter) wait() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := time.Now()
+	for len(l.sent) > 0 && now.Sub(l.sent[0]) >= time.Minute {
+		l.sent = l.sent[1:]
+	}
+	if len(l.sent) >= wechatRobotRateLimit {
+		sleep(l.sent[0].Add(time.Minute).Sub(now))
+		l.sent = l.sent[1:]
+	}
+	l.sent = append(l.sent, time.Now())
+}
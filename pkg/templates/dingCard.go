@@ -2,6 +2,7 @@ package templates
 
 import (
 	"fmt"
+	"strings"
 	models2 "watchAlert/internal/models"
 	"watchAlert/pkg/tools"
 )
@@ -11,10 +12,23 @@ func dingdingTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemp
 	Title := ParserTemplate("Title", alert, noticeTmpl.Template)
 	Footer := ParserTemplate("Footer", alert, noticeTmpl.Template)
 
-	userId := alert.DutyUser
+	at := models2.At{
+		AtUserIds: []string{},
+		AtMobiles: []string{},
+	}
+	if len(alert.DutyUserPhoneNumber) > 0 {
+		at.AtMobiles = alert.DutyUserPhoneNumber
+	}
 
-	if alert.DutyUser != "暂无" {
+	switch {
+	case alert.DutyUser != "暂无" && alert.DutyUser != "":
+		at.AtUserIds = []string{alert.DutyUser}
 		alert.DutyUser = fmt.Sprintf("@%s", alert.DutyUser)
+	case len(at.AtMobiles) > 0:
+		// 值班人员未配置钉钉 UserId 时, 通过手机号 @ 值班人员
+		alert.DutyUser = "@" + strings.Join(at.AtMobiles, " @")
+	default:
+		alert.DutyUser = "暂无"
 	}
 
 	t := models2.DingMsg{
@@ -27,11 +41,7 @@ func dingdingTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemp
 				"\n" +
 				Footer,
 		},
-		At: models2.At{
-			AtUserIds: []string{userId},
-			AtMobiles: []string{userId},
-			IsAtAll:   false,
-		},
+		At: at,
 	}
 
 	cardContentString := tools.JsonMarshal(t)
@@ -1,8 +1,15 @@
 package templates
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logc"
+	"net/url"
+	"sort"
 	"strings"
+	"time"
+	"watchAlert/internal/global"
 	models "watchAlert/internal/models"
 	"watchAlert/pkg/tools"
 )
@@ -35,8 +42,12 @@ func feishuTemplate(alert models.AlertCurEvent, noticeTmpl models.NoticeTemplate
 		cardContentString = ParserTemplate("Card", alert, cardContentString)
 
 	} else {
+		titleColor := ParserTemplate("TitleColor", alert, noticeTmpl.Template)
+		if titleColor == "" {
+			titleColor = feishuSeverityColor(alert)
+		}
 		cardHeader := models.Headers{
-			Template: ParserTemplate("TitleColor", alert, noticeTmpl.Template),
+			Template: titleColor,
 			Title: models.Titles{
 				Content: ParserTemplate("Title", alert, noticeTmpl.Template),
 				Tag:     "plain_text",
@@ -65,10 +76,18 @@ func feishuTemplate(alert models.AlertCurEvent, noticeTmpl models.NoticeTemplate
 					},
 				},
 			},
-			{
+		}
+		if fields := feishuLabelFields(alert); len(fields) > 0 {
+			cardElements = append(cardElements, models.Elements{Tag: "div", Fields: fields})
+		}
+		if actions := feishuActions(alert); len(actions) > 0 {
+			cardElements = append(cardElements, models.Elements{Tag: "action", Actions: actions})
+		}
+		cardElements = append(cardElements,
+			models.Elements{
 				Tag: "hr",
 			},
-			{
+			models.Elements{
 				Tag: "note",
 				Elements: []models.ElementsElements{
 					{
@@ -77,7 +96,7 @@ func feishuTemplate(alert models.AlertCurEvent, noticeTmpl models.NoticeTemplate
 					},
 				},
 			},
-		}
+		)
 
 		defaultTemplate.Card.Elements = cardElements
 		defaultTemplate.Card.Header = cardHeader
@@ -91,3 +110,90 @@ func feishuTemplate(alert models.AlertCurEvent, noticeTmpl models.NoticeTemplate
 	return cardContentString
 
 }
+
+const (
+	// feishuMaxLabelFields 卡片中最多展示的标签数
+	feishuMaxLabelFields = 20
+	// feishuAckTokenTTL 认领链接的有效期
+	feishuAckTokenTTL = 24 * time.Hour
+)
+
+// feishuHiddenLabels 已在告警内容中展示的标签, 不重复展示
+var feishuHiddenLabels = map[string]struct{}{
+	"rule_name":   {},
+	"fingerprint": {},
+	"severity":    {},
+}
+
+// feishuSeverityColor 按告警等级设置卡片标题颜色, 恢复通知为绿色
+func feishuSeverityColor(alert models.AlertCurEvent) string {
+	if alert.IsRecovered {
+		return "green"
+	}
+	switch alert.Severity {
+	case "P0":
+		return "red"
+	case "P1":
+		return "orange"
+	default:
+		return "yellow"
+	}
+}
+
+// feishuLabelFields 将告警标签按名称排序后以双列字段展示
+func feishuLabelFields(alert models.AlertCurEvent) []models.Fields {
+	keys := make([]string, 0, len(alert.Metric))
+	for k := range alert.Metric {
+		if _, ok := feishuHiddenLabels[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > feishuMaxLabelFields {
+		keys = keys[:feishuMaxLabelFields]
+	}
+
+	fields := make([]models.Fields, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, models.Fields{
+			IsShort: true,
+			Text: models.Texts{
+				Content: fmt.Sprintf("**%s**\n%v", k, alert.Metric[k]),
+				Tag:     "lark_md",
+			},
+		})
+	}
+	return fields
+}
+
+// feishuActions 生成「认领」「查看」按钮, 需配置 Server.externalURL, 恢复通知不展示认领按钮
+func feishuActions(alert models.AlertCurEvent) []models.Actions {
+	externalURL := strings.TrimSuffix(global.GetConfig().Server.ExternalURL, "/")
+	if externalURL == "" {
+		return nil
+	}
+
+	var actions []models.Actions
+	if !alert.IsRecovered && !alert.UpgradeState.IsConfirm {
+		token, err := tools.GenerateAlertActionToken(alert.TenantId, alert.FaultCenterId, alert.Fingerprint, feishuAckTokenTTL)
+		if err != nil {
+			logc.Error(context.Background(), fmt.Sprintf("生成认领链接失败, fingerprint: %s, err: %s", alert.Fingerprint, err.Error()))
+		} else {
+			actions = append(actions, models.Actions{
+				Tag:  "button",
+				Text: models.ActionsText{Content: "认领", Tag: "plain_text"},
+				Type: "primary",
+				URL:  externalURL + "/api/callback/ackAlert?token=" + url.QueryEscape(token),
+			})
+		}
+	}
+
+	actions = append(actions, models.Actions{
+		Tag:  "button",
+		Text: models.ActionsText{Content: "查看", Tag: "plain_text"},
+		Type: "default",
+		URL:  externalURL + "/events?query=" + url.QueryEscape(alert.RuleName),
+	})
+
+	return actions
+}
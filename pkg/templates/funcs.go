@@ -0,0 +1,118 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"watchAlert/internal/global"
+	"watchAlert/pkg/tools"
+)
+
+// templateFuncs 消息模版中可使用的函数
+var templateFuncs = template.FuncMap{
+	"toUpper":          strings.ToUpper,
+	"toLower":          strings.ToLower,
+	"trimSpace":        strings.TrimSpace,
+	"join":             joinValues,
+	"humanizeDuration": humanizeDuration,
+	"formatTime":       formatTime,
+	"datasourceURL":    datasourceURL,
+	"toJson":           tools.JsonMarshal,
+}
+
+// humanizeDuration 将秒数或 time.Duration 转换为易读的时长, 如 1h 2m 3s
+func humanizeDuration(v interface{}) string {
+	var d time.Duration
+	switch t := v.(type) {
+	case time.Duration:
+		d = t
+	case int:
+		d = time.Duration(t) * time.Second
+	case int64:
+		d = time.Duration(t) * time.Second
+	case float64:
+		d = time.Duration(t * float64(time.Second))
+	case string:
+		seconds, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return t
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+
+	if d < time.Second {
+		return d.String()
+	}
+
+	d = d.Round(time.Second)
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	var parts []string
+	for _, unit := range units {
+		if d >= unit.size {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.name))
+			d %= unit.size
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatTime 将秒级时间戳格式化, 可选指定 Go 时间格式
+func formatTime(ts int64, layout ...string) string {
+	if ts <= 0 {
+		return ""
+	}
+	l := global.Layout
+	if len(layout) > 0 && layout[0] != "" {
+		l = layout[0]
+	}
+	return time.Unix(ts, 0).Format(l)
+}
+
+// datasourceURL 拼接数据源查询链接, 参数按 key value 成对传入并进行 URL 编码,
+// 如 {{ datasourceURL "http://prometheus:9090/graph" "g0.expr" .SearchQL }}
+func datasourceURL(base string, kv ...interface{}) string {
+	if len(kv) == 0 {
+		return base
+	}
+
+	params := url.Values{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		params.Add(fmt.Sprintf("%v", kv[i]), fmt.Sprintf("%v", kv[i+1]))
+	}
+
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + params.Encode()
+}
+
+// joinValues 拼接列表, 支持 []string 及 []interface{}
+func joinValues(sep string, v interface{}) string {
+	switch t := v.(type) {
+	case []string:
+		return strings.Join(t, sep)
+	case []interface{}:
+		items := make([]string, 0, len(t))
+		for _, item := range t {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(items, sep)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -22,6 +22,16 @@ func NewTemplate(ctx *ctx.Context, alert models.AlertCurEvent, notice models.Ale
 		return Template{CardContentMsg: wechatTemplate(alert, noticeTmpl)}
 	case "PhoneCall":
 		return Template{CardContentMsg: phoneCallTemplate(alert, noticeTmpl)}
+	case "Telegram":
+		return Template{CardContentMsg: telegramTemplate(alert, noticeTmpl)}
+	case "Teams":
+		return Template{CardContentMsg: teamsTemplate(alert, noticeTmpl)}
+	case "PagerDuty":
+		return Template{CardContentMsg: pagerDutyTemplate(alert, noticeTmpl)}
+	case "OpsGenie":
+		return Template{CardContentMsg: opsGenieTemplate(alert, noticeTmpl)}
+	case "Slack":
+		return Template{CardContentMsg: slackTemplate(alert, noticeTmpl)}
 	}
 
 	return Template{}
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	models2 "watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// opsGeniePriorities 告警等级与 OpsGenie priority 的映射, 未匹配时为 P3
+var opsGeniePriorities = map[string]string{
+	"P0": "P1",
+	"P1": "P2",
+	"P2": "P3",
+}
+
+const (
+	// OpsGenie 字段长度限制
+	opsGenieMessageMaxLength     = 130
+	opsGenieDescriptionMaxLength = 15000
+)
+
+// opsGenieTemplate 生成 OpsGenie 告警, 以告警指纹作为 alias, 重复触发时更新同一条告警, 恢复时按 alias 关闭
+func opsGenieTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemplateExample) string {
+	Title := ParserTemplate("Title", alert, noticeTmpl.Template)
+	if Title == "" {
+		Title = alert.RuleName
+	}
+	Event := ParserTemplate("Event", alert, noticeTmpl.Template)
+
+	t := models2.OpsGenieAlert{
+		Alias:  alert.Fingerprint,
+		Source: "WatchAlert",
+	}
+	if alert.IsRecovered {
+		t.Note = truncateRunes(Event, opsGenieDescriptionMaxLength)
+		return tools.JsonMarshal(t)
+	}
+
+	priority, ok := opsGeniePriorities[alert.Severity]
+	if !ok {
+		priority = "P3"
+	}
+
+	keys := make([]string, 0, len(alert.Metric))
+	for k := range alert.Metric {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	details := make(map[string]string, len(keys))
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := fmt.Sprintf("%v", alert.Metric[k])
+		details[k] = v
+		tags = append(tags, k+":"+v)
+	}
+
+	t.Message = truncateRunes(Title, opsGenieMessageMaxLength)
+	t.Description = truncateRunes(Event, opsGenieDescriptionMaxLength)
+	t.Tags = tags
+	t.Details = details
+	t.Entity = alert.RuleName
+	t.Priority = priority
+
+	return tools.JsonMarshal(t)
+}
+
+// truncateRunes 按字符截断, 避免截断多字节字符
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
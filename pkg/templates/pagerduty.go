@@ -0,0 +1,66 @@
+package templates
+
+import (
+	"fmt"
+	"time"
+	models2 "watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// pagerDutySeverities 告警等级与 PagerDuty severity 的映射, 未匹配时为 info
+var pagerDutySeverities = map[string]string{
+	"P0": "critical",
+	"P1": "error",
+	"P2": "warning",
+}
+
+// pagerDutySummaryMaxLength PagerDuty summary 最大长度
+const pagerDutySummaryMaxLength = 1024
+
+// pagerDutyTemplate 生成 PagerDuty 事件, 以告警指纹作为 dedup_key, 恢复时关闭对应的 Incident, routing_key 由发送时填充
+func pagerDutyTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemplateExample) string {
+	event := models2.PagerDutyEvent{
+		EventAction: "trigger",
+		DedupKey:    alert.Fingerprint,
+		Client:      "WatchAlert",
+	}
+	if alert.IsRecovered {
+		// resolve 事件仅需 dedup_key
+		event.EventAction = "resolve"
+		return tools.JsonMarshal(event)
+	}
+
+	severity, ok := pagerDutySeverities[alert.Severity]
+	if !ok {
+		severity = "info"
+	}
+
+	summary := []rune(ParserTemplate("Title", alert, noticeTmpl.Template))
+	if len(summary) == 0 {
+		summary = []rune(alert.RuleName)
+	}
+	if len(summary) > pagerDutySummaryMaxLength {
+		summary = summary[:pagerDutySummaryMaxLength]
+	}
+
+	source := alert.RuleName
+	if instance, ok := alert.Metric["instance"]; ok {
+		source = fmt.Sprintf("%v", instance)
+	}
+
+	event.Payload = &models2.PagerDutyPayload{
+		Summary:   string(summary),
+		Source:    source,
+		Severity:  severity,
+		Timestamp: time.Unix(alert.FirstTriggerTime, 0).UTC().Format(time.RFC3339),
+		Component: alert.DatasourceType,
+		Group:     alert.RuleName,
+		CustomDetails: map[string]interface{}{
+			"event":    ParserTemplate("Event", alert, noticeTmpl.Template),
+			"metric":   alert.Metric,
+			"severity": alert.Severity,
+		},
+	}
+
+	return tools.JsonMarshal(event)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
 	"strconv"
 	"text/template"
@@ -13,7 +14,40 @@ import (
 	"watchAlert/pkg/tools"
 )
 
-var tmpl *template.Template
+// fallbackTemplate 用户模版解析或执行失败时使用的兜底模版
+const fallbackTemplate = `{{- define "Title" -}}
+{{ if .IsRecovered }}[已恢复] {{ else }}[告警中] {{ end }}{{ .RuleName }}
+{{- end -}}
+{{- define "Event" -}}
+规则名称: {{ .RuleName }}
+告警等级: {{ .Severity }}
+告警指纹: {{ .Fingerprint }}
+触发时间: {{ formatTime .FirstTriggerTime }}
+{{- if .ValueName }}
+告警值: {{ .ValueName }} = {{ .Metric.value }}
+{{- end }}
+{{- if gt .OccurrenceCount 1 }}
+触发次数: {{ .OccurrenceCount }}
+最近触发: {{ formatTime .LastSeenTime }}
+{{- end }}
+{{- if .IsRecovered }}
+恢复时间: {{ formatTime .RecoverTime }}
+持续时长: {{ .FiringDuration }}
+{{- if .Metric.recover_value }}
+恢复值: {{ .Metric.recover_value }}
+{{- end }}
+{{- end }}
+告警详情: {{ .Annotations }}
+{{- end -}}`
+
+// messageData 消息模版的渲染数据, 在告警事件的基础上提供 .Labels、.Value 及 .RecoverValue
+type messageData struct {
+	models.AlertCurEvent
+	Labels       map[string]interface{}
+	Value        interface{}
+	ValueName    string
+	RecoverValue interface{}
+}
 
 // ParserTemplate 处理告警推送的消息模版
 func ParserTemplate(defineName string, alert models.AlertCurEvent, templateStr string) string {
@@ -22,13 +56,28 @@ func ParserTemplate(defineName string, alert models.AlertCurEvent, templateStr s
 	recoverTime := time.Unix(alert.RecoverTime, 0).Format(global.Layout)
 	alert.FirstTriggerTimeFormat = firstTriggerTime
 	alert.RecoverTimeFormat = recoverTime
+	alert.LastSeenTimeFormat = time.Unix(alert.LastSeenTime, 0).Format(global.Layout)
+	if alert.IsRecovered {
+		alert.FiringDuration = humanizeDuration(alert.GetFiringDuration())
+	}
+
+	// 规则配置了消息模版时, 使用规则模版渲染告警内容
+	if defineName == "Event" && alert.MessageTemplate != "" {
+		content, err := renderMessageTemplate(alert)
+		if err == nil {
+			return tools.ParserVariables(content, parserEvent(alert))
+		}
+		logc.Error(context.Background(), fmt.Sprintf("规则消息模版渲染失败, 使用兜底模版, ruleId: %s, err: %s", alert.RuleId, err.Error()))
+		return executeFallback(defineName, alert)
+	}
 
-	tmpl = template.Must(template.New("tmpl").Parse(templateStr))
+	tmpl, err := template.New("tmpl").Funcs(templateFuncs).Parse(templateStr)
+	if err != nil {
+		logc.Error(context.Background(), "告警模版解析失败, 使用兜底模版 ->", err.Error())
+		return executeFallback(defineName, alert)
+	}
 
-	var (
-		buf bytes.Buffer
-		err error
-	)
+	var buf bytes.Buffer
 
 	if defineName == "Card" {
 		err = tmpl.Execute(&buf, alert)
@@ -40,7 +89,7 @@ func ParserTemplate(defineName string, alert models.AlertCurEvent, templateStr s
 	err = tmpl.ExecuteTemplate(&buf, defineName, alert)
 	if err != nil {
 		logc.Error(context.Background(), "告警模版执行失败 ->", err.Error())
-		return ""
+		return executeFallback(defineName, alert)
 	}
 
 	// 前面只会渲染出模版框架, 下面来渲染告警数据内容
@@ -53,6 +102,47 @@ func ParserTemplate(defineName string, alert models.AlertCurEvent, templateStr s
 
 }
 
+// ValidateTemplate 校验消息模版语法
+func ValidateTemplate(templateStr string) error {
+	_, err := template.New("tmpl").Funcs(templateFuncs).Parse(templateStr)
+	return err
+}
+
+// renderMessageTemplate 渲染规则消息模版
+func renderMessageTemplate(alert models.AlertCurEvent) (string, error) {
+	tmpl, err := template.New("message").Funcs(templateFuncs).Option("missingkey=zero").Parse(alert.MessageTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, messageData{
+		AlertCurEvent: alert,
+		Labels:        alert.Metric,
+		Value:         alert.Metric["value"],
+		ValueName:     alert.ValueName,
+		RecoverValue:  alert.Metric["recover_value"],
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// executeFallback 使用兜底模版渲染, 未定义的部分返回空
+func executeFallback(defineName string, alert models.AlertCurEvent) string {
+	tmpl := template.Must(template.New("fallback").Funcs(templateFuncs).Parse(fallbackTemplate))
+	if tmpl.Lookup(defineName) == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, defineName, alert); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
 func parserEvent(alert models.AlertCurEvent) map[string]interface{} {
 
 	data := make(map[string]interface{})
@@ -63,7 +153,7 @@ func parserEvent(alert models.AlertCurEvent) map[string]interface{} {
 		logc.Error(context.Background(), "parserEvent Unmarshal failed: ", err)
 	}
 
-	if alert.DatasourceType == "AliCloudSLS" || alert.DatasourceType == "Loki" || alert.DatasourceType == "ElasticSearch" || alert.DatasourceType == "VictoriaLogs" {
+	if alert.DatasourceType == "AliCloudSLS" || alert.DatasourceType == "Loki" || alert.DatasourceType == "ElasticSearch" || alert.DatasourceType == "VictoriaLogs" || alert.DatasourceType == "ClickHouse" || alert.DatasourceType == "Graylog" || alert.DatasourceType == "SQL" || alert.DatasourceType == "HTTPProbe" {
 		// 需要转义, 日志中可能会出现特殊符号
 		alarmInfo := strconv.Quote(data["annotations"].(string))
 		data["annotations"] = alarmInfo[1 : len(alarmInfo)-1]
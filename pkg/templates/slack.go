@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"watchAlert/internal/global"
+	models2 "watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+const (
+	// Slack header 块最多 150 个字符, section 文本最多 3000 个字符, 单个 section 最多 10 个字段, 每个字段最多 2000 个字符
+	slackHeaderMaxLen = 150
+	slackTextMaxLen   = 3000
+	slackMaxFields    = 10
+	slackFieldMaxLen  = 2000
+)
+
+// slackTemplate 生成 Slack Block Kit 消息, 告警等级颜色与 Teams 保持一致
+func slackTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemplateExample) string {
+	Title := ParserTemplate("Title", alert, noticeTmpl.Template)
+	Footer := ParserTemplate("Footer", alert, noticeTmpl.Template)
+
+	color, ok := teamsThemeColors[alert.Severity]
+	if !ok {
+		color = teamsDefaultColor
+	}
+	if alert.IsRecovered {
+		color = teamsRecoveredColor
+	}
+
+	fields := []models2.SlackText{
+		{Type: "mrkdwn", Text: "*告警等级*\n" + alert.Severity},
+		{Type: "mrkdwn", Text: "*触发时间*\n" + time.Unix(alert.FirstTriggerTime, 0).Format(global.Layout)},
+	}
+	if alert.IsRecovered {
+		fields = append(fields, models2.SlackText{Type: "mrkdwn", Text: "*恢复时间*\n" + time.Unix(alert.RecoverTime, 0).Format(global.Layout)})
+		fields = append(fields, models2.SlackText{Type: "mrkdwn", Text: "*持续时长*\n" + humanizeDuration(alert.GetFiringDuration())})
+	}
+
+	keys := make([]string, 0, len(alert.Metric))
+	for k := range alert.Metric {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(fields) >= slackMaxFields {
+			break
+		}
+		fields = append(fields, models2.SlackText{Type: "mrkdwn", Text: truncateRunes(fmt.Sprintf("*%s*\n%v", k, alert.Metric[k]), slackFieldMaxLen)})
+	}
+
+	text := ParserTemplate("Event", alert, noticeTmpl.Template)
+	if Footer != "" {
+		text += "\n\n" + Footer
+	}
+
+	blocks := []models2.SlackBlock{
+		{Type: "header", Text: &models2.SlackText{Type: "plain_text", Text: truncateRunes(Title, slackHeaderMaxLen)}},
+		{Type: "section", Fields: fields},
+	}
+	if text != "" {
+		blocks = append(blocks, models2.SlackBlock{Type: "section", Text: &models2.SlackText{Type: "mrkdwn", Text: truncateRunes(text, slackTextMaxLen)}})
+	}
+
+	return tools.JsonMarshal(models2.SlackMessage{
+		Text: Title,
+		Attachments: []models2.SlackAttachment{
+			{
+				Color:  "#" + color,
+				Blocks: blocks,
+			},
+		},
+	})
+}
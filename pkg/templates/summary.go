@@ -0,0 +1,69 @@
+package templates
+
+import (
+	"html"
+	"strings"
+	models2 "watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// SummaryTemplate 生成限流期间合并的告警汇总消息, 按通知类型生成对应格式, 不支持的类型返回空
+func SummaryTemplate(noticeType, title, text string) string {
+	switch noticeType {
+	case "DingDing":
+		return tools.JsonMarshal(models2.DingMsg{
+			Msgtype: "markdown",
+			Markdown: models2.Markdown{
+				Title: title,
+				Text:  "**" + title + "**" + "\n" + "\n" + text,
+			},
+		})
+	case "WeChat":
+		return tools.JsonMarshal(models2.WeChatMsgTemplate{
+			MsgType: "markdown",
+			MarkDown: models2.WeChatMarkDown{
+				Content: "**" + title + "**" + "\n" + "\n" + text,
+			},
+		})
+	case "FeiShu":
+		return tools.JsonMarshal(map[string]interface{}{
+			"msg_type": "text",
+			"content": map[string]string{
+				"text": title + "\n" + "\n" + text,
+			},
+		})
+	case "Telegram":
+		return "*" + telegramMarkdownEscaper.Replace(title) + "*" + "\n" + "\n" + telegramMarkdownEscaper.Replace(text)
+	case "Teams":
+		return tools.JsonMarshal(models2.TeamsMessageCard{
+			Type:       "MessageCard",
+			Context:    "http://schema.org/extensions",
+			ThemeColor: teamsDefaultColor,
+			Summary:    title,
+			Title:      title,
+			Sections: []models2.TeamsSection{
+				{
+					Text:     strings.ReplaceAll(text, "\n", "\n\n"),
+					Markdown: true,
+				},
+			},
+		})
+	case "Slack":
+		return tools.JsonMarshal(models2.SlackMessage{
+			Text: title,
+			Attachments: []models2.SlackAttachment{
+				{
+					Color: "#" + teamsDefaultColor,
+					Blocks: []models2.SlackBlock{
+						{Type: "header", Text: &models2.SlackText{Type: "plain_text", Text: truncateRunes(title, slackHeaderMaxLen)}},
+						{Type: "section", Text: &models2.SlackText{Type: "mrkdwn", Text: truncateRunes(text, slackTextMaxLen)}},
+					},
+				},
+			},
+		})
+	case "Email":
+		return "<h3>" + html.EscapeString(title) + "</h3><p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p>"
+	}
+
+	return ""
+}
@@ -0,0 +1,102 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"watchAlert/internal/global"
+	models2 "watchAlert/internal/models"
+	"watchAlert/pkg/tools"
+)
+
+// teamsThemeColors 告警等级对应的卡片主题色
+var teamsThemeColors = map[string]string{
+	"P0": "E81123",
+	"P1": "FF8C00",
+	"P2": "FFB900",
+}
+
+const (
+	teamsRecoveredColor = "2EB886"
+	teamsDefaultColor   = "808080"
+)
+
+// teamsTemplate 生成 Teams MessageCard, 模版中定义 Link 时添加跳转到告警详情的按钮
+func teamsTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemplateExample) string {
+	Title := ParserTemplate("Title", alert, noticeTmpl.Template)
+	Footer := ParserTemplate("Footer", alert, noticeTmpl.Template)
+
+	themeColor, ok := teamsThemeColors[alert.Severity]
+	if !ok {
+		themeColor = teamsDefaultColor
+	}
+	if alert.IsRecovered {
+		themeColor = teamsRecoveredColor
+	}
+
+	facts := []models2.TeamsFact{
+		{Name: "告警等级", Value: alert.Severity},
+		{Name: "触发时间", Value: time.Unix(alert.FirstTriggerTime, 0).Format(global.Layout)},
+	}
+	if alert.IsRecovered {
+		facts = append(facts, models2.TeamsFact{Name: "恢复时间", Value: time.Unix(alert.RecoverTime, 0).Format(global.Layout)})
+		facts = append(facts, models2.TeamsFact{Name: "持续时长", Value: humanizeDuration(alert.GetFiringDuration())})
+	}
+
+	keys := make([]string, 0, len(alert.Metric))
+	for k := range alert.Metric {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		facts = append(facts, models2.TeamsFact{Name: k, Value: fmt.Sprintf("%v", alert.Metric[k])})
+	}
+
+	t := models2.TeamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: themeColor,
+		Summary:    Title,
+		Title:      Title,
+		Sections: []models2.TeamsSection{
+			{
+				Facts:    facts,
+				Markdown: true,
+			},
+			{
+				Text:     ParserTemplate("Event", alert, noticeTmpl.Template) + "\n\n" + Footer,
+				Markdown: true,
+			},
+		},
+	}
+
+	if link := teamsLink(alert, noticeTmpl.Template); link != "" {
+		t.PotentialAction = []models2.TeamsPotentialAction{
+			{
+				Type: "OpenUri",
+				Name: "查看告警详情",
+				Targets: []models2.TeamsTarget{
+					{OS: "default", URI: link},
+				},
+			},
+		}
+	}
+
+	return tools.JsonMarshal(t)
+}
+
+// teamsLink 解析模版中可选的 Link 定义, 支持 ${xx} 变量, 如 http://localhost:3000/events?query=${rule_name}
+func teamsLink(alert models2.AlertCurEvent, templateStr string) string {
+	t, err := template.New("tmpl").Parse(templateStr)
+	if err != nil || t.Lookup("Link") == nil {
+		return ""
+	}
+
+	var buf strings.Builder
+	if err := t.ExecuteTemplate(&buf, "Link", alert); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(tools.ParserVariables(buf.String(), parserEvent(alert)))
+}
@@ -0,0 +1,25 @@
+package templates
+
+import (
+	"strings"
+	models2 "watchAlert/internal/models"
+)
+
+// telegramMarkdownEscaper MarkdownV2 中需要转义的特殊字符
+var telegramMarkdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// telegramTemplate 生成 MarkdownV2 格式的消息, 标题加粗, 内容按纯文本转义
+func telegramTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTemplateExample) string {
+	Title := ParserTemplate("Title", alert, noticeTmpl.Template)
+	Footer := ParserTemplate("Footer", alert, noticeTmpl.Template)
+
+	return "*" + telegramMarkdownEscaper.Replace(Title) + "*" +
+		"\n" + "\n" +
+		telegramMarkdownEscaper.Replace(ParserTemplate("Event", alert, noticeTmpl.Template)) +
+		"\n" +
+		telegramMarkdownEscaper.Replace(Footer)
+}
@@ -0,0 +1,40 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+)
+
+// webHookFuncs 请求体模版额外可使用的函数
+var webHookFuncs = template.FuncMap{
+	// toJson 将值序列化为 JSON, 字符串会带引号并转义, 如 "text": {{ .Annotations | toJson }}
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func newWebHookTemplate(bodyTemplate string) (*template.Template, error) {
+	return template.New("webhook").Funcs(templateFuncs).Funcs(webHookFuncs).Option("missingkey=zero").Parse(bodyTemplate)
+}
+
+// RenderWebHookBody 使用请求体模版渲染告警事件, data 为告警或拨测事件
+func RenderWebHookBody(bodyTemplate string, data interface{}) (string, error) {
+	tmpl, err := newWebHookTemplate(bodyTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ValidateWebHookTemplate 校验请求体模版
+func ValidateWebHookTemplate(bodyTemplate string) error {
+	_, err := newWebHookTemplate(bodyTemplate)
+	return err
+}
@@ -12,13 +12,27 @@ func wechatTemplate(alert models2.AlertCurEvent, noticeTmpl models2.NoticeTempla
 	t := models2.WeChatMsgTemplate{
 		MsgType: "markdown",
 		MarkDown: models2.WeChatMarkDown{
-			Content: "**" + Title + "**" +
+			Content: `<font color="` + wechatSeverityColor(alert) + `">**` + Title + `**</font>` +
 				"\n" + "\n" +
 				ParserTemplate("Event", alert, noticeTmpl.Template) +
 				"\n" +
 				Footer,
+			MentionedMobileList: alert.DutyUserPhoneNumber,
 		},
 	}
 
 	return tools.JsonMarshal(t)
 }
+
+// wechatSeverityColor 企业微信 markdown 仅支持 info (绿色)、comment (灰色)、warning (橙红色) 三种颜色
+func wechatSeverityColor(alert models2.AlertCurEvent) string {
+	if alert.IsRecovered {
+		return "info"
+	}
+	switch alert.Severity {
+	case "P0", "P1":
+		return "warning"
+	default:
+		return "comment"
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"github.com/zeromicro/go-zero/core/logc"
@@ -43,6 +44,11 @@ func Get(headers map[string]string, url string, timeout int) (*http.Response, er
 }
 
 func Post(headers map[string]string, url string, bodyReader *bytes.Reader, timeout int) (*http.Response, error) {
+	return Request(http.MethodPost, headers, url, bodyReader, timeout)
+}
+
+// Request 发送指定方法的 HTTP 请求, 默认 Content-Type 为 application/json, 可通过 headers 覆盖
+func Request(method string, headers map[string]string, url string, bodyReader *bytes.Reader, timeout int) (*http.Response, error) {
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
@@ -55,15 +61,15 @@ func Post(headers map[string]string, url string, bodyReader *bytes.Reader, timeo
 		Transport: transport,
 	}
 
-	request, err := http.NewRequest(http.MethodPost, url, bodyReader)
-	request.Header.Set("Content-Type", "application/json")
-	for k, v := range headers {
-		request.Header.Set(k, v)
-	}
+	request, err := http.NewRequest(method, url, bodyReader)
 	if err != nil {
 		logc.Error(context.Background(), fmt.Sprintf("Tools post 请求建立失败, err: %s", err.Error()))
 		return nil, err
 	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
 	resp, err := client.Do(request)
 	if err != nil {
 		logc.Error(context.Background(), fmt.Sprintf("Tools post 请求发送失败, err: %s", err.Error()))
@@ -73,6 +79,34 @@ func Post(headers map[string]string, url string, bodyReader *bytes.Reader, timeo
 	return resp, nil
 }
 
+// NewTLSConfig 根据 PEM 格式的 CA 证书及客户端证书创建 TLS 配置
+func NewTLSConfig(caCert, clientCert, clientKey string, insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, fmt.Errorf("CA 证书解析失败")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if clientCert != "" || clientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("客户端证书解析失败, err: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // CreateBasicAuthHeader 创建带认证的HTTP头
 func CreateBasicAuthHeader(username, password string) map[string]string {
 	headers := make(map[string]string)
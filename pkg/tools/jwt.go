@@ -1,17 +1,26 @@
 package tools
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"os"
+	"sync"
 	"time"
+	"watchAlert/config"
 	"watchAlert/internal/global"
 )
 
 // JwtCustomClaims 注册声明是JWT声明集的结构化版本，仅限于注册声明名称
 type JwtCustomClaims struct {
-	ID             string `json:"id"`
-	Name           string `json:"name"`
-	Pass           string `json:"pass"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Pass string `json:"pass"`
+	// 签发时用户所属的租户, 租户成员变更以数据库为准, 访问时仍需校验成员关系
+	Tenants        []string `json:"tenants"`
 	StandardClaims jwt.StandardClaims
 }
 
@@ -26,34 +35,222 @@ func (j JwtCustomClaims) Valid() error {
 	return nil
 }
 
-// ParseToken 解析token
+// ParseToken 解析token, 依次使用当前及轮换前的密钥校验签名
 func ParseToken(tokenStr string) (JwtCustomClaims, error) {
-	iJwtCustomClaims := JwtCustomClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, &iJwtCustomClaims, func(token *jwt.Token) (interface{}, error) {
-		return global.StSignKey, nil
-	})
+	jc := global.GetConfig().Jwt
+	method, keys, err := getVerifyKeys(jc)
+	if err != nil {
+		return JwtCustomClaims{}, err
+	}
+
+	for _, key := range keys {
+		iJwtCustomClaims := JwtCustomClaims{}
+		token, err := jwt.ParseWithClaims(tokenStr, &iJwtCustomClaims, func(token *jwt.Token) (interface{}, error) {
+			// 签名算法必须与配置一致, 防止算法混淆攻击
+			if token.Method.Alg() != method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+			}
+			return key, nil
+		})
+		if err == nil && token.Valid {
+			return iJwtCustomClaims, nil
+		}
 
-	if err == nil && !token.Valid {
-		err = errors.New("invalid Token")
+		// 仅签名不匹配时尝试下一个密钥
+		var vErr *jwt.ValidationError
+		if !errors.As(err, &vErr) || vErr.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+			if err == nil {
+				err = errors.New("invalid Token")
+			}
+			return JwtCustomClaims{}, err
+		}
 	}
-	return iJwtCustomClaims, err
+
+	return JwtCustomClaims{}, errors.New("invalid Token")
 }
 
 // GenerateToken 生成Token
-func GenerateToken(userId, userName, password string) (string, error) {
+func GenerateToken(userId, userName, password string, tenants []string) (string, error) {
 	// 初始化
+	jc := global.GetConfig().Jwt
 	iJwtCustomClaims := JwtCustomClaims{
-		ID:   userId,
-		Name: userName,
-		Pass: password,
+		ID:      userId,
+		Name:    userName,
+		Pass:    password,
+		Tenants: tenants,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Unix() + global.Config.Jwt.Expire,
+			ExpiresAt: time.Now().Unix() + jc.Expire,
 			IssuedAt:  time.Now().Unix(),
 			Issuer:    AppGuardName,
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, iJwtCustomClaims)
-	return token.SignedString(global.StSignKey)
+	method, key, err := getSigningKey(jc)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, iJwtCustomClaims)
+	return token.SignedString(key)
+}
+
+// alertActionTokenType 告警操作 Token 的类型标识, 与登陆 Token 区分
+const alertActionTokenType = "alert_action"
+
+// AlertActionClaims 通知消息中操作告警 (如认领) 的链接参数, 使用登陆 Token 的密钥签名
+type AlertActionClaims struct {
+	Type          string `json:"typ"`
+	TenantId      string `json:"tenantId"`
+	FaultCenterId string `json:"faultCenterId"`
+	Fingerprint   string `json:"fingerprint"`
+	ExpiresAt     int64  `json:"exp"`
+}
+
+func (c AlertActionClaims) Valid() error {
+	if c.Type != alertActionTokenType {
+		return errors.New("invalid Token")
+	}
+	if time.Now().Unix() > c.ExpiresAt {
+		return errors.New("链接已过期")
+	}
+	return nil
+}
+
+// GenerateAlertActionToken 生成告警操作 Token, ttl 为有效期
+func GenerateAlertActionToken(tenantId, faultCenterId, fingerprint string, ttl time.Duration) (string, error) {
+	method, key, err := getSigningKey(global.GetConfig().Jwt)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, AlertActionClaims{
+		Type:          alertActionTokenType,
+		TenantId:      tenantId,
+		FaultCenterId: faultCenterId,
+		Fingerprint:   fingerprint,
+		ExpiresAt:     time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString(key)
+}
+
+// ParseAlertActionToken 解析告警操作 Token, 依次使用当前及轮换前的密钥校验签名
+func ParseAlertActionToken(tokenStr string) (AlertActionClaims, error) {
+	method, keys, err := getVerifyKeys(global.GetConfig().Jwt)
+	if err != nil {
+		return AlertActionClaims{}, err
+	}
+
+	err = errors.New("invalid Token")
+	for _, key := range keys {
+		claims := AlertActionClaims{}
+		var token *jwt.Token
+		token, err = jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
+			if token.Method.Alg() != method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+			}
+			return key, nil
+		})
+		if err == nil && token.Valid {
+			return claims, nil
+		}
+	}
+
+	return AlertActionClaims{}, err
+}
+
+// getSigningKey 获取当前签名密钥
+func getSigningKey(jc config.Jwt) (jwt.SigningMethod, interface{}, error) {
+	switch jc.GetAlgorithm() {
+	case config.JwtAlgorithmHS256:
+		return jwt.SigningMethodHS256, getHmacSecret(jc.Secret), nil
+	case config.JwtAlgorithmRS256:
+		privateKey, err := loadRSAPrivateKey(jc.PrivateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return jwt.SigningMethodRS256, privateKey, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported jwt algorithm: %s", jc.Algorithm)
+	}
+}
+
+// getVerifyKeys 获取校验密钥, 当前密钥在前, 轮换前的密钥在后
+func getVerifyKeys(jc config.Jwt) (jwt.SigningMethod, []interface{}, error) {
+	switch jc.GetAlgorithm() {
+	case config.JwtAlgorithmHS256:
+		keys := []interface{}{getHmacSecret(jc.Secret)}
+		for _, secret := range jc.PreviousSecrets {
+			keys = append(keys, []byte(secret))
+		}
+		return jwt.SigningMethodHS256, keys, nil
+	case config.JwtAlgorithmRS256:
+		privateKey, err := loadRSAPrivateKey(jc.PrivateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys := []interface{}{&privateKey.PublicKey}
+		for _, path := range jc.PreviousPublicKeys {
+			publicKey, err := loadRSAPublicKey(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			keys = append(keys, publicKey)
+		}
+		return jwt.SigningMethodRS256, keys, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported jwt algorithm: %s", jc.Algorithm)
+	}
+}
+
+// getHmacSecret 未配置密钥时兼容旧版本的签名密钥, 避免升级后已登陆用户失效
+func getHmacSecret(secret string) []byte {
+	if secret == "" {
+		return global.StSignKey
+	}
+	return []byte(secret)
+}
+
+// rsaKeys 缓存已解析的 RSA 密钥, key 为文件路径
+var rsaKeys sync.Map
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if v, ok := rsaKeys.Load("private:" + path); ok {
+		return v.(*rsa.PrivateKey), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 JWT 私钥失败: %s", err.Error())
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析 JWT 私钥失败: %s", err.Error())
+	}
+	rsaKeys.Store("private:"+path, key)
+	return key, nil
+}
+
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	if v, ok := rsaKeys.Load("public:" + path); ok {
+		return v.(*rsa.PublicKey), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 JWT 公钥失败: %s", err.Error())
+	}
+	key, err := jwt.ParseRSAPublicKeyFromPEM(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析 JWT 公钥失败: %s", err.Error())
+	}
+	rsaKeys.Store("public:"+path, key)
+	return key, nil
+}
+
+// RandToken 生成随机的刷新 Token
+func RandToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 func GetUser(tokenStr string) string {
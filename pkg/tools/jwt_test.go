@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"watchAlert/config"
+	"watchAlert/internal/global"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// writeRSAKeys 生成 RSA 密钥对并写入临时目录, 返回私钥及公钥路径
+func writeRSAKeys(t *testing.T, name string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func TestParseToken(t *testing.T) {
+	oldPrivate, oldPublic := writeRSAKeys(t, "old")
+	newPrivate, _ := writeRSAKeys(t, "new")
+
+	tests := []struct {
+		name    string
+		sign    config.Jwt
+		verify  config.Jwt
+		wantErr bool
+	}{
+		{name: "hs256", sign: config.Jwt{Secret: "s1"}, verify: config.Jwt{Secret: "s1"}},
+		{name: "hs256 legacy default secret", sign: config.Jwt{}, verify: config.Jwt{}},
+		{name: "hs256 rotated secret", sign: config.Jwt{Secret: "s1"}, verify: config.Jwt{Secret: "s2", PreviousSecrets: []string{"s1"}}},
+		{name: "hs256 unknown secret", sign: config.Jwt{Secret: "s1"}, verify: config.Jwt{Secret: "s2"}, wantErr: true},
+		{name: "rs256", sign: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate}, verify: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate}},
+		{
+			name:   "rs256 rotated key",
+			sign:   config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: oldPrivate},
+			verify: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate, PreviousPublicKeys: []string{oldPublic}},
+		},
+		{name: "rs256 unknown key", sign: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: oldPrivate}, verify: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate}, wantErr: true},
+		{name: "hs256 token with rs256 config", sign: config.Jwt{Secret: "s1"}, verify: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate}, wantErr: true},
+		{name: "rs256 token with hs256 config", sign: config.Jwt{Algorithm: config.JwtAlgorithmRS256, PrivateKey: newPrivate}, verify: config.Jwt{Secret: "s1"}, wantErr: true},
+		{name: "unsupported algorithm", sign: config.Jwt{Secret: "s1"}, verify: config.Jwt{Algorithm: "ES256"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.sign.Expire, tt.verify.Expire = 60, 60
+			global.SetConfig(config.App{Jwt: tt.sign})
+			token, err := GenerateToken("u1", "alice", "hash", []string{"t1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			global.SetConfig(config.App{Jwt: tt.verify})
+			claims, err := ParseToken(token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseToken() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseToken() error = %v", err)
+			}
+			if claims.ID != "u1" || claims.Name != "alice" || claims.Pass != "hash" || len(claims.Tenants) != 1 || claims.StandardClaims.Issuer != AppGuardName {
+				t.Errorf("claims = %+v", claims)
+			}
+			if exp := claims.StandardClaims.ExpiresAt - claims.StandardClaims.IssuedAt; exp != 60 {
+				t.Errorf("expires in %d, want 60", exp)
+			}
+		})
+	}
+
+	// 未签名的 Token (alg: none) 不能通过校验
+	global.SetConfig(config.App{Jwt: config.Jwt{Secret: "s1"}})
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, JwtCustomClaims{ID: "u1"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(unsigned); err == nil {
+		t.Error("ParseToken() accepted an unsigned token")
+	}
+}
+
+func TestParseAlertActionToken(t *testing.T) {
+	global.SetConfig(config.App{Jwt: config.Jwt{Secret: "s1", Expire: 60}})
+	loginToken, err := GenerateToken("u1", "alice", "hash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() (string, error)
+		wantErr bool
+	}{
+		{name: "valid", token: func() (string, error) { return GenerateAlertActionToken("t1", "fc1", "fp1", time.Hour) }},
+		{name: "expired", token: func() (string, error) { return GenerateAlertActionToken("t1", "fc1", "fp1", -time.Minute) }, wantErr: true},
+		{name: "login token is not an action token", token: func() (string, error) { return loginToken, nil }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := ParseAlertActionToken(token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseAlertActionToken() error = nil, want error")
+				}
+				return
+			}
+			if err != nil || claims.TenantId != "t1" || claims.FaultCenterId != "fc1" || claims.Fingerprint != "fp1" {
+				t.Errorf("ParseAlertActionToken() = %+v, %v", claims, err)
+			}
+		})
+	}
+}
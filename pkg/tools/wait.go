@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// WaitWithContext 等待 WaitGroup 完成, ctx 取消或超时时提前返回 ctx 的错误
+func WaitWithContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
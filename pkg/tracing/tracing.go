@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	ztrace "github.com/zeromicro/go-zero/core/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "watchAlert"
+	// traceParentKey W3C Trace Context 中的 traceparent 字段
+	traceParentKey = "traceparent"
+)
+
+const (
+	AttrRuleId         = attribute.Key("rule.id")
+	AttrRuleName       = attribute.Key("rule.name")
+	AttrDatasourceId   = attribute.Key("datasource.id")
+	AttrDatasourceType = attribute.Key("datasource.type")
+	AttrQuery          = attribute.Key("datasource.query")
+	AttrIndex          = attribute.Key("datasource.index")
+	AttrResultCount    = attribute.Key("result.count")
+	AttrQueryTookMs    = attribute.Key("datasource.took_ms")
+	AttrQueryWarning   = attribute.Key("datasource.warning")
+	AttrNoticeType     = attribute.Key("notice.type")
+	AttrNoticeId       = attribute.Key("notice.id")
+)
+
+// Setup 启动链路追踪上报, endpoint 为空时不启用, 此时所有 Span 均为空实现
+func Setup(name, endpoint, batcher string, sampler float64) {
+	ztrace.StartAgent(ztrace.Config{
+		Name:     name,
+		Endpoint: endpoint,
+		Batcher:  batcher,
+		Sampler:  sampler,
+		Disabled: endpoint == "",
+	})
+}
+
+// Shutdown 停止链路追踪, 上报缓冲中的 Span
+func Shutdown() {
+	ztrace.StopAgent()
+}
+
+// Start 创建 Span, 需由调用方通过 End 结束
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartQuery 创建数据源查询的 Span, 记录数据源类型及查询语句, 便于在链路中定位慢查询
+func StartQuery(ctx context.Context, datasourceId, datasourceType, query, index string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		AttrDatasourceId.String(datasourceId),
+		AttrDatasourceType.String(datasourceType),
+		AttrQuery.String(query),
+	}
+	if index != "" {
+		attrs = append(attrs, AttrIndex.String(index))
+	}
+	return Start(ctx, "datasource.query", attrs...)
+}
+
+// End 记录错误后结束 Span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EndWithCount 记录结果数量及错误后结束 Span
+func EndWithCount(span trace.Span, count int, err error) {
+	span.SetAttributes(AttrResultCount.Int(count))
+	End(span, err)
+}
+
+// SetQueryStats 记录数据源侧的查询耗时及结果不完整的说明
+func SetQueryStats(span trace.Span, tookMs int64, warning string) {
+	span.SetAttributes(AttrQueryTookMs.Int64(tookMs))
+	if warning != "" {
+		span.SetAttributes(AttrQueryWarning.String(warning))
+	}
+}
+
+// TraceId 获取上下文中 Span 的 TraceId, 未启用链路追踪时返回空
+func TraceId(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// TraceParent 以 W3C traceparent 格式导出上下文中的 Span, 用于异步流程 (如告警通知) 关联到同一条链路
+func TraceParent(ctx context.Context) string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceParentKey)
+}
+
+// WithTraceParent 将 traceparent 还原为上下文中的父 Span
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentKey: traceParent})
+}